@@ -1,26 +1,34 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 // Config represents the application configuration
 type Config struct {
-	App       AppConfig       `mapstructure:"app"`
-	Node      NodeConfig      `mapstructure:"node"`
-	Server    ServerConfig    `mapstructure:"server"`
-	Consensus ConsensusConfig `mapstructure:"consensus"`
-	Sharding  ShardingConfig  `mapstructure:"sharding"`
-	Network   NetworkConfig   `mapstructure:"network"`
-	Storage   StorageConfig   `mapstructure:"storage"`
-	Security  SecurityConfig  `mapstructure:"security"`
-	Logging   LoggingConfig   `mapstructure:"logging"`
-	Bootstrap BootstrapConfig `mapstructure:"bootstrap"`
+	App        AppConfig        `mapstructure:"app"`
+	Node       NodeConfig       `mapstructure:"node"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Consensus  ConsensusConfig  `mapstructure:"consensus"`
+	Sharding   ShardingConfig   `mapstructure:"sharding"`
+	CrossShard CrossShardConfig `mapstructure:"cross_shard"`
+	Mempool    MempoolConfig    `mapstructure:"mempool"`
+	Network    NetworkConfig    `mapstructure:"network"`
+	Storage    StorageConfig    `mapstructure:"storage"`
+	Genesis    GenesisConfig    `mapstructure:"genesis"`
+	Security   SecurityConfig   `mapstructure:"security"`
+	Logging    LoggingConfig    `mapstructure:"logging"`
+	SLO        SLOConfig        `mapstructure:"slo"`
+	Comparator ComparatorConfig `mapstructure:"comparator"`
+	Bootstrap  BootstrapConfig  `mapstructure:"bootstrap"`
 }
 
 type AppConfig struct {
@@ -45,22 +53,89 @@ type NodeConfig struct {
 }
 
 type ServerConfig struct {
-	Port int    `mapstructure:"port"`
-	Host string `mapstructure:"host"`
-	Mode string `mapstructure:"mode"`
+	Port        int        `mapstructure:"port"`
+	Host        string     `mapstructure:"host"`
+	Mode        string     `mapstructure:"mode"`
+	GzipMinSize int        `mapstructure:"gzip_min_size"` // Smallest response body in bytes worth compressing
+	CORS        CORSConfig `mapstructure:"cors"`
+
+	MaxRequestBytes int64 `mapstructure:"max_request_bytes"` // Largest request body accepted; 0 for no limit
+
+	AdminTokens []AdminToken `mapstructure:"admin_tokens"` // Credentials accepted on admin endpoints
+	AuthReads   bool         `mapstructure:"auth_reads"`   // Require an admin token on every /api/v1 endpoint, not just admin ones
+}
+
+// AdminToken is a credential for the admin endpoints. The label names its
+// holder in the audit log; the token itself is never logged.
+type AdminToken struct {
+	Label string `mapstructure:"label"`
+	Token string `mapstructure:"token"`
+}
+
+// CORSConfig is the cross-origin policy of the HTTP API. Only listed origins
+// are admitted, so with none every cross-origin request is refused.
+type CORSConfig struct {
+	AllowedOrigins   []string `mapstructure:"allowed_origins"`   // Origins such as "https://explorer.example.com"; "*" admits any
+	AllowedMethods   []string `mapstructure:"allowed_methods"`   // Methods a cross-origin request may use
+	AllowedHeaders   []string `mapstructure:"allowed_headers"`   // Request headers a cross-origin request may send
+	AllowCredentials bool     `mapstructure:"allow_credentials"` // Let browsers send cookies and auth headers cross-origin
+	MaxAge           int      `mapstructure:"max_age"`           // Seconds browsers may cache a preflight answer; 0 omits the header
 }
 
 type ConsensusConfig struct {
-	Algorithm    string  `mapstructure:"algorithm"`
-	Difficulty   int     `mapstructure:"difficulty"`
-	BlockTime    int     `mapstructure:"block_time"`
-	MinStake     int64   `mapstructure:"min_stake"`
-	StakeRatio   float64 `mapstructure:"stake_ratio"`
-	ViewTimeout  int     `mapstructure:"view_timeout"`
-	Byzantine    int     `mapstructure:"byzantine"`
-	LayerDepth   int     `mapstructure:"layer_depth"`
-	ChannelCount int     `mapstructure:"channel_count"`
-	GasLimit     int64   `mapstructure:"gas_limit"`
+	Algorithm     string  `mapstructure:"algorithm"`
+	Difficulty    int     `mapstructure:"difficulty"`
+	BlockTime     int     `mapstructure:"block_time"`
+	MinStake      int64   `mapstructure:"min_stake"`
+	StakeRatio    float64 `mapstructure:"stake_ratio"`
+	ViewTimeout   int     `mapstructure:"view_timeout"`
+	Byzantine     int     `mapstructure:"byzantine"`
+	LayerDepth    int     `mapstructure:"layer_depth"`
+	ChannelCount  int     `mapstructure:"channel_count"`
+	GasLimit      int64   `mapstructure:"gas_limit"`
+	PhaseTimeout  int     `mapstructure:"phase_timeout"`    // Per-phase deadline in milliseconds
+	MetricsAlpha  float64 `mapstructure:"metrics_alpha"`    // EWMA smoothing factor for throughput/latency
+	LatencyWindow int     `mapstructure:"latency_window"`   // Samples kept for latency percentiles
+	MetricsWindow int     `mapstructure:"metrics_window"`   // Samples in the simple moving averages reported beside the EWMAs
+	FinalityDepth int64   `mapstructure:"finality_depth"`   // Blocks behind the head that can no longer be reorganized
+	StaleAfter    int     `mapstructure:"stale_after"`      // Seconds without a decision before consensus is reported unhealthy
+	MaxTxPerBlock int     `mapstructure:"max_tx_per_block"` // Transactions allowed in a single block
+	MaxBlockSize  int     `mapstructure:"max_block_size"`   // Encoded block size limit in bytes
+
+	UseBLSAggregation       bool   `mapstructure:"use_bls_aggregation"`       // Carry PBFT/PPBFT prepare and commit votes as one aggregated signature per phase
+	LSCCCheckpointInterval  int64  `mapstructure:"lscc_checkpoint_interval"`  // Committed LSCC rounds between state checkpoints; 0 disables periodic checkpoints
+	PPBFTCheckpointInterval int64  `mapstructure:"ppbft_checkpoint_interval"` // Committed PPBFT sequences between stable checkpoints, which advance the watermarks
+	EventLogSize            int    `mapstructure:"event_log_size"`            // Consensus events kept in storage for querying; 0 disables the event log
+	LSCCQueueSize           int    `mapstructure:"lscc_queue_size"`           // Blocks waiting for an LSCC round before EnqueueBlock rejects more
+	LSCCPipelineDepth       int    `mapstructure:"lscc_pipeline_depth"`       // Queued blocks handed to ProcessBlock at once
+	LSCCSelectionMode       string `mapstructure:"lscc_selection_mode"`       // How LSCC picks a proposer within a layer: "round_robin" or "stake_weighted"
+	LSCCSelectionSeed       string `mapstructure:"lscc_selection_seed"`       // Mixed with the round to draw stake-weighted proposers; nodes must share it to agree
+	ShardsPerLayer          int    `mapstructure:"shards_per_layer"`          // Shards in each LSCC layer; 0 spreads sharding.num_shards over layer_depth layers
+	LivenessWindow          int    `mapstructure:"liveness_window"`           // Seconds without participation before a validator is marked inactive; 0 disables liveness monitoring
+	BlockReward             int64  `mapstructure:"block_reward"`              // Subsidy paid out by each block, split with its tips between proposer and voters by stake, before any halving; 0 disables it
+	HalvingInterval         int64  `mapstructure:"halving_interval"`          // Blocks between halvings of the subsidy; 0 never halves it
+	EpochLength             int64  `mapstructure:"epoch_length"`              // Blocks between recomputations of the active validator set; 0 keeps every validator active
+	MaxValidators           int    `mapstructure:"max_validators"`            // Highest-staked validators admitted to each epoch; 0 admits every eligible one
+	MaxRetainedVotes        int    `mapstructure:"max_retained_votes"`        // Votes an LSCC or PPBFT engine keeps across rounds before evicting the oldest rounds'; 0 keeps them all
+	WarmupDelay             int    `mapstructure:"warmup_delay"`              // Longest wait in seconds for warmup_min_peers and warmup_min_validators before the first round; 0 starts at once
+	WarmupMinPeers          int    `mapstructure:"warmup_min_peers"`          // Connected peers needed to end the warm-up early
+	WarmupMinValidators     int    `mapstructure:"warmup_min_validators"`     // Active validators needed to end the warm-up early
+	ByzantineCheck          string `mapstructure:"byzantine_check"`           // What PBFT, PPBFT and LSCC do with a validator set smaller than 3*byzantine+1: "warn" or "reject"
+
+	FeeMarket FeeMarketConfig `mapstructure:"fee_market"`
+}
+
+// FeeMarketConfig prices block space with a base fee every transaction in a
+// block must pay. The base fee rises after blocks fuller than
+// TargetUtilization and falls after emptier ones, by at most
+// 1/ChangeDenominator per block; it is burned, and only the rest of each fee
+// is paid out with the block's rewards.
+type FeeMarketConfig struct {
+	Enabled           bool    `mapstructure:"enabled"`            // Charge and burn a base fee per transaction
+	InitialBaseFee    int64   `mapstructure:"initial_base_fee"`   // Base fee of the first block priced by the market
+	MinBaseFee        int64   `mapstructure:"min_base_fee"`       // Lowest base fee a block may charge
+	TargetUtilization float64 `mapstructure:"target_utilization"` // Block fullness (0-1) at which the base fee holds steady
+	ChangeDenominator int64   `mapstructure:"change_denominator"` // A full or empty block moves the base fee by 1/change_denominator
 }
 
 type ShardingConfig struct {
@@ -69,6 +144,74 @@ type ShardingConfig struct {
 	CrossShardDelay  int     `mapstructure:"cross_shard_delay"`
 	RebalanceThresh  float64 `mapstructure:"rebalance_threshold"`
 	LayeredStructure bool    `mapstructure:"layered_structure"`
+
+	AssignmentStrategy string `mapstructure:"assignment_strategy"` // How addresses map to shards: modulo or consistent (hash ring)
+	VirtualNodes       int    `mapstructure:"virtual_nodes"`       // Points per shard on the consistent hash ring
+
+	ShardConsensus   bool   `mapstructure:"shard_consensus"`   // Run a consensus engine per shard, each building its own shard chain
+	CoordinationMode string `mapstructure:"coordination_mode"` // How shard engines are scheduled: parallel, sequential or adaptive
+
+	AdaptiveHighConflictRate float64 `mapstructure:"adaptive_high_conflict_rate"` // Cross-shard conflict rate at which adaptive coordination turns sequential
+	AdaptiveLowConflictRate  float64 `mapstructure:"adaptive_low_conflict_rate"`  // Conflict rate at or below which it returns to parallel
+
+	JointRoundRetries int `mapstructure:"joint_round_retries"` // Aborted joint rounds before a cross-shard transaction is retried alone; if that aborts too it goes to its destination shard's pool
+}
+
+type CrossShardConfig struct {
+	Workers        int `mapstructure:"workers"`         // Goroutines handling cross-shard messages
+	QueueSize      int `mapstructure:"queue_size"`      // Messages buffered across all shard queues
+	EnqueueTimeout int `mapstructure:"enqueue_timeout"` // Milliseconds a sender waits on a full queue
+	PriorityAging  int `mapstructure:"priority_aging"`  // Milliseconds of waiting worth one priority level
+	DedupCapacity  int `mapstructure:"dedup_capacity"`  // Delivered message IDs remembered per shard
+	DedupTTL       int `mapstructure:"dedup_ttl"`       // Seconds a delivered message ID is remembered
+
+	LoadBalanceStrategy string `mapstructure:"load_balance_strategy"` // Route choice between shards: round_robin, least_latency or adaptive
+
+	ErrorRateAlertThreshold float64 `mapstructure:"error_rate_alert_threshold"` // Percent of messages failing over the window that marks cross-shard messaging degraded; 0 disables
+	ErrorRateWindow         int     `mapstructure:"error_rate_window"`          // Seconds of traffic the alerting error rate is measured over
+
+	SyncStrategy string               `mapstructure:"sync_strategy"` // How a lagging shard catches up: incremental (missing blocks only) or full
+	Sync         CrossShardSyncConfig `mapstructure:"sync"`
+
+	Durable bool `mapstructure:"durable"` // Log in-flight messages to storage and replay them after a restart
+
+	MaxHops int `mapstructure:"max_hops"` // Relays a message may pass through before it is dropped as looping
+
+	RelayBufferSize      int    `mapstructure:"relay_buffer_size"`      // Messages each relay node buffers before its overflow policy applies
+	RelayOverflowPolicy  string `mapstructure:"relay_overflow_policy"`  // What happens to a message when every relay on its route is full: drop_new (or its alias reject), drop_oldest, block_with_timeout or spill_to_disk
+	RelayOverflowTimeout int    `mapstructure:"relay_overflow_timeout"` // Milliseconds block_with_timeout waits for relay buffer space
+
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+}
+
+// CircuitBreakerConfig sets when sends to a shard are cut off. After
+// FailureThreshold consecutive failed sends to one shard, further sends to it
+// fail at once for Cooldown seconds; then a single probe send is let through,
+// and its outcome closes or reopens the breaker.
+type CircuitBreakerConfig struct {
+	FailureThreshold int `mapstructure:"failure_threshold"` // Consecutive failed sends that open a shard's breaker; 0 disables
+	Cooldown         int `mapstructure:"cooldown"`          // Seconds a breaker stays open before a probe is allowed
+}
+
+// CrossShardSyncConfig paces the shard sync requests raised by cross-shard
+// sync messages. Requests are picked up every Interval seconds, at most
+// Concurrency at a time, and each moves at most BatchSize blocks before
+// waiting for the next pass.
+type CrossShardSyncConfig struct {
+	BatchSize   int `mapstructure:"batch_size"`  // Blocks an incremental sync transfers per pass; longer ranges take several passes
+	Interval    int `mapstructure:"interval"`    // Seconds between passes over pending sync requests
+	MaxRetries  int `mapstructure:"max_retries"` // Failed attempts after which a sync request is given up
+	Concurrency int `mapstructure:"concurrency"` // Sync requests run in parallel per pass
+}
+
+type MempoolConfig struct {
+	MinFee           int64   `mapstructure:"min_fee"`            // Lowest fee accepted while the pool is uncongested; 0 disables the floor
+	CongestionTarget float64 `mapstructure:"congestion_target"`  // Pool occupancy (0-1) above which the fee floor rises
+	MaxFeeMultiplier float64 `mapstructure:"max_fee_multiplier"` // Fee floor at a full pool, as a multiple of min_fee
+	TxTTL            int     `mapstructure:"tx_ttl"`             // Seconds after its timestamp a transaction expires and is dropped; 0 disables expiry
+	MaxPerSender     int     `mapstructure:"max_per_sender"`     // Pending transactions one sender may hold in the pool; 0 disables the cap
+	MaxSenderValue   int64   `mapstructure:"max_sender_value"`   // Total amount plus fees one sender may have pending; 0 disables the cap
+	MaxBytes         int     `mapstructure:"max_bytes"`          // Encoded size of all pending transactions; 0 disables the cap
 }
 
 type NetworkConfig struct {
@@ -82,13 +225,29 @@ type NetworkConfig struct {
 	BindAddress  string   `mapstructure:"bind_address"`
 	Encryption   bool     `mapstructure:"encryption"`
 	AuthRequired bool     `mapstructure:"auth_required"`
+	MinPeers     int      `mapstructure:"min_peers"` // Connected peers required to report healthy
+	ChainID      string   `mapstructure:"chain_id"`  // Peers, messages and blocks from other chains are rejected
+
+	SyncBatchSize   int `mapstructure:"sync_batch_size"`  // Blocks requested from a peer at once when backfilling
+	SyncConcurrency int `mapstructure:"sync_concurrency"` // Batches fetched in parallel when backfilling
+	SyncMaxSpan     int `mapstructure:"sync_max_span"`    // Most blocks one backfill round fetches; a wider gap is filled over several rounds
 }
 
 type StorageConfig struct {
+	Backend    string `mapstructure:"backend"` // "badger" or "memory"
 	DataDir    string `mapstructure:"data_dir"`
 	CacheSize  int    `mapstructure:"cache_size"`
 	Compact    bool   `mapstructure:"compact"`
 	Encryption bool   `mapstructure:"encryption"`
+
+	AccountCacheSize int `mapstructure:"account_cache_size"` // Account balances and nonces cached in front of badger; 0 disables the cache
+}
+
+// GenesisConfig points at the genesis file used to create the chain when the
+// database is empty. Without a path the built-in genesis block is used.
+type GenesisConfig struct {
+	Path         string `mapstructure:"path"`
+	ExpectedHash string `mapstructure:"expected_hash"` // refuse to start on any other genesis
 }
 
 type SecurityConfig struct {
@@ -101,13 +260,32 @@ type SecurityConfig struct {
 }
 
 type LoggingConfig struct {
-	Level      string `mapstructure:"level"`
-	Format     string `mapstructure:"format"`
+	Level      string `mapstructure:"level"`  // debug, info, warn or error; LOG_LEVEL overrides
+	Format     string `mapstructure:"format"` // json or text; LOG_FORMAT overrides
 	Output     string `mapstructure:"output"`
 	MaxSize    int    `mapstructure:"max_size"`
 	MaxBackups int    `mapstructure:"max_backups"`
 	MaxAge     int    `mapstructure:"max_age"`
 	Compress   bool   `mapstructure:"compress"`
+
+	TraceBuffer int `mapstructure:"trace_buffer"` // Recent traces kept for GET /api/v1/trace/:id; 0 disables recording
+	TraceEvents int `mapstructure:"trace_events"` // Log lines kept per trace
+}
+
+// SLOConfig sets the service level objectives reported by GET /metrics/slo
+// and the lscc_slo_violation gauge
+type SLOConfig struct {
+	MinTPS           float64 `mapstructure:"min_tps"`            // Committed transactions per second to sustain; 0 disables
+	MaxBlockInterval int     `mapstructure:"max_block_interval"` // Seconds allowed between committed blocks; 0 disables
+	Window           int     `mapstructure:"window"`             // Seconds a threshold must stay breached before it counts as violated
+	WebhookURL       string  `mapstructure:"webhook_url"`        // Receives a POST when a violation starts or ends; empty disables
+}
+
+// ComparatorConfig sets where comparison baselines are kept and how far a
+// run may fall behind one before GET /comparator/regressions flags it
+type ComparatorConfig struct {
+	BaselineDir         string  `mapstructure:"baseline_dir"`         // Directory baselines are saved in as JSON; empty uses <data_dir>/baselines
+	RegressionTolerance float64 `mapstructure:"regression_tolerance"` // Fraction of its baseline value a metric may degrade by before it counts as a regression
 }
 
 // LoadConfig loads configuration from file and environment variables
@@ -220,6 +398,15 @@ func setDefaults() {
 	viper.SetDefault("server.port", 5000)
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.mode", "development")
+	viper.SetDefault("server.gzip_min_size", 1024)
+	viper.SetDefault("server.max_request_bytes", 1<<20)
+	viper.SetDefault("server.cors.allowed_origins", []string{})
+	viper.SetDefault("server.cors.allowed_methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
+	viper.SetDefault("server.cors.allowed_headers", []string{"Content-Type", "Content-Length", "Accept-Encoding", "Authorization", "Accept", "Origin", "Cache-Control", "X-Requested-With", "X-Request-ID", "X-API-Key"})
+	viper.SetDefault("server.cors.allow_credentials", false)
+	viper.SetDefault("server.cors.max_age", 600)
+	viper.SetDefault("server.admin_tokens", []map[string]string{})
+	viper.SetDefault("server.auth_reads", false)
 
 	// Consensus defaults
 	viper.SetDefault("consensus.algorithm", "lscc")
@@ -230,7 +417,39 @@ func setDefaults() {
 	viper.SetDefault("consensus.view_timeout", 30)
 	viper.SetDefault("consensus.byzantine", 1)
 	viper.SetDefault("consensus.layer_depth", 3)
+	viper.SetDefault("consensus.shards_per_layer", 0)
 	viper.SetDefault("consensus.channel_count", 5)
+	viper.SetDefault("consensus.phase_timeout", 2000)
+	viper.SetDefault("consensus.metrics_alpha", 0.2)
+	viper.SetDefault("consensus.latency_window", 256)
+	viper.SetDefault("consensus.metrics_window", 20)
+	viper.SetDefault("consensus.finality_depth", 6)
+	viper.SetDefault("consensus.stale_after", 60)
+	viper.SetDefault("consensus.max_tx_per_block", 2000)
+	viper.SetDefault("consensus.max_block_size", 2*1024*1024)
+	viper.SetDefault("consensus.use_bls_aggregation", false)
+	viper.SetDefault("consensus.lscc_checkpoint_interval", 10)
+	viper.SetDefault("consensus.ppbft_checkpoint_interval", 10)
+	viper.SetDefault("consensus.event_log_size", 10000)
+	viper.SetDefault("consensus.lscc_queue_size", 100)
+	viper.SetDefault("consensus.lscc_pipeline_depth", 2)
+	viper.SetDefault("consensus.lscc_selection_mode", "round_robin")
+	viper.SetDefault("consensus.lscc_selection_seed", "")
+	viper.SetDefault("consensus.liveness_window", 0)
+	viper.SetDefault("consensus.block_reward", 0)
+	viper.SetDefault("consensus.halving_interval", 210000)
+	viper.SetDefault("consensus.epoch_length", 0)
+	viper.SetDefault("consensus.max_validators", 0)
+	viper.SetDefault("consensus.max_retained_votes", 10000)
+	viper.SetDefault("consensus.warmup_delay", 30)
+	viper.SetDefault("consensus.warmup_min_peers", 0)
+	viper.SetDefault("consensus.warmup_min_validators", 1)
+	viper.SetDefault("consensus.byzantine_check", "warn")
+	viper.SetDefault("consensus.fee_market.enabled", false)
+	viper.SetDefault("consensus.fee_market.initial_base_fee", 10)
+	viper.SetDefault("consensus.fee_market.min_base_fee", 1)
+	viper.SetDefault("consensus.fee_market.target_utilization", 0.5)
+	viper.SetDefault("consensus.fee_market.change_denominator", 8)
 
 	// Sharding defaults
 	viper.SetDefault("sharding.num_shards", 4)
@@ -238,27 +457,77 @@ func setDefaults() {
 	viper.SetDefault("sharding.cross_shard_delay", 100)
 	viper.SetDefault("sharding.rebalance_threshold", 0.7)
 	viper.SetDefault("sharding.layered_structure", true)
+	viper.SetDefault("sharding.assignment_strategy", "modulo")
+	viper.SetDefault("sharding.virtual_nodes", 128)
+	viper.SetDefault("sharding.shard_consensus", false)
+	viper.SetDefault("sharding.coordination_mode", "adaptive")
+	viper.SetDefault("sharding.adaptive_high_conflict_rate", 0.1)
+	viper.SetDefault("sharding.adaptive_low_conflict_rate", 0.02)
+	viper.SetDefault("sharding.joint_round_retries", 3)
+
+	// Cross-shard defaults
+	viper.SetDefault("cross_shard.workers", 4)
+	viper.SetDefault("cross_shard.queue_size", 1000)
+	viper.SetDefault("cross_shard.enqueue_timeout", 100)
+	viper.SetDefault("cross_shard.priority_aging", 500)
+	viper.SetDefault("cross_shard.dedup_capacity", 10000)
+	viper.SetDefault("cross_shard.dedup_ttl", 300)
+	viper.SetDefault("cross_shard.load_balance_strategy", "adaptive")
+	viper.SetDefault("cross_shard.error_rate_alert_threshold", 10.0)
+	viper.SetDefault("cross_shard.error_rate_window", 60)
+	viper.SetDefault("cross_shard.sync_strategy", "incremental")
+	viper.SetDefault("cross_shard.sync.batch_size", 100)
+	viper.SetDefault("cross_shard.sync.interval", 10)
+	viper.SetDefault("cross_shard.sync.max_retries", 3)
+	viper.SetDefault("cross_shard.sync.concurrency", 5)
+	viper.SetDefault("cross_shard.durable", false)
+	viper.SetDefault("cross_shard.max_hops", 4)
+	viper.SetDefault("cross_shard.relay_buffer_size", 1000)
+	viper.SetDefault("cross_shard.relay_overflow_policy", "drop_new")
+	viper.SetDefault("cross_shard.relay_overflow_timeout", 200)
+	viper.SetDefault("cross_shard.circuit_breaker.failure_threshold", 5)
+	viper.SetDefault("cross_shard.circuit_breaker.cooldown", 30)
+
+	// Mempool defaults
+	viper.SetDefault("mempool.min_fee", 1)
+	viper.SetDefault("mempool.congestion_target", 0.5)
+	viper.SetDefault("mempool.max_fee_multiplier", 8.0)
+	viper.SetDefault("mempool.tx_ttl", 86400)
+	viper.SetDefault("mempool.max_per_sender", 64)
+	viper.SetDefault("mempool.max_sender_value", 0)
+	viper.SetDefault("mempool.max_bytes", 64*1024*1024)
 
 	// Network defaults
 	viper.SetDefault("network.port", 9000)
 	viper.SetDefault("network.max_peers", 50)
+	viper.SetDefault("network.min_peers", 0)
+	viper.SetDefault("network.chain_id", "lscc-mainnet")
 	viper.SetDefault("network.timeout", 30)
 	viper.SetDefault("network.keep_alive", 60)
 	viper.SetDefault("network.external_ip", "")
 	viper.SetDefault("network.bind_address", "0.0.0.0")
 	viper.SetDefault("network.encryption", false)
 	viper.SetDefault("network.auth_required", false)
+	viper.SetDefault("network.sync_batch_size", 100)
+	viper.SetDefault("network.sync_concurrency", 4)
+	viper.SetDefault("network.sync_max_span", 1000)
 
 	// Bootstrap defaults
 	viper.SetDefault("bootstrap.enabled", false)
 	viper.SetDefault("bootstrap.advertise_address", "")
 
 	// Storage defaults
+	viper.SetDefault("storage.backend", "badger")
 	viper.SetDefault("storage.data_dir", "./data")
 	viper.SetDefault("storage.cache_size", 100)
+	viper.SetDefault("storage.account_cache_size", 10000)
 	viper.SetDefault("storage.compact", true)
 	viper.SetDefault("storage.encryption", false)
 
+	// Genesis defaults
+	viper.SetDefault("genesis.path", "")
+	viper.SetDefault("genesis.expected_hash", "")
+
 	// Security defaults
 	viper.SetDefault("security.jwt_secret", "default-jwt-secret-change-in-production")
 	viper.SetDefault("security.tls_enabled", false)
@@ -273,6 +542,18 @@ func setDefaults() {
 	viper.SetDefault("logging.max_backups", 3)
 	viper.SetDefault("logging.max_age", 28)
 	viper.SetDefault("logging.compress", true)
+	viper.SetDefault("logging.trace_buffer", 1000)
+	viper.SetDefault("logging.trace_events", 200)
+
+	// SLO defaults
+	viper.SetDefault("slo.min_tps", 0.0)
+	viper.SetDefault("slo.max_block_interval", 0)
+	viper.SetDefault("slo.window", 60)
+	viper.SetDefault("slo.webhook_url", "")
+
+	// Comparator defaults
+	viper.SetDefault("comparator.baseline_dir", "")
+	viper.SetDefault("comparator.regression_tolerance", 0.1)
 }
 
 func overrideWithEnv(config *Config) {
@@ -303,15 +584,230 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("invalid consensus algorithm: %s", config.Consensus.Algorithm)
 	}
 
+	if config.Consensus.LSCCCheckpointInterval < 0 {
+		return fmt.Errorf("lscc checkpoint interval cannot be negative: %d", config.Consensus.LSCCCheckpointInterval)
+	}
+
+	if config.Consensus.PPBFTCheckpointInterval <= 0 {
+		return fmt.Errorf("ppbft checkpoint interval must be positive: %d", config.Consensus.PPBFTCheckpointInterval)
+	}
+
+	if config.Consensus.LSCCQueueSize < 1 {
+		return fmt.Errorf("lscc queue size must be at least 1: %d", config.Consensus.LSCCQueueSize)
+	}
+
+	if config.Consensus.LSCCPipelineDepth < 1 {
+		return fmt.Errorf("lscc pipeline depth must be at least 1: %d", config.Consensus.LSCCPipelineDepth)
+	}
+
+	switch config.Consensus.LSCCSelectionMode {
+	case "round_robin", "stake_weighted":
+	default:
+		return fmt.Errorf("invalid lscc selection mode %q: must be round_robin or stake_weighted", config.Consensus.LSCCSelectionMode)
+	}
+
+	if config.Consensus.BlockReward < 0 {
+		return fmt.Errorf("block reward cannot be negative: %d", config.Consensus.BlockReward)
+	}
+
+	if config.Consensus.HalvingInterval < 0 {
+		return fmt.Errorf("halving interval cannot be negative: %d", config.Consensus.HalvingInterval)
+	}
+
+	if market := config.Consensus.FeeMarket; market.Enabled {
+		// A zero base fee could never rise again
+		if market.MinBaseFee < 1 {
+			return fmt.Errorf("fee market min base fee must be at least 1: %d", market.MinBaseFee)
+		}
+		if market.InitialBaseFee < market.MinBaseFee {
+			return fmt.Errorf("fee market initial base fee %d is below the min base fee %d", market.InitialBaseFee, market.MinBaseFee)
+		}
+		if market.TargetUtilization <= 0 || market.TargetUtilization >= 1 {
+			return fmt.Errorf("fee market target utilization must be between 0 and 1 exclusive: %f", market.TargetUtilization)
+		}
+		if market.ChangeDenominator < 1 {
+			return fmt.Errorf("fee market change denominator must be at least 1: %d", market.ChangeDenominator)
+		}
+	}
+
+	if config.Consensus.EpochLength < 0 {
+		return fmt.Errorf("consensus epoch length cannot be negative: %d", config.Consensus.EpochLength)
+	}
+
+	if config.Consensus.MaxValidators < 0 {
+		return fmt.Errorf("consensus max validators cannot be negative: %d", config.Consensus.MaxValidators)
+	}
+
+	if config.Consensus.Byzantine < 0 {
+		return fmt.Errorf("consensus byzantine cannot be negative: %d", config.Consensus.Byzantine)
+	}
+
+	switch config.Consensus.ByzantineCheck {
+	case "warn", "reject":
+	default:
+		return fmt.Errorf("invalid byzantine check %q: must be warn or reject", config.Consensus.ByzantineCheck)
+	}
+
+	// Epochs capped below 3f+1 validators could never tolerate f faults
+	if required := 3*config.Consensus.Byzantine + 1; config.Consensus.ByzantineCheck == "reject" && config.Consensus.MaxValidators > 0 && config.Consensus.MaxValidators < required {
+		return fmt.Errorf("consensus max validators %d cannot tolerate %d byzantine validators, which needs at least %d", config.Consensus.MaxValidators, config.Consensus.Byzantine, required)
+	}
+
+	if config.Consensus.MaxRetainedVotes < 0 {
+		return fmt.Errorf("consensus max retained votes cannot be negative: %d", config.Consensus.MaxRetainedVotes)
+	}
+
+	if config.Consensus.WarmupDelay < 0 {
+		return fmt.Errorf("consensus warmup delay cannot be negative: %d", config.Consensus.WarmupDelay)
+	}
+
+	if config.Consensus.WarmupMinPeers < 0 || config.Consensus.WarmupMinValidators < 0 {
+		return fmt.Errorf("consensus warmup minimums cannot be negative: %d peers, %d validators",
+			config.Consensus.WarmupMinPeers, config.Consensus.WarmupMinValidators)
+	}
+
+	if config.Consensus.LivenessWindow < 0 {
+		return fmt.Errorf("consensus liveness window cannot be negative: %d", config.Consensus.LivenessWindow)
+	}
+
+	if config.Consensus.EventLogSize < 0 {
+		return fmt.Errorf("consensus event log size cannot be negative: %d", config.Consensus.EventLogSize)
+	}
+
+	if config.Consensus.PhaseTimeout < 0 {
+		return fmt.Errorf("consensus phase timeout cannot be negative: %d", config.Consensus.PhaseTimeout)
+	}
+
+	if config.Consensus.MetricsAlpha <= 0 || config.Consensus.MetricsAlpha > 1 {
+		return fmt.Errorf("consensus metrics alpha must be in (0, 1]: %g", config.Consensus.MetricsAlpha)
+	}
+
+	if config.Consensus.LatencyWindow < 1 {
+		return fmt.Errorf("consensus latency window must be at least 1: %d", config.Consensus.LatencyWindow)
+	}
+
+	if config.Consensus.MetricsWindow < 1 {
+		return fmt.Errorf("consensus metrics window must be at least 1: %d", config.Consensus.MetricsWindow)
+	}
+
+	if config.Consensus.FinalityDepth < 1 {
+		return fmt.Errorf("consensus finality depth must be at least 1: %d", config.Consensus.FinalityDepth)
+	}
+
+	if config.Consensus.StaleAfter < 1 {
+		return fmt.Errorf("consensus stale-after must be at least 1 second: %d", config.Consensus.StaleAfter)
+	}
+
+	if config.Consensus.MaxTxPerBlock < 1 {
+		return fmt.Errorf("consensus max transactions per block must be at least 1: %d", config.Consensus.MaxTxPerBlock)
+	}
+
+	if config.Consensus.MaxBlockSize < 1 {
+		return fmt.Errorf("consensus max block size must be at least 1 byte: %d", config.Consensus.MaxBlockSize)
+	}
+
 	// Validate ports
 	if config.Server.Port < 1 || config.Server.Port > 65535 {
 		return fmt.Errorf("invalid server port: %d", config.Server.Port)
 	}
 
+	if config.Server.GzipMinSize < 0 {
+		return fmt.Errorf("server gzip min size cannot be negative: %d", config.Server.GzipMinSize)
+	}
+
+	if config.Server.MaxRequestBytes < 0 {
+		return fmt.Errorf("server max request bytes cannot be negative: %d", config.Server.MaxRequestBytes)
+	}
+
+	for _, origin := range config.Server.CORS.AllowedOrigins {
+		if origin == "*" {
+			// Echoing any origin with credentials would let every site act
+			// as the signed-in user
+			if config.Server.CORS.AllowCredentials {
+				return fmt.Errorf("server CORS origin \"*\" cannot be combined with allow_credentials")
+			}
+			continue
+		}
+		parsed, err := url.Parse(origin)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") {
+			return fmt.Errorf("invalid server CORS origin %q: expected scheme://host[:port] or \"*\"", origin)
+		}
+	}
+
+	labels := make(map[string]bool)
+	for i, token := range config.Server.AdminTokens {
+		if token.Label == "" {
+			return fmt.Errorf("server admin token %d has no label", i)
+		}
+		if labels[token.Label] {
+			return fmt.Errorf("duplicate server admin token label %q", token.Label)
+		}
+		labels[token.Label] = true
+		if len(token.Token) < 16 {
+			return fmt.Errorf("server admin token %q must be at least 16 characters", token.Label)
+		}
+	}
+
+	if config.Server.AuthReads && len(config.Server.AdminTokens) == 0 {
+		return fmt.Errorf("server auth_reads requires at least one admin token")
+	}
+
+	for _, method := range config.Server.CORS.AllowedMethods {
+		if method == "" || strings.ToUpper(method) != method || strings.ContainsAny(method, " \t,") {
+			return fmt.Errorf("invalid server CORS method %q: expected an upper-case HTTP method", method)
+		}
+	}
+
+	if config.Server.CORS.MaxAge < 0 {
+		return fmt.Errorf("server CORS max age cannot be negative: %d", config.Server.CORS.MaxAge)
+	}
+
 	if config.Network.Port < 1 || config.Network.Port > 65535 {
 		return fmt.Errorf("invalid network port: %d", config.Network.Port)
 	}
 
+	if config.Network.MinPeers < 0 || config.Network.MinPeers > config.Network.MaxPeers {
+		return fmt.Errorf("network min peers must be between 0 and max peers (%d): %d", config.Network.MaxPeers, config.Network.MinPeers)
+	}
+
+	if config.Network.ChainID == "" {
+		return fmt.Errorf("network chain ID is required")
+	}
+
+	if config.Network.SyncBatchSize < 1 {
+		return fmt.Errorf("network sync batch size must be at least 1: %d", config.Network.SyncBatchSize)
+	}
+
+	if config.Network.SyncConcurrency < 1 {
+		return fmt.Errorf("network sync concurrency must be at least 1: %d", config.Network.SyncConcurrency)
+	}
+
+	if config.Network.SyncMaxSpan < 1 {
+		return fmt.Errorf("network sync max span must be at least 1: %d", config.Network.SyncMaxSpan)
+	}
+
+	// Validate storage configuration
+	if config.Storage.Backend != "badger" && config.Storage.Backend != "memory" {
+		return fmt.Errorf("unknown storage backend: %s", config.Storage.Backend)
+	}
+
+	// Validate genesis configuration
+	if config.Genesis.Path != "" {
+		if _, err := os.Stat(config.Genesis.Path); err != nil {
+			return fmt.Errorf("genesis file not readable: %w", err)
+		}
+	}
+
+	if hash := config.Genesis.ExpectedHash; hash != "" {
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 64 {
+			return fmt.Errorf("genesis expected hash must be 64 hex characters: %s", hash)
+		}
+	}
+
+	if config.Storage.AccountCacheSize < 0 {
+		return fmt.Errorf("storage account cache size cannot be negative: %d", config.Storage.AccountCacheSize)
+	}
+
 	// Validate sharding configuration
 	if config.Sharding.NumShards < 1 {
 		return fmt.Errorf("number of shards must be at least 1")
@@ -321,6 +817,197 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("shard size must be at least 1")
 	}
 
+	if config.Consensus.LayerDepth < 1 {
+		return fmt.Errorf("consensus layer depth must be at least 1: %d", config.Consensus.LayerDepth)
+	}
+
+	if perLayer := config.Consensus.ShardsPerLayer; perLayer < 0 {
+		return fmt.Errorf("consensus shards per layer cannot be negative: %d", perLayer)
+	} else if perLayer > 0 && perLayer*config.Consensus.LayerDepth < config.Sharding.NumShards {
+		return fmt.Errorf("consensus shards per layer %d across %d layers cannot hold %d shards",
+			perLayer, config.Consensus.LayerDepth, config.Sharding.NumShards)
+	}
+
+	switch config.Sharding.AssignmentStrategy {
+	case "modulo", "consistent":
+	default:
+		return fmt.Errorf("unknown shard assignment strategy %q (want modulo or consistent)", config.Sharding.AssignmentStrategy)
+	}
+
+	if config.Sharding.AssignmentStrategy == "consistent" && config.Sharding.VirtualNodes < 1 {
+		return fmt.Errorf("sharding virtual nodes must be at least 1: %d", config.Sharding.VirtualNodes)
+	}
+
+	switch config.Sharding.CoordinationMode {
+	case "parallel", "sequential", "adaptive":
+	default:
+		return fmt.Errorf("unknown shard coordination mode %q (want parallel, sequential or adaptive)", config.Sharding.CoordinationMode)
+	}
+
+	if config.Sharding.AdaptiveLowConflictRate < 0 || config.Sharding.AdaptiveHighConflictRate > 1 ||
+		config.Sharding.AdaptiveLowConflictRate >= config.Sharding.AdaptiveHighConflictRate {
+		return fmt.Errorf("adaptive conflict rates must satisfy 0 <= low < high <= 1: low %.3f, high %.3f",
+			config.Sharding.AdaptiveLowConflictRate, config.Sharding.AdaptiveHighConflictRate)
+	}
+
+	if config.Sharding.JointRoundRetries < 1 {
+		return fmt.Errorf("sharding joint round retries must be at least 1: %d", config.Sharding.JointRoundRetries)
+	}
+
+	// Validate cross-shard configuration
+	if config.CrossShard.Workers < 1 {
+		return fmt.Errorf("cross-shard workers must be at least 1")
+	}
+
+	if config.CrossShard.QueueSize < config.CrossShard.Workers {
+		return fmt.Errorf("cross-shard queue size must be at least the number of workers (%d)", config.CrossShard.Workers)
+	}
+
+	if config.CrossShard.EnqueueTimeout < 0 {
+		return fmt.Errorf("cross-shard enqueue timeout cannot be negative: %d", config.CrossShard.EnqueueTimeout)
+	}
+
+	if config.CrossShard.PriorityAging < 1 {
+		return fmt.Errorf("cross-shard priority aging must be at least 1ms: %d", config.CrossShard.PriorityAging)
+	}
+
+	if config.CrossShard.DedupCapacity < 1 {
+		return fmt.Errorf("cross-shard dedup capacity must be at least 1: %d", config.CrossShard.DedupCapacity)
+	}
+
+	if config.CrossShard.DedupTTL < 1 {
+		return fmt.Errorf("cross-shard dedup TTL must be at least 1 second: %d", config.CrossShard.DedupTTL)
+	}
+
+	switch config.CrossShard.LoadBalanceStrategy {
+	case "round_robin", "least_latency", "adaptive":
+	default:
+		return fmt.Errorf("unknown cross-shard load balance strategy %q (want round_robin, least_latency or adaptive)", config.CrossShard.LoadBalanceStrategy)
+	}
+
+	if config.CrossShard.ErrorRateAlertThreshold < 0 || config.CrossShard.ErrorRateAlertThreshold > 100 {
+		return fmt.Errorf("cross-shard error rate alert threshold must be a percentage between 0 and 100: %v", config.CrossShard.ErrorRateAlertThreshold)
+	}
+
+	if config.CrossShard.ErrorRateWindow < 1 {
+		return fmt.Errorf("cross-shard error rate window must be at least 1 second: %d", config.CrossShard.ErrorRateWindow)
+	}
+
+	switch config.CrossShard.SyncStrategy {
+	case "incremental", "full":
+	default:
+		return fmt.Errorf("unknown cross-shard sync strategy %q (want incremental or full)", config.CrossShard.SyncStrategy)
+	}
+
+	if config.CrossShard.Sync.BatchSize < 1 {
+		return fmt.Errorf("cross-shard sync batch size must be at least 1: %d", config.CrossShard.Sync.BatchSize)
+	}
+
+	if config.CrossShard.Sync.Interval < 1 {
+		return fmt.Errorf("cross-shard sync interval must be at least 1 second: %d", config.CrossShard.Sync.Interval)
+	}
+
+	if config.CrossShard.Sync.MaxRetries < 1 {
+		return fmt.Errorf("cross-shard sync max retries must be at least 1: %d", config.CrossShard.Sync.MaxRetries)
+	}
+
+	if config.CrossShard.Sync.Concurrency < 1 {
+		return fmt.Errorf("cross-shard sync concurrency must be at least 1: %d", config.CrossShard.Sync.Concurrency)
+	}
+
+	if config.CrossShard.MaxHops < 1 {
+		return fmt.Errorf("cross-shard max hops must be at least 1: %d", config.CrossShard.MaxHops)
+	}
+
+	if config.CrossShard.RelayBufferSize < 1 {
+		return fmt.Errorf("cross-shard relay buffer size must be at least 1: %d", config.CrossShard.RelayBufferSize)
+	}
+
+	switch config.CrossShard.RelayOverflowPolicy {
+	case "drop_new", "reject", "drop_oldest", "block_with_timeout", "spill_to_disk":
+	default:
+		return fmt.Errorf("unknown cross-shard relay overflow policy %q (want drop_new or reject, drop_oldest, block_with_timeout or spill_to_disk)", config.CrossShard.RelayOverflowPolicy)
+	}
+
+	if config.CrossShard.RelayOverflowTimeout < 1 {
+		return fmt.Errorf("cross-shard relay overflow timeout must be at least 1ms: %d", config.CrossShard.RelayOverflowTimeout)
+	}
+
+	if config.CrossShard.CircuitBreaker.FailureThreshold < 0 {
+		return fmt.Errorf("cross-shard circuit breaker failure threshold cannot be negative: %d", config.CrossShard.CircuitBreaker.FailureThreshold)
+	}
+
+	if config.CrossShard.CircuitBreaker.Cooldown < 1 {
+		return fmt.Errorf("cross-shard circuit breaker cooldown must be at least 1 second: %d", config.CrossShard.CircuitBreaker.Cooldown)
+	}
+
+	// Validate mempool configuration
+	if config.Mempool.MinFee < 0 {
+		return fmt.Errorf("mempool min fee cannot be negative: %d", config.Mempool.MinFee)
+	}
+
+	if config.Mempool.CongestionTarget <= 0 || config.Mempool.CongestionTarget > 1 {
+		return fmt.Errorf("mempool congestion target must be in (0, 1]: %v", config.Mempool.CongestionTarget)
+	}
+
+	if config.Mempool.MaxFeeMultiplier < 1 {
+		return fmt.Errorf("mempool max fee multiplier must be at least 1: %v", config.Mempool.MaxFeeMultiplier)
+	}
+
+	if config.Mempool.TxTTL < 0 {
+		return fmt.Errorf("mempool transaction TTL cannot be negative: %d", config.Mempool.TxTTL)
+	}
+
+	if config.Mempool.MaxPerSender < 0 {
+		return fmt.Errorf("mempool max per sender cannot be negative: %d", config.Mempool.MaxPerSender)
+	}
+
+	if config.Mempool.MaxSenderValue < 0 {
+		return fmt.Errorf("mempool max sender value cannot be negative: %d", config.Mempool.MaxSenderValue)
+	}
+
+	if config.Mempool.MaxBytes < 0 {
+		return fmt.Errorf("mempool max bytes cannot be negative: %d", config.Mempool.MaxBytes)
+	}
+
+	// Validate logging configuration
+	switch strings.ToLower(config.Logging.Level) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		return fmt.Errorf("unknown logging level %q (want debug, info, warn or error)", config.Logging.Level)
+	}
+
+	switch strings.ToLower(config.Logging.Format) {
+	case "json", "text":
+	default:
+		return fmt.Errorf("unknown logging format %q (want json or text)", config.Logging.Format)
+	}
+
+	if config.Logging.TraceBuffer < 0 {
+		return fmt.Errorf("logging trace buffer cannot be negative: %d", config.Logging.TraceBuffer)
+	}
+
+	if config.Logging.TraceBuffer > 0 && config.Logging.TraceEvents < 1 {
+		return fmt.Errorf("logging trace events must be at least 1: %d", config.Logging.TraceEvents)
+	}
+
+	// Validate SLO configuration
+	if config.SLO.MinTPS < 0 {
+		return fmt.Errorf("slo min tps cannot be negative: %v", config.SLO.MinTPS)
+	}
+
+	if config.SLO.MaxBlockInterval < 0 {
+		return fmt.Errorf("slo max block interval cannot be negative: %d", config.SLO.MaxBlockInterval)
+	}
+
+	if config.SLO.Window < 1 {
+		return fmt.Errorf("slo window must be at least 1 second: %d", config.SLO.Window)
+	}
+
+	if config.Comparator.RegressionTolerance < 0 {
+		return fmt.Errorf("comparator regression tolerance cannot be negative: %f", config.Comparator.RegressionTolerance)
+	}
+
 	// Create data directory if it doesn't exist
 	if err := os.MkdirAll(config.Storage.DataDir, 0755); err != nil {
 		return fmt.Errorf("failed to create data directory: %w", err)
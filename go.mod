@@ -3,6 +3,7 @@ module lscc-blockchain
 go 1.19
 
 require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.2.0
 	github.com/dgraph-io/badger/v3 v3.2103.5
 	github.com/gin-gonic/gin v1.9.1
 	github.com/prometheus/client_golang v1.15.1
@@ -0,0 +1,153 @@
+package api
+
+import (
+        "crypto/subtle"
+        "lscc-blockchain/config"
+        "lscc-blockchain/internal/utils"
+        "net/http"
+        "strings"
+
+        "github.com/gin-gonic/gin"
+        "github.com/sirupsen/logrus"
+)
+
+// APIKeyHeader carries an admin token as an alternative to a bearer token
+const APIKeyHeader = "X-API-Key"
+
+// authLabelKey is the gin context key holding the label of the token a
+// request was authorized with
+const authLabelKey = "auth_label"
+
+// adminPrefixes are API paths every route under which is an admin route:
+// operator tools and the test transaction generators
+var adminPrefixes = []string{
+        "/api/v1/admin",
+        "/api/v1/testing",
+        "/api/v1/transaction-injection",
+        "/api/v1/transactions/generate",
+}
+
+// adminMutationPrefixes are API paths whose non-read routes are admin
+// routes: switching consensus, recording validator heartbeats, pruning the
+// PPBFT message log and running or configuring comparisons
+var adminMutationPrefixes = []string{
+        "/api/v1/consensus",
+        "/api/v1/comparator",
+}
+
+// isAdminRoute reports whether a request for path with method changes node
+// state in a way reserved to operators
+func isAdminRoute(method, path string) bool {
+        for _, prefix := range adminPrefixes {
+                if hasPathPrefix(path, prefix) {
+                        return true
+                }
+        }
+        if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+                return false
+        }
+        for _, prefix := range adminMutationPrefixes {
+                if hasPathPrefix(path, prefix) {
+                        return true
+                }
+        }
+        return false
+}
+
+// hasPathPrefix reports whether path is prefix or lies beneath it
+func hasPathPrefix(path, prefix string) bool {
+        return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// requestToken returns the token sent as a bearer token or in X-API-Key
+func requestToken(c *gin.Context) string {
+        if header := c.GetHeader("Authorization"); header != "" {
+                if scheme, token, found := strings.Cut(header, " "); found && strings.EqualFold(scheme, "Bearer") {
+                        return strings.TrimSpace(token)
+                }
+        }
+        return c.GetHeader(APIKeyHeader)
+}
+
+// tokenLabel returns the label of the configured token matching token
+func tokenLabel(tokens []config.AdminToken, token string) (string, bool) {
+        label, found := "", false
+        // Every token is compared, so timing does not reveal which matched
+        for _, candidate := range tokens {
+                if subtle.ConstantTimeCompare([]byte(candidate.Token), []byte(token)) == 1 {
+                        label, found = candidate.Label, true
+                }
+        }
+        return label, found
+}
+
+// AuthMiddleware guards the admin endpoints with the tokens in
+// server.admin_tokens. A request to an admin route without a token gets 401
+// and one with an unknown token gets 403; authorized requests are logged
+// with the token's label. Other /api/v1 routes stay public unless
+// server.auth_reads is set. With no tokens configured, admin routes are
+// open in development mode and refused in production.
+func AuthMiddleware(server config.ServerConfig, logger *utils.Logger) gin.HandlerFunc {
+        return gin.HandlerFunc(func(c *gin.Context) {
+                path := c.Request.URL.Path
+                admin := isAdminRoute(c.Request.Method, path)
+                if !admin && !(server.AuthReads && hasPathPrefix(path, "/api/v1")) {
+                        c.Next()
+                        return
+                }
+                if c.Request.Method == http.MethodOptions {
+                        // Preflights carry no credentials
+                        c.Next()
+                        return
+                }
+
+                if len(server.AdminTokens) == 0 {
+                        if server.Mode == "production" {
+                                c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+                                        "error": "admin endpoints are disabled: no admin tokens configured",
+                                })
+                                return
+                        }
+                        c.Next()
+                        return
+                }
+
+                token := requestToken(c)
+                if token == "" {
+                        c.Header("WWW-Authenticate", `Bearer realm="lscc"`)
+                        c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+                                "error": "admin token required",
+                        })
+                        return
+                }
+
+                label, ok := tokenLabel(server.AdminTokens, token)
+                if !ok {
+                        logger.GetContextLogger("auth", logrus.Fields{
+                                "action":         "token_rejected",
+                                "method":         c.Request.Method,
+                                "path":           path,
+                                "client_ip":      c.ClientIP(),
+                                utils.TraceField: c.GetString(utils.TraceField),
+                        }).Warn("Rejected request with unknown admin token")
+                        c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+                                "error": "invalid admin token",
+                        })
+                        return
+                }
+
+                c.Set(authLabelKey, label)
+                if admin {
+                        logger.GetContextLogger("auth", logrus.Fields{
+                                "action":         "admin_request",
+                                "token_label":    label,
+                                "method":         c.Request.Method,
+                                "path":           path,
+                                "client_ip":      c.ClientIP(),
+                                utils.TraceField: c.GetString(utils.TraceField),
+                        }).Info("Admin request authorized")
+                }
+
+                c.Next()
+        })
+}
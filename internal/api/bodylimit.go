@@ -0,0 +1,61 @@
+package api
+
+import (
+        "bytes"
+        "errors"
+        "fmt"
+        "io"
+        "net/http"
+
+        "github.com/gin-gonic/gin"
+)
+
+// MaxBodyBytes caps request bodies at max bytes. A body over the cap is
+// refused with 413 before any handler runs: one that declares a larger
+// Content-Length is refused outright, and one of unknown length is read
+// through http.MaxBytesReader up to the cap. A max of 0 leaves bodies
+// unbounded.
+func MaxBodyBytes(max int64) gin.HandlerFunc {
+        return func(c *gin.Context) {
+                if max <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+                        c.Next()
+                        return
+                }
+
+                if c.Request.ContentLength > max {
+                        abortBodyTooLarge(c, max)
+                        return
+                }
+
+                body := http.MaxBytesReader(c.Writer, c.Request.Body, max)
+                if c.Request.ContentLength < 0 {
+                        // Read chunked bodies here so an overflow still gets 413
+                        data, err := io.ReadAll(body)
+                        body.Close()
+                        if err != nil {
+                                var tooLarge *http.MaxBytesError
+                                if errors.As(err, &tooLarge) {
+                                        abortBodyTooLarge(c, max)
+                                        return
+                                }
+                                c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+                                        "error": "failed to read request body",
+                                })
+                                return
+                        }
+                        c.Request.ContentLength = int64(len(data))
+                        c.Request.Body = io.NopCloser(bytes.NewReader(data))
+                } else {
+                        c.Request.Body = body
+                }
+
+                c.Next()
+        }
+}
+
+func abortBodyTooLarge(c *gin.Context, max int64) {
+        c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+                "error":     fmt.Sprintf("request body exceeds %d bytes", max),
+                "max_bytes": max,
+        })
+}
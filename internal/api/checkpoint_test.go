@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/blockchain"
+	"lscc-blockchain/internal/consensus"
+)
+
+func TestForceCheckpointRoute(t *testing.T) {
+	cfg := testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.Algorithm = "ppbft"
+		cfg.Consensus.PPBFTCheckpointInterval = 50
+	})
+	handlers := newTestHandlers(t, cfg)
+	router := newTestRouter(handlers)
+
+	var body struct {
+		Error      string                     `json:"error"`
+		Checkpoint consensus.CheckpointStatus `json:"checkpoint"`
+	}
+
+	// Genesis is already the stable checkpoint
+	rec := serve(router, http.MethodPost, "/api/v1/consensus/checkpoint", "")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("checkpoint at genesis: expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+	decode(t, rec, &body)
+	if body.Checkpoint.Interval != 50 || body.Checkpoint.LastCheckpoint != 0 {
+		t.Fatalf("unexpected checkpoint state %+v", body.Checkpoint)
+	}
+
+	// Without validators to vote, a new height cannot reach 2f+1
+	bc := handlers.blockchain
+	bm := blockchain.NewBlockManager(discardLogger(), 0, 0, 0, cfg.Network.ChainID)
+	block, err := bm.CreateBlock(bc.GetLatestBlock(), nil, "proposer", 0)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if len(bc.GetValidators()) != 0 {
+		t.Fatalf("expected no validators, got %d", len(bc.GetValidators()))
+	}
+	rec = serve(router, http.MethodPost, "/api/v1/consensus/checkpoint", "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("checkpoint without a quorum: expected 503, got %d: %s", rec.Code, rec.Body.String())
+	}
+	decode(t, rec, &body)
+	if body.Checkpoint.LastCheckpoint != 0 || body.Checkpoint.WatermarkLow != 0 {
+		t.Fatalf("failed checkpoint moved the window: %+v", body.Checkpoint)
+	}
+}
+
+func TestForceCheckpointNeedsPPBFT(t *testing.T) {
+	router := newTestRouter(newTestHandlers(t, testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.Algorithm = "lscc"
+	})))
+	if rec := serve(router, http.MethodPost, "/api/v1/consensus/checkpoint", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+        "errors"
         "fmt"
         "net/http"
         "strconv"
@@ -19,7 +20,8 @@ type ComparatorHandlers struct {
         logger     *utils.Logger
 }
 
-// NewComparatorHandlers creates new comparator handlers
+// NewComparatorHandlers creates new comparator handlers. comp may be nil when
+// the comparator failed to start, in which case every endpoint answers 503.
 func NewComparatorHandlers(comp *comparator.ConsensusComparator, logger *utils.Logger) *ComparatorHandlers {
         return &ComparatorHandlers{
                 comparator: comp,
@@ -29,7 +31,7 @@ func NewComparatorHandlers(comp *comparator.ConsensusComparator, logger *utils.L
 
 // RegisterRoutes registers comparator routes
 func (ch *ComparatorHandlers) RegisterRoutes(router *gin.RouterGroup) {
-        comparatorGroup := router.Group("/comparator")
+        comparatorGroup := router.Group("/comparator", ch.requireComparator)
         {
                 // Basic comparison endpoints
                 comparatorGroup.POST("/run", ch.RunComparison)
@@ -52,14 +54,31 @@ func (ch *ComparatorHandlers) RegisterRoutes(router *gin.RouterGroup) {
                 // Export results
                 comparatorGroup.GET("/export/:test_id", ch.ExportResults)
                 comparatorGroup.GET("/report/:test_id", ch.GenerateReport)
+
+                // Baselines and regression detection
+                comparatorGroup.POST("/baselines/:test_id", ch.SaveBaseline)
+                comparatorGroup.GET("/regressions", ch.GetRegressions)
         }
         
         ch.logger.Info("Comparator API routes registered", logrus.Fields{
-                "endpoints": 10,
+                "endpoints": 14,
+                "available": ch.comparator != nil,
                 "timestamp": time.Now(),
         })
 }
 
+// requireComparator rejects requests with 503 when there is no comparator
+func (ch *ComparatorHandlers) requireComparator(c *gin.Context) {
+        if ch.comparator == nil {
+                c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+                        "error":   "Consensus comparator is unavailable",
+                        "message": "The comparator failed to initialize when the node started; see the node log for the cause",
+                })
+                return
+        }
+        c.Next()
+}
+
 // RunComparison handles custom comparison test execution
 func (ch *ComparatorHandlers) RunComparison(c *gin.Context) {
         ch.logger.Info("Starting custom consensus comparison", logrus.Fields{
@@ -204,7 +223,7 @@ func (ch *ComparatorHandlers) RunStressTest(c *gin.Context) {
         c.JSON(http.StatusAccepted, gin.H{
                 "message":    "Stress test started asynchronously",
                 "duration":   "10 minutes",
-                "algorithms": []string{"lscc", "pbft", "ppbft", "pow", "pos"},
+                "algorithms": ch.comparator.GetAvailableAlgorithms(),
                 "note":       "Check /comparator/history for results",
         })
 }
@@ -323,10 +342,11 @@ func (ch *ComparatorHandlers) GetDefaultConfig(c *gin.Context) {
                         "concurrent_nodes":   4,
                         "network_latency":    "50ms",
                         "byzantine":          0.33,
-                        "algorithms":         []string{"lscc", "pbft", "ppbft", "pow", "pos"},
+                        "algorithms":         ch.comparator.GetAvailableAlgorithms(),
                         "metrics":            []string{"throughput", "latency", "finality", "energy", "scalability"},
                         "stress_test":        false,
                         "real_time_reporting": true,
+                        "resource_weight":    0.0,
                 },
                 "available_algorithms": ch.comparator.GetAvailableAlgorithms(),
                 "available_metrics":   []string{"throughput", "latency", "finality", "energy", "scalability", "security", "decentralization"},
@@ -464,10 +484,106 @@ func (ch *ComparatorHandlers) GenerateReport(c *gin.Context) {
         c.JSON(http.StatusOK, report)
 }
 
+// SaveBaseline saves the latest run of a test as the baseline later runs of
+// it are compared with
+func (ch *ComparatorHandlers) SaveBaseline(c *gin.Context) {
+        testID := c.Param("test_id")
+
+        testResult := ch.latestTest(testID)
+        if testResult == nil {
+                c.JSON(http.StatusNotFound, gin.H{
+                        "error":   "Test result not found",
+                        "test_id": testID,
+                })
+                return
+        }
+
+        if err := ch.comparator.SaveBaseline(testResult); err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{
+                        "error":   "Failed to save baseline",
+                        "details": err.Error(),
+                })
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "baseline":   testResult.TestName,
+                "test_start": testResult.StartTime,
+                "algorithms": testResult.AlgorithmsCompared,
+                "saved_at":   time.Now(),
+        })
+}
+
+// GetRegressions compares a run with a saved baseline. The run is the latest
+// of test_id, or the latest of all when test_id is omitted; tolerance
+// overrides the configured one.
+func (ch *ComparatorHandlers) GetRegressions(c *gin.Context) {
+        baseline := c.Query("baseline")
+        if baseline == "" {
+                c.JSON(http.StatusBadRequest, gin.H{
+                        "error": "baseline query parameter is required",
+                })
+                return
+        }
+
+        tolerance := ch.comparator.RegressionTolerance()
+        if raw := c.Query("tolerance"); raw != "" {
+                parsed, err := strconv.ParseFloat(raw, 64)
+                if err != nil || parsed < 0 {
+                        c.JSON(http.StatusBadRequest, gin.H{
+                                "error":     "tolerance must be a non-negative number",
+                                "tolerance": raw,
+                        })
+                        return
+                }
+                tolerance = parsed
+        }
+
+        testID := c.Query("test_id")
+        testResult := ch.latestTest(testID)
+        if testResult == nil {
+                c.JSON(http.StatusNotFound, gin.H{
+                        "error":   "Test result not found",
+                        "test_id": testID,
+                })
+                return
+        }
+
+        report, err := ch.comparator.CompareWithBaseline(baseline, testResult, tolerance)
+        if errors.Is(err, comparator.ErrBaselineNotFound) {
+                c.JSON(http.StatusNotFound, gin.H{
+                        "error":    "Baseline not found",
+                        "baseline": baseline,
+                })
+                return
+        }
+        if err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{
+                        "error":   "Failed to compare with baseline",
+                        "details": err.Error(),
+                })
+                return
+        }
+
+        c.JSON(http.StatusOK, report)
+}
+
+// latestTest returns the most recent run named testID, or the most recent
+// run of all when testID is empty
+func (ch *ComparatorHandlers) latestTest(testID string) *comparator.ComparatorSummary {
+        history := ch.comparator.GetTestHistory()
+        for i := len(history) - 1; i >= 0; i-- {
+                if testID == "" || history[i].TestName == testID {
+                        return history[i]
+                }
+        }
+        return nil
+}
+
 // validateTestConfig validates test configuration parameters
 func (ch *ComparatorHandlers) validateTestConfig(config *comparator.TestConfiguration) error {
-        if config.Duration <= 0 {
-                return fmt.Errorf("duration must be positive")
+        if err := config.Validate(); err != nil {
+                return err
         }
         if config.Duration > 30*time.Minute {
                 return fmt.Errorf("duration cannot exceed 30 minutes")
@@ -484,9 +600,6 @@ func (ch *ComparatorHandlers) validateTestConfig(config *comparator.TestConfigur
         if config.ConcurrentNodes > 16 {
                 return fmt.Errorf("concurrent nodes cannot exceed 16")
         }
-        if len(config.Algorithms) == 0 {
-                return fmt.Errorf("at least one algorithm must be specified")
-        }
         
         // Validate algorithm availability
         available := ch.comparator.GetAvailableAlgorithms()
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/comparator"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestComparatorEndpointsWithoutComparator(t *testing.T) {
+	router := newTestRouter(newTestHandlers(t, testConfig(t, nil)))
+
+	endpoints := []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/comparator/run"},
+		{http.MethodPost, "/api/v1/comparator/quick"},
+		{http.MethodPost, "/api/v1/comparator/stress"},
+		{http.MethodGet, "/api/v1/comparator/history"},
+		{http.MethodGet, "/api/v1/comparator/active"},
+		{http.MethodGet, "/api/v1/comparator/algorithms"},
+		{http.MethodGet, "/api/v1/comparator/config"},
+		{http.MethodPost, "/api/v1/comparator/config"},
+		{http.MethodGet, "/api/v1/comparator/status"},
+		{http.MethodGet, "/api/v1/comparator/metrics"},
+		{http.MethodGet, "/api/v1/comparator/export/test_1"},
+		{http.MethodGet, "/api/v1/comparator/report/test_1"},
+		{http.MethodPost, "/api/v1/comparator/baselines/test_1"},
+		{http.MethodGet, "/api/v1/comparator/regressions"},
+	}
+	for _, endpoint := range endpoints {
+		t.Run(endpoint.method+" "+endpoint.path, func(t *testing.T) {
+			rec := serve(router, endpoint.method, endpoint.path, "{}")
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("status = %d, want 503: %s", rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), "comparator is unavailable") {
+				t.Fatalf("body does not explain the 503: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestComparatorRegressionsEndpoint(t *testing.T) {
+	cfg := testConfig(t, func(cfg *config.Config) {
+		cfg.Comparator.BaselineDir = t.TempDir()
+	})
+	cc, err := comparator.NewConsensusComparator(cfg, discardLogger())
+	if err != nil {
+		t.Fatalf("failed to create comparator: %v", err)
+	}
+	t.Cleanup(func() { cc.Shutdown() })
+	router := gin.New()
+	SetupRoutes(router, newTestHandlers(t, cfg), cc, nil)
+
+	if rec := serve(router, http.MethodPost, "/api/v1/comparator/baselines/nightly", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("baseline of an unknown test: expected 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if _, err := cc.RunComparison(&comparator.TestConfiguration{
+		Name:            "nightly",
+		Duration:        2 * time.Second,
+		TransactionLoad: 30,
+		ConcurrentNodes: 4,
+		Algorithms:      []string{"pos"},
+		Metrics:         []string{"throughput"},
+	}); err != nil {
+		t.Fatalf("comparison failed: %v", err)
+	}
+	if rec := serve(router, http.MethodPost, "/api/v1/comparator/baselines/nightly", ""); rec.Code != http.StatusOK {
+		t.Fatalf("saving a baseline: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The run compared with its own baseline has not regressed
+	rec := serve(router, http.MethodGet, "/api/v1/comparator/regressions?baseline=nightly&test_id=nightly&tolerance=0.25", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report comparator.RegressionReport
+	decode(t, rec, &report)
+	if report.Baseline != "nightly" || report.TestName != "nightly" || report.Tolerance != 0.25 || report.Regressed {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	for _, metric := range report.Metrics {
+		if metric.Degradation != 0 {
+			t.Fatalf("%s of %s degraded by %v against itself", metric.Metric, metric.Algorithm, metric.Degradation)
+		}
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/comparator/regressions":                               http.StatusBadRequest,
+		"/api/v1/comparator/regressions?baseline=nightly&tolerance=-1": http.StatusBadRequest,
+		"/api/v1/comparator/regressions?baseline=weekly":               http.StatusNotFound,
+		"/api/v1/comparator/regressions?baseline=nightly&test_id=none": http.StatusNotFound,
+	} {
+		if rec := serve(router, http.MethodGet, path, ""); rec.Code != want {
+			t.Fatalf("%s: expected %d, got %d: %s", path, want, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"lscc-blockchain/config"
+)
+
+func TestConsensusEventsEndpoint(t *testing.T) {
+	router := newTestRouter(newTestHandlers(t, testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.EventLogSize = 50
+	})))
+
+	rec := serve(router, http.MethodGet, "/api/v1/consensus/events?block=missing&type=view_change&limit=5", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Count int `json:"count"`
+		Limit int `json:"limit"`
+	}
+	decode(t, rec, &body)
+	if body.Count != 0 || body.Limit != 5 {
+		t.Fatalf("response = %+v, want no events under a limit of 5", body)
+	}
+
+	for _, query := range []string{"?type=gossip", "?limit=0", "?limit=many"} {
+		if rec := serve(router, http.MethodGet, "/api/v1/consensus/events"+query, ""); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestConsensusEventsDisabled(t *testing.T) {
+	router := newTestRouter(newTestHandlers(t, testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.EventLogSize = 0
+	})))
+	if rec := serve(router, http.MethodGet, "/api/v1/consensus/events", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+}
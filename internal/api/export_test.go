@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"lscc-blockchain/internal/blockchain"
+	"lscc-blockchain/pkg/types"
+)
+
+// newExportHandlers returns test handlers over a chain of blocks blocks
+// after genesis, each carrying one transfer
+func newExportHandlers(t *testing.T, blocks int) *Handlers {
+	t.Helper()
+	sender := newTestAccount(t)
+	cfg := testConfig(t, nil)
+	withGenesisAlloc(t, cfg, 1000, sender)
+	handlers := newTestHandlers(t, cfg)
+
+	bm := blockchain.NewBlockManager(discardLogger(), 200000000, cfg.Consensus.MaxTxPerBlock, cfg.Consensus.MaxBlockSize, cfg.Network.ChainID)
+	for i := 1; i <= blocks; i++ {
+		tx := signedTransfer(t, sender, newTestAccount(t).address, 10, 10, int64(i))
+		block, err := bm.CreateBlock(handlers.blockchain.GetLatestBlock(), []*types.Transaction{tx}, "validator_0", 0)
+		if err != nil {
+			t.Fatalf("failed to create block %d: %v", i, err)
+		}
+		if err := handlers.blockchain.AddBlock(block); err != nil {
+			t.Fatalf("failed to add block %d: %v", i, err)
+		}
+	}
+	return handlers
+}
+
+// exportedLines returns each line of an export response
+func exportedLines(t *testing.T, router http.Handler, query string) []json.RawMessage {
+	t.Helper()
+	rec := serve(router, http.MethodGet, "/export/blocks"+query, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Fatalf("content type = %q, want application/x-ndjson", got)
+	}
+	var lines []json.RawMessage
+	scanner := bufio.NewScanner(rec.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, json.RawMessage(append([]byte(nil), scanner.Bytes()...)))
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func TestExportBlocksStreamsRangeInOrder(t *testing.T) {
+	handlers := newExportHandlers(t, 5)
+	router := newTestRouter(handlers)
+
+	lines := exportedLines(t, router, "?from=1&to=4")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines for blocks 1-4, got %d", len(lines))
+	}
+	var previous *types.Block
+	for i, line := range lines {
+		var block types.Block
+		if err := json.Unmarshal(line, &block); err != nil {
+			t.Fatalf("line %d is not a block: %v", i, err)
+		}
+		if block.Index != int64(i+1) {
+			t.Fatalf("line %d holds block %d, want %d", i, block.Index, i+1)
+		}
+		if !block.VerifyHash() {
+			t.Fatalf("block %d does not match its hash", block.Index)
+		}
+		if previous != nil && block.PreviousHash != previous.Hash {
+			t.Fatalf("block %d does not follow block %d", block.Index, previous.Index)
+		}
+		stored, err := handlers.blockchain.GetBlockByIndex(block.Index)
+		if err != nil || stored.Hash != block.Hash {
+			t.Fatalf("block %d differs from the chain's", block.Index)
+		}
+		if len(block.Transactions) != 1 || block.Transactions[0].ID != stored.Transactions[0].ID {
+			t.Fatalf("block %d exported without its transaction inline", block.Index)
+		}
+		previous = &block
+	}
+
+	// No bounds: the whole chain from genesis
+	if lines := exportedLines(t, router, ""); len(lines) != 6 {
+		t.Fatalf("expected genesis and 5 blocks, got %d lines", len(lines))
+	}
+	// A range past the head stops at the head
+	if lines := exportedLines(t, router, "?from=3&to=100"); len(lines) != 3 {
+		t.Fatalf("expected blocks 3-5, got %d lines", len(lines))
+	}
+}
+
+func TestExportBlocksByTransactionReference(t *testing.T) {
+	handlers := newExportHandlers(t, 2)
+	router := newTestRouter(handlers)
+
+	for i, line := range exportedLines(t, router, "?from=1&include_txs=false") {
+		var ref struct {
+			Index        int64    `json:"index"`
+			Transactions []string `json:"transactions"`
+		}
+		if err := json.Unmarshal(line, &ref); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		stored, err := handlers.blockchain.GetBlockByIndex(ref.Index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ref.Transactions) != 1 || ref.Transactions[0] != stored.Transactions[0].ID {
+			t.Fatalf("block %d: transactions %v, want the ID %s", ref.Index, ref.Transactions, stored.Transactions[0].ID)
+		}
+	}
+}
+
+func TestExportBlocksRejectsBadRange(t *testing.T) {
+	router := newTestRouter(newExportHandlers(t, 1))
+	tests := map[string]int{
+		"?from=-1":           http.StatusBadRequest,
+		"?from=x":            http.StatusBadRequest,
+		"?from=1&to=0":       http.StatusBadRequest,
+		"?include_txs=maybe": http.StatusBadRequest,
+		"?from=50":           http.StatusNotFound,
+	}
+	for query, want := range tests {
+		if rec := serve(router, http.MethodGet, "/export/blocks"+query, ""); rec.Code != want {
+			t.Fatalf("%s: expected %d, got %d", query, want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"lscc-blockchain/config"
+)
+
+func TestFeeEstimateReportsFloor(t *testing.T) {
+	router := newTestRouter(newTestHandlers(t, testConfig(t, func(cfg *config.Config) {
+		cfg.Mempool.MinFee = 7
+	})))
+
+	rec := serve(router, http.MethodGet, "/api/v1/fees/estimate", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var estimate struct {
+		FeeFloor  int64 `json:"fee_floor"`
+		MinFee    int64 `json:"min_fee"`
+		Congested bool  `json:"congested"`
+	}
+	decode(t, rec, &estimate)
+	if estimate.FeeFloor != 7 || estimate.MinFee != 7 || estimate.Congested {
+		t.Fatalf("estimate of an empty pool = %+v, want an uncongested floor of 7", estimate)
+	}
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+        "bufio"
+        "bytes"
+        "compress/gzip"
+        "net"
+        "net/http"
+        "strings"
+        "sync"
+
+        "github.com/gin-gonic/gin"
+)
+
+var gzipWriterPool = sync.Pool{
+        New: func() interface{} {
+                return gzip.NewWriter(nil)
+        },
+}
+
+// GzipMiddleware compresses responses for clients that send
+// Accept-Encoding: gzip. Bodies are buffered until they reach minSize bytes;
+// smaller responses are sent as-is. Handlers that set their own
+// Content-Encoding, or serve already-compressed content, are passed through.
+func GzipMiddleware(minSize int) gin.HandlerFunc {
+        return func(c *gin.Context) {
+                if c.Request.Method == http.MethodHead || !acceptsGzip(c.Request) {
+                        c.Next()
+                        return
+                }
+
+                gw := &gzipResponseWriter{
+                        ResponseWriter: c.Writer,
+                        minSize:        minSize,
+                }
+                c.Writer = gw
+                c.Header("Vary", "Accept-Encoding")
+
+                defer func() {
+                        gw.finish()
+                        c.Writer = gw.ResponseWriter
+                }()
+
+                c.Next()
+        }
+}
+
+// acceptsGzip reports whether the request lists gzip as an accepted encoding
+func acceptsGzip(r *http.Request) bool {
+        for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+                name := strings.TrimSpace(strings.SplitN(encoding, ";", 2)[0])
+                if name == "gzip" || name == "*" {
+                        return true
+                }
+        }
+        return false
+}
+
+// gzipResponseWriter buffers the start of a response to decide whether it is
+// worth compressing, then either gzips or passes through the rest
+type gzipResponseWriter struct {
+        gin.ResponseWriter
+        minSize     int
+        buffer      bytes.Buffer
+        gz          *gzip.Writer
+        passthrough bool
+}
+
+// Write buffers until the body is large enough to compress
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+        if w.passthrough {
+                return w.ResponseWriter.Write(data)
+        }
+        if w.gz != nil {
+                return w.gz.Write(data)
+        }
+
+        if w.skipCompression() {
+                if err := w.startPassthrough(); err != nil {
+                        return 0, err
+                }
+                return w.ResponseWriter.Write(data)
+        }
+
+        w.buffer.Write(data)
+        if w.buffer.Len() >= w.minSize {
+                if err := w.startGzip(); err != nil {
+                        return 0, err
+                }
+        }
+        return len(data), nil
+}
+
+// WriteString buffers like Write
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+        return w.Write([]byte(s))
+}
+
+// Flush starts compressing whatever has been buffered so streaming handlers
+// deliver data as they produce it
+func (w *gzipResponseWriter) Flush() {
+        if w.gz == nil && !w.passthrough {
+                var err error
+                if w.skipCompression() {
+                        err = w.startPassthrough()
+                } else {
+                        err = w.startGzip()
+                }
+                if err != nil {
+                        return
+                }
+        }
+        if w.gz != nil {
+                w.gz.Flush()
+        }
+        w.ResponseWriter.Flush()
+}
+
+// Size returns the number of body bytes accepted from the handler so far
+func (w *gzipResponseWriter) Size() int {
+        if w.gz == nil && !w.passthrough {
+                return w.buffer.Len()
+        }
+        return w.ResponseWriter.Size()
+}
+
+// Written reports whether the handler has produced a response
+func (w *gzipResponseWriter) Written() bool {
+        return w.buffer.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Hijack hands the connection to the handler uncompressed
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+        w.passthrough = true
+        return w.ResponseWriter.Hijack()
+}
+
+// skipCompression reports whether the handler's headers rule out gzip
+func (w *gzipResponseWriter) skipCompression() bool {
+        header := w.Header()
+        if header.Get("Content-Encoding") != "" {
+                return true
+        }
+        contentType := header.Get("Content-Type")
+        return strings.HasPrefix(contentType, "application/gzip") ||
+                strings.HasPrefix(contentType, "application/zip") ||
+                strings.HasPrefix(contentType, "image/") ||
+                strings.HasPrefix(contentType, "text/event-stream")
+}
+
+func (w *gzipResponseWriter) startPassthrough() error {
+        w.passthrough = true
+        if w.buffer.Len() == 0 {
+                return nil
+        }
+        _, err := w.ResponseWriter.Write(w.buffer.Bytes())
+        w.buffer.Reset()
+        return err
+}
+
+func (w *gzipResponseWriter) startGzip() error {
+        header := w.Header()
+        header.Set("Content-Encoding", "gzip")
+        header.Del("Content-Length")
+
+        w.gz = gzipWriterPool.Get().(*gzip.Writer)
+        w.gz.Reset(w.ResponseWriter)
+
+        if w.buffer.Len() == 0 {
+                return nil
+        }
+        _, err := w.gz.Write(w.buffer.Bytes())
+        w.buffer.Reset()
+        return err
+}
+
+// finish writes out a response that never reached minSize, or closes the
+// gzip stream
+func (w *gzipResponseWriter) finish() {
+        if w.gz != nil {
+                w.gz.Close()
+                gzipWriterPool.Put(w.gz)
+                w.gz = nil
+                return
+        }
+        if !w.passthrough && w.buffer.Len() > 0 {
+                w.ResponseWriter.Write(w.buffer.Bytes())
+                w.buffer.Reset()
+        }
+}
@@ -1,11 +1,15 @@
 package api
 
 import (
+        "compress/gzip"
         "crypto/rand"
         "encoding/hex"
+        "encoding/json"
+        "errors"
         "fmt"
         "lscc-blockchain/config"
         "lscc-blockchain/internal/blockchain"
+        "lscc-blockchain/internal/consensus"
         "lscc-blockchain/internal/metrics"
         "lscc-blockchain/internal/network"
         "lscc-blockchain/internal/sharding"
@@ -18,6 +22,7 @@ import (
         "time"
 
         "github.com/gin-gonic/gin"
+        "github.com/sirupsen/logrus"
 )
 
 // Handlers contains all API handlers
@@ -213,10 +218,134 @@ func (h *Handlers) APIDocumentation(c *gin.Context) {
 
 // Health returns the health status
 func (h *Handlers) Health(c *gin.Context) {
-        c.JSON(200, gin.H{
-                "status":  "healthy",
-                "node_id": h.config.Node.ID,
+        report := h.CheckHealth()
+        c.JSON(report.HTTPStatus(), report)
+}
+
+// ExportSnapshot streams a gzip-compressed backup of the node database
+func (h *Handlers) ExportSnapshot(c *gin.Context) {
+        height := h.blockchain.GetBlockHeight()
+        filename := fmt.Sprintf("lscc-snapshot-%d.bak.gz", height)
+
+        h.logger.Info("Exporting database snapshot", map[string]interface{}{
+                "component":    "storage",
+                "action":       "export_snapshot",
+                "block_height": height,
+                "timestamp":    time.Now(),
         })
+
+        c.Header("Content-Type", "application/gzip")
+        c.Header("Content-Disposition", "attachment; filename="+filename)
+        c.Status(http.StatusOK)
+
+        gz := gzip.NewWriter(c.Writer)
+        err := h.blockchain.GetDB().Backup(gz)
+        if closeErr := gz.Close(); err == nil {
+                err = closeErr
+        }
+
+        // Headers are already sent, so a failure can only be logged and the
+        // truncated stream left for the client to detect
+        if err != nil {
+                h.logger.Error("Failed to export database snapshot", map[string]interface{}{
+                        "component": "storage",
+                        "action":    "export_snapshot",
+                        "error":     err.Error(),
+                        "timestamp": time.Now(),
+                })
+        }
+}
+
+// exportFlushBlocks is how many exported blocks are written between flushes
+const exportFlushBlocks = 100
+
+// exportedBlockRef is an exported block whose transactions are listed by ID
+type exportedBlockRef struct {
+        *types.Block
+        Transactions []string `json:"transactions"`
+}
+
+// ExportBlocks streams the blocks from index from to index to, inclusive, as
+// newline-delimited JSON in index order. Blocks are read from storage one
+// at a time, so the range may be as long as the chain. Transactions are
+// inline unless include_txs=false, which lists their IDs instead.
+func (h *Handlers) ExportBlocks(c *gin.Context) {
+        height := h.blockchain.GetBlockHeight()
+
+        from, err := strconv.ParseInt(c.DefaultQuery("from", "0"), 10, 64)
+        if err != nil || from < 0 {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a non-negative block index"})
+                return
+        }
+        to := height
+        if raw := c.Query("to"); raw != "" {
+                if to, err = strconv.ParseInt(raw, 10, 64); err != nil || to < from {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a block index no lower than from"})
+                        return
+                }
+        }
+        if from > height {
+                c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("block %d is beyond the chain height %d", from, height)})
+                return
+        }
+        if to > height {
+                to = height
+        }
+        includeTxs, err := strconv.ParseBool(c.DefaultQuery("include_txs", "true"))
+        if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "include_txs must be true or false"})
+                return
+        }
+
+        h.logger.Info("Exporting blocks", map[string]interface{}{
+                "component":   "storage",
+                "action":      "export_blocks",
+                "from":        from,
+                "to":          to,
+                "include_txs": includeTxs,
+                "timestamp":   time.Now(),
+        })
+
+        c.Header("Content-Type", "application/x-ndjson")
+        c.Header("Transfer-Encoding", "chunked")
+        c.Status(http.StatusOK)
+
+        encoder := json.NewEncoder(c.Writer)
+        ctx := c.Request.Context()
+        for index := from; index <= to; index++ {
+                if ctx.Err() != nil {
+                        return
+                }
+                block, err := h.blockchain.GetBlockByIndex(index)
+                if err == nil {
+                        if includeTxs {
+                                err = encoder.Encode(block)
+                        } else {
+                                ref := exportedBlockRef{Block: block, Transactions: make([]string, len(block.Transactions))}
+                                for i, tx := range block.Transactions {
+                                        ref.Transactions[i] = tx.ID
+                                }
+                                err = encoder.Encode(ref)
+                        }
+                }
+
+                // Headers are already sent, so the stream just ends early and
+                // the client sees the last index it received
+                if err != nil {
+                        h.logger.Error("Failed to export block", map[string]interface{}{
+                                "component":   "storage",
+                                "action":      "export_blocks",
+                                "block_index": index,
+                                "error":       err.Error(),
+                                "timestamp":   time.Now(),
+                        })
+                        return
+                }
+                if (index-from+1)%exportFlushBlocks == 0 {
+                        c.Writer.Flush()
+                }
+        }
+        c.Writer.Flush()
 }
 
 // GetTransactionStatus returns overall transaction status across all layers and shards
@@ -285,12 +414,11 @@ func (h *Handlers) GenerateTransactions(c *gin.Context) {
                 return
         }
 
-        h.logger.Info("Generating test transactions", map[string]interface{}{
-                "component": "transaction",
-                "action":    "generate_bulk",
-                "count":     count,
-                "timestamp": time.Now(),
-        })
+        h.logger.GetContextLogger("transaction", logrus.Fields{
+                "action":         "generate_bulk",
+                "count":          count,
+                utils.TraceField: c.GetString(utils.TraceField),
+        }).Info("Generating test transactions")
 
         generatedTxs := make([]map[string]interface{}, 0)
 
@@ -325,7 +453,7 @@ func (h *Handlers) GenerateTransactions(c *gin.Context) {
                 }
 
                 // Submit transaction to blockchain
-                err := h.blockchain.SubmitTransaction(tx)
+                err := h.blockchain.SubmitTransactionContext(c.Request.Context(), tx)
                 if err != nil {
                         h.logger.Error("Failed to submit generated transaction", map[string]interface{}{
                                 "error":   err.Error(),
@@ -568,6 +696,57 @@ func (h *Handlers) ServeDocumentation(c *gin.Context) {
         c.File(realFilename)
 }
 
+// GetFeeEstimate returns the fee floor new transactions must meet, the
+// mempool state it was derived from and the next block's base fee
+func (h *Handlers) GetFeeEstimate(c *gin.Context) {
+        estimate := h.blockchain.GetFeeEstimate()
+
+        c.JSON(200, gin.H{
+                "fee_floor":         estimate.Floor,
+                "min_fee":           estimate.MinFee,
+                "base_fee":          estimate.BaseFee,
+                "recommended_fee":   estimate.RecommendedFee,
+                "pending_count":     estimate.PendingCount,
+                "pool_capacity":     estimate.PoolCapacity,
+                "utilization":       estimate.Utilization,
+                "congestion_target": estimate.CongestionTarget,
+                "congested":         estimate.Congested,
+                "timestamp":         time.Now().UTC(),
+        })
+}
+
+// GetMempoolStats returns mempool occupancy against the admission limits
+// and the senders with the most transactions pending
+func (h *Handlers) GetMempoolStats(c *gin.Context) {
+        limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+        if err != nil || limit < 1 {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+                return
+        }
+
+        stats := h.blockchain.GetMempoolStats(limit)
+        c.JSON(http.StatusOK, gin.H{
+                "pending_count":    stats.PendingCount,
+                "pool_capacity":    stats.PoolCapacity,
+                "pending_bytes":    stats.PendingBytes,
+                "max_bytes":        stats.MaxBytes,
+                "max_per_sender":   stats.MaxPerSender,
+                "max_sender_value": stats.MaxSenderValue,
+                "sender_count":     stats.SenderCount,
+                "senders":          stats.Senders,
+                "timestamp":        time.Now().UTC(),
+        })
+}
+
+// GetSupply reports the coins allocated at genesis, issued as block
+// subsidies and burned as base fees so far
+func (h *Handlers) GetSupply(c *gin.Context) {
+        c.JSON(http.StatusOK, gin.H{
+                "supply":    h.blockchain.GetSupply(),
+                "timestamp": time.Now().UTC(),
+        })
+}
+
 // GetBlockchainInfo returns general blockchain information
 func (h *Handlers) GetBlockchainInfo(c *gin.Context) {
         stats := h.blockchain.GetStats()
@@ -609,6 +788,7 @@ func (h *Handlers) GetBlockchainInfo(c *gin.Context) {
                 "chain_height":        stats.ChainHeight,
                 "total_transactions":  stats.TotalTransactions,
                 "last_block_hash":     stats.LastBlockHash,
+                "orphan_blocks":       stats.OrphanBlocks,
                 "network_peers":       networkPeers,
                 "average_latency":     averageLatency,
                 "current_tps":         currentTPS,
@@ -644,6 +824,401 @@ func (h *Handlers) GetConsensusStatus(c *gin.Context) {
         })
 }
 
+// GetConsensusEvents returns recorded consensus events, newest first,
+// optionally filtered by block hash and event type
+func (h *Handlers) GetConsensusEvents(c *gin.Context) {
+        limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+        if err != nil || limit < 1 {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+                return
+        }
+
+        eventType := c.Query("type")
+        if eventType != "" && !consensus.IsEventType(eventType) {
+                c.JSON(http.StatusBadRequest, gin.H{
+                        "error": fmt.Sprintf("unknown event type: %s", eventType),
+                        "types": []string{consensus.EventVoteReceived, consensus.EventPhaseCompleted, consensus.EventViewChange, consensus.EventCheckpoint},
+                })
+                return
+        }
+
+        events, err := h.blockchain.GetConsensusEvents(consensus.EventFilter{
+                BlockHash: c.Query("block"),
+                Type:      eventType,
+                Limit:     limit,
+        })
+        if err != nil {
+                if errors.Is(err, blockchain.ErrEventLogDisabled) {
+                        c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+                        return
+                }
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "events":    events,
+                "count":     len(events),
+                "limit":     limit,
+                "timestamp": time.Now().UTC(),
+        })
+}
+
+// GetTrace returns the log lines recorded under a correlation ID: the
+// request that started it, the transactions it submitted, the consensus
+// rounds that committed them and the cross-shard messages they caused
+func (h *Handlers) GetTrace(c *gin.Context) {
+        traces := h.logger.Traces()
+        if traces == nil {
+                c.JSON(http.StatusServiceUnavailable, gin.H{"error": "trace recording is disabled"})
+                return
+        }
+
+        traceID := c.Param("id")
+        if !utils.ValidTraceID(traceID) {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "invalid trace ID"})
+                return
+        }
+
+        trace, found := traces.Get(traceID)
+        if !found {
+                c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no events recorded for trace %s", traceID)})
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "trace":       trace,
+                "event_count": len(trace.Events),
+                "timestamp":   time.Now().UTC(),
+        })
+}
+
+// GetEpoch returns the current validator epoch and up to limit of the
+// epochs before it, most recent first
+func (h *Handlers) GetEpoch(c *gin.Context) {
+        limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+        if err != nil || limit < 0 {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a non-negative integer"})
+                return
+        }
+
+        epoch, err := h.blockchain.GetEpoch()
+        if err != nil {
+                if errors.Is(err, blockchain.ErrEpochsDisabled) {
+                        c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+                        return
+                }
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                return
+        }
+        previous := []*blockchain.Epoch{}
+        if limit > 0 {
+                if previous, err = h.blockchain.GetEpochs(limit); err != nil {
+                        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                        return
+                }
+        }
+
+        height := h.blockchain.GetBlockHeight()
+        c.JSON(http.StatusOK, gin.H{
+                "epoch":            epoch,
+                "block_height":     height,
+                "blocks_remaining": epoch.EndHeight - height,
+                "previous":         previous,
+                "timestamp":        time.Now().UTC(),
+        })
+}
+
+// GetValidators returns every validator with the block rewards it has
+// accrued
+func (h *Handlers) GetValidators(c *gin.Context) {
+        validators := h.blockchain.GetValidatorRewards()
+        var total int64
+        for _, validator := range validators {
+                total += validator.AccruedRewards
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "validators":    validators,
+                "count":         len(validators),
+                "total_rewards": total,
+                "timestamp":     time.Now().UTC(),
+        })
+}
+
+// RecordValidatorHeartbeat keeps a validator active between the rounds it
+// takes part in
+func (h *Handlers) RecordValidatorHeartbeat(c *gin.Context) {
+        address := c.Param("address")
+        if err := h.blockchain.RecordHeartbeat(address); err != nil {
+                switch {
+                case errors.Is(err, blockchain.ErrLivenessDisabled):
+                        c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+                case errors.Is(err, blockchain.ErrUnknownValidator):
+                        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+                default:
+                        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                }
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "validator": address,
+                "timestamp": time.Now().UTC(),
+        })
+}
+
+// GetPPBFTMessageLog returns the size and sequence range of the PPBFT message log
+func (h *Handlers) GetPPBFTMessageLog(c *gin.Context) {
+        stats, err := h.blockchain.GetPPBFTMessageLog()
+        if err != nil {
+                h.messageLogError(c, err)
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "message_log": stats,
+                "timestamp":   time.Now().UTC(),
+        })
+}
+
+// PrunePPBFTMessageLog drops PPBFT messages below the low watermark without
+// waiting for the next commit
+func (h *Handlers) PrunePPBFTMessageLog(c *gin.Context) {
+        removed, stats, err := h.blockchain.PrunePPBFTMessageLog()
+        if err != nil {
+                h.messageLogError(c, err)
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "removed":     removed,
+                "message_log": stats,
+                "timestamp":   time.Now().UTC(),
+        })
+}
+
+// ForceCheckpoint has PPBFT take a stable checkpoint at the current height
+// instead of waiting for the next interval, such as before maintenance
+func (h *Handlers) ForceCheckpoint(c *gin.Context) {
+        sequence, status, err := h.blockchain.ForcePPBFTCheckpoint()
+        switch {
+        case errors.Is(err, blockchain.ErrNoMessageLog):
+                h.messageLogError(c, err)
+                return
+        case errors.Is(err, consensus.ErrCheckpointNotAhead):
+                c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "checkpoint": status})
+                return
+        case consensus.ClassifyFailure(err) == consensus.FailureInsufficientVotes:
+                c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "checkpoint": status})
+                return
+        case err != nil:
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "sequence":   sequence,
+                "checkpoint": status,
+                "timestamp":  time.Now().UTC(),
+        })
+}
+
+// DebugConsensusStep dry-runs an LSCC round on a block and returns the state
+// after each of its four phases. The body may carry the block to run under
+// "block"; without one the block the next round would propose is used.
+func (h *Handlers) DebugConsensusStep(c *gin.Context) {
+        var request struct {
+                Block *types.Block `json:"block"`
+        }
+        if c.Request.ContentLength != 0 {
+                if err := c.ShouldBindJSON(&request); err != nil {
+                        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+                        return
+                }
+        }
+
+        simulation, err := h.blockchain.SimulateBlock(request.Block)
+        switch {
+        case errors.Is(err, blockchain.ErrNoSimulator):
+                c.JSON(http.StatusConflict, gin.H{
+                        "error":     err.Error(),
+                        "algorithm": h.blockchain.GetConsensusAlgorithm(),
+                })
+                return
+        case errors.Is(err, consensus.ErrNoValidators):
+                c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+                return
+        case err != nil:
+                c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "simulation": simulation,
+                "timestamp":  time.Now().UTC(),
+        })
+}
+
+func (h *Handlers) messageLogError(c *gin.Context, err error) {
+        if errors.Is(err, blockchain.ErrNoMessageLog) {
+                c.JSON(http.StatusConflict, gin.H{
+                        "error":     err.Error(),
+                        "algorithm": h.blockchain.GetConsensusAlgorithm(),
+                })
+                return
+        }
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// GetTransactionReceipt reports whether a transaction is pending, confirmed,
+// failed or expired
+func (h *Handlers) GetTransactionReceipt(c *gin.Context) {
+        txID := c.Param("hash")
+
+        receipt, err := h.blockchain.GetTransactionReceipt(txID)
+        if err != nil {
+                c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found", "tx_id": txID})
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "receipt":   receipt,
+                "timestamp": time.Now().UTC(),
+        })
+}
+
+// GetTxStatus reports where a transaction is in its lifecycle: pending,
+// included, finalized or rejected
+func (h *Handlers) GetTxStatus(c *gin.Context) {
+        txID := c.Param("hash")
+
+        status, err := h.blockchain.GetTxStatus(txID)
+        if err != nil {
+                c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found", "tx_id": txID})
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "status":           status,
+                "finalized_height": h.blockchain.GetFinalizedHeight(),
+                "timestamp":        time.Now().UTC(),
+        })
+}
+
+// GetRoutes lists every known cross-shard route with its latency,
+// reliability, load and priority
+func (h *Handlers) GetRoutes(c *gin.Context) {
+        communicator := h.shardManager.Communicator()
+        if communicator == nil {
+                c.JSON(http.StatusServiceUnavailable, gin.H{"error": "cross-shard communication is not running"})
+                return
+        }
+
+        routes := communicator.ListRoutes()
+        c.JSON(http.StatusOK, gin.H{
+                "routes":    routes,
+                "count":     len(routes),
+                "timestamp": time.Now().UTC(),
+        })
+}
+
+// PruneRoutes removes cross-shard routes unused for longer than the
+// older_than duration
+func (h *Handlers) PruneRoutes(c *gin.Context) {
+        olderThan, err := time.ParseDuration(c.Query("older_than"))
+        if err != nil || olderThan <= 0 {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "older_than must be a positive duration such as 10m"})
+                return
+        }
+
+        communicator := h.shardManager.Communicator()
+        if communicator == nil {
+                c.JSON(http.StatusServiceUnavailable, gin.H{"error": "cross-shard communication is not running"})
+                return
+        }
+
+        removed := communicator.PruneRoutes(olderThan)
+        c.JSON(http.StatusOK, gin.H{
+                "removed":    removed,
+                "remaining":  len(communicator.ListRoutes()),
+                "older_than": olderThan.String(),
+                "timestamp":  time.Now().UTC(),
+        })
+}
+
+// GetSLOStatus reports the node's block-interval and throughput SLOs
+func (h *Handlers) GetSLOStatus(c *gin.Context) {
+        if h.metrics == nil {
+                c.JSON(http.StatusServiceUnavailable, gin.H{"error": "metrics are not collected on this node"})
+                return
+        }
+        report, ok := h.metrics.GetSLOReport()
+        if !ok {
+                c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SLO monitoring is not enabled"})
+                return
+        }
+
+        violated := make([]string, 0)
+        for _, slo := range report.SLOs {
+                if slo.Violated {
+                        violated = append(violated, slo.Type)
+                }
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "slo":       report,
+                "violated":  violated,
+                "healthy":   len(violated) == 0,
+                "timestamp": time.Now().UTC(),
+        })
+}
+
+// GetActiveConsensus returns the consensus algorithm the node is running
+func (h *Handlers) GetActiveConsensus(c *gin.Context) {
+        c.JSON(http.StatusOK, gin.H{
+                "algorithm": h.blockchain.GetConsensusAlgorithm(),
+                "available": consensus.RegisteredAlgorithms(),
+                "running":   h.blockchain.IsRunning(),
+                "timestamp": time.Now().UTC(),
+        })
+}
+
+// SwitchConsensus swaps the active consensus algorithm at runtime
+func (h *Handlers) SwitchConsensus(c *gin.Context) {
+        var request struct {
+                Algorithm string `json:"algorithm" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&request); err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "algorithm is required"})
+                return
+        }
+
+        algorithm := strings.ToLower(request.Algorithm)
+        previous := h.blockchain.GetConsensusAlgorithm()
+
+        if err := h.blockchain.SwitchConsensusAlgorithm(algorithm); err != nil {
+                switch {
+                case errors.Is(err, consensus.ErrUnknownAlgorithm):
+                        c.JSON(http.StatusBadRequest, gin.H{
+                                "error":     err.Error(),
+                                "available": consensus.RegisteredAlgorithms(),
+                        })
+                case errors.Is(err, blockchain.ErrBlockInProgress):
+                        c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+                default:
+                        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+                }
+                return
+        }
+
+        c.JSON(http.StatusOK, gin.H{
+                "algorithm":          algorithm,
+                "previous_algorithm": previous,
+                "timestamp":          time.Now().UTC(),
+        })
+}
+
 // generateRandomHash generates a random hash for demo purposes
 func generateRandomHash() string {
         bytes := make([]byte, 32)
@@ -0,0 +1,241 @@
+package api
+
+import (
+        "fmt"
+        "lscc-blockchain/internal/blockchain"
+        "lscc-blockchain/internal/network"
+        "lscc-blockchain/internal/sharding"
+        "lscc-blockchain/internal/storage"
+        "net/http"
+        "time"
+)
+
+// HealthStatus is the result of checking a single subsystem
+type HealthStatus struct {
+        Component string                 `json:"component"`
+        Healthy   bool                   `json:"healthy"`
+        Message   string                 `json:"message"`
+        Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// HealthChecker checks the health of one subsystem
+type HealthChecker interface {
+        Check() HealthStatus
+}
+
+// HealthReport aggregates the health of all subsystems
+type HealthReport struct {
+        Status     string         `json:"status"`
+        NodeID     string         `json:"node_id"`
+        Components []HealthStatus `json:"components"`
+        Unhealthy  []string       `json:"unhealthy,omitempty"`
+        Timestamp  time.Time      `json:"timestamp"`
+}
+
+// HTTPStatus returns 200 when every component is healthy and 503 otherwise
+func (r *HealthReport) HTTPStatus() int {
+        if len(r.Unhealthy) > 0 {
+                return http.StatusServiceUnavailable
+        }
+        return http.StatusOK
+}
+
+// ConsensusHealthChecker reports consensus as stale when pending work has
+// waited longer than staleAfter without a block being committed
+type ConsensusHealthChecker struct {
+        blockchain *blockchain.Blockchain
+        staleAfter time.Duration
+}
+
+// Check implements HealthChecker. It only reads blockchain state so that a
+// stuck consensus round cannot block the health endpoint.
+func (c *ConsensusHealthChecker) Check() HealthStatus {
+        status := HealthStatus{Component: "consensus", Healthy: true, Message: "ok"}
+        if c.blockchain == nil {
+                status.Healthy = false
+                status.Message = "blockchain not initialized"
+                return status
+        }
+
+        lastDecision := c.blockchain.GetLastDecisionTime()
+        pending := c.blockchain.GetPendingTransactions()
+
+        // Measure from whichever is later: the last decision or the oldest
+        // pending transaction, so an idle chain is not reported as stale as
+        // soon as new work arrives
+        waitingSince := lastDecision
+        var oldestPending time.Time
+        for _, tx := range pending {
+                if oldestPending.IsZero() || tx.Timestamp.Before(oldestPending) {
+                        oldestPending = tx.Timestamp
+                }
+        }
+        if oldestPending.After(waitingSince) {
+                waitingSince = oldestPending
+        }
+        waiting := time.Since(waitingSince)
+
+        status.Details = map[string]interface{}{
+                "running":              c.blockchain.IsRunning(),
+                "block_height":         c.blockchain.GetBlockHeight(),
+                "last_decision":        lastDecision.UTC(),
+                "last_decision_age_ms": time.Since(lastDecision).Milliseconds(),
+                "pending_transactions": len(pending),
+                "stale_after_ms":       c.staleAfter.Milliseconds(),
+        }
+
+        switch {
+        case !c.blockchain.IsRunning():
+                status.Healthy = false
+                status.Message = "consensus is not running"
+        case len(pending) > 0 && waiting > c.staleAfter:
+                status.Healthy = false
+                status.Message = fmt.Sprintf("no decision for %s with %d pending transactions", waiting.Round(time.Second), len(pending))
+        }
+        return status
+}
+
+// ShardingHealthChecker reports sharding as unhealthy when no shard is active
+type ShardingHealthChecker struct {
+        shardManager *sharding.ShardManager
+}
+
+// Check implements HealthChecker
+func (c *ShardingHealthChecker) Check() HealthStatus {
+        status := HealthStatus{Component: "sharding", Healthy: true, Message: "ok"}
+        if c.shardManager == nil {
+                status.Healthy = false
+                status.Message = "shard manager not initialized"
+                return status
+        }
+
+        shards := c.shardManager.GetAllShards()
+        active := 0
+        healthy := 0
+        for _, shard := range shards {
+                if shard.GetStatus().Status == "active" {
+                        active++
+                }
+                if shard.IsHealthy() {
+                        healthy++
+                }
+        }
+
+        status.Details = map[string]interface{}{
+                "total_shards":   len(shards),
+                "active_shards":  active,
+                "healthy_shards": healthy,
+        }
+
+        if active == 0 {
+                status.Healthy = false
+                status.Message = "no active shards"
+        }
+        return status
+}
+
+// P2PHealthChecker reports P2P as unhealthy when fewer than minPeers peers
+// are connected
+type P2PHealthChecker struct {
+        network  *network.P2PNetwork
+        minPeers int
+}
+
+// Check implements HealthChecker
+func (c *P2PHealthChecker) Check() HealthStatus {
+        status := HealthStatus{Component: "p2p", Healthy: true, Message: "ok"}
+
+        connected := 0
+        total := 0
+        if c.network != nil {
+                peers := c.network.GetPeers()
+                total = len(peers)
+                for _, peer := range peers {
+                        if peer.Connected {
+                                connected++
+                        }
+                }
+        }
+
+        status.Details = map[string]interface{}{
+                "enabled":         c.network != nil,
+                "connected_peers": connected,
+                "known_peers":     total,
+                "min_peers":       c.minPeers,
+        }
+
+        if connected < c.minPeers {
+                status.Healthy = false
+                status.Message = fmt.Sprintf("%d connected peers, need at least %d", connected, c.minPeers)
+        }
+        return status
+}
+
+// StorageHealthChecker reports storage as unhealthy when the latest block
+// cannot be read back from the database
+type StorageHealthChecker struct {
+        db storage.Database
+}
+
+// Check implements HealthChecker
+func (c *StorageHealthChecker) Check() HealthStatus {
+        status := HealthStatus{Component: "storage", Healthy: true, Message: "ok"}
+        if c.db == nil {
+                status.Healthy = false
+                status.Message = "database not initialized"
+                return status
+        }
+
+        start := time.Now()
+        _, err := c.db.GetLatestBlock()
+        status.Details = map[string]interface{}{
+                "latency_ms": time.Since(start).Milliseconds(),
+        }
+
+        if err != nil {
+                status.Healthy = false
+                status.Message = fmt.Sprintf("database unreachable: %v", err)
+        }
+        return status
+}
+
+// healthCheckers returns the checkers for every subsystem this node runs
+func (h *Handlers) healthCheckers() []HealthChecker {
+        var db storage.Database
+        if h.blockchain != nil {
+                db = h.blockchain.GetDB()
+        }
+
+        return []HealthChecker{
+                &ConsensusHealthChecker{
+                        blockchain: h.blockchain,
+                        staleAfter: time.Duration(h.config.Consensus.StaleAfter) * time.Second,
+                },
+                &ShardingHealthChecker{shardManager: h.shardManager},
+                &P2PHealthChecker{network: h.network, minPeers: h.config.Network.MinPeers},
+                &StorageHealthChecker{db: db},
+        }
+}
+
+// CheckHealth runs every subsystem check and aggregates the results
+func (h *Handlers) CheckHealth() *HealthReport {
+        report := &HealthReport{
+                Status:     "healthy",
+                NodeID:     h.config.Node.ID,
+                Components: make([]HealthStatus, 0, 4),
+                Timestamp:  time.Now().UTC(),
+        }
+
+        for _, checker := range h.healthCheckers() {
+                status := checker.Check()
+                report.Components = append(report.Components, status)
+                if !status.Healthy {
+                        report.Unhealthy = append(report.Unhealthy, status.Component)
+                }
+        }
+
+        if len(report.Unhealthy) > 0 {
+                report.Status = "unhealthy"
+        }
+        return report
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+)
+
+func staleAfterOneSecond(cfg *config.Config) {
+	cfg.Consensus.StaleAfter = 1
+}
+
+func TestHealthReportsStoppedConsensus(t *testing.T) {
+	router := newTestRouter(newTestHandlers(t, testConfig(t, staleAfterOneSecond)))
+
+	rec := serve(router, http.MethodGet, "/health", "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	var report HealthReport
+	decode(t, rec, &report)
+	if len(report.Unhealthy) != 1 || report.Unhealthy[0] != "consensus" {
+		t.Fatalf("unhealthy = %v, want [consensus]", report.Unhealthy)
+	}
+}
+
+func TestHealthReportsStalledConsensus(t *testing.T) {
+	sender := newTestAccount(t)
+	cfg := testConfig(t, staleAfterOneSecond)
+	withGenesisAlloc(t, cfg, 1000, sender)
+	handlers := newTestHandlers(t, cfg)
+	router := newTestRouter(handlers)
+
+	// Without validators the running consensus never decides
+	handlers.blockchain.StartConsensus()
+	t.Cleanup(func() { handlers.blockchain.StopConsensus(context.Background()) })
+	if rec := serve(router, http.MethodGet, "/health", ""); rec.Code != http.StatusOK {
+		t.Fatalf("idle running node: status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	if err := handlers.blockchain.SubmitTransaction(signedTransfer(t, sender, newTestAccount(t).address, 10, 10, 1)); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+
+	rec := serve(router, http.MethodGet, "/health", "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("stalled node: status = %d, want 503", rec.Code)
+	}
+	var report HealthReport
+	decode(t, rec, &report)
+	if len(report.Unhealthy) != 1 || report.Unhealthy[0] != "consensus" {
+		t.Fatalf("unhealthy = %v, want [consensus]", report.Unhealthy)
+	}
+	for _, component := range report.Components {
+		if component.Component == "consensus" && component.Message == "ok" {
+			t.Fatal("consensus component gives no reason")
+		}
+	}
+}
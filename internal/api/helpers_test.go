@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/blockchain"
+	"lscc-blockchain/internal/sharding"
+	"lscc-blockchain/internal/storage"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func discardLogger() *utils.Logger {
+	logger := utils.NewLogger()
+	logger.Logger.SetOutput(io.Discard)
+	return logger
+}
+
+// testConfig returns the repository configuration with the built-in
+// genesis, adjusted by configure
+func testConfig(t *testing.T, configure func(cfg *config.Config)) *config.Config {
+	t.Helper()
+	cfg, err := config.LoadConfigFromPath("../../config/config.yaml")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Genesis.Path = ""
+	cfg.Network.MinPeers = 0
+	if configure != nil {
+		configure(cfg)
+	}
+	return cfg
+}
+
+// newTestHandlers returns handlers over a blockchain on an in-memory
+// database and a started shard manager, without a P2P network
+func newTestHandlers(t *testing.T, cfg *config.Config) *Handlers {
+	t.Helper()
+	logger := discardLogger()
+	bc, err := blockchain.NewBlockchain(cfg, storage.NewMemoryDB(), logger)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	sm := sharding.NewShardManager(cfg, bc, logger)
+	if err := sm.Initialize(); err != nil {
+		t.Fatalf("failed to initialize shard manager: %v", err)
+	}
+	if err := sm.Start(); err != nil {
+		t.Fatalf("failed to start shard manager: %v", err)
+	}
+	t.Cleanup(func() { sm.Stop() })
+	return NewHandlers(bc, sm, nil, nil, logger, cfg)
+}
+
+// newTestRouter returns the node's full router over handlers, without a
+// comparator or P2P network
+func newTestRouter(handlers *Handlers) *gin.Engine {
+	router := gin.New()
+	SetupRoutes(router, handlers, nil, nil)
+	return router
+}
+
+// serve sends a request with an optional JSON body through router
+func serve(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode unmarshals a JSON response body into v
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("failed to decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+// testAccount is a key pair able to sign transactions
+type testAccount struct {
+	key     *secp256k1.PrivateKey
+	address string
+}
+
+func newTestAccount(t *testing.T) testAccount {
+	t.Helper()
+	key, err := utils.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testAccount{key: key, address: utils.SigningKeyToAddress(key.PubKey())}
+}
+
+// withGenesisAlloc points cfg at a genesis file crediting each account with
+// balance, without validators
+func withGenesisAlloc(t *testing.T, cfg *config.Config, balance int64, accounts ...testAccount) {
+	t.Helper()
+	alloc := make(map[string]int64, len(accounts))
+	for _, account := range accounts {
+		alloc[account.address] = balance
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"chain_id":   cfg.Network.ChainID,
+		"timestamp":  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		"alloc":      alloc,
+		"validators": []interface{}{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Genesis.Path = path
+}
+
+// signedTransfer returns a transfer from one account to another with the
+// given nonce, signed by the sender
+func signedTransfer(t *testing.T, from testAccount, to string, amount, fee, nonce int64) *types.Transaction {
+	t.Helper()
+	tx := &types.Transaction{
+		From:      from.address,
+		To:        to,
+		Amount:    amount,
+		Fee:       fee,
+		Nonce:     nonce,
+		Timestamp: time.Now().UTC(),
+		Type:      "regular",
+		ShardID:   utils.GenerateShardKey(from.address, 4),
+	}
+	tx.ID = tx.Hash()
+	if err := utils.SignTransaction(tx, from.key); err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
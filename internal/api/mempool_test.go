@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"lscc-blockchain/config"
+)
+
+func TestMempoolStatsListsSenders(t *testing.T) {
+	busy, quiet := newTestAccount(t), newTestAccount(t)
+	cfg := testConfig(t, func(cfg *config.Config) {
+		cfg.Mempool.MaxPerSender = 2
+	})
+	withGenesisAlloc(t, cfg, 1000, busy, quiet)
+	handlers := newTestHandlers(t, cfg)
+	router := newTestRouter(handlers)
+
+	recipient := newTestAccount(t).address
+	for nonce := int64(1); nonce <= 2; nonce++ {
+		if err := handlers.blockchain.SubmitTransaction(signedTransfer(t, busy, recipient, 10, 10, nonce)); err != nil {
+			t.Fatalf("failed to submit: %v", err)
+		}
+	}
+	if err := handlers.blockchain.SubmitTransaction(signedTransfer(t, quiet, recipient, 10, 10, 1)); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+
+	rec := serve(router, http.MethodGet, "/api/v1/mempool/stats?limit=1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var stats struct {
+		PendingCount int `json:"pending_count"`
+		MaxPerSender int `json:"max_per_sender"`
+		SenderCount  int `json:"sender_count"`
+		Senders      []struct {
+			Address string `json:"address"`
+			Pending int    `json:"pending"`
+			Value   int64  `json:"value"`
+		} `json:"senders"`
+	}
+	decode(t, rec, &stats)
+	if stats.PendingCount != 3 || stats.MaxPerSender != 2 || stats.SenderCount != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if len(stats.Senders) != 1 || stats.Senders[0].Address != busy.address || stats.Senders[0].Pending != 2 || stats.Senders[0].Value != 40 {
+		t.Fatalf("expected only the busiest sender listed, got %+v", stats.Senders)
+	}
+
+	for _, limit := range []string{"0", "-1", "many"} {
+		if rec := serve(router, http.MethodGet, "/api/v1/mempool/stats?limit="+limit, ""); rec.Code != http.StatusBadRequest {
+			t.Fatalf("limit %s: expected 400, got %d", limit, rec.Code)
+		}
+	}
+}
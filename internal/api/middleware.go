@@ -2,21 +2,132 @@ package api
 
 import (
         "fmt"
+        "lscc-blockchain/config"
+        "lscc-blockchain/internal/utils"
+        "net/http"
+        "net/url"
+        "strconv"
+        "strings"
         "time"
 
         "github.com/gin-gonic/gin"
 )
 
-// CORSMiddleware handles CORS headers
-func CORSMiddleware() gin.HandlerFunc {
+// RequestIDHeader carries a request's correlation ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// TraceMiddleware gives every request a correlation ID: the client's
+// X-Request-ID when it is a valid ID, a fresh one otherwise. The ID is
+// stored in the request context for handlers to pass on, set on the gin
+// context under utils.TraceField, and echoed in the response header.
+func TraceMiddleware() gin.HandlerFunc {
+        return gin.HandlerFunc(func(c *gin.Context) {
+                traceID := c.GetHeader(RequestIDHeader)
+                if !utils.ValidTraceID(traceID) {
+                        traceID = utils.NewTraceID()
+                }
+
+                c.Set(utils.TraceField, traceID)
+                c.Request = c.Request.WithContext(utils.ContextWithTraceID(c.Request.Context(), traceID))
+                c.Header(RequestIDHeader, traceID)
+
+                c.Next()
+        })
+}
+
+// corsPolicy decides which cross-origin requests the API answers
+type corsPolicy struct {
+        anyOrigin   bool
+        origins     map[string]bool
+        methods     string
+        allowed     map[string]bool // methods, for checking preflights
+        headers     string
+        credentials bool
+        maxAge      string // Access-Control-Max-Age value, "" to omit it
+}
+
+func newCORSPolicy(server config.ServerConfig) *corsPolicy {
+        policy := &corsPolicy{
+                origins:     make(map[string]bool),
+                methods:     strings.Join(server.CORS.AllowedMethods, ", "),
+                headers:     strings.Join(server.CORS.AllowedHeaders, ", "),
+                allowed:     make(map[string]bool),
+                credentials: server.CORS.AllowCredentials,
+        }
+        if server.CORS.MaxAge > 0 {
+                policy.maxAge = strconv.Itoa(server.CORS.MaxAge)
+        }
+        for _, method := range server.CORS.AllowedMethods {
+                policy.allowed[method] = true
+        }
+        for _, origin := range server.CORS.AllowedOrigins {
+                if origin == "*" {
+                        policy.anyOrigin = true
+                        continue
+                }
+                policy.origins[strings.TrimSuffix(strings.ToLower(origin), "/")] = true
+        }
+        return policy
+}
+
+// allows reports whether origin may make cross-origin requests
+func (p *corsPolicy) allows(origin string) bool {
+        return p.anyOrigin || p.origins[strings.ToLower(origin)]
+}
+
+// sameOrigin reports whether origin names the host the request was sent to,
+// as browsers also send Origin on some same-origin requests
+func sameOrigin(origin string, r *http.Request) bool {
+        parsed, err := url.Parse(origin)
+        return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
+
+// CORSMiddleware applies the cross-origin policy in server.CORS. Requests
+// without an Origin header, or from the API's own origin, pass untouched.
+// Requests from an allowed origin get CORS headers naming that origin, and
+// preflights for an allowed method are answered with 204 and, when
+// server.cors.max_age is set, an Access-Control-Max-Age. Requests and
+// preflights from any other origin, and preflights for other methods, are
+// rejected with 403 and no CORS headers.
+func CORSMiddleware(server config.ServerConfig) gin.HandlerFunc {
+        policy := newCORSPolicy(server)
+
         return gin.HandlerFunc(func(c *gin.Context) {
-                c.Header("Access-Control-Allow-Origin", "*")
-                c.Header("Access-Control-Allow-Credentials", "true")
-                c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-                c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+                origin := c.GetHeader("Origin")
+                if origin == "" || sameOrigin(origin, c.Request) {
+                        c.Next()
+                        return
+                }
+
+                if !policy.allows(origin) {
+                        c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+                                "error":  "cross-origin request not allowed",
+                                "origin": origin,
+                        })
+                        return
+                }
+
+                c.Header("Vary", "Origin")
+                c.Header("Access-Control-Allow-Origin", origin)
+                if policy.credentials {
+                        c.Header("Access-Control-Allow-Credentials", "true")
+                }
+                c.Header("Access-Control-Expose-Headers", RequestIDHeader)
 
-                if c.Request.Method == "OPTIONS" {
-                        c.AbortWithStatus(204)
+                if method := c.GetHeader("Access-Control-Request-Method"); c.Request.Method == http.MethodOptions && method != "" {
+                        if !policy.allowed[method] {
+                                c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+                                        "error":  "cross-origin method not allowed",
+                                        "method": method,
+                                })
+                                return
+                        }
+                        c.Header("Access-Control-Allow-Methods", policy.methods)
+                        c.Header("Access-Control-Allow-Headers", policy.headers)
+                        if policy.maxAge != "" {
+                                c.Header("Access-Control-Max-Age", policy.maxAge)
+                        }
+                        c.AbortWithStatus(http.StatusNoContent)
                         return
                 }
 
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// traceRequest sends a GET with an optional X-Request-ID through a router
+// answering with the trace ID its handler sees
+func traceRequest(requestID string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(TraceMiddleware())
+	router.GET("/trace", func(c *gin.Context) {
+		c.String(http.StatusOK, utils.TraceIDFromContext(c.Request.Context()))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/trace", nil)
+	if requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTraceMiddlewareKeepsClientRequestID(t *testing.T) {
+	rec := traceRequest("client-req.42:a_b")
+	if got := rec.Header().Get(RequestIDHeader); got != "client-req.42:a_b" {
+		t.Fatalf("echoed request ID = %q, want the client's", got)
+	}
+	if rec.Body.String() != "client-req.42:a_b" {
+		t.Fatalf("handler saw trace ID %q, want the client's", rec.Body.String())
+	}
+}
+
+func TestTraceMiddlewareReplacesMissingOrInvalidRequestID(t *testing.T) {
+	for name, requestID := range map[string]string{
+		"missing": "",
+		"invalid": "bad id\twith spaces",
+	} {
+		rec := traceRequest(requestID)
+		traceID := rec.Header().Get(RequestIDHeader)
+		if !utils.ValidTraceID(traceID) || traceID == requestID {
+			t.Fatalf("%s: expected a fresh trace ID, got %q", name, traceID)
+		}
+		if rec.Body.String() != traceID {
+			t.Fatalf("%s: handler saw %q, response carried %q", name, rec.Body.String(), traceID)
+		}
+	}
+
+	if first, second := traceRequest("").Header().Get(RequestIDHeader), traceRequest("").Header().Get(RequestIDHeader); first == second {
+		t.Fatalf("two requests shared trace ID %q", first)
+	}
+}
+
+func TestRequestIDFollowsSubmittedTransactions(t *testing.T) {
+	handlers := newTestHandlers(t, testConfig(t, nil))
+	handlers.logger.EnableTraceRecording(10, 100)
+	router := gin.New()
+	router.Use(TraceMiddleware())
+	SetupRoutes(router, handlers, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transactions/generate/2", nil)
+	req.Header.Set(RequestIDHeader, "req-trace-1")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(router, http.MethodGet, "/api/v1/trace/req-trace-1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Trace utils.Trace `json:"trace"`
+	}
+	decode(t, rec, &body)
+
+	// The API's own line and the blockchain's submission of each
+	// generated transaction are all recorded under the client's ID
+	actions := make(map[string]int)
+	for _, event := range body.Trace.Events {
+		if event.TraceID != "req-trace-1" {
+			t.Fatalf("event %+v recorded under another trace", event)
+		}
+		actions[event.Action]++
+	}
+	if actions["generate_bulk"] != 1 || actions["submit"] != 2 {
+		t.Fatalf("expected the request and both submissions traced, got %v", actions)
+	}
+
+	if rec := serve(router, http.MethodGet, "/api/v1/trace/unknown-trace", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown trace: expected 404, got %d", rec.Code)
+	}
+}
+
+// corsRequest sends method from origin through a router applying the CORS
+// policy in cors. A non-empty preflight is sent as the
+// Access-Control-Request-Method.
+func corsRequest(cors config.CORSConfig, method, origin, preflight string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(CORSMiddleware(config.ServerConfig{CORS: cors}))
+	router.Any("/api", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest(method, "http://node.example/api", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if preflight != "" {
+		req.Header.Set("Access-Control-Request-Method", preflight)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func testCORS() config.CORSConfig {
+	return config.CORSConfig{
+		AllowedOrigins: []string{"https://wallet.example"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", RequestIDHeader},
+		MaxAge:         600,
+	}
+}
+
+func TestCORSAllowedOriginGetsHeaders(t *testing.T) {
+	rec := corsRequest(testCORS(), http.MethodGet, "https://Wallet.example", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://Wallet.example" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want the request's origin", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Origin" {
+		t.Fatalf("Vary = %q, want Origin", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Fatalf("credentials allowed without allow_credentials: %q", got)
+	}
+}
+
+func TestCORSDisallowedOriginRejected(t *testing.T) {
+	for name, cors := range map[string]config.CORSConfig{
+		"unlisted origin": testCORS(),
+		"default deny":    {AllowedMethods: []string{"GET"}},
+	} {
+		for _, preflight := range []string{"", "GET"} {
+			method := http.MethodGet
+			if preflight != "" {
+				method = http.MethodOptions
+			}
+			rec := corsRequest(cors, method, "https://evil.example", preflight)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("%s, %s: expected 403, got %d", name, method, rec.Code)
+			}
+			for header := range rec.Header() {
+				if strings.HasPrefix(header, "Access-Control-") {
+					t.Fatalf("%s, %s: rejected origin got %s", name, method, header)
+				}
+			}
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	rec := corsRequest(testCORS(), http.MethodOptions, "https://wallet.example", "POST")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":  "https://wallet.example",
+		"Access-Control-Allow-Methods": "GET, POST",
+		"Access-Control-Allow-Headers": "Content-Type, " + RequestIDHeader,
+		"Access-Control-Max-Age":       "600",
+	}
+	for header, value := range want {
+		if got := rec.Header().Get(header); got != value {
+			t.Fatalf("%s = %q, want %q", header, got, value)
+		}
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("preflight reached the handler: %q", rec.Body.String())
+	}
+
+	// Methods outside the allow-list are refused
+	if rec := corsRequest(testCORS(), http.MethodOptions, "https://wallet.example", "DELETE"); rec.Code != http.StatusForbidden {
+		t.Fatalf("disallowed method: expected 403, got %d", rec.Code)
+	}
+
+	// Without max_age the header is left out
+	cors := testCORS()
+	cors.MaxAge = 0
+	if rec := corsRequest(cors, http.MethodOptions, "https://wallet.example", "GET"); rec.Header().Get("Access-Control-Max-Age") != "" {
+		t.Fatal("Access-Control-Max-Age sent with max_age unset")
+	}
+}
+
+func TestCORSWildcardIsOptIn(t *testing.T) {
+	cors := testCORS()
+	cors.AllowedOrigins = []string{"*"}
+	rec := corsRequest(cors, http.MethodGet, "https://anywhere.example", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://anywhere.example" {
+		t.Fatalf("wildcard: expected any origin admitted, got %d %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// Same-origin and non-browser requests are not cross-origin at all
+	for _, origin := range []string{"", "http://node.example"} {
+		rec := corsRequest(testCORS(), http.MethodGet, origin, "")
+		if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Fatalf("origin %q: expected the request passed untouched, got %d", origin, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/utils"
+)
+
+func TestReceiptReportsExpiredTransaction(t *testing.T) {
+	sender := newTestAccount(t)
+	cfg := testConfig(t, func(cfg *config.Config) {
+		cfg.Mempool.TxTTL = 60
+	})
+	withGenesisAlloc(t, cfg, 1000, sender)
+	handlers := newTestHandlers(t, cfg)
+	router := newTestRouter(handlers)
+
+	tx := signedTransfer(t, sender, newTestAccount(t).address, 10, 10, 1)
+	tx.Timestamp = time.Now().Add(-2 * time.Minute).UTC()
+	tx.ID = tx.Hash()
+	if err := utils.SignTransaction(tx, sender.key); err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if err := handlers.blockchain.SubmitTransaction(tx); err == nil {
+		t.Fatal("expected a transaction past its TTL refused")
+	}
+
+	rec := serve(router, http.MethodGet, "/api/v1/transactions/"+tx.ID+"/receipt", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Receipt struct {
+			TxID      string     `json:"tx_id"`
+			Status    string     `json:"status"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		} `json:"receipt"`
+	}
+	decode(t, rec, &body)
+	if body.Receipt.TxID != tx.ID || body.Receipt.Status != "expired" || body.Receipt.ExpiresAt == nil {
+		t.Fatalf("expected an expired receipt with its expiry time, got %+v", body.Receipt)
+	}
+
+	if rec := serve(router, http.MethodGet, "/api/v1/transactions/unknown/receipt", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown transaction: expected 404, got %d", rec.Code)
+	}
+}
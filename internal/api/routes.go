@@ -12,6 +12,9 @@ import (
 
 // SetupRoutes sets up all API routes
 func SetupRoutes(router *gin.Engine, handlers *Handlers, consensusComparator *comparator.ConsensusComparator, p2pNetwork interface{}) {
+        router.Use(GzipMiddleware(handlers.config.Server.GzipMinSize))
+        router.Use(MaxBodyBytes(handlers.config.Server.MaxRequestBytes))
+
         // Root API documentation
         router.GET("/", handlers.APIDocumentation)
         router.HEAD("/", handlers.APIDocumentation)
@@ -29,6 +32,9 @@ func SetupRoutes(router *gin.Engine, handlers *Handlers, consensusComparator *co
 
 // SetupRoutesWithoutHealth sets up all API routes except the health endpoint
 func SetupRoutesWithoutHealth(router *gin.Engine, handlers *Handlers, consensusComparator *comparator.ConsensusComparator, p2pNetwork interface{}) {
+        router.Use(GzipMiddleware(handlers.config.Server.GzipMinSize))
+        router.Use(MaxBodyBytes(handlers.config.Server.MaxRequestBytes))
+
         // Root API documentation
         router.GET("/", handlers.APIDocumentation)
         router.HEAD("/", handlers.APIDocumentation)
@@ -44,6 +50,12 @@ func SetupRoutesWithoutHealth(router *gin.Engine, handlers *Handlers, consensusC
 // setupCommonRoutes sets up all common API routes
 func setupCommonRoutes(router *gin.Engine, handlers *Handlers, consensusComparator *comparator.ConsensusComparator, p2pNetwork interface{}) {
 
+        // SLO status, alongside the Prometheus /metrics endpoint
+        router.GET("/metrics/slo", handlers.GetSLOStatus)
+
+        // Streaming block export for external indexers
+        router.GET("/export/blocks", handlers.ExportBlocks)
+
         // API v1 routes
         v1 := router.Group("/api/v1")
         {
@@ -53,6 +65,8 @@ func setupCommonRoutes(router *gin.Engine, handlers *Handlers, consensusComparat
                         blockchain.GET("/info", handlers.GetBlockchainInfo)
                         blockchain.GET("/blocks", handlers.GetBlocks)
                         blockchain.GET("/blocks/:hash", handlers.GetBlock)
+                        blockchain.GET("/snapshot", handlers.ExportSnapshot)
+                        blockchain.GET("/supply", handlers.GetSupply)
                 }
 
                 // Transaction routes
@@ -60,12 +74,26 @@ func setupCommonRoutes(router *gin.Engine, handlers *Handlers, consensusComparat
                 {
                         transactions.POST("/", handlers.SubmitTransaction)
                         transactions.GET("/:hash", handlers.GetTransaction)
+                        transactions.GET("/:hash/receipt", handlers.GetTransactionReceipt)
+                        transactions.GET("/:hash/status", handlers.GetTxStatus)
                         transactions.GET("/", handlers.GetTransactions)
                         transactions.GET("/status", handlers.GetTransactionStatus)
                         transactions.POST("/generate/:count", handlers.GenerateTransactions)
                         transactions.GET("/stats", handlers.GetTransactionStats)
                 }
 
+                // Fee routes
+                fees := v1.Group("/fees")
+                {
+                        fees.GET("/estimate", handlers.GetFeeEstimate)
+                }
+
+                // Mempool routes
+                mempool := v1.Group("/mempool")
+                {
+                        mempool.GET("/stats", handlers.GetMempoolStats)
+                }
+
                 // Shard routes
                 shards := v1.Group("/shards")
                 {
@@ -77,8 +105,16 @@ func setupCommonRoutes(router *gin.Engine, handlers *Handlers, consensusComparat
                 // Consensus routes
                 consensus := v1.Group("/consensus")
                 {
+                        consensus.GET("", handlers.GetActiveConsensus)
+                        consensus.POST("", handlers.SwitchConsensus)
                         consensus.GET("/status", handlers.GetConsensusStatus)
                         consensus.GET("/metrics", handlers.GetConsensusMetrics)
+                        consensus.GET("/events", handlers.GetConsensusEvents)
+                        consensus.POST("/validators/:address/heartbeat", handlers.RecordValidatorHeartbeat)
+                        consensus.GET("/ppbft/messagelog", handlers.GetPPBFTMessageLog)
+                        consensus.DELETE("/ppbft/messagelog", handlers.PrunePPBFTMessageLog)
+                        consensus.POST("/checkpoint", handlers.ForceCheckpoint)
+                        consensus.POST("/debug/step", handlers.DebugConsensusStep)
                 }
 
                 // Network routes  
@@ -91,6 +127,20 @@ func setupCommonRoutes(router *gin.Engine, handlers *Handlers, consensusComparat
                         network.GET("/algorithm-peers", handlers.GetAlgorithmPeers)
                 }
 
+                // Request traces
+                v1.GET("/trace/:id", handlers.GetTrace)
+
+                // Validator epochs and rewards
+                v1.GET("/epoch", handlers.GetEpoch)
+                v1.GET("/validators", handlers.GetValidators)
+
+                // Admin routes
+                admin := v1.Group("/admin")
+                {
+                        admin.GET("/routes", handlers.GetRoutes)
+                        admin.DELETE("/routes", handlers.PruneRoutes)
+                }
+
                 // Wallet routes
                 wallet := v1.Group("/wallet")
                 {
@@ -101,11 +151,10 @@ func setupCommonRoutes(router *gin.Engine, handlers *Handlers, consensusComparat
                 }
         }
 
-        // Consensus Comparator routes (if available)
-        if consensusComparator != nil {
-                comparatorHandlers := NewComparatorHandlers(consensusComparator, handlers.logger)
-                comparatorHandlers.RegisterRoutes(v1)
-        }
+        // Consensus Comparator routes. They are registered even when the
+        // comparator failed to start so clients get a 503 rather than a 404.
+        comparatorHandlers := NewComparatorHandlers(consensusComparator, handlers.logger)
+        comparatorHandlers.RegisterRoutes(v1)
 
         // Academic Testing Framework routes
         testingGroup := v1.Group("/testing")
@@ -152,7 +201,32 @@ func (h *Handlers) SubmitTransaction(c *gin.Context) {
 }
 
 func (h *Handlers) GetTransaction(c *gin.Context) {
-        c.JSON(200, gin.H{"message": "get transaction"})
+        txID := c.Param("hash")
+
+        h.logger.Info("Getting transaction", map[string]interface{}{
+                "component": "transaction",
+                "action":    "get_transaction",
+                "tx_id":     txID,
+                "timestamp": time.Now(),
+        })
+
+        tx, err := h.blockchain.GetTransaction(txID)
+        if err != nil {
+                c.JSON(404, gin.H{"error": "Transaction not found", "tx_id": txID})
+                return
+        }
+
+        response := gin.H{
+                "transaction": tx,
+                "status":      "pending",
+        }
+        if block, err := h.blockchain.GetTransactionBlock(txID); err == nil {
+                response["status"] = "confirmed"
+                response["block_hash"] = block.Hash
+                response["block_index"] = block.Index
+        }
+
+        c.JSON(200, response)
 }
 
 func (h *Handlers) GetTransactions(c *gin.Context) {
@@ -538,6 +612,7 @@ func (h *Handlers) GetPeersWithData(c *gin.Context) {
         response := gin.H{
                 "local_node": gin.H{
                         "id": nodeInfo.ID,
+                        "chain_id": nodeInfo.ChainID,
                         "role": nodeInfo.Role,
                         "consensus_algorithm": nodeInfo.ConsensusAlgorithm,
                         "external_ip": nodeInfo.ExternalIP,
@@ -648,7 +723,40 @@ func (h *Handlers) GetWalletBalance(c *gin.Context) {
 }
 
 func (h *Handlers) GetWalletTransactions(c *gin.Context) {
-        c.JSON(200, gin.H{"message": "get wallet transactions"})
+        address := c.Param("address")
+        limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+        if err != nil || limit < 1 {
+                c.JSON(400, gin.H{"error": "Invalid limit"})
+                return
+        }
+
+        h.logger.Info("Getting wallet transactions", map[string]interface{}{
+                "component": "transaction",
+                "action":    "get_wallet_transactions",
+                "address":   address,
+                "limit":     limit,
+                "timestamp": time.Now(),
+        })
+
+        transactions, err := h.blockchain.GetTransactionsByAddress(address, limit)
+        if err != nil {
+                h.logger.Error("Failed to get wallet transactions", map[string]interface{}{
+                        "component": "transaction",
+                        "action":    "get_wallet_transactions",
+                        "address":   address,
+                        "error":     err.Error(),
+                        "timestamp": time.Now(),
+                })
+                c.JSON(500, gin.H{"error": "Failed to get wallet transactions"})
+                return
+        }
+
+        c.JSON(200, gin.H{
+                "address":      address,
+                "transactions": transactions,
+                "count":        len(transactions),
+                "limit":        limit,
+        })
 }
 
 // WebSocket handlers removed - UI functionality disabledpackage api
\ No newline at end of file
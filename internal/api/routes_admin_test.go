@@ -0,0 +1,177 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"lscc-blockchain/internal/sharding"
+	"lscc-blockchain/pkg/types"
+)
+
+// routesResponse is the body of GET /api/v1/admin/routes
+type routesResponse struct {
+	Routes []sharding.RouteInfo `json:"routes"`
+	Count  int                  `json:"count"`
+}
+
+// sendBetween sends a message between two shards, creating or refreshing
+// the route the pair uses
+func sendBetween(t *testing.T, h *Handlers, from, to int) {
+	t.Helper()
+	err := h.shardManager.Communicator().SendMessage(&types.CrossShardMessage{
+		ID:        fmt.Sprintf("route-%d-%d-%d", from, to, time.Now().UnixNano()),
+		FromShard: from,
+		ToShard:   to,
+		Type:      "sync",
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("failed to send from shard %d to %d: %v", from, to, err)
+	}
+}
+
+// hasRoute reports whether routes holds one from one shard to another
+func hasRoute(routes []sharding.RouteInfo, from, to int) bool {
+	for _, route := range routes {
+		if route.FromShard == from && route.ToShard == to {
+			return true
+		}
+	}
+	return false
+}
+
+// newRoutingHandlers returns test handlers with cross-shard communication
+// running
+func newRoutingHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	h := newTestHandlers(t, testConfig(t, nil))
+	h.shardManager.StartCrossCommunication()
+	if h.shardManager.Communicator() == nil {
+		t.Fatal("cross-shard communication did not start")
+	}
+	return h
+}
+
+func TestRoutesUnavailableWithoutCommunication(t *testing.T) {
+	router := newTestRouter(newTestHandlers(t, testConfig(t, nil)))
+	if rec := serve(router, http.MethodGet, "/api/v1/admin/routes", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("list: expected 503, got %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodDelete, "/api/v1/admin/routes?older_than=1m", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("prune: expected 503, got %d", rec.Code)
+	}
+}
+
+func TestListRoutes(t *testing.T) {
+	h := newRoutingHandlers(t)
+	router := newTestRouter(h)
+	sendBetween(t, h, 0, 1)
+
+	rec := serve(router, http.MethodGet, "/api/v1/admin/routes", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body routesResponse
+	decode(t, rec, &body)
+	if body.Count != len(body.Routes) || !hasRoute(body.Routes, 0, 1) {
+		t.Fatalf("expected the route from shard 0 to 1 listed, got %+v", body)
+	}
+	for _, route := range body.Routes {
+		if route.FromShard == 0 && route.ToShard == 1 && route.Active && route.Reliability <= 0 {
+			t.Fatalf("expected the active route to report its reliability, got %+v", route)
+		}
+	}
+}
+
+func TestPruneRoutesKeepsActiveRoutes(t *testing.T) {
+	h := newRoutingHandlers(t)
+	router := newTestRouter(h)
+
+	sendBetween(t, h, 0, 1)
+	time.Sleep(100 * time.Millisecond)
+	sendBetween(t, h, 1, 2)
+
+	rec := serve(router, http.MethodDelete, "/api/v1/admin/routes?older_than=50ms", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var pruned struct {
+		Removed   int `json:"removed"`
+		Remaining int `json:"remaining"`
+	}
+	decode(t, rec, &pruned)
+	if pruned.Removed == 0 {
+		t.Fatalf("expected the stale route pruned, got %+v", pruned)
+	}
+
+	var body routesResponse
+	decode(t, serve(router, http.MethodGet, "/api/v1/admin/routes", ""), &body)
+	if hasRoute(body.Routes, 0, 1) {
+		t.Fatal("stale route from shard 0 to 1 survived pruning")
+	}
+	if !hasRoute(body.Routes, 1, 2) {
+		t.Fatal("recently used route from shard 1 to 2 was pruned")
+	}
+	if pruned.Remaining != body.Count {
+		t.Fatalf("prune reported %d remaining, listing has %d", pruned.Remaining, body.Count)
+	}
+
+	// A pruned pair gets a fresh route on its next message
+	sendBetween(t, h, 0, 1)
+	decode(t, serve(router, http.MethodGet, "/api/v1/admin/routes", ""), &body)
+	if !hasRoute(body.Routes, 0, 1) {
+		t.Fatal("expected a new route from shard 0 to 1 after sending again")
+	}
+}
+
+func TestPruneRoutesRejectsBadDuration(t *testing.T) {
+	router := newTestRouter(newRoutingHandlers(t))
+	for _, query := range []string{"", "?older_than=soon", "?older_than=-5m", "?older_than=0s"} {
+		rec := serve(router, http.MethodDelete, "/api/v1/admin/routes"+query, "")
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+// TestPruneRoutesWhileSending prunes every route while messages are sent.
+// Run under -race.
+func TestPruneRoutesWhileSending(t *testing.T) {
+	h := newRoutingHandlers(t)
+	router := newTestRouter(h)
+
+	sent := make(chan error, 1)
+	go func() {
+		for i := 0; i < 200; i++ {
+			err := h.shardManager.Communicator().SendMessage(&types.CrossShardMessage{
+				ID:        fmt.Sprintf("flowing-%d", i),
+				FromShard: i % 3,
+				ToShard:   (i + 1) % 3,
+				Type:      "sync",
+				Timestamp: time.Now().UTC(),
+			})
+			if err != nil {
+				sent <- err
+				return
+			}
+		}
+		sent <- nil
+	}()
+	for i := 0; i < 50; i++ {
+		if rec := serve(router, http.MethodDelete, "/api/v1/admin/routes?older_than=1ns", ""); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	if err := <-sent; err != nil {
+		t.Fatalf("send failed while pruning: %v", err)
+	}
+
+	sendBetween(t, h, 0, 1)
+	var body routesResponse
+	decode(t, serve(router, http.MethodGet, "/api/v1/admin/routes", ""), &body)
+	if !hasRoute(body.Routes, 0, 1) {
+		t.Fatal("expected a route from shard 0 to 1 after pruning")
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/blockchain"
+)
+
+func TestSupplyReportsSchedule(t *testing.T) {
+	sender := newTestAccount(t)
+	cfg := testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.BlockReward = 100
+		cfg.Consensus.HalvingInterval = 10
+	})
+	withGenesisAlloc(t, cfg, 5000, sender)
+	router := newTestRouter(newTestHandlers(t, cfg))
+
+	rec := serve(router, http.MethodGet, "/api/v1/blockchain/supply", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Supply blockchain.SupplyInfo `json:"supply"`
+	}
+	decode(t, rec, &body)
+	want := blockchain.SupplyInfo{
+		GenesisSupply:     5000,
+		TotalSupply:       5000,
+		BlockReward:       100,
+		InitialReward:     100,
+		HalvingInterval:   10,
+		NextHalvingHeight: 10,
+	}
+	if body.Supply != want {
+		t.Fatalf("supply at genesis = %+v, want %+v", body.Supply, want)
+	}
+}
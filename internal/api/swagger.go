@@ -2,6 +2,7 @@ package api
 
 import (
         "fmt"
+        "lscc-blockchain/internal/consensus"
         "net/http"
 
         "github.com/gin-gonic/gin"
@@ -276,7 +277,7 @@ func generateSwaggerPaths() map[string]interface{} {
                 "get": map[string]interface{}{
                         "tags":        []string{"System"},
                         "summary":     "Health Check",
-                        "description": "Check the health status of the LSCC blockchain node",
+                        "description": "Check consensus liveness, shard activity, connected peers and database reachability",
                         "responses": map[string]interface{}{
                                 "200": map[string]interface{}{
                                         "description": "Node is healthy",
@@ -285,14 +286,35 @@ func generateSwaggerPaths() map[string]interface{} {
                                                         "schema": map[string]interface{}{
                                                                 "type": "object",
                                                                 "properties": map[string]interface{}{
-                                                                        "status":    map[string]interface{}{"type": "string", "example": "healthy"},
-                                                                        "timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
-                                                                        "version":   map[string]interface{}{"type": "string", "example": "1.0.0"},
+                                                                        "status":     map[string]interface{}{"type": "string", "example": "healthy"},
+                                                                        "node_id":    map[string]interface{}{"type": "string"},
+                                                                        "components": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+                                                                        "unhealthy":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "example": []string{"consensus"}},
+                                                                        "timestamp":  map[string]interface{}{"type": "string", "format": "date-time"},
                                                                 },
                                                         },
                                                 },
                                         },
                                 },
+                                "503": map[string]interface{}{
+                                        "description": "One or more components are unhealthy; see the unhealthy list",
+                                },
+                        },
+                },
+        }
+
+        paths["/metrics/slo"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"System"},
+                        "summary":     "SLO Status",
+                        "description": "Current minimum-TPS and maximum-block-interval SLOs. A threshold breached for the whole slo.window is a violation and sets lscc_slo_violation{type} to 1 until it recovers.",
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "SLO status",
+                                },
+                                "503": map[string]interface{}{
+                                        "description": "SLO monitoring is not enabled",
+                                },
                         },
                 },
         }
@@ -318,6 +340,81 @@ func generateSwaggerPaths() map[string]interface{} {
                 },
         }
 
+        paths["/api/v1/blockchain/snapshot"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Blockchain"},
+                        "summary":     "Export Database Snapshot",
+                        "description": "Stream a gzip-compressed backup of the node database",
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Snapshot stream",
+                                        "content": map[string]interface{}{
+                                                "application/gzip": map[string]interface{}{
+                                                        "schema": map[string]interface{}{"type": "string", "format": "binary"},
+                                                },
+                                        },
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/blockchain/supply"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Blockchain"},
+                        "summary":     "Get Coin Supply",
+                        "description": "Report the genesis allocation, the block subsidies issued through the current height, the next block's subsidy and when it next halves",
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Supply at the current height",
+                                },
+                        },
+                },
+        }
+
+        paths["/export/blocks"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Blockchain"},
+                        "summary":     "Export Blocks",
+                        "description": "Stream a range of blocks as newline-delimited JSON, one block per line in index order",
+                        "parameters": []interface{}{
+                                map[string]interface{}{
+                                        "name":        "from",
+                                        "in":          "query",
+                                        "description": "First block index",
+                                        "schema":      map[string]interface{}{"type": "integer", "default": 0},
+                                },
+                                map[string]interface{}{
+                                        "name":        "to",
+                                        "in":          "query",
+                                        "description": "Last block index, inclusive (defaults to the chain height)",
+                                        "schema":      map[string]interface{}{"type": "integer"},
+                                },
+                                map[string]interface{}{
+                                        "name":        "include_txs",
+                                        "in":          "query",
+                                        "description": "Include transactions inline; false lists their IDs instead",
+                                        "schema":      map[string]interface{}{"type": "boolean", "default": true},
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Block stream",
+                                        "content": map[string]interface{}{
+                                                "application/x-ndjson": map[string]interface{}{
+                                                        "schema": map[string]interface{}{"type": "string"},
+                                                },
+                                        },
+                                },
+                                "400": map[string]interface{}{
+                                        "description": "Invalid range or include_txs value",
+                                },
+                                "404": map[string]interface{}{
+                                        "description": "from is beyond the chain height",
+                                },
+                        },
+                },
+        }
+
         paths["/api/v1/blockchain/blocks"] = map[string]interface{}{
                 "get": map[string]interface{}{
                         "tags":        []string{"Blockchain"},
@@ -427,7 +524,136 @@ func generateSwaggerPaths() map[string]interface{} {
                 },
         }
 
+        paths["/api/v1/transactions/{hash}/receipt"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Transactions"},
+                        "summary":     "Get Transaction Receipt",
+                        "description": "Report whether a transaction is pending, confirmed, failed or expired. Transactions older than mempool.tx_ttl are dropped and reported as expired.",
+                        "parameters": []interface{}{
+                                map[string]interface{}{
+                                        "name":        "hash",
+                                        "in":          "path",
+                                        "required":    true,
+                                        "description": "Transaction ID",
+                                        "schema":      map[string]interface{}{"type": "string"},
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Transaction receipt",
+                                },
+                                "404": map[string]interface{}{
+                                        "description": "Transaction not found",
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/transactions/{hash}/status"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Transactions"},
+                        "summary":     "Get Transaction Lifecycle Status",
+                        "description": "Report where a transaction is in its lifecycle: pending in the mempool, included in a main-chain block, finalized once that block is at or below the finalized height, or rejected by the pool, by expiry or by failing to execute. The history lists each transition with its time.",
+                        "parameters": []interface{}{
+                                map[string]interface{}{
+                                        "name":        "hash",
+                                        "in":          "path",
+                                        "required":    true,
+                                        "description": "Transaction ID",
+                                        "schema":      map[string]interface{}{"type": "string"},
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Transaction status and its transitions",
+                                },
+                                "404": map[string]interface{}{
+                                        "description": "Transaction not found",
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/fees/estimate"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Transactions"},
+                        "summary":     "Get Fee Floor",
+                        "description": "Return the minimum fee the mempool currently accepts, the base fee the next block charges under consensus.fee_market, and a recommended fee meeting both. The floor rises above the configured minimum as the pool fills past its congestion target.",
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Current fee floor and mempool occupancy",
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/mempool/stats"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Transactions"},
+                        "summary":     "Get Mempool Stats",
+                        "description": "Return mempool occupancy by count and bytes, the per-sender admission limits, and the senders with the most transactions pending. Submissions past mempool.max_per_sender, mempool.max_sender_value or mempool.max_bytes are refused.",
+                        "parameters": []map[string]interface{}{
+                                {
+                                        "name":        "limit",
+                                        "in":          "query",
+                                        "description": "Maximum number of senders to list",
+                                        "schema":      map[string]interface{}{"type": "integer", "default": 20, "minimum": 1},
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Mempool occupancy and pending transactions per sender",
+                                },
+                                "400": map[string]interface{}{
+                                        "description": "Invalid limit",
+                                },
+                        },
+                },
+        }
+
         // Consensus endpoints
+        paths["/api/v1/consensus"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Consensus"},
+                        "summary":     "Get Active Consensus Algorithm",
+                        "description": "Retrieve the consensus algorithm the node is running and the algorithms it can switch to",
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Active algorithm retrieved successfully",
+                                },
+                        },
+                },
+                "post": map[string]interface{}{
+                        "tags":        []string{"Consensus"},
+                        "summary":     "Switch Consensus Algorithm",
+                        "description": "Reset the active consensus algorithm and install another one without restarting the node",
+                        "requestBody": map[string]interface{}{
+                                "required": true,
+                                "content": map[string]interface{}{
+                                        "application/json": map[string]interface{}{
+                                                "schema": map[string]interface{}{
+                                                        "type": "object",
+                                                        "properties": map[string]interface{}{
+                                                                "algorithm": map[string]interface{}{"type": "string", "enum": consensus.RegisteredAlgorithms()},
+                                                        },
+                                                },
+                                        },
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Consensus algorithm switched",
+                                },
+                                "400": map[string]interface{}{
+                                        "description": "Unknown consensus algorithm",
+                                },
+                                "409": map[string]interface{}{
+                                        "description": "A block is being committed; retry the switch",
+                                },
+                        },
+                },
+        }
+
         paths["/api/v1/consensus/status"] = map[string]interface{}{
                 "get": map[string]interface{}{
                         "tags":        []string{"Consensus"},
@@ -448,6 +674,228 @@ func generateSwaggerPaths() map[string]interface{} {
                 },
         }
 
+        paths["/api/v1/consensus/events"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Consensus"},
+                        "summary":     "Query Consensus Events",
+                        "description": "Return recorded consensus events (votes, completed phases, view changes, checkpoints), newest first. Only the most recent consensus.event_log_size events are kept.",
+                        "parameters": []map[string]interface{}{
+                                {
+                                        "name":        "block",
+                                        "in":          "query",
+                                        "description": "Only events for this block hash",
+                                        "schema":      map[string]interface{}{"type": "string"},
+                                },
+                                {
+                                        "name":        "type",
+                                        "in":          "query",
+                                        "description": "Only events of this type",
+                                        "schema":      map[string]interface{}{"type": "string", "enum": []string{consensus.EventVoteReceived, consensus.EventPhaseCompleted, consensus.EventViewChange, consensus.EventCheckpoint}},
+                                },
+                                {
+                                        "name":        "limit",
+                                        "in":          "query",
+                                        "description": "Maximum number of events to return",
+                                        "schema":      map[string]interface{}{"type": "integer", "default": 100, "minimum": 1},
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Matching events",
+                                },
+                                "400": map[string]interface{}{
+                                        "description": "Invalid limit or unknown event type",
+                                },
+                                "503": map[string]interface{}{
+                                        "description": "The event log is disabled on this node",
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/trace/{id}"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"System"},
+                        "summary":     "Get Request Trace",
+                        "description": "Return the log lines recorded under a correlation ID, oldest first: the API request, the transactions it submitted, the consensus rounds that committed them and any cross-shard messages. Only the most recent logging.trace_buffer traces are kept.",
+                        "parameters": []map[string]interface{}{
+                                {
+                                        "name":        "id",
+                                        "in":          "path",
+                                        "required":    true,
+                                        "description": "Trace ID, as returned in the X-Request-ID response header",
+                                        "schema":      map[string]interface{}{"type": "string"},
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Recorded events",
+                                },
+                                "400": map[string]interface{}{
+                                        "description": "Malformed trace ID",
+                                },
+                                "404": map[string]interface{}{
+                                        "description": "No events recorded for the trace",
+                                },
+                                "503": map[string]interface{}{
+                                        "description": "Trace recording is disabled on this node",
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/epoch"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Consensus"},
+                        "summary":     "Get Validator Epoch",
+                        "description": "Return the current epoch's active validator set and block range, and the epochs before it. The set is recomputed every consensus.epoch_length blocks.",
+                        "parameters": []map[string]interface{}{
+                                {
+                                        "name":        "limit",
+                                        "in":          "query",
+                                        "required":    false,
+                                        "description": "Previous epochs to return, most recent first",
+                                        "schema":      map[string]interface{}{"type": "integer", "default": 10, "minimum": 0},
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Current and previous epochs",
+                                },
+                                "400": map[string]interface{}{
+                                        "description": "Invalid limit",
+                                },
+                                "503": map[string]interface{}{
+                                        "description": "Validator epochs are disabled on this node",
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/validators"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Consensus"},
+                        "summary":     "List Validators",
+                        "description": "Return every validator with its stake, status, current epoch membership, balance and the block rewards it has accrued. Each committed block's subsidy and tips are split by stake between its proposer and the validators whose votes committed it; slashed or jailed validators earn nothing.",
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Validators and their accrued rewards",
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/consensus/validators/{address}/heartbeat"] = map[string]interface{}{
+                "post": map[string]interface{}{
+                        "tags":        []string{"Consensus"},
+                        "summary":     "Record Validator Heartbeat",
+                        "description": "Record that a validator is alive. Validators that neither take part in consensus nor send a heartbeat for consensus.liveness_window seconds are marked inactive and left out of quorum until they do.",
+                        "parameters": []map[string]interface{}{
+                                {
+                                        "name":        "address",
+                                        "in":          "path",
+                                        "required":    true,
+                                        "description": "Validator address",
+                                        "schema":      map[string]interface{}{"type": "string"},
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Heartbeat recorded",
+                                },
+                                "404": map[string]interface{}{
+                                        "description": "No validator has this address",
+                                },
+                                "503": map[string]interface{}{
+                                        "description": "Liveness monitoring is disabled on this node",
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/consensus/ppbft/messagelog"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Consensus"},
+                        "summary":     "Inspect PPBFT Message Log",
+                        "description": "Return the number of logged PPBFT messages, the sequences they cover and the low watermark below which they are pruned",
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Message log summary",
+                                },
+                                "409": map[string]interface{}{
+                                        "description": "The active consensus algorithm is not PPBFT",
+                                },
+                        },
+                },
+                "delete": map[string]interface{}{
+                        "tags":        []string{"Consensus"},
+                        "summary":     "Prune PPBFT Message Log",
+                        "description": "Drop messages for sequences below the low watermark now instead of at the next commit. Messages from the last stable checkpoint onward are kept for view-change proofs.",
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Messages pruned",
+                                },
+                                "409": map[string]interface{}{
+                                        "description": "The active consensus algorithm is not PPBFT",
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/consensus/checkpoint"] = map[string]interface{}{
+                "post": map[string]interface{}{
+                        "tags":        []string{"Consensus"},
+                        "summary":     "Force PPBFT Checkpoint",
+                        "description": "Take a stable checkpoint at the current chain height now rather than at the next consensus.ppbft_checkpoint_interval. The checkpoint still needs 2f+1 validator votes; on success the watermarks advance and older messages are pruned.",
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Checkpoint taken",
+                                },
+                                "409": map[string]interface{}{
+                                        "description": "The active consensus algorithm is not PPBFT, or the current height is already checkpointed",
+                                },
+                                "503": map[string]interface{}{
+                                        "description": "The checkpoint did not reach 2f+1 votes; nothing changed",
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/consensus/debug/step"] = map[string]interface{}{
+                "post": map[string]interface{}{
+                        "tags":        []string{"Consensus"},
+                        "summary":     "Step Through an LSCC Round",
+                        "description": "Dry-run the four LSCC phases on a block with the validators of the next round and return the state after each: per-layer votes and results, per-channel approvals, shard sync results and the commitment score breakdown. Nothing is voted on or committed. Without a block in the body, the block the next round would propose from the pending transactions is used.",
+                        "requestBody": map[string]interface{}{
+                                "required": false,
+                                "content": map[string]interface{}{
+                                        "application/json": map[string]interface{}{
+                                                "schema": map[string]interface{}{
+                                                        "type": "object",
+                                                        "properties": map[string]interface{}{
+                                                                "block": map[string]interface{}{"type": "object", "description": "Block to simulate; hashed first when it has no hash"},
+                                                        },
+                                                },
+                                        },
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "State after each phase",
+                                },
+                                "400": map[string]interface{}{
+                                        "description": "Malformed request body",
+                                },
+                                "409": map[string]interface{}{
+                                        "description": "The active consensus algorithm is not LSCC",
+                                },
+                                "503": map[string]interface{}{
+                                        "description": "No validators to run the round",
+                                },
+                        },
+                },
+        }
+
         // Network endpoints
         paths["/api/v1/network/peers"] = map[string]interface{}{
                 "get": map[string]interface{}{
@@ -483,6 +931,47 @@ func generateSwaggerPaths() map[string]interface{} {
                 },
         }
 
+        paths["/api/v1/admin/routes"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Sharding"},
+                        "summary":     "List Cross-Shard Routes",
+                        "description": "List every known route between shards, including relay alternatives, with latency, reliability, current load, priority and time since last use",
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Routes ordered by shard pair",
+                                },
+                                "503": map[string]interface{}{
+                                        "description": "Cross-shard communication is not running",
+                                },
+                        },
+                },
+                "delete": map[string]interface{}{
+                        "tags":        []string{"Sharding"},
+                        "summary":     "Prune Stale Routes",
+                        "description": "Remove routes that have not carried a message within older_than. A shard pair left without routes gets a new direct route when next used.",
+                        "parameters": []interface{}{
+                                map[string]interface{}{
+                                        "name":        "older_than",
+                                        "in":          "query",
+                                        "required":    true,
+                                        "description": "Go duration, e.g. 10m or 1h",
+                                        "schema":      map[string]interface{}{"type": "string"},
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Stale routes removed",
+                                },
+                                "400": map[string]interface{}{
+                                        "description": "older_than is missing or not a positive duration",
+                                },
+                                "503": map[string]interface{}{
+                                        "description": "Cross-shard communication is not running",
+                                },
+                        },
+                },
+        }
+
         // Consensus Comparator endpoints
         paths["/api/v1/comparator/start"] = map[string]interface{}{
                 "post": map[string]interface{}{
@@ -496,7 +985,7 @@ func generateSwaggerPaths() map[string]interface{} {
                                                 "schema": map[string]interface{}{
                                                         "type": "object",
                                                         "properties": map[string]interface{}{
-                                                                "algorithms":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "enum": []string{"pow", "pos", "pbft", "ppbft", "lscc"}}},
+                                                                "algorithms":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "enum": consensus.RegisteredAlgorithms()}},
                                                                 "duration":       map[string]interface{}{"type": "integer", "description": "Test duration in seconds"},
                                                                 "transaction_rate": map[string]interface{}{"type": "integer", "description": "Transactions per second to generate"},
                                                         },
@@ -512,6 +1001,73 @@ func generateSwaggerPaths() map[string]interface{} {
                 },
         }
 
+        paths["/api/v1/comparator/baselines/{test_id}"] = map[string]interface{}{
+                "post": map[string]interface{}{
+                        "tags":        []string{"Research & Testing"},
+                        "summary":     "Save Comparison Baseline",
+                        "description": "Save the latest run of a test as its baseline, replacing any saved before. Baselines are written as JSON under comparator.baseline_dir and survive restarts.",
+                        "parameters": []map[string]interface{}{
+                                {
+                                        "name":        "test_id",
+                                        "in":          "path",
+                                        "required":    true,
+                                        "description": "Name of the test whose latest run becomes the baseline",
+                                        "schema":      map[string]interface{}{"type": "string"},
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Baseline saved",
+                                },
+                                "404": map[string]interface{}{
+                                        "description": "No run of the test in the history",
+                                },
+                        },
+                },
+        }
+
+        paths["/api/v1/comparator/regressions"] = map[string]interface{}{
+                "get": map[string]interface{}{
+                        "tags":        []string{"Research & Testing"},
+                        "summary":     "Detect Comparison Regressions",
+                        "description": "Compare a run's per-algorithm throughput, average latency and finality time with a saved baseline, flagging each that is worse by more than the tolerance as a fraction of its baseline value.",
+                        "parameters": []map[string]interface{}{
+                                {
+                                        "name":        "baseline",
+                                        "in":          "query",
+                                        "required":    true,
+                                        "description": "Name of the baseline to compare with",
+                                        "schema":      map[string]interface{}{"type": "string"},
+                                },
+                                {
+                                        "name":        "test_id",
+                                        "in":          "query",
+                                        "required":    false,
+                                        "description": "Test whose latest run is compared; the latest run of any test when omitted",
+                                        "schema":      map[string]interface{}{"type": "string"},
+                                },
+                                {
+                                        "name":        "tolerance",
+                                        "in":          "query",
+                                        "required":    false,
+                                        "description": "Allowed degradation as a fraction; comparator.regression_tolerance when omitted",
+                                        "schema":      map[string]interface{}{"type": "number", "minimum": 0},
+                                },
+                        },
+                        "responses": map[string]interface{}{
+                                "200": map[string]interface{}{
+                                        "description": "Regression report",
+                                },
+                                "400": map[string]interface{}{
+                                        "description": "Missing baseline or invalid tolerance",
+                                },
+                                "404": map[string]interface{}{
+                                        "description": "Baseline or test run not found",
+                                },
+                        },
+                },
+        }
+
         // Academic Testing endpoints
         paths["/api/v1/testing/benchmark/comprehensive"] = map[string]interface{}{
                 "post": map[string]interface{}{
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/blockchain"
+	"lscc-blockchain/pkg/types"
+)
+
+func TestTxStatusEndpointFollowsTransaction(t *testing.T) {
+	sender := newTestAccount(t)
+	cfg := testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.FinalityDepth = 1
+	})
+	withGenesisAlloc(t, cfg, 1000, sender)
+	handlers := newTestHandlers(t, cfg)
+	router := newTestRouter(handlers)
+	bc := handlers.blockchain
+
+	statusOf := func(txID string) blockchain.TxStatus {
+		t.Helper()
+		rec := serve(router, http.MethodGet, "/api/v1/transactions/"+txID+"/status", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Status blockchain.TxStatus `json:"status"`
+		}
+		decode(t, rec, &body)
+		return body.Status
+	}
+	addBlock := func(txs ...*types.Transaction) *types.Block {
+		t.Helper()
+		bm := blockchain.NewBlockManager(discardLogger(), 0, 0, 0, cfg.Network.ChainID)
+		block, err := bm.CreateBlock(bc.GetLatestBlock(), txs, "proposer", 0)
+		if err != nil {
+			t.Fatalf("failed to create block: %v", err)
+		}
+		if err := bc.AddBlock(block); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+		return block
+	}
+
+	tx := signedTransfer(t, sender, newTestAccount(t).address, 10, 10, 1)
+	if err := bc.SubmitTransaction(tx); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if status := statusOf(tx.ID); status.TxID != tx.ID || status.Status != blockchain.TxPending {
+		t.Fatalf("after submitting: %+v", status)
+	}
+
+	block := addBlock(tx)
+	if status := statusOf(tx.ID); status.Status != blockchain.TxIncluded || status.BlockHash != block.Hash || status.BlockIndex != block.Index {
+		t.Fatalf("after commit: %+v", status)
+	}
+
+	addBlock()
+	status := statusOf(tx.ID)
+	if status.Status != blockchain.TxFinalized || status.BlockHash != block.Hash || len(status.History) != 3 {
+		t.Fatalf("below the finalized height: %+v", status)
+	}
+
+	// Resubmitting the committed transaction leaves its status alone, while
+	// one the sender cannot cover is rejected
+	bc.SubmitTransaction(tx)
+	if status := statusOf(tx.ID); status.Status != blockchain.TxFinalized {
+		t.Fatalf("after resubmitting: %+v", status)
+	}
+	overdraft := signedTransfer(t, sender, newTestAccount(t).address, 5000, 10, 2)
+	if err := bc.SubmitTransaction(overdraft); err == nil {
+		t.Fatal("overdraft accepted")
+	}
+	if status := statusOf(overdraft.ID); status.Status != blockchain.TxRejected || status.Reason == "" {
+		t.Fatalf("overdraft: %+v", status)
+	}
+
+	if rec := serve(router, http.MethodGet, "/api/v1/transactions/unknown/status", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown transaction: expected 404, got %d", rec.Code)
+	}
+}
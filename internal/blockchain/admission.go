@@ -0,0 +1,139 @@
+package blockchain
+
+import (
+        "errors"
+        "fmt"
+        "lscc-blockchain/pkg/types"
+        "sort"
+)
+
+// ErrMempoolFull is returned when the pool has no room for another
+// transaction, by count or by size
+var ErrMempoolFull = errors.New("transaction pool is full")
+
+// ErrSenderLimit is returned when a sender already has as many transactions,
+// or as much value, pending as one sender is allowed
+var ErrSenderLimit = errors.New("sender has reached its pending limit")
+
+// AdmissionLimits bound how much of the mempool any one sender can take and
+// how large the pool can grow, so a single client cannot crowd out everyone
+// else. A zero field disables that limit.
+type AdmissionLimits struct {
+        MaxPerSender   int   // pending transactions per sender
+        MaxSenderValue int64 // pending amount plus fees per sender
+        MaxBytes       int   // encoded size of all pending transactions
+}
+
+// senderUsage is what one sender has pending
+type senderUsage struct {
+        count int
+        value int64
+}
+
+// SenderStats is one sender's share of the mempool
+type SenderStats struct {
+        Address string `json:"address"`
+        Pending int    `json:"pending"`
+        Value   int64  `json:"value"`
+}
+
+// MempoolStats describes the pool's occupancy against its admission limits
+type MempoolStats struct {
+        PendingCount   int           `json:"pending_count"`
+        PoolCapacity   int           `json:"pool_capacity"`
+        PendingBytes   int           `json:"pending_bytes"`
+        MaxBytes       int           `json:"max_bytes"`
+        MaxPerSender   int           `json:"max_per_sender"`
+        MaxSenderValue int64         `json:"max_sender_value"`
+        SenderCount    int           `json:"sender_count"`
+        Senders        []SenderStats `json:"senders"` // most pending first
+}
+
+// SetAdmissionLimits sets the per-sender and pool size caps new transactions
+// must fit within
+func (tm *TransactionManager) SetAdmissionLimits(limits AdmissionLimits) {
+        tm.mu.Lock()
+        defer tm.mu.Unlock()
+        tm.limits = limits
+}
+
+// checkAdmission reports whether tx fits within the admission limits given
+// what is already pending. Caller must hold tm.mu.
+func (tm *TransactionManager) checkAdmission(tx *types.Transaction) error {
+        if tm.limits.MaxBytes > 0 && tm.pool.bytes+tx.Size() > tm.limits.MaxBytes {
+                return fmt.Errorf("%w: %d of %d bytes pending, transaction needs %d", ErrMempoolFull, tm.pool.bytes, tm.limits.MaxBytes, tx.Size())
+        }
+
+        usage := tm.pool.senders[tx.From]
+        if usage == nil {
+                usage = &senderUsage{}
+        }
+        if tm.limits.MaxPerSender > 0 && usage.count >= tm.limits.MaxPerSender {
+                return fmt.Errorf("%w: %s has %d transactions pending, limit %d", ErrSenderLimit, tx.From, usage.count, tm.limits.MaxPerSender)
+        }
+        if tm.limits.MaxSenderValue > 0 && usage.value+tx.Amount+tx.Fee > tm.limits.MaxSenderValue {
+                return fmt.Errorf("%w: %s has value %d pending, transaction adds %d, limit %d", ErrSenderLimit, tx.From, usage.value, tx.Amount+tx.Fee, tm.limits.MaxSenderValue)
+        }
+        return nil
+}
+
+// trackPending records tx entering the pending pool. Caller must hold tm.mu.
+func (tm *TransactionManager) trackPending(tx *types.Transaction) {
+        tm.pool.pending[tx.ID] = tx
+        tm.pool.bytes += tx.Size()
+
+        usage := tm.pool.senders[tx.From]
+        if usage == nil {
+                usage = &senderUsage{}
+                tm.pool.senders[tx.From] = usage
+        }
+        usage.count++
+        usage.value += tx.Amount + tx.Fee
+}
+
+// untrackPending removes tx from the pending pool. Caller must hold tm.mu.
+func (tm *TransactionManager) untrackPending(tx *types.Transaction) {
+        delete(tm.pool.pending, tx.ID)
+        tm.pool.bytes -= tx.Size()
+
+        if usage := tm.pool.senders[tx.From]; usage != nil {
+                usage.count--
+                usage.value -= tx.Amount + tx.Fee
+                if usage.count <= 0 {
+                        delete(tm.pool.senders, tx.From)
+                }
+        }
+}
+
+// GetMempoolStats returns the pool's occupancy against its limits and up to
+// limit senders with transactions pending, most pending first. A
+// non-positive limit lists every sender.
+func (tm *TransactionManager) GetMempoolStats(limit int) MempoolStats {
+        tm.mu.RLock()
+        defer tm.mu.RUnlock()
+
+        senders := make([]SenderStats, 0, len(tm.pool.senders))
+        for address, usage := range tm.pool.senders {
+                senders = append(senders, SenderStats{Address: address, Pending: usage.count, Value: usage.value})
+        }
+        sort.Slice(senders, func(i, j int) bool {
+                if senders[i].Pending != senders[j].Pending {
+                        return senders[i].Pending > senders[j].Pending
+                }
+                return senders[i].Address < senders[j].Address
+        })
+        if limit > 0 && len(senders) > limit {
+                senders = senders[:limit]
+        }
+
+        return MempoolStats{
+                PendingCount:   len(tm.pool.pending),
+                PoolCapacity:   tm.pool.maxSize,
+                PendingBytes:   tm.pool.bytes,
+                MaxBytes:       tm.limits.MaxBytes,
+                MaxPerSender:   tm.limits.MaxPerSender,
+                MaxSenderValue: tm.limits.MaxSenderValue,
+                SenderCount:    len(tm.pool.senders),
+                Senders:        senders,
+        }
+}
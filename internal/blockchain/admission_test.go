@@ -0,0 +1,112 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"lscc-blockchain/config"
+)
+
+func TestSenderFloodCappedWithoutAffectingOthers(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", func(cfg *config.Config) {
+		cfg.Mempool.MaxPerSender = 3
+		cfg.Mempool.MaxSenderValue = 0
+	})
+	addValidators(t, bc, 4)
+	flooder := newTestAccount(t)
+	other := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, flooder)
+	fund(t, bc, flooder.address, 10000)
+	fund(t, bc, other.address, 10000)
+
+	// Past the cap the flooder keeps retrying its next nonce
+	var refused []error
+	for i := int64(1); i <= 10; i++ {
+		nonce := i
+		if nonce > 4 {
+			nonce = 4
+		}
+		if err := bc.SubmitTransaction(signedTransfer(t, flooder, recipient, i, 10, nonce)); err != nil {
+			refused = append(refused, err)
+		}
+	}
+	if len(refused) != 7 {
+		t.Fatalf("expected 7 of 10 transactions refused, got %d", len(refused))
+	}
+	for _, err := range refused {
+		if !errors.Is(err, ErrSenderLimit) {
+			t.Fatalf("expected ErrSenderLimit, got %v", err)
+		}
+	}
+
+	// Another sender is admitted while the first is at its cap
+	for nonce := int64(1); nonce <= 3; nonce++ {
+		if err := bc.SubmitTransaction(signedTransfer(t, other, recipient, 10, 10, nonce)); err != nil {
+			t.Fatalf("other sender refused: %v", err)
+		}
+	}
+
+	stats := bc.GetMempoolStats(0)
+	if stats.PendingCount != 6 || stats.SenderCount != 2 || stats.MaxPerSender != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	want := map[string]int64{flooder.address: 1 + 2 + 3 + 30, other.address: 60}
+	for _, sender := range stats.Senders {
+		if sender.Pending != 3 || sender.Value != want[sender.Address] {
+			t.Fatalf("sender %s: %d pending worth %d, want 3 worth %d", sender.Address, sender.Pending, sender.Value, want[sender.Address])
+		}
+	}
+
+	// Committing the pending transactions frees the flooder's slots
+	bc.processConsensusRound()
+	if stats := bc.GetMempoolStats(0); stats.PendingCount != 0 || stats.PendingBytes != 0 || len(stats.Senders) != 0 {
+		t.Fatalf("pool not emptied by the block: %+v", stats)
+	}
+	if err := bc.SubmitTransaction(signedTransfer(t, flooder, recipient, 10, 10, 4)); err != nil {
+		t.Fatalf("sender still refused after its transactions were committed: %v", err)
+	}
+}
+
+func TestSenderValueCap(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", func(cfg *config.Config) {
+		cfg.Mempool.MaxPerSender = 0
+		cfg.Mempool.MaxSenderValue = 250
+	})
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 10000)
+
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 190, 10, 1)); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 90, 10, 2)); !errors.Is(err, ErrSenderLimit) {
+		t.Fatalf("expected a transfer past the value cap refused with ErrSenderLimit, got %v", err)
+	}
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 40, 10, 2)); err != nil {
+		t.Fatalf("transfer within the value cap refused: %v", err)
+	}
+}
+
+func TestMempoolByteCap(t *testing.T) {
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	size := signedTransfer(t, sender, recipient, 10, 10, 1).Size()
+
+	bc := newTestBlockchain(t, "lscc", func(cfg *config.Config) {
+		cfg.Mempool.MaxPerSender = 0
+		cfg.Mempool.MaxBytes = 2*size + size/2
+	})
+	fund(t, bc, sender.address, 10000)
+
+	for nonce := int64(1); nonce <= 2; nonce++ {
+		if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, nonce)); err != nil {
+			t.Fatalf("failed to submit: %v", err)
+		}
+	}
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, 3)); !errors.Is(err, ErrMempoolFull) {
+		t.Fatalf("expected a transaction past the byte cap refused with ErrMempoolFull, got %v", err)
+	}
+	if stats := bc.GetMempoolStats(0); stats.PendingBytes > stats.MaxBytes {
+		t.Fatalf("%d bytes pending, cap %d", stats.PendingBytes, stats.MaxBytes)
+	}
+}
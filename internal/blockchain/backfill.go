@@ -0,0 +1,172 @@
+package blockchain
+
+import (
+        "fmt"
+        "lscc-blockchain/pkg/types"
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// BackfillFunc fetches the main-chain blocks from index from to index to,
+// inclusive, from elsewhere and imports them with ImportBlocks
+type BackfillFunc func(from, to int64) error
+
+// SetBackfillHandler sets the function called when a block arrives more than
+// one block ahead of the head, so the blocks in between can be fetched.
+// Only one backfill runs at a time; it runs on its own goroutine. A gap wider
+// than network.sync_max_span blocks is fetched over several calls.
+func (bc *Blockchain) SetBackfillHandler(fetch BackfillFunc) {
+        bc.mu.Lock()
+        defer bc.mu.Unlock()
+        bc.backfill = fetch
+}
+
+// IsBackfilling reports whether missing blocks are being fetched
+func (bc *Blockchain) IsBackfilling() bool {
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+        return bc.backfillTo > 0
+}
+
+// noteGap starts a backfill when block, which has no known parent, lies
+// beyond the block after the head. A block further ahead than the gap
+// being filled extends it. Caller must hold bc.mu.
+func (bc *Blockchain) noteGap(block *types.Block) {
+        if bc.backfill == nil || block.Index <= bc.blockHeight+1 {
+                return
+        }
+        if block.Index-1 > bc.backfillTarget {
+                bc.backfillTarget = block.Index - 1
+        }
+        if bc.backfillTo > 0 {
+                return
+        }
+        bc.startBackfillLocked(block.Hash)
+}
+
+// startBackfillLocked starts the next round of the backfill, fetching at
+// most network.sync_max_span blocks after the head towards backfillTarget.
+// Caller must hold bc.mu.
+func (bc *Blockchain) startBackfillLocked(triggerHash string) {
+        maxSpan := int64(bc.config.Network.SyncMaxSpan)
+        if maxSpan < 1 {
+                maxSpan = 1
+        }
+        from, to := bc.blockHeight+1, bc.backfillTarget
+        if to-from+1 > maxSpan {
+                to = from + maxSpan - 1
+        }
+        bc.backfillTo = to
+
+        bc.logger.LogBlockchain("backfill_started", logrus.Fields{
+                "from": from,
+                "to": to,
+                "target": bc.backfillTarget,
+                "trigger_hash": triggerHash,
+                "height": bc.blockHeight,
+                "timestamp": time.Now().UTC(),
+        })
+
+        go bc.runBackfill(bc.backfill, from, to)
+}
+
+// runBackfill fetches the blocks from from to to and records the outcome.
+// While the gap extends past to, the next round starts from the new head;
+// a round that fails or leaves the head short of to ends the backfill.
+func (bc *Blockchain) runBackfill(fetch BackfillFunc, from, to int64) {
+        startTime := time.Now()
+        err := fetch(from, to)
+
+        bc.mu.Lock()
+        defer bc.mu.Unlock()
+        bc.backfillTo = 0
+
+        if err != nil {
+                bc.backfillTarget = 0
+                bc.logger.LogError("blockchain", "backfill", err, logrus.Fields{
+                        "from": from,
+                        "to": to,
+                        "height": bc.blockHeight,
+                        "timestamp": time.Now().UTC(),
+                })
+                return
+        }
+
+        bc.logger.LogBlockchain("backfill_completed", logrus.Fields{
+                "from": from,
+                "to": to,
+                "height": bc.blockHeight,
+                "orphan_count": bc.orphans.size(),
+                "duration": time.Since(startTime).Milliseconds(),
+                "timestamp": time.Now().UTC(),
+        })
+
+        if bc.backfill != nil && bc.blockHeight >= to && bc.blockHeight < bc.backfillTarget {
+                bc.startBackfillLocked("")
+                return
+        }
+        bc.backfillTarget = 0
+}
+
+// ImportBlocks adds blocks fetched from a peer, in order. The blocks must be
+// consecutive, each linking to the one before, and the first must build on a
+// block this node has. Each block is checked as ValidateBlock does and by the
+// active consensus engine before it is added; blocks already known are
+// skipped. It returns the number of blocks added, stopping at the first one
+// that is rejected.
+func (bc *Blockchain) ImportBlocks(blocks []*types.Block) (int, error) {
+        if err := CheckBlockLinkage(blocks); err != nil {
+                return 0, err
+        }
+
+        bc.mu.RLock()
+        engine := bc.consensus
+        bc.mu.RUnlock()
+        validators := bc.GetValidators()
+
+        imported := 0
+        for _, block := range blocks {
+                bc.mu.RLock()
+                known := bc.isKnownBlock(block.Hash)
+                bc.mu.RUnlock()
+                if known {
+                        continue
+                }
+
+                if err := bc.ValidateBlock(block); err != nil {
+                        return imported, fmt.Errorf("block %d: %w", block.Index, err)
+                }
+                if err := engine.ValidateBlock(block, validators); err != nil {
+                        return imported, fmt.Errorf("block %d rejected by %s consensus: %w", block.Index, engine.GetAlgorithmName(), err)
+                }
+                if err := bc.AddBlock(block); err != nil {
+                        return imported, fmt.Errorf("failed to import block %d: %w", block.Index, err)
+                }
+                imported++
+        }
+
+        return imported, nil
+}
+
+// CheckBlockLinkage checks that blocks have consecutive indexes, hashes that
+// match their contents, and that each one names the previous block as its
+// parent
+func CheckBlockLinkage(blocks []*types.Block) error {
+        for i, block := range blocks {
+                if !block.VerifyHash() {
+                        return fmt.Errorf("block %d hash mismatch: expected %s, got %s", block.Index, block.ComputeHash(), block.Hash)
+                }
+                if i == 0 {
+                        continue
+                }
+                previous := blocks[i-1]
+                if block.Index != previous.Index+1 {
+                        return fmt.Errorf("block %d follows block %d", block.Index, previous.Index)
+                }
+                if block.PreviousHash != previous.Hash {
+                        return fmt.Errorf("block %d does not link to block %d: parent %s, expected %s", block.Index, previous.Index, block.PreviousHash, previous.Hash)
+                }
+        }
+        return nil
+}
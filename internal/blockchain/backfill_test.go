@@ -0,0 +1,190 @@
+package blockchain
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/pkg/types"
+)
+
+// newPeerChains returns a chain that has committed count blocks of
+// transfers and an empty one behind it, built from the same genesis file
+// and with the same validators, so the second accepts the first's blocks
+func newPeerChains(t *testing.T, count int) (ahead, behind *Blockchain) {
+	t.Helper()
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+
+	var path string
+	build := func() *Blockchain {
+		bc := newTestBlockchain(t, "lscc", func(cfg *config.Config) {
+			if path == "" {
+				path = writeGenesis(t, &Genesis{
+					ChainID:   cfg.Network.ChainID,
+					Timestamp: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+					Alloc:     map[string]int64{sender.address: 1000},
+				})
+			}
+			cfg.Genesis.Path = path
+		})
+		addValidators(t, bc, 4)
+		return bc
+	}
+	ahead, behind = build(), build()
+	if ahead.GetGenesisBlock().Hash != behind.GetGenesisBlock().Hash {
+		t.Fatal("peer chains start from different genesis blocks")
+	}
+
+	for nonce := int64(1); nonce <= int64(count); nonce++ {
+		if err := ahead.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, nonce)); err != nil {
+			t.Fatalf("failed to submit: %v", err)
+		}
+		ahead.processConsensusRound()
+	}
+	if got := ahead.GetBlockHeight(); got != int64(count) {
+		t.Fatalf("source chain at height %d, want %d", got, count)
+	}
+	return ahead, behind
+}
+
+// blockRange returns bc's main-chain blocks from index from to index to
+func blockRange(t *testing.T, bc *Blockchain, from, to int64) []*types.Block {
+	t.Helper()
+	blocks := make([]*types.Block, 0, to-from+1)
+	for index := from; index <= to; index++ {
+		block, err := bc.GetBlockByIndex(index)
+		if err != nil {
+			t.Fatalf("no block %d: %v", index, err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func TestGapTriggersBackfillToPeerHeight(t *testing.T) {
+	ahead, behind := newPeerChains(t, 5)
+
+	requested := make(chan [2]int64, 1)
+	behind.SetBackfillHandler(func(from, to int64) error {
+		requested <- [2]int64{from, to}
+		_, err := behind.ImportBlocks(blockRange(t, ahead, from, to))
+		return err
+	})
+
+	// The peer's head arrives while blocks 1 to 4 are missing
+	head := ahead.GetLatestBlock()
+	if err := behind.AddOrphan(head); err != nil {
+		t.Fatalf("failed to accept the peer's head: %v", err)
+	}
+
+	select {
+	case got := <-requested:
+		if got != [2]int64{1, 4} {
+			t.Fatalf("backfill requested blocks %d-%d, want 1-4", got[0], got[1])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no backfill started for the gap")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for behind.IsBackfilling() || behind.GetBlockHeight() != 5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("node at height %d after backfill, want the peer's 5", behind.GetBlockHeight())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := behind.GetLatestBlock().Hash; got != head.Hash {
+		t.Fatalf("node's head is %s, want the peer's %s", got, head.Hash)
+	}
+	if behind.GetOrphanCount() != 0 {
+		t.Fatalf("%d orphans left after the gap was filled", behind.GetOrphanCount())
+	}
+}
+
+func TestWideGapBackfilledOverRounds(t *testing.T) {
+	ahead, behind := newPeerChains(t, 7)
+	behind.config.Network.SyncMaxSpan = 2
+
+	var mu sync.Mutex
+	var requested [][2]int64
+	behind.SetBackfillHandler(func(from, to int64) error {
+		mu.Lock()
+		requested = append(requested, [2]int64{from, to})
+		mu.Unlock()
+		_, err := behind.ImportBlocks(blockRange(t, ahead, from, to))
+		return err
+	})
+
+	// The peer's head arrives while blocks 1 to 6 are missing
+	head := ahead.GetLatestBlock()
+	if err := behind.AddOrphan(head); err != nil {
+		t.Fatalf("failed to accept the peer's head: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for behind.IsBackfilling() || behind.GetBlockHeight() != 7 {
+		if time.Now().After(deadline) {
+			t.Fatalf("node at height %d after backfill, want the peer's 7", behind.GetBlockHeight())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := [][2]int64{{1, 2}, {3, 4}, {5, 6}}
+	if len(requested) != len(want) {
+		t.Fatalf("backfill requested %v, want %v", requested, want)
+	}
+	for i := range want {
+		if requested[i] != want[i] {
+			t.Fatalf("backfill requested %v, want %v", requested, want)
+		}
+	}
+}
+
+func TestNextBlockDoesNotTriggerBackfill(t *testing.T) {
+	ahead, behind := newPeerChains(t, 1)
+	behind.SetBackfillHandler(func(from, to int64) error {
+		t.Errorf("backfill of blocks %d-%d started without a gap", from, to)
+		return nil
+	})
+
+	if err := behind.AddOrphan(ahead.GetLatestBlock()); err != nil {
+		t.Fatalf("failed to accept the next block: %v", err)
+	}
+	if behind.IsBackfilling() || behind.GetBlockHeight() != 1 {
+		t.Fatalf("expected the next block added directly, at height %d", behind.GetBlockHeight())
+	}
+}
+
+func TestImportBlocksRejectsBrokenLinkage(t *testing.T) {
+	ahead, behind := newPeerChains(t, 3)
+	blocks := blockRange(t, ahead, 1, 3)
+
+	// Dropping a block breaks the chain in the middle
+	if imported, err := behind.ImportBlocks([]*types.Block{blocks[0], blocks[2]}); err == nil || imported != 0 {
+		t.Fatalf("expected unlinked blocks refused before importing any, got %d, %v", imported, err)
+	}
+
+	// A block that does not build on anything this node has is refused too
+	if imported, err := behind.ImportBlocks(blocks[1:]); err == nil || imported != 0 {
+		t.Fatalf("expected blocks without a known parent refused, got %d, %v", imported, err)
+	}
+	if behind.GetBlockHeight() != 0 {
+		t.Fatalf("height %d after refused imports", behind.GetBlockHeight())
+	}
+
+	// The refused block waited as an orphan and joins once its parent does
+	if _, err := behind.ImportBlocks(blocks); err != nil {
+		t.Fatalf("expected the linked range imported, got %v", err)
+	}
+	if behind.GetBlockHeight() != 3 || behind.GetLatestBlock().Hash != blocks[2].Hash {
+		t.Fatalf("node at height %d after import, want the peer's head at 3", behind.GetBlockHeight())
+	}
+	// Importing again skips the blocks already known
+	if imported, err := behind.ImportBlocks(blocks); err != nil || imported != 0 {
+		t.Fatalf("expected known blocks skipped, got %d, %v", imported, err)
+	}
+}
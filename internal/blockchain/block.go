@@ -15,21 +15,49 @@ import (
 
 // BlockManager handles block operations
 type BlockManager struct {
-        logger   *utils.Logger
-        gasLimit int64
+        logger       *utils.Logger
+        gasLimit     int64
+        maxTxs       int // Maximum transactions per block
+        maxBlockSize int // Maximum encoded block size in bytes
+        chainID      string
+        rewards      RewardSchedule
+        feeMarket    FeeMarket
 }
 
 // NewBlockManager creates a new block manager
-func NewBlockManager(logger *utils.Logger, gasLimit int64) *BlockManager {
+func NewBlockManager(logger *utils.Logger, gasLimit int64, maxTxs int, maxBlockSize int, chainID string) *BlockManager {
         if gasLimit <= 0 {
                 gasLimit = 200000000 // Default to 200M gas if not specified
         }
         return &BlockManager{
-                logger:   logger,
-                gasLimit: gasLimit,
+                logger:       logger,
+                gasLimit:     gasLimit,
+                maxTxs:       maxTxs,
+                maxBlockSize: maxBlockSize,
+                chainID:      chainID,
         }
 }
 
+// FitTransactions returns the longest prefix of transactions that fits in a
+// single block under the transaction count, size and gas limits. It stops at
+// the first transaction that does not fit rather than skipping it, so later
+// nonces from the same sender are never selected without their predecessor.
+func (bm *BlockManager) FitTransactions(transactions []*types.Transaction) []*types.Transaction {
+        size := types.BlockHeaderSize
+        var gas int64
+        for i, tx := range transactions {
+                if bm.maxTxs > 0 && i >= bm.maxTxs {
+                        return transactions[:i]
+                }
+                size += tx.Size()
+                gas += tx.Gas()
+                if (bm.maxBlockSize > 0 && size > bm.maxBlockSize) || gas > bm.gasLimit {
+                        return transactions[:i]
+                }
+        }
+        return transactions
+}
+
 // CreateBlock creates a new block with transactions
 func (bm *BlockManager) CreateBlock(previousBlock *types.Block, transactions []*types.Transaction, validator string, shardID int) (*types.Block, error) {
         startTime := time.Now()
@@ -45,6 +73,9 @@ func (bm *BlockManager) CreateBlock(previousBlock *types.Block, transactions []*
         // Calculate next index
         index := previousBlock.Index + 1
 
+        // Every node orders the same transactions the same way
+        transactions = OrderTransactions(transactions)
+
         // Create Merkle tree and get root
         merkleTree := NewMerkleTree(transactions)
         merkleRoot := merkleTree.GetRootHash()
@@ -54,7 +85,19 @@ func (bm *BlockManager) CreateBlock(previousBlock *types.Block, transactions []*
         gasLimit := bm.gasLimit // Use configured gas limit
 
         if gasUsed > gasLimit {
-                return nil, fmt.Errorf("block gas usage %d exceeds limit %d", gasUsed, gasLimit)
+                return nil, fmt.Errorf("%w: %d > %d", types.ErrBlockGasExceeded, gasUsed, gasLimit)
+        }
+        for _, tx := range transactions {
+                tx.GasUsed = tx.Gas()
+        }
+
+        if bm.maxTxs > 0 && len(transactions) > bm.maxTxs {
+                return nil, fmt.Errorf("%w: %d > %d", types.ErrTooManyTransactions, len(transactions), bm.maxTxs)
+        }
+
+        blockSize := bm.calculateBlockSize(transactions)
+        if bm.maxBlockSize > 0 && blockSize > bm.maxBlockSize {
+                return nil, fmt.Errorf("%w: %d > %d bytes", types.ErrBlockTooLarge, blockSize, bm.maxBlockSize)
         }
 
         // Create block
@@ -68,9 +111,11 @@ func (bm *BlockManager) CreateBlock(previousBlock *types.Block, transactions []*
                 Difficulty:   4, // Will be set by consensus
                 Validator:    validator,
                 ShardID:      shardID,
-                Size:         bm.calculateBlockSize(transactions),
+                Size:         blockSize,
                 GasUsed:      gasUsed,
                 GasLimit:     gasLimit,
+                ChainID:      bm.chainID,
+                BaseFee:      bm.feeMarket.NextBaseFee(previousBlock, bm.maxTxs),
                 Metadata: map[string]interface{}{
                         "merkle_tree_depth": merkleTree.GetDepth(),
                         "merkle_leaf_count": merkleTree.GetLeafCount(),
@@ -80,7 +125,7 @@ func (bm *BlockManager) CreateBlock(previousBlock *types.Block, transactions []*
         }
 
         // Calculate block hash
-        block.Hash = block.CalculateHash()
+        block.Hash = block.ComputeHash()
 
         duration := time.Since(startTime)
         bm.logger.LogBlockchain("block_created", logrus.Fields{
@@ -99,15 +144,7 @@ func (bm *BlockManager) CreateBlock(previousBlock *types.Block, transactions []*
 
 // CalculateBlockHash calculates the hash for a block
 func (bm *BlockManager) CalculateBlockHash(block *types.Block) string {
-        data := fmt.Sprintf("%d:%s:%s:%s:%d:%d",
-                block.Index,
-                block.PreviousHash,
-                block.MerkleRoot,
-                block.Validator,
-                block.Timestamp.Unix(),
-                block.Nonce,
-        )
-        return utils.CalculateHash(data)
+        return block.ComputeHash()
 }
 
 // ValidateBlock validates a block against the previous block
@@ -145,12 +182,27 @@ func (bm *BlockManager) ValidateBlock(block *types.Block, previousBlock *types.B
                 validationErrors = append(validationErrors, "block timestamp is before previous block")
         }
 
+        // Reject blocks from another network
+        if block.ChainID != bm.chainID {
+                validationErrors = append(validationErrors, fmt.Sprintf("invalid chain ID: expected %s, got %s", bm.chainID, block.ChainID))
+        }
+
+        // The base fee follows from the parent, so every node derives the same
+        if expected := bm.feeMarket.NextBaseFee(previousBlock, bm.maxTxs); block.BaseFee != expected {
+                validationErrors = append(validationErrors, fmt.Sprintf("invalid base fee: expected %d, got %d", expected, block.BaseFee))
+        }
+
         // Validate hash
-        calculatedHash := block.CalculateHash()
+        calculatedHash := block.ComputeHash()
         if block.Hash != calculatedHash {
                 validationErrors = append(validationErrors, fmt.Sprintf("invalid block hash: expected %s, got %s", calculatedHash, block.Hash))
         }
 
+        // Validate transaction order
+        if err := CheckTransactionOrder(block.Transactions); err != nil {
+                validationErrors = append(validationErrors, err.Error())
+        }
+
         // Validate Merkle root
         merkleTree := NewMerkleTree(block.Transactions)
         expectedMerkleRoot := merkleTree.GetRootHash()
@@ -168,6 +220,22 @@ func (bm *BlockManager) ValidateBlock(block *types.Block, previousBlock *types.B
                 validationErrors = append(validationErrors, fmt.Sprintf("gas used %d exceeds gas limit %d", block.GasUsed, block.GasLimit))
         }
 
+        // The block's own gas limit is declared by its producer, so the
+        // transactions are also held to the limit this node is configured with
+        if err := block.CheckGas(bm.gasLimit); err != nil {
+                validationErrors = append(validationErrors, err.Error())
+        }
+        for _, tx := range block.Transactions {
+                if tx.GasUsed != 0 && tx.GasUsed != tx.Gas() {
+                        validationErrors = append(validationErrors, fmt.Sprintf("transaction %s charged %d gas, costs %d", tx.ID, tx.GasUsed, tx.Gas()))
+                }
+        }
+
+        // Validate transaction count and size limits
+        if err := block.CheckLimits(bm.maxTxs, bm.maxBlockSize); err != nil {
+                validationErrors = append(validationErrors, err.Error())
+        }
+
         // Validate transactions
         for i, tx := range block.Transactions {
                 if err := bm.validateTransactionInBlock(tx, block); err != nil {
@@ -232,10 +300,18 @@ func (bm *BlockManager) validateTransactionInBlock(tx *types.Transaction, block
                 return errors.New("transaction fee cannot be negative")
         }
 
+        if tx.Type != "genesis" && tx.Fee < block.BaseFee {
+                return fmt.Errorf("%w: fee %d, block base fee %d", ErrFeeTooLow, tx.Fee, block.BaseFee)
+        }
+
         if tx.Signature == "" {
                 return errors.New("transaction signature is empty")
         }
 
+        if err := utils.VerifyTransactionSender(tx); err != nil {
+                return fmt.Errorf("invalid transaction signature: %w", err)
+        }
+
         // Validate transaction hash
         calculatedHash := tx.Hash()
         if tx.ID != calculatedHash {
@@ -261,47 +337,16 @@ func (bm *BlockManager) validateTransactionInBlock(tx *types.Transaction, block
 
 // calculateGasUsed calculates the total gas used by transactions
 func (bm *BlockManager) calculateGasUsed(transactions []*types.Transaction) int64 {
-        var totalGas int64 = 0
-
-        for _, tx := range transactions {
-                // Base gas cost
-                gas := int64(21000)
-
-                // Data gas cost (per byte)
-                gas += int64(len(tx.Data)) * 68
-
-                // Additional gas for cross-shard transactions
-                if tx.Type == "cross_shard" {
-                        gas += 50000
-                }
-
-                // Additional gas for staking transactions
-                if tx.Type == "stake" || tx.Type == "unstake" {
-                        gas += 100000
-                }
-
-                totalGas += gas
-        }
-
-        return totalGas
+        return types.GasUsedBy(transactions)
 }
 
 // calculateBlockSize calculates the size of a block in bytes
 func (bm *BlockManager) calculateBlockSize(transactions []*types.Transaction) int {
-        // Approximate block size calculation
-        baseSize := 200 // Block header approximate size
-
+        size := types.BlockHeaderSize
         for _, tx := range transactions {
-                // Transaction base size
-                txSize := 150 // Base transaction size
-                txSize += len(tx.Data)
-                txSize += len(tx.Signature)
-                txSize += len(tx.From)
-                txSize += len(tx.To)
-                baseSize += txSize
+                size += tx.Size()
         }
-
-        return baseSize
+        return size
 }
 
 // CreateGenesisBlock creates the genesis block
@@ -352,15 +397,16 @@ func (bm *BlockManager) CreateGenesisBlock() *types.Block {
                 Size:         bm.calculateBlockSize(transactions),
                 GasUsed:      bm.calculateGasUsed(transactions),
                 GasLimit:     5000000,
+                ChainID:      bm.chainID,
                 Metadata: map[string]interface{}{
                         "genesis": true,
                         "version": "1.0.0",
-                        "network": "lscc-mainnet",
+                        "network": bm.chainID,
                         "creation_time": startTime,
                 },
         }
 
-        genesisBlock.Hash = genesisBlock.CalculateHash()
+        genesisBlock.Hash = genesisBlock.ComputeHash()
 
         bm.logger.LogBlockchain("genesis_block_created", logrus.Fields{
                 "genesis_hash":   genesisBlock.Hash,
@@ -391,7 +437,7 @@ func (bm *BlockManager) MineBlock(block *types.Block, difficulty int) error {
 
         for {
                 hashAttempts++
-                block.Hash = block.CalculateHash()
+                block.Hash = block.ComputeHash()
 
                 // Log mining progress every 100,000 attempts
                 if hashAttempts%100000 == 0 {
@@ -524,33 +570,24 @@ func (bm *BlockManager) GetTransactionFromBlock(block *types.Block, txID string)
         return nil
 }
 
-// CalculateBlockReward calculates the mining reward for a block
+// CalculateBlockReward returns what the proposer and voters of block earn
+// between them: the block subsidy plus the fees of its transactions, less
+// the base fees burned
 func (bm *BlockManager) CalculateBlockReward(block *types.Block) int64 {
-        baseReward := int64(50000000) // 50 LSCC tokens (assuming 6 decimal places)
-
-        // Reduce reward based on block height (halving every 210,000 blocks)
-        halvingInterval := int64(210000)
-        halvings := block.Index / halvingInterval
-
-        if halvings >= 32 {
-                return 0 // No more rewards after 32 halvings
-        }
-
-        reward := baseReward >> halvings // Divide by 2 for each halving
+        subsidy := bm.rewards.BlockReward(block.Index)
 
-        // Add transaction fees
+        var fees int64
         for _, tx := range block.Transactions {
-                reward += tx.Fee
+                fees += tx.Fee - burnedFee(tx, block.BaseFee)
         }
 
         bm.logger.LogBlockchain("calculate_reward", logrus.Fields{
-                "block_index":   block.Index,
-                "base_reward":   baseReward,
-                "halvings":      halvings,
-                "final_reward":  reward,
-                "tx_fees":       reward - (baseReward >> halvings),
-                "timestamp":     time.Now().UTC(),
+                "block_index":  block.Index,
+                "subsidy":      subsidy,
+                "tx_fees":      fees,
+                "final_reward": subsidy + fees,
+                "timestamp":    time.Now().UTC(),
         })
 
-        return reward
+        return subsidy + fees
 }
\ No newline at end of file
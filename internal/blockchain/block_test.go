@@ -0,0 +1,215 @@
+package blockchain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/pkg/types"
+)
+
+func TestAddBlockRejectsMismatchedHash(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", nil)
+	block := sideBlock(t, bc, bc.GetLatestBlock(), nil, "proposer")
+
+	// Contents changed after hashing
+	block.Validator = "impostor"
+	err := bc.AddBlock(block)
+	if err == nil || !strings.Contains(err.Error(), "invalid block hash") {
+		t.Fatalf("block with a stale hash: got %v", err)
+	}
+	if got := bc.GetBlockHeight(); got != 0 {
+		t.Fatalf("height = %d after a rejected block", got)
+	}
+
+	block.Hash = block.ComputeHash()
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatalf("rehashed block rejected: %v", err)
+	}
+}
+
+func TestAssemblyStopsAtMaxTxPerBlock(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", func(cfg *config.Config) {
+		cfg.Consensus.MaxTxPerBlock = 3
+	})
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+	for nonce := int64(1); nonce <= 5; nonce++ {
+		if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, nonce)); err != nil {
+			t.Fatalf("failed to submit nonce %d: %v", nonce, err)
+		}
+	}
+
+	candidates := bc.candidateTransactions(bc.GetLatestBlock())
+	if len(candidates) != 3 {
+		t.Fatalf("assembled %d transactions, want the limit of 3", len(candidates))
+	}
+	for i, tx := range candidates {
+		if tx.Nonce != int64(i+1) {
+			t.Fatalf("candidate %d has nonce %d, want the lowest nonces first", i, tx.Nonce)
+		}
+	}
+
+	block, err := bc.blockManager.CreateBlock(bc.GetLatestBlock(), candidates, "proposer", 0)
+	if err != nil {
+		t.Fatalf("failed to create a full block: %v", err)
+	}
+	if err := bc.ValidateBlock(block); err != nil {
+		t.Fatalf("full block rejected: %v", err)
+	}
+}
+
+func TestBlockOverMaxTxPerBlockRejected(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", func(cfg *config.Config) {
+		cfg.Consensus.MaxTxPerBlock = 3
+	})
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+	var txs []*types.Transaction
+	for nonce := int64(1); nonce <= 4; nonce++ {
+		txs = append(txs, signedTransfer(t, sender, recipient, 10, 10, nonce))
+	}
+
+	if _, err := bc.blockManager.CreateBlock(bc.GetLatestBlock(), txs, "proposer", 0); !errors.Is(err, types.ErrTooManyTransactions) {
+		t.Fatalf("creating an oversized block: got %v", err)
+	}
+
+	// A peer without the limit can still build one
+	unlimited := NewBlockManager(discardLogger(), 0, 0, 0, bc.config.Network.ChainID)
+	block, err := unlimited.CreateBlock(bc.GetLatestBlock(), txs, "proposer", 0)
+	if err != nil {
+		t.Fatalf("failed to build an unlimited block: %v", err)
+	}
+	if err := bc.ValidateBlock(block); !errors.Is(err, types.ErrTooManyTransactions) {
+		t.Fatalf("validating an oversized block: got %v", err)
+	}
+	if err := bc.AddBlock(block); err == nil {
+		t.Fatal("oversized block added")
+	}
+	if got := bc.GetBlockHeight(); got != 0 {
+		t.Fatalf("height = %d after a rejected block", got)
+	}
+}
+
+func TestAssemblyStopsAtGasLimit(t *testing.T) {
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	perTx := signedTransfer(t, sender, recipient, 10, 10, 1).Gas()
+
+	// Room for three transfers and part of a fourth
+	bc := newTestBlockchain(t, "pbft", func(cfg *config.Config) {
+		cfg.Consensus.GasLimit = 3*perTx + perTx/2
+	})
+	addValidators(t, bc, 4)
+	fund(t, bc, sender.address, 1000)
+	for nonce := int64(1); nonce <= 5; nonce++ {
+		tx := signedTransfer(t, sender, recipient, 10, 10, nonce)
+		if err := bc.SubmitTransaction(tx); err != nil {
+			t.Fatalf("failed to submit nonce %d: %v", nonce, err)
+		}
+		if tx.GasUsed != perTx {
+			t.Fatalf("validated transaction charged %d gas, costs %d", tx.GasUsed, perTx)
+		}
+	}
+
+	bc.processConsensusRound()
+	block, err := bc.GetBlockByIndex(1)
+	if err != nil {
+		t.Fatalf("no block committed: %v", err)
+	}
+	if len(block.Transactions) != 3 {
+		t.Fatalf("block holds %d transactions, want the 3 that fit", len(block.Transactions))
+	}
+
+	// The block's gas is the sum of what each transaction was charged
+	var sum int64
+	for _, tx := range block.Transactions {
+		sum += tx.GasUsed
+	}
+	if block.GasUsed != sum || sum != 3*perTx || block.GasUsed > block.GasLimit {
+		t.Fatalf("block used %d gas of %d, transactions charged %d", block.GasUsed, block.GasLimit, sum)
+	}
+
+	// The rest wait for the next block
+	bc.processConsensusRound()
+	block, err = bc.GetBlockByIndex(2)
+	if err != nil {
+		t.Fatalf("no second block: %v", err)
+	}
+	if len(block.Transactions) != 2 {
+		t.Fatalf("second block holds %d transactions, want the remaining 2", len(block.Transactions))
+	}
+}
+
+func TestBlockOverGasLimitRejected(t *testing.T) {
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	var txs []*types.Transaction
+	for nonce := int64(1); nonce <= 3; nonce++ {
+		txs = append(txs, signedTransfer(t, sender, recipient, 10, 10, nonce))
+	}
+	limit := types.GasUsedBy(txs) - 1
+
+	bc := newTestBlockchain(t, "pbft", func(cfg *config.Config) {
+		cfg.Consensus.GasLimit = limit
+	})
+	fund(t, bc, sender.address, 1000)
+	if _, err := bc.blockManager.CreateBlock(bc.GetLatestBlock(), txs, "proposer", 0); !errors.Is(err, types.ErrBlockGasExceeded) {
+		t.Fatalf("creating a block over the gas limit: got %v", err)
+	}
+
+	// A producer with a higher limit declares it in the block
+	generous := NewBlockManager(discardLogger(), 10*limit, 0, 0, bc.config.Network.ChainID)
+	block, err := generous.CreateBlock(bc.GetLatestBlock(), txs, "proposer", 0)
+	if err != nil {
+		t.Fatalf("failed to build the block: %v", err)
+	}
+	if block.GasLimit <= limit {
+		t.Fatalf("block declares gas limit %d", block.GasLimit)
+	}
+	if err := bc.ValidateBlock(block); !errors.Is(err, types.ErrBlockGasExceeded) {
+		t.Fatalf("validating a block over the gas limit: got %v", err)
+	}
+	if err := bc.AddBlock(block); err == nil {
+		t.Fatal("block over the gas limit added")
+	}
+
+	// Overcharging a transaction is caught too
+	fair, err := NewBlockManager(discardLogger(), 0, 0, 0, bc.config.Network.ChainID).CreateBlock(bc.GetLatestBlock(), txs[:1], "proposer", 0)
+	if err != nil {
+		t.Fatalf("failed to build the block: %v", err)
+	}
+	fair.Transactions[0].GasUsed++
+	if err := bc.AddBlock(fair); err == nil || !strings.Contains(err.Error(), "charged") {
+		t.Fatalf("block with an overcharged transaction: got %v", err)
+	}
+}
+
+// TestAssemblyWhileBlocksCommit assembles candidates while blocks commit.
+// Run under -race: assembly must read the head only through its parent.
+func TestAssemblyWhileBlocksCommit(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", nil)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, 1)); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			bc.candidateTransactions(bc.GetLatestBlock())
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if err := bc.AddBlock(sideBlock(t, bc, bc.GetLatestBlock(), nil, "proposer")); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+	}
+	<-done
+}
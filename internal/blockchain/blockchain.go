@@ -2,19 +2,38 @@
 package blockchain
 
 import (
+        "context"
         "errors"
         "fmt"
         "lscc-blockchain/config"
         "lscc-blockchain/internal/consensus"
+        "lscc-blockchain/internal/metrics"
         "lscc-blockchain/internal/storage"
         "lscc-blockchain/internal/utils"
         "lscc-blockchain/pkg/types"
+        "sort"
         "sync"
         "time"
 
         "github.com/sirupsen/logrus"
 )
 
+// ErrBlockInProgress is returned when an operation must wait for the block
+// currently being produced to be committed
+var ErrBlockInProgress = errors.New("a block is being committed")
+
+// ErrEventLogDisabled is returned when consensus events are queried on a node
+// configured not to record them
+var ErrEventLogDisabled = errors.New("consensus event log is disabled")
+
+// ErrNoMessageLog is returned when the message log is requested while the
+// active consensus algorithm is not PPBFT
+var ErrNoMessageLog = errors.New("active consensus algorithm keeps no ppbft message log")
+
+// ErrNoSimulator is returned when a round is simulated while the active
+// consensus algorithm is not LSCC
+var ErrNoSimulator = errors.New("active consensus algorithm cannot simulate rounds")
+
 // Blockchain represents the main blockchain structure
 type Blockchain struct {
         config *config.Config
@@ -22,17 +41,42 @@ type Blockchain struct {
         logger *utils.Logger
         blockManager *BlockManager
         txManager *TransactionManager
+        accountState *AccountState
         consensus consensus.Consensus
         genesisBlock *types.Block
         latestBlock *types.Block
         validators []*types.Validator
+        validatorSetChecked bool // CheckValidatorSet ran; later changes to the set are checked too
         isRunning bool
         mu sync.RWMutex
+        roundMu sync.Mutex // held while a consensus round produces and commits a block
         blockHeight int64
         totalTxCount int64
         startTime time.Time
+        lastDecision time.Time // when the most recent block was committed
         stopChan chan struct{}
+        loops sync.WaitGroup // consensus and liveness loops started by StartConsensus
         consensusMetrics map[string]interface{}
+        forkBlocks map[string]*types.Block // hash -> valid block not on the main chain
+        orphans *orphanPool // blocks whose parent is unknown, by missing parent hash
+        events *consensus.EventLog // consensus event history, nil when disabled
+        collector *metrics.MetricsCollector // receives committed blocks, nil when unset
+        unsubscribeCollector func() // removes the collector's commit observer, nil when unset
+        observers []*observerEntry // notified of every committed block
+        observerMu sync.RWMutex
+        notifyMu sync.Mutex // held while observers are notified, so commits are reported in order
+        commitQueue []committedBlock // committed under bc.mu, reported when it is released
+        liveness *livenessMonitor // validator participation, nil when disabled
+        epochs epochState // validator set rotation, unused when epochs are disabled
+        rewards RewardSchedule // block subsidy paid to each block's proposer and voters
+        voters *roundVoters // validators whose votes the current round counted
+        txStatus *txStatusTracker // lifecycle of the transactions this node has seen
+        feeMarket FeeMarket // base fee charged and burned per transaction
+        backfill BackfillFunc // fetches blocks missed while behind, nil when unset
+        backfillTo int64 // last index of the range being backfilled, 0 when idle
+        backfillTarget int64 // last index of the whole gap, filled over rounds of at most network.sync_max_span blocks
+        peerCount func() int // connected peers, for the warm-up gate; nil when unset
+        warmingUp bool // consensus is waiting for peers and validators before its first round
 }
 
 // NewBlockchain creates a new blockchain instance
@@ -50,8 +94,19 @@ func NewBlockchain(cfg *config.Config, db storage.Database, logger *utils.Logger
         if gasLimit <= 0 {
                 gasLimit = 200000000 // Default to 200M gas if not configured
         }
-        blockManager := NewBlockManager(logger, gasLimit)
+        blockManager := NewBlockManager(logger, gasLimit, cfg.Consensus.MaxTxPerBlock, cfg.Consensus.MaxBlockSize, cfg.Network.ChainID)
         txManager := NewTransactionManager(1000, logger) // Max 1000 pending transactions
+        txManager.SetFeePolicy(FeePolicy{
+                MinFee:           cfg.Mempool.MinFee,
+                CongestionTarget: cfg.Mempool.CongestionTarget,
+                MaxMultiplier:    cfg.Mempool.MaxFeeMultiplier,
+        })
+        txManager.SetTxTTL(time.Duration(cfg.Mempool.TxTTL) * time.Second)
+        txManager.SetAdmissionLimits(AdmissionLimits{
+                MaxPerSender:   cfg.Mempool.MaxPerSender,
+                MaxSenderValue: cfg.Mempool.MaxSenderValue,
+                MaxBytes:       cfg.Mempool.MaxBytes,
+        })
 
         // Create blockchain instance
         bc := &Blockchain{
@@ -60,11 +115,42 @@ func NewBlockchain(cfg *config.Config, db storage.Database, logger *utils.Logger
                 logger: logger,
                 blockManager: blockManager,
                 txManager: txManager,
+                accountState: NewAccountState(db, logger),
                 validators: make([]*types.Validator, 0),
                 isRunning: false,
                 startTime: startTime,
+                lastDecision: startTime,
                 stopChan: make(chan struct{}),
                 consensusMetrics: make(map[string]interface{}),
+                forkBlocks: make(map[string]*types.Block),
+                orphans: newOrphanPool(maxSideBlocks),
+                voters: newRoundVoters(),
+                txStatus: newTxStatusTracker(),
+                rewards: RewardSchedule{
+                        InitialReward:   cfg.Consensus.BlockReward,
+                        HalvingInterval: cfg.Consensus.HalvingInterval,
+                },
+                feeMarket: FeeMarket{
+                        Enabled:           cfg.Consensus.FeeMarket.Enabled,
+                        InitialBaseFee:    cfg.Consensus.FeeMarket.InitialBaseFee,
+                        MinBaseFee:        cfg.Consensus.FeeMarket.MinBaseFee,
+                        TargetUtilization: cfg.Consensus.FeeMarket.TargetUtilization,
+                        ChangeDenominator: cfg.Consensus.FeeMarket.ChangeDenominator,
+                },
+        }
+        blockManager.rewards = bc.rewards
+        blockManager.feeMarket = bc.feeMarket
+        txManager.SetNonceProvider(bc.GetAccountNonce)
+        if cfg.Consensus.LivenessWindow > 0 {
+                bc.liveness = newLivenessMonitor(time.Duration(cfg.Consensus.LivenessWindow) * time.Second)
+        }
+
+        if cfg.Consensus.EventLogSize > 0 {
+                events, err := consensus.NewEventLog(db, cfg.Consensus.EventLogSize, logger)
+                if err != nil {
+                        return nil, fmt.Errorf("failed to open consensus event log: %w", err)
+                }
+                bc.events = events
         }
 
         // Initialize genesis block
@@ -85,6 +171,13 @@ func NewBlockchain(cfg *config.Config, db storage.Database, logger *utils.Logger
                 })
         }
 
+        // Index blocks committed before the transaction index existed
+        if err := bc.buildTransactionIndex(); err != nil {
+                logger.LogError("blockchain", "build_transaction_index", err, logrus.Fields{
+                        "timestamp": time.Now().UTC(),
+                })
+        }
+
         logger.LogBlockchain("initialized", logrus.Fields{
                 "genesis_hash": bc.genesisBlock.Hash,
                 "latest_block": bc.latestBlock.Hash,
@@ -97,28 +190,72 @@ func NewBlockchain(cfg *config.Config, db storage.Database, logger *utils.Logger
         return bc, nil
 }
 
-// initializeGenesis creates or loads the genesis block
+// initializeGenesis creates or loads the genesis block. A new chain is built
+// from the configured genesis file, or the built-in genesis if none is set.
 func (bc *Blockchain) initializeGenesis() error {
         // Try to load existing genesis block
         genesisBlock, err := bc.db.GetBlockByIndex(0)
         if err != nil {
                 // Create new genesis block
                 bc.logger.LogBlockchain("create_genesis", logrus.Fields{
+                        "genesis_path": bc.config.Genesis.Path,
                         "timestamp": time.Now().UTC(),
                 })
 
-                genesisBlock = bc.blockManager.CreateGenesisBlock()
+                var genesis *Genesis
+                if bc.config.Genesis.Path != "" {
+                        if genesis, err = LoadGenesis(bc.config.Genesis.Path); err != nil {
+                                return err
+                        }
+                        if genesis.ChainID != bc.config.Network.ChainID {
+                                return fmt.Errorf("%w: genesis file is for %s, node is configured for %s", types.ErrChainIDMismatch, genesis.ChainID, bc.config.Network.ChainID)
+                        }
+                        if err := genesis.CheckShards(bc.config.Sharding.NumShards); err != nil {
+                                return fmt.Errorf("invalid genesis file: %w", err)
+                        }
+                        genesisBlock = bc.blockManager.CreateGenesisBlockFrom(genesis)
+                } else {
+                        genesisBlock = bc.blockManager.CreateGenesisBlock()
+                }
+
+                // Check before saving so a mismatched genesis never reaches disk
+                if err := bc.checkGenesisHash(genesisBlock); err != nil {
+                        return err
+                }
 
                 // Save genesis block
                 if err := bc.db.SaveBlock(genesisBlock); err != nil {
                         return fmt.Errorf("failed to save genesis block: %w", err)
                 }
 
+                // Apply genesis allocations
+                for _, tx := range genesisBlock.Transactions {
+                        if err := bc.accountState.ApplyTransaction(tx, genesisBlock.Validator); err != nil {
+                                return fmt.Errorf("failed to apply genesis transaction: %w", err)
+                        }
+                }
+
+                // Register the genesis validator set; loadState picks it up
+                if genesis != nil {
+                        for _, validator := range genesis.InitialValidators() {
+                                if err := bc.db.SaveValidator(validator); err != nil {
+                                        return fmt.Errorf("failed to save genesis validator: %w", err)
+                                }
+                        }
+                }
+
                 bc.logger.LogBlockchain("genesis_saved", logrus.Fields{
                         "genesis_hash": genesisBlock.Hash,
                         "timestamp": time.Now().UTC(),
                 })
         } else {
+                if err := bc.checkGenesisHash(genesisBlock); err != nil {
+                        return err
+                }
+                if err := bc.checkChainID(genesisBlock); err != nil {
+                        return fmt.Errorf("stored genesis: %w", err)
+                }
+
                 bc.logger.LogBlockchain("genesis_loaded", logrus.Fields{
                         "genesis_hash": genesisBlock.Hash,
                         "timestamp": time.Now().UTC(),
@@ -132,6 +269,25 @@ func (bc *Blockchain) initializeGenesis() error {
         return nil
 }
 
+// checkChainID rejects a block stamped with another network's chain ID.
+// Blocks written before chain IDs were recorded carry none and are accepted.
+func (bc *Blockchain) checkChainID(block *types.Block) error {
+        if block.ChainID == "" || block.ChainID == bc.config.Network.ChainID {
+                return nil
+        }
+        return fmt.Errorf("%w: block %d is from %s, expected %s", types.ErrChainIDMismatch, block.Index, block.ChainID, bc.config.Network.ChainID)
+}
+
+// checkGenesisHash rejects a genesis block other than the configured
+// expected one, so a node cannot silently start a different network
+func (bc *Blockchain) checkGenesisHash(genesisBlock *types.Block) error {
+        expected := bc.config.Genesis.ExpectedHash
+        if expected == "" || genesisBlock.Hash == expected {
+                return nil
+        }
+        return fmt.Errorf("genesis hash %s does not match expected %s", genesisBlock.Hash, expected)
+}
+
 // initializeConsensus initializes the consensus algorithm
 func (bc *Blockchain) initializeConsensus() error {
         algorithm := bc.config.Consensus.Algorithm
@@ -145,25 +301,16 @@ func (bc *Blockchain) initializeConsensus() error {
                 "timestamp": time.Now().UTC(),
         })
 
-        var err error
-        switch algorithm {
-        case "pow":
-                bc.consensus, err = consensus.NewProofOfWork(bc.config, bc.logger)
-        case "pos":
-                bc.consensus, err = consensus.NewProofOfStake(bc.config, bc.logger)
-        case "pbft":
-                bc.consensus, err = consensus.NewPBFT(bc.config, bc.logger)
-        case "ppbft":
-                bc.consensus, err = consensus.NewPracticalPBFT(bc.config, bc.logger)
-        case "lscc":
-                bc.consensus, err = consensus.NewLSCC(bc.config, bc.logger)
-        default:
+        if !consensus.IsRegistered(algorithm) {
                 return fmt.Errorf("unsupported consensus algorithm: %s", algorithm)
         }
 
+        engine, err := consensus.New(algorithm, bc.config, bc.logger)
         if err != nil {
                 return fmt.Errorf("failed to initialize consensus: %w", err)
         }
+        bc.attachConsensus(engine)
+        bc.consensus = engine
 
         bc.logger.LogConsensus(algorithm, "initialized", logrus.Fields{
                 "timestamp": time.Now().UTC(),
@@ -172,6 +319,47 @@ func (bc *Blockchain) initializeConsensus() error {
         return nil
 }
 
+// attachConsensus connects a new engine to the node's event log and
+// liveness monitor, hands it the current epoch's validators and, for
+// engines that checkpoint their state, to the database, resuming them from
+// the last checkpoint
+func (bc *Blockchain) attachConsensus(engine consensus.Consensus) {
+        if bc.epochs.current != nil {
+                if err := engine.UpdateValidators(bc.epochs.validators); err != nil {
+                        bc.logger.LogError("consensus", "update_validators", err, logrus.Fields{
+                                "algorithm": engine.GetAlgorithmName(),
+                                "epoch": bc.epochs.current.Number,
+                                "timestamp": time.Now().UTC(),
+                        })
+                }
+        }
+        if recorder, ok := engine.(consensus.EventRecorder); ok && bc.events != nil {
+                recorder.SetEventLog(bc.events)
+        }
+        if recorder, ok := engine.(consensus.ParticipationRecorder); ok {
+                liveness, voters := bc.liveness, bc.voters
+                recorder.SetParticipationHook(func(address string) {
+                        voters.record(address)
+                        if liveness != nil {
+                                liveness.heartbeat(address, time.Now())
+                        }
+                })
+        }
+
+        checkpointer, ok := engine.(consensus.Checkpointer)
+        if !ok {
+                return
+        }
+
+        checkpointer.SetStateStore(bc.db)
+        if err := checkpointer.RestoreFromCheckpoint(); err != nil && !errors.Is(err, consensus.ErrNoCheckpoint) {
+                bc.logger.LogError("consensus", "restore_checkpoint", err, logrus.Fields{
+                        "algorithm": engine.GetAlgorithmName(),
+                        "timestamp": time.Now().UTC(),
+                })
+        }
+}
+
 // loadState loads existing blockchain state from database
 func (bc *Blockchain) loadState() error {
         // Load latest block
@@ -219,34 +407,68 @@ func (bc *Blockchain) StartConsensus() {
         }
 
         bc.isRunning = true
+        if bc.epochsEnabled() && bc.epochs.current == nil {
+                bc.startEpochLocked(bc.blockHeight)
+        }
         bc.logger.LogConsensus(bc.config.Consensus.Algorithm, "start", logrus.Fields{
                 "block_height": bc.blockHeight,
                 "timestamp": time.Now().UTC(),
         })
 
+        bc.loops.Add(1)
         go bc.consensusLoop()
+        if bc.liveness != nil {
+                bc.loops.Add(1)
+                go bc.livenessLoop()
+        }
 }
 
-// StopConsensus stops the consensus process
-func (bc *Blockchain) StopConsensus() {
+// StopConsensus stops new consensus rounds from starting and waits until the
+// round in progress, if any, has committed or abandoned its block. It gives
+// up waiting when ctx is done, returning its error; the round then finishes
+// in the background.
+func (bc *Blockchain) StopConsensus(ctx context.Context) error {
         bc.mu.Lock()
-        defer bc.mu.Unlock()
-
         if !bc.isRunning {
-                return
+                bc.mu.Unlock()
+                return nil
         }
-
         bc.isRunning = false
         close(bc.stopChan)
+        algorithm := bc.config.Consensus.Algorithm
+        bc.mu.Unlock()
+
+        // Rounds take bc.mu to commit, so the wait is outside it
+        stopped := make(chan struct{})
+        go func() {
+                bc.loops.Wait()
+                close(stopped)
+        }()
+
+        select {
+        case <-stopped:
+        case <-ctx.Done():
+                bc.logger.LogError("consensus", "stop", ctx.Err(), logrus.Fields{
+                        "algorithm": algorithm,
+                        "block_height": bc.GetBlockHeight(),
+                        "timestamp": time.Now().UTC(),
+                })
+                return fmt.Errorf("consensus round still in progress: %w", ctx.Err())
+        }
 
-        bc.logger.LogConsensus(bc.config.Consensus.Algorithm, "stop", logrus.Fields{
-                "final_block_height": bc.blockHeight,
+        bc.logger.LogConsensus(algorithm, "stop", logrus.Fields{
+                "final_block_height": bc.GetBlockHeight(),
                 "timestamp": time.Now().UTC(),
         })
+        return nil
 }
 
 // consensusLoop runs the main consensus loop
 func (bc *Blockchain) consensusLoop() {
+        defer bc.loops.Done()
+        if !bc.warmUp() {
+                return
+        }
         ticker := time.NewTicker(time.Duration(bc.config.Consensus.BlockTime) * time.Second)
         defer ticker.Stop()
 
@@ -255,6 +477,13 @@ func (bc *Blockchain) consensusLoop() {
                 case <-bc.stopChan:
                         return
                 case <-ticker.C:
+                        // A stop that arrived with the tick wins, so no
+                        // round starts once StopConsensus is waiting
+                        select {
+                        case <-bc.stopChan:
+                                return
+                        default:
+                        }
                         bc.processConsensusRound()
                 }
         }
@@ -262,54 +491,89 @@ func (bc *Blockchain) consensusLoop() {
 
 // processConsensusRound processes a single consensus round
 func (bc *Blockchain) processConsensusRound() {
+        bc.roundMu.Lock()
+        defer bc.roundMu.Unlock()
+
         startTime := time.Now()
         roundStartTime := startTime
+        // Every log line of the round, and the block it produces, carries this
+        traceID := utils.NewTraceID()
+
+        // The round builds on the head as it is now; a block committed from a
+        // peer meanwhile fails validation against the new head below
+        bc.mu.RLock()
+        parent := bc.latestBlock
+        bc.mu.RUnlock()
 
         bc.logger.LogConsensus(bc.config.Consensus.Algorithm, "round_start", logrus.Fields{
-                "round": bc.blockHeight + 1,
+                "round": parent.Index + 1,
+                "trace_id": traceID,
                 "current_time": startTime,
                 "timestamp": startTime,
         })
 
-        // Get pending transactions from all shards with higher throughput
-        var allTransactions []*types.Transaction
-        for shardID := 0; shardID < bc.config.Sharding.NumShards; shardID++ {
-                shardTransactions := bc.txManager.GetPendingTransactionsForShard(shardID, 500) // 500 per shard = 2000 total max for high TPS
-                allTransactions = append(allTransactions, shardTransactions...)
+        // Drop transactions that outlived the TTL before picking from the pool
+        if expired := bc.txManager.ExpireTransactions(); len(expired) > 0 {
+                for _, txID := range expired {
+                        bc.txStatus.drop(txID, ErrTransactionExpired.Error())
+                }
+                bc.logger.LogBlockchain("transactions_expired", logrus.Fields{
+                        "count": len(expired),
+                        "timestamp": time.Now().UTC(),
+                })
         }
-        transactions := allTransactions
+        bc.txStatus.prune(time.Now().Add(-txStatusRetention))
+
+        transactions := bc.candidateTransactions(parent)
 
         if len(transactions) == 0 {
                 bc.logger.LogConsensus(bc.config.Consensus.Algorithm, "no_transactions", logrus.Fields{
+                        "trace_id": traceID,
                         "timestamp": time.Now().UTC(),
                 })
                 return
         }
 
         // Create new block
-        validator := bc.selectValidator()
-        block, err := bc.blockManager.CreateBlock(bc.latestBlock, transactions, validator, 0)
+        validators := bc.consensusValidators()
+        validator := bc.selectValidator(validators, parent.Index)
+        block, err := bc.blockManager.CreateBlock(parent, transactions, validator, 0)
         if err != nil {
                 bc.logger.LogError("consensus", "create_block", err, logrus.Fields{
                         "validator": validator,
                         "tx_count": len(transactions),
+                        "trace_id": traceID,
                         "timestamp": time.Now().UTC(),
                 })
                 return
         }
+        block.TraceID = traceID
+        bc.txStatus.proposed(transactions, block)
+
+        // Link the requests that submitted the transactions to this round
+        bc.logger.LogConsensus(bc.config.Consensus.Algorithm, "block_proposed", logrus.Fields{
+                "block_hash": block.Hash,
+                "block_index": block.Index,
+                "tx_count": len(transactions),
+                "tx_trace_ids": transactionTraceIDs(transactions),
+                "trace_id": traceID,
+                "timestamp": time.Now().UTC(),
+        })
 
         blockCreationTime := time.Since(startTime)
         startTime = time.Now()
 
         // Run consensus algorithm
+        bc.voters.reset()
         consensusStart := time.Now()
-        approved, err := bc.consensus.ProcessBlock(block, bc.validators)
+        approved, err := bc.consensus.ProcessBlock(block, validators)
         consensusDuration := time.Since(consensusStart)
 
         if err != nil {
                 bc.logger.LogError("consensus", "process_block", err, logrus.Fields{
                         "block_hash": block.Hash,
                         "block_index": block.Index,
+                        "trace_id": traceID,
                         "timestamp": time.Now().UTC(),
                 })
                 return
@@ -320,17 +584,23 @@ func (bc *Blockchain) processConsensusRound() {
                         "block_hash": block.Hash,
                         "block_index": block.Index,
                         "consensus_duration": consensusDuration.Milliseconds(),
+                        "trace_id": traceID,
                         "timestamp": time.Now().UTC(),
                 })
                 return
         }
+        // The voters share the block's rewards with its proposer. They are
+        // part of the header, so the block is hashed again to commit to them.
+        block.Voters = bc.voters.list()
+        block.Hash = block.ComputeHash()
 
         // Validate block
         validationStart := time.Now()
-        if err := bc.blockManager.ValidateBlock(block, bc.latestBlock); err != nil {
+        if err := bc.blockManager.ValidateBlock(block, bc.GetLatestBlock()); err != nil {
                 bc.logger.LogError("consensus", "validate_block", err, logrus.Fields{
                         "block_hash": block.Hash,
                         "block_index": block.Index,
+                        "trace_id": traceID,
                         "timestamp": time.Now().UTC(),
                 })
                 return
@@ -343,11 +613,15 @@ func (bc *Blockchain) processConsensusRound() {
                 bc.logger.LogError("consensus", "add_block", err, logrus.Fields{
                         "block_hash": block.Hash,
                         "block_index": block.Index,
+                        "trace_id": traceID,
                         "timestamp": time.Now().UTC(),
                 })
                 return
         }
         addBlockDuration := time.Since(addBlockStart)
+        if bc.liveness != nil {
+                bc.liveness.heartbeat(block.Validator, time.Now())
+        }
 
         totalRoundDuration := time.Since(roundStartTime)
 
@@ -376,11 +650,38 @@ func (bc *Blockchain) processConsensusRound() {
                 "block_size": block.Size,
                 "gas_used": block.GasUsed,
                 "gas_limit": block.GasLimit,
+                "trace_id": traceID,
                 "timestamp": time.Now().UTC(),
         })
 }
 
-// selectValidator selects a validator for the next block
+// candidateTransactions returns the pending transactions a block on parent
+// would include, in block order
+func (bc *Blockchain) candidateTransactions(parent *types.Block) []*types.Transaction {
+        // Get pending transactions from all shards with higher throughput
+        var allTransactions []*types.Transaction
+        for shardID := 0; shardID < bc.config.Sharding.NumShards; shardID++ {
+                shardTransactions := bc.txManager.GetPendingTransactionsForShard(shardID, 500) // 500 per shard = 2000 total max for high TPS
+                allTransactions = append(allTransactions, shardTransactions...)
+        }
+        return bc.blockManager.FitTransactions(OrderTransactions(bc.selectExecutableTransactions(parent, allTransactions)))
+}
+
+// transactionTraceIDs returns the distinct trace IDs carried by txs, in the
+// order first seen
+func transactionTraceIDs(txs []*types.Transaction) []string {
+        seen := make(map[string]bool)
+        traceIDs := make([]string, 0)
+        for _, tx := range txs {
+                if tx.TraceID == "" || seen[tx.TraceID] {
+                        continue
+                }
+                seen[tx.TraceID] = true
+                traceIDs = append(traceIDs, tx.TraceID)
+        }
+        return traceIDs
+}
+
 // GetCurrentBlock returns the latest block
 func (bc *Blockchain) GetCurrentBlock() *types.Block {
         bc.mu.RLock()
@@ -388,22 +689,41 @@ func (bc *Blockchain) GetCurrentBlock() *types.Block {
         return bc.latestBlock
 }
 
-func (bc *Blockchain) selectValidator() string {
-        if len(bc.validators) == 0 {
+// selectValidator selects a validator for the block after height
+func (bc *Blockchain) selectValidator(validators []*types.Validator, height int64) string {
+        if len(validators) == 0 {
                 return fmt.Sprintf("node-%s", bc.config.Node.ID)
         }
 
         // Simple round-robin selection for now
         // In production, this would be based on the consensus algorithm
-        validatorIndex := bc.blockHeight % int64(len(bc.validators))
-        return bc.validators[validatorIndex].Address
+        validatorIndex := height % int64(len(validators))
+        return validators[validatorIndex].Address
 }
 
-// AddBlock adds a new block to the blockchain
+// AddBlock adds a new block to the blockchain. A block whose parent is not
+// known yet is kept in the orphan pool and ErrOrphanBlock is returned; it is
+// added once its parent is. Orphans waiting for block are added after it.
 func (bc *Blockchain) AddBlock(block *types.Block) error {
         bc.mu.Lock()
-        defer bc.mu.Unlock()
+        defer bc.unlockAndNotify()
+
+        if block.PreviousHash != bc.latestBlock.Hash && !bc.isKnownBlock(block.PreviousHash) {
+                bc.bufferOrphan(block)
+                return fmt.Errorf("%w: parent %s of block %d", ErrOrphanBlock, block.PreviousHash, block.Index)
+        }
 
+        if err := bc.addBlockLocked(block); err != nil {
+                return err
+        }
+
+        bc.connectOrphans(block.Hash)
+        return nil
+}
+
+// addBlockLocked validates block against the current head and commits it.
+// Caller must hold bc.mu.
+func (bc *Blockchain) addBlockLocked(block *types.Block) error {
         startTime := time.Now()
 
         bc.logger.LogBlockchain("add_block", logrus.Fields{
@@ -419,29 +739,123 @@ func (bc *Blockchain) AddBlock(block *types.Block) error {
                 return fmt.Errorf("block validation failed: %w", err)
         }
 
-        // Save block to database
-        if err := bc.db.SaveBlock(block); err != nil {
-                return fmt.Errorf("failed to save block: %w", err)
+        // Reject replayed or out-of-order nonces
+        nonces, err := bc.validateBlockNonces(block)
+        if err != nil {
+                return fmt.Errorf("block validation failed: %w", err)
+        }
+
+        // Reject blocks that would overdraw an account
+        if err := bc.validateBlockBalances(block); err != nil {
+                return fmt.Errorf("block validation failed: %w", err)
         }
 
-        // Save transactions
+        // Stage the block, its transactions, balance changes and nonces so a
+        // crash can never leave a partially committed block
+        batch := storage.NewWriteSet()
         for _, tx := range block.Transactions {
-                if err := bc.db.SaveTransaction(tx); err != nil {
+                if err := batch.PutTransaction(tx); err != nil {
                         bc.logger.LogError("blockchain", "save_transaction", err, logrus.Fields{
                                 "tx_id": tx.ID,
                                 "timestamp": time.Now().UTC(),
                         })
                 }
-                // Mark transaction as confirmed
+                batch.IndexTransaction(tx, block.Hash, block.Index)
+        }
+
+        // Apply balance changes
+        failed := bc.accountState.StageTransactions(batch, block.Transactions, block.BaseFee)
+        failedTxs := make([]string, 0, len(failed))
+        for _, tx := range block.Transactions {
+                if err, exists := failed[tx.ID]; exists {
+                        failedTxs = append(failedTxs, tx.ID)
+                        bc.logger.LogError("blockchain", "apply_transaction", err, logrus.Fields{
+                                "tx_id": tx.ID,
+                                "timestamp": time.Now().UTC(),
+                        })
+                }
+        }
+
+        // Pay the subsidy and the tips to the proposer and voters. The
+        // split is recomputed whatever the block claims, and kept on it so
+        // it is undone exactly if the block leaves the main chain.
+        reward := bc.rewards.BlockReward(block.Index)
+        pot := reward + blockTips(block, failed)
+        payouts := bc.blockPayoutsLocked(block, pot)
+        block.Rewards = payouts
+        block.FailedTxs = nil
+        if len(failedTxs) > 0 {
+                block.FailedTxs = failedTxs
+        }
+        if err := bc.accountState.StageRewards(batch, payouts); err != nil {
+                return fmt.Errorf("failed to credit block rewards: %w", err)
+        }
+        if err := batch.PutBlock(block); err != nil {
+                return fmt.Errorf("failed to save block: %w", err)
+        }
+
+        // The base fees are destroyed rather than paid to anyone
+        burned := blockBurn(block)
+        if burned > 0 {
+                if err := bc.accountState.StageBurn(batch, burned); err != nil {
+                        return fmt.Errorf("failed to record burned fees: %w", err)
+                }
+        }
+
+        // Record the last committed nonce for each sender
+        for address, nonce := range nonces {
+                if err := batch.SetAccountNonce(address, nonce); err != nil {
+                        bc.logger.LogError("blockchain", "save_account_nonce", err, logrus.Fields{
+                                "address": address,
+                                "nonce": nonce,
+                                "timestamp": time.Now().UTC(),
+                        })
+                }
+        }
+
+        if err := bc.db.WriteBatch(batch.Ops()); err != nil {
+                return fmt.Errorf("failed to commit block: %w", err)
+        }
+
+        // Mark transactions as confirmed
+        for _, tx := range block.Transactions {
                 bc.txManager.ConfirmTransaction(tx.ID)
+                bc.txStatus.include(tx.ID, block, failed[tx.ID])
         }
 
         // Update blockchain state
         bc.latestBlock = block
         bc.blockHeight = block.Index
+        bc.txStatus.finalize(bc.finalizedHeight())
         bc.totalTxCount += int64(len(block.Transactions))
+        bc.lastDecision = time.Now()
+
+        if current := bc.epochs.current; current != nil && current.contains(block.Index) {
+                current.Rewards += pot
+        }
+
+        // The last block of an epoch brings in the next validator set
+        var epoch *Epoch
+        if bc.epochsEnabled() && block.Index%bc.config.Consensus.EpochLength == 0 {
+                epoch = bc.startEpochLocked(block.Index)
+        }
 
         duration := time.Since(startTime)
+        algorithm := ""
+        if bc.consensus != nil {
+                algorithm = bc.consensus.GetAlgorithmName()
+        }
+        bc.queueCommitted(block, CommitResult{
+                Algorithm: algorithm,
+                Height: bc.blockHeight,
+                Reward: reward,
+                Payouts: payouts,
+                Burned: burned,
+                FailedTxs: failedTxs,
+                Epoch: epoch,
+                Duration: duration,
+                CommittedAt: bc.lastDecision,
+        })
 
         bc.logger.LogBlockchain("block_added", logrus.Fields{
                 "block_hash": block.Hash,
@@ -449,12 +863,161 @@ func (bc *Blockchain) AddBlock(block *types.Block) error {
                 "new_height": bc.blockHeight,
                 "total_tx_count": bc.totalTxCount,
                 "add_duration": duration.Milliseconds(),
+                "batch_writes": batch.Len(),
+                "tx_trace_ids": transactionTraceIDs(block.Transactions),
+                "trace_id": block.TraceID,
                 "timestamp": time.Now().UTC(),
         })
 
         return nil
 }
 
+// validateBlockNonces checks that every sender's transactions in the block carry
+// consecutive nonces following the last committed one, and returns the new last
+// nonce per sender
+func (bc *Blockchain) validateBlockNonces(block *types.Block) (map[string]int64, error) {
+        nonces := make(map[string]int64)
+
+        for _, tx := range block.Transactions {
+                if tx.Type == "genesis" {
+                        continue
+                }
+
+                last, seen := nonces[tx.From]
+                if !seen {
+                        last = bc.GetAccountNonce(tx.From)
+                }
+
+                if tx.Nonce != last+1 {
+                        return nil, fmt.Errorf("transaction %s has nonce %d, expected %d", tx.ID, tx.Nonce, last+1)
+                }
+                nonces[tx.From] = tx.Nonce
+        }
+
+        return nonces, nil
+}
+
+// validateBlockBalances checks that no sender spends more than its balance
+// across the transactions of the block
+func (bc *Blockchain) validateBlockBalances(block *types.Block) error {
+        balances := make(map[string]int64)
+        balanceOf := func(address string) int64 {
+                if balance, exists := balances[address]; exists {
+                        return balance
+                }
+                return bc.accountState.GetBalance(address)
+        }
+
+        for _, tx := range block.Transactions {
+                if tx.Type == "genesis" {
+                        continue
+                }
+
+                cost := tx.Amount + tx.Fee
+                balance := balanceOf(tx.From)
+                if cost > balance {
+                        return fmt.Errorf("transaction %s overdraws %s: balance %d, cost %d", tx.ID, tx.From, balance, cost)
+                }
+                balances[tx.From] = balance - cost
+
+                // Cross-shard recipients are credited only once the transfer commits
+                if tx.Type != "cross_shard" {
+                        balances[tx.To] = balanceOf(tx.To) + tx.Amount
+                }
+                // Tips are paid out only once every transaction has run
+        }
+
+        return nil
+}
+
+// selectExecutableTransactions orders transactions by sender and nonce and keeps
+// only those that continue each sender's committed nonce sequence without gaps,
+// pay at least the base fee of a block on parent and that the sender can afford
+func (bc *Blockchain) selectExecutableTransactions(parent *types.Block, transactions []*types.Transaction) []*types.Transaction {
+        sort.SliceStable(transactions, func(i, j int) bool {
+                if transactions[i].From != transactions[j].From {
+                        return transactions[i].From < transactions[j].From
+                }
+                return transactions[i].Nonce < transactions[j].Nonce
+        })
+
+        baseFee := bc.feeMarket.NextBaseFee(parent, bc.config.Consensus.MaxTxPerBlock)
+        nextNonce := make(map[string]int64)
+        spendable := make(map[string]int64)
+        selected := make([]*types.Transaction, 0, len(transactions))
+        for _, tx := range transactions {
+                expected, seen := nextNonce[tx.From]
+                if !seen {
+                        expected = bc.GetAccountNonce(tx.From) + 1
+                        spendable[tx.From] = bc.accountState.GetBalance(tx.From)
+                }
+
+                if tx.Nonce != expected || tx.Fee < baseFee || tx.Amount+tx.Fee > spendable[tx.From] {
+                        nextNonce[tx.From] = expected
+                        continue
+                }
+
+                selected = append(selected, tx)
+                nextNonce[tx.From] = expected + 1
+                spendable[tx.From] -= tx.Amount + tx.Fee
+        }
+
+        return selected
+}
+
+// GetAccountNonce returns the last committed nonce for an address
+func (bc *Blockchain) GetAccountNonce(address string) int64 {
+        nonce, err := bc.db.GetAccountNonce(address)
+        if err != nil {
+                bc.logger.LogError("blockchain", "get_account_nonce", err, logrus.Fields{
+                        "address": address,
+                        "timestamp": time.Now().UTC(),
+                })
+                return 0
+        }
+        return nonce
+}
+
+// GetBalance returns the committed balance of an address
+func (bc *Blockchain) GetBalance(address string) int64 {
+        return bc.accountState.GetBalance(address)
+}
+
+// CommitCrossShardTransfer credits the recipient of a cross-shard transaction
+// whose debit has already been committed
+func (bc *Blockchain) CommitCrossShardTransfer(txID string) error {
+        return bc.accountState.CommitTransfer(txID)
+}
+
+// AbortCrossShardTransfer refunds the sender of a cross-shard transaction that
+// could not be delivered
+func (bc *Blockchain) AbortCrossShardTransfer(txID string) error {
+        return bc.accountState.AbortTransfer(txID)
+}
+
+// DropPendingTransaction takes a transaction out of the pool before it is
+// committed, recording it rejected for reason. It reports whether the
+// transaction was pending.
+func (bc *Blockchain) DropPendingTransaction(txID, reason string) bool {
+        if !bc.txManager.FailTransaction(txID, reason) {
+                return false
+        }
+        bc.txStatus.drop(txID, reason)
+        return true
+}
+
+// GetPendingCrossShardTransfer returns the escrowed funds of a cross-shard
+// transaction whose debit has committed but whose credit has not
+func (bc *Blockchain) GetPendingCrossShardTransfer(txID string) (*PendingTransfer, error) {
+        return bc.accountState.GetPendingTransfer(txID)
+}
+
+// GetNextNonce returns the nonce the next transaction from address must use,
+// including transactions still waiting in the pool
+func (bc *Blockchain) GetNextNonce(address string) int64 {
+        return bc.txManager.GetNextNonce(address)
+}
+
 // GetBlock retrieves a block by hash
 func (bc *Blockchain) GetBlock(hash string) (*types.Block, error) {
         return bc.db.GetBlock(hash)
@@ -472,6 +1035,14 @@ func (bc *Blockchain) GetLatestBlock() *types.Block {
         return bc.latestBlock
 }
 
+// GetLastDecisionTime returns when the most recent block was committed, or
+// the start time if none has been committed since startup
+func (bc *Blockchain) GetLastDecisionTime() time.Time {
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+        return bc.lastDecision
+}
+
 // GetGenesisBlock returns the genesis block
 func (bc *Blockchain) GetGenesisBlock() *types.Block {
         return bc.genesisBlock
@@ -484,11 +1055,56 @@ func (bc *Blockchain) GetBlockHeight() int64 {
         return bc.blockHeight
 }
 
+// SetMetricsCollector reports every block committed from now on to collector,
+// along with the consensus engine's own metrics
+func (bc *Blockchain) SetMetricsCollector(collector *metrics.MetricsCollector) {
+        bc.mu.Lock()
+        defer bc.mu.Unlock()
+        if bc.unsubscribeCollector != nil {
+                bc.unsubscribeCollector()
+                bc.unsubscribeCollector = nil
+        }
+        bc.collector = collector
+        if collector == nil {
+                return
+        }
+        if bc.consensus != nil {
+                collector.TrackConsensus(bc.consensus)
+        }
+        if cache, ok := bc.db.(metrics.AccountCacheSource); ok {
+                collector.TrackAccountCache(cache)
+        }
+        bc.unsubscribeCollector = bc.RegisterCommitObserver(BlockCommittedFunc(func(block *types.Block, result CommitResult) {
+                collector.RecordBlockCommitted(len(block.Transactions), result.Duration)
+        }))
+}
+
 // GetTransactionManager returns the transaction manager
 func (bc *Blockchain) GetTransactionManager() *TransactionManager {
         return bc.txManager
 }
 
+// GetFeeEstimate returns the fee floor transactions currently have to meet,
+// the next block's base fee and the fee recommended to clear both
+func (bc *Blockchain) GetFeeEstimate() FeeEstimate {
+        estimate := bc.txManager.GetFeeEstimate()
+        estimate.BaseFee = bc.GetCurrentBaseFee()
+
+        // Enough to stay eligible even if the next block fills up and raises
+        // the base fee before the transaction is included
+        estimate.RecommendedFee = estimate.Floor
+        if ceiling := bc.feeMarket.ceiling(estimate.BaseFee); ceiling > estimate.RecommendedFee {
+                estimate.RecommendedFee = ceiling
+        }
+        return estimate
+}
+
+// GetMempoolStats returns mempool occupancy and the senders with the most
+// transactions pending, at most limit of them
+func (bc *Blockchain) GetMempoolStats(limit int) MempoolStats {
+        return bc.txManager.GetMempoolStats(limit)
+}
+
 // GetTotalTransactionCount returns the total number of transactions across all blocks
 func (bc *Blockchain) GetTotalTransactionCount() int64 {
         bc.mu.RLock()
@@ -498,7 +1114,17 @@ func (bc *Blockchain) GetTotalTransactionCount() int64 {
 
 // SubmitTransaction submits a new transaction
 func (bc *Blockchain) SubmitTransaction(tx *types.Transaction) error {
+        return bc.SubmitTransactionContext(context.Background(), tx)
+}
+
+// SubmitTransactionContext submits a new transaction on behalf of the
+// request in ctx. A transaction without a trace ID takes the one carried by
+// ctx, so it can be followed into the block and consensus round it ends up in.
+func (bc *Blockchain) SubmitTransactionContext(ctx context.Context, tx *types.Transaction) error {
         startTime := time.Now()
+        if tx.TraceID == "" {
+                tx.TraceID = utils.TraceIDFromContext(ctx)
+        }
 
         bc.logger.LogTransaction(tx.ID, "submit", logrus.Fields{
                 "from": tx.From,
@@ -506,22 +1132,40 @@ func (bc *Blockchain) SubmitTransaction(tx *types.Transaction) error {
                 "amount": tx.Amount,
                 "fee": tx.Fee,
                 "type": tx.Type,
+                "trace_id": tx.TraceID,
                 "timestamp": startTime,
         })
 
+        // Reject transactions the sender cannot cover
+        if balance := bc.accountState.GetBalance(tx.From); tx.Amount+tx.Fee > balance {
+                err := fmt.Errorf("insufficient balance: have %d, need %d", balance, tx.Amount+tx.Fee)
+                bc.txStatus.reject(tx.ID, err.Error())
+                bc.logger.LogError("blockchain", "submit_transaction", err, logrus.Fields{
+                        "tx_id": tx.ID,
+                        "from": tx.From,
+                        "trace_id": tx.TraceID,
+                        "timestamp": time.Now().UTC(),
+                })
+                return err
+        }
+
         // Add to transaction pool
         if err := bc.txManager.AddToPool(tx); err != nil {
+                bc.txStatus.reject(tx.ID, err.Error())
                 bc.logger.LogError("blockchain", "submit_transaction", err, logrus.Fields{
                         "tx_id": tx.ID,
+                        "trace_id": tx.TraceID,
                         "timestamp": time.Now().UTC(),
                 })
                 return fmt.Errorf("failed to add transaction to pool: %w", err)
         }
+        bc.txStatus.pending(tx.ID)
 
         duration := time.Since(startTime)
 
         bc.logger.LogTransaction(tx.ID, "submitted", logrus.Fields{
                 "pool_size": bc.txManager.GetPoolStats().Size,
+                "trace_id": tx.TraceID,
                 "submit_duration": duration.Milliseconds(),
                 "timestamp": time.Now().UTC(),
         })
@@ -540,7 +1184,17 @@ func (bc *Blockchain) GetTransaction(txID string) (*types.Transaction, error) {
                 return tx, nil
         }
 
-        // Then check database
+        // Then the block it was committed in, via the transaction index
+        if tx, block, err := bc.indexedTransaction(txID); err == nil {
+                bc.logger.LogTransaction(txID, "retrieved_from_index", logrus.Fields{
+                        "block_hash": block.Hash,
+                        "block_index": block.Index,
+                        "timestamp": time.Now().UTC(),
+                })
+                return tx, nil
+        }
+
+        // Finally fall back to the raw transaction record
         tx, err := bc.db.GetTransaction(txID)
         if err != nil {
                 return nil, fmt.Errorf("transaction not found: %w", err)
@@ -553,9 +1207,151 @@ func (bc *Blockchain) GetTransaction(txID string) (*types.Transaction, error) {
         return tx, nil
 }
 
-// GetTransactionsByAddress retrieves transactions for an address
-func (bc *Blockchain) GetTransactionsByAddress(address string) ([]*types.Transaction, error) {
-        return bc.db.GetTransactionsByAddress(address)
+// GetTransactionBlock returns the main-chain block a transaction was
+// committed in
+func (bc *Blockchain) GetTransactionBlock(txID string) (*types.Block, error) {
+        blockHash, err := bc.db.GetTransactionBlockHash(txID)
+        if err != nil {
+                return nil, err
+        }
+
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+
+        block, onMainChain := bc.mainChainBlock(blockHash)
+        if !onMainChain {
+                return nil, fmt.Errorf("block %s is not on the main chain", blockHash)
+        }
+        return block, nil
+}
+
+// TransactionReceipt reports what became of a transaction
+type TransactionReceipt struct {
+        TxID       string     `json:"tx_id"`
+        Status     string     `json:"status"` // pending, confirmed, failed or expired
+        BlockHash  string     `json:"block_hash,omitempty"`
+        BlockIndex int64      `json:"block_index,omitempty"`
+        ExpiresAt  *time.Time `json:"expires_at,omitempty"` // when a pending or expired transaction runs out its TTL
+}
+
+// GetTransactionReceipt returns the receipt of a transaction known to the
+// pool or committed on the main chain
+func (bc *Blockchain) GetTransactionReceipt(txID string) (*TransactionReceipt, error) {
+        if block, err := bc.GetTransactionBlock(txID); err == nil {
+                return &TransactionReceipt{
+                        TxID:       txID,
+                        Status:     "confirmed",
+                        BlockHash:  block.Hash,
+                        BlockIndex: block.Index,
+                }, nil
+        }
+
+        tx, status := bc.txManager.GetTransaction(txID)
+        if tx == nil {
+                return nil, fmt.Errorf("transaction %s not found", txID)
+        }
+
+        receipt := &TransactionReceipt{TxID: txID, Status: status}
+        if ttl := bc.txManager.TxTTL(); ttl > 0 && (status == "pending" || status == "expired") {
+                // A pending transaction past its TTL is dropped at the next round
+                if tx.Expired(ttl, time.Now()) {
+                        receipt.Status = "expired"
+                }
+                expiresAt := tx.Timestamp.Add(ttl).UTC()
+                receipt.ExpiresAt = &expiresAt
+        }
+        return receipt, nil
+}
+
+// indexedTransaction reads a committed transaction out of the main-chain
+// block the index points at. Genesis allocations only exist inside their
+// block, so this also finds transactions with no standalone record.
+func (bc *Blockchain) indexedTransaction(txID string) (*types.Transaction, *types.Block, error) {
+        block, err := bc.GetTransactionBlock(txID)
+        if err != nil {
+                return nil, nil, err
+        }
+        for _, tx := range block.Transactions {
+                if tx.ID == txID {
+                        return tx, block, nil
+                }
+        }
+        return nil, nil, fmt.Errorf("transaction %s missing from block %s", txID, block.Hash)
+}
+
+// GetTransactionsByAddress retrieves the committed transactions sent or
+// received by an address, newest first. A non-positive limit returns all.
+func (bc *Blockchain) GetTransactionsByAddress(address string, limit int) ([]*types.Transaction, error) {
+        txIDs, err := bc.db.GetTransactionIDsByAddress(address, limit)
+        if err != nil {
+                return nil, fmt.Errorf("failed to read transaction index: %w", err)
+        }
+
+        transactions := make([]*types.Transaction, 0, len(txIDs))
+        for _, txID := range txIDs {
+                tx, err := bc.db.GetTransaction(txID)
+                if err != nil {
+                        if tx, _, err = bc.indexedTransaction(txID); err != nil {
+                                return nil, fmt.Errorf("indexed transaction %s: %w", txID, err)
+                        }
+                }
+                transactions = append(transactions, tx)
+        }
+        return transactions, nil
+}
+
+// txIndexVersionKey marks that every committed block has been indexed.
+// Blocks added afterwards are indexed as part of their commit batch.
+const (
+        txIndexVersionKey = "txindex:version"
+        txIndexVersion = 1
+        txIndexBatchBlocks = 100
+)
+
+// buildTransactionIndex indexes the transactions of every block on the
+// main chain the first time a node starts with an unindexed database
+func (bc *Blockchain) buildTransactionIndex() error {
+        var version int
+        if err := bc.db.GetState(txIndexVersionKey, &version); err == nil && version >= txIndexVersion {
+                return nil
+        }
+
+        startTime := time.Now()
+        indexed := 0
+        batch := storage.NewWriteSet()
+        for height := int64(0); height <= bc.blockHeight; height++ {
+                block, err := bc.db.GetBlockByIndex(height)
+                if err != nil {
+                        return fmt.Errorf("failed to load block %d: %w", height, err)
+                }
+                for _, tx := range block.Transactions {
+                        batch.IndexTransaction(tx, block.Hash, block.Index)
+                        indexed++
+                }
+
+                if (height+1)%txIndexBatchBlocks == 0 {
+                        if err := bc.db.WriteBatch(batch.Ops()); err != nil {
+                                return fmt.Errorf("failed to write transaction index: %w", err)
+                        }
+                        batch = storage.NewWriteSet()
+                }
+        }
+
+        if err := batch.SetState(txIndexVersionKey, txIndexVersion); err != nil {
+                return err
+        }
+        if err := bc.db.WriteBatch(batch.Ops()); err != nil {
+                return fmt.Errorf("failed to write transaction index: %w", err)
+        }
+
+        bc.logger.LogBlockchain("transaction_index_built", logrus.Fields{
+                "block_height": bc.blockHeight,
+                "indexed_transactions": indexed,
+                "duration": time.Since(startTime).Milliseconds(),
+                "timestamp": time.Now().UTC(),
+        })
+
+        return nil
 }
 
 // GetPendingTransactions returns all pending transactions
@@ -580,6 +1376,10 @@ func (bc *Blockchain) AddValidator(validator *types.Validator) error {
                 "timestamp": time.Now().UTC(),
         })
 
+        if err := bc.checkValidatorChangeLocked(append(append([]*types.Validator(nil), bc.validators...), validator)); err != nil {
+                return err
+        }
+
         // Save validator to database
         if err := bc.db.SaveValidator(validator); err != nil {
                 return fmt.Errorf("failed to save validator: %w", err)
@@ -588,6 +1388,12 @@ func (bc *Blockchain) AddValidator(validator *types.Validator) error {
         // Add to validators list
         bc.validators = append(bc.validators, validator)
 
+        // A validator joining waits for the next epoch, unless the current
+        // one has nobody to produce blocks
+        if bc.epochs.current != nil && len(bc.epochs.validators) == 0 {
+                bc.startEpochLocked(bc.blockHeight)
+        }
+
         bc.logger.LogBlockchain("validator_added", logrus.Fields{
                 "validator_address": validator.Address,
                 "total_validators": len(bc.validators),
@@ -597,6 +1403,60 @@ func (bc *Blockchain) AddValidator(validator *types.Validator) error {
         return nil
 }
 
+// RemoveValidator removes the validator with address. Like a validator
+// joining, one leaving takes effect at the next epoch when epochs are on.
+func (bc *Blockchain) RemoveValidator(address string) error {
+        bc.mu.Lock()
+        defer bc.mu.Unlock()
+
+        remaining := make([]*types.Validator, 0, len(bc.validators))
+        for _, validator := range bc.validators {
+                if validator.Address != address {
+                        remaining = append(remaining, validator)
+                }
+        }
+        if len(remaining) == len(bc.validators) {
+                return fmt.Errorf("validator %s not found", address)
+        }
+        if err := bc.checkValidatorChangeLocked(remaining); err != nil {
+                return err
+        }
+
+        if err := bc.db.DeleteValidator(address); err != nil {
+                return fmt.Errorf("failed to delete validator: %w", err)
+        }
+        bc.validators = remaining
+
+        bc.logger.LogBlockchain("validator_removed", logrus.Fields{
+                "validator_address": address,
+                "total_validators": len(bc.validators),
+                "timestamp": time.Now().UTC(),
+        })
+
+        return nil
+}
+
+// CheckValidatorSet applies consensus.byzantine_check to the validator set.
+// Call it once the node's validator set is assembled, from the database,
+// the genesis file or the initial validators; from then on AddValidator
+// and RemoveValidator apply the same check to the set they would leave.
+func (bc *Blockchain) CheckValidatorSet() error {
+        bc.mu.Lock()
+        defer bc.mu.Unlock()
+
+        bc.validatorSetChecked = true
+        return consensus.CheckValidatorSet(bc.config.Consensus.Algorithm, bc.config.Consensus.ByzantineCheck, bc.config.Consensus.Byzantine, bc.validators, bc.logger)
+}
+
+// checkValidatorChangeLocked checks validators, the set a change would
+// leave, once the assembled set has been checked. Caller must hold bc.mu.
+func (bc *Blockchain) checkValidatorChangeLocked(validators []*types.Validator) error {
+        if !bc.validatorSetChecked {
+                return nil
+        }
+        return consensus.CheckValidatorSet(bc.config.Consensus.Algorithm, bc.config.Consensus.ByzantineCheck, bc.config.Consensus.Byzantine, validators, bc.logger)
+}
+
 // GetValidators returns all validators
 func (bc *Blockchain) GetValidators() []*types.Validator {
         bc.mu.RLock()
@@ -627,6 +1487,7 @@ func (bc *Blockchain) GetBlockchainStats() *types.BlockchainStats {
                 TotalShards: bc.config.Sharding.NumShards,
                 AvgBlockTime: avgBlockTime,
                 TPS: tps,
+                OrphanBlocks: bc.orphans.size(),
                 LastUpdate: time.Now().UTC(),
         }
 }
@@ -637,6 +1498,7 @@ func (bc *Blockchain) updateConsensusMetrics(metrics map[string]interface{}) {
         bc.consensusMetrics["timestamp"] = time.Now().UTC()
         bc.consensusMetrics["algorithm"] = bc.config.Consensus.Algorithm
         bc.consensusMetrics["block_height"] = bc.blockHeight
+        bc.consensusMetrics["algorithm_metrics"] = bc.consensus.GetMetrics()
 }
 
 // GetConsensusMetrics returns current consensus metrics
@@ -672,39 +1534,154 @@ func (bc *Blockchain) GetNodeStatus() *types.NodeStatus {
         }
 }
 
-// SwitchConsensusAlgorithm switches to a different consensus algorithm
+// SwitchConsensusAlgorithm replaces the active consensus algorithm with the
+// one registered under algorithm. The swap waits for no round to be in
+// progress: it fails with ErrBlockInProgress if a block is being committed.
+// The outgoing algorithm is reset before the new one is installed.
 func (bc *Blockchain) SwitchConsensusAlgorithm(algorithm string) error {
-        bc.mu.Lock()
-        defer bc.mu.Unlock()
+        if !consensus.IsRegistered(algorithm) {
+                return fmt.Errorf("%w: %s", consensus.ErrUnknownAlgorithm, algorithm)
+        }
 
-        if bc.isRunning {
-                return errors.New("cannot switch consensus algorithm while blockchain is running")
+        // Holding roundMu keeps the consensus loop from starting a new round
+        if !bc.roundMu.TryLock() {
+                return ErrBlockInProgress
         }
+        defer bc.roundMu.Unlock()
+
+        bc.mu.Lock()
+        defer bc.mu.Unlock()
 
         oldAlgorithm := bc.config.Consensus.Algorithm
-        bc.config.Consensus.Algorithm = algorithm
+        if oldAlgorithm == algorithm {
+                return nil
+        }
 
         bc.logger.LogConsensus(algorithm, "switch_algorithm", logrus.Fields{
                 "old_algorithm": oldAlgorithm,
                 "new_algorithm": algorithm,
+                "running": bc.isRunning,
                 "timestamp": time.Now().UTC(),
         })
 
-        // Initialize new consensus
-        if err := bc.initializeConsensus(); err != nil {
-                bc.config.Consensus.Algorithm = oldAlgorithm // Rollback
+        engine, err := consensus.New(algorithm, bc.config, bc.logger)
+        if err != nil {
                 return fmt.Errorf("failed to initialize new consensus: %w", err)
         }
+        bc.attachConsensus(engine)
+
+        if err := bc.consensus.Reset(); err != nil {
+                bc.logger.LogError("consensus", "reset_algorithm", err, logrus.Fields{
+                        "algorithm": oldAlgorithm,
+                        "timestamp": time.Now().UTC(),
+                })
+        }
+
+        bc.consensus = engine
+        bc.config.Consensus.Algorithm = algorithm
+        bc.consensusMetrics = make(map[string]interface{})
+        if bc.collector != nil {
+                bc.collector.TrackConsensus(engine)
+        }
 
         bc.logger.LogConsensus(algorithm, "algorithm_switched", logrus.Fields{
                 "old_algorithm": oldAlgorithm,
                 "new_algorithm": algorithm,
+                "block_height": bc.blockHeight,
                 "timestamp": time.Now().UTC(),
         })
 
         return nil
 }
 
+// GetConsensusEvents returns recorded consensus events matching filter,
+// newest first
+func (bc *Blockchain) GetConsensusEvents(filter consensus.EventFilter) ([]consensus.ConsensusEvent, error) {
+        if bc.events == nil {
+                return nil, ErrEventLogDisabled
+        }
+        return bc.events.Query(filter), nil
+}
+
+// ppbftEngine returns the active engine if it is PPBFT
+func (bc *Blockchain) ppbftEngine() (*consensus.PracticalPBFT, error) {
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+
+        engine, ok := bc.consensus.(*consensus.PracticalPBFT)
+        if !ok {
+                return nil, ErrNoMessageLog
+        }
+        return engine, nil
+}
+
+// SimulateBlock dry-runs an LSCC round on block with the validators of the
+// next round and returns what each phase decided. Nothing is voted on or
+// committed. A nil block stands for the block the next round would propose
+// from the pending transactions; a block without a hash is hashed first.
+func (bc *Blockchain) SimulateBlock(block *types.Block) (*consensus.LSCCSimulation, error) {
+        bc.mu.RLock()
+        engine, ok := bc.consensus.(*consensus.LSCC)
+        latest := bc.latestBlock
+        bc.mu.RUnlock()
+        if !ok {
+                return nil, ErrNoSimulator
+        }
+
+        validators := bc.consensusValidators()
+        if block == nil {
+                candidate, err := bc.blockManager.CreateBlock(latest, bc.candidateTransactions(latest), bc.selectValidator(validators, latest.Index), 0)
+                if err != nil {
+                        return nil, fmt.Errorf("failed to build candidate block: %w", err)
+                }
+                block = candidate
+        }
+        if block.Hash == "" {
+                block.Hash = block.ComputeHash()
+        }
+        return engine.SimulateBlock(block, validators)
+}
+
+// GetPPBFTMessageLog describes the PPBFT message log
+func (bc *Blockchain) GetPPBFTMessageLog() (consensus.MessageLogStats, error) {
+        engine, err := bc.ppbftEngine()
+        if err != nil {
+                return consensus.MessageLogStats{}, err
+        }
+        return engine.MessageLog(), nil
+}
+
+// PrunePPBFTMessageLog drops PPBFT messages below the low watermark and
+// returns how many were removed along with the log as it is afterwards
+func (bc *Blockchain) PrunePPBFTMessageLog() (int, consensus.MessageLogStats, error) {
+        engine, err := bc.ppbftEngine()
+        if err != nil {
+                return 0, consensus.MessageLogStats{}, err
+        }
+        removed := engine.PruneMessageLog()
+        return removed, engine.MessageLog(), nil
+}
+
+// ForcePPBFTCheckpoint has PPBFT checkpoint the current chain height now,
+// voted on by the validators of the next round, and returns the checkpoint
+// state afterwards
+func (bc *Blockchain) ForcePPBFTCheckpoint() (int64, consensus.CheckpointStatus, error) {
+        engine, err := bc.ppbftEngine()
+        if err != nil {
+                return 0, consensus.CheckpointStatus{}, err
+        }
+        height := bc.GetBlockHeight()
+        status, err := engine.ForceCheckpoint(height, bc.consensusValidators())
+        return height, status, err
+}
+
+// GetConsensusAlgorithm returns the name of the active consensus algorithm
+func (bc *Blockchain) GetConsensusAlgorithm() string {
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+        return bc.config.Consensus.Algorithm
+}
+
 // GetDB returns the database instance
 func (bc *Blockchain) GetDB() storage.Database {
         return bc.db
@@ -748,6 +1725,7 @@ func (bc *Blockchain) GetStats() *types.BlockchainStats {
                         }
                         return 0
                 }(),
+                OrphanBlocks: bc.orphans.size(),
                 LastUpdate: time.Now().UTC(),
         }
 }
@@ -865,10 +1843,7 @@ func (bc *Blockchain) ProcessBlock(block *types.Block) error {
 func (bc *Blockchain) stopOtherConsensusAlgorithms() error {
         currentAlg := bc.config.Consensus.Algorithm
 
-        // List of all possible algorithms
-        allAlgorithms := []string{"pow", "pos", "pbft", "ppbft", "lscc"}
-
-        for _, alg := range allAlgorithms {
+        for _, alg := range consensus.RegisteredAlgorithms() {
                 if alg != currentAlg {
                         bc.logger.LogConsensus(alg, "stopping_background_consensus", logrus.Fields{
                                 "current_active": currentAlg,
@@ -907,13 +1882,27 @@ func (bc *Blockchain) ValidateBlock(block *types.Block) error {
                 return errors.New("block validator is empty")
         }
 
-        // Skip hash validation for PoW as it's already validated during mining
-        if bc.config.Consensus.Algorithm != "pow" {
-                // Calculate expected hash for non-PoW algorithms
-                expectedHash := bc.blockManager.CalculateBlockHash(block)
-                if block.Hash != expectedHash {
-                        return fmt.Errorf("block hash mismatch: expected %s, got %s", expectedHash, block.Hash)
-                }
+        if block.ChainID != bc.config.Network.ChainID {
+                return fmt.Errorf("%w: expected %s, got %s", types.ErrChainIDMismatch, bc.config.Network.ChainID, block.ChainID)
+        }
+
+        // Recompute the hash from the block contents; PoW blocks hash the same
+        // way with the mined nonce
+        expectedHash := block.ComputeHash()
+        if block.Hash != expectedHash {
+                return fmt.Errorf("block hash mismatch: expected %s, got %s", expectedHash, block.Hash)
+        }
+
+        if err := block.CheckLimits(bc.config.Consensus.MaxTxPerBlock, bc.config.Consensus.MaxBlockSize); err != nil {
+                return err
+        }
+
+        if err := block.CheckGas(bc.config.Consensus.GasLimit); err != nil {
+                return err
+        }
+
+        if err := CheckTransactionOrder(block.Transactions); err != nil {
+                return err
         }
 
         // Validate transactions
@@ -948,5 +1937,11 @@ func (bc *Blockchain) validateTransaction(tx *types.Transaction) error {
                 return errors.New("transaction fee is negative")
         }
 
+        if tx.Type != "genesis" {
+                if err := utils.VerifyTransactionSender(tx); err != nil {
+                        return fmt.Errorf("invalid signature: %w", err)
+                }
+        }
+
         return nil
 }
\ No newline at end of file
@@ -0,0 +1,81 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"lscc-blockchain/internal/consensus"
+)
+
+// roundMetricsAlgorithm returns the algorithm the last round's metrics were
+// recorded by, and the one reporting the engine metrics
+func roundMetricsAlgorithm(t *testing.T, bc *Blockchain) (string, interface{}) {
+	t.Helper()
+	metrics := bc.GetConsensusMetrics()
+	engine, ok := metrics["algorithm_metrics"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("round recorded no engine metrics: %v", metrics)
+	}
+	algorithm, _ := metrics["algorithm"].(string)
+	return algorithm, engine["algorithm"]
+}
+
+func TestSwitchConsensusAppliesToNextBlocks(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", nil)
+	addValidators(t, bc, 4)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, 1)); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	bc.processConsensusRound()
+	if got := bc.GetBlockHeight(); got != 1 {
+		t.Fatalf("height after the lscc round = %d, want 1", got)
+	}
+	if algorithm, engine := roundMetricsAlgorithm(t, bc); algorithm != "lscc" || engine != "lscc" {
+		t.Fatalf("lscc round recorded metrics of %v/%v", algorithm, engine)
+	}
+
+	if err := bc.SwitchConsensusAlgorithm("pbft"); err != nil {
+		t.Fatalf("failed to switch: %v", err)
+	}
+	if got := bc.GetConsensusAlgorithm(); got != "pbft" {
+		t.Fatalf("active algorithm = %s, want pbft", got)
+	}
+	if len(bc.GetConsensusMetrics()) != 0 {
+		t.Fatal("metrics of the old algorithm kept after the switch")
+	}
+
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, 2)); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	bc.processConsensusRound()
+	if got := bc.GetBlockHeight(); got != 2 {
+		t.Fatalf("height after the pbft round = %d, want 2", got)
+	}
+	if algorithm, engine := roundMetricsAlgorithm(t, bc); algorithm != "pbft" || engine != "pbft" {
+		t.Fatalf("pbft round recorded metrics of %v/%v", algorithm, engine)
+	}
+}
+
+func TestSwitchConsensusRejectsUnknownAlgorithm(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", nil)
+	if err := bc.SwitchConsensusAlgorithm("raft"); !errors.Is(err, consensus.ErrUnknownAlgorithm) {
+		t.Fatalf("expected ErrUnknownAlgorithm, got %v", err)
+	}
+	if got := bc.GetConsensusAlgorithm(); got != "lscc" {
+		t.Fatalf("active algorithm = %s after a refused switch", got)
+	}
+}
+
+func TestSwitchConsensusDuringRound(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", nil)
+	bc.roundMu.Lock()
+	err := bc.SwitchConsensusAlgorithm("pbft")
+	bc.roundMu.Unlock()
+	if !errors.Is(err, ErrBlockInProgress) {
+		t.Fatalf("expected ErrBlockInProgress, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package blockchain
+
+import (
+        "lscc-blockchain/pkg/types"
+)
+
+// maxHalvings is the point at which any int64 subsidy has shifted to zero
+const maxHalvings = 63
+
+// RewardSchedule is the block subsidy: newly issued coins credited to the
+// producer of each block on top of the fees it collects. Blocks
+// 1..HalvingInterval-1 earn InitialReward, and the subsidy halves at every
+// multiple of HalvingInterval, so block HalvingInterval is the first to earn
+// half. The genesis block earns nothing. An InitialReward of zero disables
+// the subsidy and a HalvingInterval of zero never halves it.
+type RewardSchedule struct {
+        InitialReward   int64
+        HalvingInterval int64
+}
+
+// SupplyInfo reports the coins in existence at a height
+type SupplyInfo struct {
+        Height            int64 `json:"height"`
+        GenesisSupply     int64 `json:"genesis_supply"`     // allocated by the genesis block
+        Issued            int64 `json:"issued"`             // block subsidies paid through Height
+        Burned            int64 `json:"burned"`             // base fees destroyed through Height
+        TotalSupply       int64 `json:"total_supply"`       // genesis supply plus issuance, less burned fees
+        BlockReward       int64 `json:"block_reward"`       // subsidy of the next block
+        InitialReward     int64 `json:"initial_reward"`
+        HalvingInterval   int64 `json:"halving_interval"`
+        NextHalvingHeight int64 `json:"next_halving_height,omitempty"` // 0 when the subsidy never halves again
+}
+
+// BlockReward returns the subsidy for the block at height
+func (s RewardSchedule) BlockReward(height int64) int64 {
+        if height <= 0 || s.InitialReward <= 0 {
+                return 0
+        }
+        if s.HalvingInterval <= 0 {
+                return s.InitialReward
+        }
+
+        halvings := height / s.HalvingInterval
+        if halvings >= maxHalvings {
+                return 0
+        }
+        return s.InitialReward >> uint(halvings)
+}
+
+// IssuedThrough returns the subsidies paid to blocks 1..height, summed a
+// halving era at a time
+func (s RewardSchedule) IssuedThrough(height int64) int64 {
+        if height <= 0 || s.InitialReward <= 0 {
+                return 0
+        }
+        if s.HalvingInterval <= 0 {
+                return s.InitialReward * height
+        }
+
+        var issued int64
+        for start := int64(1); start <= height; {
+                reward := s.BlockReward(start)
+                if reward == 0 {
+                        break
+                }
+                // Last block of the era that starts at start
+                end := (start/s.HalvingInterval+1)*s.HalvingInterval - 1
+                if end > height {
+                        end = height
+                }
+                issued += reward * (end - start + 1)
+                start = end + 1
+        }
+        return issued
+}
+
+// nextHalving returns the first height above height at which the subsidy
+// halves, 0 if it never does
+func (s RewardSchedule) nextHalving(height int64) int64 {
+        if s.InitialReward <= 0 || s.HalvingInterval <= 0 {
+                return 0
+        }
+        next := (height/s.HalvingInterval + 1) * s.HalvingInterval
+        if s.BlockReward(next-1) == 0 {
+                return 0
+        }
+        return next
+}
+
+// genesisSupply returns the coins allocated by the genesis block
+func genesisSupply(genesis *types.Block) int64 {
+        if genesis == nil {
+                return 0
+        }
+        var supply int64
+        for _, tx := range genesis.Transactions {
+                if tx.Type == "genesis" {
+                        supply += tx.Amount
+                }
+        }
+        return supply
+}
+
+// GetSupply reports the genesis allocation, the subsidies issued and the
+// base fees burned through the current head
+func (bc *Blockchain) GetSupply() SupplyInfo {
+        bc.mu.RLock()
+        height := bc.blockHeight
+        genesis := bc.genesisBlock
+        bc.mu.RUnlock()
+
+        schedule := bc.rewards
+        info := SupplyInfo{
+                Height:            height,
+                GenesisSupply:     genesisSupply(genesis),
+                Issued:            schedule.IssuedThrough(height),
+                Burned:            bc.accountState.Burned(),
+                BlockReward:       schedule.BlockReward(height + 1),
+                InitialReward:     schedule.InitialReward,
+                HalvingInterval:   schedule.HalvingInterval,
+                NextHalvingHeight: schedule.nextHalving(height),
+        }
+        info.TotalSupply = info.GenesisSupply + info.Issued - info.Burned
+        return info
+}
@@ -0,0 +1,156 @@
+package blockchain
+
+import (
+	"testing"
+
+	"lscc-blockchain/config"
+)
+
+func TestBlockRewardHalvesAtInterval(t *testing.T) {
+	schedule := RewardSchedule{InitialReward: 100, HalvingInterval: 10}
+	tests := []struct {
+		height int64
+		want   int64
+	}{
+		{-1, 0},
+		{0, 0}, // genesis
+		{1, 100},
+		{9, 100},
+		{10, 50},
+		{19, 50},
+		{20, 25},
+		{30, 12},
+		{60, 1},
+		{70, 0},
+		{10 * 63, 0},
+		{10 * 1000, 0},
+	}
+	for _, tt := range tests {
+		if got := schedule.BlockReward(tt.height); got != tt.want {
+			t.Fatalf("reward at height %d = %d, want %d", tt.height, got, tt.want)
+		}
+	}
+
+	if got := (RewardSchedule{InitialReward: 100}).BlockReward(1 << 40); got != 100 {
+		t.Fatalf("reward without halving = %d, want 100", got)
+	}
+	if got := (RewardSchedule{HalvingInterval: 10}).BlockReward(5); got != 0 {
+		t.Fatalf("reward without a subsidy = %d, want 0", got)
+	}
+}
+
+func TestIssuedThroughMatchesSumOfRewards(t *testing.T) {
+	schedules := []RewardSchedule{
+		{InitialReward: 100, HalvingInterval: 10},
+		{InitialReward: 7, HalvingInterval: 3},
+		{InitialReward: 50, HalvingInterval: 1},
+		{InitialReward: 50},
+		{HalvingInterval: 10},
+		{InitialReward: 1 << 40, HalvingInterval: 2},
+	}
+	for _, schedule := range schedules {
+		var sum int64
+		for height := int64(0); height <= 200; height++ {
+			sum += schedule.BlockReward(height)
+			if got := schedule.IssuedThrough(height); got != sum {
+				t.Fatalf("%+v: issued through %d = %d, want %d", schedule, height, got, sum)
+			}
+		}
+	}
+}
+
+func TestNextHalving(t *testing.T) {
+	schedule := RewardSchedule{InitialReward: 4, HalvingInterval: 10}
+	tests := map[int64]int64{0: 10, 9: 10, 10: 20, 25: 30, 29: 30, 30: 0}
+	for height, want := range tests {
+		if got := schedule.nextHalving(height); got != want {
+			t.Fatalf("next halving after %d = %d, want %d", height, got, want)
+		}
+	}
+	if got := (RewardSchedule{InitialReward: 4}).nextHalving(5); got != 0 {
+		t.Fatalf("next halving without an interval = %d, want 0", got)
+	}
+}
+
+func TestCommittedBlocksIssueSchedule(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", func(cfg *config.Config) {
+		cfg.Consensus.BlockReward = 100
+		cfg.Consensus.HalvingInterval = 2
+	})
+	addValidators(t, bc, 4)
+	before := bc.GetSupply()
+
+	var paid int64
+	for i := 0; i < 5; i++ {
+		block, err := bc.blockManager.CreateBlock(bc.GetLatestBlock(), nil, "a_validator", 0)
+		if err != nil {
+			t.Fatalf("failed to create block: %v", err)
+		}
+		if err := bc.AddBlock(block); err != nil {
+			t.Fatalf("failed to add block %d: %v", block.Index, err)
+		}
+		var blockPaid int64
+		for _, amount := range block.Rewards {
+			blockPaid += amount
+		}
+		if want := bc.rewards.BlockReward(block.Index); blockPaid != want {
+			t.Fatalf("block %d paid %d, want the subsidy %d", block.Index, blockPaid, want)
+		}
+		paid += blockPaid
+	}
+
+	// 100 + 50 + 50 + 25 + 25
+	supply := bc.GetSupply()
+	if paid != 250 || supply.Issued != 250 {
+		t.Fatalf("issued %d (paid %d), want 250", supply.Issued, paid)
+	}
+	if supply.TotalSupply != before.TotalSupply+250 {
+		t.Fatalf("total supply %d, want %d", supply.TotalSupply, before.TotalSupply+250)
+	}
+	if supply.BlockReward != 12 || supply.NextHalvingHeight != 6 {
+		t.Fatalf("expected block 6 to be the first to earn 12, got %+v", supply)
+	}
+}
+
+func TestBlockRewardsRecomputedFromHashedVoters(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", func(cfg *config.Config) {
+		cfg.Consensus.BlockReward = 100
+	})
+	addValidators(t, bc, 4)
+
+	block, err := bc.blockManager.CreateBlock(bc.GetLatestBlock(), nil, "a_validator", 0)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+	block.Voters = []string{"b_validator"}
+	block.Hash = block.ComputeHash()
+
+	// Voters are hashed, so adding one afterwards breaks the block
+	tampered := *block
+	tampered.Voters = []string{"b_validator", "c_validator"}
+	if err := bc.AddBlock(&tampered); err == nil {
+		t.Fatal("block with voters added after hashing accepted")
+	}
+
+	// Rewards are not, so a forged split is ignored
+	block.Rewards = map[string]int64{"mallory": 100}
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if balance := bc.GetBalance("mallory"); balance != 0 {
+		t.Fatalf("forged payout credited %d", balance)
+	}
+	if _, ok := block.Rewards["mallory"]; ok || len(block.Rewards) != 2 {
+		t.Fatalf("block rewards %v, want the proposer and its voter", block.Rewards)
+	}
+	var paid int64
+	for address, amount := range block.Rewards {
+		if bc.GetBalance(address) != amount {
+			t.Fatalf("%s paid %d, balance %d", address, amount, bc.GetBalance(address))
+		}
+		paid += amount
+	}
+	if paid != 100 {
+		t.Fatalf("paid %d, want the subsidy 100", paid)
+	}
+}
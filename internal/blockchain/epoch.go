@@ -0,0 +1,176 @@
+package blockchain
+
+import (
+        "errors"
+        "lscc-blockchain/pkg/types"
+        "sort"
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// ErrEpochsDisabled is returned when the epoch is requested on a node
+// configured without validator epochs
+var ErrEpochsDisabled = errors.New("validator epochs are disabled")
+
+// maxEpochHistory bounds the finished epochs kept for GetEpochs
+const maxEpochHistory = 64
+
+// EpochValidator is one member of an epoch's active validator set
+type EpochValidator struct {
+        Address string `json:"address"`
+        Stake   int64  `json:"stake"`
+}
+
+// Epoch is a run of consensus.epoch_length blocks produced by one validator
+// set. The set is chosen when the epoch starts and holds until it ends,
+// whatever happens to the validators in between.
+type Epoch struct {
+        Number      int64            `json:"number"`
+        StartHeight int64            `json:"start_height"` // first block of the epoch
+        EndHeight   int64            `json:"end_height"`   // last block; the set is recomputed once it is committed
+        Validators  []EpochValidator `json:"validators"`   // highest stake first
+        TotalStake  int64            `json:"total_stake"`
+        Excluded    int              `json:"excluded"` // validators left out as jailed, under min_stake or beyond max_validators
+        Rewards     int64            `json:"rewards"`  // subsidy and tips paid to the set by the epoch's blocks so far
+        StartedAt   time.Time        `json:"started_at"`
+}
+
+// contains reports whether the block at height belongs to the epoch
+func (e *Epoch) contains(height int64) bool {
+        return height >= e.StartHeight && height <= e.EndHeight
+}
+
+// epochState is the current epoch and the validators it admitted
+type epochState struct {
+        current    *Epoch
+        validators []*types.Validator
+        history    []*Epoch // finished epochs, oldest first
+}
+
+// epochsEnabled reports whether the validator set rotates
+func (bc *Blockchain) epochsEnabled() bool {
+        return bc.config.Consensus.EpochLength > 0
+}
+
+// epochFor returns the number of the epoch holding the block after height
+func (bc *Blockchain) epochFor(height int64) int64 {
+        return height / bc.config.Consensus.EpochLength
+}
+
+// selectEpochValidators returns the validators eligible for the next epoch,
+// highest stake first, and how many were left out. Jailed validators and
+// those staking less than min_stake are never eligible; inactive ones are,
+// as liveness monitoring leaves them out of each round on its own. Caller
+// must hold bc.mu.
+func (bc *Blockchain) selectEpochValidators() ([]*types.Validator, int) {
+        eligible := make([]*types.Validator, 0, len(bc.validators))
+        for _, validator := range bc.validators {
+                if validator.Status != validatorActive && validator.Status != validatorInactive {
+                        continue
+                }
+                if validator.Stake < bc.config.Consensus.MinStake {
+                        continue
+                }
+                eligible = append(eligible, validator)
+        }
+
+        sort.SliceStable(eligible, func(i, j int) bool {
+                if eligible[i].Stake != eligible[j].Stake {
+                        return eligible[i].Stake > eligible[j].Stake
+                }
+                return eligible[i].Address < eligible[j].Address
+        })
+        if limit := bc.config.Consensus.MaxValidators; limit > 0 && len(eligible) > limit {
+                eligible = eligible[:limit]
+        }
+        return eligible, len(bc.validators) - len(eligible)
+}
+
+// startEpochLocked recomputes the validator set for the epoch holding the
+// block after height, hands it to the consensus engine and returns the new
+// epoch. Restarting the current epoch, as after a validator joins an empty
+// set, replaces it without adding it to the history. Caller must hold bc.mu.
+func (bc *Blockchain) startEpochLocked(height int64) *Epoch {
+        validators, excluded := bc.selectEpochValidators()
+        number := bc.epochFor(height)
+        length := bc.config.Consensus.EpochLength
+
+        epoch := &Epoch{
+                Number:      number,
+                StartHeight: number*length + 1,
+                EndHeight:   (number + 1) * length,
+                Validators:  make([]EpochValidator, 0, len(validators)),
+                Excluded:    excluded,
+                StartedAt:   time.Now().UTC(),
+        }
+        for _, validator := range validators {
+                epoch.Validators = append(epoch.Validators, EpochValidator{Address: validator.Address, Stake: validator.Stake})
+                epoch.TotalStake += validator.Stake
+        }
+
+        if previous := bc.epochs.current; previous != nil && previous.Number != number {
+                bc.epochs.history = append(bc.epochs.history, previous)
+                if len(bc.epochs.history) > maxEpochHistory {
+                        bc.epochs.history = bc.epochs.history[len(bc.epochs.history)-maxEpochHistory:]
+                }
+        }
+        bc.epochs.current = epoch
+        bc.epochs.validators = validators
+
+        if bc.consensus != nil {
+                if err := bc.consensus.UpdateValidators(validators); err != nil {
+                        bc.logger.LogError("consensus", "update_validators", err, logrus.Fields{
+                                "epoch": number,
+                                "timestamp": time.Now().UTC(),
+                        })
+                }
+        }
+
+        bc.logger.LogBlockchain("epoch_started", logrus.Fields{
+                "epoch": number,
+                "start_height": epoch.StartHeight,
+                "end_height": epoch.EndHeight,
+                "validators": len(validators),
+                "excluded": excluded,
+                "total_stake": epoch.TotalStake,
+                "timestamp": epoch.StartedAt,
+        })
+        return epoch
+}
+
+// GetEpoch returns the current epoch
+func (bc *Blockchain) GetEpoch() (*Epoch, error) {
+        if !bc.epochsEnabled() {
+                return nil, ErrEpochsDisabled
+        }
+
+        bc.mu.Lock()
+        defer bc.mu.Unlock()
+        if bc.epochs.current == nil {
+                bc.startEpochLocked(bc.blockHeight)
+        }
+        epoch := *bc.epochs.current
+        return &epoch, nil
+}
+
+// GetEpochs returns up to limit finished epochs, most recent first. A
+// non-positive limit returns every one kept.
+func (bc *Blockchain) GetEpochs(limit int) ([]*Epoch, error) {
+        if !bc.epochsEnabled() {
+                return nil, ErrEpochsDisabled
+        }
+
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+        history := bc.epochs.history
+        if limit <= 0 || limit > len(history) {
+                limit = len(history)
+        }
+        epochs := make([]*Epoch, 0, limit)
+        for i := len(history) - 1; i >= len(history)-limit; i-- {
+                epoch := *history[i]
+                epochs = append(epochs, &epoch)
+        }
+        return epochs, nil
+}
@@ -0,0 +1,104 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+)
+
+// agedTransfer returns a signed transfer timestamped age ago
+func agedTransfer(t *testing.T, from, to testAccount, nonce int64, age time.Duration) *types.Transaction {
+	t.Helper()
+	tx := signedTransfer(t, from, to, 10, 10, nonce)
+	tx.Timestamp = time.Now().Add(-age)
+	tx.ID = tx.Hash()
+	if err := utils.SignTransaction(tx, from.key); err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+func TestStaleTransactionRefusedAsExpired(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", nil)
+	bc.txManager.SetTxTTL(time.Minute)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	tx := agedTransfer(t, sender, recipient, 1, 2*time.Minute)
+	if err := bc.SubmitTransaction(tx); !errors.Is(err, ErrTransactionExpired) {
+		t.Fatalf("expected ErrTransactionExpired, got %v", err)
+	}
+	if pending := bc.txManager.GetPendingTransactions(); len(pending) != 0 {
+		t.Fatalf("expired transaction entered the pool: %d pending", len(pending))
+	}
+
+	receipt, err := bc.GetTransactionReceipt(tx.ID)
+	if err != nil {
+		t.Fatalf("no receipt for the expired transaction: %v", err)
+	}
+	if receipt.Status != "expired" || receipt.ExpiresAt == nil || !receipt.ExpiresAt.Equal(tx.Timestamp.Add(time.Minute).UTC()) {
+		t.Fatalf("expected an expired receipt with its expiry time, got %+v", receipt)
+	}
+}
+
+func TestPendingTransactionExpires(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", nil)
+	addValidators(t, bc, 4)
+	bc.txManager.SetTxTTL(time.Second)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	tx := agedTransfer(t, sender, recipient, 1, 800*time.Millisecond)
+	if err := bc.SubmitTransaction(tx); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if receipt, err := bc.GetTransactionReceipt(tx.ID); err != nil || receipt.Status != "pending" {
+		t.Fatalf("expected a pending receipt, got %+v, %v", receipt, err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	// Past its TTL the receipt says so even before the pool drops it
+	if receipt, err := bc.GetTransactionReceipt(tx.ID); err != nil || receipt.Status != "expired" {
+		t.Fatalf("expected an expired receipt, got %+v, %v", receipt, err)
+	}
+	if candidates := bc.candidateTransactions(bc.GetLatestBlock()); len(candidates) != 0 {
+		t.Fatalf("expired transaction offered for a block: %d candidates", len(candidates))
+	}
+
+	bc.processConsensusRound()
+	if height := bc.GetBlockHeight(); height != 0 {
+		t.Fatalf("expired transaction committed at height %d", height)
+	}
+	if _, status := bc.txManager.GetTransaction(tx.ID); status != "expired" {
+		t.Fatalf("expected the pool to hold the transaction as expired, got %q", status)
+	}
+	status, err := bc.GetTxStatus(tx.ID)
+	if err != nil {
+		t.Fatalf("no status: %v", err)
+	}
+	if status.Status != TxRejected || status.Reason != ErrTransactionExpired.Error() {
+		t.Fatalf("expected the transaction rejected as expired, got %+v", status)
+	}
+}
+
+func TestNoTxTTLNeverExpires(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", nil)
+	bc.txManager.SetTxTTL(0)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	tx := agedTransfer(t, sender, recipient, 1, 24*time.Hour)
+	if err := bc.SubmitTransaction(tx); err != nil {
+		t.Fatalf("expected an old transaction accepted without a TTL, got %v", err)
+	}
+	if expired := bc.txManager.ExpireTransactions(); len(expired) != 0 {
+		t.Fatalf("expired %v without a TTL", expired)
+	}
+}
@@ -0,0 +1,124 @@
+package blockchain
+
+import (
+        "lscc-blockchain/pkg/types"
+        "math"
+)
+
+// supplyBurnedKey holds the base fees burned by the blocks on the main chain
+const supplyBurnedKey = "supply:burned"
+
+// FeeMarket sets the base fee every transaction in a block must pay, in the
+// manner of EIP-1559. Each block's base fee follows from its parent's: it
+// holds steady when the parent was TargetUtilization full, rises when the
+// parent was fuller and falls when it was emptier, moving by up to
+// 1/ChangeDenominator per block, rounded up, and never below MinBaseFee. A
+// block's fullness is the larger of its share of the gas limit and of the
+// transaction limit. The first block priced by the market, or the first
+// after one whose header carries no base fee, pays InitialBaseFee.
+//
+// The base fee part of each fee is burned; only what a transaction pays
+// above it, its tip, is paid to the block's proposer and voters. A disabled
+// market charges no base fee and leaves headers as they were.
+type FeeMarket struct {
+        Enabled           bool
+        InitialBaseFee    int64
+        MinBaseFee        int64
+        TargetUtilization float64
+        ChangeDenominator int64
+}
+
+// NextBaseFee returns the base fee of the block after parent, in a chain
+// allowing maxTxs transactions per block
+func (m FeeMarket) NextBaseFee(parent *types.Block, maxTxs int) int64 {
+        if !m.Enabled {
+                return 0
+        }
+        if parent == nil || parent.BaseFee <= 0 {
+                return m.clamp(m.InitialBaseFee)
+        }
+
+        utilization := blockUtilization(parent, maxTxs)
+        target := m.TargetUtilization
+        if target <= 0 || target >= 1 {
+                target = 0.5
+        }
+        denominator := m.ChangeDenominator
+        if denominator < 1 {
+                denominator = 1
+        }
+
+        // Rounded up, so a small base fee still moves
+        base := parent.BaseFee
+        delta := int64(math.Ceil(float64(base) * math.Abs(utilization-target) / target / float64(denominator)))
+        switch {
+        case utilization > target:
+                if base > math.MaxInt64-delta {
+                        return math.MaxInt64
+                }
+                return m.clamp(base + delta)
+        case utilization < target:
+                return m.clamp(base - delta)
+        default:
+                return m.clamp(base)
+        }
+}
+
+// ceiling returns the highest base fee a block after one charging baseFee
+// can charge: what a completely full block would raise it to
+func (m FeeMarket) ceiling(baseFee int64) int64 {
+        if !m.Enabled {
+                return 0
+        }
+        full := &types.Block{BaseFee: baseFee, GasUsed: 1, GasLimit: 1}
+        return m.NextBaseFee(full, 0)
+}
+
+func (m FeeMarket) clamp(baseFee int64) int64 {
+        if baseFee < m.MinBaseFee {
+                return m.MinBaseFee
+        }
+        return baseFee
+}
+
+// blockUtilization returns how full block is, from 0 to 1: the larger of
+// its share of its gas limit and of maxTxs transactions
+func blockUtilization(block *types.Block, maxTxs int) float64 {
+        utilization := 0.0
+        if block.GasLimit > 0 {
+                utilization = float64(block.GasUsed) / float64(block.GasLimit)
+        }
+        if maxTxs > 0 {
+                utilization = math.Max(utilization, float64(len(block.Transactions))/float64(maxTxs))
+        }
+        return math.Min(utilization, 1)
+}
+
+// burnedFee returns the part of tx's fee burned in a block charging
+// baseFee. Genesis allocations pay nothing.
+func burnedFee(tx *types.Transaction, baseFee int64) int64 {
+        if tx.Type == "genesis" || baseFee <= 0 {
+                return 0
+        }
+        if tx.Fee < baseFee {
+                return tx.Fee
+        }
+        return baseFee
+}
+
+// blockBurn returns the base fees burned by the transactions of block
+func blockBurn(block *types.Block) int64 {
+        var burned int64
+        for _, tx := range block.Transactions {
+                burned += burnedFee(tx, block.BaseFee)
+        }
+        return burned
+}
+
+// GetCurrentBaseFee returns the base fee the next block will charge each
+// of its transactions, 0 when the fee market is disabled
+func (bc *Blockchain) GetCurrentBaseFee() int64 {
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+        return bc.feeMarket.NextBaseFee(bc.latestBlock, bc.config.Consensus.MaxTxPerBlock)
+}
@@ -0,0 +1,69 @@
+package blockchain
+
+import (
+        "errors"
+        "math"
+)
+
+// ErrFeeTooLow is returned when a transaction pays less than the current fee floor
+var ErrFeeTooLow = errors.New("transaction fee is below the current floor")
+
+// FeePolicy sets the lowest fee the mempool accepts.
+//
+// While the pool is at or below CongestionTarget of its capacity the floor
+// is MinFee. Beyond that it rises linearly with occupancy, reaching
+// MinFee*MaxMultiplier when the pool is full, so senders are priced out as
+// space runs short and the floor relaxes again as blocks drain the pool.
+// A MinFee of zero disables the floor.
+type FeePolicy struct {
+        MinFee           int64
+        CongestionTarget float64 // pool occupancy, 0-1, where the floor starts to rise
+        MaxMultiplier    float64 // floor at a full pool, as a multiple of MinFee
+}
+
+// FeeEstimate describes the fee floor and the pool state it was derived
+// from, and the fee market's base fee
+type FeeEstimate struct {
+        Floor            int64   `json:"floor"`
+        MinFee           int64   `json:"min_fee"`
+        BaseFee          int64   `json:"base_fee"`        // charged by the next block, 0 without a fee market
+        RecommendedFee   int64   `json:"recommended_fee"` // meets the floor and the base fee after one more full block
+        PendingCount     int     `json:"pending_count"`
+        PoolCapacity     int     `json:"pool_capacity"`
+        Utilization      float64 `json:"utilization"`
+        CongestionTarget float64 `json:"congestion_target"`
+        Congested        bool    `json:"congested"`
+}
+
+// Floor returns the minimum fee for a pool holding pending of capacity transactions
+func (p FeePolicy) Floor(pending, capacity int) int64 {
+        if p.MinFee <= 0 || capacity <= 0 {
+                return p.MinFee
+        }
+
+        utilization := math.Min(float64(pending)/float64(capacity), 1)
+        if utilization <= p.CongestionTarget || p.CongestionTarget >= 1 {
+                return p.MinFee
+        }
+
+        pressure := (utilization - p.CongestionTarget) / (1 - p.CongestionTarget)
+        multiplier := 1 + (math.Max(p.MaxMultiplier, 1)-1)*pressure
+        return int64(math.Ceil(float64(p.MinFee) * multiplier))
+}
+
+// estimate reports the floor for a pool holding pending of capacity transactions
+func (p FeePolicy) estimate(pending, capacity int) FeeEstimate {
+        utilization := 0.0
+        if capacity > 0 {
+                utilization = float64(pending) / float64(capacity)
+        }
+        return FeeEstimate{
+                Floor:            p.Floor(pending, capacity),
+                MinFee:           p.MinFee,
+                PendingCount:     pending,
+                PoolCapacity:     capacity,
+                Utilization:      utilization,
+                CongestionTarget: p.CongestionTarget,
+                Congested:        utilization > p.CongestionTarget,
+        }
+}
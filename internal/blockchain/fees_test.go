@@ -0,0 +1,84 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"lscc-blockchain/config"
+)
+
+func TestFeeFloorRisesWithOccupancy(t *testing.T) {
+	policy := FeePolicy{MinFee: 10, CongestionTarget: 0.5, MaxMultiplier: 3}
+	tests := []struct {
+		pending int
+		want    int64
+	}{
+		{0, 10},
+		{50, 10},  // at the target
+		{75, 20},  // halfway to full: 1 + 2*0.5
+		{100, 30}, // full: MinFee*MaxMultiplier
+		{150, 30}, // never beyond full
+	}
+	for _, tt := range tests {
+		if got := policy.Floor(tt.pending, 100); got != tt.want {
+			t.Errorf("floor with %d of 100 pending = %d, want %d", tt.pending, got, tt.want)
+		}
+	}
+
+	if got := (FeePolicy{MinFee: 0, CongestionTarget: 0.5, MaxMultiplier: 3}).Floor(100, 100); got != 0 {
+		t.Fatalf("disabled floor = %d, want 0", got)
+	}
+}
+
+func TestUnderpricedTransactionRejected(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", func(cfg *config.Config) {
+		cfg.Mempool.MinFee = 5
+	})
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	cheap := signedTransfer(t, sender, recipient, 10, 4, 1)
+	if err := bc.SubmitTransaction(cheap); !errors.Is(err, ErrFeeTooLow) {
+		t.Fatalf("fee below the floor: got %v", err)
+	}
+	if status, err := bc.GetTxStatus(cheap.ID); err != nil || status.Status != TxRejected {
+		t.Fatalf("underpriced transaction status = %+v, %v", status, err)
+	}
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 5, 1)); err != nil {
+		t.Fatalf("fee at the floor rejected: %v", err)
+	}
+	if got := bc.GetFeeEstimate().Floor; got != 5 {
+		t.Fatalf("estimated floor = %d, want 5", got)
+	}
+}
+
+func TestFeeFloorRisesUnderCongestion(t *testing.T) {
+	tm := NewTransactionManager(10, discardLogger())
+	tm.SetFeePolicy(FeePolicy{MinFee: 10, CongestionTarget: 0.5, MaxMultiplier: 3})
+
+	// Separate senders keep admission limits and nonces out of the way
+	submit := func(fee int64) error {
+		sender, recipient := newTestAccount(t), newTestAccount(t)
+		return tm.AddToPool(signedTransfer(t, sender, recipient, 1, fee, 1))
+	}
+	for i := 0; i < 6; i++ {
+		if err := submit(10); err != nil {
+			t.Fatalf("transaction %d at the base floor rejected: %v", i, err)
+		}
+	}
+
+	estimate := tm.GetFeeEstimate()
+	if !estimate.Congested || estimate.Floor != 14 {
+		t.Fatalf("estimate with 6 of 10 pending = %+v, want a congested floor of 14", estimate)
+	}
+	if err := submit(10); !errors.Is(err, ErrFeeTooLow) {
+		t.Fatalf("base fee in a congested pool: got %v", err)
+	}
+	if err := submit(14); err != nil {
+		t.Fatalf("fee at the raised floor rejected: %v", err)
+	}
+	if got := tm.GetFeeEstimate().Floor; got != 18 {
+		t.Fatalf("floor with 7 of 10 pending = %d, want 18", got)
+	}
+}
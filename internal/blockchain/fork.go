@@ -0,0 +1,563 @@
+package blockchain
+
+import (
+        "errors"
+        "fmt"
+        "lscc-blockchain/internal/storage"
+        "lscc-blockchain/pkg/types"
+        "sort"
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// ErrReorgBelowFinality is returned when a better branch forks off below the
+// finalized height and therefore cannot replace the main chain
+var ErrReorgBelowFinality = errors.New("reorganization below finalized height")
+
+// ErrOrphanBlock is returned when a block is buffered because its parent is
+// not known yet
+var ErrOrphanBlock = errors.New("block parent is unknown")
+
+// maxSideBlocks bounds the number of fork and orphan blocks kept in memory
+const maxSideBlocks = 256
+
+// BranchTip describes the head of a side branch competing with the main chain
+type BranchTip struct {
+        Hash      string `json:"hash"`
+        Index     int64  `json:"index"`
+        ForkPoint int64  `json:"fork_point"` // height of the main-chain block the branch builds on
+        Weight    int64  `json:"weight"`     // fork-choice weight of the branch above the fork point
+}
+
+// ResolveFork accepts a block that does not necessarily extend the current
+// head and applies the fork-choice rule. Blocks whose parent is unknown are
+// buffered as orphans until it arrives. Blocks on a side branch are kept, and
+// when that branch is preferred over the main chain the chain is reorganized
+// onto it: blocks back to the common ancestor are rolled back, the branch is
+// applied in their place, and transactions only the old branch contained
+// return to the pool.
+//
+// The preferred branch is the one with the highest cumulative weight (see
+// blockWeight). Between branches of equal weight, the one whose first block
+// after the fork point has the earlier timestamp wins, then the lower block
+// hash, so every node picks the same branch whatever order blocks arrive in.
+//
+// adopted reports whether candidate is on the main chain afterwards.
+func (bc *Blockchain) ResolveFork(candidate *types.Block) (adopted bool, err error) {
+        bc.mu.Lock()
+        defer bc.unlockAndNotify()
+
+        if !candidate.VerifyHash() {
+                return false, fmt.Errorf("block hash mismatch: expected %s, got %s", candidate.ComputeHash(), candidate.Hash)
+        }
+
+        if !bc.isKnownBlock(candidate.Hash) {
+                if err := bc.connectBlock(candidate); err != nil {
+                        return false, err
+                }
+
+                // Blocks that were waiting for this one can now be connected
+                bc.connectOrphans(candidate.Hash)
+        }
+
+        _, adopted = bc.mainChainBlock(candidate.Hash)
+        return adopted, nil
+}
+
+// HandleCompetingBlock accepts a block received from a peer that does not
+// necessarily extend the current head, as ResolveFork does
+func (bc *Blockchain) HandleCompetingBlock(block *types.Block) error {
+        _, err := bc.ResolveFork(block)
+        return err
+}
+
+// GetBranchTips returns the heads of the side branches currently competing
+// with the main chain, heaviest first
+func (bc *Blockchain) GetBranchTips() []BranchTip {
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+
+        hasChild := make(map[string]bool, len(bc.forkBlocks))
+        for _, block := range bc.forkBlocks {
+                hasChild[block.PreviousHash] = true
+        }
+
+        tips := make([]BranchTip, 0)
+        for hash, block := range bc.forkBlocks {
+                if hasChild[hash] {
+                        continue
+                }
+                branch, ancestor, err := bc.branchTo(block)
+                if err != nil {
+                        continue
+                }
+                tips = append(tips, BranchTip{
+                        Hash:      hash,
+                        Index:     block.Index,
+                        ForkPoint: ancestor.Index,
+                        Weight:    bc.branchWeight(branch),
+                })
+        }
+
+        sort.Slice(tips, func(i, j int) bool {
+                if tips[i].Weight != tips[j].Weight {
+                        return tips[i].Weight > tips[j].Weight
+                }
+                return tips[i].Hash < tips[j].Hash
+        })
+        return tips
+}
+
+// AddOrphan accepts a block that may have arrived before its parent. A block
+// whose parent is unknown is buffered in the bounded orphan pool, evicting
+// the oldest orphan when full, and is connected as soon as its parent is
+// added; otherwise it is connected right away as by ResolveFork.
+func (bc *Blockchain) AddOrphan(block *types.Block) error {
+        _, err := bc.ResolveFork(block)
+        return err
+}
+
+// GetOrphanCount returns the number of blocks waiting for their parent
+func (bc *Blockchain) GetOrphanCount() int {
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+        return bc.orphans.size()
+}
+
+// GetFinalizedHeight returns the height at and below which blocks can no
+// longer be reorganized
+func (bc *Blockchain) GetFinalizedHeight() int64 {
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+        return bc.finalizedHeight()
+}
+
+// finalizedHeight returns the finalized height. Caller must hold bc.mu.
+func (bc *Blockchain) finalizedHeight() int64 {
+        finalized := bc.blockHeight - bc.config.Consensus.FinalityDepth
+        if finalized < 0 {
+                return 0
+        }
+        return finalized
+}
+
+// connectBlock places a block on the main chain, on a side branch or in the
+// orphan pool. Caller must hold bc.mu.
+func (bc *Blockchain) connectBlock(block *types.Block) error {
+        if block.PreviousHash == bc.latestBlock.Hash {
+                return bc.addBlockLocked(block)
+        }
+
+        parent, found := bc.findBlock(block.PreviousHash)
+        if !found {
+                bc.bufferOrphan(block)
+                return nil
+        }
+
+        if err := bc.blockManager.ValidateBlock(block, parent); err != nil {
+                return fmt.Errorf("block validation failed: %w", err)
+        }
+
+        if err := bc.addForkBlock(block); err != nil {
+                return err
+        }
+
+        return bc.maybeReorganize(block)
+}
+
+// connectOrphans connects buffered orphans descending from parentHash.
+// Caller must hold bc.mu.
+func (bc *Blockchain) connectOrphans(parentHash string) {
+        for _, child := range bc.orphans.takeChildren(parentHash) {
+                if err := bc.connectBlock(child); err != nil {
+                        bc.logger.LogError("blockchain", "connect_orphan", err, logrus.Fields{
+                                "block_hash": child.Hash,
+                                "block_index": child.Index,
+                                "timestamp": time.Now().UTC(),
+                        })
+                        continue
+                }
+                bc.connectOrphans(child.Hash)
+        }
+}
+
+// bufferOrphan keeps a block whose parent is unknown. Caller must hold bc.mu.
+func (bc *Blockchain) bufferOrphan(block *types.Block) {
+        if evicted := bc.orphans.add(block); evicted != nil {
+                bc.logger.LogBlockchain("orphan_evicted", logrus.Fields{
+                        "block_hash": evicted.Hash,
+                        "block_index": evicted.Index,
+                        "timestamp": time.Now().UTC(),
+                })
+        }
+
+        bc.logger.LogBlockchain("orphan_buffered", logrus.Fields{
+                "block_hash": block.Hash,
+                "block_index": block.Index,
+                "previous_hash": block.PreviousHash,
+                "orphan_count": bc.orphans.size(),
+                "timestamp": time.Now().UTC(),
+        })
+
+        // A block from beyond the next height means blocks were missed
+        bc.noteGap(block)
+}
+
+// addForkBlock records a block on a side branch, pruning branches that can no
+// longer win once the buffer is full. Caller must hold bc.mu.
+func (bc *Blockchain) addForkBlock(block *types.Block) error {
+        if len(bc.forkBlocks) >= maxSideBlocks {
+                finalized := bc.finalizedHeight()
+                for hash, forkBlock := range bc.forkBlocks {
+                        if forkBlock.Index <= finalized {
+                                delete(bc.forkBlocks, hash)
+                        }
+                }
+                if len(bc.forkBlocks) >= maxSideBlocks {
+                        return fmt.Errorf("fork buffer is full (%d blocks)", len(bc.forkBlocks))
+                }
+        }
+
+        bc.forkBlocks[block.Hash] = block
+        return nil
+}
+
+// maybeReorganize switches the main chain to the branch ending at tip if the
+// fork-choice rule prefers it. Caller must hold bc.mu.
+func (bc *Blockchain) maybeReorganize(tip *types.Block) error {
+        branch, ancestor, err := bc.branchTo(tip)
+        if err != nil {
+                return err
+        }
+
+        mainBranch := make([]*types.Block, 0, bc.blockHeight-ancestor.Index)
+        for index := ancestor.Index + 1; index <= bc.blockHeight; index++ {
+                block, err := bc.db.GetBlockByIndex(index)
+                if err != nil {
+                        return fmt.Errorf("failed to load main chain block %d: %w", index, err)
+                }
+                mainBranch = append(mainBranch, block)
+        }
+
+        branchWeight := bc.branchWeight(branch)
+        mainWeight := bc.branchWeight(mainBranch)
+
+        if !prefersBranch(branch, mainBranch, branchWeight, mainWeight) {
+                bc.logger.LogBlockchain("fork_tracked", logrus.Fields{
+                        "tip_hash": tip.Hash,
+                        "fork_point": ancestor.Index,
+                        "branch_weight": branchWeight,
+                        "main_weight": mainWeight,
+                        "timestamp": time.Now().UTC(),
+                })
+                return nil
+        }
+
+        if finalized := bc.finalizedHeight(); ancestor.Index < finalized {
+                bc.logger.LogBlockchain("reorg_rejected", logrus.Fields{
+                        "tip_hash": tip.Hash,
+                        "fork_point": ancestor.Index,
+                        "finalized_height": finalized,
+                        "timestamp": time.Now().UTC(),
+                })
+                return fmt.Errorf("%w: fork point %d, finalized height %d", ErrReorgBelowFinality, ancestor.Index, finalized)
+        }
+
+        return bc.reorganize(ancestor, mainBranch, branch)
+}
+
+// prefersBranch applies the fork-choice rule to a side branch and the main
+// chain above their common ancestor
+func prefersBranch(branch, mainBranch []*types.Block, branchWeight, mainWeight int64) bool {
+        if branchWeight != mainWeight {
+                return branchWeight > mainWeight
+        }
+        if len(mainBranch) == 0 {
+                return false
+        }
+
+        first, current := branch[0], mainBranch[0]
+        if !first.Timestamp.Equal(current.Timestamp) {
+                return first.Timestamp.Before(current.Timestamp)
+        }
+        return first.Hash < current.Hash
+}
+
+// branchTo returns the side-branch blocks from the main chain up to tip, in
+// ascending order, together with the main-chain block they fork from.
+// Caller must hold bc.mu.
+func (bc *Blockchain) branchTo(tip *types.Block) ([]*types.Block, *types.Block, error) {
+        branch := []*types.Block{tip}
+        current := tip
+
+        for {
+                if parent, exists := bc.forkBlocks[current.PreviousHash]; exists {
+                        branch = append([]*types.Block{parent}, branch...)
+                        current = parent
+                        continue
+                }
+
+                ancestor, onMainChain := bc.mainChainBlock(current.PreviousHash)
+                if !onMainChain {
+                        return nil, nil, fmt.Errorf("branch ending at %s does not connect to the main chain", tip.Hash)
+                }
+                return branch, ancestor, nil
+        }
+}
+
+// branchWeight returns the fork-choice weight of a sequence of blocks
+func (bc *Blockchain) branchWeight(blocks []*types.Block) int64 {
+        var weight int64
+        for _, block := range blocks {
+                weight += bc.blockWeight(block)
+        }
+        return weight
+}
+
+// blockWeight returns the fork-choice weight of a single block. Stake-based
+// algorithms weigh a block by its validator's stake, so the branch with the
+// highest cumulative stake wins; the others count blocks, so the longest
+// valid chain wins.
+func (bc *Blockchain) blockWeight(block *types.Block) int64 {
+        switch bc.config.Consensus.Algorithm {
+        case "pos", "lscc":
+                if len(bc.validators) == 0 {
+                        return 1
+                }
+                for _, validator := range bc.validators {
+                        if validator.Address == block.Validator {
+                                return validator.Stake
+                        }
+                }
+                return 0
+        default:
+                return 1
+        }
+}
+
+// reorganize rolls the main chain back to ancestor and applies newBranch. If
+// a block of the new branch turns out to be invalid, the original chain is
+// restored. Caller must hold bc.mu.
+func (bc *Blockchain) reorganize(ancestor *types.Block, oldBranch, newBranch []*types.Block) error {
+        startTime := time.Now()
+        oldHead := bc.latestBlock
+        // Commits of an aborted reorganization are undone, so never reported
+        queued := len(bc.commitQueue)
+
+        bc.logger.LogBlockchain("reorg_start", logrus.Fields{
+                "fork_point": ancestor.Index,
+                "old_head": oldHead.Hash,
+                "new_head": newBranch[len(newBranch)-1].Hash,
+                "rolled_back": len(oldBranch),
+                "applied": len(newBranch),
+                "timestamp": startTime,
+        })
+
+        // Roll back the current branch, newest block first
+        for i := len(oldBranch) - 1; i >= 0; i-- {
+                if err := bc.revertBlockLocked(oldBranch[i]); err != nil {
+                        return fmt.Errorf("failed to roll back block %d: %w", oldBranch[i].Index, err)
+                }
+        }
+
+        for i, block := range newBranch {
+                if err := bc.addBlockLocked(block); err != nil {
+                        bc.restoreBranch(newBranch[:i], oldBranch)
+                        bc.commitQueue = bc.commitQueue[:queued]
+                        for _, invalid := range newBranch[i:] {
+                                delete(bc.forkBlocks, invalid.Hash)
+                        }
+                        return fmt.Errorf("reorganization aborted, block %d is invalid: %w", block.Index, err)
+                }
+                delete(bc.forkBlocks, block.Hash)
+        }
+
+        // The replaced blocks become a side branch that may win again later
+        included := make(map[string]bool)
+        for _, block := range newBranch {
+                for _, tx := range block.Transactions {
+                        included[tx.ID] = true
+                }
+        }
+        for _, block := range oldBranch {
+                bc.forkBlocks[block.Hash] = block
+        }
+
+        // Transactions only the old branch contained go back to the pool
+        requeued := 0
+        for _, block := range oldBranch {
+                for _, tx := range block.Transactions {
+                        if included[tx.ID] || tx.Type == "genesis" {
+                                continue
+                        }
+                        if err := bc.txManager.RequeueTransaction(tx); err != nil {
+                                bc.txStatus.drop(tx.ID, err.Error())
+                                bc.logger.LogTransaction(tx.ID, "requeue_failed", logrus.Fields{
+                                        "error": err.Error(),
+                                        "timestamp": time.Now().UTC(),
+                                })
+                                continue
+                        }
+                        bc.txStatus.pending(tx.ID)
+                        requeued++
+                }
+        }
+
+        bc.logger.LogBlockchain("reorg_completed", logrus.Fields{
+                "fork_point": ancestor.Index,
+                "old_head": oldHead.Hash,
+                "new_head": bc.latestBlock.Hash,
+                "new_height": bc.blockHeight,
+                "requeued_transactions": requeued,
+                "duration": time.Since(startTime).Milliseconds(),
+                "timestamp": time.Now().UTC(),
+        })
+
+        return nil
+}
+
+// restoreBranch undoes the partially applied blocks of a failed
+// reorganization and re-applies the original branch. Caller must hold bc.mu.
+func (bc *Blockchain) restoreBranch(applied, original []*types.Block) {
+        for i := len(applied) - 1; i >= 0; i-- {
+                if err := bc.revertBlockLocked(applied[i]); err != nil {
+                        bc.logger.LogError("blockchain", "restore_branch", err, logrus.Fields{
+                                "block_hash": applied[i].Hash,
+                                "timestamp": time.Now().UTC(),
+                        })
+                }
+        }
+
+        for _, block := range original {
+                if err := bc.addBlockLocked(block); err != nil {
+                        bc.logger.LogError("blockchain", "restore_branch", err, logrus.Fields{
+                                "block_hash": block.Hash,
+                                "timestamp": time.Now().UTC(),
+                        })
+                }
+        }
+}
+
+// revertBlockLocked removes the head block from the main chain and undoes its
+// balance and nonce changes. Caller must hold bc.mu.
+func (bc *Blockchain) revertBlockLocked(block *types.Block) error {
+        if block.Hash != bc.latestBlock.Hash {
+                return fmt.Errorf("block %s is not the chain head", block.Hash)
+        }
+
+        parent, err := bc.db.GetBlock(block.PreviousHash)
+        if err != nil {
+                return fmt.Errorf("failed to load parent block: %w", err)
+        }
+
+        // Stage every write so a failed revert leaves the block fully applied
+        batch := storage.NewWriteSet()
+
+        // The rewards were credited after the transactions, so they are
+        // taken back first. A block committed before rewards were split
+        // carries none and paid its subsidy and tips to its producer.
+        feeRecipient := ""
+        if block.Rewards != nil {
+                if err := bc.accountState.RevertRewards(batch, block.Rewards); err != nil {
+                        return fmt.Errorf("failed to revert block rewards: %w", err)
+                }
+        } else {
+                feeRecipient = block.Validator
+                if reward := bc.rewards.BlockReward(block.Index); reward > 0 {
+                        if err := bc.accountState.RevertBlockReward(batch, block.Validator, reward); err != nil {
+                                return fmt.Errorf("failed to revert block reward: %w", err)
+                        }
+                }
+        }
+        if burned := blockBurn(block); burned > 0 {
+                if err := bc.accountState.RevertBurn(batch, burned); err != nil {
+                        return fmt.Errorf("failed to revert burned fees: %w", err)
+                }
+        }
+
+        // Undo transactions in reverse order so each sender's nonce ends up
+        // just below its first reverted transaction. Failed transactions
+        // moved no funds, but did use up their nonces.
+        failed := make(map[string]bool, len(block.FailedTxs))
+        for _, txID := range block.FailedTxs {
+                failed[txID] = true
+        }
+        for i := len(block.Transactions) - 1; i >= 0; i-- {
+                tx := block.Transactions[i]
+                if !failed[tx.ID] {
+                        if err := bc.accountState.RevertTransaction(batch, tx, feeRecipient, block.BaseFee); err != nil {
+                                return fmt.Errorf("failed to revert transaction %s: %w", tx.ID, err)
+                        }
+                }
+                if tx.Type == "genesis" {
+                        continue
+                }
+                if err := batch.SetAccountNonce(tx.From, tx.Nonce-1); err != nil {
+                        return fmt.Errorf("failed to revert nonce of %s: %w", tx.From, err)
+                }
+        }
+
+        for _, tx := range block.Transactions {
+                batch.UnindexTransaction(tx, block.Index)
+        }
+        batch.RemoveBlock(block, parent.Hash)
+        if err := bc.db.WriteBatch(batch.Ops()); err != nil {
+                return fmt.Errorf("failed to commit revert: %w", err)
+        }
+
+        if current := bc.epochs.current; current != nil && current.contains(block.Index) {
+                for _, amount := range block.Rewards {
+                        current.Rewards -= amount
+                }
+        }
+        bc.latestBlock = parent
+        bc.blockHeight = parent.Index
+        bc.totalTxCount -= int64(len(block.Transactions))
+
+        bc.logger.LogBlockchain("block_reverted", logrus.Fields{
+                "block_hash": block.Hash,
+                "block_index": block.Index,
+                "new_height": bc.blockHeight,
+                "timestamp": time.Now().UTC(),
+        })
+
+        return nil
+}
+
+// findBlock looks a block up on the main chain or on a side branch.
+// Caller must hold bc.mu.
+func (bc *Blockchain) findBlock(hash string) (*types.Block, bool) {
+        if block, exists := bc.forkBlocks[hash]; exists {
+                return block, true
+        }
+        return bc.mainChainBlock(hash)
+}
+
+// mainChainBlock returns the block with the given hash if it is part of the
+// main chain. Caller must hold bc.mu.
+func (bc *Blockchain) mainChainBlock(hash string) (*types.Block, bool) {
+        block, err := bc.db.GetBlock(hash)
+        if err != nil || block.Index > bc.blockHeight {
+                return nil, false
+        }
+
+        canonical, err := bc.db.GetBlockByIndex(block.Index)
+        if err != nil || canonical.Hash != hash {
+                return nil, false
+        }
+        return block, true
+}
+
+// isKnownBlock reports whether a block has already been seen.
+// Caller must hold bc.mu.
+func (bc *Blockchain) isKnownBlock(hash string) bool {
+        if _, exists := bc.forkBlocks[hash]; exists {
+                return true
+        }
+        if bc.orphans.contains(hash) {
+                return true
+        }
+        _, onMainChain := bc.mainChainBlock(hash)
+        return onMainChain
+}
@@ -0,0 +1,286 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/storage"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+)
+
+// sideBlock builds a block on parent that is not yet part of any chain
+func sideBlock(t *testing.T, bc *Blockchain, parent *types.Block, txs []*types.Transaction, validator string) *types.Block {
+	t.Helper()
+	block, err := bc.blockManager.CreateBlock(parent, txs, validator, 0)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+	return block
+}
+
+func TestReorgRestoresCommittedState(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", nil)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	genesis := bc.GetLatestBlock()
+	tx := signedTransfer(t, sender, recipient, 100, 10, 1)
+	if err := bc.AddBlock(sideBlock(t, bc, genesis, []*types.Transaction{tx}, "proposer")); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// A longer branch from genesis replaces the block
+	time.Sleep(time.Millisecond)
+	first := sideBlock(t, bc, genesis, nil, "rival")
+	second := sideBlock(t, bc, first, nil, "rival")
+	for _, side := range []*types.Block{first, second} {
+		if _, err := bc.ResolveFork(side); err != nil {
+			t.Fatalf("failed to resolve fork: %v", err)
+		}
+	}
+	if head := bc.GetLatestBlock(); head.Hash != second.Hash {
+		t.Fatalf("head = %s, want the rival branch tip %s", head.Hash, second.Hash)
+	}
+
+	if got := bc.GetBalance(sender.address); got != 1000 {
+		t.Errorf("sender balance after reorg = %d, want 1000", got)
+	}
+	if got := bc.GetBalance(recipient.address); got != 0 {
+		t.Errorf("recipient balance after reorg = %d, want 0", got)
+	}
+	if got := bc.GetBalance("proposer"); got != 0 {
+		t.Errorf("proposer balance after reorg = %d, want 0", got)
+	}
+	if got := bc.GetAccountNonce(sender.address); got != 0 {
+		t.Errorf("sender nonce after reorg = %d, want 0", got)
+	}
+	if _, err := bc.GetTransactionBlock(tx.ID); err == nil {
+		t.Errorf("transaction %s is still indexed after reorg", tx.ID)
+	}
+}
+
+func TestReorgRevertsSettledCrossShardTransfer(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		settle func(bc *Blockchain, txID string) error
+	}{
+		{"committed", (*Blockchain).CommitCrossShardTransfer},
+		{"aborted", (*Blockchain).AbortCrossShardTransfer},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bc := newTestBlockchain(t, "pbft", nil)
+			sender := newTestAccount(t)
+			recipient := newTestAccount(t)
+			for utils.GenerateShardKey(recipient.address, 4) == utils.GenerateShardKey(sender.address, 4) {
+				recipient = newTestAccount(t)
+			}
+			fund(t, bc, sender.address, 1000)
+
+			genesis := bc.GetLatestBlock()
+			tx := signedTransfer(t, sender, recipient, 100, 10, 1)
+			if tx.Type != "cross_shard" {
+				t.Fatalf("transaction type %s, want cross_shard", tx.Type)
+			}
+			if err := bc.AddBlock(sideBlock(t, bc, genesis, []*types.Transaction{tx}, "proposer")); err != nil {
+				t.Fatalf("failed to add block: %v", err)
+			}
+			if err := tc.settle(bc, tx.ID); err != nil {
+				t.Fatalf("failed to settle transfer: %v", err)
+			}
+
+			// A longer branch without the transaction undoes the settlement too
+			time.Sleep(time.Millisecond)
+			first := sideBlock(t, bc, genesis, nil, "rival")
+			second := sideBlock(t, bc, first, nil, "rival")
+			for _, side := range []*types.Block{first, second} {
+				if _, err := bc.ResolveFork(side); err != nil {
+					t.Fatalf("failed to resolve fork: %v", err)
+				}
+			}
+			if head := bc.GetLatestBlock(); head.Hash != second.Hash {
+				t.Fatalf("head = %s, want the rival branch tip %s", head.Hash, second.Hash)
+			}
+			if got := bc.GetBalance(sender.address); got != 1000 {
+				t.Errorf("sender balance after reorg = %d, want 1000", got)
+			}
+			if got := bc.GetBalance(recipient.address); got != 0 {
+				t.Errorf("recipient balance after reorg = %d, want 0", got)
+			}
+			if _, err := bc.accountState.transferOutcome(tx.ID); err == nil {
+				t.Error("transfer outcome kept after reorg")
+			}
+		})
+	}
+}
+
+func TestReorgInvalidatesAccountCache(t *testing.T) {
+	db := storage.NewCachedDB(storage.NewMemoryDB(), 100)
+	bc := newTestBlockchainOn(t, db, "pbft", nil)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	genesis := bc.GetLatestBlock()
+	tx := signedTransfer(t, sender, recipient, 100, 10, 1)
+	if err := bc.AddBlock(sideBlock(t, bc, genesis, []*types.Transaction{tx}, "proposer")); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// Warm the cache with the committed block's balances and nonce
+	for i := 0; i < 2; i++ {
+		if got := bc.GetBalance(sender.address); got != 890 {
+			t.Fatalf("sender balance = %d, want 890", got)
+		}
+		if got := bc.GetBalance(recipient.address); got != 100 {
+			t.Fatalf("recipient balance = %d, want 100", got)
+		}
+		if got := bc.GetAccountNonce(sender.address); got != 1 {
+			t.Fatalf("sender nonce = %d, want 1", got)
+		}
+	}
+	if stats := db.AccountCacheStats(); stats.Hits == 0 {
+		t.Fatalf("repeated lookups never hit the cache: %+v", stats)
+	}
+
+	time.Sleep(time.Millisecond)
+	first := sideBlock(t, bc, genesis, nil, "rival")
+	second := sideBlock(t, bc, first, nil, "rival")
+	for _, side := range []*types.Block{first, second} {
+		if _, err := bc.ResolveFork(side); err != nil {
+			t.Fatalf("failed to resolve fork: %v", err)
+		}
+	}
+	if head := bc.GetLatestBlock(); head.Hash != second.Hash {
+		t.Fatalf("head = %s, want the rival branch tip %s", head.Hash, second.Hash)
+	}
+
+	// The rolled back block's cached values are gone
+	if got := bc.GetBalance(sender.address); got != 1000 {
+		t.Errorf("sender balance after reorg = %d, want 1000", got)
+	}
+	if got := bc.GetBalance(recipient.address); got != 0 {
+		t.Errorf("recipient balance after reorg = %d, want 0", got)
+	}
+	if got := bc.GetAccountNonce(sender.address); got != 0 {
+		t.Errorf("sender nonce after reorg = %d, want 0", got)
+	}
+	if stats := db.AccountCacheStats(); stats.Invalidations == 0 {
+		t.Errorf("reorg invalidated no cached accounts: %+v", stats)
+	}
+}
+
+func TestRevertSkipsFailedTransactions(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", nil)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	genesis := bc.GetLatestBlock()
+	ok := signedTransfer(t, sender, recipient, 100, 10, 1)
+	block := sideBlock(t, bc, genesis, []*types.Transaction{ok}, "proposer")
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// Stand in for a transaction that failed to execute in the block: it
+	// used up its nonce but moved no funds
+	failed := signedTransfer(t, sender, recipient, 50, 10, 2)
+	block.Transactions = append(block.Transactions, failed)
+	block.FailedTxs = []string{failed.ID}
+	bc.mu.Lock()
+	if err := bc.db.SaveAccountNonce(sender.address, 2); err != nil {
+		t.Fatalf("failed to save nonce: %v", err)
+	}
+	err := bc.revertBlockLocked(block)
+	bc.mu.Unlock()
+	if err != nil {
+		t.Fatalf("failed to revert block: %v", err)
+	}
+
+	if got := bc.GetBalance(sender.address); got != 1000 {
+		t.Errorf("sender balance after revert = %d, want 1000", got)
+	}
+	if got := bc.GetBalance(recipient.address); got != 0 {
+		t.Errorf("recipient balance after revert = %d, want 0", got)
+	}
+	if got := bc.GetAccountNonce(sender.address); got != 0 {
+		t.Errorf("sender nonce after revert = %d, want 0", got)
+	}
+}
+
+func TestRevertIsAtomic(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", nil)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	genesis := bc.GetLatestBlock()
+	block := sideBlock(t, bc, genesis, []*types.Transaction{signedTransfer(t, sender, recipient, 100, 10, 1)}, "proposer")
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// The recipient spends the transfer elsewhere, so it cannot be taken back
+	bc.accountState.mu.Lock()
+	if err := bc.accountState.debit(recipient.address, 100); err != nil {
+		t.Fatalf("failed to drain recipient: %v", err)
+	}
+	bc.accountState.mu.Unlock()
+
+	bc.mu.Lock()
+	err := bc.revertBlockLocked(block)
+	bc.mu.Unlock()
+	if err == nil {
+		t.Fatal("revert succeeded although the recipient cannot be debited")
+	}
+
+	// Nothing the revert staged before failing was written
+	if got := bc.GetBalance("proposer"); got != 10 {
+		t.Errorf("proposer balance = %d, want its reward 10 kept", got)
+	}
+	if got := bc.GetAccountNonce(sender.address); got != 1 {
+		t.Errorf("sender nonce = %d, want 1", got)
+	}
+	if head := bc.GetLatestBlock(); head.Hash != block.Hash {
+		t.Errorf("head moved to %s after a failed revert", head.Hash)
+	}
+}
+
+func TestReorgBelowFinalityRejected(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", func(cfg *config.Config) {
+		cfg.Consensus.FinalityDepth = 1
+	})
+	genesis := bc.GetLatestBlock()
+
+	parent := genesis
+	for i := 0; i < 3; i++ {
+		block := sideBlock(t, bc, parent, nil, "proposer")
+		if err := bc.AddBlock(block); err != nil {
+			t.Fatalf("failed to add block: %v", err)
+		}
+		parent = block
+	}
+	head := bc.GetLatestBlock()
+	if got := bc.GetFinalizedHeight(); got != 2 {
+		t.Fatalf("finalized height = %d, want 2", got)
+	}
+
+	// A heavier branch forking at genesis would undo finalized blocks
+	time.Sleep(time.Millisecond)
+	var rejected error
+	rival := genesis
+	for i := 0; i < 5 && rejected == nil; i++ {
+		rival = sideBlock(t, bc, rival, nil, "rival")
+		_, rejected = bc.ResolveFork(rival)
+	}
+	if !errors.Is(rejected, ErrReorgBelowFinality) {
+		t.Fatalf("expected ErrReorgBelowFinality, got %v", rejected)
+	}
+	if got := bc.GetLatestBlock(); got.Hash != head.Hash {
+		t.Fatalf("head moved to %s after a rejected reorg", got.Hash)
+	}
+}
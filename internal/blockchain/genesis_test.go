@@ -0,0 +1,148 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/storage"
+	"lscc-blockchain/pkg/types"
+)
+
+// writeGenesis writes genesis to a file in a temporary directory and
+// returns its path
+func writeGenesis(t *testing.T, genesis *Genesis) string {
+	t.Helper()
+	data, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testGenesis(chainID string) *Genesis {
+	genesis := &Genesis{
+		ChainID:   chainID,
+		Timestamp: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		Alloc: map[string]int64{
+			"0x1111111111111111111111111111111111111111": 1000000,
+			"0x2222222222222222222222222222222222222222": 500000,
+		},
+	}
+	for i, address := range []string{"0xaaaa", "0xbbbb", "0xcccc", "0xdddd"} {
+		genesis.Validators = append(genesis.Validators, GenesisValidator{Address: address, Stake: int64(1000 * (i + 1)), ShardID: i})
+	}
+	return genesis
+}
+
+func sortedValidators(bc *Blockchain) []*types.Validator {
+	validators := bc.GetValidators()
+	sort.Slice(validators, func(i, j int) bool { return validators[i].Address < validators[j].Address })
+	return validators
+}
+
+func TestGenesisFileIsReproducible(t *testing.T) {
+	var path string
+	build := func() *Blockchain {
+		return newTestBlockchain(t, "pbft", func(cfg *config.Config) {
+			if path == "" {
+				path = writeGenesis(t, testGenesis(cfg.Network.ChainID))
+			}
+			cfg.Genesis.Path = path
+		})
+	}
+	first, second := build(), build()
+
+	if first.GetGenesisBlock().Hash != second.GetGenesisBlock().Hash {
+		t.Fatalf("genesis hashes differ: %s and %s", first.GetGenesisBlock().Hash, second.GetGenesisBlock().Hash)
+	}
+	if got := first.GetGenesisBlock().Timestamp; !got.Equal(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("genesis timestamp = %s, want the file's", got)
+	}
+
+	firstValidators, secondValidators := sortedValidators(first), sortedValidators(second)
+	if len(firstValidators) != 4 || len(secondValidators) != 4 {
+		t.Fatalf("validator sets of %d and %d, want the 4 in the file", len(firstValidators), len(secondValidators))
+	}
+	for i := range firstValidators {
+		a, b := firstValidators[i], secondValidators[i]
+		if a.Address != b.Address || a.Stake != b.Stake || a.ShardID != b.ShardID {
+			t.Fatalf("validator %d differs: %+v and %+v", i, a, b)
+		}
+	}
+	if v := firstValidators[3]; v.Address != "0xdddd" || v.Stake != 4000 || v.ShardID != 3 {
+		t.Fatalf("validator = %+v, want the file's stake and shard", v)
+	}
+
+	for _, bc := range []*Blockchain{first, second} {
+		if got := bc.GetBalance("0x1111111111111111111111111111111111111111"); got != 1000000 {
+			t.Fatalf("allocated balance = %d, want 1000000", got)
+		}
+	}
+}
+
+func TestGenesisContentsChangeTheHash(t *testing.T) {
+	bm := NewBlockManager(discardLogger(), 0, 0, 0, "test")
+	base := bm.CreateGenesisBlockFrom(testGenesis("test")).Hash
+
+	changes := map[string]func(g *Genesis){
+		"timestamp":  func(g *Genesis) { g.Timestamp = g.Timestamp.Add(time.Second) },
+		"allocation": func(g *Genesis) { g.Alloc["0x1111111111111111111111111111111111111111"]++ },
+		"stake":      func(g *Genesis) { g.Validators[0].Stake++ },
+		"shard":      func(g *Genesis) { g.Validators[0].ShardID = 1 },
+		"chain":      func(g *Genesis) { g.ChainID = "other" },
+	}
+	for name, change := range changes {
+		genesis := testGenesis("test")
+		change(genesis)
+		if bm.CreateGenesisBlockFrom(genesis).Hash == base {
+			t.Errorf("changing the %s kept the genesis hash", name)
+		}
+	}
+}
+
+func TestGenesisFileRejected(t *testing.T) {
+	tests := map[string]struct {
+		change func(g *Genesis)
+		want   string
+	}{
+		"missing chain":      {func(g *Genesis) { g.ChainID = "" }, "chain_id is required"},
+		"missing timestamp":  {func(g *Genesis) { g.Timestamp = time.Time{} }, "timestamp is required"},
+		"empty allocation":   {func(g *Genesis) { g.Alloc["0x3333"] = 0 }, "must be positive"},
+		"duplicate":          {func(g *Genesis) { g.Validators[1].Address = g.Validators[0].Address }, "duplicate validator"},
+		"zero stake":         {func(g *Genesis) { g.Validators[0].Stake = 0 }, "stake of validator"},
+		"validator no shard": {func(g *Genesis) { g.Validators[0].ShardID = -1 }, "must not be negative"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			genesis := testGenesis("test")
+			tt.change(genesis)
+			_, err := LoadGenesis(writeGenesis(t, genesis))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenesisForAnotherChainRejected(t *testing.T) {
+	cfg, err := config.LoadConfigFromPath("../../config/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Genesis.Path = writeGenesis(t, testGenesis("other-chain"))
+	_, err = NewBlockchain(cfg, storage.NewMemoryDB(), discardLogger())
+	if !errors.Is(err, types.ErrChainIDMismatch) {
+		t.Fatalf("genesis for another chain: got %v", err)
+	}
+}
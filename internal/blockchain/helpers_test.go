@@ -0,0 +1,118 @@
+package blockchain
+
+import (
+	"io"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/storage"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// newTestBlockchain returns a blockchain on an in-memory database, running
+// algorithm with the built-in genesis and logging discarded
+func newTestBlockchain(t *testing.T, algorithm string, configure func(cfg *config.Config)) *Blockchain {
+	t.Helper()
+	return newTestBlockchainOn(t, storage.NewMemoryDB(), algorithm, configure)
+}
+
+// newTestBlockchainOn is newTestBlockchain storing the chain in db
+func newTestBlockchainOn(t *testing.T, db storage.Database, algorithm string, configure func(cfg *config.Config)) *Blockchain {
+	t.Helper()
+
+	cfg, err := config.LoadConfigFromPath("../../config/config.yaml")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Genesis.Path = ""
+	cfg.Consensus.Algorithm = algorithm
+	if configure != nil {
+		configure(cfg)
+	}
+
+	bc, err := NewBlockchain(cfg, db, discardLogger())
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	return bc
+}
+
+func discardLogger() *utils.Logger {
+	logger := utils.NewLogger()
+	logger.Logger.SetOutput(io.Discard)
+	return logger
+}
+
+// testAccount is a key pair able to sign transactions
+type testAccount struct {
+	key     *secp256k1.PrivateKey
+	address string
+}
+
+func newTestAccount(t *testing.T) testAccount {
+	t.Helper()
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return testAccount{key: key, address: utils.SigningKeyToAddress(key.PubKey())}
+}
+
+// newTestAccountOnShard returns an account whose address maps to the same
+// shard as other, so transfers between them are not cross-shard
+func newTestAccountOnShard(t *testing.T, other testAccount) testAccount {
+	t.Helper()
+	shard := utils.GenerateShardKey(other.address, 4)
+	for {
+		account := newTestAccount(t)
+		if utils.GenerateShardKey(account.address, 4) == shard {
+			return account
+		}
+	}
+}
+
+// fund credits address with amount directly in the committed state
+func fund(t *testing.T, bc *Blockchain, address string, amount int64) {
+	t.Helper()
+	bc.accountState.mu.Lock()
+	defer bc.accountState.mu.Unlock()
+	if err := bc.accountState.credit(address, amount); err != nil {
+		t.Fatalf("failed to fund %s: %v", address, err)
+	}
+}
+
+// signedTransfer returns a transfer from one account to another with the
+// given nonce, signed by the sender
+func signedTransfer(t *testing.T, from, to testAccount, amount, fee, nonce int64) *types.Transaction {
+	t.Helper()
+	tx, err := NewTransactionManager(1, discardLogger()).CreateTransaction(from.address, to.address, amount, fee, nil, from.key)
+	if err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+	tx.Nonce = nonce
+	tx.ID = tx.Hash()
+	if err := utils.SignTransaction(tx, from.key); err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+// addValidators registers count active validators with equal stake
+func addValidators(t *testing.T, bc *Blockchain, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		validator := &types.Validator{
+			Address:    string(rune('a'+i)) + "_validator",
+			Stake:      1000,
+			Status:     validatorActive,
+			ShardID:    i % 4,
+			Reputation: 1,
+		}
+		if err := bc.AddValidator(validator); err != nil {
+			t.Fatalf("failed to add validator: %v", err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package blockchain
+
+import (
+        "errors"
+        "fmt"
+        "lscc-blockchain/pkg/types"
+        "sync"
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// ErrLivenessDisabled is returned for heartbeats on a node configured
+// without a liveness window
+var ErrLivenessDisabled = errors.New("validator liveness monitoring is disabled")
+
+// ErrUnknownValidator is returned for heartbeats from an address that is not
+// a validator
+var ErrUnknownValidator = errors.New("unknown validator")
+
+// Validator statuses moved by the liveness monitor
+const (
+        validatorActive   = "active"
+        validatorInactive = "inactive"
+)
+
+// livenessMonitor remembers when each validator last took part in
+// consensus: voting in a round, proposing a committed block or sending a
+// heartbeat
+type livenessMonitor struct {
+        window   time.Duration
+        started  time.Time // validators get a full window from startup before they can go inactive
+        mu       sync.Mutex
+        lastSeen map[string]time.Time
+}
+
+func newLivenessMonitor(window time.Duration) *livenessMonitor {
+        return &livenessMonitor{
+                window:   window,
+                started:  time.Now(),
+                lastSeen: make(map[string]time.Time),
+        }
+}
+
+// heartbeat records that address took part at at
+func (lm *livenessMonitor) heartbeat(address string, at time.Time) {
+        lm.mu.Lock()
+        defer lm.mu.Unlock()
+        if at.After(lm.lastSeen[address]) {
+                lm.lastSeen[address] = at
+        }
+}
+
+// seen returns when address last took part, zero if never
+func (lm *livenessMonitor) seen(address string) time.Time {
+        lm.mu.Lock()
+        defer lm.mu.Unlock()
+        return lm.lastSeen[address]
+}
+
+// checkInterval is how often statuses are re-evaluated: a quarter of the
+// window, but at least once a second
+func (lm *livenessMonitor) checkInterval() time.Duration {
+        interval := lm.window / 4
+        if interval < time.Second {
+                interval = time.Second
+        }
+        return interval
+}
+
+// RecordHeartbeat records that the validator at address is alive. An
+// inactive validator is made active again at the next liveness check.
+func (bc *Blockchain) RecordHeartbeat(address string) error {
+        if bc.liveness == nil {
+                return ErrLivenessDisabled
+        }
+
+        bc.mu.RLock()
+        known := false
+        for _, validator := range bc.validators {
+                if validator.Address == address {
+                        known = true
+                        break
+                }
+        }
+        bc.mu.RUnlock()
+        if !known {
+                return fmt.Errorf("%w: %s", ErrUnknownValidator, address)
+        }
+
+        bc.liveness.heartbeat(address, time.Now())
+        return nil
+}
+
+// livenessLoop re-evaluates validator statuses until consensus stops
+func (bc *Blockchain) livenessLoop() {
+        defer bc.loops.Done()
+        ticker := time.NewTicker(bc.liveness.checkInterval())
+        defer ticker.Stop()
+
+        for {
+                select {
+                case <-bc.stopChan:
+                        return
+                case now := <-ticker.C:
+                        bc.checkLiveness(now)
+                }
+        }
+}
+
+// checkLiveness marks active validators that have not taken part within
+// the window inactive, and inactive ones that have active again. Slashed
+// and other statuses are left alone. It runs between consensus rounds, so
+// a round never sees a status change part way through.
+func (bc *Blockchain) checkLiveness(now time.Time) {
+        bc.roundMu.Lock()
+        defer bc.roundMu.Unlock()
+        bc.mu.Lock()
+        defer bc.mu.Unlock()
+
+        for _, validator := range bc.validators {
+                if seen := bc.liveness.seen(validator.Address); seen.After(validator.LastActive) {
+                        validator.LastActive = seen
+                }
+                since := validator.LastActive
+                if bc.liveness.started.After(since) {
+                        since = bc.liveness.started
+                }
+                idle := now.Sub(since)
+
+                switch {
+                case validator.Status == validatorActive && idle > bc.liveness.window:
+                        validator.Status = validatorInactive
+                        bc.logger.LogBlockchain("validator_inactive", logrus.Fields{
+                                "validator_address": validator.Address,
+                                "last_active":       validator.LastActive,
+                                "idle_seconds":      idle.Seconds(),
+                                "timestamp":         now.UTC(),
+                        })
+                case validator.Status == validatorInactive && idle <= bc.liveness.window:
+                        validator.Status = validatorActive
+                        bc.logger.LogBlockchain("validator_reactivated", logrus.Fields{
+                                "validator_address": validator.Address,
+                                "last_active":       validator.LastActive,
+                                "timestamp":         now.UTC(),
+                        })
+                }
+        }
+}
+
+// consensusValidators returns the validators that vote in the next round:
+// the current epoch's set, or every validator when epochs are disabled.
+// With liveness monitoring on, inactive validators are left out, so they
+// no longer count toward quorum.
+func (bc *Blockchain) consensusValidators() []*types.Validator {
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+
+        members := bc.validators
+        if bc.epochs.current != nil {
+                members = bc.epochs.validators
+        }
+        if bc.liveness == nil {
+                return members
+        }
+        validators := make([]*types.Validator, 0, len(members))
+        for _, validator := range members {
+                if validator.Status != validatorInactive {
+                        validators = append(validators, validator)
+                }
+        }
+        return validators
+}
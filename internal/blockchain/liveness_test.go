@@ -0,0 +1,115 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+)
+
+// newLivenessBlockchain returns a blockchain with a 10 second liveness
+// window, count validators and the startup grace period already over
+func newLivenessBlockchain(t *testing.T, count int) *Blockchain {
+	t.Helper()
+	bc := newTestBlockchain(t, "pbft", func(cfg *config.Config) {
+		cfg.Consensus.LivenessWindow = 10
+	})
+	addValidators(t, bc, count)
+	bc.liveness.started = time.Now().Add(-time.Minute)
+	return bc
+}
+
+// quorum is the 2f+1 vote count the voting engines require of n validators
+func quorum(n int) int {
+	return (n*2)/3 + 1
+}
+
+func TestIdleValidatorAgesOutOfQuorum(t *testing.T) {
+	bc := newLivenessBlockchain(t, 7)
+	now := time.Now()
+	validators := bc.GetValidators()
+	if got := len(bc.consensusValidators()); got != 7 || quorum(got) != 5 {
+		t.Fatalf("expected 7 voting validators and a quorum of 5, got %d", got)
+	}
+
+	// Three validators never take part
+	for _, validator := range validators[:4] {
+		bc.liveness.heartbeat(validator.Address, now.Add(-2*time.Second))
+	}
+	bc.checkLiveness(now)
+
+	voting := bc.consensusValidators()
+	if len(voting) != 4 || quorum(len(voting)) != 3 {
+		t.Fatalf("expected 4 voting validators and a quorum of 3, got %d", len(voting))
+	}
+	for _, validator := range validators[4:] {
+		if validator.Status != validatorInactive {
+			t.Fatalf("idle validator %s is %s", validator.Address, validator.Status)
+		}
+		for _, v := range voting {
+			if v.Address == validator.Address {
+				t.Fatalf("inactive validator %s still votes", validator.Address)
+			}
+		}
+	}
+
+	// Renewed participation makes a validator active again
+	idle := validators[6]
+	if err := bc.RecordHeartbeat(idle.Address); err != nil {
+		t.Fatalf("heartbeat failed: %v", err)
+	}
+	bc.checkLiveness(time.Now())
+	if idle.Status != validatorActive {
+		t.Fatalf("validator not reactivated by its heartbeat: %s", idle.Status)
+	}
+	if got := len(bc.consensusValidators()); got != 5 {
+		t.Fatalf("expected 5 voting validators after the heartbeat, got %d", got)
+	}
+}
+
+func TestLivenessGracePeriodAndOtherStatuses(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", func(cfg *config.Config) {
+		cfg.Consensus.LivenessWindow = 10
+	})
+	addValidators(t, bc, 4)
+	validators := bc.GetValidators()
+	validators[0].Status = "slashed"
+
+	// Validators get a full window from startup
+	bc.checkLiveness(bc.liveness.started.Add(5 * time.Second))
+	for _, validator := range validators[1:] {
+		if validator.Status != validatorActive {
+			t.Fatalf("validator %s went %s within the startup window", validator.Address, validator.Status)
+		}
+	}
+
+	bc.checkLiveness(bc.liveness.started.Add(time.Minute))
+	if validators[0].Status != "slashed" {
+		t.Fatalf("liveness changed a slashed validator to %s", validators[0].Status)
+	}
+	for _, validator := range validators[1:] {
+		if validator.Status != validatorInactive {
+			t.Fatalf("validator %s still %s after the window", validator.Address, validator.Status)
+		}
+	}
+}
+
+func TestRecordHeartbeatErrors(t *testing.T) {
+	bc := newLivenessBlockchain(t, 1)
+	if err := bc.RecordHeartbeat("nobody"); !errors.Is(err, ErrUnknownValidator) {
+		t.Fatalf("expected ErrUnknownValidator, got %v", err)
+	}
+
+	disabled := newTestBlockchain(t, "pbft", func(cfg *config.Config) {
+		cfg.Consensus.LivenessWindow = 0
+	})
+	addValidators(t, disabled, 2)
+	if err := disabled.RecordHeartbeat(disabled.GetValidators()[0].Address); !errors.Is(err, ErrLivenessDisabled) {
+		t.Fatalf("expected ErrLivenessDisabled, got %v", err)
+	}
+	disabled.GetValidators()[1].Status = validatorInactive
+	if got := len(disabled.consensusValidators()); got != 2 {
+		t.Fatalf("expected every validator to vote without liveness monitoring, got %d", got)
+	}
+}
@@ -0,0 +1,61 @@
+package blockchain
+
+import (
+	"strings"
+	"testing"
+
+	"lscc-blockchain/pkg/types"
+)
+
+func TestSubmitEnforcesSequentialNonces(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", nil)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, 2)); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("out-of-order nonce accepted: %v", err)
+	}
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, 1)); err != nil {
+		t.Fatalf("first nonce rejected: %v", err)
+	}
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 20, 10, 1)); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("duplicate nonce accepted: %v", err)
+	}
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, 2)); err != nil {
+		t.Fatalf("next nonce rejected: %v", err)
+	}
+	if got := bc.GetNextNonce(sender.address); got != 3 {
+		t.Fatalf("next nonce = %d, want 3", got)
+	}
+}
+
+func TestAddBlockRejectsReplayedNonces(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", nil)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	first := sideBlock(t, bc, bc.GetLatestBlock(), []*types.Transaction{signedTransfer(t, sender, recipient, 10, 10, 1)}, "proposer")
+	if err := bc.AddBlock(first); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if got := bc.GetAccountNonce(sender.address); got != 1 {
+		t.Fatalf("committed nonce = %d, want 1", got)
+	}
+
+	tests := map[string][]*types.Transaction{
+		"replayed":  {signedTransfer(t, sender, recipient, 20, 10, 1)},
+		"gap":       {signedTransfer(t, sender, recipient, 20, 10, 3)},
+		"duplicate": {signedTransfer(t, sender, recipient, 20, 10, 2), signedTransfer(t, sender, recipient, 30, 10, 2)},
+	}
+	for name, txs := range tests {
+		block := sideBlock(t, bc, first, txs, "proposer")
+		if err := bc.AddBlock(block); err == nil {
+			t.Errorf("%s nonces: block accepted", name)
+		}
+	}
+	if got := bc.GetAccountNonce(sender.address); got != 1 {
+		t.Fatalf("committed nonce after rejected blocks = %d, want 1", got)
+	}
+}
@@ -0,0 +1,121 @@
+package blockchain
+
+import (
+        "fmt"
+        "lscc-blockchain/pkg/types"
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// CommitResult describes how a block was committed to the main chain
+type CommitResult struct {
+        Algorithm   string           `json:"algorithm"`         // consensus engine active when the block was committed
+        Height      int64            `json:"height"`            // chain height after the commit
+        Reward      int64            `json:"reward"`            // subsidy paid out by the block
+        Payouts     map[string]int64 `json:"payouts,omitempty"` // subsidy and tips paid to each validator
+        Burned      int64            `json:"burned"`            // base fees destroyed by the block's transactions
+        FailedTxs   []string         `json:"failed_txs"`        // transactions in the block whose transfers could not be applied
+        Epoch       *Epoch           `json:"epoch,omitempty"`   // epoch the block brought in by ending the last one, nil mid-epoch
+        Duration    time.Duration    `json:"duration"`          // time taken to validate and write the block
+        CommittedAt time.Time        `json:"committed_at"`
+}
+
+// BlockCommittedObserver is told about every block added to the main chain,
+// whichever consensus engine produced it and whether it came from a local
+// round, a peer or a reorganization. Each commit is reported once, in chain
+// order, after the chain lock is released; blocks applied and undone by an
+// aborted reorganization are not reported. An observer may read the chain
+// but must not add blocks from OnBlockCommitted.
+type BlockCommittedObserver interface {
+        OnBlockCommitted(block *types.Block, result CommitResult)
+}
+
+// BlockCommittedFunc adapts a function to a BlockCommittedObserver
+type BlockCommittedFunc func(block *types.Block, result CommitResult)
+
+// OnBlockCommitted calls f(block, result)
+func (f BlockCommittedFunc) OnBlockCommitted(block *types.Block, result CommitResult) {
+        f(block, result)
+}
+
+// committedBlock is a commit waiting to be reported to observers
+type committedBlock struct {
+        block  *types.Block
+        result CommitResult
+}
+
+// observerEntry is a registered observer; the pointer identifies it for
+// removal
+type observerEntry struct {
+        observer BlockCommittedObserver
+}
+
+// RegisterCommitObserver subscribes observer to committed blocks and returns
+// a function that unsubscribes it
+func (bc *Blockchain) RegisterCommitObserver(observer BlockCommittedObserver) func() {
+        entry := &observerEntry{observer: observer}
+
+        bc.observerMu.Lock()
+        bc.observers = append(bc.observers, entry)
+        bc.observerMu.Unlock()
+
+        return func() {
+                bc.observerMu.Lock()
+                defer bc.observerMu.Unlock()
+                for i, registered := range bc.observers {
+                        if registered == entry {
+                                bc.observers = append(bc.observers[:i:i], bc.observers[i+1:]...)
+                                return
+                        }
+                }
+        }
+}
+
+// queueCommitted records a block committed while bc.mu is held, to be
+// reported once it is released. Caller must hold bc.mu.
+func (bc *Blockchain) queueCommitted(block *types.Block, result CommitResult) {
+        bc.commitQueue = append(bc.commitQueue, committedBlock{block: block, result: result})
+}
+
+// unlockAndNotify releases bc.mu and reports the blocks committed while it
+// was held. The notify lock is taken before bc.mu is released so commits
+// from concurrent callers reach observers in chain order.
+func (bc *Blockchain) unlockAndNotify() {
+        committed := bc.commitQueue
+        bc.commitQueue = nil
+        if len(committed) == 0 {
+                bc.mu.Unlock()
+                return
+        }
+
+        bc.notifyMu.Lock()
+        bc.mu.Unlock()
+        defer bc.notifyMu.Unlock()
+
+        bc.observerMu.RLock()
+        observers := append([]*observerEntry{}, bc.observers...)
+        bc.observerMu.RUnlock()
+
+        for _, commit := range committed {
+                for _, entry := range observers {
+                        bc.notifyObserver(entry.observer, commit)
+                }
+        }
+}
+
+// notifyObserver reports one commit to one observer, so a failing observer
+// cannot stop the others from hearing about it
+func (bc *Blockchain) notifyObserver(observer BlockCommittedObserver, commit committedBlock) {
+        defer func() {
+                if r := recover(); r != nil {
+                        bc.logger.LogError("blockchain", "commit_observer", fmt.Errorf("observer panicked: %v", r), logrus.Fields{
+                                "observer": fmt.Sprintf("%T", observer),
+                                "block_hash": commit.block.Hash,
+                                "block_index": commit.block.Index,
+                                "timestamp": time.Now().UTC(),
+                        })
+                }
+        }()
+        observer.OnBlockCommitted(commit.block, commit.result)
+}
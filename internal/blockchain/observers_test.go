@@ -0,0 +1,145 @@
+package blockchain
+
+import (
+	"sync"
+	"testing"
+
+	"lscc-blockchain/internal/consensus"
+	"lscc-blockchain/pkg/types"
+)
+
+// spyObserver records every commit it is told about
+type spyObserver struct {
+	mu      sync.Mutex
+	blocks  []*types.Block
+	results []CommitResult
+}
+
+func (s *spyObserver) OnBlockCommitted(block *types.Block, result CommitResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocks = append(s.blocks, block)
+	s.results = append(s.results, result)
+}
+
+func (s *spyObserver) commits() ([]*types.Block, []CommitResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*types.Block(nil), s.blocks...), append([]CommitResult(nil), s.results...)
+}
+
+// checkCommits fails unless spy was told about exactly bc's main-chain
+// blocks from index 1 to height, in order
+func checkCommits(t *testing.T, bc *Blockchain, spy *spyObserver, height int64) {
+	t.Helper()
+	blocks, results := spy.commits()
+	if int64(len(blocks)) != height {
+		t.Fatalf("observer told of %d commits, want %d", len(blocks), height)
+	}
+	for i, block := range blocks {
+		committed, err := bc.GetBlockByIndex(int64(i + 1))
+		if err != nil {
+			t.Fatalf("no block %d: %v", i+1, err)
+		}
+		if block.Hash != committed.Hash || results[i].Height != committed.Index {
+			t.Fatalf("commit %d reported block %d at height %d, want block %d", i, block.Index, results[i].Height, committed.Index)
+		}
+	}
+}
+
+func TestObserverCalledOncePerCommittedBlock(t *testing.T) {
+	for _, algorithm := range consensus.RegisteredAlgorithms() {
+		t.Run(algorithm, func(t *testing.T) {
+			bc := newTestBlockchain(t, algorithm, nil)
+			addValidators(t, bc, 4)
+			sender := newTestAccount(t)
+			recipient := newTestAccountOnShard(t, sender)
+			fund(t, bc, sender.address, 1000)
+
+			spy := &spyObserver{}
+			bc.RegisterCommitObserver(spy)
+
+			for nonce := int64(1); nonce <= 3; nonce++ {
+				if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, nonce)); err != nil {
+					t.Fatalf("failed to submit: %v", err)
+				}
+				bc.processConsensusRound()
+			}
+			// A round with nothing to commit reports nothing
+			bc.processConsensusRound()
+
+			// Only lscc and pbft reach agreement in a single local round;
+			// whatever the others commit is still reported exactly once
+			height := bc.GetBlockHeight()
+			if (algorithm == "lscc" || algorithm == "pbft") && height != 3 {
+				t.Fatalf("height %d after three rounds, want 3", height)
+			}
+			checkCommits(t, bc, spy, height)
+			_, results := spy.commits()
+			for _, result := range results {
+				if result.Algorithm != algorithm {
+					t.Fatalf("commit attributed to %q, want %q", result.Algorithm, algorithm)
+				}
+			}
+		})
+	}
+}
+
+func TestObserverSeesPeerBlocksInChainOrder(t *testing.T) {
+	ahead, behind := newPeerChains(t, 3)
+	spy := &spyObserver{}
+	behind.RegisterCommitObserver(spy)
+
+	// Blocks arriving out of order wait as orphans and are reported once
+	// each, in chain order, when their parent arrives
+	blocks := blockRange(t, ahead, 1, 3)
+	for _, i := range []int{2, 1, 0} {
+		if err := behind.AddOrphan(blocks[i]); err != nil {
+			t.Fatalf("failed to accept block %d: %v", blocks[i].Index, err)
+		}
+	}
+	checkCommits(t, behind, spy, 3)
+
+	// Seeing a block again commits nothing
+	behind.AddOrphan(blocks[2])
+	checkCommits(t, behind, spy, 3)
+}
+
+func TestObserverUnsubscribeAndPanics(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", nil)
+	addValidators(t, bc, 4)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	// An observer may read the chain while being notified
+	var heights []int64
+	bc.RegisterCommitObserver(BlockCommittedFunc(func(block *types.Block, result CommitResult) {
+		heights = append(heights, bc.GetBlockHeight())
+		panic("observer failure")
+	}))
+	spy := &spyObserver{}
+	unsubscribe := bc.RegisterCommitObserver(spy)
+
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, 1)); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	bc.processConsensusRound()
+	checkCommits(t, bc, spy, 1)
+	if len(heights) != 1 || heights[0] != 1 {
+		t.Fatalf("panicking observer saw heights %v, want [1]", heights)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if err := bc.SubmitTransaction(signedTransfer(t, sender, recipient, 10, 10, 2)); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	bc.processConsensusRound()
+	if blocks, _ := spy.commits(); len(blocks) != 1 {
+		t.Fatalf("unsubscribed observer told of %d commits, want 1", len(blocks))
+	}
+	if len(heights) != 2 {
+		t.Fatalf("remaining observer told of %d commits, want 2", len(heights))
+	}
+}
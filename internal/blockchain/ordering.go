@@ -0,0 +1,94 @@
+package blockchain
+
+import (
+        "container/heap"
+        "errors"
+        "fmt"
+        "lscc-blockchain/pkg/types"
+        "sort"
+)
+
+// ErrTransactionOrder is returned for a block whose transactions are not in
+// the order OrderTransactions gives them
+var ErrTransactionOrder = errors.New("transactions are not in canonical order")
+
+// OrderTransactions returns transactions in the canonical block order, which
+// depends only on the set of transactions and not on how they were
+// gathered, so every node assembling a block from the same transactions gets
+// the same Merkle root. Each sender's transactions keep ascending nonce
+// order; between senders, the next transaction with the higher fee goes
+// first, then the lower nonce, then the lower ID. Any prefix of the result is
+// itself in canonical order, so a block can be cut short to fit its limits.
+func OrderTransactions(transactions []*types.Transaction) []*types.Transaction {
+        bySender := make(map[string][]*types.Transaction)
+        for _, tx := range transactions {
+                bySender[tx.From] = append(bySender[tx.From], tx)
+        }
+
+        queues := make(senderQueues, 0, len(bySender))
+        for _, txs := range bySender {
+                sort.Slice(txs, func(i, j int) bool {
+                        if txs[i].Nonce != txs[j].Nonce {
+                                return txs[i].Nonce < txs[j].Nonce
+                        }
+                        return txs[i].ID < txs[j].ID
+                })
+                queues = append(queues, txs)
+        }
+        heap.Init(&queues)
+
+        ordered := make([]*types.Transaction, 0, len(transactions))
+        for queues.Len() > 0 {
+                ordered = append(ordered, queues[0][0])
+                if queues[0] = queues[0][1:]; len(queues[0]) == 0 {
+                        heap.Pop(&queues)
+                } else {
+                        heap.Fix(&queues, 0)
+                }
+        }
+        return ordered
+}
+
+// CheckTransactionOrder returns an ErrTransactionOrder error naming the
+// first position at which transactions differ from their canonical order
+func CheckTransactionOrder(transactions []*types.Transaction) error {
+        for i, tx := range OrderTransactions(transactions) {
+                if transactions[i].ID != tx.ID {
+                        return fmt.Errorf("%w: position %d holds %s, expected %s", ErrTransactionOrder, i, transactions[i].ID, tx.ID)
+                }
+        }
+        return nil
+}
+
+// senderQueues is a heap of per-sender transaction lists, each in nonce
+// order, keyed on the transaction at the head of each list
+type senderQueues [][]*types.Transaction
+
+func (q senderQueues) Len() int { return len(q) }
+
+func (q senderQueues) Less(i, j int) bool {
+        a, b := q[i][0], q[j][0]
+        if a.Fee != b.Fee {
+                return a.Fee > b.Fee
+        }
+        if a.Nonce != b.Nonce {
+                return a.Nonce < b.Nonce
+        }
+        if a.ID != b.ID {
+                return a.ID < b.ID
+        }
+        return a.From < b.From
+}
+
+func (q senderQueues) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *senderQueues) Push(x interface{}) { *q = append(*q, x.([]*types.Transaction)) }
+
+func (q *senderQueues) Pop() interface{} {
+        old := *q
+        n := len(old)
+        item := old[n-1]
+        old[n-1] = nil
+        *q = old[:n-1]
+        return item
+}
@@ -0,0 +1,190 @@
+package blockchain
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"lscc-blockchain/pkg/types"
+)
+
+// orderingSet returns three senders and transfers from them whose fees and
+// nonces exercise every ordering rule
+func orderingSet(t *testing.T) ([]testAccount, []*types.Transaction) {
+	t.Helper()
+	senders := []testAccount{newTestAccount(t), newTestAccount(t), newTestAccount(t)}
+	recipient := newTestAccount(t)
+	txs := []*types.Transaction{
+		// The first sender's better paying transaction waits for its nonce 1
+		signedTransfer(t, senders[0], recipient, 10, 10, 1),
+		signedTransfer(t, senders[0], recipient, 10, 40, 2),
+		// The other two tie on fee and nonce, so their IDs decide
+		signedTransfer(t, senders[1], recipient, 10, 20, 1),
+		signedTransfer(t, senders[2], recipient, 10, 20, 1),
+		signedTransfer(t, senders[2], recipient, 10, 20, 2),
+	}
+	return senders, txs
+}
+
+func shuffled(txs []*types.Transaction, seed int64) []*types.Transaction {
+	out := append([]*types.Transaction(nil), txs...)
+	rand.New(rand.NewSource(seed)).Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	return out
+}
+
+// gossiped shuffles txs the way gossip might deliver them: senders
+// interleave in any order, but each sender's nonces arrive in sequence
+func gossiped(txs []*types.Transaction, seed int64) []*types.Transaction {
+	out := shuffled(txs, seed)
+	bySender := make(map[string][]*types.Transaction)
+	for _, tx := range out {
+		bySender[tx.From] = append(bySender[tx.From], tx)
+	}
+	for _, pending := range bySender {
+		sort.Slice(pending, func(i, j int) bool { return pending[i].Nonce < pending[j].Nonce })
+	}
+	for i, tx := range out {
+		out[i] = bySender[tx.From][0]
+		bySender[tx.From] = bySender[tx.From][1:]
+	}
+	return out
+}
+
+func txIDs(txs []*types.Transaction) []string {
+	ids := make([]string, len(txs))
+	for i, tx := range txs {
+		ids[i] = tx.ID
+	}
+	return ids
+}
+
+func TestOrderTransactionsFollowsFeeNonceAndID(t *testing.T) {
+	senders, txs := orderingSet(t)
+	ordered := OrderTransactions(shuffled(txs, 1))
+
+	tied := []*types.Transaction{txs[2], txs[3]}
+	if tied[1].ID < tied[0].ID {
+		tied[0], tied[1] = tied[1], tied[0]
+	}
+	// The fee 20 nonce 1 pair by ID, then the fee 20 nonce 2, then the
+	// first sender's transactions in nonce order
+	want := []*types.Transaction{tied[0], tied[1], txs[4], txs[0], txs[1]}
+	if got := txIDs(ordered); len(got) != len(want) {
+		t.Fatalf("ordered %d transactions, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if ordered[i].ID != want[i].ID {
+			t.Fatalf("position %d: %s (fee %d, nonce %d), want %s (fee %d, nonce %d)", i,
+				ordered[i].ID, ordered[i].Fee, ordered[i].Nonce, want[i].ID, want[i].Fee, want[i].Nonce)
+		}
+	}
+
+	// No sender's nonces go backwards, whatever the fees
+	last := make(map[string]int64)
+	for _, tx := range ordered {
+		if tx.Nonce < last[tx.From] {
+			t.Fatalf("sender %s: nonce %d after %d", tx.From, tx.Nonce, last[tx.From])
+		}
+		last[tx.From] = tx.Nonce
+	}
+	if len(last) != len(senders) {
+		t.Fatalf("ordered transactions from %d senders, want %d", len(last), len(senders))
+	}
+
+	if err := CheckTransactionOrder(ordered); err != nil {
+		t.Fatalf("canonical order refused: %v", err)
+	}
+	if err := CheckTransactionOrder(ordered[:3]); err != nil {
+		t.Fatalf("canonical prefix refused: %v", err)
+	}
+}
+
+func TestNodesAssembleIdenticalBlocks(t *testing.T) {
+	senders, txs := orderingSet(t)
+
+	// Each node receives the transactions in a different order
+	if a, b := txIDs(gossiped(txs, 1)), txIDs(gossiped(txs, 2)); strings.Join(a, ",") == strings.Join(b, ",") {
+		t.Fatal("both nodes would receive the transactions in the same order")
+	}
+	var blocks []*types.Block
+	for seed := int64(1); seed <= 2; seed++ {
+		bc := newTestBlockchain(t, "pbft", nil)
+		addValidators(t, bc, 4)
+		for _, sender := range senders {
+			fund(t, bc, sender.address, 1000)
+		}
+		for _, tx := range gossiped(txs, seed) {
+			if err := bc.SubmitTransaction(tx); err != nil {
+				t.Fatalf("node %d: failed to submit: %v", seed, err)
+			}
+		}
+		bc.processConsensusRound()
+		block, err := bc.GetBlockByIndex(1)
+		if err != nil {
+			t.Fatalf("node %d: no block committed: %v", seed, err)
+		}
+		blocks = append(blocks, block)
+	}
+
+	first, second := txIDs(blocks[0].Transactions), txIDs(blocks[1].Transactions)
+	if len(first) != len(txs) || len(second) != len(txs) {
+		t.Fatalf("blocks hold %d and %d transactions, want %d", len(first), len(second), len(txs))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("position %d: nodes ordered %s and %s", i, first[i], second[i])
+		}
+	}
+	if blocks[0].MerkleRoot != blocks[1].MerkleRoot {
+		t.Fatalf("nodes computed Merkle roots %s and %s", blocks[0].MerkleRoot, blocks[1].MerkleRoot)
+	}
+
+	// Two block managers given the same set in any order agree as well
+	bm := NewBlockManager(discardLogger(), 0, 0, 0, "test")
+	genesis := bm.CreateGenesisBlock()
+	for seed := int64(3); seed <= 5; seed++ {
+		block, err := NewBlockManager(discardLogger(), 0, 0, 0, "test").CreateBlock(genesis, shuffled(txs, seed), "proposer", 0)
+		if err != nil {
+			t.Fatalf("failed to create block: %v", err)
+		}
+		if block.MerkleRoot != blocks[0].MerkleRoot {
+			t.Fatalf("shuffle %d: Merkle root %s, want %s", seed, block.MerkleRoot, blocks[0].MerkleRoot)
+		}
+	}
+}
+
+func TestBlockOutOfCanonicalOrderRejected(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", nil)
+	senders, txs := orderingSet(t)
+	for _, sender := range senders {
+		fund(t, bc, sender.address, 1000)
+	}
+
+	block, err := bc.blockManager.CreateBlock(bc.GetLatestBlock(), txs, "proposer", 0)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+
+	// A producer that swaps two transactions and rebuilds the Merkle root
+	// and hash still breaks the order
+	swapped := *block
+	swapped.Transactions = append([]*types.Transaction(nil), block.Transactions...)
+	swapped.Transactions[0], swapped.Transactions[1] = swapped.Transactions[1], swapped.Transactions[0]
+	swapped.MerkleRoot = NewMerkleTree(swapped.Transactions).GetRootHash()
+	swapped.Hash = swapped.ComputeHash()
+
+	if err := CheckTransactionOrder(swapped.Transactions); !errors.Is(err, ErrTransactionOrder) {
+		t.Fatalf("expected ErrTransactionOrder, got %v", err)
+	}
+	if err := bc.ValidateBlock(&swapped); !errors.Is(err, ErrTransactionOrder) {
+		t.Fatalf("validating a misordered block: expected ErrTransactionOrder, got %v", err)
+	}
+	if err := bc.AddBlock(&swapped); err == nil {
+		t.Fatal("misordered block added")
+	}
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatalf("canonically ordered block refused: %v", err)
+	}
+}
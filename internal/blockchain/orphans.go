@@ -0,0 +1,87 @@
+package blockchain
+
+import (
+        "container/list"
+        "lscc-blockchain/pkg/types"
+)
+
+// orphanPool holds blocks whose parent has not arrived yet, indexed by the
+// missing parent hash so they can be found when it does. When full, the
+// oldest orphan is evicted to make room. Callers must hold bc.mu.
+type orphanPool struct {
+        capacity int
+        byHash   map[string]*list.Element  // orphan hash -> element in order
+        byParent map[string][]*types.Block // missing parent hash -> orphans
+        order    *list.List                // orphans, oldest first
+}
+
+func newOrphanPool(capacity int) *orphanPool {
+        return &orphanPool{
+                capacity: capacity,
+                byHash:   make(map[string]*list.Element),
+                byParent: make(map[string][]*types.Block),
+                order:    list.New(),
+        }
+}
+
+// add buffers block, returning the orphan evicted to make room, if any
+func (p *orphanPool) add(block *types.Block) (evicted *types.Block) {
+        if _, exists := p.byHash[block.Hash]; exists {
+                return nil
+        }
+
+        if p.order.Len() >= p.capacity {
+                evicted = p.order.Front().Value.(*types.Block)
+                p.remove(evicted)
+        }
+
+        p.byHash[block.Hash] = p.order.PushBack(block)
+        p.byParent[block.PreviousHash] = append(p.byParent[block.PreviousHash], block)
+        return evicted
+}
+
+// contains reports whether a block is buffered
+func (p *orphanPool) contains(hash string) bool {
+        _, exists := p.byHash[hash]
+        return exists
+}
+
+// takeChildren removes and returns the orphans waiting for parentHash, in
+// arrival order
+func (p *orphanPool) takeChildren(parentHash string) []*types.Block {
+        children := p.byParent[parentHash]
+        delete(p.byParent, parentHash)
+        for _, child := range children {
+                p.order.Remove(p.byHash[child.Hash])
+                delete(p.byHash, child.Hash)
+        }
+        return children
+}
+
+// remove drops a buffered block
+func (p *orphanPool) remove(block *types.Block) {
+        element, exists := p.byHash[block.Hash]
+        if !exists {
+                return
+        }
+        p.order.Remove(element)
+        delete(p.byHash, block.Hash)
+
+        siblings := p.byParent[block.PreviousHash]
+        for i, sibling := range siblings {
+                if sibling.Hash == block.Hash {
+                        siblings = append(siblings[:i], siblings[i+1:]...)
+                        break
+                }
+        }
+        if len(siblings) == 0 {
+                delete(p.byParent, block.PreviousHash)
+        } else {
+                p.byParent[block.PreviousHash] = siblings
+        }
+}
+
+// size returns the number of buffered orphans
+func (p *orphanPool) size() int {
+        return p.order.Len()
+}
@@ -0,0 +1,174 @@
+package blockchain
+
+import (
+        "lscc-blockchain/pkg/types"
+        "math/big"
+        "sort"
+        "sync"
+        "time"
+)
+
+// accruedRewardKey holds the rewards paid to a validator by the blocks on
+// the main chain
+func accruedRewardKey(address string) string {
+        return "rewards:accrued:" + address
+}
+
+// roundVoters collects the validators whose votes the current consensus
+// round counted, as reported by the engine's participation hook
+type roundVoters struct {
+        mu   sync.Mutex
+        seen map[string]bool
+}
+
+func newRoundVoters() *roundVoters {
+        return &roundVoters{seen: make(map[string]bool)}
+}
+
+// reset forgets the voters of the last round
+func (rv *roundVoters) reset() {
+        rv.mu.Lock()
+        defer rv.mu.Unlock()
+        rv.seen = make(map[string]bool)
+}
+
+func (rv *roundVoters) record(address string) {
+        rv.mu.Lock()
+        defer rv.mu.Unlock()
+        rv.seen[address] = true
+}
+
+// list returns the voters recorded since the last reset, sorted
+func (rv *roundVoters) list() []string {
+        rv.mu.Lock()
+        defer rv.mu.Unlock()
+        voters := make([]string, 0, len(rv.seen))
+        for address := range rv.seen {
+                voters = append(voters, address)
+        }
+        sort.Strings(voters)
+        return voters
+}
+
+// ValidatorRewards is a validator together with what it has earned
+type ValidatorRewards struct {
+        Address        string    `json:"address"`
+        Stake          int64     `json:"stake"`
+        Status         string    `json:"status"`
+        Reputation     float64   `json:"reputation"`
+        ShardID        int       `json:"shard_id"`
+        LastActive     time.Time `json:"last_active"`
+        InEpoch        bool      `json:"in_epoch"`        // member of the current epoch's set, or of every round when epochs are disabled
+        AccruedRewards int64     `json:"accrued_rewards"` // subsidy and tips paid to it by the blocks on the main chain
+        Balance        int64     `json:"balance"`
+}
+
+// blockTips returns the fees the transactions of block paid above its base
+// fee, leaving out those in failed, whose fees were never collected
+func blockTips(block *types.Block, failed map[string]error) int64 {
+        var tips int64
+        for _, tx := range block.Transactions {
+                if _, exists := failed[tx.ID]; exists {
+                        continue
+                }
+                tips += tx.Fee - burnedFee(tx, block.BaseFee)
+        }
+        return tips
+}
+
+// splitRewards divides pot between recipients in proportion to their
+// stakes, rounding down, and gives what rounding leaves to proposer when it
+// is a recipient or otherwise to the first. With no recipients, proposer is
+// paid all of it.
+func splitRewards(pot int64, proposer string, recipients []string, stakes map[string]int64) map[string]int64 {
+        if pot <= 0 {
+                return nil
+        }
+        var total int64
+        for _, address := range recipients {
+                total += stakes[address]
+        }
+        if len(recipients) == 0 || total <= 0 {
+                return map[string]int64{proposer: pot}
+        }
+
+        payouts := make(map[string]int64, len(recipients))
+        paid := int64(0)
+        share := new(big.Int)
+        for _, address := range recipients {
+                // In big integers, as pot times a stake can overflow int64
+                share.Mul(big.NewInt(pot), big.NewInt(stakes[address]))
+                share.Quo(share, big.NewInt(total))
+                payouts[address] = share.Int64()
+                paid += payouts[address]
+        }
+
+        remainder := recipients[0]
+        if _, exists := payouts[proposer]; exists {
+                remainder = proposer
+        }
+        payouts[remainder] += pot - paid
+        return payouts
+}
+
+// blockPayoutsLocked returns what each validator is paid for block out of
+// pot, its subsidy and tips, split by stake between its proposer and voters
+// that are in good standing: active or inactive, not slashed or jailed, and
+// staking something. The payouts a block carries are never trusted, as they
+// are not hashed; the voters are, so the split is the same on every node.
+// Caller must hold bc.mu.
+func (bc *Blockchain) blockPayoutsLocked(block *types.Block, pot int64) map[string]int64 {
+        participants := map[string]bool{block.Validator: true}
+        for _, voter := range block.Voters {
+                participants[voter] = true
+        }
+
+        stakes := make(map[string]int64)
+        recipients := make([]string, 0, len(participants))
+        for _, validator := range bc.validators {
+                if !participants[validator.Address] || validator.Stake <= 0 {
+                        continue
+                }
+                if validator.Status != validatorActive && validator.Status != validatorInactive {
+                        continue
+                }
+                if _, exists := stakes[validator.Address]; !exists {
+                        recipients = append(recipients, validator.Address)
+                }
+                stakes[validator.Address] = validator.Stake
+        }
+        sort.Strings(recipients)
+        return splitRewards(pot, block.Validator, recipients, stakes)
+}
+
+// GetValidatorRewards returns every validator with the rewards it has
+// accrued and its balance
+func (bc *Blockchain) GetValidatorRewards() []ValidatorRewards {
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+
+        inEpoch := make(map[string]bool)
+        members := bc.validators
+        if bc.epochs.current != nil {
+                members = bc.epochs.validators
+        }
+        for _, validator := range members {
+                inEpoch[validator.Address] = true
+        }
+
+        validators := make([]ValidatorRewards, 0, len(bc.validators))
+        for _, validator := range bc.validators {
+                validators = append(validators, ValidatorRewards{
+                        Address:        validator.Address,
+                        Stake:          validator.Stake,
+                        Status:         validator.Status,
+                        Reputation:     validator.Reputation,
+                        ShardID:        validator.ShardID,
+                        LastActive:     validator.LastActive,
+                        InEpoch:        inEpoch[validator.Address],
+                        AccruedRewards: bc.accountState.AccruedRewards(validator.Address),
+                        Balance:        bc.accountState.GetBalance(validator.Address),
+                })
+        }
+        return validators
+}
@@ -0,0 +1,486 @@
+package blockchain
+
+import (
+        "errors"
+        "fmt"
+        "lscc-blockchain/internal/storage"
+        "lscc-blockchain/internal/utils"
+        "lscc-blockchain/pkg/types"
+        "sync"
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// AccountState tracks account balances persisted in the database
+type AccountState struct {
+        db     storage.Database
+        logger *utils.Logger
+        mu     sync.Mutex
+        staged *storage.WriteSet // receives writes instead of db while a block is staged
+}
+
+// PendingTransfer is the escrowed half of a cross-shard transfer whose sender
+// has been debited but whose recipient has not been credited yet
+type PendingTransfer struct {
+        TxID      string    `json:"tx_id"`
+        From      string    `json:"from"`
+        To        string    `json:"to"`
+        Amount    int64     `json:"amount"`
+        CreatedAt time.Time `json:"created_at"`
+}
+
+// Outcomes of a settled cross-shard transfer, kept so that reverting its
+// transaction undoes exactly what the settlement did
+const (
+        transferCommitted = "committed"
+        transferAborted   = "aborted"
+)
+
+// NewAccountState creates a new account state backed by db
+func NewAccountState(db storage.Database, logger *utils.Logger) *AccountState {
+        return &AccountState{
+                db:     db,
+                logger: logger,
+        }
+}
+
+// GetBalance returns the committed balance of an address
+func (as *AccountState) GetBalance(address string) int64 {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+        return as.balance(address)
+}
+
+// ApplyTransaction applies a committed transaction to account balances.
+// Fees are credited to feeRecipient. Cross-shard transactions only perform
+// the debit phase; the recipient is credited by CommitTransfer.
+func (as *AccountState) ApplyTransaction(tx *types.Transaction, feeRecipient string) error {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+        return as.applyLocked(tx, feeRecipient, 0)
+}
+
+// StageTransactions applies txs as ApplyTransaction would, but records the
+// writes in ws instead of the database so they can be committed atomically
+// with the block. Of each fee, up to baseFee is burned; the tip above it is
+// credited to no one, but paid out with the block's rewards by
+// StageRewards. Transactions that fail are returned by ID; the rest stay
+// staged.
+func (as *AccountState) StageTransactions(ws *storage.WriteSet, txs []*types.Transaction, baseFee int64) map[string]error {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+
+        as.staged = ws
+        defer func() { as.staged = nil }()
+
+        failed := make(map[string]error)
+        for _, tx := range txs {
+                if err := as.applyLocked(tx, "", baseFee); err != nil {
+                        failed[tx.ID] = err
+                }
+        }
+        return failed
+}
+
+// StageRewards credits each validator in payouts with its reward for a
+// block, and adds it to the rewards the validator has accrued, in ws, to be
+// committed with the block
+func (as *AccountState) StageRewards(ws *storage.WriteSet, payouts map[string]int64) error {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+
+        as.staged = ws
+        defer func() { as.staged = nil }()
+        for address, amount := range payouts {
+                if err := as.credit(address, amount); err != nil {
+                        return err
+                }
+                if err := as.saveState(accruedRewardKey(address), as.counterLocked(accruedRewardKey(address))+amount); err != nil {
+                        return fmt.Errorf("failed to record reward of %s: %w", address, err)
+                }
+        }
+        return nil
+}
+
+// RevertRewards takes back the rewards a block paid when it leaves the main
+// chain, in ws
+func (as *AccountState) RevertRewards(ws *storage.WriteSet, payouts map[string]int64) error {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+
+        as.staged = ws
+        defer func() { as.staged = nil }()
+        for address, amount := range payouts {
+                if err := as.debit(address, amount); err != nil {
+                        return err
+                }
+                if err := as.saveState(accruedRewardKey(address), as.counterLocked(accruedRewardKey(address))-amount); err != nil {
+                        return fmt.Errorf("failed to revert reward of %s: %w", address, err)
+                }
+        }
+        return nil
+}
+
+// RevertBlockReward takes back the subsidy of a block committed before
+// rewards were split, which paid it all to producer, when the block leaves
+// the main chain, in ws
+func (as *AccountState) RevertBlockReward(ws *storage.WriteSet, producer string, reward int64) error {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+
+        as.staged = ws
+        defer func() { as.staged = nil }()
+        return as.debit(producer, reward)
+}
+
+// AccruedRewards returns the rewards paid to address by the blocks on the
+// main chain
+func (as *AccountState) AccruedRewards(address string) int64 {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+        return as.counterLocked(accruedRewardKey(address))
+}
+
+// StageBurn adds burned to the running total of burned base fees in ws, to
+// be committed with the block
+func (as *AccountState) StageBurn(ws *storage.WriteSet, burned int64) error {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+
+        as.staged = ws
+        defer func() { as.staged = nil }()
+        return as.saveState(supplyBurnedKey, as.counterLocked(supplyBurnedKey)+burned)
+}
+
+// RevertBurn takes a block's burned base fees back off the running total
+// when the block leaves the main chain, in ws
+func (as *AccountState) RevertBurn(ws *storage.WriteSet, burned int64) error {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+
+        as.staged = ws
+        defer func() { as.staged = nil }()
+        return as.saveState(supplyBurnedKey, as.counterLocked(supplyBurnedKey)-burned)
+}
+
+// Burned returns the base fees burned by the blocks on the main chain
+func (as *AccountState) Burned() int64 {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+        return as.counterLocked(supplyBurnedKey)
+}
+
+// counterLocked returns the running total kept under key, 0 before the
+// first write
+func (as *AccountState) counterLocked(key string) int64 {
+        var total int64
+        if as.staged != nil {
+                deleted, found, err := as.staged.State(key, &total)
+                if deleted {
+                        return 0
+                }
+                if found && err == nil {
+                        return total
+                }
+        }
+        if err := as.db.GetState(key, &total); err != nil {
+                // Nothing recorded yet
+                return 0
+        }
+        return total
+}
+
+func (as *AccountState) applyLocked(tx *types.Transaction, feeRecipient string, baseFee int64) error {
+        switch tx.Type {
+        case "genesis":
+                return as.credit(tx.To, tx.Amount)
+        case "cross_shard":
+                return as.prepareTransfer(tx, feeRecipient, baseFee)
+        }
+
+        if err := as.debit(tx.From, tx.Amount+tx.Fee); err != nil {
+                return err
+        }
+        if err := as.credit(tx.To, tx.Amount); err != nil {
+                return err
+        }
+        return as.creditTip(tx, feeRecipient, baseFee)
+}
+
+// creditTip credits feeRecipient with what tx paid above baseFee. With no
+// feeRecipient the tip is left for the block's rewards.
+func (as *AccountState) creditTip(tx *types.Transaction, feeRecipient string, baseFee int64) error {
+        if feeRecipient == "" {
+                return nil
+        }
+        return as.credit(feeRecipient, tx.Fee-burnedFee(tx, baseFee))
+}
+
+// prepareTransfer debits the sender of a cross-shard transaction and escrows
+// the amount until the destination shard confirms
+func (as *AccountState) prepareTransfer(tx *types.Transaction, feeRecipient string, baseFee int64) error {
+        if err := as.debit(tx.From, tx.Amount+tx.Fee); err != nil {
+                return err
+        }
+        if err := as.creditTip(tx, feeRecipient, baseFee); err != nil {
+                return err
+        }
+
+        transfer := &PendingTransfer{
+                TxID:      tx.ID,
+                From:      tx.From,
+                To:        tx.To,
+                Amount:    tx.Amount,
+                CreatedAt: time.Now().UTC(),
+        }
+        if err := as.saveState(pendingTransferKey(tx.ID), transfer); err != nil {
+                return fmt.Errorf("failed to save pending transfer: %w", err)
+        }
+
+        as.logger.LogTransaction(tx.ID, "transfer_prepared", logrus.Fields{
+                "from":      tx.From,
+                "to":        tx.To,
+                "amount":    tx.Amount,
+                "timestamp": time.Now().UTC(),
+        })
+
+        return nil
+}
+
+// CommitTransfer credits the recipient of a prepared cross-shard transfer
+func (as *AccountState) CommitTransfer(txID string) error {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+
+        transfer, err := as.pendingTransfer(txID)
+        if err != nil {
+                return err
+        }
+
+        if err := as.credit(transfer.To, transfer.Amount); err != nil {
+                return err
+        }
+        if err := as.settleTransfer(txID, transferCommitted); err != nil {
+                return err
+        }
+
+        as.logger.LogTransaction(txID, "transfer_committed", logrus.Fields{
+                "to":        transfer.To,
+                "amount":    transfer.Amount,
+                "timestamp": time.Now().UTC(),
+        })
+
+        return nil
+}
+
+// AbortTransfer refunds the sender of a prepared cross-shard transfer. The fee
+// is not refunded.
+func (as *AccountState) AbortTransfer(txID string) error {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+
+        transfer, err := as.pendingTransfer(txID)
+        if err != nil {
+                return err
+        }
+
+        if err := as.credit(transfer.From, transfer.Amount); err != nil {
+                return err
+        }
+        if err := as.settleTransfer(txID, transferAborted); err != nil {
+                return err
+        }
+
+        as.logger.LogTransaction(txID, "transfer_aborted", logrus.Fields{
+                "from":      transfer.From,
+                "amount":    transfer.Amount,
+                "timestamp": time.Now().UTC(),
+        })
+
+        return nil
+}
+
+// settleTransfer replaces the escrow of a cross-shard transfer with its
+// outcome
+func (as *AccountState) settleTransfer(txID, outcome string) error {
+        if err := as.saveState(transferOutcomeKey(txID), outcome); err != nil {
+                return fmt.Errorf("failed to save transfer outcome: %w", err)
+        }
+        if err := as.deleteState(pendingTransferKey(txID)); err != nil {
+                return fmt.Errorf("failed to delete pending transfer: %w", err)
+        }
+        return nil
+}
+
+// RevertTransaction undoes the balance changes ApplyTransaction or
+// StageTransactions made for tx in a block charging baseFee, taking its tip
+// back from feeRecipient unless it is empty. The writes are recorded in ws.
+// Transactions must be reverted in the reverse order they were applied.
+func (as *AccountState) RevertTransaction(ws *storage.WriteSet, tx *types.Transaction, feeRecipient string, baseFee int64) error {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+
+        as.staged = ws
+        defer func() { as.staged = nil }()
+
+        switch tx.Type {
+        case "genesis":
+                return as.debit(tx.To, tx.Amount)
+        case "cross_shard":
+                // Drop the escrow, or undo whichever way the transfer settled
+                refund := tx.Amount
+                if _, err := as.pendingTransfer(tx.ID); err == nil {
+                        if err := as.deleteState(pendingTransferKey(tx.ID)); err != nil {
+                                return fmt.Errorf("failed to delete pending transfer: %w", err)
+                        }
+                } else {
+                        outcome, err := as.transferOutcome(tx.ID)
+                        if err != nil {
+                                return err
+                        }
+                        switch outcome {
+                        case transferCommitted:
+                                if err := as.debit(tx.To, tx.Amount); err != nil {
+                                        return err
+                                }
+                        case transferAborted:
+                                // The sender was refunded the amount when it aborted
+                                refund = 0
+                        default:
+                                return fmt.Errorf("unknown outcome %q for transfer %s", outcome, tx.ID)
+                        }
+                        if err := as.deleteState(transferOutcomeKey(tx.ID)); err != nil {
+                                return fmt.Errorf("failed to delete transfer outcome: %w", err)
+                        }
+                }
+                if err := as.revertTip(tx, feeRecipient, baseFee); err != nil {
+                        return err
+                }
+                return as.credit(tx.From, refund+tx.Fee)
+        default:
+                if err := as.debit(tx.To, tx.Amount); err != nil {
+                        return err
+                }
+        }
+
+        if err := as.revertTip(tx, feeRecipient, baseFee); err != nil {
+                return err
+        }
+        return as.credit(tx.From, tx.Amount+tx.Fee)
+}
+
+// revertTip takes back from feeRecipient the tip creditTip paid it for tx
+func (as *AccountState) revertTip(tx *types.Transaction, feeRecipient string, baseFee int64) error {
+        if feeRecipient == "" {
+                return nil
+        }
+        return as.debit(feeRecipient, tx.Fee-burnedFee(tx, baseFee))
+}
+
+// GetPendingTransfer returns a prepared cross-shard transfer by transaction ID
+func (as *AccountState) GetPendingTransfer(txID string) (*PendingTransfer, error) {
+        as.mu.Lock()
+        defer as.mu.Unlock()
+        return as.pendingTransfer(txID)
+}
+
+func (as *AccountState) pendingTransfer(txID string) (*PendingTransfer, error) {
+        var transfer PendingTransfer
+        if as.staged != nil {
+                deleted, found, err := as.staged.State(pendingTransferKey(txID), &transfer)
+                if deleted {
+                        return nil, fmt.Errorf("no pending transfer for transaction %s", txID)
+                }
+                if found {
+                        return &transfer, err
+                }
+        }
+        if err := as.db.GetState(pendingTransferKey(txID), &transfer); err != nil {
+                return nil, fmt.Errorf("no pending transfer for transaction %s: %w", txID, err)
+        }
+        return &transfer, nil
+}
+
+// transferOutcome returns how the cross-shard transfer of txID settled
+func (as *AccountState) transferOutcome(txID string) (string, error) {
+        var outcome string
+        if as.staged != nil {
+                deleted, found, err := as.staged.State(transferOutcomeKey(txID), &outcome)
+                if deleted {
+                        return "", fmt.Errorf("no settled transfer for transaction %s", txID)
+                }
+                if found {
+                        return outcome, err
+                }
+        }
+        if err := as.db.GetState(transferOutcomeKey(txID), &outcome); err != nil {
+                return "", fmt.Errorf("no settled transfer for transaction %s: %w", txID, err)
+        }
+        return outcome, nil
+}
+
+func (as *AccountState) balance(address string) int64 {
+        if as.staged != nil {
+                if balance, ok := as.staged.AccountBalance(address); ok {
+                        return balance
+                }
+        }
+        balance, err := as.db.GetAccountBalance(address)
+        if err != nil {
+                as.logger.LogError("state", "get_balance", err, logrus.Fields{
+                        "address":   address,
+                        "timestamp": time.Now().UTC(),
+                })
+                return 0
+        }
+        return balance
+}
+
+func (as *AccountState) debit(address string, amount int64) error {
+        balance := as.balance(address)
+        if amount > balance {
+                return fmt.Errorf("insufficient balance for %s: have %d, need %d", address, balance, amount)
+        }
+        return as.saveBalance(address, balance-amount)
+}
+
+func (as *AccountState) credit(address string, amount int64) error {
+        if amount == 0 {
+                return nil
+        }
+        if amount < 0 {
+                return errors.New("cannot credit a negative amount")
+        }
+        return as.saveBalance(address, as.balance(address)+amount)
+}
+
+func (as *AccountState) saveBalance(address string, balance int64) error {
+        if as.staged != nil {
+                return as.staged.SetAccountBalance(address, balance)
+        }
+        return as.db.SaveAccountBalance(address, balance)
+}
+
+func (as *AccountState) saveState(key string, value interface{}) error {
+        if as.staged != nil {
+                return as.staged.SetState(key, value)
+        }
+        return as.db.SaveState(key, value)
+}
+
+func (as *AccountState) deleteState(key string) error {
+        if as.staged != nil {
+                as.staged.DeleteState(key)
+                return nil
+        }
+        return as.db.DeleteState(key)
+}
+
+func pendingTransferKey(txID string) string {
+        return fmt.Sprintf("transfer:pending:%s", txID)
+}
+
+func transferOutcomeKey(txID string) string {
+        return fmt.Sprintf("transfer:outcome:%s", txID)
+}
@@ -0,0 +1,78 @@
+package blockchain
+
+import (
+	"context"
+	"testing"
+
+	"lscc-blockchain/internal/utils"
+)
+
+func TestTraceFollowsTransactionIntoConsensusRound(t *testing.T) {
+	bc := newTestBlockchain(t, "lscc", nil)
+	traces := bc.logger.EnableTraceRecording(10, 500)
+	addValidators(t, bc, 4)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	tx := signedTransfer(t, sender, recipient, 10, 10, 1)
+	ctx := utils.ContextWithTraceID(context.Background(), "req-round-1")
+	if err := bc.SubmitTransactionContext(ctx, tx); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	if tx.TraceID != "req-round-1" {
+		t.Fatalf("transaction took trace ID %q, want the request's", tx.TraceID)
+	}
+
+	bc.processConsensusRound()
+	block, err := bc.GetTransactionBlock(tx.ID)
+	if err != nil {
+		t.Fatalf("transaction not committed: %v", err)
+	}
+	if block.TraceID == "" || block.TraceID == tx.TraceID {
+		t.Fatalf("block carries trace ID %q, want the round's own", block.TraceID)
+	}
+	if committed := block.Transactions[0]; committed.TraceID != "req-round-1" {
+		t.Fatalf("committed transaction lost its trace ID: %q", committed.TraceID)
+	}
+
+	// The request's trace leads to the round that committed the
+	// transaction, and includes the consensus engine's lines for it
+	trace, found := traces.Get("req-round-1")
+	if !found {
+		t.Fatal("no trace recorded for the request")
+	}
+	if len(trace.Linked) != 1 || trace.Linked[0] != block.TraceID {
+		t.Fatalf("linked traces = %v, want the round %s", trace.Linked, block.TraceID)
+	}
+	components := make(map[string]bool)
+	actions := make(map[string]bool)
+	for _, event := range trace.Events {
+		if event.TraceID != "req-round-1" && event.TraceID != block.TraceID {
+			t.Fatalf("event %+v recorded under an unrelated trace", event)
+		}
+		components[event.Component] = true
+		actions[event.Action] = true
+	}
+	for _, component := range []string{"transaction", "consensus", "blockchain"} {
+		if !components[component] {
+			t.Fatalf("trace has no %s lines: %v", component, trace.Components)
+		}
+	}
+	for _, action := range []string{"submit", "round_start", "block_proposed", "block_added"} {
+		if !actions[action] {
+			t.Fatalf("trace has no %s line: %v", action, actions)
+		}
+	}
+
+	// Looking up the round alone does not pull in the request
+	round, found := traces.Get(block.TraceID)
+	if !found {
+		t.Fatal("no trace recorded for the round")
+	}
+	for _, event := range round.Events {
+		if event.TraceID == "req-round-1" {
+			t.Fatalf("round trace includes the request's line %+v", event)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package blockchain
 
 import (
-        "crypto/ecdsa"
         "encoding/json"
         "errors"
         "fmt"
@@ -10,14 +9,23 @@ import (
         "sync"
         "time"
 
+        "github.com/decred/dcrd/dcrec/secp256k1/v4"
         "github.com/sirupsen/logrus"
 )
 
+// ErrTransactionExpired is returned for a transaction older than the
+// configured TTL
+var ErrTransactionExpired = errors.New("transaction expired")
+
 // TransactionManager handles transaction operations
 type TransactionManager struct {
-        pool   *TransactionPool
-        logger *utils.Logger
-        mu     sync.RWMutex // Add mutex for thread safety
+        pool          *TransactionPool
+        logger        *utils.Logger
+        nonceProvider func(address string) int64 // Last committed nonce per sender
+        feePolicy     FeePolicy
+        txTTL         time.Duration // Age at which a transaction expires; 0 never expires
+        limits        AdmissionLimits
+        mu            sync.RWMutex // Add mutex for thread safety
 }
 
 // TransactionPool manages pending transactions
@@ -25,6 +33,9 @@ type TransactionPool struct {
         pending   map[string]*types.Transaction
         confirmed map[string]*types.Transaction
         failed    map[string]*types.Transaction
+        expired   map[string]*types.Transaction // dropped for exceeding the TTL, kept for receipts
+        senders   map[string]*senderUsage       // pending transactions and value per sender
+        bytes     int                           // encoded size of the pending transactions
         maxSize   int
         mu        sync.RWMutex // Add mutex for thread safety
 }
@@ -36,14 +47,83 @@ func NewTransactionManager(maxPoolSize int, logger *utils.Logger) *TransactionMa
                         pending:   make(map[string]*types.Transaction),
                         confirmed: make(map[string]*types.Transaction),
                         failed:    make(map[string]*types.Transaction),
+                        expired:   make(map[string]*types.Transaction),
+                        senders:   make(map[string]*senderUsage),
                         maxSize:   maxPoolSize,
                 },
                 logger: logger,
+                txTTL:  24 * time.Hour,
+        }
+}
+
+// SetTxTTL sets the age after which transactions expire. A ttl of 0 disables
+// expiry.
+func (tm *TransactionManager) SetTxTTL(ttl time.Duration) {
+        tm.mu.Lock()
+        defer tm.mu.Unlock()
+        tm.txTTL = ttl
+}
+
+// TxTTL returns the age after which transactions expire, 0 when they never do
+func (tm *TransactionManager) TxTTL() time.Duration {
+        tm.mu.RLock()
+        defer tm.mu.RUnlock()
+        return tm.txTTL
+}
+
+// SetNonceProvider sets the source of last committed nonces used for replay protection
+func (tm *TransactionManager) SetNonceProvider(provider func(address string) int64) {
+        tm.mu.Lock()
+        defer tm.mu.Unlock()
+        tm.nonceProvider = provider
+}
+
+// SetFeePolicy sets the fee floor new transactions must meet to enter the pool
+func (tm *TransactionManager) SetFeePolicy(policy FeePolicy) {
+        tm.mu.Lock()
+        defer tm.mu.Unlock()
+        tm.feePolicy = policy
+}
+
+// GetFeeEstimate returns the current fee floor and the pool state behind it
+func (tm *TransactionManager) GetFeeEstimate() FeeEstimate {
+        tm.mu.RLock()
+        defer tm.mu.RUnlock()
+        return tm.feePolicy.estimate(len(tm.pool.pending), tm.pool.maxSize)
+}
+
+// feeFloor returns the current fee floor. Caller must hold tm.mu.
+func (tm *TransactionManager) feeFloor() int64 {
+        return tm.feePolicy.Floor(len(tm.pool.pending), tm.pool.maxSize)
+}
+
+// GetNextNonce returns the nonce the next transaction from address must carry,
+// taking transactions already waiting in the pool into account
+func (tm *TransactionManager) GetNextNonce(address string) int64 {
+        tm.mu.RLock()
+        defer tm.mu.RUnlock()
+        return tm.pendingNonce(address) + 1
+}
+
+// pendingNonce returns the highest nonce known for address, committed or pending.
+// Caller must hold tm.mu.
+func (tm *TransactionManager) pendingNonce(address string) int64 {
+        var nonce int64
+        if tm.nonceProvider != nil {
+                nonce = tm.nonceProvider(address)
+        }
+        
+        for _, tx := range tm.pool.pending {
+                if tx.From == address && tx.Nonce > nonce {
+                        nonce = tx.Nonce
+                }
         }
+        
+        return nonce
 }
 
 // CreateTransaction creates a new transaction
-func (tm *TransactionManager) CreateTransaction(from, to string, amount, fee int64, data []byte, privateKey *ecdsa.PrivateKey) (*types.Transaction, error) {
+func (tm *TransactionManager) CreateTransaction(from, to string, amount, fee int64, data []byte, privateKey *secp256k1.PrivateKey) (*types.Transaction, error) {
         tm.logger.LogTransaction("", "create_transaction", logrus.Fields{
                 "from":   from,
                 "to":     to,
@@ -51,11 +131,8 @@ func (tm *TransactionManager) CreateTransaction(from, to string, amount, fee int
                 "fee":    fee,
         })
         
-        // Generate nonce
-        nonce, err := utils.GenerateNonce()
-        if err != nil {
-                return nil, fmt.Errorf("failed to generate nonce: %w", err)
-        }
+        // Use the next sequential nonce for the sender
+        nonce := tm.GetNextNonce(from)
         
         // Determine shard ID based on sender
         shardID := utils.GenerateShardKey(from, 4) // TODO: Get from config
@@ -84,11 +161,9 @@ func (tm *TransactionManager) CreateTransaction(from, to string, amount, fee int
         tx.ID = tx.Hash()
         
         // Sign transaction
-        signature, err := tm.signTransaction(tx, privateKey)
-        if err != nil {
+        if err := utils.SignTransaction(tx, privateKey); err != nil {
                 return nil, fmt.Errorf("failed to sign transaction: %w", err)
         }
-        tx.Signature = signature
         
         tm.logger.LogTransaction(tx.ID, "transaction_created", logrus.Fields{
                 "type":     txType,
@@ -99,39 +174,6 @@ func (tm *TransactionManager) CreateTransaction(from, to string, amount, fee int
         return tx, nil
 }
 
-// signTransaction signs a transaction
-func (tm *TransactionManager) signTransaction(tx *types.Transaction, privateKey *ecdsa.PrivateKey) (string, error) {
-        // Create signing data
-        signingData := struct {
-                From      string    `json:"from"`
-                To        string    `json:"to"`
-                Amount    int64     `json:"amount"`
-                Fee       int64     `json:"fee"`
-                Data      []byte    `json:"data,omitempty"`
-                Timestamp time.Time `json:"timestamp"`
-                Nonce     int64     `json:"nonce"`
-                ShardID   int       `json:"shard_id"`
-                Type      string    `json:"type"`
-        }{
-                From:      tx.From,
-                To:        tx.To,
-                Amount:    tx.Amount,
-                Fee:       tx.Fee,
-                Data:      tx.Data,
-                Timestamp: tx.Timestamp,
-                Nonce:     tx.Nonce,
-                ShardID:   tx.ShardID,
-                Type:      tx.Type,
-        }
-        
-        data, err := json.Marshal(signingData)
-        if err != nil {
-                return "", fmt.Errorf("failed to marshal signing data: %w", err)
-        }
-        
-        return utils.Sign(privateKey, data)
-}
-
 // ValidateTransaction validates a transaction
 func (tm *TransactionManager) ValidateTransaction(tx *types.Transaction) error {
         tm.logger.LogTransaction(tx.ID, "validate_transaction", logrus.Fields{
@@ -161,9 +203,8 @@ func (tm *TransactionManager) ValidateTransaction(tx *types.Transaction) error {
                 return errors.New("transaction must have a timestamp")
         }
         
-        // Check if transaction is too old (24 hours)
-        if time.Since(tx.Timestamp) > 24*time.Hour {
-                return errors.New("transaction is too old")
+        if tx.Expired(tm.txTTL, time.Now()) {
+                return fmt.Errorf("%w: older than %s", ErrTransactionExpired, tm.txTTL)
         }
         
         // Check if transaction is from the future (5 minutes tolerance)
@@ -180,40 +221,86 @@ func (tm *TransactionManager) ValidateTransaction(tx *types.Transaction) error {
                 return errors.New("invalid receiver address")
         }
         
-        // Validate signature (simplified - in production would verify with public key)
+        // Validate signature against the sender address
         if tx.Signature == "" {
                 return errors.New("transaction must be signed")
         }
         
+        if err := utils.VerifyTransactionSender(tx); err != nil {
+                return fmt.Errorf("invalid signature: %w", err)
+        }
+        
         // Verify transaction hash
         calculatedHash := tx.Hash()
         if tx.ID != calculatedHash {
                 return errors.New("transaction ID does not match calculated hash")
         }
         
+        tx.GasUsed = tx.Gas()
+        
         tm.logger.LogTransaction(tx.ID, "transaction_validated", logrus.Fields{
-                "valid": true,
+                "valid":    true,
+                "gas_used": tx.GasUsed,
         })
         
         return nil
 }
 
-// AddToPool adds a transaction to the pending pool
+// AddToPool adds a transaction to the pending pool. Transactions paying less
+// than the current fee floor are rejected with ErrFeeTooLow, and those that
+// would take their sender past its admission limits with ErrSenderLimit.
 func (tm *TransactionManager) AddToPool(tx *types.Transaction) error {
+        return tm.addToPool(tx, true)
+}
+
+func (tm *TransactionManager) addToPool(tx *types.Transaction, admit bool) error {
         tm.mu.Lock()
         defer tm.mu.Unlock()
         
         if len(tm.pool.pending) >= tm.pool.maxSize {
-                return errors.New("transaction pool is full")
+                return ErrMempoolFull
         }
         
         // Validate transaction
         if err := tm.ValidateTransaction(tx); err != nil {
-                tm.pool.failed[tx.ID] = tx
+                if errors.Is(err, ErrTransactionExpired) {
+                        tm.pool.expired[tx.ID] = tx
+                } else {
+                        tm.pool.failed[tx.ID] = tx
+                }
                 return fmt.Errorf("invalid transaction: %w", err)
         }
         
-        tm.pool.pending[tx.ID] = tx
+        // Price out spam, more aggressively as the pool fills
+        if floor := tm.feeFloor(); admit && tx.Fee < floor {
+                tm.logger.LogTransaction(tx.ID, "rejected_underpriced", logrus.Fields{
+                        "fee":       tx.Fee,
+                        "floor":     floor,
+                        "pool_size": len(tm.pool.pending),
+                })
+                return fmt.Errorf("%w: fee %d, floor %d", ErrFeeTooLow, tx.Fee, floor)
+        }
+        
+        // Reject replayed or out-of-order nonces
+        if expected := tm.pendingNonce(tx.From) + 1; tx.Nonce != expected {
+                tm.pool.failed[tx.ID] = tx
+                return fmt.Errorf("invalid transaction: nonce %d for %s, expected %d", tx.Nonce, tx.From, expected)
+        }
+        
+        // Keep any one sender from crowding out the rest
+        if admit {
+                if err := tm.checkAdmission(tx); err != nil {
+                        tm.logger.LogTransaction(tx.ID, "rejected_admission", logrus.Fields{
+                                "from":          tx.From,
+                                "pool_size":     len(tm.pool.pending),
+                                "pending_bytes": tm.pool.bytes,
+                                "error":         err.Error(),
+                        })
+                        return err
+                }
+        }
+        
+        tm.trackPending(tx)
         
         tm.logger.LogTransaction(tx.ID, "added_to_pool", logrus.Fields{
                 "pool_size": len(tm.pool.pending),
@@ -242,8 +329,12 @@ func (tm *TransactionManager) GetPendingTransactionsForShard(shardID int, limit
         
         var transactions []*types.Transaction
         count := 0
+        now := time.Now()
         
         for _, tx := range tm.pool.pending {
+                if tx.Expired(tm.txTTL, now) {
+                        continue
+                }
                 if tx.ShardID == shardID && count < limit {
                         transactions = append(transactions, tx)
                         count++
@@ -265,7 +356,7 @@ func (tm *TransactionManager) ConfirmTransaction(txID string) {
         defer tm.mu.Unlock()
         
         if tx, exists := tm.pool.pending[txID]; exists {
-                delete(tm.pool.pending, txID)
+                tm.untrackPending(tx)
                 tm.pool.confirmed[txID] = tx
                 
                 tm.logger.LogTransaction(txID, "transaction_confirmed", logrus.Fields{
@@ -275,21 +366,36 @@ func (tm *TransactionManager) ConfirmTransaction(txID string) {
         }
 }
 
-// FailTransaction moves a transaction from pending to failed
-func (tm *TransactionManager) FailTransaction(txID string, reason string) {
+// RequeueTransaction returns a confirmed transaction whose block was rolled
+// back to the pending pool. It was admitted once, so the fee floor and
+// sender limits are not applied again.
+func (tm *TransactionManager) RequeueTransaction(tx *types.Transaction) error {
+        tm.mu.Lock()
+        delete(tm.pool.confirmed, tx.ID)
+        tm.mu.Unlock()
+        
+        return tm.addToPool(tx, false)
+}
+
+// FailTransaction moves a transaction from pending to failed, reporting
+// whether it was pending
+func (tm *TransactionManager) FailTransaction(txID string, reason string) bool {
         tm.mu.Lock()
         defer tm.mu.Unlock()
         
-        if tx, exists := tm.pool.pending[txID]; exists {
-                delete(tm.pool.pending, txID)
-                tm.pool.failed[txID] = tx
-                
-                tm.logger.LogTransaction(txID, "transaction_failed", logrus.Fields{
-                        "reason":        reason,
-                        "pending_count": len(tm.pool.pending),
-                        "failed_count":  len(tm.pool.failed),
-                })
+        tx, exists := tm.pool.pending[txID]
+        if !exists {
+                return false
         }
+        tm.untrackPending(tx)
+        tm.pool.failed[txID] = tx
+        
+        tm.logger.LogTransaction(txID, "transaction_failed", logrus.Fields{
+                "reason":        reason,
+                "pending_count": len(tm.pool.pending),
+                "failed_count":  len(tm.pool.failed),
+        })
+        return true
 }
 
 // GetTransaction returns a transaction by ID from any pool
@@ -306,9 +412,39 @@ func (tm *TransactionManager) GetTransaction(txID string) (*types.Transaction, s
         if tx, exists := tm.pool.failed[txID]; exists {
                 return tx, "failed"
         }
+        if tx, exists := tm.pool.expired[txID]; exists {
+                return tx, "expired"
+        }
         return nil, ""
 }
 
+// ExpireTransactions moves pending transactions older than the TTL out of the
+// pool, keeping them only so their receipts report "expired". It returns the
+// IDs of the transactions dropped.
+func (tm *TransactionManager) ExpireTransactions() []string {
+        tm.mu.Lock()
+        defer tm.mu.Unlock()
+        
+        now := time.Now()
+        var expired []string
+        for txID, tx := range tm.pool.pending {
+                if !tx.Expired(tm.txTTL, now) {
+                        continue
+                }
+                tm.untrackPending(tx)
+                tm.pool.expired[txID] = tx
+                expired = append(expired, txID)
+                
+                tm.logger.LogTransaction(txID, "transaction_expired", logrus.Fields{
+                        "age":   now.Sub(tx.Timestamp).String(),
+                        "ttl":   tm.txTTL.String(),
+                        "from":  tx.From,
+                        "nonce": tx.Nonce,
+                })
+        }
+        return expired
+}
+
 // GetPoolStats returns transaction pool statistics
 func (tm *TransactionManager) GetPoolStats() *types.TransactionPool {
         tm.mu.RLock()
@@ -357,10 +493,18 @@ func (tm *TransactionManager) CleanupPool() {
                 }
         }
         
+        // Expired transactions are kept a day past their expiry for receipts
+        for txID, tx := range tm.pool.expired {
+                if tx.Timestamp.Add(tm.txTTL).Before(cutoff) {
+                        delete(tm.pool.expired, txID)
+                }
+        }
+        
         tm.logger.LogTransaction("", "pool_cleanup", logrus.Fields{
                 "pending_count":   len(tm.pool.pending),
                 "confirmed_count": len(tm.pool.confirmed),
                 "failed_count":    len(tm.pool.failed),
+                "expired_count":   len(tm.pool.expired),
                 "cutoff_time":     cutoff,
         })
 }
@@ -389,7 +533,7 @@ func (tm *TransactionManager) EstimateTransactionFee(tx *types.Transaction) int6
 }
 
 // CreateStakeTransaction creates a staking transaction
-func (tm *TransactionManager) CreateStakeTransaction(validator string, amount int64, privateKey *ecdsa.PrivateKey) (*types.Transaction, error) {
+func (tm *TransactionManager) CreateStakeTransaction(validator string, amount int64, privateKey *secp256k1.PrivateKey) (*types.Transaction, error) {
         // Create stake transaction data
         stakeData := map[string]interface{}{
                 "action":    "stake",
@@ -409,6 +553,12 @@ func (tm *TransactionManager) CreateStakeTransaction(validator string, amount in
         
         tx.Type = "stake"
         
+        // The type is covered by the hash and signature, so both must be redone
+        tx.ID = tx.Hash()
+        if err := utils.SignTransaction(tx, privateKey); err != nil {
+                return nil, fmt.Errorf("failed to sign stake transaction: %w", err)
+        }
+        
         tm.logger.LogTransaction(tx.ID, "stake_transaction_created", logrus.Fields{
                 "validator": validator,
                 "amount":    amount,
@@ -418,7 +568,7 @@ func (tm *TransactionManager) CreateStakeTransaction(validator string, amount in
 }
 
 // CreateUnstakeTransaction creates an unstaking transaction
-func (tm *TransactionManager) CreateUnstakeTransaction(validator string, amount int64, privateKey *ecdsa.PrivateKey) (*types.Transaction, error) {
+func (tm *TransactionManager) CreateUnstakeTransaction(validator string, amount int64, privateKey *secp256k1.PrivateKey) (*types.Transaction, error) {
         // Create unstake transaction data
         unstakeData := map[string]interface{}{
                 "action":    "unstake",
@@ -438,6 +588,12 @@ func (tm *TransactionManager) CreateUnstakeTransaction(validator string, amount
         
         tx.Type = "unstake"
         
+        // The type is covered by the hash and signature, so both must be redone
+        tx.ID = tx.Hash()
+        if err := utils.SignTransaction(tx, privateKey); err != nil {
+                return nil, fmt.Errorf("failed to sign unstake transaction: %w", err)
+        }
+        
         tm.logger.LogTransaction(tx.ID, "unstake_transaction_created", logrus.Fields{
                 "validator": validator,
                 "amount":    amount,
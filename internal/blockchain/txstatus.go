@@ -0,0 +1,212 @@
+package blockchain
+
+import (
+        "lscc-blockchain/pkg/types"
+        "sync"
+        "time"
+)
+
+// Stages of a transaction's lifecycle
+const (
+        TxPending   = "pending"   // in the mempool
+        TxIncluded  = "included"  // committed in a main-chain block above the finalized height
+        TxFinalized = "finalized" // committed at or below the finalized height
+        TxRejected  = "rejected"  // refused by the pool, expired or failed to execute
+)
+
+// txStatusRetention is how long a finalized or rejected transaction's status
+// is kept after its last transition
+const txStatusRetention = 24 * time.Hour
+
+// TxStatusTransition is one move of a transaction to a new status
+type TxStatusTransition struct {
+        Status     string    `json:"status"`
+        BlockHash  string    `json:"block_hash,omitempty"`
+        BlockIndex int64     `json:"block_index,omitempty"`
+        Reason     string    `json:"reason,omitempty"`
+        At         time.Time `json:"at"`
+}
+
+// TxStatus is where a transaction is in its lifecycle and how it got there
+type TxStatus struct {
+        TxID          string               `json:"tx_id"`
+        Status        string               `json:"status"`
+        BlockHash     string               `json:"block_hash,omitempty"`
+        BlockIndex    int64                `json:"block_index,omitempty"`
+        Reason        string               `json:"reason,omitempty"`         // why it was rejected
+        ProposedBlock string               `json:"proposed_block,omitempty"` // latest block proposed with it while pending
+        UpdatedAt     time.Time            `json:"updated_at"`
+        History       []TxStatusTransition `json:"history"` // oldest first; empty when derived from the chain
+}
+
+// txStatusTracker follows transactions from the mempool into blocks and on
+// to finality or rejection
+type txStatusTracker struct {
+        mu       sync.RWMutex
+        statuses map[string]*TxStatus
+        included map[string]bool // transactions awaiting finality
+}
+
+func newTxStatusTracker() *txStatusTracker {
+        return &txStatusTracker{
+                statuses: make(map[string]*TxStatus),
+                included: make(map[string]bool),
+        }
+}
+
+// transitionLocked moves txID to status. Caller must hold tt.mu.
+func (tt *txStatusTracker) transitionLocked(txID string, transition TxStatusTransition) {
+        status, exists := tt.statuses[txID]
+        if !exists {
+                status = &TxStatus{TxID: txID}
+                tt.statuses[txID] = status
+        }
+        status.Status = transition.Status
+        status.BlockHash = transition.BlockHash
+        status.BlockIndex = transition.BlockIndex
+        status.Reason = transition.Reason
+        status.ProposedBlock = ""
+        status.UpdatedAt = transition.At
+        status.History = append(status.History, transition)
+
+        if transition.Status == TxIncluded {
+                tt.included[txID] = true
+        } else {
+                delete(tt.included, txID)
+        }
+}
+
+// pending records txID entering the mempool
+func (tt *txStatusTracker) pending(txID string) {
+        tt.mu.Lock()
+        defer tt.mu.Unlock()
+        tt.transitionLocked(txID, TxStatusTransition{Status: TxPending, At: time.Now().UTC()})
+}
+
+// proposed notes that block was assembled with the pending transactions txs
+func (tt *txStatusTracker) proposed(txs []*types.Transaction, block *types.Block) {
+        tt.mu.Lock()
+        defer tt.mu.Unlock()
+        for _, tx := range txs {
+                if status, exists := tt.statuses[tx.ID]; exists && status.Status == TxPending {
+                        status.ProposedBlock = block.Hash
+                }
+        }
+}
+
+// include records txID being committed in block. A transaction that failed
+// to execute there, with execErr, is rejected instead.
+func (tt *txStatusTracker) include(txID string, block *types.Block, execErr error) {
+        tt.mu.Lock()
+        defer tt.mu.Unlock()
+        transition := TxStatusTransition{
+                Status:     TxIncluded,
+                BlockHash:  block.Hash,
+                BlockIndex: block.Index,
+                At:         time.Now().UTC(),
+        }
+        if execErr != nil {
+                transition.Status = TxRejected
+                transition.Reason = execErr.Error()
+        }
+        tt.transitionLocked(txID, transition)
+}
+
+// reject records the pool refusing txID. A transaction already pending or
+// further along is left alone, so resubmitting it cannot mark it rejected.
+func (tt *txStatusTracker) reject(txID string, reason string) {
+        tt.mu.Lock()
+        defer tt.mu.Unlock()
+        if status, exists := tt.statuses[txID]; exists && status.Status != TxRejected {
+                return
+        }
+        tt.transitionLocked(txID, TxStatusTransition{Status: TxRejected, Reason: reason, At: time.Now().UTC()})
+}
+
+// drop records txID leaving the pool, or a block rolled back, without
+// being committed again
+func (tt *txStatusTracker) drop(txID string, reason string) {
+        tt.mu.Lock()
+        defer tt.mu.Unlock()
+        if status, exists := tt.statuses[txID]; exists && status.Status == TxFinalized {
+                return
+        }
+        tt.transitionLocked(txID, TxStatusTransition{Status: TxRejected, Reason: reason, At: time.Now().UTC()})
+}
+
+// finalize moves included transactions at or below height to finalized
+func (tt *txStatusTracker) finalize(height int64) {
+        tt.mu.Lock()
+        defer tt.mu.Unlock()
+        now := time.Now().UTC()
+        for txID := range tt.included {
+                status := tt.statuses[txID]
+                if status.BlockIndex > height {
+                        continue
+                }
+                tt.transitionLocked(txID, TxStatusTransition{
+                        Status:     TxFinalized,
+                        BlockHash:  status.BlockHash,
+                        BlockIndex: status.BlockIndex,
+                        At:         now,
+                })
+        }
+}
+
+// prune forgets finalized and rejected transactions last moved before cutoff
+func (tt *txStatusTracker) prune(cutoff time.Time) {
+        tt.mu.Lock()
+        defer tt.mu.Unlock()
+        for txID, status := range tt.statuses {
+                if (status.Status == TxFinalized || status.Status == TxRejected) && status.UpdatedAt.Before(cutoff) {
+                        delete(tt.statuses, txID)
+                }
+        }
+}
+
+// get returns a copy of txID's status
+func (tt *txStatusTracker) get(txID string) (*TxStatus, bool) {
+        tt.mu.RLock()
+        defer tt.mu.RUnlock()
+        status, exists := tt.statuses[txID]
+        if !exists {
+                return nil, false
+        }
+        copied := *status
+        copied.History = append([]TxStatusTransition(nil), status.History...)
+        return &copied, true
+}
+
+// GetTxStatus returns where a transaction is in its lifecycle. Transactions
+// the tracker has not seen since the node started, or has forgotten, have
+// their status worked out from the chain and the pool, without a history.
+func (bc *Blockchain) GetTxStatus(txID string) (*TxStatus, error) {
+        if status, exists := bc.txStatus.get(txID); exists {
+                return status, nil
+        }
+
+        receipt, err := bc.GetTransactionReceipt(txID)
+        if err != nil {
+                return nil, err
+        }
+        status := &TxStatus{
+                TxID:       txID,
+                BlockHash:  receipt.BlockHash,
+                BlockIndex: receipt.BlockIndex,
+                UpdatedAt:  time.Now().UTC(),
+                History:    []TxStatusTransition{},
+        }
+        switch receipt.Status {
+        case "confirmed":
+                status.Status = TxIncluded
+                if receipt.BlockIndex <= bc.GetFinalizedHeight() {
+                        status.Status = TxFinalized
+                }
+        case "pending":
+                status.Status = TxPending
+        default:
+                status.Status = TxRejected
+                status.Reason = receipt.Status
+        }
+        return status, nil
+}
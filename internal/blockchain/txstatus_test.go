@@ -0,0 +1,132 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/pkg/types"
+)
+
+// expectTxStatus checks the status of txID, and that it is the last of its
+// transitions
+func expectTxStatus(t *testing.T, bc *Blockchain, txID, want string) *TxStatus {
+	t.Helper()
+	status, err := bc.GetTxStatus(txID)
+	if err != nil {
+		t.Fatalf("no status for %s: %v", txID, err)
+	}
+	if status.Status != want {
+		t.Fatalf("status = %s (%s), want %s", status.Status, status.Reason, want)
+	}
+	if n := len(status.History); n == 0 || status.History[n-1].Status != want {
+		t.Fatalf("history %+v does not end in %s", status.History, want)
+	}
+	return status
+}
+
+func TestTxStatusFollowsLifecycle(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", func(cfg *config.Config) {
+		cfg.Consensus.FinalityDepth = 2
+	})
+	addValidators(t, bc, 4)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	tx := signedTransfer(t, sender, recipient, 100, 10, 1)
+	if err := bc.SubmitTransaction(tx); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	expectTxStatus(t, bc, tx.ID, TxPending)
+
+	bc.processConsensusRound()
+	block, err := bc.GetBlockByIndex(1)
+	if err != nil {
+		t.Fatalf("no block committed: %v", err)
+	}
+	status := expectTxStatus(t, bc, tx.ID, TxIncluded)
+	if status.BlockHash != block.Hash || status.BlockIndex != 1 {
+		t.Fatalf("included in %s at %d, want %s at 1", status.BlockHash, status.BlockIndex, block.Hash)
+	}
+
+	// One block on top is not yet enough for a finality depth of 2
+	if err := bc.AddBlock(sideBlock(t, bc, bc.GetLatestBlock(), nil, "proposer")); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	expectTxStatus(t, bc, tx.ID, TxIncluded)
+	if err := bc.AddBlock(sideBlock(t, bc, bc.GetLatestBlock(), nil, "proposer")); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	status = expectTxStatus(t, bc, tx.ID, TxFinalized)
+	if status.BlockHash != block.Hash {
+		t.Fatalf("finalized in %s, want %s", status.BlockHash, block.Hash)
+	}
+
+	var seen []string
+	for _, transition := range status.History {
+		seen = append(seen, transition.Status)
+	}
+	if len(seen) != 3 || seen[0] != TxPending || seen[1] != TxIncluded || seen[2] != TxFinalized {
+		t.Fatalf("transitions %v, want pending, included, finalized", seen)
+	}
+}
+
+func TestTxStatusRejected(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", nil)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 50)
+
+	// The pool refuses a transfer the sender cannot cover
+	broke := signedTransfer(t, sender, recipient, 100, 10, 1)
+	if err := bc.SubmitTransaction(broke); err == nil {
+		t.Fatal("transfer beyond the balance accepted")
+	}
+	if status := expectTxStatus(t, bc, broke.ID, TxRejected); status.Reason == "" {
+		t.Fatal("rejected without a reason")
+	}
+
+	// A pending transaction that outlives the TTL is rejected as expired
+	stale := signedTransfer(t, sender, recipient, 10, 10, 1)
+	if err := bc.SubmitTransaction(stale); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+	expectTxStatus(t, bc, stale.ID, TxPending)
+	bc.txManager.SetTxTTL(time.Nanosecond)
+	bc.processConsensusRound()
+	if status := expectTxStatus(t, bc, stale.ID, TxRejected); status.Reason != ErrTransactionExpired.Error() {
+		t.Fatalf("expired transaction rejected for %q", status.Reason)
+	}
+
+	if _, err := bc.GetTxStatus("unknown"); err == nil {
+		t.Fatal("status reported for an unknown transaction")
+	}
+}
+
+func TestTxStatusPendingAgainAfterReorg(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", nil)
+	sender := newTestAccount(t)
+	recipient := newTestAccountOnShard(t, sender)
+	fund(t, bc, sender.address, 1000)
+
+	genesis := bc.GetLatestBlock()
+	tx := signedTransfer(t, sender, recipient, 100, 10, 1)
+	if err := bc.AddBlock(sideBlock(t, bc, genesis, []*types.Transaction{tx}, "proposer")); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	expectTxStatus(t, bc, tx.ID, TxIncluded)
+
+	// A longer branch without the transaction returns it to the pool
+	time.Sleep(time.Millisecond)
+	first := sideBlock(t, bc, genesis, nil, "rival")
+	second := sideBlock(t, bc, first, nil, "rival")
+	for _, side := range []*types.Block{first, second} {
+		if _, err := bc.ResolveFork(side); err != nil {
+			t.Fatalf("failed to resolve fork: %v", err)
+		}
+	}
+	if status := expectTxStatus(t, bc, tx.ID, TxPending); status.BlockHash != "" {
+		t.Fatalf("requeued transaction still points at block %s", status.BlockHash)
+	}
+}
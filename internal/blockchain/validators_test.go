@@ -0,0 +1,86 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/consensus"
+	"lscc-blockchain/pkg/types"
+)
+
+func rejectSmallSets(cfg *config.Config) {
+	cfg.Consensus.Byzantine = 1
+	cfg.Consensus.ByzantineCheck = consensus.ByzantineCheckReject
+}
+
+func TestValidatorSetCheckedOnceAssembled(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", rejectSmallSets)
+
+	// Validators added while the set is assembled are not checked one by one
+	addValidators(t, bc, 2)
+	if err := bc.CheckValidatorSet(); !errors.Is(err, consensus.ErrByzantineThreshold) {
+		t.Fatalf("expected ErrByzantineThreshold for 2 validators, got %v", err)
+	}
+
+	// A restart loading the same set succeeds and is checked the same way
+	restarted, err := NewBlockchain(bc.config, bc.db, discardLogger())
+	if err != nil {
+		t.Fatalf("restart refused before the set was assembled: %v", err)
+	}
+	if got := len(restarted.GetValidators()); got != 2 {
+		t.Fatalf("expected 2 stored validators, got %d", got)
+	}
+	if err := restarted.CheckValidatorSet(); !errors.Is(err, consensus.ErrByzantineThreshold) {
+		t.Fatalf("expected ErrByzantineThreshold after restart, got %v", err)
+	}
+}
+
+func TestAddValidatorChecksSet(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", rejectSmallSets)
+	if err := bc.CheckValidatorSet(); err != nil {
+		t.Fatalf("empty set refused: %v", err)
+	}
+
+	err := bc.AddValidator(&types.Validator{Address: "lonely_validator", Stake: 1000, Status: validatorActive})
+	if !errors.Is(err, consensus.ErrByzantineThreshold) {
+		t.Fatalf("expected ErrByzantineThreshold, got %v", err)
+	}
+	if got := len(bc.GetValidators()); got != 0 {
+		t.Fatalf("refused validator was added, have %d", got)
+	}
+	if _, err := bc.db.GetValidator("lonely_validator"); err == nil {
+		t.Fatal("refused validator was stored")
+	}
+}
+
+func TestRemoveValidatorChecksSet(t *testing.T) {
+	bc := newTestBlockchain(t, "pbft", rejectSmallSets)
+	addValidators(t, bc, 4)
+	if err := bc.CheckValidatorSet(); err != nil {
+		t.Fatalf("4 validators refused for f=1: %v", err)
+	}
+
+	address := bc.GetValidators()[0].Address
+	if err := bc.RemoveValidator(address); !errors.Is(err, consensus.ErrByzantineThreshold) {
+		t.Fatalf("expected ErrByzantineThreshold, got %v", err)
+	}
+	if got := len(bc.GetValidators()); got != 4 {
+		t.Fatalf("refused removal changed the set to %d", got)
+	}
+
+	// Under warn the removal goes through and is persisted
+	bc.config.Consensus.ByzantineCheck = consensus.ByzantineCheckWarn
+	if err := bc.RemoveValidator(address); err != nil {
+		t.Fatalf("removal under warn failed: %v", err)
+	}
+	if got := len(bc.GetValidators()); got != 3 {
+		t.Fatalf("expected 3 validators, got %d", got)
+	}
+	if _, err := bc.db.GetValidator(address); err == nil {
+		t.Fatal("removed validator is still stored")
+	}
+	if err := bc.RemoveValidator(address); err == nil {
+		t.Fatal("removing an unknown validator succeeded")
+	}
+}
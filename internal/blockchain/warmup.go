@@ -0,0 +1,138 @@
+package blockchain
+
+import (
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// warmupPollInterval is how often the warm-up gate rechecks peers and
+// validators
+const warmupPollInterval = 500 * time.Millisecond
+
+// SetPeerCounter sets the function the warm-up gate asks for the number of
+// connected peers. Without one the node counts as having no peers.
+func (bc *Blockchain) SetPeerCounter(count func() int) {
+        bc.mu.Lock()
+        defer bc.mu.Unlock()
+        bc.peerCount = count
+}
+
+// IsWarmingUp reports whether consensus has started but is still waiting
+// for peers and validators before its first round
+func (bc *Blockchain) IsWarmingUp() bool {
+        bc.mu.RLock()
+        defer bc.mu.RUnlock()
+        return bc.warmingUp
+}
+
+// readiness returns the connected peers and consensus validators the
+// warm-up gate counts
+func (bc *Blockchain) readiness() (int, int) {
+        bc.mu.RLock()
+        count := bc.peerCount
+        bc.mu.RUnlock()
+
+        peers := 0
+        if count != nil {
+                peers = count()
+        }
+        return peers, len(bc.consensusValidators())
+}
+
+// warmUp holds the first consensus round back until consensus.warmup_min_peers
+// peers are connected and consensus.warmup_min_validators validators are
+// active, or until consensus.warmup_delay seconds have passed, so rounds do
+// not fail while the network and shards are still coming up. It reports
+// false if consensus was stopped while waiting.
+func (bc *Blockchain) warmUp() bool {
+        delay := time.Duration(bc.config.Consensus.WarmupDelay) * time.Second
+        if delay <= 0 {
+                return true
+        }
+        minPeers := bc.config.Consensus.WarmupMinPeers
+        minValidators := bc.config.Consensus.WarmupMinValidators
+        algorithm := bc.config.Consensus.Algorithm
+
+        started := time.Now()
+        peers, validators := bc.readiness()
+        if peers >= minPeers && validators >= minValidators {
+                bc.logger.LogConsensus(algorithm, "warmup_complete", logrus.Fields{
+                        "reason": "ready",
+                        "peers": peers,
+                        "validators": validators,
+                        "elapsed_ms": int64(0),
+                        "timestamp": time.Now().UTC(),
+                })
+                return true
+        }
+
+        bc.mu.Lock()
+        bc.warmingUp = true
+        bc.mu.Unlock()
+        defer func() {
+                bc.mu.Lock()
+                bc.warmingUp = false
+                bc.mu.Unlock()
+        }()
+
+        bc.logger.LogConsensus(algorithm, "warmup_started", logrus.Fields{
+                "peers": peers,
+                "validators": validators,
+                "min_peers": minPeers,
+                "min_validators": minValidators,
+                "warmup_delay": delay.String(),
+                "timestamp": time.Now().UTC(),
+        })
+
+        deadline := time.NewTimer(delay)
+        defer deadline.Stop()
+        ticker := time.NewTicker(warmupPollInterval)
+        defer ticker.Stop()
+
+        for {
+                select {
+                case <-bc.stopChan:
+                        bc.logger.LogConsensus(algorithm, "warmup_aborted", logrus.Fields{
+                                "peers": peers,
+                                "validators": validators,
+                                "elapsed_ms": time.Since(started).Milliseconds(),
+                                "timestamp": time.Now().UTC(),
+                        })
+                        return false
+                case <-deadline.C:
+                        bc.logger.LogConsensus(algorithm, "warmup_complete", logrus.Fields{
+                                "reason": "timeout",
+                                "peers": peers,
+                                "validators": validators,
+                                "min_peers": minPeers,
+                                "min_validators": minValidators,
+                                "elapsed_ms": time.Since(started).Milliseconds(),
+                                "timestamp": time.Now().UTC(),
+                        })
+                        return true
+                case <-ticker.C:
+                        nowPeers, nowValidators := bc.readiness()
+                        if nowPeers != peers || nowValidators != validators {
+                                peers, validators = nowPeers, nowValidators
+                                bc.logger.LogConsensus(algorithm, "warmup_progress", logrus.Fields{
+                                        "peers": peers,
+                                        "validators": validators,
+                                        "min_peers": minPeers,
+                                        "min_validators": minValidators,
+                                        "timestamp": time.Now().UTC(),
+                                })
+                        }
+                        if peers >= minPeers && validators >= minValidators {
+                                bc.logger.LogConsensus(algorithm, "warmup_complete", logrus.Fields{
+                                        "reason": "ready",
+                                        "peers": peers,
+                                        "validators": validators,
+                                        "elapsed_ms": time.Since(started).Milliseconds(),
+                                        "timestamp": time.Now().UTC(),
+                                })
+                                return true
+                        }
+                }
+        }
+}
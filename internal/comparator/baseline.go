@@ -0,0 +1,265 @@
+package comparator
+
+import (
+        "encoding/json"
+        "errors"
+        "fmt"
+        "net/url"
+        "os"
+        "path/filepath"
+        "sort"
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// ErrBaselineNotFound is returned when no baseline has been saved under a name
+var ErrBaselineNotFound = errors.New("baseline not found")
+
+// defaultRegressionTolerance is used without a configuration
+const defaultRegressionTolerance = 0.1
+
+// Baseline is a comparison summary saved to judge later runs against
+type Baseline struct {
+        Name    string             `json:"name"`
+        SavedAt time.Time          `json:"saved_at"`
+        Summary *ComparatorSummary `json:"summary"`
+}
+
+// MetricComparison is one algorithm's metric in a run set against the
+// baseline. Degradation is the fraction by which the metric got worse, so
+// it is positive for lower throughput or higher latency and negative for an
+// improvement.
+type MetricComparison struct {
+        Algorithm   string  `json:"algorithm"`
+        Metric      string  `json:"metric"` // throughput, latency or finality
+        Unit        string  `json:"unit"`   // tps or ms
+        Baseline    float64 `json:"baseline"`
+        Current     float64 `json:"current"`
+        Degradation float64 `json:"degradation"`
+        Regressed   bool    `json:"regressed"` // degraded by more than the tolerance
+}
+
+// RegressionReport compares a run with a saved baseline
+type RegressionReport struct {
+        Baseline        string             `json:"baseline"`
+        BaselineSavedAt time.Time          `json:"baseline_saved_at"`
+        TestName        string             `json:"test_name"`
+        TestStartTime   time.Time          `json:"test_start_time"`
+        Tolerance       float64            `json:"tolerance"`
+        Regressed       bool               `json:"regressed"`
+        Regressions     []MetricComparison `json:"regressions"`       // metrics degraded beyond the tolerance
+        Metrics         []MetricComparison `json:"metrics"`           // every metric compared
+        Missing         []string           `json:"missing,omitempty"` // baseline algorithms the run did not include
+}
+
+// regressionMetric is a metric regressions are detected on
+type regressionMetric struct {
+        name           string
+        unit           string
+        higherIsBetter bool
+        value          func(*ComparisonResult) float64
+}
+
+var regressionMetrics = []regressionMetric{
+        {"throughput", "tps", true, func(r *ComparisonResult) float64 { return r.ThroughputTPS }},
+        {"latency", "ms", false, func(r *ComparisonResult) float64 { return durationMillis(r.AverageLatency) }},
+        {"finality", "ms", false, func(r *ComparisonResult) float64 { return durationMillis(r.FinalityTime) }},
+}
+
+func durationMillis(d time.Duration) float64 {
+        return float64(d) / float64(time.Millisecond)
+}
+
+// SaveBaseline saves summary as the baseline for later runs of the same
+// test, replacing any saved before under its name
+func (cc *ConsensusComparator) SaveBaseline(summary *ComparatorSummary) error {
+        if summary == nil {
+                return fmt.Errorf("no summary to save")
+        }
+        path, err := cc.baselinePath(summary.TestName)
+        if err != nil {
+                return err
+        }
+
+        baseline := &Baseline{
+                Name:    summary.TestName,
+                SavedAt: time.Now().UTC(),
+                Summary: summary,
+        }
+        encoded, err := json.MarshalIndent(baseline, "", "  ")
+        if err != nil {
+                return fmt.Errorf("failed to encode baseline: %w", err)
+        }
+
+        // Written beside the old baseline and renamed over it, so a crash
+        // never leaves a half-written file
+        dir := filepath.Dir(path)
+        if err := os.MkdirAll(dir, 0755); err != nil {
+                return fmt.Errorf("failed to create baseline directory: %w", err)
+        }
+        tmp, err := os.CreateTemp(dir, ".baseline-*")
+        if err != nil {
+                return fmt.Errorf("failed to save baseline: %w", err)
+        }
+        defer os.Remove(tmp.Name())
+        if _, err := tmp.Write(encoded); err != nil {
+                tmp.Close()
+                return fmt.Errorf("failed to save baseline: %w", err)
+        }
+        if err := tmp.Close(); err != nil {
+                return fmt.Errorf("failed to save baseline: %w", err)
+        }
+        if err := os.Rename(tmp.Name(), path); err != nil {
+                return fmt.Errorf("failed to save baseline: %w", err)
+        }
+
+        cc.logger.Info("Comparison baseline saved", logrus.Fields{
+                "baseline":   baseline.Name,
+                "path":       path,
+                "algorithms": summary.AlgorithmsCompared,
+                "timestamp":  time.Now(),
+        })
+        return nil
+}
+
+// LoadBaseline reads the baseline saved under name
+func (cc *ConsensusComparator) LoadBaseline(name string) (*Baseline, error) {
+        path, err := cc.baselinePath(name)
+        if err != nil {
+                return nil, err
+        }
+        encoded, err := os.ReadFile(path)
+        if errors.Is(err, os.ErrNotExist) {
+                return nil, fmt.Errorf("%w: %q", ErrBaselineNotFound, name)
+        }
+        if err != nil {
+                return nil, fmt.Errorf("failed to read baseline %q: %w", name, err)
+        }
+
+        var baseline Baseline
+        if err := json.Unmarshal(encoded, &baseline); err != nil {
+                return nil, fmt.Errorf("failed to decode baseline %q: %w", name, err)
+        }
+        if baseline.Summary == nil {
+                return nil, fmt.Errorf("baseline %q holds no summary", name)
+        }
+        return &baseline, nil
+}
+
+// CompareToBaseline compares summary with the baseline saved for its test
+// under the configured tolerance
+func (cc *ConsensusComparator) CompareToBaseline(summary *ComparatorSummary) (*RegressionReport, error) {
+        if summary == nil {
+                return nil, fmt.Errorf("no summary to compare")
+        }
+        return cc.CompareWithBaseline(summary.TestName, summary, cc.RegressionTolerance())
+}
+
+// CompareWithBaseline compares summary with the baseline saved under name.
+// A metric regresses when it is worse than the baseline by more than
+// tolerance, as a fraction of the baseline value; metrics the baseline
+// recorded as zero cannot be compared and are skipped.
+func (cc *ConsensusComparator) CompareWithBaseline(name string, summary *ComparatorSummary, tolerance float64) (*RegressionReport, error) {
+        if summary == nil {
+                return nil, fmt.Errorf("no summary to compare")
+        }
+        if tolerance < 0 {
+                return nil, fmt.Errorf("tolerance cannot be negative: %v", tolerance)
+        }
+        baseline, err := cc.LoadBaseline(name)
+        if err != nil {
+                return nil, err
+        }
+
+        report := &RegressionReport{
+                Baseline:        baseline.Name,
+                BaselineSavedAt: baseline.SavedAt,
+                TestName:        summary.TestName,
+                TestStartTime:   summary.StartTime,
+                Tolerance:       tolerance,
+                Regressions:     make([]MetricComparison, 0),
+                Metrics:         make([]MetricComparison, 0),
+        }
+
+        algorithms := make([]string, 0, len(baseline.Summary.Results))
+        for algorithm := range baseline.Summary.Results {
+                algorithms = append(algorithms, algorithm)
+        }
+        sort.Strings(algorithms)
+
+        for _, algorithm := range algorithms {
+                before := baseline.Summary.Results[algorithm]
+                after, exists := summary.Results[algorithm]
+                if !exists || before == nil || after == nil {
+                        report.Missing = append(report.Missing, algorithm)
+                        continue
+                }
+
+                for _, metric := range regressionMetrics {
+                        was, now := metric.value(before), metric.value(after)
+                        if was <= 0 {
+                                continue
+                        }
+                        degradation := (now - was) / was
+                        if metric.higherIsBetter {
+                                degradation = -degradation
+                        }
+
+                        comparison := MetricComparison{
+                                Algorithm:   algorithm,
+                                Metric:      metric.name,
+                                Unit:        metric.unit,
+                                Baseline:    was,
+                                Current:     now,
+                                Degradation: degradation,
+                                Regressed:   degradation > tolerance,
+                        }
+                        report.Metrics = append(report.Metrics, comparison)
+                        if comparison.Regressed {
+                                report.Regressions = append(report.Regressions, comparison)
+                        }
+                }
+        }
+        report.Regressed = len(report.Regressions) > 0
+
+        if report.Regressed {
+                cc.logger.Warn("Comparison regressed against baseline", logrus.Fields{
+                        "baseline":    report.Baseline,
+                        "test_name":   report.TestName,
+                        "regressions": len(report.Regressions),
+                        "tolerance":   tolerance,
+                        "timestamp":   time.Now(),
+                })
+        }
+        return report, nil
+}
+
+// RegressionTolerance returns the configured fraction a metric may degrade
+// by before it counts as a regression
+func (cc *ConsensusComparator) RegressionTolerance() float64 {
+        if cc.config == nil {
+                return defaultRegressionTolerance
+        }
+        return cc.config.Comparator.RegressionTolerance
+}
+
+// baselinePath returns the file the baseline named name is kept in. Names
+// are escaped, so none can reach outside the baseline directory.
+func (cc *ConsensusComparator) baselinePath(name string) (string, error) {
+        if name == "" {
+                return "", fmt.Errorf("baseline name cannot be empty")
+        }
+
+        dir := ""
+        if cc.config != nil {
+                dir = cc.config.Comparator.BaselineDir
+                if dir == "" {
+                        dir = filepath.Join(cc.config.Storage.DataDir, "baselines")
+                }
+        }
+        if dir == "" {
+                dir = "baselines"
+        }
+        return filepath.Join(dir, url.PathEscape(name)+".json"), nil
+}
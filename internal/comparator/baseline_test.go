@@ -0,0 +1,121 @@
+package comparator
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// runSummary returns a summary of a run named name with the given results
+func runSummary(name string, results ...*ComparisonResult) *ComparatorSummary {
+	summary := &ComparatorSummary{
+		TestName:  name,
+		StartTime: time.Now(),
+		Results:   make(map[string]*ComparisonResult),
+	}
+	for _, result := range results {
+		summary.AlgorithmsCompared = append(summary.AlgorithmsCompared, result.Algorithm)
+		summary.Results[result.Algorithm] = result
+	}
+	return summary
+}
+
+func runResult(algorithm string, tps float64, latency, finality time.Duration) *ComparisonResult {
+	return &ComparisonResult{Algorithm: algorithm, ThroughputTPS: tps, AverageLatency: latency, FinalityTime: finality}
+}
+
+// newBaselineComparator returns a test comparator keeping its baselines in
+// a temporary directory, with a tolerance of 10%
+func newBaselineComparator(t *testing.T) *ConsensusComparator {
+	t.Helper()
+	cc := newTestComparator(t)
+	cc.config.Comparator.BaselineDir = t.TempDir()
+	cc.config.Comparator.RegressionTolerance = 0.1
+	return cc
+}
+
+func TestBaselinePersistsAsJSON(t *testing.T) {
+	cc := newBaselineComparator(t)
+	saved := runSummary("nightly", runResult("lscc", 120, 40*time.Millisecond, 200*time.Millisecond))
+	if err := cc.SaveBaseline(saved); err != nil {
+		t.Fatalf("failed to save baseline: %v", err)
+	}
+
+	path := filepath.Join(cc.config.Comparator.BaselineDir, "nightly.json")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("baseline not written to %s: %v", path, err)
+	}
+
+	// A fresh comparator on the same directory reads it back
+	reopened := newTestComparator(t)
+	reopened.config.Comparator.BaselineDir = cc.config.Comparator.BaselineDir
+	baseline, err := reopened.LoadBaseline("nightly")
+	if err != nil {
+		t.Fatalf("failed to load baseline: %v", err)
+	}
+	got := baseline.Summary.Results["lscc"]
+	if baseline.Name != "nightly" || got == nil || got.ThroughputTPS != 120 || got.AverageLatency != 40*time.Millisecond {
+		t.Fatalf("loaded baseline %+v with lscc result %+v", baseline, got)
+	}
+
+	if _, err := cc.LoadBaseline("weekly"); !errors.Is(err, ErrBaselineNotFound) {
+		t.Fatalf("expected ErrBaselineNotFound, got %v", err)
+	}
+	if _, err := cc.CompareToBaseline(runSummary("weekly")); !errors.Is(err, ErrBaselineNotFound) {
+		t.Fatalf("comparing without a baseline: expected ErrBaselineNotFound, got %v", err)
+	}
+}
+
+func TestRegressionDetectedAgainstBaseline(t *testing.T) {
+	cc := newBaselineComparator(t)
+	if err := cc.SaveBaseline(runSummary("nightly",
+		runResult("lscc", 100, 50*time.Millisecond, 200*time.Millisecond),
+		runResult("pbft", 80, 60*time.Millisecond, 300*time.Millisecond),
+	)); err != nil {
+		t.Fatalf("failed to save baseline: %v", err)
+	}
+
+	// Throughput falls by 30%, latency rises by exactly the tolerance and
+	// finality halves; pbft was left out of the run
+	degraded := runSummary("nightly", runResult("lscc", 70, 55*time.Millisecond, 100*time.Millisecond))
+	report, err := cc.CompareToBaseline(degraded)
+	if err != nil {
+		t.Fatalf("failed to compare: %v", err)
+	}
+	if !report.Regressed || report.Tolerance != 0.1 || len(report.Metrics) != 3 {
+		t.Fatalf("report %+v", report)
+	}
+	if len(report.Regressions) != 1 {
+		t.Fatalf("regressions %+v, want throughput only", report.Regressions)
+	}
+	regression := report.Regressions[0]
+	if regression.Algorithm != "lscc" || regression.Metric != "throughput" || regression.Baseline != 100 || regression.Current != 70 {
+		t.Fatalf("regression %+v", regression)
+	}
+	if math.Abs(regression.Degradation-0.3) > 1e-9 {
+		t.Fatalf("throughput degraded by %v, want 0.3", regression.Degradation)
+	}
+
+	degradation := make(map[string]float64)
+	for _, metric := range report.Metrics {
+		degradation[metric.Metric] = metric.Degradation
+	}
+	if math.Abs(degradation["latency"]-0.1) > 1e-9 || math.Abs(degradation["finality"]+0.5) > 1e-9 {
+		t.Fatalf("degradation by metric %v, want latency 0.1 and finality -0.5", degradation)
+	}
+	if len(report.Missing) != 1 || report.Missing[0] != "pbft" {
+		t.Fatalf("missing %v, want pbft", report.Missing)
+	}
+
+	// A looser tolerance lets the same run through
+	report, err = cc.CompareWithBaseline("nightly", degraded, 0.5)
+	if err != nil {
+		t.Fatalf("failed to compare: %v", err)
+	}
+	if report.Regressed || len(report.Regressions) != 0 {
+		t.Fatalf("regressions %+v under a 50%% tolerance", report.Regressions)
+	}
+}
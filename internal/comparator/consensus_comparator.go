@@ -3,10 +3,12 @@ package comparator
 import (
         "fmt"
         "math"
+        "sort"
         "sync"
         "time"
 
         "lscc-blockchain/config"
+        "lscc-blockchain/internal/blockchain"
         "lscc-blockchain/internal/consensus"
         "lscc-blockchain/internal/utils"
         "lscc-blockchain/pkg/types"
@@ -21,21 +23,35 @@ type ComparisonResult struct {
         EndTime            time.Time              `json:"end_time"`
         Duration           time.Duration          `json:"duration"`
         BlocksProcessed    int                    `json:"blocks_processed"`
-        TransactionsTotal  int                    `json:"transactions_total"`
+        TransactionsTotal  int                    `json:"transactions_total"` // transactions in the blocks committed during the run
         ThroughputTPS      float64               `json:"throughput_tps"`
         AverageLatency     time.Duration         `json:"average_latency"`
         ConsensusRounds    int                    `json:"consensus_rounds"`
         FailedRounds       int                    `json:"failed_rounds"`
+        FailureReasons     map[string]int         `json:"failure_reasons"` // failed rounds by consensus.ClassifyFailure reason
         NetworkMessages    int                    `json:"network_messages"`
         EnergyConsumption  float64               `json:"energy_consumption"`
-        MemoryUsage        int64                 `json:"memory_usage"`
-        CPUUsage           float64               `json:"cpu_usage"`
+        MemoryUsage        int64                 `json:"memory_usage"` // heap growth in bytes over the test
+        CPUUsage           float64               `json:"cpu_usage"`    // percent of GOMAXPROCS kept busy, -1 when unknown
         FinalityTime       time.Duration         `json:"finality_time"`
         SecurityLevel      float64               `json:"security_level"`
         ScalabilityScore   float64               `json:"scalability_score"`
         DecentralizationScore float64            `json:"decentralization_score"`
+        Work               WorkMeasurements       `json:"work"` // observed work the energy, security and scalability scores are derived from
         CustomMetrics      map[string]interface{} `json:"custom_metrics"`
         ErrorMessages      []string              `json:"error_messages"`
+        Notes              []string              `json:"notes,omitempty"` // caveats about how far the result can be trusted
+}
+
+// noRoundsNote is recorded on results where no block reached consensus
+const noRoundsNote = "no blocks processed: the test ended before any consensus round ran"
+
+// failureRate returns the fraction of rounds that failed, 0 when none ran
+func (r *ComparisonResult) failureRate() float64 {
+        if r.ConsensusRounds == 0 {
+                return 0
+        }
+        return float64(r.FailedRounds) / float64(r.ConsensusRounds)
 }
 
 // ComparatorSummary provides overall comparison results
@@ -74,6 +90,57 @@ type TestConfiguration struct {
         Metrics            []string      `json:"metrics"`
         StressTest         bool          `json:"stress_test"`
         RealTimeReporting  bool          `json:"real_time_reporting"`
+        ResourceWeight     float64       `json:"resource_weight"` // share of the overall score given to memory and cpu usage, 0 to ignore them
+        TransactionSource  string        `json:"transaction_source,omitempty"` // JSONL trace replayed instead of random transactions, TransactionLoad capping how many (0 for all)
+}
+
+// SupportedMetrics are the metric names a TestConfiguration may request
+var SupportedMetrics = []string{"throughput", "latency", "finality", "energy", "scalability", "security", "decentralization"}
+
+// Validate checks that the configuration describes a runnable comparison
+func (tc *TestConfiguration) Validate() error {
+        if tc.Duration <= 0 {
+                return fmt.Errorf("duration must be positive: %s", tc.Duration)
+        }
+        if tc.TransactionLoad < 0 {
+                return fmt.Errorf("transaction load cannot be negative: %d", tc.TransactionLoad)
+        }
+        if tc.ConcurrentNodes < 0 {
+                return fmt.Errorf("concurrent nodes cannot be negative: %d", tc.ConcurrentNodes)
+        }
+        if tc.NetworkLatency < 0 {
+                return fmt.Errorf("network latency cannot be negative: %s", tc.NetworkLatency)
+        }
+        if math.IsNaN(tc.Byzantine) || tc.Byzantine < 0 || tc.Byzantine > 1 {
+                return fmt.Errorf("byzantine fraction must be between 0 and 1: %v", tc.Byzantine)
+        }
+        if math.IsNaN(tc.ResourceWeight) || tc.ResourceWeight < 0 || tc.ResourceWeight > 1 {
+                return fmt.Errorf("resource weight must be between 0 and 1: %v", tc.ResourceWeight)
+        }
+
+        if len(tc.Algorithms) == 0 {
+                return fmt.Errorf("at least one algorithm must be specified")
+        }
+        for _, algorithm := range tc.Algorithms {
+                if !consensus.IsRegistered(algorithm) {
+                        return fmt.Errorf("unknown algorithm %q (known: %v)", algorithm, consensus.RegisteredAlgorithms())
+                }
+        }
+
+        for _, metric := range tc.Metrics {
+                supported := false
+                for _, name := range SupportedMetrics {
+                        if metric == name {
+                                supported = true
+                                break
+                        }
+                }
+                if !supported {
+                        return fmt.Errorf("unsupported metric %q (supported: %v)", metric, SupportedMetrics)
+                }
+        }
+
+        return nil
 }
 
 // ConsensusComparator manages consensus algorithm comparisons
@@ -81,6 +148,7 @@ type ConsensusComparator struct {
         config          *config.Config
         logger          *utils.Logger
         mu              sync.RWMutex
+        runMu           sync.Mutex // serializes comparisons, which share the consensus instances
         
         // Consensus instances
         algorithms      map[string]consensus.Consensus
@@ -100,9 +168,11 @@ type ConsensusComparator struct {
         
         // Configuration
         defaultConfig   *TestConfiguration
+        energyModel     EnergyModel
 }
 
-// TestExecution tracks ongoing test execution
+// TestExecution tracks ongoing test execution. Results and IsComplete
+// change while the test runs and are guarded by mu.
 type TestExecution struct {
         TestConfig      *TestConfiguration
         StartTime       time.Time
@@ -111,6 +181,58 @@ type TestExecution struct {
         mu              sync.RWMutex
 }
 
+// addResult records the result of one algorithm
+func (te *TestExecution) addResult(result *ComparisonResult) {
+        te.mu.Lock()
+        defer te.mu.Unlock()
+        te.Results[result.Algorithm] = result
+}
+
+// complete marks the test finished
+func (te *TestExecution) complete() {
+        te.mu.Lock()
+        defer te.mu.Unlock()
+        te.IsComplete = true
+}
+
+// snapshot returns a deep copy that is safe to read while the test runs
+func (te *TestExecution) snapshot() *TestExecution {
+        te.mu.RLock()
+        defer te.mu.RUnlock()
+        
+        testConfig := *te.TestConfig
+        testConfig.Algorithms = append([]string(nil), te.TestConfig.Algorithms...)
+        testConfig.Metrics = append([]string(nil), te.TestConfig.Metrics...)
+        
+        results := make(map[string]*ComparisonResult, len(te.Results))
+        for algorithm, result := range te.Results {
+                results[algorithm] = result.clone()
+        }
+        
+        return &TestExecution{
+                TestConfig: &testConfig,
+                StartTime:  te.StartTime,
+                Results:    results,
+                IsComplete: te.IsComplete,
+        }
+}
+
+// clone returns a deep copy of the result
+func (r *ComparisonResult) clone() *ComparisonResult {
+        c := *r
+        c.FailureReasons = make(map[string]int, len(r.FailureReasons))
+        for reason, count := range r.FailureReasons {
+                c.FailureReasons[reason] = count
+        }
+        c.CustomMetrics = make(map[string]interface{}, len(r.CustomMetrics))
+        for key, value := range r.CustomMetrics {
+                c.CustomMetrics[key] = value
+        }
+        c.ErrorMessages = append([]string(nil), r.ErrorMessages...)
+        c.Notes = append([]string(nil), r.Notes...)
+        return &c
+}
+
 // MetricUpdate carries real-time metric updates
 type MetricUpdate struct {
         Algorithm   string
@@ -138,6 +260,7 @@ func NewConsensusComparator(cfg *config.Config, logger *utils.Logger) (*Consensu
                 stopChannel:    make(chan struct{}),
                 startTime:      startTime,
                 testCounter:    0,
+                energyModel:    MeasuredEnergyModel{},
                 defaultConfig: &TestConfiguration{
                         Name:              "Default Comparison",
                         Duration:          5 * time.Minute,
@@ -145,7 +268,7 @@ func NewConsensusComparator(cfg *config.Config, logger *utils.Logger) (*Consensu
                         ConcurrentNodes:   4,
                         NetworkLatency:    50 * time.Millisecond,
                         Byzantine:         0.33,
-                        Algorithms:        []string{"lscc", "pbft", "ppbft", "pow", "pos"},
+                        Algorithms:        consensus.RegisteredAlgorithms(),
                         Metrics:           []string{"throughput", "latency", "finality", "energy", "scalability"},
                         StressTest:        false,
                         RealTimeReporting: true,
@@ -171,9 +294,7 @@ func NewConsensusComparator(cfg *config.Config, logger *utils.Logger) (*Consensu
 
 // initializeAlgorithms creates instances of all consensus algorithms
 func (cc *ConsensusComparator) initializeAlgorithms() error {
-        algorithms := []string{"lscc", "pbft", "ppbft", "pow", "pos"}
-        
-        for _, alg := range algorithms {
+        for _, alg := range consensus.RegisteredAlgorithms() {
                 cc.logger.Info("Initializing consensus algorithm", logrus.Fields{
                         "algorithm": alg,
                         "timestamp": time.Now(),
@@ -182,24 +303,7 @@ func (cc *ConsensusComparator) initializeAlgorithms() error {
                 // Create algorithm-specific configuration
                 algConfig := cc.createAlgorithmConfig(alg)
                 
-                var consensusInstance consensus.Consensus
-                var err error
-                
-                switch alg {
-                case "lscc":
-                        consensusInstance, err = consensus.NewLSCC(algConfig, cc.logger)
-                case "pbft":
-                        consensusInstance, err = consensus.NewPBFT(algConfig, cc.logger)
-                case "ppbft":
-                        consensusInstance, err = consensus.NewPracticalPBFT(algConfig, cc.logger)
-                case "pow":
-                        consensusInstance, err = consensus.NewProofOfWork(algConfig, cc.logger)
-                case "pos":
-                        consensusInstance, err = consensus.NewProofOfStake(algConfig, cc.logger)
-                default:
-                        return fmt.Errorf("unsupported algorithm: %s", alg)
-                }
-                
+                consensusInstance, err := consensus.New(alg, algConfig, cc.logger)
                 if err != nil {
                         cc.logger.Error("Failed to initialize algorithm", logrus.Fields{
                                 "algorithm": alg,
@@ -254,13 +358,32 @@ func (cc *ConsensusComparator) createAlgorithmConfig(algorithm string) *config.C
 
 // RunComparison executes a consensus algorithm comparison
 func (cc *ConsensusComparator) RunComparison(testConfig *TestConfiguration) (*ComparatorSummary, error) {
-        cc.mu.Lock()
-        defer cc.mu.Unlock()
+        cc.runMu.Lock()
+        defer cc.runMu.Unlock()
         
+        // cc.mu is held only while registering and retiring the test, so
+        // GetActiveTests can observe it while the algorithms run
+        cc.mu.Lock()
         if testConfig == nil {
                 testConfig = cc.defaultConfig
         }
+        if err := testConfig.Validate(); err != nil {
+                cc.mu.Unlock()
+                return nil, fmt.Errorf("invalid test configuration: %w", err)
+        }
+        cc.mu.Unlock()
         
+        // A recorded trace is read once so every algorithm replays the same
+        // workload
+        var trace []*types.Transaction
+        if testConfig.TransactionSource != "" {
+                var err error
+                if trace, err = loadTransactionTrace(testConfig.TransactionSource); err != nil {
+                        return nil, fmt.Errorf("failed to load transaction trace: %w", err)
+                }
+        }
+        
+        cc.mu.Lock()
         cc.testCounter++
         testID := fmt.Sprintf("test_%d_%s", cc.testCounter, testConfig.Name)
         
@@ -269,6 +392,8 @@ func (cc *ConsensusComparator) RunComparison(testConfig *TestConfiguration) (*Co
                 "algorithms":  testConfig.Algorithms,
                 "duration":    testConfig.Duration,
                 "tx_load":     testConfig.TransactionLoad,
+                "tx_source":   testConfig.TransactionSource,
+                "trace_txs":   len(trace),
                 "timestamp":   time.Now(),
         })
         
@@ -282,14 +407,22 @@ func (cc *ConsensusComparator) RunComparison(testConfig *TestConfiguration) (*Co
         
         cc.activeTests[testID] = testExecution
         
+        instances := make(map[string]consensus.Consensus, len(testConfig.Algorithms))
+        for _, algorithm := range testConfig.Algorithms {
+                if consensusInstance, exists := cc.algorithms[algorithm]; exists {
+                        instances[algorithm] = consensusInstance
+                }
+        }
+        cc.mu.Unlock()
+        
         // Run comparison for each algorithm
         var wg sync.WaitGroup
         resultsChan := make(chan *ComparisonResult, len(testConfig.Algorithms))
         
         for _, algorithm := range testConfig.Algorithms {
-                if consensusInstance, exists := cc.algorithms[algorithm]; exists {
+                if consensusInstance, exists := instances[algorithm]; exists {
                         wg.Add(1)
-                        go cc.runAlgorithmTest(algorithm, consensusInstance, testConfig, &wg, resultsChan)
+                        go cc.runAlgorithmTest(algorithm, consensusInstance, testConfig, trace, &wg, resultsChan)
                 } else {
                         cc.logger.Warn("Algorithm not available for comparison", logrus.Fields{
                                 "algorithm": algorithm,
@@ -304,20 +437,26 @@ func (cc *ConsensusComparator) RunComparison(testConfig *TestConfiguration) (*Co
                 close(resultsChan)
         }()
         
-        // Collect results
+        // Collect results. Resources are sampled process-wide, so when
+        // algorithms ran side by side their usage figures overlap.
         for result := range resultsChan {
-                testExecution.Results[result.Algorithm] = result
+                if len(instances) > 1 {
+                        result.Notes = append(result.Notes, concurrentResourcesNote)
+                }
+                testExecution.addResult(result)
         }
         
-        // Generate summary
-        summary := cc.generateSummary(testExecution)
+        // Generate summary from a copy so later readers of the summary and
+        // of the execution never share result maps
+        summary := cc.generateSummary(testExecution.snapshot())
         
         // Mark test as complete
-        testExecution.IsComplete = true
-        cc.testHistory = append(cc.testHistory, summary)
+        testExecution.complete()
         
-        // Cleanup
+        cc.mu.Lock()
+        cc.testHistory = append(cc.testHistory, summary)
         delete(cc.activeTests, testID)
+        cc.mu.Unlock()
         
         cc.logger.Info("Consensus comparison completed", logrus.Fields{
                 "test_id":     testID,
@@ -335,6 +474,7 @@ func (cc *ConsensusComparator) runAlgorithmTest(
         algorithm string,
         consensusInstance consensus.Consensus,
         testConfig *TestConfiguration,
+        trace []*types.Transaction,
         wg *sync.WaitGroup,
         resultsChan chan<- *ComparisonResult,
 ) {
@@ -346,6 +486,7 @@ func (cc *ConsensusComparator) runAlgorithmTest(
                 StartTime:     startTime,
                 CustomMetrics: make(map[string]interface{}),
                 ErrorMessages: make([]string, 0),
+                FailureReasons: make(map[string]int),
         }
         
         cc.logger.Info("Starting algorithm test", logrus.Fields{
@@ -354,11 +495,17 @@ func (cc *ConsensusComparator) runAlgorithmTest(
                 "timestamp": startTime,
         })
         
-        // Generate test transactions
-        transactions := cc.generateTestTransactions(testConfig.TransactionLoad)
+        // Replay the recorded trace, or generate random test transactions
+        var transactions []*types.Transaction
+        if trace != nil {
+                transactions = replayTransactions(trace, testConfig.TransactionLoad)
+        } else {
+                transactions = cc.generateTestTransactions(testConfig.TransactionLoad)
+        }
         
         // Track metrics
         var blocksProcessed int
+        var transactionsCommitted int
         var consensusRounds int
         var failedRounds int
         var networkMessages int
@@ -367,6 +514,9 @@ func (cc *ConsensusComparator) runAlgorithmTest(
         // Create test blocks from transactions
         testBlocks := cc.createTestBlocks(transactions)
         
+        resourcesBefore := sampleResources()
+        hashesBefore := totalHashes(consensusInstance)
+        
         // Run consensus for specified duration
         testEnd := startTime.Add(testConfig.Duration)
         
@@ -378,13 +528,19 @@ func (cc *ConsensusComparator) runAlgorithmTest(
                 consensusRounds++
                 
                 // Process block through consensus
-                success, err := consensusInstance.ProcessBlock(block, cc.generateValidators())
+                auditBefore, audited := voteAudit(consensusInstance)
+                success, err := consensusInstance.ProcessBlock(block, cc.generateValidators(defaultTestValidators))
                 
                 blockLatency := time.Since(blockStart)
+                if audited {
+                        auditAfter, _ := voteAudit(consensusInstance)
+                        result.Work.recordRound(auditBefore, auditAfter, err == nil && success)
+                }
                 totalLatency += blockLatency
                 
                 if err != nil {
                         failedRounds++
+                        result.FailureReasons[consensus.ClassifyFailure(err)]++
                         result.ErrorMessages = append(result.ErrorMessages, err.Error())
                         cc.logger.Warn("Consensus failed for block", logrus.Fields{
                                 "algorithm":  algorithm,
@@ -394,15 +550,20 @@ func (cc *ConsensusComparator) runAlgorithmTest(
                         })
                 } else if success {
                         blocksProcessed++
+                        transactionsCommitted += len(block.Transactions)
                         networkMessages += cc.estimateNetworkMessages(algorithm)
                 } else {
                         failedRounds++
+                        result.FailureReasons[consensus.FailureNotCommitted]++
                 }
                 
                 // Simulate network delay
                 time.Sleep(testConfig.NetworkLatency)
         }
         
+        usage := usageSince(resourcesBefore, sampleResources())
+        result.Work.HashAttempts = totalHashes(consensusInstance) - hashesBefore
+        
         endTime := time.Now()
         actualDuration := endTime.Sub(startTime)
         
@@ -410,13 +571,23 @@ func (cc *ConsensusComparator) runAlgorithmTest(
         result.EndTime = endTime
         result.Duration = actualDuration
         result.BlocksProcessed = blocksProcessed
-        result.TransactionsTotal = blocksProcessed * 10 // Assuming 10 tx per block
+        result.TransactionsTotal = transactionsCommitted
         result.ConsensusRounds = consensusRounds
         result.FailedRounds = failedRounds
         result.NetworkMessages = networkMessages
+        result.MemoryUsage = usage.heapGrowth
+        result.CPUUsage = usage.cpuPercent
         
         if consensusRounds > 0 {
                 result.AverageLatency = totalLatency / time.Duration(consensusRounds)
+        } else {
+                result.Notes = append(result.Notes, noRoundsNote)
+                cc.logger.Warn("Algorithm processed no blocks", logrus.Fields{
+                        "algorithm":       algorithm,
+                        "duration":        testConfig.Duration,
+                        "network_latency": testConfig.NetworkLatency,
+                        "timestamp":       time.Now(),
+                })
         }
         
         if actualDuration.Seconds() > 0 {
@@ -425,19 +596,40 @@ func (cc *ConsensusComparator) runAlgorithmTest(
         
         // Calculate algorithm-specific metrics
         result.FinalityTime = cc.calculateFinalityTime(algorithm, result.AverageLatency)
-        result.EnergyConsumption = cc.calculateEnergyConsumption(algorithm, blocksProcessed)
-        result.SecurityLevel = cc.calculateSecurityLevel(algorithm)
-        result.ScalabilityScore = cc.calculateScalabilityScore(algorithm, result.ThroughputTPS)
+        cc.mu.RLock()
+        energyModel := cc.energyModel
+        cc.mu.RUnlock()
+        result.EnergyConsumption = energyModel.Cost(algorithm, result)
+        
+        // Security and scalability come from what the run observed; the
+        // per-algorithm estimates only cover engines that report nothing
+        if score, ok := measuredSecurity(result.Work); ok {
+                result.SecurityLevel = score
+        } else {
+                result.SecurityLevel = cc.calculateSecurityLevel(algorithm)
+        }
+        if consensusRounds > 0 {
+                result.Work.Scaling = cc.probeScaling(consensusInstance)
+        }
+        if score, ok := measuredScalability(result.Work); ok {
+                result.ScalabilityScore = score
+        } else {
+                result.ScalabilityScore = cc.calculateScalabilityScore(algorithm, result.ThroughputTPS)
+        }
         result.DecentralizationScore = cc.calculateDecentralizationScore(algorithm)
         
         // Add custom metrics based on algorithm
         result.CustomMetrics = cc.collectCustomMetrics(algorithm, consensusInstance)
+        result.CustomMetrics["bytes_allocated"] = usage.bytesAllocated
+        result.CustomMetrics["goroutine_delta"] = usage.goroutineDelta
         
         cc.logger.Info("Algorithm test completed", logrus.Fields{
                 "algorithm":        algorithm,
                 "blocks_processed": blocksProcessed,
                 "throughput_tps":   result.ThroughputTPS,
                 "avg_latency":      result.AverageLatency,
+                "memory_usage":     result.MemoryUsage,
+                "cpu_usage":        result.CPUUsage,
                 "duration":         actualDuration,
                 "timestamp":        endTime,
         })
@@ -468,9 +660,15 @@ func (cc *ConsensusComparator) generateTestTransactions(count int) []*types.Tran
 
 // createTestBlocks creates blocks from transactions
 func (cc *ConsensusComparator) createTestBlocks(transactions []*types.Transaction) []*types.Block {
-        const txPerBlock = 10
+        // Small blocks give each algorithm many rounds to measure, but never
+        // more than the configured block limit
+        txPerBlock := 10
+        if maxTxs := cc.config.Consensus.MaxTxPerBlock; maxTxs > 0 && maxTxs < txPerBlock {
+                txPerBlock = maxTxs
+        }
         numBlocks := (len(transactions) + txPerBlock - 1) / txPerBlock
         blocks := make([]*types.Block, numBlocks)
+        previousHash := ""
         
         for i := 0; i < numBlocks; i++ {
                 start := i * txPerBlock
@@ -479,14 +677,18 @@ func (cc *ConsensusComparator) createTestBlocks(transactions []*types.Transactio
                         end = len(transactions)
                 }
                 
+                blockTxs := blockchain.OrderTransactions(transactions[start:end])
                 block := &types.Block{
-                        Hash:         fmt.Sprintf("block_hash_%d_%d", time.Now().UnixNano(), i),
-                        PreviousHash: fmt.Sprintf("prev_hash_%d", i),
+                        PreviousHash: previousHash,
                         Index:        int64(i + 1),
                         Timestamp:    time.Now(),
-                        Transactions: transactions[start:end],
+                        Transactions: blockTxs,
+                        MerkleRoot:   blockchain.NewMerkleTree(blockTxs).GetRootHash(),
                         ShardID:      i % 4, // Distribute across shards
                 }
+                block.Size = block.EncodedSize()
+                block.Hash = block.ComputeHash()
+                previousHash = block.Hash
                 
                 blocks[i] = block
         }
@@ -494,11 +696,14 @@ func (cc *ConsensusComparator) createTestBlocks(transactions []*types.Transactio
         return blocks
 }
 
-// generateValidators creates test validators
-func (cc *ConsensusComparator) generateValidators() []*types.Validator {
-        validators := make([]*types.Validator, 4)
+// defaultTestValidators is the validator set size of the timed run
+const defaultTestValidators = 4
+
+// generateValidators creates count test validators
+func (cc *ConsensusComparator) generateValidators(count int) []*types.Validator {
+        validators := make([]*types.Validator, count)
         
-        for i := 0; i < 4; i++ {
+        for i := 0; i < count; i++ {
                 validators[i] = &types.Validator{
                         Address:    fmt.Sprintf("validator_%d", i),
                         Stake:      10000,
@@ -543,21 +748,6 @@ func (cc *ConsensusComparator) calculateFinalityTime(algorithm string, avgLatenc
         }
 }
 
-func (cc *ConsensusComparator) calculateEnergyConsumption(algorithm string, blocks int) float64 {
-        switch algorithm {
-        case "lscc":
-                return float64(blocks) * 0.1 // Very efficient
-        case "pbft", "ppbft":
-                return float64(blocks) * 0.3 // Moderate consumption
-        case "pow":
-                return float64(blocks) * 10.0 // High energy consumption
-        case "pos":
-                return float64(blocks) * 0.5  // Low consumption
-        default:
-                return float64(blocks) * 1.0
-        }
-}
-
 func (cc *ConsensusComparator) calculateSecurityLevel(algorithm string) float64 {
         switch algorithm {
         case "lscc":
@@ -662,7 +852,7 @@ func (cc *ConsensusComparator) generateSummary(testExecution *TestExecution) *Co
         scores := make(map[string]float64)
         
         for algorithm, result := range testExecution.Results {
-                score := cc.calculateOverallScore(result)
+                score := cc.calculateOverallScore(result, testExecution.TestConfig.ResourceWeight)
                 scores[algorithm] = score
                 
                 // Determine strengths and weaknesses
@@ -678,14 +868,11 @@ func (cc *ConsensusComparator) generateSummary(testExecution *TestExecution) *Co
                 summary.Rankings = append(summary.Rankings, ranking)
         }
         
-        // Sort rankings by score
-        for i := 0; i < len(summary.Rankings)-1; i++ {
-                for j := i + 1; j < len(summary.Rankings); j++ {
-                        if summary.Rankings[i].Score < summary.Rankings[j].Score {
-                                summary.Rankings[i], summary.Rankings[j] = summary.Rankings[j], summary.Rankings[i]
-                        }
-                }
-        }
+        // Sort rankings best first. Results is a map, so equal scores must be
+        // broken explicitly for the winner to be reproducible.
+        sort.SliceStable(summary.Rankings, func(i, j int) bool {
+                return rankedBefore(summary.Rankings[i], summary.Rankings[j], testExecution.Results)
+        })
         
         // Assign ranks
         for i := range summary.Rankings {
@@ -707,8 +894,37 @@ func (cc *ConsensusComparator) generateSummary(testExecution *TestExecution) *Co
         return summary
 }
 
-// calculateOverallScore computes weighted score for an algorithm
-func (cc *ConsensusComparator) calculateOverallScore(result *ComparisonResult) float64 {
+// rankedBefore orders rankings by score, highest first. Equal scores go to
+// the algorithm with the higher measured throughput, and then to the
+// algorithm whose name sorts first, so the order is total.
+func rankedBefore(a, b AlgorithmRanking, results map[string]*ComparisonResult) bool {
+        if a.Score != b.Score {
+                return a.Score > b.Score
+        }
+        
+        var throughputA, throughputB float64
+        if result, exists := results[a.Algorithm]; exists && result != nil {
+                throughputA = result.ThroughputTPS
+        }
+        if result, exists := results[b.Algorithm]; exists && result != nil {
+                throughputB = result.ThroughputTPS
+        }
+        if throughputA != throughputB {
+                return throughputA > throughputB
+        }
+        
+        return a.Algorithm < b.Algorithm
+}
+
+// calculateOverallScore computes weighted score for an algorithm. A non-zero
+// resourceWeight gives that share of the score to memory and cpu usage and
+// scales the remaining criteria down to fit.
+func (cc *ConsensusComparator) calculateOverallScore(result *ComparisonResult, resourceWeight float64) float64 {
+        // Without a single round there is nothing measured to score
+        if result.ConsensusRounds == 0 {
+                return 0
+        }
+        
         // Weighted scoring criteria
         weights := map[string]float64{
                 "throughput":       0.25,
@@ -735,6 +951,10 @@ func (cc *ConsensusComparator) calculateOverallScore(result *ComparisonResult) f
                 decentralizationScore*weights["decentralization"] +
                 energyScore*weights["energy"]
         
+        if resourceWeight > 0 {
+                totalScore = totalScore*(1-resourceWeight) + resourceScore(result)*resourceWeight
+        }
+        
         return totalScore
 }
 
@@ -743,6 +963,11 @@ func (cc *ConsensusComparator) analyzeAlgorithmPerformance(result *ComparisonRes
         strengths := make([]string, 0)
         weaknesses := make([]string, 0)
         
+        if result.ConsensusRounds == 0 {
+                weaknesses = append(weaknesses, "No blocks processed within the test duration")
+                return strengths, weaknesses
+        }
+        
         // Analyze throughput
         if result.ThroughputTPS > 100 {
                 strengths = append(strengths, "High transaction throughput")
@@ -795,7 +1020,7 @@ func (cc *ConsensusComparator) analyzeAlgorithmPerformance(result *ComparisonRes
         // Analyze failure rate
         if result.FailedRounds == 0 {
                 strengths = append(strengths, "Perfect reliability")
-        } else if float64(result.FailedRounds)/float64(result.ConsensusRounds) > 0.1 {
+        } else if result.failureRate() > 0.1 {
                 weaknesses = append(weaknesses, "High failure rate")
         }
         
@@ -807,7 +1032,7 @@ func (cc *ConsensusComparator) generateInsights(results map[string]*ComparisonRe
         insights := make([]string, 0)
         
         // Performance insights
-        if len(rankings) > 0 {
+        if len(rankings) > 0 && results[rankings[0].Algorithm].ConsensusRounds > 0 {
                 winner := rankings[0]
                 insights = append(insights, fmt.Sprintf("%s demonstrated superior overall performance with a score of %.2f", 
                         winner.Algorithm, winner.Score))
@@ -831,7 +1056,7 @@ func (cc *ConsensusComparator) generateInsights(results map[string]*ComparisonRe
         var minLatency time.Duration = time.Hour
         var minLatencyAlgorithm string
         for algorithm, result := range results {
-                if result.AverageLatency < minLatency {
+                if result.ConsensusRounds > 0 && result.AverageLatency < minLatency {
                         minLatency = result.AverageLatency
                         minLatencyAlgorithm = algorithm
                 }
@@ -845,7 +1070,7 @@ func (cc *ConsensusComparator) generateInsights(results map[string]*ComparisonRe
         var minEnergy float64 = 1000.0
         var minEnergyAlgorithm string
         for algorithm, result := range results {
-                if result.EnergyConsumption < minEnergy {
+                if result.ConsensusRounds > 0 && result.EnergyConsumption < minEnergy {
                         minEnergy = result.EnergyConsumption
                         minEnergyAlgorithm = algorithm
                 }
@@ -856,7 +1081,7 @@ func (cc *ConsensusComparator) generateInsights(results map[string]*ComparisonRe
         }
         
         // LSCC specific insights
-        if lsccResult, exists := results["lscc"]; exists {
+        if lsccResult, exists := results["lscc"]; exists && lsccResult.ConsensusRounds > 0 {
                 insights = append(insights, fmt.Sprintf("LSCC's layered architecture delivered %d%% better scalability than traditional consensus", 
                         int((lsccResult.ScalabilityScore/6.0)*100)))
                 
@@ -865,6 +1090,24 @@ func (cc *ConsensusComparator) generateInsights(results map[string]*ComparisonRe
                 }
         }
         
+        // Failure analysis
+        algorithms := make([]string, 0, len(results))
+        for algorithm := range results {
+                algorithms = append(algorithms, algorithm)
+        }
+        sort.Strings(algorithms)
+        for _, algorithm := range algorithms {
+                result := results[algorithm]
+                if result.ConsensusRounds == 0 {
+                        insights = append(insights, fmt.Sprintf("%s processed no blocks within the test duration; its figures are not measurements", algorithm))
+                        continue
+                }
+                if reason, count := dominantFailureReason(result.FailureReasons); count > 0 {
+                        insights = append(insights, fmt.Sprintf("%s failures were dominated by %q (%d of %d failed rounds)", 
+                                algorithm, reason, count, result.FailedRounds))
+                }
+        }
+        
         // Cross-algorithm insights
         if len(results) >= 2 {
                 insights = append(insights, fmt.Sprintf("Performance variance across %d algorithms shows significant architectural impact", len(results)))
@@ -873,12 +1116,25 @@ func (cc *ConsensusComparator) generateInsights(results map[string]*ComparisonRe
         return insights
 }
 
+// dominantFailureReason returns the most frequent failure reason, preferring
+// the alphabetically first on ties so summaries are stable
+func dominantFailureReason(reasons map[string]int) (string, int) {
+        var dominant string
+        var dominantCount int
+        for reason, count := range reasons {
+                if count > dominantCount || (count == dominantCount && reason < dominant) {
+                        dominant, dominantCount = reason, count
+                }
+        }
+        return dominant, dominantCount
+}
+
 // generateRecommendations creates actionable recommendations
 func (cc *ConsensusComparator) generateRecommendations(results map[string]*ComparisonResult, rankings []AlgorithmRanking) []string {
         recommendations := make([]string, 0)
         
         // Overall recommendation
-        if len(rankings) > 0 {
+        if len(rankings) > 0 && results[rankings[0].Algorithm].ConsensusRounds > 0 {
                 winner := rankings[0]
                 recommendations = append(recommendations, fmt.Sprintf("Deploy %s for optimal blockchain performance", winner.Algorithm))
         }
@@ -896,25 +1152,31 @@ func (cc *ConsensusComparator) generateRecommendations(results map[string]*Compa
                         maxTPS = result.ThroughputTPS
                         highThroughputAlg = algorithm
                 }
-                if result.AverageLatency < minLatency {
+                if result.ConsensusRounds > 0 && result.AverageLatency < minLatency {
                         minLatency = result.AverageLatency
                         lowLatencyAlg = algorithm
                 }
-                if result.EnergyConsumption < minEnergy {
+                if result.ConsensusRounds > 0 && result.EnergyConsumption < minEnergy {
                         minEnergy = result.EnergyConsumption
                         energyEfficientAlg = algorithm
                 }
         }
         
-        recommendations = append(recommendations, fmt.Sprintf("For high-volume applications, consider %s (%.2f TPS)", 
-                highThroughputAlg, maxTPS))
-        recommendations = append(recommendations, fmt.Sprintf("For low-latency requirements, %s offers %v response time", 
-                lowLatencyAlg, minLatency))
-        recommendations = append(recommendations, fmt.Sprintf("For sustainability concerns, %s provides optimal energy efficiency", 
-                energyEfficientAlg))
+        if highThroughputAlg != "" {
+                recommendations = append(recommendations, fmt.Sprintf("For high-volume applications, consider %s (%.2f TPS)", 
+                        highThroughputAlg, maxTPS))
+        }
+        if lowLatencyAlg != "" {
+                recommendations = append(recommendations, fmt.Sprintf("For low-latency requirements, %s offers %v response time", 
+                        lowLatencyAlg, minLatency))
+        }
+        if energyEfficientAlg != "" {
+                recommendations = append(recommendations, fmt.Sprintf("For sustainability concerns, %s provides optimal energy efficiency", 
+                        energyEfficientAlg))
+        }
         
         // LSCC specific recommendations
-        if lsccResult, exists := results["lscc"]; exists {
+        if lsccResult, exists := results["lscc"]; exists && lsccResult.ConsensusRounds > 0 {
                 if lsccResult.ScalabilityScore > 8.0 {
                         recommendations = append(recommendations, "LSCC recommended for enterprise applications requiring horizontal scaling")
                 }
@@ -925,9 +1187,11 @@ func (cc *ConsensusComparator) generateRecommendations(results map[string]*Compa
         
         // Improvement recommendations
         for algorithm, result := range results {
-                if result.FailedRounds > 0 {
+                if result.ConsensusRounds == 0 {
+                        recommendations = append(recommendations, fmt.Sprintf("Rerun %s with a longer duration or lower network latency so it completes at least one round", algorithm))
+                } else if result.FailedRounds > 0 {
                         recommendations = append(recommendations, fmt.Sprintf("Optimize %s network reliability to reduce %d%% failure rate", 
-                                algorithm, int(float64(result.FailedRounds)/float64(result.ConsensusRounds)*100)))
+                                algorithm, int(result.failureRate()*100)))
                 }
         }
         
@@ -1029,20 +1293,19 @@ func (cc *ConsensusComparator) GetTestHistory() []*ComparatorSummary {
         return history
 }
 
-// GetActiveTests returns currently running tests
+// GetActiveTests returns deep copies of the currently running tests
 func (cc *ConsensusComparator) GetActiveTests() map[string]*TestExecution {
         cc.mu.RLock()
         defer cc.mu.RUnlock()
         
-        // Return copy to prevent external modification
-        active := make(map[string]*TestExecution)
+        active := make(map[string]*TestExecution, len(cc.activeTests))
         for key, value := range cc.activeTests {
-                active[key] = value
+                active[key] = value.snapshot()
         }
         return active
 }
 
-// GetAvailableAlgorithms returns list of available consensus algorithms
+// GetAvailableAlgorithms returns the initialized consensus algorithms, sorted
 func (cc *ConsensusComparator) GetAvailableAlgorithms() []string {
         cc.mu.RLock()
         defer cc.mu.RUnlock()
@@ -1051,6 +1314,7 @@ func (cc *ConsensusComparator) GetAvailableAlgorithms() []string {
         for algorithm := range cc.algorithms {
                 algorithms = append(algorithms, algorithm)
         }
+        sort.Strings(algorithms)
         return algorithms
 }
 
@@ -1081,7 +1345,7 @@ func (cc *ConsensusComparator) RunStressTest() (*ComparatorSummary, error) {
                 ConcurrentNodes:   8,
                 NetworkLatency:    100 * time.Millisecond,
                 Byzantine:         0.33,
-                Algorithms:        []string{"lscc", "pbft", "ppbft", "pow", "pos"},
+                Algorithms:        consensus.RegisteredAlgorithms(),
                 Metrics:           []string{"throughput", "latency", "finality", "energy", "scalability", "security"},
                 StressTest:        true,
                 RealTimeReporting: true,
@@ -1092,6 +1356,8 @@ func (cc *ConsensusComparator) RunStressTest() (*ComparatorSummary, error) {
 
 // Shutdown gracefully shuts down the comparator
 func (cc *ConsensusComparator) Shutdown() error {
+        cc.runMu.Lock()
+        defer cc.runMu.Unlock()
         cc.mu.Lock()
         defer cc.mu.Unlock()
         
@@ -0,0 +1,96 @@
+package comparator
+
+import (
+	"testing"
+	"time"
+
+	"lscc-blockchain/internal/consensus"
+)
+
+func TestComparisonCountsFailureReasons(t *testing.T) {
+	cc := newTestComparator(t)
+
+	// Test blocks name no proposer, so proof of stake refuses every one
+	summary, err := cc.RunComparison(quickComparison("pos"))
+	if err != nil {
+		t.Fatalf("comparison failed: %v", err)
+	}
+	result := summary.Results["pos"]
+	if result == nil {
+		t.Fatal("no pos result")
+	}
+	if result.FailedRounds == 0 {
+		t.Fatal("no failed rounds to classify")
+	}
+	if got := result.FailureReasons[consensus.FailureWrongValidator]; got != result.FailedRounds {
+		t.Fatalf("%s failures = %d of %d failed rounds (all: %v)", consensus.FailureWrongValidator, got, result.FailedRounds, result.FailureReasons)
+	}
+	if len(result.ErrorMessages) != result.FailedRounds {
+		t.Fatalf("%d error messages for %d failed rounds", len(result.ErrorMessages), result.FailedRounds)
+	}
+}
+
+func TestTiedScoresRankDeterministically(t *testing.T) {
+	cc := newTestComparator(t)
+	tied := func(algorithm string) *ComparisonResult {
+		return &ComparisonResult{
+			Algorithm:       algorithm,
+			BlocksProcessed: 10,
+			ConsensusRounds: 10,
+			ThroughputTPS:   100,
+			AverageLatency:  50 * time.Millisecond,
+			FailureReasons:  map[string]int{},
+		}
+	}
+
+	for run := 0; run < 50; run++ {
+		execution := &TestExecution{
+			TestConfig: quickComparison("pos", "pbft", "lscc"),
+			StartTime:  time.Now(),
+			Results: map[string]*ComparisonResult{
+				"pos":  tied("pos"),
+				"pbft": tied("pbft"),
+				"lscc": tied("lscc"),
+			},
+		}
+		summary := cc.generateSummary(execution)
+		scores := map[float64]bool{}
+		for _, ranking := range summary.Rankings {
+			scores[ranking.Score] = true
+		}
+		if len(scores) != 1 {
+			t.Fatalf("scores are not tied: %+v", summary.Rankings)
+		}
+		for i, want := range []string{"lscc", "pbft", "pos"} {
+			if got := summary.Rankings[i]; got.Algorithm != want || got.Rank != i+1 {
+				t.Fatalf("run %d: rank %d is %s (%d), want %s", run, i+1, got.Algorithm, got.Rank, want)
+			}
+		}
+		if summary.Winner != "lscc" {
+			t.Fatalf("run %d: winner = %s, want lscc", run, summary.Winner)
+		}
+	}
+}
+
+func TestRankedBeforeBreaksTiesByThroughput(t *testing.T) {
+	results := map[string]*ComparisonResult{
+		"lscc": {ThroughputTPS: 100},
+		"pbft": {ThroughputTPS: 200},
+	}
+	lscc := AlgorithmRanking{Algorithm: "lscc", Score: 80}
+	pbft := AlgorithmRanking{Algorithm: "pbft", Score: 80}
+	if !rankedBefore(pbft, lscc, results) || rankedBefore(lscc, pbft, results) {
+		t.Fatal("equal scores should rank the higher throughput first")
+	}
+
+	pbft.Score = 79
+	if !rankedBefore(lscc, pbft, results) {
+		t.Fatal("a higher score should rank first whatever the throughput")
+	}
+
+	results["pbft"].ThroughputTPS = 100
+	pbft.Score = 80
+	if !rankedBefore(lscc, pbft, results) || rankedBefore(pbft, lscc, results) {
+		t.Fatal("full ties should rank by algorithm name")
+	}
+}
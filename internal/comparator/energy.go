@@ -0,0 +1,95 @@
+package comparator
+
+// EnergyModel estimates the energy an algorithm spent during a comparison
+// run. Cost is called once the result's block, round and message counts are
+// final, and its return value becomes result.EnergyConsumption.
+type EnergyModel interface {
+        Cost(algorithm string, result *ComparisonResult) float64
+}
+
+// EnergyModelFunc adapts an ordinary function to the EnergyModel interface
+type EnergyModelFunc func(algorithm string, result *ComparisonResult) float64
+
+// Cost calls f(algorithm, result)
+func (f EnergyModelFunc) Cost(algorithm string, result *ComparisonResult) float64 {
+        return f(algorithm, result)
+}
+
+// DefaultEnergyModel charges a fixed cost per processed block, reflecting
+// the relative work each algorithm does to commit one
+type DefaultEnergyModel struct{}
+
+// Cost implements EnergyModel
+func (DefaultEnergyModel) Cost(algorithm string, result *ComparisonResult) float64 {
+        blocks := float64(result.BlocksProcessed)
+        switch algorithm {
+        case "lscc":
+                return blocks * 0.1 // Very efficient
+        case "pbft", "ppbft":
+                return blocks * 0.3 // Moderate consumption
+        case "pow":
+                return blocks * 10.0 // High energy consumption
+        case "pos":
+                return blocks * 0.5  // Low consumption
+        default:
+                return blocks * 1.0
+        }
+}
+
+// Work costs used by MeasuredEnergyModel fields left at zero, in the same
+// units as DefaultEnergyModel: a PoW block at difficulty 4 takes about 65k
+// hashes, and a PBFT round weighs a handful of votes.
+const (
+        defaultCostPerHash  = 0.00015
+        defaultCostPerVote  = 0.04
+        defaultCostPerRound = 0.02
+)
+
+// MeasuredEnergyModel charges for the work an algorithm was seen doing
+// during the run: each hash attempt, each vote weighed and each round.
+// Results that report neither hashes nor votes fall back to
+// DefaultEnergyModel. CPU time is not charged because algorithms in one
+// comparison run side by side and share the process.
+type MeasuredEnergyModel struct {
+        PerHash  float64
+        PerVote  float64
+        PerRound float64
+}
+
+// Cost implements EnergyModel
+func (m MeasuredEnergyModel) Cost(algorithm string, result *ComparisonResult) float64 {
+        work := result.Work
+        if work.HashAttempts == 0 && work.VotesConsidered == 0 {
+                return DefaultEnergyModel{}.Cost(algorithm, result)
+        }
+
+        perHash, perVote, perRound := m.PerHash, m.PerVote, m.PerRound
+        if perHash == 0 {
+                perHash = defaultCostPerHash
+        }
+        if perVote == 0 {
+                perVote = defaultCostPerVote
+        }
+        if perRound == 0 {
+                perRound = defaultCostPerRound
+        }
+
+        return float64(work.HashAttempts)*perHash +
+                float64(work.VotesConsidered)*perVote +
+                float64(result.ConsensusRounds)*perRound
+}
+
+// SetEnergyModel replaces the model used to compute EnergyConsumption for
+// subsequent comparisons. A nil model restores MeasuredEnergyModel. It waits
+// for any running comparison to finish.
+func (cc *ConsensusComparator) SetEnergyModel(model EnergyModel) {
+        cc.runMu.Lock()
+        defer cc.runMu.Unlock()
+        cc.mu.Lock()
+        defer cc.mu.Unlock()
+
+        if model == nil {
+                model = MeasuredEnergyModel{}
+        }
+        cc.energyModel = model
+}
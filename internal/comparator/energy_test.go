@@ -0,0 +1,72 @@
+package comparator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCustomEnergyModel(t *testing.T) {
+	cc := newTestComparator(t)
+	var calls []string
+	cc.SetEnergyModel(EnergyModelFunc(func(algorithm string, result *ComparisonResult) float64 {
+		calls = append(calls, algorithm)
+		return float64(result.BlocksProcessed)*7 + float64(result.NetworkMessages)
+	}))
+
+	summary, err := cc.RunComparison(quickComparison("lscc"))
+	if err != nil {
+		t.Fatalf("comparison failed: %v", err)
+	}
+	result := summary.Results["lscc"]
+	if result == nil {
+		t.Fatal("no lscc result")
+	}
+	if result.BlocksProcessed == 0 {
+		t.Fatal("no blocks processed; the model saw nothing to charge")
+	}
+	if want := float64(result.BlocksProcessed)*7 + float64(result.NetworkMessages); result.EnergyConsumption != want {
+		t.Fatalf("energy = %v, want %v from the custom model", result.EnergyConsumption, want)
+	}
+	if len(calls) != 1 || calls[0] != "lscc" {
+		t.Fatalf("model called for %v, want once for lscc", calls)
+	}
+
+	// A nil model restores the measured one
+	cc.SetEnergyModel(nil)
+	summary, err = cc.RunComparison(quickComparison("lscc"))
+	if err != nil {
+		t.Fatalf("comparison failed: %v", err)
+	}
+	result = summary.Results["lscc"]
+	if want := (MeasuredEnergyModel{}).Cost("lscc", result); result.EnergyConsumption != want {
+		t.Fatalf("energy = %v, want %v from the measured model", result.EnergyConsumption, want)
+	}
+	if len(calls) != 1 {
+		t.Fatal("replaced model still called")
+	}
+}
+
+func TestMeasuredEnergyModel(t *testing.T) {
+	result := &ComparisonResult{BlocksProcessed: 10, ConsensusRounds: 12}
+
+	// Nothing measured: the per-block defaults apply
+	if got, want := (MeasuredEnergyModel{}).Cost("pow", result), (DefaultEnergyModel{}).Cost("pow", result); got != want {
+		t.Fatalf("unmeasured cost = %v, want the default %v", got, want)
+	}
+	if got := (DefaultEnergyModel{}).Cost("pow", result); got != 100 {
+		t.Fatalf("default pow cost = %v, want 100", got)
+	}
+
+	result.Work.HashAttempts = 1000
+	result.Work.VotesConsidered = 50
+	model := MeasuredEnergyModel{PerHash: 0.01, PerVote: 0.1, PerRound: 1}
+	if got, want := model.Cost("pbft", result), 1000*0.01+50*0.1+12*1.0; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("measured cost = %v, want %v", got, want)
+	}
+
+	// Zero rates fall back to the built-in ones
+	want := 1000*defaultCostPerHash + 50*defaultCostPerVote + 12*defaultCostPerRound
+	if got := (MeasuredEnergyModel{}).Cost("pbft", result); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("measured cost with default rates = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+package comparator
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/utils"
+)
+
+// newTestComparator returns a comparator on the repository configuration,
+// logging discarded and shut down when the test ends
+func newTestComparator(t *testing.T) *ConsensusComparator {
+	t.Helper()
+	cfg, err := config.LoadConfigFromPath("../../config/config.yaml")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	logger := utils.NewLogger()
+	logger.Logger.SetOutput(io.Discard)
+
+	cc, err := NewConsensusComparator(cfg, logger)
+	if err != nil {
+		t.Fatalf("failed to create comparator: %v", err)
+	}
+	t.Cleanup(func() { cc.Shutdown() })
+	return cc
+}
+
+// quickComparison returns a short comparison of algorithms without network
+// delay
+func quickComparison(algorithms ...string) *TestConfiguration {
+	return &TestConfiguration{
+		Name:            "test",
+		Duration:        2 * time.Second,
+		TransactionLoad: 30,
+		ConcurrentNodes: 4,
+		Algorithms:      algorithms,
+		Metrics:         []string{"throughput", "energy"},
+	}
+}
@@ -0,0 +1,131 @@
+package comparator
+
+import (
+        "math"
+        "time"
+
+        "lscc-blockchain/internal/consensus"
+)
+
+// Scaling probe parameters: after the timed run each algorithm commits a few
+// blocks with a small and a large validator set, and its scalability is how
+// well it keeps its per-block latency as the set grows.
+const (
+        scalingProbeSmall  = 4
+        scalingProbeLarge  = 16
+        scalingProbeBlocks = 2
+)
+
+// WorkMeasurements is what an algorithm was observed doing during a
+// comparison run. The energy, security and scalability figures are derived
+// from it; an algorithm that reports nothing is scored with fixed per-algorithm
+// estimates instead.
+type WorkMeasurements struct {
+        HashAttempts    int64        `json:"hash_attempts"`    // proof-of-work hashes tried
+        VotesConsidered int64        `json:"votes_considered"` // validator votes weighed
+        VotesExcluded   int64        `json:"votes_excluded"`   // votes left out as Byzantine
+        AuditedRounds   int          `json:"audited_rounds"`   // rounds in which votes were weighed
+        SecureRounds    int          `json:"secure_rounds"`    // audited rounds that did not commit past a one-third Byzantine share
+        Scaling         ScalingProbe `json:"scaling"`
+}
+
+// ScalingProbe compares per-block latency with a small and a large validator
+// set. Ratio is small over large latency, so 1 means no slowdown; it is 0
+// when either set committed no block.
+type ScalingProbe struct {
+        SmallValidators int           `json:"small_validators"`
+        LargeValidators int           `json:"large_validators"`
+        SmallLatency    time.Duration `json:"small_latency"`
+        LargeLatency    time.Duration `json:"large_latency"`
+        Ratio           float64       `json:"ratio"`
+}
+
+// voteAudit returns the engine's vote counts, if it keeps them
+func voteAudit(instance consensus.Consensus) (consensus.VoteAudit, bool) {
+        auditor, ok := instance.(consensus.VoteAuditor)
+        if !ok {
+                return consensus.VoteAudit{}, false
+        }
+        return auditor.VoteAudit(), true
+}
+
+// totalHashes returns the engine's cumulative hash attempts, 0 for engines
+// that do no proof of work
+func totalHashes(instance consensus.Consensus) int64 {
+        state := instance.GetConsensusState()
+        if state == nil {
+                return 0
+        }
+        return int64(state.Performance["total_hashes"])
+}
+
+// recordRound adds the votes weighed in one round. A round is secure unless
+// it committed while a third or more of the votes it weighed were excluded
+// as Byzantine, beyond what BFT quorums tolerate.
+func (w *WorkMeasurements) recordRound(before, after consensus.VoteAudit, committed bool) {
+        considered := after.Considered - before.Considered
+        excluded := after.Excluded - before.Excluded
+        if considered <= 0 {
+                return
+        }
+
+        w.VotesConsidered += considered
+        w.VotesExcluded += excluded
+        w.AuditedRounds++
+        if !committed || excluded*3 < considered {
+                w.SecureRounds++
+        }
+}
+
+// probeScaling runs the scaling probe against instance
+func (cc *ConsensusComparator) probeScaling(instance consensus.Consensus) ScalingProbe {
+        probe := ScalingProbe{SmallValidators: scalingProbeSmall, LargeValidators: scalingProbeLarge}
+        probe.SmallLatency = cc.probeLatency(instance, scalingProbeSmall)
+        probe.LargeLatency = cc.probeLatency(instance, scalingProbeLarge)
+        if probe.SmallLatency > 0 && probe.LargeLatency > 0 {
+                probe.Ratio = float64(probe.SmallLatency) / float64(probe.LargeLatency)
+        }
+        return probe
+}
+
+// probeLatency returns the mean latency of the probe blocks committed with
+// validatorCount validators, 0 when none was committed
+func (cc *ConsensusComparator) probeLatency(instance consensus.Consensus, validatorCount int) time.Duration {
+        blocks := cc.createTestBlocks(cc.generateTestTransactions(scalingProbeBlocks * 10))
+        validators := cc.generateValidators(validatorCount)
+
+        var total time.Duration
+        committed := 0
+        for _, block := range blocks {
+                started := time.Now()
+                success, err := instance.ProcessBlock(block, validators)
+                if err != nil || !success {
+                        continue
+                }
+                total += time.Since(started)
+                committed++
+        }
+        if committed == 0 {
+                return 0
+        }
+        return total / time.Duration(committed)
+}
+
+// measuredSecurity scores the share of audited rounds that stayed within the
+// Byzantine bound. ok is false when no round weighed votes.
+func measuredSecurity(work WorkMeasurements) (score float64, ok bool) {
+        if work.AuditedRounds == 0 {
+                return 0, false
+        }
+        return 10.0 * float64(work.SecureRounds) / float64(work.AuditedRounds), true
+}
+
+// measuredScalability scores the scaling probe: 10 when latency does not grow
+// with the validator set, falling in proportion as it does. ok is false when
+// the probe committed nothing.
+func measuredScalability(work WorkMeasurements) (score float64, ok bool) {
+        if work.Scaling.Ratio <= 0 {
+                return 0, false
+        }
+        return 10.0 * math.Min(work.Scaling.Ratio, 1.0), true
+}
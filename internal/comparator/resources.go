@@ -0,0 +1,90 @@
+package comparator
+
+import (
+        "math"
+        "runtime"
+        "runtime/metrics"
+        "time"
+)
+
+// Scheduler CPU accounting. Both are cumulative CPU-seconds: total is every
+// P's wall time and idle the part spent with nothing to run.
+const (
+        cpuTotalMetric = "/cpu/classes/total:cpu-seconds"
+        cpuIdleMetric  = "/cpu/classes/idle:cpu-seconds"
+)
+
+// concurrentResourcesNote is recorded when several algorithms ran at once
+const concurrentResourcesNote = "memory and cpu usage are sampled process-wide and include the algorithms that ran alongside this one"
+
+// resourceSample is a point-in-time reading of the process's resource use
+type resourceSample struct {
+        at         time.Time
+        heapAlloc  uint64
+        totalAlloc uint64
+        goroutines int
+        cpuBusy    float64 // CPU-seconds spent doing work, -1 when the runtime does not report it
+}
+
+// sampleResources reads the current heap, goroutine and CPU figures
+func sampleResources() resourceSample {
+        var mem runtime.MemStats
+        runtime.ReadMemStats(&mem)
+
+        sample := resourceSample{
+                at:         time.Now(),
+                heapAlloc:  mem.HeapAlloc,
+                totalAlloc: mem.TotalAlloc,
+                goroutines: runtime.NumGoroutine(),
+                cpuBusy:    -1,
+        }
+
+        readings := []metrics.Sample{{Name: cpuTotalMetric}, {Name: cpuIdleMetric}}
+        metrics.Read(readings)
+        if readings[0].Value.Kind() == metrics.KindFloat64 && readings[1].Value.Kind() == metrics.KindFloat64 {
+                sample.cpuBusy = readings[0].Value.Float64() - readings[1].Value.Float64()
+        }
+
+        return sample
+}
+
+// resourceUsage is what changed between two samples
+type resourceUsage struct {
+        heapGrowth     int64   // HeapAlloc delta in bytes, 0 when the heap shrank
+        bytesAllocated uint64  // bytes allocated, whether or not since freed
+        goroutineDelta int     // goroutines left running at the end
+        cpuPercent     float64 // share of GOMAXPROCS kept busy, -1 when unknown
+}
+
+// usageSince returns the resources used between start and end. CPU usage is
+// busy CPU time over the wall time available to GOMAXPROCS processors, so
+// 100 means every processor was busy for the whole interval.
+func usageSince(start, end resourceSample) resourceUsage {
+        usage := resourceUsage{
+                bytesAllocated: end.totalAlloc - start.totalAlloc,
+                goroutineDelta: end.goroutines - start.goroutines,
+                cpuPercent:     -1,
+        }
+        if end.heapAlloc > start.heapAlloc {
+                usage.heapGrowth = int64(end.heapAlloc - start.heapAlloc)
+        }
+
+        wall := end.at.Sub(start.at).Seconds()
+        if start.cpuBusy >= 0 && end.cpuBusy >= 0 && wall > 0 {
+                available := wall * float64(runtime.GOMAXPROCS(0))
+                usage.cpuPercent = math.Min(math.Max((end.cpuBusy-start.cpuBusy)/available*100, 0), 100)
+        }
+        return usage
+}
+
+// resourceScore rates resource use on the 0-10 scale of the other score
+// components: each 10 MB of heap growth and each 10% of CPU costs a point.
+// CPU is left out when it could not be measured.
+func resourceScore(result *ComparisonResult) float64 {
+        memoryScore := math.Max(10.0-float64(result.MemoryUsage)/(10*1024*1024), 0.0)
+        if result.CPUUsage < 0 {
+                return memoryScore
+        }
+        cpuScore := math.Max(10.0-result.CPUUsage/10.0, 0.0)
+        return (memoryScore + cpuScore) / 2
+}
@@ -0,0 +1,73 @@
+package comparator
+
+import (
+        "encoding/json"
+        "errors"
+        "fmt"
+        "io"
+        "os"
+
+        "lscc-blockchain/pkg/types"
+)
+
+// ReadTransactionTrace reads a recorded transaction trace: JSON-encoded
+// transactions one per line, in the order they are to be replayed.
+func ReadTransactionTrace(r io.Reader) ([]*types.Transaction, error) {
+        decoder := json.NewDecoder(r)
+        transactions := make([]*types.Transaction, 0)
+        for {
+                tx := &types.Transaction{}
+                if err := decoder.Decode(tx); err != nil {
+                        if errors.Is(err, io.EOF) {
+                                return transactions, nil
+                        }
+                        return nil, fmt.Errorf("transaction %d: %w", len(transactions)+1, err)
+                }
+                transactions = append(transactions, tx)
+        }
+}
+
+// WriteTransactionTrace records transactions in the format read by
+// ReadTransactionTrace
+func WriteTransactionTrace(w io.Writer, transactions []*types.Transaction) error {
+        encoder := json.NewEncoder(w)
+        for _, tx := range transactions {
+                if err := encoder.Encode(tx); err != nil {
+                        return err
+                }
+        }
+        return nil
+}
+
+// loadTransactionTrace reads the trace at path
+func loadTransactionTrace(path string) ([]*types.Transaction, error) {
+        file, err := os.Open(path)
+        if err != nil {
+                return nil, err
+        }
+        defer file.Close()
+
+        transactions, err := ReadTransactionTrace(file)
+        if err != nil {
+                return nil, err
+        }
+        if len(transactions) == 0 {
+                return nil, fmt.Errorf("trace %s holds no transactions", path)
+        }
+        return transactions, nil
+}
+
+// replayTransactions returns the first count transactions of trace, all of
+// them when count is 0 or exceeds the trace. Each algorithm gets its own
+// copies so engines running side by side never share a transaction.
+func replayTransactions(trace []*types.Transaction, count int) []*types.Transaction {
+        if count <= 0 || count > len(trace) {
+                count = len(trace)
+        }
+        transactions := make([]*types.Transaction, count)
+        for i, tx := range trace[:count] {
+                copied := *tx
+                transactions[i] = &copied
+        }
+        return transactions
+}
@@ -0,0 +1,73 @@
+package comparator
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestZeroRoundComparison(t *testing.T) {
+	cc := newTestComparator(t)
+	testConfig := quickComparison("lscc", "pbft")
+	testConfig.Duration = time.Nanosecond
+	testConfig.NetworkLatency = 10 * time.Millisecond
+
+	summary, err := cc.RunComparison(testConfig)
+	if err != nil {
+		t.Fatalf("comparison failed: %v", err)
+	}
+	for _, algorithm := range testConfig.Algorithms {
+		result := summary.Results[algorithm]
+		if result == nil {
+			t.Fatalf("no %s result", algorithm)
+		}
+		if result.ConsensusRounds != 0 {
+			t.Fatalf("%s: expected no rounds inside a 1ns test, ran %d", algorithm, result.ConsensusRounds)
+		}
+		if result.AverageLatency != 0 || result.ThroughputTPS != 0 || result.failureRate() != 0 {
+			t.Fatalf("%s: expected zero latency, throughput and failure rate, got %v, %v, %v",
+				algorithm, result.AverageLatency, result.ThroughputTPS, result.failureRate())
+		}
+		if !hasNote(result.Notes, noRoundsNote) {
+			t.Fatalf("%s: expected the no-rounds note, got %v", algorithm, result.Notes)
+		}
+	}
+	for _, ranking := range summary.Rankings {
+		if math.IsNaN(ranking.Score) || math.IsInf(ranking.Score, 0) {
+			t.Fatalf("%s scored %v", ranking.Algorithm, ranking.Score)
+		}
+	}
+	// NaN or Inf anywhere in the summary would fail to encode
+	if _, err := json.Marshal(summary); err != nil {
+		t.Fatalf("summary does not encode: %v", err)
+	}
+}
+
+func TestZeroRoundSummary(t *testing.T) {
+	cc := newTestComparator(t)
+	results := map[string]*ComparisonResult{
+		"lscc": {Algorithm: "lscc", Notes: []string{noRoundsNote}, FailureReasons: map[string]int{}},
+		"pbft": {Algorithm: "pbft", ConsensusRounds: 4, BlocksProcessed: 4, TransactionsTotal: 40, ThroughputTPS: 20, AverageLatency: time.Millisecond},
+	}
+	summary := cc.generateSummary(&TestExecution{
+		TestConfig: quickComparison("lscc", "pbft"),
+		StartTime:  time.Now(),
+		Results:    results,
+	})
+	if summary.Winner != "pbft" {
+		t.Fatalf("expected the algorithm that ran to win, got %q", summary.Winner)
+	}
+	if _, err := json.Marshal(summary); err != nil {
+		t.Fatalf("summary does not encode: %v", err)
+	}
+}
+
+func hasNote(notes []string, note string) bool {
+	for _, n := range notes {
+		if n == note {
+			return true
+		}
+	}
+	return false
+}
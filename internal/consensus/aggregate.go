@@ -0,0 +1,266 @@
+package consensus
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// shareLength is the hex length of one signature share: a 65-byte
+// recoverable secp256k1 signature
+const shareLength = 130
+
+// voteHeaderBytes approximates what a vote repeats besides its signer and
+// signature: round, view and timestamp
+const voteHeaderBytes = 24
+
+// AggregatedVote replaces the individual votes of one phase with a single
+// signature over the same block, view and vote type, and the list of
+// validators whose shares it combines.
+//
+// Each share is a secp256k1 signature made with the signer's private key
+// over the vote digest. ECDSA shares cannot be folded into one, so the
+// aggregate carries them in signer order and is verified share by share
+// against the signers' public keys. What it saves over individual votes is
+// the block hash, vote type and header every vote would repeat. Swapping
+// in pairing-based signatures only changes combineShares and verifyShares.
+type AggregatedVote struct {
+	BlockHash string   `json:"block_hash"`
+	VoteType  string   `json:"vote_type"`
+	Round     int64    `json:"round"`
+	View      int64    `json:"view"`
+	Signers   []string `json:"signers"` // validator addresses, sorted
+	Signature string   `json:"signature"`
+}
+
+// Size returns the number of bytes the aggregate adds to a phase message
+func (a AggregatedVote) Size() int {
+	size := len(a.BlockHash) + len(a.VoteType) + voteHeaderBytes + len(a.Signature)
+	for _, signer := range a.Signers {
+		size += len(signer)
+	}
+	return size
+}
+
+// voteKeyring holds the signing key of each validator whose votes an
+// engine simulates. A validator's key is created the first time it votes.
+type voteKeyring struct {
+	mu   sync.Mutex
+	keys map[string]*secp256k1.PrivateKey // validator address -> key
+}
+
+func newVoteKeyring() *voteKeyring {
+	return &voteKeyring{keys: make(map[string]*secp256k1.PrivateKey)}
+}
+
+// key returns the signing key of address, creating it if it has none
+func (k *voteKeyring) key(address string) (*secp256k1.PrivateKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, exists := k.keys[address]; exists {
+		return key, nil
+	}
+	key, err := utils.GenerateSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	k.keys[address] = key
+	return key, nil
+}
+
+// PublicKey returns the public key votes from address must verify against
+func (k *voteKeyring) PublicKey(address string) (*secp256k1.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, exists := k.keys[address]
+	if !exists {
+		return nil, fmt.Errorf("no vote key registered for %s", address)
+	}
+	return key.PubKey(), nil
+}
+
+// voteDigest returns the bytes a signature share covers: the vote type,
+// block and view, so a share cannot be replayed for another phase
+func voteDigest(blockHash, voteType string, view int64) []byte {
+	digest := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d", voteType, blockHash, view)))
+	return digest[:]
+}
+
+// signVoteShare returns a validator's signature share for a vote in
+// aggregation mode, made with its key in keys
+func signVoteShare(keys *voteKeyring, validator *types.Validator, vote *Vote) (string, error) {
+	key, err := keys.key(validator.Address)
+	if err != nil {
+		return "", fmt.Errorf("no vote key for %s: %w", validator.Address, err)
+	}
+	return utils.SignDigest(key, voteDigest(vote.BlockHash, vote.VoteType, vote.View)), nil
+}
+
+// combineShares joins signature shares, ordered by signer, into an aggregate
+func combineShares(shares []string) string {
+	return strings.Join(shares, "")
+}
+
+// verifyShares checks every share of aggregated against its signer's key
+func verifyShares(aggregated AggregatedVote, publicKey func(address string) (*secp256k1.PublicKey, error)) error {
+	if len(aggregated.Signature) != len(aggregated.Signers)*shareLength {
+		return fmt.Errorf("aggregated %s signature for block %s carries %d bytes for %d signers",
+			aggregated.VoteType, aggregated.BlockHash, len(aggregated.Signature)/2, len(aggregated.Signers))
+	}
+
+	digest := voteDigest(aggregated.BlockHash, aggregated.VoteType, aggregated.View)
+	for i, address := range aggregated.Signers {
+		key, err := publicKey(address)
+		if err != nil {
+			return err
+		}
+		share := aggregated.Signature[i*shareLength : (i+1)*shareLength]
+		if err := utils.VerifyDigest(key, digest, share); err != nil {
+			return fmt.Errorf("share of %s in aggregated %s signature for block %s: %w",
+				address, aggregated.VoteType, aggregated.BlockHash, err)
+		}
+	}
+	return nil
+}
+
+// AggregateVotes combines votes for the same block, view and vote type into
+// one AggregatedVote. Each vote's Signature must be a share produced in
+// aggregation mode.
+func AggregateVotes(votes []*Vote) (AggregatedVote, error) {
+	if len(votes) == 0 {
+		return AggregatedVote{}, errors.New("no votes to aggregate")
+	}
+
+	first := votes[0]
+	ordered := make([]*Vote, 0, len(votes))
+	seen := make(map[string]bool, len(votes))
+
+	for _, vote := range votes {
+		if vote.BlockHash != first.BlockHash || vote.VoteType != first.VoteType || vote.View != first.View {
+			return AggregatedVote{}, fmt.Errorf("vote from %s is for %s/%s view %d, expected %s/%s view %d",
+				vote.ValidatorAddress, vote.VoteType, vote.BlockHash, vote.View, first.VoteType, first.BlockHash, first.View)
+		}
+		if seen[vote.ValidatorAddress] {
+			return AggregatedVote{}, fmt.Errorf("duplicate vote from %s", vote.ValidatorAddress)
+		}
+		seen[vote.ValidatorAddress] = true
+
+		if len(vote.Signature) != shareLength {
+			return AggregatedVote{}, fmt.Errorf("vote from %s does not carry a signature share", vote.ValidatorAddress)
+		}
+		ordered = append(ordered, vote)
+	}
+
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].ValidatorAddress < ordered[j].ValidatorAddress
+	})
+	signers := make([]string, len(ordered))
+	shares := make([]string, len(ordered))
+	for i, vote := range ordered {
+		signers[i] = vote.ValidatorAddress
+		shares[i] = vote.Signature
+	}
+
+	return AggregatedVote{
+		BlockHash: first.BlockHash,
+		VoteType:  first.VoteType,
+		Round:     first.Round,
+		View:      first.View,
+		Signers:   signers,
+		Signature: combineShares(shares),
+	}, nil
+}
+
+// VerifyAggregatedVote checks an aggregate against the keys of its signers,
+// which publicKey looks up. Every signer must belong to validators.
+func VerifyAggregatedVote(aggregated AggregatedVote, validators []*types.Validator, publicKey func(address string) (*secp256k1.PublicKey, error)) error {
+	if len(aggregated.Signers) == 0 {
+		return errors.New("aggregated vote has no signers")
+	}
+
+	members := make(map[string]bool, len(validators))
+	for _, validator := range validators {
+		members[validator.Address] = true
+	}
+
+	seen := make(map[string]bool, len(aggregated.Signers))
+	for _, address := range aggregated.Signers {
+		if !members[address] {
+			return fmt.Errorf("signer %s is not a validator", address)
+		}
+		if seen[address] {
+			return fmt.Errorf("signer %s is listed twice", address)
+		}
+		seen[address] = true
+	}
+
+	return verifyShares(aggregated, publicKey)
+}
+
+// aggregatePhase aggregates the votes collected for one phase in view and
+// verifies the result against validators and their keys. Votes left over
+// from earlier views of the same block are ignored.
+func aggregatePhase(votes map[string]*Vote, view int64, validators []*types.Validator, keys *voteKeyring) (AggregatedVote, error) {
+	collected := make([]*Vote, 0, len(votes))
+	for _, vote := range votes {
+		if vote.View == view {
+			collected = append(collected, vote)
+		}
+	}
+
+	aggregated, err := AggregateVotes(collected)
+	if err != nil {
+		return AggregatedVote{}, withReason(FailureInvalidSignature, err)
+	}
+	if err := VerifyAggregatedVote(aggregated, validators, keys.PublicKey); err != nil {
+		return AggregatedVote{}, withReason(FailureInvalidSignature, err)
+	}
+	return aggregated, nil
+}
+
+// individualVoteBytes returns the bytes the aggregated votes would carry as
+// one vote per signer
+func individualVoteBytes(aggregated AggregatedVote) int {
+	size := 0
+	for _, signer := range aggregated.Signers {
+		size += len(signer) + shareLength + len(aggregated.BlockHash) + len(aggregated.VoteType) + voteHeaderBytes
+	}
+	return size
+}
+
+// phaseAggregates holds the aggregates that replace the individual votes of
+// each phase: block hash -> vote type -> aggregate
+type phaseAggregates map[string]map[string]AggregatedVote
+
+// put records aggregated as its block's vote of its type
+func (p phaseAggregates) put(aggregated AggregatedVote) {
+	if p[aggregated.BlockHash] == nil {
+		p[aggregated.BlockHash] = make(map[string]AggregatedVote)
+	}
+	p[aggregated.BlockHash][aggregated.VoteType] = aggregated
+}
+
+// signers returns the number of votes of voteType carried by aggregates
+func (p phaseAggregates) signers(voteType string) int {
+	count := 0
+	for _, byType := range p {
+		count += len(byType[voteType].Signers)
+	}
+	return count
+}
+
+// keepOnly drops the aggregates of every block but blockHash
+func (p phaseAggregates) keepOnly(blockHash string) {
+	for hash := range p {
+		if hash != blockHash {
+			delete(p, hash)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package consensus
+
+import (
+	"strings"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/utils"
+)
+
+func TestAggregatedVoteVerifiesEveryShare(t *testing.T) {
+	keys := newVoteKeyring()
+	validators := testValidators(4)
+	block := testBlock(1)
+
+	votes := make([]*Vote, 0, len(validators))
+	for _, validator := range validators {
+		vote := &Vote{ValidatorAddress: validator.Address, BlockHash: block.Hash, VoteType: "prepare", View: 2}
+		share, err := signVoteShare(keys, validator, vote)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vote.Signature = share
+		votes = append(votes, vote)
+	}
+	aggregated, err := AggregateVotes(votes)
+	if err != nil {
+		t.Fatalf("failed to aggregate: %v", err)
+	}
+	if err := VerifyAggregatedVote(aggregated, validators, keys.PublicKey); err != nil {
+		t.Fatalf("aggregate of honest shares rejected: %v", err)
+	}
+	if saved := individualVoteBytes(aggregated) - aggregated.Size(); saved <= 0 {
+		t.Fatalf("aggregate saves %d bytes over the individual votes", saved)
+	}
+
+	// A share made without the signer's key does not verify
+	impostor, err := utils.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := aggregated
+	forged.Signature = utils.SignDigest(impostor, voteDigest(block.Hash, "prepare", 2)) + aggregated.Signature[shareLength:]
+	if err := VerifyAggregatedVote(forged, validators, keys.PublicKey); err == nil {
+		t.Fatal("aggregate with a forged share accepted")
+	}
+
+	// Nor does one replayed for another phase or view
+	for _, replayed := range []AggregatedVote{
+		{BlockHash: aggregated.BlockHash, VoteType: "commit", View: 2, Signers: aggregated.Signers, Signature: aggregated.Signature},
+		{BlockHash: aggregated.BlockHash, VoteType: "prepare", View: 3, Signers: aggregated.Signers, Signature: aggregated.Signature},
+	} {
+		if err := VerifyAggregatedVote(replayed, validators, keys.PublicKey); err == nil {
+			t.Fatalf("shares replayed for %s view %d accepted", replayed.VoteType, replayed.View)
+		}
+	}
+
+	// Claiming a signer whose share is missing fails
+	padded := aggregated
+	padded.Signers = append(append([]string{}, aggregated.Signers...), "validator_9")
+	if err := VerifyAggregatedVote(padded, append(validators, testValidators(10)[9]), keys.PublicKey); err == nil {
+		t.Fatal("aggregate claiming a signer without a share accepted")
+	}
+	if err := VerifyAggregatedVote(aggregated, validators[:3], keys.PublicKey); err == nil || !strings.Contains(err.Error(), "not a validator") {
+		t.Fatalf("aggregate signed by a non-member: got %v", err)
+	}
+}
+
+func TestAggregationReplacesPhaseVotes(t *testing.T) {
+	// PPBFT's early termination only reaches quorum with a single validator
+	for algorithm, count := range map[string]int{"pbft": 7, "ppbft": 1} {
+		algorithm, count := algorithm, count
+		t.Run(algorithm, func(t *testing.T) {
+			engine := newTestEngine(t, algorithm, func(cfg *config.Config) {
+				cfg.Consensus.UseBLSAggregation = true
+			})
+			validators := testValidators(count)
+			if err := engine.UpdateValidators(validators); err != nil {
+				t.Fatal(err)
+			}
+			block := testBlock(1)
+			approved, err := engine.ProcessBlock(block, validators)
+			if err != nil || !approved {
+				t.Fatalf("block not approved: %v, %v", approved, err)
+			}
+
+			var prepareVotes, commitVotes map[string]*Vote
+			var aggregates phaseAggregates
+			switch e := engine.(type) {
+			case *PBFT:
+				prepareVotes, commitVotes, aggregates = e.prepareVotes[block.Hash], e.commitVotes[block.Hash], e.aggregates
+			case *PracticalPBFT:
+				prepareVotes, commitVotes, aggregates = e.prepareVotes[block.Hash], e.commitVotes[block.Hash], e.aggregates
+			}
+			if len(prepareVotes) != 0 || len(commitVotes) != 0 {
+				t.Fatalf("%d prepare and %d commit votes kept beside their aggregates", len(prepareVotes), len(commitVotes))
+			}
+			for _, voteType := range []string{"prepare", "commit"} {
+				if len(aggregates[block.Hash][voteType].Signers) == 0 {
+					t.Fatalf("no %s aggregate kept for the block", voteType)
+				}
+			}
+
+			metrics := engine.GetMetrics()
+			if metrics["aggregated_vote_sets"] != int64(2) || metrics["aggregation_bytes_saved"].(int64) < 0 {
+				t.Fatalf("aggregation metrics %v sets, %v bytes saved", metrics["aggregated_vote_sets"], metrics["aggregation_bytes_saved"])
+			}
+			if metrics["prepare_votes"] == 0 {
+				t.Fatal("aggregated votes not counted")
+			}
+		})
+	}
+}
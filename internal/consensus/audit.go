@@ -0,0 +1,63 @@
+package consensus
+
+import "sync/atomic"
+
+// VoteAuditor is implemented by engines that collect votes and leave out
+// the votes of validators they judge Byzantine
+type VoteAuditor interface {
+	// VoteAudit returns the votes weighed since the engine started or was
+	// last reset
+	VoteAudit() VoteAudit
+}
+
+// ParticipationRecorder is implemented by engines that can report which
+// validators take part in their rounds
+type ParticipationRecorder interface {
+	// SetParticipationHook registers hook to be called with the address of
+	// every validator whose vote the engine counts
+	SetParticipationHook(hook func(address string))
+}
+
+// VoteAudit counts the votes an engine has weighed. Every validator an
+// engine asks for a vote is considered; those it judged Byzantine and left
+// out are also excluded.
+type VoteAudit struct {
+	Considered int64 `json:"considered"`
+	Excluded   int64 `json:"excluded"`
+}
+
+// voteCounter accumulates a VoteAudit and reports counted votes to a
+// participation hook
+type voteCounter struct {
+	considered atomic.Int64
+	excluded   atomic.Int64
+	hook       atomic.Value // func(address string)
+}
+
+// count records address's vote being weighed
+func (vc *voteCounter) count(address string, excluded bool) {
+	vc.considered.Add(1)
+	if excluded {
+		vc.excluded.Add(1)
+		return
+	}
+	if hook, ok := vc.hook.Load().(func(string)); ok && hook != nil {
+		hook(address)
+	}
+}
+
+// setHook sets the function told of each counted vote
+func (vc *voteCounter) setHook(hook func(address string)) {
+	vc.hook.Store(hook)
+}
+
+// audit returns the counts so far
+func (vc *voteCounter) audit() VoteAudit {
+	return VoteAudit{Considered: vc.considered.Load(), Excluded: vc.excluded.Load()}
+}
+
+// reset clears the counts
+func (vc *voteCounter) reset() {
+	vc.considered.Store(0)
+	vc.excluded.Store(0)
+}
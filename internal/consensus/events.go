@@ -0,0 +1,224 @@
+package consensus
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"lscc-blockchain/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Consensus event types
+const (
+	EventVoteReceived   = "vote_received"
+	EventPhaseCompleted = "phase_completed"
+	EventViewChange     = "view_change"
+	EventCheckpoint     = "checkpoint"
+)
+
+// IsEventType reports whether eventType is one of the recorded event types
+func IsEventType(eventType string) bool {
+	switch eventType {
+	case EventVoteReceived, EventPhaseCompleted, EventViewChange, EventCheckpoint:
+		return true
+	}
+	return false
+}
+
+// ConsensusEvent is one entry in the consensus event log
+type ConsensusEvent struct {
+	Seq       uint64                 `json:"seq"`
+	Type      string                 `json:"type"`
+	Algorithm string                 `json:"algorithm"`
+	BlockHash string                 `json:"block_hash,omitempty"`
+	Round     int64                  `json:"round"`
+	View      int64                  `json:"view"`
+	Phase     string                 `json:"phase,omitempty"`
+	Validator string                 `json:"validator,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// EventFilter selects events from the log. Empty fields match everything.
+type EventFilter struct {
+	BlockHash string
+	Type      string
+	Limit     int
+}
+
+const eventLogMetaKey = "consensus:events:meta"
+
+func eventLogSlotKey(slot int) string {
+	return fmt.Sprintf("consensus:events:slot:%d", slot)
+}
+
+// eventLogMeta records where the ring stands so it can be reopened
+type eventLogMeta struct {
+	Next     uint64 `json:"next"`
+	Capacity int    `json:"capacity"`
+}
+
+// EventLog is a fixed-size ring of consensus events kept in a StateStore.
+// Event seq lives in slot seq%capacity, so once the ring is full each new
+// event overwrites the oldest and disk use stays bounded. The ring is
+// mirrored in memory for queries.
+type EventLog struct {
+	mu       sync.RWMutex
+	store    StateStore
+	logger   *utils.Logger
+	capacity int
+	events   []*ConsensusEvent // indexed by slot
+	next     uint64            // seq of the next event
+}
+
+// NewEventLog opens the event log in store, reloading the events already
+// there. When capacity differs from the stored ring the newest events that
+// fit are carried over.
+func NewEventLog(store StateStore, capacity int, logger *utils.Logger) (*EventLog, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("event log capacity must be positive: %d", capacity)
+	}
+
+	log := &EventLog{
+		store:    store,
+		logger:   logger,
+		capacity: capacity,
+		events:   make([]*ConsensusEvent, capacity),
+	}
+
+	var meta eventLogMeta
+	if err := store.GetState(eventLogMetaKey, &meta); err != nil || meta.Capacity <= 0 {
+		// Nothing recorded yet
+		return log, nil
+	}
+
+	log.next = meta.Next
+	first := uint64(0)
+	if meta.Next > uint64(capacity) {
+		first = meta.Next - uint64(capacity)
+	}
+	if meta.Next > uint64(meta.Capacity) && first < meta.Next-uint64(meta.Capacity) {
+		first = meta.Next - uint64(meta.Capacity)
+	}
+
+	for seq := first; seq < meta.Next; seq++ {
+		var event ConsensusEvent
+		if err := store.GetState(eventLogSlotKey(int(seq%uint64(meta.Capacity))), &event); err != nil || event.Seq != seq {
+			continue
+		}
+		log.events[seq%uint64(capacity)] = &event
+	}
+
+	if meta.Capacity == capacity {
+		return log, nil
+	}
+
+	// Rewrite the ring under the new layout and drop slots past its end
+	for _, event := range log.events {
+		if event == nil {
+			continue
+		}
+		if err := store.SaveState(eventLogSlotKey(int(event.Seq%uint64(capacity))), event); err != nil {
+			return nil, fmt.Errorf("failed to resize event log: %w", err)
+		}
+	}
+	for slot := capacity; slot < meta.Capacity; slot++ {
+		if err := store.DeleteState(eventLogSlotKey(slot)); err != nil {
+			return nil, fmt.Errorf("failed to resize event log: %w", err)
+		}
+	}
+	if err := store.SaveState(eventLogMetaKey, eventLogMeta{Next: log.next, Capacity: capacity}); err != nil {
+		return nil, fmt.Errorf("failed to resize event log: %w", err)
+	}
+
+	return log, nil
+}
+
+// Record appends an event, stamping its seq and, if unset, its timestamp.
+// Recording to a nil log does nothing, so engines without a log need no
+// checks. Storage failures are logged; the event stays queryable in memory.
+func (l *EventLog) Record(event ConsensusEvent) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event.Seq = l.next
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	slot := int(event.Seq % uint64(l.capacity))
+	l.events[slot] = &event
+	l.next++
+
+	if err := l.store.SaveState(eventLogSlotKey(slot), &event); err != nil {
+		l.logStoreError(err, event)
+		return
+	}
+	if err := l.store.SaveState(eventLogMetaKey, eventLogMeta{Next: l.next, Capacity: l.capacity}); err != nil {
+		l.logStoreError(err, event)
+	}
+}
+
+func (l *EventLog) logStoreError(err error, event ConsensusEvent) {
+	if l.logger == nil {
+		return
+	}
+	l.logger.LogError("consensus", "record_event", err, logrus.Fields{
+		"seq":       event.Seq,
+		"type":      event.Type,
+		"timestamp": time.Now().UTC(),
+	})
+}
+
+// Query returns the events matching filter, newest first, up to filter.Limit
+// when it is positive
+func (l *EventLog) Query(filter EventFilter) []ConsensusEvent {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	matches := make([]ConsensusEvent, 0)
+	count := uint64(l.capacity)
+	if l.next < count {
+		count = l.next
+	}
+	for i := uint64(1); i <= count; i++ {
+		event := l.events[(l.next-i)%uint64(l.capacity)]
+		if event == nil {
+			continue
+		}
+		if filter.BlockHash != "" && event.BlockHash != filter.BlockHash {
+			continue
+		}
+		if filter.Type != "" && event.Type != filter.Type {
+			continue
+		}
+		matches = append(matches, *event)
+		if filter.Limit > 0 && len(matches) >= filter.Limit {
+			break
+		}
+	}
+	return matches
+}
+
+// Capacity returns the number of events the log keeps
+func (l *EventLog) Capacity() int {
+	return l.capacity
+}
+
+// voteEvent describes a vote as a vote_received event
+func voteEvent(algorithm string, vote *Vote) ConsensusEvent {
+	return ConsensusEvent{
+		Type:      EventVoteReceived,
+		Algorithm: algorithm,
+		BlockHash: vote.BlockHash,
+		Round:     vote.Round,
+		View:      vote.View,
+		Phase:     vote.VoteType,
+		Validator: vote.ValidatorAddress,
+	}
+}
@@ -0,0 +1,149 @@
+package consensus
+
+import (
+	"fmt"
+	"testing"
+
+	"lscc-blockchain/internal/storage"
+)
+
+// recordEvents records count events of each type for each of blocks
+func recordEvents(log *EventLog, blocks []string, count int) {
+	for i := 0; i < count; i++ {
+		for _, block := range blocks {
+			for _, eventType := range []string{EventVoteReceived, EventPhaseCompleted} {
+				log.Record(ConsensusEvent{Type: eventType, Algorithm: "ppbft", BlockHash: block, Round: int64(i)})
+			}
+		}
+	}
+}
+
+func TestEventLogQueries(t *testing.T) {
+	log, err := NewEventLog(storage.NewMemoryDB(), 100, discardLogger())
+	if err != nil {
+		t.Fatalf("failed to open event log: %v", err)
+	}
+	recordEvents(log, []string{"block_a", "block_b"}, 5)
+	log.Record(ConsensusEvent{Type: EventViewChange, Algorithm: "ppbft", BlockHash: "block_b", View: 1})
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   int
+	}{
+		{"all", EventFilter{}, 21},
+		{"by block", EventFilter{BlockHash: "block_a"}, 10},
+		{"by type", EventFilter{Type: EventVoteReceived}, 10},
+		{"by block and type", EventFilter{BlockHash: "block_b", Type: EventViewChange}, 1},
+		{"limited", EventFilter{BlockHash: "block_a", Limit: 3}, 3},
+		{"unknown block", EventFilter{BlockHash: "block_c"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := log.Query(tt.filter)
+			if len(events) != tt.want {
+				t.Fatalf("%d events, want %d", len(events), tt.want)
+			}
+			for i, event := range events {
+				if tt.filter.BlockHash != "" && event.BlockHash != tt.filter.BlockHash {
+					t.Fatalf("event for %s matched block %s", event.BlockHash, tt.filter.BlockHash)
+				}
+				if tt.filter.Type != "" && event.Type != tt.filter.Type {
+					t.Fatalf("%s event matched type %s", event.Type, tt.filter.Type)
+				}
+				if i > 0 && event.Seq >= events[i-1].Seq {
+					t.Fatal("events not newest first")
+				}
+			}
+		})
+	}
+
+	if got := log.Query(EventFilter{Limit: 1}); got[0].Type != EventViewChange || got[0].Timestamp.IsZero() {
+		t.Fatalf("newest event = %+v, want the stamped view change", got[0])
+	}
+}
+
+func TestEventLogIsBounded(t *testing.T) {
+	store := storage.NewMemoryDB()
+	log, err := NewEventLog(store, 8, discardLogger())
+	if err != nil {
+		t.Fatalf("failed to open event log: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		log.Record(ConsensusEvent{Type: EventCheckpoint, BlockHash: fmt.Sprintf("block_%d", i)})
+	}
+
+	events := log.Query(EventFilter{})
+	if len(events) != 8 {
+		t.Fatalf("%d events kept, want the capacity of 8", len(events))
+	}
+	if events[0].Seq != 19 || events[7].Seq != 12 {
+		t.Fatalf("kept seqs %d..%d, want the newest 19..12", events[0].Seq, events[7].Seq)
+	}
+	var slot ConsensusEvent
+	if err := store.GetState(eventLogSlotKey(8), &slot); err == nil {
+		t.Fatal("event stored past the end of the ring")
+	}
+
+	// Reopening restores the ring from storage
+	reopened, err := NewEventLog(store, 8, discardLogger())
+	if err != nil {
+		t.Fatalf("failed to reopen event log: %v", err)
+	}
+	if got := reopened.Query(EventFilter{}); len(got) != 8 || got[0].Seq != 19 {
+		t.Fatalf("reopened log holds %d events, newest %v", len(got), got)
+	}
+	reopened.Record(ConsensusEvent{Type: EventCheckpoint})
+	if got := reopened.Query(EventFilter{Limit: 1}); got[0].Seq != 20 {
+		t.Fatalf("next seq after reopening = %d, want 20", got[0].Seq)
+	}
+
+	// Shrinking keeps the newest events that fit
+	shrunk, err := NewEventLog(store, 4, discardLogger())
+	if err != nil {
+		t.Fatalf("failed to shrink event log: %v", err)
+	}
+	if got := shrunk.Query(EventFilter{}); len(got) != 4 || got[0].Seq != 20 || got[3].Seq != 17 {
+		t.Fatalf("shrunk log holds %v", got)
+	}
+	if err := store.GetState(eventLogSlotKey(5), &slot); err == nil {
+		t.Fatal("slot past the shrunk ring left in storage")
+	}
+}
+
+func TestEventLogRejectsZeroCapacity(t *testing.T) {
+	if _, err := NewEventLog(storage.NewMemoryDB(), 0, discardLogger()); err == nil {
+		t.Fatal("event log with no capacity opened")
+	}
+	var log *EventLog
+	log.Record(ConsensusEvent{Type: EventCheckpoint}) // a nil log ignores events
+}
+
+func TestEnginesRecordRoundEvents(t *testing.T) {
+	for _, algorithm := range []string{"lscc", "ppbft"} {
+		t.Run(algorithm, func(t *testing.T) {
+			log, err := NewEventLog(storage.NewMemoryDB(), 1000, discardLogger())
+			if err != nil {
+				t.Fatalf("failed to open event log: %v", err)
+			}
+			engine := newTestEngine(t, algorithm, nil)
+			recorder, ok := engine.(EventRecorder)
+			if !ok {
+				t.Fatalf("%s does not record events", algorithm)
+			}
+			recorder.SetEventLog(log)
+
+			block := testBlock(1)
+			engine.ProcessBlock(block, testValidators(4))
+			events := log.Query(EventFilter{BlockHash: block.Hash})
+			if len(events) == 0 {
+				t.Fatal("no events recorded for the round")
+			}
+			for _, event := range events {
+				if event.Algorithm != algorithm || !IsEventType(event.Type) {
+					t.Fatalf("recorded %+v", event)
+				}
+			}
+		})
+	}
+}
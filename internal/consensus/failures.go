@@ -0,0 +1,102 @@
+package consensus
+
+import (
+	"errors"
+	"sync"
+)
+
+// Consensus failure reasons, as reported by ClassifyFailure
+const (
+	FailureTimeout              = "timeout"
+	FailureInsufficientVotes    = "insufficient_votes"
+	FailureOutsideWindow        = "outside_window"
+	FailureInvalidBlock         = "invalid_block"
+	FailureLayerRejected        = "layer_rejected"
+	FailureCrossChannelRejected = "cross_channel_rejected"
+	FailureSyncFailed           = "sync_failed"
+	FailureCommitFailed         = "commit_failed"
+	FailureWrongValidator       = "wrong_validator"
+	FailureInsufficientStake    = "insufficient_stake"
+	FailureInvalidSignature     = "invalid_signature"
+	FailureMiningFailed         = "mining_failed"
+	FailureNoValidators         = "no_validators"
+	FailureNotCommitted         = "not_committed"
+	FailureOther                = "other"
+)
+
+// FailureError tags a consensus error with the reason the round failed. The
+// message is that of the wrapped error.
+type FailureError struct {
+	Reason string
+	Err    error
+}
+
+func (e *FailureError) Error() string { return e.Err.Error() }
+
+func (e *FailureError) Unwrap() error { return e.Err }
+
+// withReason tags err with a failure reason
+func withReason(reason string, err error) error {
+	return &FailureError{Reason: reason, Err: err}
+}
+
+// ClassifyFailure returns the reason a ProcessBlock call failed: the reason
+// of the innermost FailureError, FailureTimeout for phase deadlines, or
+// FailureOther for untagged errors. A nil error means the block was simply
+// not committed.
+func ClassifyFailure(err error) string {
+	if err == nil {
+		return FailureNotCommitted
+	}
+	if errors.Is(err, ErrPhaseTimeout) {
+		return FailureTimeout
+	}
+
+	reason := FailureOther
+	for err != nil {
+		var failure *FailureError
+		if !errors.As(err, &failure) {
+			break
+		}
+		reason = failure.Reason
+		err = failure.Err
+	}
+	return reason
+}
+
+// failureCounter counts failed rounds by reason
+type failureCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// record counts the outcome of a ProcessBlock call if it failed
+func (fc *failureCounter) record(committed bool, err error) {
+	if committed && err == nil {
+		return
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.counts == nil {
+		fc.counts = make(map[string]int)
+	}
+	fc.counts[ClassifyFailure(err)]++
+}
+
+// snapshot returns a copy of the counts
+func (fc *failureCounter) snapshot() map[string]int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	counts := make(map[string]int, len(fc.counts))
+	for reason, count := range fc.counts {
+		counts[reason] = count
+	}
+	return counts
+}
+
+// reset clears the counts
+func (fc *failureCounter) reset() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.counts = nil
+}
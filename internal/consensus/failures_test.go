@@ -0,0 +1,78 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"not committed", nil, FailureNotCommitted},
+		{"untagged", errors.New("boom"), FailureOther},
+		{"tagged", withReason(FailureInsufficientVotes, errors.New("2 of 3")), FailureInsufficientVotes},
+		{"wrapped", fmt.Errorf("prepare: %w", withReason(FailureOutsideWindow, errors.New("late"))), FailureOutsideWindow},
+		{"innermost wins", withReason(FailureInvalidBlock, withReason(FailureLayerRejected, errors.New("layer 2"))), FailureLayerRejected},
+		{"timeout", fmt.Errorf("commit: %w", ErrPhaseTimeout), FailureTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyFailure(tt.err); got != tt.want {
+				t.Fatalf("ClassifyFailure(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// failureReasons returns the failure counts an engine reports in its metrics
+func failureReasons(t *testing.T, engine Consensus) map[string]int {
+	t.Helper()
+	reasons, ok := engine.GetMetrics()["failure_reasons"].(map[string]int)
+	if !ok {
+		t.Fatalf("%s metrics have no failure_reasons: %v", engine.GetAlgorithmName(), engine.GetMetrics()["failure_reasons"])
+	}
+	return reasons
+}
+
+func TestEnginesCountFailureReasons(t *testing.T) {
+	for _, algorithm := range RegisteredAlgorithms() {
+		t.Run(algorithm, func(t *testing.T) {
+			engine := newTestEngine(t, algorithm, nil)
+			for i := int64(1); i <= 2; i++ {
+				committed, err := engine.ProcessBlock(testBlock(i), nil)
+				if committed || !errors.Is(err, ErrNoValidators) {
+					t.Fatalf("round without validators: committed=%v err=%v", committed, err)
+				}
+			}
+			if got := failureReasons(t, engine)[FailureNoValidators]; got != 2 {
+				t.Fatalf("%s failures = %d, want 2 (all: %v)", FailureNoValidators, got, failureReasons(t, engine))
+			}
+
+			if err := engine.Reset(); err != nil {
+				t.Fatalf("reset failed: %v", err)
+			}
+			if got := failureReasons(t, engine); len(got) != 0 {
+				t.Fatalf("failure reasons after reset = %v", got)
+			}
+		})
+	}
+}
+
+func TestProofOfStakeCountsWrongValidator(t *testing.T) {
+	engine := newTestEngine(t, "pos", nil)
+	block := testBlock(1)
+	block.Validator = "impostor"
+	block.Hash = block.ComputeHash()
+
+	_, err := engine.ProcessBlock(block, testValidators(4))
+	if got := ClassifyFailure(err); got != FailureWrongValidator {
+		t.Fatalf("block from an impostor failed with %q (%v), want %q", got, err, FailureWrongValidator)
+	}
+	if got := failureReasons(t, engine)[FailureWrongValidator]; got != 1 {
+		t.Fatalf("%s failures = %d, want 1", FailureWrongValidator, got)
+	}
+}
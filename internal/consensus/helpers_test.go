@@ -0,0 +1,79 @@
+package consensus
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+)
+
+// testConfig returns the repository configuration, adjusted by configure
+func testConfig(t *testing.T, configure func(cfg *config.Config)) *config.Config {
+	t.Helper()
+	cfg, err := config.LoadConfigFromPath("../../config/config.yaml")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	return cfg
+}
+
+func discardLogger() *utils.Logger {
+	logger := utils.NewLogger()
+	logger.Logger.SetOutput(io.Discard)
+	return logger
+}
+
+// newTestLSCC returns an LSCC engine on the repository configuration,
+// stopped when the test ends
+func newTestLSCC(t *testing.T, configure func(cfg *config.Config)) *LSCC {
+	t.Helper()
+	lscc, err := NewLSCC(testConfig(t, configure), discardLogger())
+	if err != nil {
+		t.Fatalf("failed to create LSCC: %v", err)
+	}
+	t.Cleanup(lscc.Stop)
+	return lscc
+}
+
+// newTestEngine returns the engine registered as algorithm on the repository
+// configuration, stopped when the test ends if it runs in the background
+func newTestEngine(t *testing.T, algorithm string, configure func(cfg *config.Config)) Consensus {
+	t.Helper()
+	engine, err := New(algorithm, testConfig(t, configure), discardLogger())
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", algorithm, err)
+	}
+	if stopper, ok := engine.(interface{ Stop() }); ok {
+		t.Cleanup(stopper.Stop)
+	}
+	return engine
+}
+
+// testValidators returns count active validators with equal stake
+func testValidators(count int) []*types.Validator {
+	validators := make([]*types.Validator, 0, count)
+	for i := 0; i < count; i++ {
+		validators = append(validators, &types.Validator{
+			Address:    fmt.Sprintf("validator_%d", i),
+			Stake:      1000,
+			Power:      1,
+			Status:     "active",
+			ShardID:    i % 4,
+			Reputation: 1,
+		})
+	}
+	return validators
+}
+
+// testBlock returns a hashed block at index with no transactions
+func testBlock(index int64) *types.Block {
+	block := &types.Block{Index: index, Validator: "validator_0", Transactions: []*types.Transaction{}}
+	block.Hash = block.ComputeHash()
+	return block
+}
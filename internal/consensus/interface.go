@@ -1,9 +1,23 @@
 package consensus
 
 import (
+	"errors"
 	"lscc-blockchain/pkg/types"
 )
 
+// ErrNoValidators is returned by SelectValidator when given no validators to
+// choose from, and by ProcessBlock, before any phase runs, when asked to
+// agree a block with none
+var ErrNoValidators = errors.New("no validators available")
+
+// requireValidators fails a round that has no validators to run it
+func requireValidators(validators []*types.Validator) error {
+	if len(validators) == 0 {
+		return withReason(FailureNoValidators, ErrNoValidators)
+	}
+	return nil
+}
+
 // Consensus defines the interface for consensus algorithms
 type Consensus interface {
 	// ProcessBlock processes a block and returns whether it's approved
@@ -31,6 +45,34 @@ type Consensus interface {
 	Reset() error
 }
 
+// StateStore persists named values for consensus engines. storage.Database
+// satisfies it.
+type StateStore interface {
+	SaveState(key string, value interface{}) error
+	GetState(key string, value interface{}) error
+	DeleteState(key string) error
+}
+
+// Checkpointer is implemented by engines that can snapshot their progress to
+// a StateStore and resume from it after a restart
+type Checkpointer interface {
+	// SetStateStore sets where checkpoints are written and read
+	SetStateStore(store StateStore)
+
+	// Checkpoint writes the current committed state
+	Checkpoint() error
+
+	// RestoreFromCheckpoint loads the last checkpoint. It returns
+	// ErrNoCheckpoint when none has been written.
+	RestoreFromCheckpoint() error
+}
+
+// EventRecorder is implemented by engines that record typed events to an
+// EventLog
+type EventRecorder interface {
+	SetEventLog(log *EventLog)
+}
+
 // ConsensusConfig holds configuration for consensus algorithms
 type ConsensusConfig struct {
 	Algorithm       string
@@ -54,6 +96,7 @@ type Vote struct {
 	Signature        string                 `json:"signature"`
 	Timestamp        int64                  `json:"timestamp"`
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+	TraceID          string                 `json:"trace_id,omitempty"` // trace ID of the block voted on
 }
 
 // ConsensusMessage represents a message in consensus protocol
@@ -0,0 +1,76 @@
+package consensus
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"lscc-blockchain/pkg/types"
+	"math"
+	"strconv"
+)
+
+// SelectPrimaryVRF picks the primary for a view from the validator set,
+// weighted by stake. The choice is a pure function of the previous block
+// hash, the view and the validator set, so every node computes the same
+// primary, yet it cannot be known before the previous block exists.
+//
+// Each validator draws a uniform value u from a hash of the seed and its
+// address and scores -ln(u)/stake; the lowest score wins. This is an
+// exponential race, so a validator wins with probability stake/total
+// regardless of the order of validators. Validators without positive stake
+// are only chosen when no validator has any.
+func SelectPrimaryVRF(validators []*types.Validator, prevHash string, view int64) *types.Validator {
+	if len(validators) == 0 {
+		return nil
+	}
+
+	staked := false
+	for _, validator := range validators {
+		if validator.Stake > 0 {
+			staked = true
+			break
+		}
+	}
+
+	seed := vrfSeed(prevHash, view)
+	var primary *types.Validator
+	best := math.Inf(1)
+	for _, validator := range validators {
+		weight := float64(validator.Stake)
+		if !staked {
+			weight = 1
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		score := -math.Log(vrfUniform(seed, validator.Address)) / weight
+		if primary == nil || score < best || (score == best && validator.Address < primary.Address) {
+			primary, best = validator, score
+		}
+	}
+	return primary
+}
+
+// VerifyPrimaryVRF reports whether address is the primary SelectPrimaryVRF
+// chooses for the given previous block hash and view
+func VerifyPrimaryVRF(validators []*types.Validator, prevHash string, view int64, address string) bool {
+	primary := SelectPrimaryVRF(validators, prevHash, view)
+	return primary != nil && primary.Address == address
+}
+
+// vrfSeed binds a selection to the previous block and view
+func vrfSeed(prevHash string, view int64) [32]byte {
+	return sha256.Sum256([]byte(prevHash + "/" + strconv.FormatInt(view, 10)))
+}
+
+// vrfUniform derives a value in (0, 1) for address from seed
+func vrfUniform(seed [32]byte, address string) float64 {
+	h := sha256.New()
+	h.Write(seed[:])
+	h.Write([]byte(address))
+	sum := h.Sum(nil)
+
+	// 53 random bits, offset by half a step so the result is never 0 or 1
+	bits := binary.BigEndian.Uint64(sum[:8]) >> 11
+	return (float64(bits) + 0.5) / (1 << 53)
+}
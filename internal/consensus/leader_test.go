@@ -0,0 +1,95 @@
+package consensus
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"lscc-blockchain/pkg/types"
+)
+
+// stakedValidators returns validators with the given stakes
+func stakedValidators(stakes ...int64) []*types.Validator {
+	validators := testValidators(len(stakes))
+	for i, stake := range stakes {
+		validators[i].Stake = stake
+	}
+	return validators
+}
+
+func TestSelectPrimaryVRFFollowsStake(t *testing.T) {
+	validators := stakedValidators(1000, 2000, 3000, 4000)
+	const rounds = 20000
+	wins := make(map[string]int)
+	for view := int64(0); view < rounds; view++ {
+		primary := SelectPrimaryVRF(validators, fmt.Sprintf("block_%d", view/10), view)
+		wins[primary.Address]++
+	}
+
+	// Pearson's chi-squared against stake/total; 16.27 is the 0.1% critical
+	// value for 3 degrees of freedom
+	chiSquared := 0.0
+	for i, validator := range validators {
+		expected := rounds * float64(validator.Stake) / 10000
+		observed := float64(wins[validator.Address])
+		chiSquared += (observed - expected) * (observed - expected) / expected
+		if share := observed / rounds; math.Abs(share-float64(i+1)/10) > 0.02 {
+			t.Errorf("%s won %.3f of rounds, want about %.1f", validator.Address, share, float64(i+1)/10)
+		}
+	}
+	if chiSquared > 16.27 {
+		t.Fatalf("chi-squared = %.2f, selection does not follow stake (wins %v)", chiSquared, wins)
+	}
+}
+
+func TestSelectPrimaryVRFIsVerifiable(t *testing.T) {
+	validators := stakedValidators(1000, 2000, 3000, 4000)
+	reversed := []*types.Validator{validators[3], validators[2], validators[1], validators[0]}
+
+	changed := false
+	first := SelectPrimaryVRF(validators, "parent", 0)
+	for view := int64(0); view < 50; view++ {
+		primary := SelectPrimaryVRF(validators, "parent", view)
+		if again := SelectPrimaryVRF(validators, "parent", view); again.Address != primary.Address {
+			t.Fatalf("view %d: selected %s then %s", view, primary.Address, again.Address)
+		}
+		if other := SelectPrimaryVRF(reversed, "parent", view); other.Address != primary.Address {
+			t.Fatalf("view %d: validator order changed the primary from %s to %s", view, primary.Address, other.Address)
+		}
+		if !VerifyPrimaryVRF(validators, "parent", view, primary.Address) {
+			t.Fatalf("view %d: primary %s not verified", view, primary.Address)
+		}
+		for _, validator := range validators {
+			if validator.Address != primary.Address && VerifyPrimaryVRF(validators, "parent", view, validator.Address) {
+				t.Fatalf("view %d: %s verified as primary instead of %s", view, validator.Address, primary.Address)
+			}
+		}
+		changed = changed || primary.Address != first.Address
+	}
+	if !changed {
+		t.Fatal("the same primary was selected for every view")
+	}
+}
+
+func TestSelectPrimaryVRFUnstaked(t *testing.T) {
+	if primary := SelectPrimaryVRF(nil, "parent", 0); primary != nil {
+		t.Fatalf("primary from no validators: %v", primary)
+	}
+
+	validators := stakedValidators(0, 1000, 0)
+	for view := int64(0); view < 100; view++ {
+		if primary := SelectPrimaryVRF(validators, "parent", view); primary.Address != "validator_1" {
+			t.Fatalf("view %d: unstaked %s selected", view, primary.Address)
+		}
+	}
+
+	// Without any stake every validator can be chosen
+	unstaked := stakedValidators(0, 0, 0)
+	seen := make(map[string]bool)
+	for view := int64(0); view < 100; view++ {
+		seen[SelectPrimaryVRF(unstaked, "parent", view).Address] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("only %v selected from an unstaked set", seen)
+	}
+}
@@ -0,0 +1,78 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/pkg/types"
+)
+
+// blockWithTransactions returns a hashed block carrying count transfers,
+// each with data of dataSize bytes
+func blockWithTransactions(count, dataSize int) *types.Block {
+	transactions := make([]*types.Transaction, 0, count)
+	for i := 0; i < count; i++ {
+		tx := &types.Transaction{
+			From:   "sender",
+			To:     "recipient",
+			Amount: 1,
+			Fee:    1,
+			Nonce:  int64(i + 1),
+			Data:   []byte(strings.Repeat("x", dataSize)),
+			Type:   "regular",
+		}
+		tx.ID = tx.Hash()
+		transactions = append(transactions, tx)
+	}
+	block := &types.Block{Index: 1, PreviousHash: "parent", MerkleRoot: "root", Validator: "validator_0", Transactions: transactions}
+	block.Hash = block.ComputeHash()
+	return block
+}
+
+func TestValidateBlockEnforcesLimits(t *testing.T) {
+	limits := func(cfg *config.Config) {
+		cfg.Consensus.MaxTxPerBlock = 3
+		cfg.Consensus.MaxBlockSize = 4096
+	}
+	for _, algorithm := range RegisteredAlgorithms() {
+		t.Run(algorithm, func(t *testing.T) {
+			engine := newTestEngine(t, algorithm, limits)
+			validators := testValidators(4)
+
+			err := engine.ValidateBlock(blockWithTransactions(4, 0), validators)
+			if !errors.Is(err, types.ErrTooManyTransactions) {
+				t.Fatalf("4 transactions over a limit of 3: got %v", err)
+			}
+			err = engine.ValidateBlock(blockWithTransactions(1, 8192), validators)
+			if !errors.Is(err, types.ErrBlockTooLarge) {
+				t.Fatalf("block over 4096 bytes: got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	tests := []struct {
+		count, dataSize, maxTxs, maxSize int
+		want                             error
+	}{
+		{count: 3, maxTxs: 3, maxSize: 4096},
+		{count: 4, maxTxs: 3, maxSize: 4096, want: types.ErrTooManyTransactions},
+		{count: 4, maxTxs: 0, maxSize: 0},
+		{count: 1, dataSize: 8192, maxTxs: 3, maxSize: 4096, want: types.ErrBlockTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d txs of %d bytes under %d/%d", tt.count, tt.dataSize, tt.maxTxs, tt.maxSize), func(t *testing.T) {
+			err := blockWithTransactions(tt.count, tt.dataSize).CheckLimits(tt.maxTxs, tt.maxSize)
+			if tt.want == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -1,27 +1,51 @@
 package consensus
 
 import (
+        "context"
+        "crypto/sha256"
+        "encoding/binary"
+        "errors"
         "fmt"
         "lscc-blockchain/config"
         "lscc-blockchain/internal/utils"
         "lscc-blockchain/pkg/types"
         "math"
+        "strconv"
         "sync"
+        "sync/atomic"
         "time"
 
         "github.com/sirupsen/logrus"
 )
 
+// ErrPhaseTimeout is returned when an LSCC phase overruns its deadline
+var ErrPhaseTimeout = errors.New("phase deadline exceeded")
+
+// lsccPhaseCount is the number of phases in an LSCC round
+const lsccPhaseCount = 4
+
+// What each requirement a block meets adds to its commitment score in the
+// final commitment phase, and the score it needs to commit
+const (
+        lsccLayerWeight     = 0.4 // more than half the layers approved
+        lsccChannelWeight   = 0.3 // a majority of cross-channels approved
+        lsccSyncWeight      = 0.2 // a majority of the block's shard layers synced
+        lsccHealthWeight    = 0.1 // enough channels and layers active
+        lsccCommitThreshold = 0.7
+)
+
 // LSCC implements the Layered Sharding with Cross-Channel Consensus algorithm
 type LSCC struct {
         config              *config.Config
         logger              *utils.Logger
+        clock               utils.Clock
         nodeID              string
         state               *types.ConsensusState
         mu                  sync.RWMutex
         currentView         int64
         currentRound        int64
         layerDepth          int
+        shardsPerLayer      int // shards in each layer; shard IDs run layer*shardsPerLayer+index
         channelCount        int
         shardLayers         map[int][]*ShardLayer // layer -> shards
         crossChannelVotes   map[string]map[string]*CrossChannelVote // channel -> validator -> vote
@@ -29,15 +53,37 @@ type LSCC struct {
         channelStates       map[string]*ChannelState // channel -> state
         isLayerPrimary      map[int]bool // layer -> is primary
         byzantineNodes      int
+        byzantineCheck      string // what UpdateValidators does with a set too small for byzantineNodes
         totalNodes          int
         startTime           time.Time
         metrics             map[string]interface{}
-        blockQueue          chan *types.Block
+        blockQueue          chan *queuedBlock // blocks waiting for a round, drained by blockQueueWorker
+        pipeline            chan struct{} // semaphore bounding queued blocks in ProcessBlock at once
+        queueRejected       int64 // EnqueueBlock calls refused because the queue was full (atomic)
         stopChan            chan struct{}
         phase               string // "prepare", "layer_consensus", "cross_channel", "commit"
         performanceMetrics  map[string]time.Duration
         throughputMetrics   map[string]float64
         latencyMetrics      map[string]time.Duration
+        throughputEWMA      *utils.EWMA // smoothed transactions per second
+        latencyEWMA         *utils.EWMA // smoothed round latency in milliseconds
+        latencyWindow       *utils.PercentileWindow // recent round latencies in milliseconds
+        throughputSMA       *utils.SMA // mean transactions per second over the last rounds
+        latencySMA          *utils.SMA // mean round latency in milliseconds over the last rounds
+        phaseTimeout        time.Duration // deadline applied to each of the four phases
+        roundTimeouts       int64 // rounds aborted because a phase overran its deadline
+        stalledRounds       int64 // rounds detected as stalled by the consensus worker
+        roundStartedAt      int64 // unix nanos at which the round holding mu began, 0 when idle (atomic)
+        resetPending        int32 // set when a stalled round requires a reset (atomic)
+        failures            failureCounter // failed rounds by reason
+        votes               voteCounter // layer and channel votes weighed
+        retention           voteRetention // caps layer and cross-channel votes kept across rounds
+        store               StateStore // where checkpoints are kept, nil when checkpointing is off
+        checkpointInterval  int64 // committed rounds between checkpoints
+        lastCheckpointRound int64
+        checkpoints         int64 // checkpoints written
+        lastCommit          *lsccCommit // most recently committed block and its layer results
+        events              *EventLog // typed event history, nil when not recorded
 }
 
 // ShardLayer represents a shard in a specific layer
@@ -64,6 +110,7 @@ type CrossChannelVote struct {
         Signature        string                 `json:"signature"`
         Timestamp        int64                  `json:"timestamp"`
         Metadata         map[string]interface{} `json:"metadata,omitempty"`
+        TraceID          string                 `json:"trace_id,omitempty"` // trace ID of the block voted on
 }
 
 // LayerConsensus represents consensus state for a specific layer
@@ -89,9 +136,21 @@ type ChannelState struct {
         Metadata        map[string]interface{} `json:"metadata"`
 }
 
+func init() {
+        Register("lscc", func(cfg *config.Config, logger *utils.Logger) (Consensus, error) {
+                return NewLSCC(cfg, logger)
+        })
+}
+
 // NewLSCC creates a new LSCC consensus instance
 func NewLSCC(cfg *config.Config, logger *utils.Logger) (*LSCC, error) {
-        startTime := time.Now()
+        return NewLSCCWithClock(cfg, logger, utils.SystemClock)
+}
+
+// NewLSCCWithClock creates an LSCC instance whose timestamps, phase
+// deadlines and background workers all run on clock
+func NewLSCCWithClock(cfg *config.Config, logger *utils.Logger, clock utils.Clock) (*LSCC, error) {
+        startTime := clock.Now()
         
         logger.LogConsensus("lscc", "initialize", logrus.Fields{
                 "node_id":       cfg.Node.ID,
@@ -104,10 +163,12 @@ func NewLSCC(cfg *config.Config, logger *utils.Logger) (*LSCC, error) {
         lscc := &LSCC{
                 config:              cfg,
                 logger:              logger,
+                clock:               clock,
                 nodeID:              cfg.Node.ID,
                 currentView:         0,
                 currentRound:        0,
                 layerDepth:          cfg.Consensus.LayerDepth,
+                shardsPerLayer:      lsccShardsPerLayer(cfg),
                 channelCount:        cfg.Consensus.ChannelCount,
                 shardLayers:         make(map[int][]*ShardLayer),
                 crossChannelVotes:   make(map[string]map[string]*CrossChannelVote),
@@ -115,14 +176,24 @@ func NewLSCC(cfg *config.Config, logger *utils.Logger) (*LSCC, error) {
                 channelStates:       make(map[string]*ChannelState),
                 isLayerPrimary:      make(map[int]bool),
                 byzantineNodes:      cfg.Consensus.Byzantine,
+                byzantineCheck:      cfg.Consensus.ByzantineCheck,
                 startTime:           startTime,
                 metrics:             make(map[string]interface{}),
-                blockQueue:          make(chan *types.Block, 100),
+                blockQueue:          make(chan *queuedBlock, utils.MaxInt(cfg.Consensus.LSCCQueueSize, 1)),
+                pipeline:            make(chan struct{}, utils.MaxInt(cfg.Consensus.LSCCPipelineDepth, 1)),
                 stopChan:            make(chan struct{}),
                 phase:               "prepare",
                 performanceMetrics:  make(map[string]time.Duration),
                 throughputMetrics:   make(map[string]float64),
                 latencyMetrics:      make(map[string]time.Duration),
+                throughputEWMA:      utils.NewEWMA(cfg.Consensus.MetricsAlpha),
+                latencyEWMA:         utils.NewEWMA(cfg.Consensus.MetricsAlpha),
+                latencyWindow:       utils.NewPercentileWindow(utils.MaxInt(cfg.Consensus.LatencyWindow, 1)),
+                throughputSMA:       utils.NewSMA(cfg.Consensus.MetricsWindow),
+                latencySMA:          utils.NewSMA(cfg.Consensus.MetricsWindow),
+                phaseTimeout:        time.Duration(cfg.Consensus.PhaseTimeout) * time.Millisecond,
+                checkpointInterval:  cfg.Consensus.LSCCCheckpointInterval,
+                retention:           voteRetention{limit: cfg.Consensus.MaxRetainedVotes},
                 state: &types.ConsensusState{
                         Algorithm:    "lscc",
                         Round:        0,
@@ -135,6 +206,10 @@ func NewLSCC(cfg *config.Config, logger *utils.Logger) (*LSCC, error) {
                 },
         }
         
+        if lscc.phaseTimeout <= 0 {
+                lscc.phaseTimeout = 2 * time.Second
+        }
+        
         // Initialize layered shard structure
         if err := lscc.initializeLayeredShards(); err != nil {
                 return nil, fmt.Errorf("failed to initialize layered shards: %w", err)
@@ -145,22 +220,24 @@ func NewLSCC(cfg *config.Config, logger *utils.Logger) (*LSCC, error) {
                 return nil, fmt.Errorf("failed to initialize cross channels: %w", err)
         }
         
+        // Initialize metrics before the workers can touch the state they read
+        lscc.updateMetrics()
+        
         // Start LSCC workers
         go lscc.consensusWorker()
+        go lscc.blockQueueWorker()
         go lscc.crossChannelWorker()
         go lscc.layerMonitor()
         
-        // Initialize metrics
-        lscc.updateMetrics()
-        
         logger.LogConsensus("lscc", "initialized", logrus.Fields{
                 "node_id":        lscc.nodeID,
                 "layer_depth":    lscc.layerDepth,
+                "shards_per_layer": lscc.shardsPerLayer,
                 "channel_count":  lscc.channelCount,
                 "byzantine_nodes": lscc.byzantineNodes,
                 "layers_initialized": len(lscc.shardLayers),
                 "channels_initialized": len(lscc.channelStates),
-                "timestamp":      time.Now().UTC(),
+                "timestamp":      clock.Now().UTC(),
         })
         
         return lscc, nil
@@ -168,12 +245,36 @@ func NewLSCC(cfg *config.Config, logger *utils.Logger) (*LSCC, error) {
 
 // ProcessBlock processes a block using LSCC consensus
 func (lscc *LSCC) ProcessBlock(block *types.Block, validators []*types.Validator) (bool, error) {
-        startTime := time.Now()
+        committed, err := lscc.processBlock(block, validators)
+        lscc.failures.record(committed, err)
+        return committed, err
+}
+
+// processBlock runs one round of consensus on a block
+func (lscc *LSCC) processBlock(block *types.Block, validators []*types.Validator) (bool, error) {
+        if err := requireValidators(validators); err != nil {
+                return false, err
+        }
+        startTime := lscc.clock.Now()
         lscc.mu.Lock()
-        defer lscc.mu.Unlock()
+        // A phase that overran its deadline keeps the lock until it exits
+        releaseLock := true
+        defer func() {
+                if releaseLock {
+                        lscc.endRound()
+                        lscc.mu.Unlock()
+                }
+        }()
+        
+        // Recover from a stalled round before starting a new one
+        if atomic.CompareAndSwapInt32(&lscc.resetPending, 1, 0) {
+                lscc.resetLocked()
+        }
+        atomic.StoreInt64(&lscc.roundStartedAt, startTime.UnixNano())
         
         lscc.logger.LogConsensus("lscc", "process_block", logrus.Fields{
                 "block_hash":     block.Hash,
+                "trace_id":       block.TraceID,
                 "block_index":    block.Index,
                 "validator":      block.Validator,
                 "shard_id":       block.ShardID,
@@ -195,54 +296,58 @@ func (lscc *LSCC) ProcessBlock(block *types.Block, validators []*types.Validator
         // LSCC Four-phase protocol
         
         // Phase 1: Layer-based Consensus
-        layerStart := time.Now()
-        layerResults, err := lscc.layerConsensusPhase(block, validators)
+        var layerResults map[int]bool
+        done, err := lscc.runPhase(block, "layer_consensus", func(ctx context.Context) error {
+                var phaseErr error
+                layerResults, phaseErr = lscc.layerConsensusPhase(ctx, block, validators)
+                return phaseErr
+        })
         if err != nil {
-                lscc.logger.LogError("consensus", "layer_consensus", err, logrus.Fields{
-                        "block_hash": block.Hash,
-                        "timestamp":  time.Now().UTC(),
-                })
-                return false, fmt.Errorf("layer consensus phase failed: %w", err)
+                lscc.abortRound(block, "layer_consensus", err)
+                releaseLock = lscc.releaseAfterPhase(done)
+                return false, withReason(FailureLayerRejected, fmt.Errorf("layer consensus phase failed: %w", err))
         }
-        lscc.performanceMetrics["layer_consensus"] = time.Since(layerStart)
         
         // Phase 2: Cross-Channel Communication
-        channelStart := time.Now()
-        channelApproval, err := lscc.crossChannelConsensusPhase(block, validators, layerResults)
+        var channelApproval bool
+        done, err = lscc.runPhase(block, "cross_channel", func(ctx context.Context) error {
+                var phaseErr error
+                channelApproval, phaseErr = lscc.crossChannelConsensusPhase(ctx, block, validators, layerResults)
+                return phaseErr
+        })
         if err != nil {
-                lscc.logger.LogError("consensus", "cross_channel", err, logrus.Fields{
-                        "block_hash": block.Hash,
-                        "timestamp":  time.Now().UTC(),
-                })
-                return false, fmt.Errorf("cross-channel consensus phase failed: %w", err)
+                lscc.abortRound(block, "cross_channel", err)
+                releaseLock = lscc.releaseAfterPhase(done)
+                return false, withReason(FailureCrossChannelRejected, fmt.Errorf("cross-channel consensus phase failed: %w", err))
         }
-        lscc.performanceMetrics["cross_channel"] = time.Since(channelStart)
         
         // Phase 3: Shard Synchronization
-        syncStart := time.Now()
-        syncSuccess, err := lscc.shardSynchronizationPhase(block, validators, layerResults)
+        var syncSuccess bool
+        done, err = lscc.runPhase(block, "shard_sync", func(ctx context.Context) error {
+                var phaseErr error
+                syncSuccess, phaseErr = lscc.shardSynchronizationPhase(ctx, block, validators, layerResults)
+                return phaseErr
+        })
         if err != nil {
-                lscc.logger.LogError("consensus", "shard_sync", err, logrus.Fields{
-                        "block_hash": block.Hash,
-                        "timestamp":  time.Now().UTC(),
-                })
-                return false, fmt.Errorf("shard synchronization phase failed: %w", err)
+                lscc.abortRound(block, "shard_sync", err)
+                releaseLock = lscc.releaseAfterPhase(done)
+                return false, withReason(FailureSyncFailed, fmt.Errorf("shard synchronization phase failed: %w", err))
         }
-        lscc.performanceMetrics["shard_sync"] = time.Since(syncStart)
         
         // Phase 4: Final Commitment
-        commitStart := time.Now()
-        finalCommit, err := lscc.finalCommitmentPhase(block, validators, layerResults, channelApproval, syncSuccess)
+        var finalCommit bool
+        done, err = lscc.runPhase(block, "final_commit", func(ctx context.Context) error {
+                var phaseErr error
+                finalCommit, phaseErr = lscc.finalCommitmentPhase(ctx, block, validators, layerResults, channelApproval, syncSuccess)
+                return phaseErr
+        })
         if err != nil {
-                lscc.logger.LogError("consensus", "final_commit", err, logrus.Fields{
-                        "block_hash": block.Hash,
-                        "timestamp":  time.Now().UTC(),
-                })
-                return false, fmt.Errorf("final commitment phase failed: %w", err)
+                lscc.abortRound(block, "final_commit", err)
+                releaseLock = lscc.releaseAfterPhase(done)
+                return false, withReason(FailureCommitFailed, fmt.Errorf("final commitment phase failed: %w", err))
         }
-        lscc.performanceMetrics["final_commit"] = time.Since(commitStart)
         
-        totalDuration := time.Since(startTime)
+        totalDuration := lscc.clock.Since(startTime)
         
         // Calculate throughput and latency metrics
         lscc.calculatePerformanceMetrics(block, totalDuration, len(validators))
@@ -251,13 +356,23 @@ func (lscc *LSCC) ProcessBlock(block *types.Block, validators []*types.Validator
                 lscc.currentRound++
                 lscc.phase = "prepare" // Reset for next round
                 lscc.state.Phase = "completed"
-                lscc.state.LastDecision = time.Now()
+                lscc.state.LastDecision = lscc.clock.Now()
                 
                 // Update shard states
                 lscc.updateShardStates(block)
                 
                 // Clean up old data
                 lscc.cleanupOldData(block.Hash, block.Index)
+                
+                lscc.lastCommit = &lsccCommit{BlockHash: block.Hash, BlockIndex: block.Index, LayerResults: layerResults}
+                if lscc.checkpointDue() {
+                        if err := lscc.checkpointLocked(); err != nil {
+                                lscc.logger.LogError("consensus", "lscc_checkpoint", err, logrus.Fields{
+                                        "round":     lscc.currentRound,
+                                        "timestamp": lscc.clock.Now().UTC(),
+                                })
+                        }
+                }
         }
         
         // Update comprehensive performance metrics
@@ -273,6 +388,7 @@ func (lscc *LSCC) ProcessBlock(block *types.Block, validators []*types.Validator
         
         lscc.logger.LogConsensus("lscc", "block_processed", logrus.Fields{
                 "block_hash":              block.Hash,
+                "trace_id":                block.TraceID,
                 "block_index":             block.Index,
                 "shard_id":                block.ShardID,
                 "final_commit":            finalCommit,
@@ -288,26 +404,184 @@ func (lscc *LSCC) ProcessBlock(block *types.Block, validators []*types.Validator
                 "average_latency":          lscc.latencyMetrics["average"].Milliseconds(),
                 "total_nodes":              lscc.totalNodes,
                 "byzantine_nodes":          lscc.byzantineNodes,
-                "timestamp":                time.Now().UTC(),
+                "phase_timeout":            lscc.phaseTimeout.Milliseconds(),
+                "timestamp":                lscc.clock.Now().UTC(),
         })
         
         return finalCommit, nil
 }
 
+// runPhase runs one consensus phase under the configured phase deadline and
+// records its duration in performanceMetrics. A phase that overruns is counted
+// as failed; the returned channel is closed once the phase goroutine exits.
+// Caller must hold lscc.mu.
+func (lscc *LSCC) runPhase(block *types.Block, name string, phase func(ctx context.Context) error) (<-chan struct{}, error) {
+        ctx, cancel := context.WithCancel(context.Background())
+        defer cancel()
+        
+        phaseStart := lscc.clock.Now()
+        deadline := lscc.clock.After(lscc.phaseTimeout)
+        done := make(chan struct{})
+        var phaseErr error
+        
+        go func() {
+                defer close(done)
+                phaseErr = phase(ctx)
+        }()
+        
+        select {
+        case <-done:
+        case <-deadline:
+                cancel()
+        }
+        
+        duration := lscc.clock.Since(phaseStart)
+        lscc.performanceMetrics[name] = duration
+        
+        select {
+        case <-done:
+                if phaseErr != nil {
+                        return done, phaseErr
+                }
+        default:
+                // The phase is still running past its deadline
+                lscc.logger.LogConsensus("lscc", "phase_deadline_exceeded", logrus.Fields{
+                        "block_hash":  block.Hash,
+                        "trace_id":    block.TraceID,
+                        "phase":       name,
+                        "duration":    duration.Milliseconds(),
+                        "deadline":    lscc.phaseTimeout.Milliseconds(),
+                        "timestamp":   lscc.clock.Now().UTC(),
+                })
+                return done, fmt.Errorf("%w: %s after %v", ErrPhaseTimeout, name, lscc.phaseTimeout)
+        }
+        
+        lscc.logger.LogConsensus("lscc", "phase_completed", logrus.Fields{
+                "block_hash":        block.Hash,
+                "trace_id":          block.TraceID,
+                "phase":             name,
+                "duration":          duration.Milliseconds(),
+                "deadline":          lscc.phaseTimeout.Milliseconds(),
+                "deadline_used_pct": float64(duration) / float64(lscc.phaseTimeout) * 100,
+                "timestamp":         lscc.clock.Now().UTC(),
+        })
+        lscc.events.Record(ConsensusEvent{
+                Type:      EventPhaseCompleted,
+                Algorithm: "lscc",
+                BlockHash: block.Hash,
+                Round:     lscc.currentRound,
+                View:      lscc.currentView,
+                Phase:     name,
+                Details:   map[string]interface{}{"duration_ms": duration.Milliseconds()},
+        })
+        
+        return done, nil
+}
+
+// releaseAfterPhase hands lscc.mu over to a goroutine that unlocks it once an
+// abandoned phase has exited, so the caller can return without waiting. It
+// returns whether the caller should still release the lock itself.
+func (lscc *LSCC) releaseAfterPhase(done <-chan struct{}) bool {
+        select {
+        case <-done:
+                return true
+        default:
+        }
+        
+        go func() {
+                <-done
+                lscc.endRound()
+                lscc.mu.Unlock()
+        }()
+        return false
+}
+
+// abortRound abandons the current round after a failed phase so the next
+// round starts from a clean phase. Caller must hold lscc.mu.
+func (lscc *LSCC) abortRound(block *types.Block, phase string, err error) {
+        // runPhase only cancels a phase's context once its deadline passes
+        timedOut := errors.Is(err, ErrPhaseTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+        if timedOut {
+                atomic.AddInt64(&lscc.roundTimeouts, 1)
+        }
+        
+        lscc.phase = "prepare"
+        lscc.state.Phase = "aborted"
+        
+        lscc.logger.LogError("consensus", phase, err, logrus.Fields{
+                "block_hash":     block.Hash,
+                "trace_id":       block.TraceID,
+                "block_index":    block.Index,
+                "timed_out":      timedOut,
+                "round_timeouts": atomic.LoadInt64(&lscc.roundTimeouts),
+                "timestamp":      lscc.clock.Now().UTC(),
+        })
+}
+
+// endRound marks the round holding lscc.mu as finished and trims the votes
+// kept to the retention cap, whether or not the round committed. It must be
+// called right before the round releases the lock.
+func (lscc *LSCC) endRound() {
+        lscc.boundVotes()
+        atomic.StoreInt64(&lscc.roundStartedAt, 0)
+}
+
+// checkStalledRound flags a reset when a round has held the consensus lock
+// for longer than all of its phase deadlines combined. It does not take
+// lscc.mu, which the stalled round is holding.
+func (lscc *LSCC) checkStalledRound() {
+        startedAt := atomic.LoadInt64(&lscc.roundStartedAt)
+        if startedAt == 0 {
+                return
+        }
+        
+        elapsed := lscc.clock.Since(time.Unix(0, startedAt))
+        roundTimeout := time.Duration(lsccPhaseCount+1) * lscc.phaseTimeout
+        if elapsed <= roundTimeout {
+                return
+        }
+        
+        if atomic.CompareAndSwapInt32(&lscc.resetPending, 0, 1) {
+                atomic.AddInt64(&lscc.stalledRounds, 1)
+                lscc.logger.LogConsensus("lscc", "round_stalled", logrus.Fields{
+                        "elapsed":       elapsed.Milliseconds(),
+                        "round_timeout": roundTimeout.Milliseconds(),
+                        "timestamp":     lscc.clock.Now().UTC(),
+                })
+        }
+}
+
+// recoverStalledRound performs a pending reset once the stalled round has
+// released the lock
+func (lscc *LSCC) recoverStalledRound() {
+        if atomic.LoadInt32(&lscc.resetPending) == 0 || !lscc.mu.TryLock() {
+                return
+        }
+        defer lscc.mu.Unlock()
+        
+        if atomic.CompareAndSwapInt32(&lscc.resetPending, 1, 0) {
+                lscc.resetLocked()
+        }
+}
+
 // layerConsensusPhase handles consensus within each layer
-func (lscc *LSCC) layerConsensusPhase(block *types.Block, validators []*types.Validator) (map[int]bool, error) {
+func (lscc *LSCC) layerConsensusPhase(ctx context.Context, block *types.Block, validators []*types.Validator) (map[int]bool, error) {
         lscc.logger.LogConsensus("lscc", "layer_consensus_start", logrus.Fields{
                 "block_hash":  block.Hash,
+                "trace_id":    block.TraceID,
                 "layer_depth": lscc.layerDepth,
                 "shard_id":    block.ShardID,
-                "timestamp":   time.Now().UTC(),
+                "timestamp":   lscc.clock.Now().UTC(),
         })
         
         layerResults := make(map[int]bool)
         
         // Process consensus in each layer
         for layer := 0; layer < lscc.layerDepth; layer++ {
-                layerStart := time.Now()
+                if err := ctx.Err(); err != nil {
+                        return nil, err
+                }
+                layerStart := lscc.clock.Now()
                 
                 // Initialize layer consensus if not exists
                 if lscc.layerConsensus[layer] == nil {
@@ -332,19 +606,23 @@ func (lscc *LSCC) layerConsensusPhase(block *types.Block, validators []*types.Va
                 lscc.logger.LogConsensus("lscc", "layer_voting", logrus.Fields{
                         "layer":            layer,
                         "block_hash":       block.Hash,
+                        "trace_id":         block.TraceID,
                         "layer_validators": len(layerValidators),
                         "required_votes":   requiredVotes,
-                        "timestamp":        time.Now().UTC(),
+                        "timestamp":        lscc.clock.Now().UTC(),
                 })
                 
                 // Collect votes from layer validators
                 for _, validator := range layerValidators {
-                        if lscc.isLayerByzantineValidator(validator.Address, layer, block.Hash) {
-                                lscc.logger.LogConsensus("lscc", "layer_byzantine_skip", logrus.Fields{
+                        byzantine := lscc.isLayerByzantineValidator(validator.Address, layer, block.Hash)
+                        lscc.votes.count(validator.Address, byzantine)
+                        if byzantine {
+                                lscc.logger.LogConsensusVote("lscc", "layer_byzantine_skip", logrus.Fields{
                                         "layer":      layer,
                                         "validator":  validator.Address,
                                         "block_hash": block.Hash,
-                                        "timestamp":  time.Now().UTC(),
+                                        "trace_id":   block.TraceID,
+                                        "timestamp":  lscc.clock.Now().UTC(),
                                 })
                                 continue
                         }
@@ -352,11 +630,12 @@ func (lscc *LSCC) layerConsensusPhase(block *types.Block, validators []*types.Va
                         vote := &Vote{
                                 ValidatorAddress: validator.Address,
                                 BlockHash:        block.Hash,
+                                TraceID:          block.TraceID,
                                 VoteType:         fmt.Sprintf("layer_%d", layer),
                                 Round:            lscc.currentRound,
                                 View:             lscc.currentView,
                                 Signature:        fmt.Sprintf("layer_%d_%s_%s", layer, validator.Address, block.Hash),
-                                Timestamp:        time.Now().Unix(),
+                                Timestamp:        lscc.clock.Now().Unix(),
                                 Metadata: map[string]interface{}{
                                         "layer":           layer,
                                         "shard_id":        block.ShardID,
@@ -367,14 +646,16 @@ func (lscc *LSCC) layerConsensusPhase(block *types.Block, validators []*types.Va
                         
                         layerConsensus.Votes[validator.Address] = vote
                         validVotes++
+                        lscc.events.Record(voteEvent("lscc", vote))
                         
-                        lscc.logger.LogConsensus("lscc", "layer_vote_received", logrus.Fields{
+                        lscc.logger.LogConsensusVote("lscc", "layer_vote_received", logrus.Fields{
                                 "layer":          layer,
                                 "validator":      validator.Address,
                                 "block_hash":     block.Hash,
+                                "trace_id":       block.TraceID,
                                 "vote_count":     validVotes,
                                 "required_votes": requiredVotes,
-                                "timestamp":      time.Now().UTC(),
+                                "timestamp":      lscc.clock.Now().UTC(),
                         })
                 }
                 
@@ -382,19 +663,20 @@ func (lscc *LSCC) layerConsensusPhase(block *types.Block, validators []*types.Va
                 layerApproved := validVotes >= requiredVotes
                 layerResults[layer] = layerApproved
                 layerConsensus.Approved = layerApproved
-                layerConsensus.EndTime = time.Now()
+                layerConsensus.EndTime = lscc.clock.Now()
                 layerConsensus.Phase = "completed"
                 
-                layerDuration := time.Since(layerStart)
+                layerDuration := lscc.clock.Since(layerStart)
                 
                 lscc.logger.LogConsensus("lscc", "layer_consensus_completed", logrus.Fields{
                         "layer":          layer,
                         "block_hash":     block.Hash,
+                        "trace_id":       block.TraceID,
                         "approved":       layerApproved,
                         "valid_votes":    validVotes,
                         "required_votes": requiredVotes,
                         "duration":       layerDuration.Milliseconds(),
-                        "timestamp":      time.Now().UTC(),
+                        "timestamp":      lscc.clock.Now().UTC(),
                 })
                 
                 // Update layer performance metrics
@@ -410,29 +692,34 @@ func (lscc *LSCC) layerConsensusPhase(block *types.Block, validators []*types.Va
         
         lscc.logger.LogConsensus("lscc", "layer_consensus_summary", logrus.Fields{
                 "block_hash":       block.Hash,
+                "trace_id":         block.TraceID,
                 "total_layers":     lscc.layerDepth,
                 "approved_layers":  approvedLayers,
                 "approval_ratio":   float64(approvedLayers) / float64(lscc.layerDepth),
-                "timestamp":        time.Now().UTC(),
+                "timestamp":        lscc.clock.Now().UTC(),
         })
         
         return layerResults, nil
 }
 
 // crossChannelConsensusPhase handles cross-channel consensus
-func (lscc *LSCC) crossChannelConsensusPhase(block *types.Block, validators []*types.Validator, layerResults map[int]bool) (bool, error) {
+func (lscc *LSCC) crossChannelConsensusPhase(ctx context.Context, block *types.Block, validators []*types.Validator, layerResults map[int]bool) (bool, error) {
         lscc.logger.LogConsensus("lscc", "cross_channel_start", logrus.Fields{
                 "block_hash":    block.Hash,
+                "trace_id":      block.TraceID,
                 "channel_count": lscc.channelCount,
                 "layer_results": layerResults,
-                "timestamp":     time.Now().UTC(),
+                "timestamp":     lscc.clock.Now().UTC(),
         })
         
         channelApprovals := make(map[string]bool)
         
         // Process each cross-channel
         for channelID, channelState := range lscc.channelStates {
-                channelStart := time.Now()
+                if err := ctx.Err(); err != nil {
+                        return false, err
+                }
+                channelStart := lscc.clock.Now()
                 
                 // Initialize cross-channel votes if not exists
                 if lscc.crossChannelVotes[channelID] == nil {
@@ -447,20 +734,24 @@ func (lscc *LSCC) crossChannelConsensusPhase(block *types.Block, validators []*t
                 lscc.logger.LogConsensus("lscc", "channel_voting", logrus.Fields{
                         "channel_id":         channelID,
                         "block_hash":         block.Hash,
+                        "trace_id":           block.TraceID,
                         "channel_validators": len(channelValidators),
                         "required_votes":     requiredVotes,
                         "connected_layers":   channelState.ConnectedLayers,
-                        "timestamp":          time.Now().UTC(),
+                        "timestamp":          lscc.clock.Now().UTC(),
                 })
                 
                 // Collect cross-channel votes
                 for _, validator := range channelValidators {
-                        if lscc.isChannelByzantineValidator(validator.Address, channelID, block.Hash) {
-                                lscc.logger.LogConsensus("lscc", "channel_byzantine_skip", logrus.Fields{
+                        byzantine := lscc.isChannelByzantineValidator(validator.Address, channelID, block.Hash)
+                        lscc.votes.count(validator.Address, byzantine)
+                        if byzantine {
+                                lscc.logger.LogConsensusVote("lscc", "channel_byzantine_skip", logrus.Fields{
                                         "channel_id": channelID,
                                         "validator":  validator.Address,
                                         "block_hash": block.Hash,
-                                        "timestamp":  time.Now().UTC(),
+                                        "trace_id":   block.TraceID,
+                                        "timestamp":  lscc.clock.Now().UTC(),
                                 })
                                 continue
                         }
@@ -469,12 +760,13 @@ func (lscc *LSCC) crossChannelConsensusPhase(block *types.Block, validators []*t
                                 ValidatorAddress: validator.Address,
                                 Channel:          channelID,
                                 BlockHash:        block.Hash,
+                                TraceID:          block.TraceID,
                                 LayerResults:     layerResults,
                                 VoteType:         "cross_channel",
                                 Round:            lscc.currentRound,
                                 View:             lscc.currentView,
                                 Signature:        fmt.Sprintf("channel_%s_%s_%s", channelID, validator.Address, block.Hash),
-                                Timestamp:        time.Now().Unix(),
+                                Timestamp:        lscc.clock.Now().Unix(),
                                 Metadata: map[string]interface{}{
                                         "channel_throughput": channelState.Throughput,
                                         "channel_latency":    channelState.Latency.Milliseconds(),
@@ -484,14 +776,25 @@ func (lscc *LSCC) crossChannelConsensusPhase(block *types.Block, validators []*t
                         
                         lscc.crossChannelVotes[channelID][validator.Address] = crossChannelVote
                         validVotes++
+                        lscc.events.Record(ConsensusEvent{
+                                Type:      EventVoteReceived,
+                                Algorithm: "lscc",
+                                BlockHash: block.Hash,
+                                Round:     crossChannelVote.Round,
+                                View:      crossChannelVote.View,
+                                Phase:     crossChannelVote.VoteType,
+                                Validator: validator.Address,
+                                Details:   map[string]interface{}{"channel_id": channelID},
+                        })
                         
-                        lscc.logger.LogConsensus("lscc", "channel_vote_received", logrus.Fields{
+                        lscc.logger.LogConsensusVote("lscc", "channel_vote_received", logrus.Fields{
                                 "channel_id":     channelID,
                                 "validator":      validator.Address,
                                 "block_hash":     block.Hash,
+                                "trace_id":       block.TraceID,
                                 "vote_count":     validVotes,
                                 "required_votes": requiredVotes,
-                                "timestamp":      time.Now().UTC(),
+                                "timestamp":      lscc.clock.Now().UTC(),
                         })
                 }
                 
@@ -500,19 +803,20 @@ func (lscc *LSCC) crossChannelConsensusPhase(block *types.Block, validators []*t
                 channelApprovals[channelID] = channelApproved
                 
                 // Update channel state
-                channelState.LastActivity = time.Now()
-                channelState.Latency = time.Since(channelStart)
+                channelState.LastActivity = lscc.clock.Now()
+                channelState.Latency = lscc.clock.Since(channelStart)
                 
-                channelDuration := time.Since(channelStart)
+                channelDuration := lscc.clock.Since(channelStart)
                 
                 lscc.logger.LogConsensus("lscc", "channel_consensus_completed", logrus.Fields{
                         "channel_id":     channelID,
                         "block_hash":     block.Hash,
+                        "trace_id":       block.TraceID,
                         "approved":       channelApproved,
                         "valid_votes":    validVotes,
                         "required_votes": requiredVotes,
                         "duration":       channelDuration.Milliseconds(),
-                        "timestamp":      time.Now().UTC(),
+                        "timestamp":      lscc.clock.Now().UTC(),
                 })
                 
                 // Update channel performance metrics
@@ -531,23 +835,25 @@ func (lscc *LSCC) crossChannelConsensusPhase(block *types.Block, validators []*t
         
         lscc.logger.LogConsensus("lscc", "cross_channel_summary", logrus.Fields{
                 "block_hash":         block.Hash,
+                "trace_id":           block.TraceID,
                 "total_channels":     len(channelApprovals),
                 "approved_channels":  approvedChannels,
                 "overall_approval":   overallChannelApproval,
                 "approval_ratio":     float64(approvedChannels) / float64(len(channelApprovals)),
-                "timestamp":          time.Now().UTC(),
+                "timestamp":          lscc.clock.Now().UTC(),
         })
         
         return overallChannelApproval, nil
 }
 
 // shardSynchronizationPhase handles shard synchronization
-func (lscc *LSCC) shardSynchronizationPhase(block *types.Block, validators []*types.Validator, layerResults map[int]bool) (bool, error) {
+func (lscc *LSCC) shardSynchronizationPhase(ctx context.Context, block *types.Block, validators []*types.Validator, layerResults map[int]bool) (bool, error) {
         lscc.logger.LogConsensus("lscc", "shard_sync_start", logrus.Fields{
                 "block_hash":   block.Hash,
+                "trace_id":     block.TraceID,
                 "shard_id":     block.ShardID,
                 "layer_results": layerResults,
-                "timestamp":    time.Now().UTC(),
+                "timestamp":    lscc.clock.Now().UTC(),
         })
         
         // Check if the target shard and related shards are synchronized
@@ -555,7 +861,10 @@ func (lscc *LSCC) shardSynchronizationPhase(block *types.Block, validators []*ty
         syncResults := make(map[int]bool)
         
         for _, shardLayer := range targetShardLayers {
-                syncStart := time.Now()
+                if err := ctx.Err(); err != nil {
+                        return false, err
+                }
+                syncStart := lscc.clock.Now()
                 
                 // Check layer approval for this shard
                 layerApproved := layerResults[shardLayer.Layer]
@@ -564,16 +873,17 @@ func (lscc *LSCC) shardSynchronizationPhase(block *types.Block, validators []*ty
                 shardSynced := lscc.performShardSync(shardLayer, block, layerApproved)
                 syncResults[shardLayer.Layer] = shardSynced
                 
-                syncDuration := time.Since(syncStart)
+                syncDuration := lscc.clock.Since(syncStart)
                 
                 lscc.logger.LogConsensus("lscc", "shard_layer_sync", logrus.Fields{
                         "shard_id":      shardLayer.ShardID,
                         "layer":         shardLayer.Layer,
                         "block_hash":    block.Hash,
+                        "trace_id":      block.TraceID,
                         "layer_approved": layerApproved,
                         "shard_synced":  shardSynced,
                         "sync_duration": syncDuration.Milliseconds(),
-                        "timestamp":     time.Now().UTC(),
+                        "timestamp":     lscc.clock.Now().UTC(),
                 })
         }
         
@@ -589,73 +899,52 @@ func (lscc *LSCC) shardSynchronizationPhase(block *types.Block, validators []*ty
         
         lscc.logger.LogConsensus("lscc", "shard_sync_summary", logrus.Fields{
                 "block_hash":        block.Hash,
+                "trace_id":          block.TraceID,
                 "shard_id":          block.ShardID,
                 "total_layers":      len(syncResults),
                 "synced_layers":     syncedLayers,
                 "overall_success":   overallSyncSuccess,
                 "sync_ratio":        float64(syncedLayers) / float64(len(syncResults)),
-                "timestamp":         time.Now().UTC(),
+                "timestamp":         lscc.clock.Now().UTC(),
         })
         
         return overallSyncSuccess, nil
 }
 
 // finalCommitmentPhase handles the final commitment decision
-func (lscc *LSCC) finalCommitmentPhase(block *types.Block, validators []*types.Validator, layerResults map[int]bool, channelApproval bool, syncSuccess bool) (bool, error) {
+func (lscc *LSCC) finalCommitmentPhase(ctx context.Context, block *types.Block, validators []*types.Validator, layerResults map[int]bool, channelApproval bool, syncSuccess bool) (bool, error) {
         lscc.logger.LogConsensus("lscc", "final_commit_start", logrus.Fields{
                 "block_hash":       block.Hash,
+                "trace_id":         block.TraceID,
                 "channel_approval": channelApproval,
                 "sync_success":     syncSuccess,
-                "timestamp":        time.Now().UTC(),
+                "timestamp":        lscc.clock.Now().UTC(),
         })
         
-        // Calculate layer approval ratio
-        approvedLayers := 0
-        for _, approved := range layerResults {
-                if approved {
-                        approvedLayers++
-                }
+        if err := ctx.Err(); err != nil {
+                return false, err
         }
-        layerApprovalRatio := float64(approvedLayers) / float64(len(layerResults))
         
         // LSCC requires:
         // 1. Majority of layers to approve (> 50%)
         // 2. Cross-channel consensus approval
         // 3. Successful shard synchronization
         // 4. Overall network health check
-        
-        layerRequirement := layerApprovalRatio > 0.5
-        networkHealthy := lscc.checkNetworkHealth()
-        
-        // Calculate final commitment score
-        commitmentScore := 0.0
-        if layerRequirement {
-                commitmentScore += 0.4
-        }
-        if channelApproval {
-                commitmentScore += 0.3
-        }
-        if syncSuccess {
-                commitmentScore += 0.2
-        }
-        if networkHealthy {
-                commitmentScore += 0.1
-        }
-        
-        // Require at least 0.7 score for final commitment
-        finalCommitment := commitmentScore >= 0.7
+        commitment := scoreCommitment(layerResults, channelApproval, syncSuccess, lscc.checkNetworkHealth())
+        finalCommitment := commitment.Committed
         
         lscc.logger.LogConsensus("lscc", "final_commitment_evaluation", logrus.Fields{
                 "block_hash":           block.Hash,
-                "layer_approval_ratio": layerApprovalRatio,
-                "layer_requirement":    layerRequirement,
+                "trace_id":             block.TraceID,
+                "layer_approval_ratio": commitment.LayerApprovalRatio,
+                "layer_requirement":    commitment.LayerRequirement,
                 "channel_approval":     channelApproval,
                 "sync_success":         syncSuccess,
-                "network_healthy":      networkHealthy,
-                "commitment_score":     commitmentScore,
+                "network_healthy":      commitment.NetworkHealthy,
+                "commitment_score":     commitment.Score,
                 "final_commitment":     finalCommitment,
-                "min_score_required":   0.7,
-                "timestamp":            time.Now().UTC(),
+                "min_score_required":   commitment.Threshold,
+                "timestamp":            lscc.clock.Now().UTC(),
         })
         
         if finalCommitment {
@@ -668,15 +957,33 @@ func (lscc *LSCC) finalCommitmentPhase(block *types.Block, validators []*types.V
 
 // Helper methods for LSCC implementation
 
+// lsccShardsPerLayer returns consensus.shards_per_layer, or when it is unset
+// enough shards per layer for the layers to hold every configured shard
+func lsccShardsPerLayer(cfg *config.Config) int {
+        if cfg.Consensus.ShardsPerLayer > 0 {
+                return cfg.Consensus.ShardsPerLayer
+        }
+        if cfg.Consensus.LayerDepth < 1 {
+                return utils.MaxInt(cfg.Sharding.NumShards, 1)
+        }
+        return utils.MaxInt((cfg.Sharding.NumShards+cfg.Consensus.LayerDepth-1)/cfg.Consensus.LayerDepth, 1)
+}
+
 // initializeLayeredShards initializes the layered shard structure
 func (lscc *LSCC) initializeLayeredShards() error {
+        if lscc.layerDepth < 1 {
+                return fmt.Errorf("layer depth must be at least 1, got %d", lscc.layerDepth)
+        }
+        if lscc.layerDepth*lscc.shardsPerLayer < lscc.config.Sharding.NumShards {
+                return fmt.Errorf("%d layers of %d shards cannot hold %d shards",
+                        lscc.layerDepth, lscc.shardsPerLayer, lscc.config.Sharding.NumShards)
+        }
+
         for layer := 0; layer < lscc.layerDepth; layer++ {
-                lscc.shardLayers[layer] = make([]*ShardLayer, 0)
+                lscc.shardLayers[layer] = make([]*ShardLayer, 0, lscc.shardsPerLayer)
                 
-                // Create shards for this layer (simplified: 2 shards per layer)
-                shardsPerLayer := 2
-                for shardIdx := 0; shardIdx < shardsPerLayer; shardIdx++ {
-                        shardID := layer*shardsPerLayer + shardIdx
+                for shardIdx := 0; shardIdx < lscc.shardsPerLayer; shardIdx++ {
+                        shardID := layer*lscc.shardsPerLayer + shardIdx
                         
                         shardLayer := &ShardLayer{
                                 ShardID:      shardID,
@@ -686,7 +993,7 @@ func (lscc *LSCC) initializeLayeredShards() error {
                                 State:        "active",
                                 Performance:  make(map[string]float64),
                                 Channels:     make([]string, 0),
-                                LastActivity: time.Now(),
+                                LastActivity: lscc.clock.Now(),
                         }
                         
                         lscc.shardLayers[layer] = append(lscc.shardLayers[layer], shardLayer)
@@ -695,7 +1002,7 @@ func (lscc *LSCC) initializeLayeredShards() error {
                 lscc.logger.LogConsensus("lscc", "layer_initialized", logrus.Fields{
                         "layer":        layer,
                         "shards_count": len(lscc.shardLayers[layer]),
-                        "timestamp":    time.Now().UTC(),
+                        "timestamp":    lscc.clock.Now().UTC(),
                 })
         }
         
@@ -722,7 +1029,7 @@ func (lscc *LSCC) initializeCrossChannels() error {
                         Throughput:      0.0,
                         Latency:         0,
                         State:           "active",
-                        LastActivity:    time.Now(),
+                        LastActivity:    lscc.clock.Now(),
                         Metadata:        make(map[string]interface{}),
                 }
                 
@@ -732,7 +1039,7 @@ func (lscc *LSCC) initializeCrossChannels() error {
                 lscc.logger.LogConsensus("lscc", "channel_initialized", logrus.Fields{
                         "channel_id":       channelID,
                         "connected_layers": connectedLayers,
-                        "timestamp":        time.Now().UTC(),
+                        "timestamp":        lscc.clock.Now().UTC(),
                 })
         }
         
@@ -837,96 +1144,128 @@ func (lscc *LSCC) getLayerPerformance(layer int) map[string]float64 {
 
 // performShardSync performs synchronization check for a shard layer
 func (lscc *LSCC) performShardSync(shardLayer *ShardLayer, block *types.Block, layerApproved bool) bool {
+        syncSuccess, validated := lscc.evaluateShardSync(shardLayer, block, layerApproved)
+        if validated {
+                // Update shard activity
+                shardLayer.LastActivity = lscc.clock.Now()
+        }
+        return syncSuccess
+}
+
+// evaluateShardSync decides whether a shard layer is in sync with block, and
+// whether deciding it took a sync validation, which counts as activity
+func (lscc *LSCC) evaluateShardSync(shardLayer *ShardLayer, block *types.Block, layerApproved bool) (bool, bool) {
         // Check if shard is in the right state for sync
         if shardLayer.State != "active" {
-                return false
+                return false, false
         }
         
         // Check if layer was approved
         if !layerApproved {
-                return false
+                return false, false
         }
         
         // Check if shard belongs to the block's target shard or is connected
         if shardLayer.ShardID != block.ShardID && !lscc.isShardConnected(shardLayer.ShardID, block.ShardID) {
-                return true // Not relevant for sync
+                return true, false // Not relevant for sync
         }
         
         // Simulate sync validation (in real implementation, this would check state consistency)
         syncHash := utils.HashString(fmt.Sprintf("%d_%s_%d", shardLayer.ShardID, block.Hash, shardLayer.Layer))
-        syncSuccess := len(syncHash) > 0 && syncHash[0] > '2' // ~80% success rate
-        
-        // Update shard activity
-        shardLayer.LastActivity = time.Now()
-        
-        return syncSuccess
+        return len(syncHash) > 0 && syncHash[0] > '2', true // ~80% success rate
+}
+
+// shardPosition returns the layer holding shardID and its index within the
+// layer, or false when no layer holds it
+func (lscc *LSCC) shardPosition(shardID int) (int, int, bool) {
+        if shardID < 0 || lscc.shardsPerLayer < 1 {
+                return 0, 0, false
+        }
+        layer := shardID / lscc.shardsPerLayer
+        if layer >= lscc.layerDepth {
+                return 0, 0, false
+        }
+        return layer, shardID % lscc.shardsPerLayer, true
 }
 
 // getShardLayers returns all shard layers for a specific shard ID
 func (lscc *LSCC) getShardLayers(shardID int) []*ShardLayer {
-        shardLayers := make([]*ShardLayer, 0)
+        shardLayers := make([]*ShardLayer, 0, 1)
         
-        for _, layers := range lscc.shardLayers {
-                for _, shardLayer := range layers {
-                        if shardLayer.ShardID == shardID {
-                                shardLayers = append(shardLayers, shardLayer)
-                        }
-                }
+        layer, index, ok := lscc.shardPosition(shardID)
+        if !ok || index >= len(lscc.shardLayers[layer]) {
+                return shardLayers
         }
-        
-        return shardLayers
+        return append(shardLayers, lscc.shardLayers[layer][index])
 }
 
-// isShardConnected checks if two shards are connected (simplified connectivity model)
+// isShardConnected checks if two shards are connected: neighbours within a
+// layer, or the shards at the same index of adjacent layers
 func (lscc *LSCC) isShardConnected(shardID1, shardID2 int) bool {
-        // Simple connectivity: adjacent shards are connected
-        return math.Abs(float64(shardID1-shardID2)) <= 1
+        layer1, index1, ok1 := lscc.shardPosition(shardID1)
+        layer2, index2, ok2 := lscc.shardPosition(shardID2)
+        if !ok1 || !ok2 {
+                return false
+        }
+        if layer1 == layer2 {
+                return math.Abs(float64(index1-index2)) <= 1
+        }
+        return index1 == index2 && math.Abs(float64(layer1-layer2)) <= 1
 }
 
 // checkNetworkHealth performs a network health check
 func (lscc *LSCC) checkNetworkHealth() bool {
+        health := lscc.assessNetworkHealth(nil, nil)
+        
+        lscc.logger.LogConsensus("lscc", "network_health_check", logrus.Fields{
+                "active_channels":  health.ActiveChannels,
+                "total_channels":   health.TotalChannels,
+                "channel_healthy":  health.ChannelHealthy,
+                "active_layers":    health.ActiveLayers,
+                "total_layers":     health.TotalLayers,
+                "layer_healthy":    health.LayerHealthy,
+                "network_healthy":  health.Healthy,
+                "timestamp":        lscc.clock.Now().UTC(),
+        })
+        
+        return health.Healthy
+}
+
+// assessNetworkHealth counts the channels and layers active in the last 30
+// seconds. The network is healthy when more than 60% of each are. Channels
+// in touchedChannels and shards in touchedShards count as just active,
+// whatever their last activity, as they are in a round that would have
+// touched them.
+func (lscc *LSCC) assessNetworkHealth(touchedChannels map[string]bool, touchedShards map[int]bool) LSCCNetworkHealth {
+        health := LSCCNetworkHealth{
+                TotalChannels: len(lscc.channelStates),
+                TotalLayers:   len(lscc.shardLayers),
+        }
+        
         // Check channel states
-        activeChannels := 0
-        for _, channelState := range lscc.channelStates {
-                if channelState.State == "active" && time.Since(channelState.LastActivity) < 30*time.Second {
-                        activeChannels++
+        for channelID, channelState := range lscc.channelStates {
+                recent := touchedChannels[channelID] || lscc.clock.Since(channelState.LastActivity) < 30*time.Second
+                if channelState.State == "active" && recent {
+                        health.ActiveChannels++
                 }
         }
         
         // Check layer health
-        activeLayers := 0
-        for layer, shardLayers := range lscc.shardLayers {
-                layerActive := false
+        for _, shardLayers := range lscc.shardLayers {
                 for _, shardLayer := range shardLayers {
-                        if shardLayer.State == "active" && time.Since(shardLayer.LastActivity) < 30*time.Second {
-                                layerActive = true
+                        recent := touchedShards[shardLayer.ShardID] || lscc.clock.Since(shardLayer.LastActivity) < 30*time.Second
+                        if shardLayer.State == "active" && recent {
+                                health.ActiveLayers++
                                 break
                         }
                 }
-                if layerActive {
-                        activeLayers++
-                }
-                _ = layer // Avoid unused variable warning
         }
         
         // Network is healthy if majority of channels and layers are active
-        channelHealthy := float64(activeChannels) / float64(len(lscc.channelStates)) > 0.6
-        layerHealthy := float64(activeLayers) / float64(len(lscc.shardLayers)) > 0.6
-        
-        networkHealthy := channelHealthy && layerHealthy
-        
-        lscc.logger.LogConsensus("lscc", "network_health_check", logrus.Fields{
-                "active_channels":  activeChannels,
-                "total_channels":   len(lscc.channelStates),
-                "channel_healthy":  channelHealthy,
-                "active_layers":    activeLayers,
-                "total_layers":     len(lscc.shardLayers),
-                "layer_healthy":    layerHealthy,
-                "network_healthy":  networkHealthy,
-                "timestamp":        time.Now().UTC(),
-        })
-        
-        return networkHealthy
+        health.ChannelHealthy = float64(health.ActiveChannels)/float64(health.TotalChannels) > 0.6
+        health.LayerHealthy = float64(health.ActiveLayers)/float64(health.TotalLayers) > 0.6
+        health.Healthy = health.ChannelHealthy && health.LayerHealthy
+        return health
 }
 
 // calculatePerformanceMetrics calculates performance metrics for the current round
@@ -937,19 +1276,19 @@ func (lscc *LSCC) calculatePerformanceMetrics(block *types.Block, totalDuration
         currentThroughput := txCount / durationSeconds
         
         // Update throughput metrics
-        if existing, ok := lscc.throughputMetrics["average"]; ok {
-                lscc.throughputMetrics["average"] = (existing + currentThroughput) / 2
-        } else {
-                lscc.throughputMetrics["average"] = currentThroughput
-        }
+        lscc.throughputEWMA.Add(currentThroughput)
+        lscc.throughputSMA.Add(currentThroughput)
+        lscc.throughputMetrics["average"] = lscc.throughputEWMA.Value()
+        lscc.throughputMetrics["moving_average"] = lscc.throughputSMA.Value()
         lscc.throughputMetrics["current"] = currentThroughput
         
-        // Calculate average latency
-        if existing, ok := lscc.latencyMetrics["average"]; ok {
-                lscc.latencyMetrics["average"] = (existing + totalDuration) / 2
-        } else {
-                lscc.latencyMetrics["average"] = totalDuration
-        }
+        // Update latency metrics
+        latencyMs := float64(totalDuration) / float64(time.Millisecond)
+        lscc.latencyEWMA.Add(latencyMs)
+        lscc.latencyWindow.Add(latencyMs)
+        lscc.latencySMA.Add(latencyMs)
+        lscc.latencyMetrics["average"] = time.Duration(lscc.latencyEWMA.Value() * float64(time.Millisecond))
+        lscc.latencyMetrics["moving_average"] = time.Duration(lscc.latencySMA.Value() * float64(time.Millisecond))
         
         // Update efficiency metrics
         efficiency := currentThroughput / float64(validatorCount)
@@ -962,7 +1301,7 @@ func (lscc *LSCC) calculatePerformanceMetrics(block *types.Block, totalDuration
                 "average_throughput": lscc.throughputMetrics["average"],
                 "average_latency":    lscc.latencyMetrics["average"].Milliseconds(),
                 "efficiency":         efficiency,
-                "timestamp":          time.Now().UTC(),
+                "timestamp":          lscc.clock.Now().UTC(),
         })
 }
 
@@ -981,9 +1320,9 @@ func (lscc *LSCC) updateLayerPerformance(layer int, duration time.Duration, appr
                                 shardLayer.Performance["approval_rate"] = 1.0
                         }
                         
-                        // Calculate average approval rate
+                        // Smooth the approval rate with the configured EWMA factor
                         if existing, ok := shardLayer.Performance["avg_approval_rate"]; ok {
-                                shardLayer.Performance["avg_approval_rate"] = (existing + shardLayer.Performance["approval_rate"]) / 2
+                                shardLayer.Performance["avg_approval_rate"] = existing + lscc.config.Consensus.MetricsAlpha*(shardLayer.Performance["approval_rate"]-existing)
                         } else {
                                 shardLayer.Performance["avg_approval_rate"] = shardLayer.Performance["approval_rate"]
                         }
@@ -1010,7 +1349,7 @@ func (lscc *LSCC) updateChannelPerformance(channelID string, duration time.Durat
                 }
                 channelState.Metadata["last_duration"] = duration.Milliseconds()
                 channelState.Metadata["last_approved"] = approved
-                channelState.Metadata["updated_at"] = time.Now().Unix()
+                channelState.Metadata["updated_at"] = lscc.clock.Now().Unix()
         }
 }
 
@@ -1021,7 +1360,7 @@ func (lscc *LSCC) updateShardStates(block *types.Block) {
                         if shardLayer.ShardID == block.ShardID {
                                 // Update shard with new transactions
                                 shardLayer.Transactions = append(shardLayer.Transactions, block.Transactions...)
-                                shardLayer.LastActivity = time.Now()
+                                shardLayer.LastActivity = lscc.clock.Now()
                                 
                                 // Maintain transaction history limit
                                 if len(shardLayer.Transactions) > 1000 {
@@ -1056,11 +1395,12 @@ func (lscc *LSCC) updateGlobalConsensusMetrics(block *types.Block, layerResults
         
         lscc.logger.LogConsensus("lscc", "global_metrics_updated", logrus.Fields{
                 "block_hash":          block.Hash,
+                "trace_id":            block.TraceID,
                 "consensus_efficiency": efficiency,
                 "layer_approval_rate": lscc.throughputMetrics["layer_approval_rate"],
                 "channel_approval":    channelApproval,
                 "sync_success":        syncSuccess,
-                "timestamp":           time.Now().UTC(),
+                "timestamp":           lscc.clock.Now().UTC(),
         })
 }
 
@@ -1068,7 +1408,7 @@ func (lscc *LSCC) updateGlobalConsensusMetrics(block *types.Block, layerResults
 func (lscc *LSCC) cleanupOldData(excludeBlockHash string, currentSequence int64) {
         // Clean up old layer consensus data
         for layer, layerConsensus := range lscc.layerConsensus {
-                if time.Since(layerConsensus.EndTime) > 5*time.Minute {
+                if lscc.clock.Since(layerConsensus.EndTime) > 5*time.Minute {
                         layerConsensus.Votes = make(map[string]*Vote)
                 }
                 _ = layer // Avoid unused variable warning
@@ -1077,7 +1417,7 @@ func (lscc *LSCC) cleanupOldData(excludeBlockHash string, currentSequence int64)
         // Clean up old cross-channel votes
         for channelID, votes := range lscc.crossChannelVotes {
                 for validatorAddr, vote := range votes {
-                        if time.Since(time.Unix(vote.Timestamp, 0)) > 5*time.Minute {
+                        if lscc.clock.Since(time.Unix(vote.Timestamp, 0)) > 5*time.Minute {
                                 delete(votes, validatorAddr)
                         }
                 }
@@ -1094,24 +1434,74 @@ func (lscc *LSCC) cleanupOldData(excludeBlockHash string, currentSequence int64)
         lscc.logger.LogConsensus("lscc", "cleanup_completed", logrus.Fields{
                 "current_sequence": currentSequence,
                 "excluded_block":   excludeBlockHash,
-                "timestamp":        time.Now().UTC(),
+                "timestamp":        lscc.clock.Now().UTC(),
         })
 }
 
+// boundVotes drops the state of layers and channels the engine no longer
+// runs, then evicts the oldest rounds' votes once more than
+// max_retained_votes are kept. The time-based cleanup after each commit
+// never runs during a burst of failing rounds, so this is what keeps the
+// vote maps bounded under load. Caller must hold lscc.mu.
+func (lscc *LSCC) boundVotes() {
+        for layer := range lscc.layerConsensus {
+                if layer < 0 || layer >= lscc.layerDepth {
+                        delete(lscc.layerConsensus, layer)
+                }
+        }
+        for channelID := range lscc.crossChannelVotes {
+                if _, exists := lscc.channelStates[channelID]; !exists {
+                        delete(lscc.crossChannelVotes, channelID)
+                }
+        }
+
+        votes := make([]retainedVote, 0)
+        for _, layerConsensus := range lscc.layerConsensus {
+                votes = retainVotes(votes, layerConsensus.Votes, nil)
+        }
+        for _, byValidator := range lscc.crossChannelVotes {
+                byValidator := byValidator
+                for address, vote := range byValidator {
+                        address := address
+                        votes = append(votes, retainedVote{
+                                round:     vote.Round,
+                                timestamp: vote.Timestamp,
+                                bytes:     voteBytes(vote.Metadata, vote.ValidatorAddress, vote.Channel, vote.BlockHash, vote.VoteType, vote.Signature, vote.TraceID) + len(vote.LayerResults)*metadataEntryBytes,
+                                drop:      func() { delete(byValidator, address) },
+                        })
+                }
+        }
+
+        if evicted := lscc.retention.enforce(votes); evicted > 0 {
+                lscc.logger.LogConsensus("lscc", "votes_evicted", logrus.Fields{
+                        "evicted":   evicted,
+                        "retained":  lscc.retention.entries,
+                        "limit":     lscc.retention.limit,
+                        "round":     lscc.currentRound,
+                        "timestamp": lscc.clock.Now().UTC(),
+                })
+        }
+}
+
 // Implement remaining interface methods
 
 // ValidateBlock validates a block according to LSCC rules
 func (lscc *LSCC) ValidateBlock(block *types.Block, validators []*types.Validator) error {
-        startTime := time.Now()
+        startTime := lscc.clock.Now()
         
         lscc.logger.LogConsensus("lscc", "validate_block", logrus.Fields{
                 "block_hash":  block.Hash,
+                "trace_id":    block.TraceID,
                 "block_index": block.Index,
                 "validator":   block.Validator,
                 "shard_id":    block.ShardID,
                 "timestamp":   startTime,
         })
         
+        if err := block.CheckLimits(lscc.config.Consensus.MaxTxPerBlock, lscc.config.Consensus.MaxBlockSize); err != nil {
+                return err
+        }
+        
         // Basic validation
         if block.Hash == "" {
                 return fmt.Errorf("block hash is empty")
@@ -1151,23 +1541,26 @@ func (lscc *LSCC) ValidateBlock(block *types.Block, validators []*types.Validato
                 return fmt.Errorf("block validator %s is not in the validator set", block.Validator)
         }
         
-        validationDuration := time.Since(startTime)
+        validationDuration := lscc.clock.Since(startTime)
         
         lscc.logger.LogConsensus("lscc", "block_validated", logrus.Fields{
                 "block_hash":         block.Hash,
+                "trace_id":           block.TraceID,
                 "block_index":        block.Index,
                 "shard_id":           block.ShardID,
                 "validation_duration": validationDuration.Milliseconds(),
-                "timestamp":          time.Now().UTC(),
+                "timestamp":          lscc.clock.Now().UTC(),
         })
         
         return nil
 }
 
-// SelectValidator selects a validator for the given round
+// SelectValidator selects a validator for the given round from the round's
+// layer, rotating through it or, with consensus.lscc_selection_mode set to
+// stake_weighted, drawing by stake
 func (lscc *LSCC) SelectValidator(validators []*types.Validator, round int64) (*types.Validator, error) {
         if len(validators) == 0 {
-                return nil, fmt.Errorf("no validators available")
+                return nil, ErrNoValidators
         }
         
         // LSCC uses layer-based validator selection
@@ -1181,21 +1574,63 @@ func (lscc *LSCC) SelectValidator(validators []*types.Validator, round int64) (*
         }
         
         // Select from layer validators
-        validatorIndex := round % int64(len(layerValidators))
-        selected := layerValidators[validatorIndex]
+        mode := lscc.config.Consensus.LSCCSelectionMode
+        var selected *types.Validator
+        if mode == "stake_weighted" {
+                selected = stakeWeightedValidator(layerValidators, lscc.config.Consensus.LSCCSelectionSeed, round)
+        }
+        if selected == nil {
+                mode = "round_robin"
+                validatorIndex := round % int64(len(layerValidators))
+                selected = layerValidators[validatorIndex]
+        }
         
         lscc.logger.LogConsensus("lscc", "validator_selected", logrus.Fields{
                 "validator":         selected.Address,
                 "round":             round,
                 "layer":             layer,
+                "selection_mode":    mode,
+                "stake":             selected.Stake,
                 "layer_validators":  len(layerValidators),
                 "total_validators":  len(validators),
-                "timestamp":         time.Now().UTC(),
+                "timestamp":         lscc.clock.Now().UTC(),
         })
         
         return selected, nil
 }
 
+// stakeWeightedValidator draws a validator with probability proportional
+// to its stake. The draw is a hash of seed and round reduced modulo the total
+// stake and located among the validators' cumulative stakes, so every node
+// with the same seed and validator order picks the same one. Validators
+// without positive stake are never drawn; it returns nil when none has any.
+func stakeWeightedValidator(validators []*types.Validator, seed string, round int64) *types.Validator {
+        var total uint64
+        for _, validator := range validators {
+                if validator.Stake > 0 {
+                        total += uint64(validator.Stake)
+                }
+        }
+        if total == 0 {
+                return nil
+        }
+        
+        sum := sha256.Sum256([]byte(seed + "/" + strconv.FormatInt(round, 10)))
+        target := binary.BigEndian.Uint64(sum[:8]) % total
+        
+        var cumulative uint64
+        for _, validator := range validators {
+                if validator.Stake <= 0 {
+                        continue
+                }
+                cumulative += uint64(validator.Stake)
+                if target < cumulative {
+                        return validator
+                }
+        }
+        return nil
+}
+
 // GetConsensusState returns the current consensus state
 func (lscc *LSCC) GetConsensusState() *types.ConsensusState {
         lscc.mu.RLock()
@@ -1208,7 +1643,7 @@ func (lscc *LSCC) GetConsensusState() *types.ConsensusState {
         lscc.state.Performance["current_round"] = float64(lscc.currentRound)
         lscc.state.Performance["layer_depth"] = float64(lscc.layerDepth)
         lscc.state.Performance["channel_count"] = float64(lscc.channelCount)
-        lscc.state.Performance["uptime"] = time.Since(lscc.startTime).Seconds()
+        lscc.state.Performance["uptime"] = lscc.clock.Since(lscc.startTime).Seconds()
         
         // Add LSCC-specific metrics
         lscc.state.Performance["active_layers"] = 0
@@ -1230,11 +1665,17 @@ func (lscc *LSCC) GetConsensusState() *types.ConsensusState {
         return lscc.state
 }
 
-// UpdateValidators updates the validator set
+// UpdateValidators updates the validator set. A set too small to tolerate
+// the configured Byzantine validators is refused with ErrByzantineThreshold
+// when consensus.byzantine_check is "reject".
 func (lscc *LSCC) UpdateValidators(validators []*types.Validator) error {
         lscc.mu.Lock()
         defer lscc.mu.Unlock()
         
+        if err := CheckValidatorSet("lscc", lscc.byzantineCheck, lscc.byzantineNodes, validators, lscc.logger); err != nil {
+                return err
+        }
+        
         oldCount := len(lscc.state.Validators)
         lscc.state.Validators = validators
         lscc.totalNodes = len(validators)
@@ -1252,7 +1693,7 @@ func (lscc *LSCC) UpdateValidators(validators []*types.Validator) error {
                 "old_count":   oldCount,
                 "new_count":   len(validators),
                 "total_nodes": lscc.totalNodes,
-                "timestamp":   time.Now().UTC(),
+                "timestamp":   lscc.clock.Now().UTC(),
         })
         
         return nil
@@ -1274,18 +1715,28 @@ func (lscc *LSCC) GetMetrics() map[string]interface{} {
 
 // updateMetrics updates internal metrics
 func (lscc *LSCC) updateMetrics() {
-        uptime := time.Since(lscc.startTime)
+        uptime := lscc.clock.Since(lscc.startTime)
         
         lscc.metrics["algorithm"] = "lscc"
+        lscc.metrics["failure_reasons"] = lscc.failures.snapshot()
         lscc.metrics["node_id"] = lscc.nodeID
         lscc.metrics["current_view"] = lscc.currentView
         lscc.metrics["current_round"] = lscc.currentRound
         lscc.metrics["total_nodes"] = lscc.totalNodes
         lscc.metrics["byzantine_nodes"] = lscc.byzantineNodes
         lscc.metrics["phase"] = lscc.phase
+        lscc.metrics["checkpoints"] = lscc.checkpoints
+        lscc.metrics["last_checkpoint_round"] = lscc.lastCheckpointRound
         lscc.metrics["layer_depth"] = lscc.layerDepth
+        lscc.metrics["shards_per_layer"] = lscc.shardsPerLayer
         lscc.metrics["channel_count"] = lscc.channelCount
         lscc.metrics["uptime_seconds"] = uptime.Seconds()
+        lscc.metrics["round_timeouts"] = atomic.LoadInt64(&lscc.roundTimeouts)
+        lscc.metrics["stalled_rounds"] = atomic.LoadInt64(&lscc.stalledRounds)
+        lscc.metrics["queue_depth"] = len(lscc.blockQueue)
+        lscc.metrics["queue_capacity"] = cap(lscc.blockQueue)
+        lscc.metrics["queue_rejected"] = atomic.LoadInt64(&lscc.queueRejected)
+        lscc.metrics["pipeline_in_flight"] = len(lscc.pipeline)
         
         // Layer metrics
         activeShards := 0
@@ -1317,8 +1768,14 @@ func (lscc *LSCC) updateMetrics() {
         
         // Performance metrics
         lscc.metrics["throughput"] = lscc.throughputMetrics
+        percentiles := lscc.latencyWindow.Percentiles(50, 95, 99)
         lscc.metrics["latency"] = map[string]interface{}{
-                "average": lscc.latencyMetrics["average"].Milliseconds(),
+                "average":        lscc.latencyMetrics["average"].Milliseconds(),
+                "moving_average": lscc.latencyMetrics["moving_average"].Milliseconds(),
+                "p50":            percentiles[0],
+                "p95":            percentiles[1],
+                "p99":            percentiles[2],
+                "samples":        lscc.latencyWindow.Count(),
         }
         
         // Layer consensus metrics
@@ -1345,8 +1802,21 @@ func (lscc *LSCC) updateMetrics() {
                 }
         }
         lscc.metrics["cross_channel"] = channelMetrics
+        lscc.metrics["vote_map_entries"] = lscc.retention.entries
+        lscc.metrics["vote_map_bytes"] = lscc.retention.bytes
+        lscc.metrics["votes_evicted"] = lscc.retention.evicted
         
-        lscc.metrics["timestamp"] = time.Now().UTC()
+        lscc.metrics["timestamp"] = lscc.clock.Now().UTC()
+}
+
+// VoteAudit implements VoteAuditor
+func (lscc *LSCC) VoteAudit() VoteAudit {
+        return lscc.votes.audit()
+}
+
+// SetParticipationHook implements ParticipationRecorder
+func (lscc *LSCC) SetParticipationHook(hook func(address string)) {
+        lscc.votes.setHook(hook)
 }
 
 // Reset resets the consensus state
@@ -1354,8 +1824,16 @@ func (lscc *LSCC) Reset() error {
         lscc.mu.Lock()
         defer lscc.mu.Unlock()
         
+        lscc.resetLocked()
+        lscc.failures.reset()
+        lscc.votes.reset()
+        return nil
+}
+
+// resetLocked resets the consensus state. Caller must hold lscc.mu.
+func (lscc *LSCC) resetLocked() {
         lscc.logger.LogConsensus("lscc", "reset", logrus.Fields{
-                "timestamp": time.Now().UTC(),
+                "timestamp": lscc.clock.Now().UTC(),
         })
         
         lscc.state.Round = 0
@@ -1363,11 +1841,13 @@ func (lscc *LSCC) Reset() error {
         lscc.state.Phase = "prepare"
         lscc.state.Leader = ""
         lscc.state.Votes = make(map[string]interface{})
-        lscc.state.LastDecision = time.Now()
+        lscc.state.LastDecision = lscc.clock.Now()
         lscc.state.Performance = make(map[string]float64)
         
         lscc.currentView = 0
         lscc.currentRound = 0
+        lscc.lastCheckpointRound = 0
+        lscc.lastCommit = nil
         lscc.phase = "prepare"
         lscc.crossChannelVotes = make(map[string]map[string]*CrossChannelVote)
         lscc.layerConsensus = make(map[int]*LayerConsensus)
@@ -1375,7 +1855,13 @@ func (lscc *LSCC) Reset() error {
         lscc.performanceMetrics = make(map[string]time.Duration)
         lscc.throughputMetrics = make(map[string]float64)
         lscc.latencyMetrics = make(map[string]time.Duration)
-        lscc.startTime = time.Now()
+        lscc.throughputEWMA.Reset()
+        lscc.latencyEWMA.Reset()
+        lscc.latencyWindow.Reset()
+        lscc.throughputSMA.Reset()
+        lscc.latencySMA.Reset()
+        lscc.retention = voteRetention{limit: lscc.retention.limit}
+        lscc.startTime = lscc.clock.Now()
         
         // Reinitialize cross-channels
         for channelID := range lscc.channelStates {
@@ -1383,42 +1869,37 @@ func (lscc *LSCC) Reset() error {
         }
         
         lscc.updateMetrics()
-        
-        return nil
 }
 
 // Worker methods
 
 // consensusWorker handles consensus operations in background
 func (lscc *LSCC) consensusWorker() {
-        ticker := time.NewTicker(1 * time.Second)
+        ticker := lscc.clock.NewTicker(1 * time.Second)
         defer ticker.Stop()
         
         for {
                 select {
                 case <-lscc.stopChan:
                         return
-                case <-ticker.C:
+                case <-ticker.C():
+                        lscc.checkStalledRound()
+                        lscc.recoverStalledRound()
                         lscc.performPeriodicMaintenance()
-                case block := <-lscc.blockQueue:
-                        lscc.logger.LogConsensus("lscc", "block_queued", logrus.Fields{
-                                "block_hash": block.Hash,
-                                "timestamp":  time.Now().UTC(),
-                        })
                 }
         }
 }
 
 // crossChannelWorker handles cross-channel communication
 func (lscc *LSCC) crossChannelWorker() {
-        ticker := time.NewTicker(2 * time.Second)
+        ticker := lscc.clock.NewTicker(2 * time.Second)
         defer ticker.Stop()
         
         for {
                 select {
                 case <-lscc.stopChan:
                         return
-                case <-ticker.C:
+                case <-ticker.C():
                         lscc.processCrossChannelMessages()
                 }
         }
@@ -1426,14 +1907,14 @@ func (lscc *LSCC) crossChannelWorker() {
 
 // layerMonitor monitors layer health and performance
 func (lscc *LSCC) layerMonitor() {
-        ticker := time.NewTicker(5 * time.Second)
+        ticker := lscc.clock.NewTicker(5 * time.Second)
         defer ticker.Stop()
         
         for {
                 select {
                 case <-lscc.stopChan:
                         return
-                case <-ticker.C:
+                case <-ticker.C():
                         lscc.monitorLayerHealth()
                 }
         }
@@ -1441,15 +1922,19 @@ func (lscc *LSCC) layerMonitor() {
 
 // performPeriodicMaintenance performs periodic maintenance tasks
 func (lscc *LSCC) performPeriodicMaintenance() {
-        lscc.mu.Lock()
+        // Skip this tick while a round holds the lock so the worker keeps
+        // watching for stalls
+        if !lscc.mu.TryLock() {
+                return
+        }
         defer lscc.mu.Unlock()
         
         // Clean up old data
-        now := time.Now()
+        now := lscc.clock.Now()
         
         // Clean up old layer consensus data
         for layer, layerConsensus := range lscc.layerConsensus {
-                if time.Since(layerConsensus.EndTime) > 10*time.Minute {
+                if lscc.clock.Since(layerConsensus.EndTime) > 10*time.Minute {
                         delete(lscc.layerConsensus, layer)
                 }
         }
@@ -1457,7 +1942,7 @@ func (lscc *LSCC) performPeriodicMaintenance() {
         // Update shard states based on activity
         for _, shardLayers := range lscc.shardLayers {
                 for _, shardLayer := range shardLayers {
-                        if time.Since(shardLayer.LastActivity) > 2*time.Minute {
+                        if lscc.clock.Since(shardLayer.LastActivity) > 2*time.Minute {
                                 shardLayer.State = "inactive"
                         } else {
                                 shardLayer.State = "active"
@@ -1467,7 +1952,7 @@ func (lscc *LSCC) performPeriodicMaintenance() {
         
         // Update channel states
         for _, channelState := range lscc.channelStates {
-                if time.Since(channelState.LastActivity) > 1*time.Minute {
+                if lscc.clock.Since(channelState.LastActivity) > 1*time.Minute {
                         channelState.State = "inactive"
                 } else if len(channelState.MessageQueue) > 50 {
                         channelState.State = "congested"
@@ -1489,13 +1974,13 @@ func (lscc *LSCC) processCrossChannelMessages() {
                         // Process messages (simplified)
                         processedCount := utils.MinInt(len(channelState.MessageQueue), 5)
                         channelState.MessageQueue = channelState.MessageQueue[processedCount:]
-                        channelState.LastActivity = time.Now()
+                        channelState.LastActivity = lscc.clock.Now()
                         
                         lscc.logger.LogConsensus("lscc", "cross_channel_messages_processed", logrus.Fields{
                                 "channel_id":       channelID,
                                 "processed_count":  processedCount,
                                 "remaining_count":  len(channelState.MessageQueue),
-                                "timestamp":        time.Now().UTC(),
+                                "timestamp":        lscc.clock.Now().UTC(),
                         })
                 }
         }
@@ -1523,7 +2008,7 @@ func (lscc *LSCC) monitorLayerHealth() {
                         "active_shards": activeShards,
                         "total_shards":  totalShards,
                         "health_ratio":  healthRatio,
-                        "timestamp":     time.Now().UTC(),
+                        "timestamp":     lscc.clock.Now().UTC(),
                 })
                 
                 // Alert if layer health is poor
@@ -1532,7 +2017,7 @@ func (lscc *LSCC) monitorLayerHealth() {
                                 "layer":        layer,
                                 "health_ratio": healthRatio,
                                 "threshold":    0.5,
-                                "timestamp":    time.Now().UTC(),
+                                "timestamp":    lscc.clock.Now().UTC(),
                         })
                 }
         }
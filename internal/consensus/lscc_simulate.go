@@ -0,0 +1,266 @@
+package consensus
+
+import (
+        "lscc-blockchain/pkg/types"
+        "sort"
+        "time"
+)
+
+// LSCCLayerTally is how one layer voted on a block in the layer consensus
+// phase
+type LSCCLayerTally struct {
+        Layer         int      `json:"layer"`
+        Validators    int      `json:"validators"`
+        RequiredVotes int      `json:"required_votes"`
+        Votes         []string `json:"votes"`    // validators whose vote counted
+        Excluded      []string `json:"excluded"` // validators left out as Byzantine
+        Approved      bool     `json:"approved"`
+}
+
+// LSCCChannelTally is how one cross-channel voted on a block in the
+// cross-channel phase
+type LSCCChannelTally struct {
+        ChannelID       string   `json:"channel_id"`
+        ConnectedLayers []int    `json:"connected_layers"`
+        Validators      int      `json:"validators"`
+        RequiredVotes   int      `json:"required_votes"`
+        Votes           []string `json:"votes"`
+        Excluded        []string `json:"excluded"`
+        Approved        bool     `json:"approved"`
+}
+
+// LSCCShardSync is the shard synchronization phase's check of one of the
+// block's shard layers
+type LSCCShardSync struct {
+        ShardID       int    `json:"shard_id"`
+        Layer         int    `json:"layer"`
+        State         string `json:"state"`
+        LayerApproved bool   `json:"layer_approved"`
+        Synced        bool   `json:"synced"`
+}
+
+// LSCCNetworkHealth is the network health check of the final commitment
+// phase
+type LSCCNetworkHealth struct {
+        ActiveChannels int  `json:"active_channels"`
+        TotalChannels  int  `json:"total_channels"`
+        ChannelHealthy bool `json:"channel_healthy"`
+        ActiveLayers   int  `json:"active_layers"`
+        TotalLayers    int  `json:"total_layers"`
+        LayerHealthy   bool `json:"layer_healthy"`
+        Healthy        bool `json:"healthy"`
+}
+
+// LSCCCommitment is the final commitment phase's scoring of a block.
+// Breakdown holds what each requirement the block met added to Score.
+type LSCCCommitment struct {
+        LayerApprovalRatio float64            `json:"layer_approval_ratio"`
+        LayerRequirement   bool               `json:"layer_requirement"` // more than half the layers approved
+        ChannelApproval    bool               `json:"channel_approval"`
+        SyncSuccess        bool               `json:"sync_success"`
+        NetworkHealthy     bool               `json:"network_healthy"`
+        Breakdown          map[string]float64 `json:"breakdown"`
+        Score              float64            `json:"score"`
+        Threshold          float64            `json:"threshold"`
+        Committed          bool               `json:"committed"`
+}
+
+// LSCCStep is the state of a simulated round once one phase has run. Each
+// step carries the results of its own phase; Passed is whether the phase's
+// requirement was met, which for the first three phases only adds to the
+// commitment score rather than stopping the round.
+type LSCCStep struct {
+        Phase            string             `json:"phase"`
+        Passed           bool               `json:"passed"`
+        LayerResults     map[int]bool       `json:"layer_results,omitempty"`
+        Layers           []LSCCLayerTally   `json:"layers,omitempty"`
+        ChannelApprovals map[string]bool    `json:"channel_approvals,omitempty"`
+        Channels         []LSCCChannelTally `json:"channels,omitempty"`
+        SyncResults      map[int]bool       `json:"sync_results,omitempty"`
+        Shards           []LSCCShardSync    `json:"shards,omitempty"`
+        NetworkHealth    *LSCCNetworkHealth `json:"network_health,omitempty"`
+        Commitment       *LSCCCommitment    `json:"commitment,omitempty"`
+}
+
+// LSCCSimulation is a dry run of an LSCC round on a block: what each of the
+// four phases would decide
+type LSCCSimulation struct {
+        BlockHash   string     `json:"block_hash"`
+        BlockIndex  int64      `json:"block_index"`
+        ShardID     int        `json:"shard_id"`
+        Validators  int        `json:"validators"`
+        Round       int64      `json:"round"`
+        View        int64      `json:"view"`
+        Steps       []LSCCStep `json:"steps"`
+        Committed   bool       `json:"committed"`
+        SimulatedAt time.Time  `json:"simulated_at"`
+}
+
+// scoreCommitment scores a block on the requirements of the final
+// commitment phase
+func scoreCommitment(layerResults map[int]bool, channelApproval bool, syncSuccess bool, networkHealthy bool) *LSCCCommitment {
+        approvedLayers := 0
+        for _, approved := range layerResults {
+                if approved {
+                        approvedLayers++
+                }
+        }
+
+        commitment := &LSCCCommitment{
+                LayerApprovalRatio: float64(approvedLayers) / float64(len(layerResults)),
+                ChannelApproval:    channelApproval,
+                SyncSuccess:        syncSuccess,
+                NetworkHealthy:     networkHealthy,
+                Breakdown:          make(map[string]float64),
+                Threshold:          lsccCommitThreshold,
+        }
+        commitment.LayerRequirement = commitment.LayerApprovalRatio > 0.5
+
+        // Added up in a fixed order, so equal inputs always score alike
+        met := []struct {
+                name   string
+                met    bool
+                weight float64
+        }{
+                {"layers", commitment.LayerRequirement, lsccLayerWeight},
+                {"channels", channelApproval, lsccChannelWeight},
+                {"sync", syncSuccess, lsccSyncWeight},
+                {"network_health", networkHealthy, lsccHealthWeight},
+        }
+        for _, requirement := range met {
+                if requirement.met {
+                        commitment.Score += requirement.weight
+                        commitment.Breakdown[requirement.name] = requirement.weight
+                } else {
+                        commitment.Breakdown[requirement.name] = 0
+                }
+        }
+        commitment.Committed = commitment.Score >= lsccCommitThreshold
+        return commitment
+}
+
+// SimulateBlock runs the four LSCC phases on block as a round with
+// validators would, without voting, committing or changing any state, and
+// returns what each phase decided. It waits for a round in progress to
+// finish.
+func (lscc *LSCC) SimulateBlock(block *types.Block, validators []*types.Validator) (*LSCCSimulation, error) {
+        if err := requireValidators(validators); err != nil {
+                return nil, err
+        }
+
+        lscc.mu.RLock()
+        defer lscc.mu.RUnlock()
+
+        simulation := &LSCCSimulation{
+                BlockHash:   block.Hash,
+                BlockIndex:  block.Index,
+                ShardID:     block.ShardID,
+                Validators:  len(validators),
+                Round:       lscc.currentRound,
+                View:        lscc.currentView,
+                Steps:       make([]LSCCStep, 0, lsccPhaseCount),
+                SimulatedAt: lscc.clock.Now().UTC(),
+        }
+
+        // Phase 1: Layer-based Consensus
+        layers := make([]LSCCLayerTally, 0, lscc.layerDepth)
+        layerResults := make(map[int]bool)
+        for layer := 0; layer < lscc.layerDepth; layer++ {
+                layerValidators := lscc.getLayerValidators(layer, validators)
+                tally := LSCCLayerTally{
+                        Layer:         layer,
+                        Validators:    len(layerValidators),
+                        RequiredVotes: lscc.getRequiredVoteCount(len(layerValidators)),
+                        Votes:         make([]string, 0, len(layerValidators)),
+                        Excluded:      make([]string, 0),
+                }
+                for _, validator := range layerValidators {
+                        if lscc.isLayerByzantineValidator(validator.Address, layer, block.Hash) {
+                                tally.Excluded = append(tally.Excluded, validator.Address)
+                                continue
+                        }
+                        tally.Votes = append(tally.Votes, validator.Address)
+                }
+                tally.Approved = len(tally.Votes) >= tally.RequiredVotes
+                layerResults[layer] = tally.Approved
+                layers = append(layers, tally)
+        }
+        layerStep := LSCCStep{Phase: "layer_consensus", LayerResults: layerResults, Layers: layers}
+
+        // Phase 2: Cross-Channel Communication
+        channelIDs := make([]string, 0, len(lscc.channelStates))
+        for channelID := range lscc.channelStates {
+                channelIDs = append(channelIDs, channelID)
+        }
+        sort.Strings(channelIDs)
+
+        channels := make([]LSCCChannelTally, 0, len(channelIDs))
+        channelApprovals := make(map[string]bool)
+        touchedChannels := make(map[string]bool)
+        approvedChannels := 0
+        for _, channelID := range channelIDs {
+                channelValidators := lscc.getChannelValidators(channelID, validators)
+                tally := LSCCChannelTally{
+                        ChannelID:       channelID,
+                        ConnectedLayers: lscc.channelStates[channelID].ConnectedLayers,
+                        Validators:      len(channelValidators),
+                        RequiredVotes:   lscc.getRequiredVoteCount(len(channelValidators)),
+                        Votes:           make([]string, 0, len(channelValidators)),
+                        Excluded:        make([]string, 0),
+                }
+                for _, validator := range channelValidators {
+                        if lscc.isChannelByzantineValidator(validator.Address, channelID, block.Hash) {
+                                tally.Excluded = append(tally.Excluded, validator.Address)
+                                continue
+                        }
+                        tally.Votes = append(tally.Votes, validator.Address)
+                }
+                tally.Approved = len(tally.Votes) >= tally.RequiredVotes
+                channelApprovals[channelID] = tally.Approved
+                touchedChannels[channelID] = true
+                if tally.Approved {
+                        approvedChannels++
+                }
+                channels = append(channels, tally)
+        }
+        channelApproval := approvedChannels >= (len(channelApprovals)+1)/2
+
+        // Phase 3: Shard Synchronization
+        shards := make([]LSCCShardSync, 0, 1)
+        syncResults := make(map[int]bool)
+        touchedShards := make(map[int]bool)
+        syncedLayers := 0
+        for _, shardLayer := range lscc.getShardLayers(block.ShardID) {
+                layerApproved := layerResults[shardLayer.Layer]
+                synced, validated := lscc.evaluateShardSync(shardLayer, block, layerApproved)
+                if validated {
+                        touchedShards[shardLayer.ShardID] = true
+                }
+                syncResults[shardLayer.Layer] = synced
+                if synced {
+                        syncedLayers++
+                }
+                shards = append(shards, LSCCShardSync{
+                        ShardID:       shardLayer.ShardID,
+                        Layer:         shardLayer.Layer,
+                        State:         shardLayer.State,
+                        LayerApproved: layerApproved,
+                        Synced:        synced,
+                })
+        }
+        syncSuccess := syncedLayers >= (len(syncResults)+1)/2
+
+        // Phase 4: Final Commitment
+        health := lscc.assessNetworkHealth(touchedChannels, touchedShards)
+        commitment := scoreCommitment(layerResults, channelApproval, syncSuccess, health.Healthy)
+
+        layerStep.Passed = commitment.LayerRequirement
+        simulation.Steps = append(simulation.Steps,
+                layerStep,
+                LSCCStep{Phase: "cross_channel", Passed: channelApproval, ChannelApprovals: channelApprovals, Channels: channels},
+                LSCCStep{Phase: "shard_sync", Passed: syncSuccess, SyncResults: syncResults, Shards: shards},
+                LSCCStep{Phase: "final_commit", Passed: commitment.Committed, NetworkHealth: &health, Commitment: commitment},
+        )
+        simulation.Committed = commitment.Committed
+        return simulation, nil
+}
@@ -0,0 +1,228 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/utils"
+)
+
+func shortPhases(cfg *config.Config) {
+	cfg.Consensus.PhaseTimeout = 50
+}
+
+// TestSlowPhaseFailsAtDeadline stands a shard sync that never checks its
+// context in for a stuck phase: the round must fail at the deadline and
+// keep the consensus lock only until the phase exits.
+func TestSlowPhaseFailsAtDeadline(t *testing.T) {
+	lscc := newTestLSCC(t, shortPhases)
+	block := testBlock(1)
+	release := make(chan struct{})
+
+	lscc.mu.Lock()
+	start := time.Now()
+	done, err := lscc.runPhase(block, "shard_sync", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("phase blocked the round for %v past a 50ms deadline", elapsed)
+	}
+	if !errors.Is(err, ErrPhaseTimeout) {
+		t.Fatalf("expected ErrPhaseTimeout, got %v", err)
+	}
+	lscc.abortRound(block, "shard_sync", err)
+	if lscc.releaseAfterPhase(done) {
+		t.Fatal("lock handed back while the phase is still running")
+	}
+	if got := atomic.LoadInt64(&lscc.roundTimeouts); got != 1 {
+		t.Fatalf("round timeouts = %d, want 1", got)
+	}
+
+	if lscc.mu.TryLock() {
+		t.Fatal("lock released before the phase exited")
+	}
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for !lscc.mu.TryLock() {
+		if time.Now().After(deadline) {
+			t.Fatal("lock still held after the phase exited")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	lscc.mu.Unlock()
+}
+
+func TestCancelledPhaseReleasesAtDeadline(t *testing.T) {
+	lscc := newTestLSCC(t, shortPhases)
+	block := testBlock(1)
+
+	lscc.mu.Lock()
+	done, err := lscc.runPhase(block, "shard_sync", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err == nil {
+		t.Fatal("phase past its deadline succeeded")
+	}
+	<-done
+	if !lscc.releaseAfterPhase(done) {
+		t.Fatal("lock kept after the phase exited")
+	}
+	lscc.mu.Unlock()
+}
+
+func TestProcessBlockWithinDeadline(t *testing.T) {
+	lscc := newTestLSCC(t, nil)
+	committed, err := lscc.ProcessBlock(testBlock(1), testValidators(4))
+	if err != nil || !committed {
+		t.Fatalf("ProcessBlock = %v, %v; want a commit", committed, err)
+	}
+	if got := atomic.LoadInt64(&lscc.roundTimeouts); got != 0 {
+		t.Fatalf("round timeouts = %d, want 0", got)
+	}
+}
+
+// TestStalledRoundIsReset holds the consensus lock as a layer that never
+// finishes would, and checks the worker's watchdog flags the round once it
+// outlives every phase deadline and resets the engine when the lock frees.
+func TestStalledRoundIsReset(t *testing.T) {
+	clock := utils.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	lscc, err := NewLSCCWithClock(testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.PhaseTimeout = 100
+	}), discardLogger(), clock)
+	if err != nil {
+		t.Fatalf("failed to create LSCC: %v", err)
+	}
+	defer lscc.Stop()
+
+	if committed, err := lscc.ProcessBlock(testBlock(1), testValidators(4)); err != nil || !committed {
+		t.Fatalf("ProcessBlock = %v, %v; want a commit", committed, err)
+	}
+
+	// A round stuck in a slow layer
+	lscc.mu.Lock()
+	atomic.StoreInt64(&lscc.roundStartedAt, clock.Now().UnixNano())
+
+	// Four phases plus one of slack: 500ms
+	clock.Advance(400 * time.Millisecond)
+	lscc.checkStalledRound()
+	if got := atomic.LoadInt64(&lscc.stalledRounds); got != 0 {
+		t.Fatalf("round flagged stalled within its deadlines")
+	}
+	clock.Advance(200 * time.Millisecond)
+	lscc.checkStalledRound()
+	if got := atomic.LoadInt64(&lscc.stalledRounds); got != 1 {
+		t.Fatalf("stalled rounds = %d, want 1", got)
+	}
+
+	// The reset waits for the stuck round to let go of the lock
+	lscc.recoverStalledRound()
+	if atomic.LoadInt32(&lscc.resetPending) != 1 || lscc.currentRound != 1 {
+		t.Fatal("engine reset while the stalled round held the lock")
+	}
+	lscc.endRound()
+	lscc.mu.Unlock()
+
+	lscc.recoverStalledRound()
+	if atomic.LoadInt32(&lscc.resetPending) != 0 {
+		t.Fatal("reset still pending after the lock was freed")
+	}
+	if lscc.currentRound != 0 || lscc.phase != "prepare" {
+		t.Fatalf("after reset round = %d phase = %s", lscc.currentRound, lscc.phase)
+	}
+
+	if committed, err := lscc.ProcessBlock(testBlock(2), testValidators(4)); err != nil || !committed {
+		t.Fatalf("ProcessBlock after the reset = %v, %v; want a commit", committed, err)
+	}
+}
+
+func TestLayeredShardsFollowConfig(t *testing.T) {
+	for _, tc := range []struct {
+		layers, perLayer, numShards int
+		wantPerLayer                int
+	}{
+		{layers: 1, perLayer: 4, numShards: 4, wantPerLayer: 4},
+		{layers: 2, perLayer: 3, numShards: 4, wantPerLayer: 3},
+		{layers: 3, perLayer: 0, numShards: 4, wantPerLayer: 2},
+		{layers: 4, perLayer: 0, numShards: 8, wantPerLayer: 2},
+		{layers: 5, perLayer: 1, numShards: 5, wantPerLayer: 1},
+	} {
+		lscc := newTestLSCC(t, func(cfg *config.Config) {
+			cfg.Consensus.LayerDepth = tc.layers
+			cfg.Consensus.ShardsPerLayer = tc.perLayer
+			cfg.Sharding.NumShards = tc.numShards
+		})
+		if lscc.shardsPerLayer != tc.wantPerLayer || len(lscc.shardLayers) != tc.layers {
+			t.Fatalf("%+v: %d layers of %d shards", tc, len(lscc.shardLayers), lscc.shardsPerLayer)
+		}
+
+		seen := make(map[int]bool)
+		for layer := 0; layer < tc.layers; layer++ {
+			shards := lscc.shardLayers[layer]
+			if len(shards) != tc.wantPerLayer {
+				t.Fatalf("%+v: layer %d holds %d shards", tc, layer, len(shards))
+			}
+			for index, shard := range shards {
+				if seen[shard.ShardID] {
+					t.Fatalf("%+v: shard %d appears twice", tc, shard.ShardID)
+				}
+				seen[shard.ShardID] = true
+				if shard.Layer != layer || shard.ShardID != layer*tc.wantPerLayer+index {
+					t.Fatalf("%+v: shard %d at layer %d index %d claims layer %d", tc, shard.ShardID, layer, index, shard.Layer)
+				}
+				if found := lscc.getShardLayers(shard.ShardID); len(found) != 1 || found[0] != shard {
+					t.Fatalf("%+v: lookup of shard %d found %v", tc, shard.ShardID, found)
+				}
+			}
+		}
+		// Every configured shard has a place
+		for shardID := 0; shardID < tc.numShards; shardID++ {
+			if !seen[shardID] {
+				t.Fatalf("%+v: shard %d in no layer", tc, shardID)
+			}
+		}
+		if found := lscc.getShardLayers(tc.layers * tc.wantPerLayer); len(found) != 0 {
+			t.Fatalf("%+v: shard past the last layer found in %v", tc, found)
+		}
+	}
+}
+
+func TestShardConnectivityFollowsLayers(t *testing.T) {
+	// Two layers of three: shards 0-2 above 3-5
+	lscc := newTestLSCC(t, func(cfg *config.Config) {
+		cfg.Consensus.LayerDepth = 2
+		cfg.Consensus.ShardsPerLayer = 3
+		cfg.Sharding.NumShards = 6
+	})
+	for _, tc := range []struct {
+		a, b int
+		want bool
+	}{
+		{0, 1, true},  // neighbours in a layer
+		{0, 2, false}, // two apart in a layer
+		{2, 3, false}, // adjacent IDs, different layers and indices
+		{1, 4, true},  // same index in adjacent layers
+		{0, 4, false},
+		{5, 6, false}, // 6 lies past the last layer
+	} {
+		if got := lscc.isShardConnected(tc.a, tc.b); got != tc.want {
+			t.Fatalf("shards %d and %d connected = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestLayeredShardsTooFewForConfig(t *testing.T) {
+	cfg := testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.LayerDepth = 2
+		cfg.Consensus.ShardsPerLayer = 1
+		cfg.Sharding.NumShards = 4
+	})
+	if _, err := NewLSCC(cfg, discardLogger()); err == nil {
+		t.Fatal("2 layers of 1 shard accepted for 4 shards")
+	}
+}
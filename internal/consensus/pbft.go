@@ -24,14 +24,29 @@ type PBFT struct {
         commitVotes     map[string]map[string]*Vote // blockHash -> validatorAddress -> vote
         viewChangeVotes map[int64]map[string]*Vote  // view -> validatorAddress -> vote
         isPrimary       bool
+        lastBlockHash   string // last committed block, seeds primary selection
         viewTimeout     time.Duration
         byzantineNodes  int
+        byzantineCheck  string // what UpdateValidators does with a set too small for byzantineNodes
         totalNodes      int
         startTime       time.Time
         metrics         map[string]interface{}
         blockQueue      chan *types.Block
         stopChan        chan struct{}
         phase           string // "prepare", "commit", "view_change"
+        failures        failureCounter
+        votes           voteCounter
+        aggregateVotes  bool            // combine each phase's votes into one signature
+        aggregatedSets  int64           // phases carried by an aggregated signature
+        bytesSaved      int64           // vote bytes saved by aggregation
+        aggregates      phaseAggregates // aggregates standing in for the votes they combine
+        voteKeys        *voteKeyring    // signing keys of the validators whose votes are simulated
+}
+
+func init() {
+        Register("pbft", func(cfg *config.Config, logger *utils.Logger) (Consensus, error) {
+                return NewPBFT(cfg, logger)
+        })
 }
 
 // NewPBFT creates a new PBFT consensus instance
@@ -57,11 +72,15 @@ func NewPBFT(cfg *config.Config, logger *utils.Logger) (*PBFT, error) {
                 isPrimary:       false,
                 viewTimeout:     time.Duration(cfg.Consensus.ViewTimeout) * time.Second,
                 byzantineNodes:  cfg.Consensus.Byzantine,
+                byzantineCheck:  cfg.Consensus.ByzantineCheck,
                 startTime:       startTime,
                 metrics:         make(map[string]interface{}),
                 blockQueue:      make(chan *types.Block, 100),
                 stopChan:        make(chan struct{}),
                 phase:           "prepare",
+                aggregateVotes:  cfg.Consensus.UseBLSAggregation,
+                aggregates:      make(phaseAggregates),
+                voteKeys:        newVoteKeyring(),
                 state: &types.ConsensusState{
                         Algorithm:    "pbft",
                         Round:        0,
@@ -92,12 +111,23 @@ func NewPBFT(cfg *config.Config, logger *utils.Logger) (*PBFT, error) {
 
 // ProcessBlock processes a block using PBFT consensus
 func (pbft *PBFT) ProcessBlock(block *types.Block, validators []*types.Validator) (bool, error) {
+        committed, err := pbft.processBlock(block, validators)
+        pbft.failures.record(committed, err)
+        return committed, err
+}
+
+// processBlock runs one round of consensus on a block
+func (pbft *PBFT) processBlock(block *types.Block, validators []*types.Validator) (bool, error) {
+        if err := requireValidators(validators); err != nil {
+                return false, err
+        }
         startTime := time.Now()
         pbft.mu.Lock()
         defer pbft.mu.Unlock()
         
         pbft.logger.LogConsensus("pbft", "process_block", logrus.Fields{
                 "block_hash":   block.Hash,
+                "trace_id":     block.TraceID,
                 "block_index":  block.Index,
                 "validator":    block.Validator,
                 "current_view": pbft.currentView,
@@ -114,7 +144,7 @@ func (pbft *PBFT) ProcessBlock(block *types.Block, validators []*types.Validator
         pbft.totalNodes = len(validators)
         
         // Determine if this node is the primary for current view
-        primary := pbft.getPrimary(validators, pbft.currentView)
+        primary := pbft.getPrimary(validators, block.PreviousHash, pbft.currentView)
         pbft.isPrimary = (primary != nil && primary.Address == pbft.nodeID)
         pbft.state.Leader = ""
         if primary != nil {
@@ -129,6 +159,7 @@ func (pbft *PBFT) ProcessBlock(block *types.Block, validators []*types.Validator
                 if err := pbft.prePreparePhase(block, validators); err != nil {
                         pbft.logger.LogError("consensus", "pre_prepare", err, logrus.Fields{
                                 "block_hash": block.Hash,
+                                "trace_id":   block.TraceID,
                                 "timestamp":  time.Now().UTC(),
                         })
                         return false, fmt.Errorf("pre-prepare phase failed: %w", err)
@@ -141,6 +172,7 @@ func (pbft *PBFT) ProcessBlock(block *types.Block, validators []*types.Validator
         if err := pbft.preparePhase(block, validators); err != nil {
                 pbft.logger.LogError("consensus", "prepare", err, logrus.Fields{
                         "block_hash": block.Hash,
+                        "trace_id":   block.TraceID,
                         "timestamp":  time.Now().UTC(),
                 })
                 return false, fmt.Errorf("prepare phase failed: %w", err)
@@ -153,6 +185,7 @@ func (pbft *PBFT) ProcessBlock(block *types.Block, validators []*types.Validator
         if err != nil {
                 pbft.logger.LogError("consensus", "commit", err, logrus.Fields{
                         "block_hash": block.Hash,
+                        "trace_id":   block.TraceID,
                         "timestamp":  time.Now().UTC(),
                 })
                 return false, fmt.Errorf("commit phase failed: %w", err)
@@ -164,6 +197,7 @@ func (pbft *PBFT) ProcessBlock(block *types.Block, validators []*types.Validator
         
         if committed {
                 pbft.currentRound++
+                pbft.lastBlockHash = block.Hash
                 pbft.phase = "prepare" // Reset for next round
                 pbft.state.Phase = "completed"
                 pbft.state.LastDecision = time.Now()
@@ -185,6 +219,7 @@ func (pbft *PBFT) ProcessBlock(block *types.Block, validators []*types.Validator
         
         pbft.logger.LogConsensus("pbft", "block_processed", logrus.Fields{
                 "block_hash":       block.Hash,
+                "trace_id":         block.TraceID,
                 "block_index":      block.Index,
                 "committed":        committed,
                 "is_primary":       pbft.isPrimary,
@@ -205,6 +240,7 @@ func (pbft *PBFT) ProcessBlock(block *types.Block, validators []*types.Validator
 func (pbft *PBFT) prePreparePhase(block *types.Block, validators []*types.Validator) error {
         pbft.logger.LogConsensus("pbft", "pre_prepare_start", logrus.Fields{
                 "block_hash":   block.Hash,
+                "trace_id":     block.TraceID,
                 "view":         pbft.currentView,
                 "round":        pbft.currentRound,
                 "timestamp":    time.Now().UTC(),
@@ -212,13 +248,14 @@ func (pbft *PBFT) prePreparePhase(block *types.Block, validators []*types.Valida
         
         // Primary validates the block first
         if err := pbft.validateBlockStructure(block); err != nil {
-                return fmt.Errorf("block validation failed: %w", err)
+                return withReason(FailureInvalidBlock, fmt.Errorf("block validation failed: %w", err))
         }
         
         // In a real implementation, primary would broadcast pre-prepare message to all nodes
         // For simulation, we'll just log the pre-prepare
         pbft.logger.LogConsensus("pbft", "pre_prepare_broadcast", logrus.Fields{
                 "block_hash":     block.Hash,
+                "trace_id":       block.TraceID,
                 "validator_count": len(validators),
                 "timestamp":      time.Now().UTC(),
         })
@@ -231,6 +268,7 @@ func (pbft *PBFT) prePreparePhase(block *types.Block, validators []*types.Valida
 func (pbft *PBFT) preparePhase(block *types.Block, validators []*types.Validator) error {
         pbft.logger.LogConsensus("pbft", "prepare_start", logrus.Fields{
                 "block_hash": block.Hash,
+                "trace_id":   block.TraceID,
                 "view":       pbft.currentView,
                 "round":      pbft.currentRound,
                 "timestamp":  time.Now().UTC(),
@@ -247,10 +285,13 @@ func (pbft *PBFT) preparePhase(block *types.Block, validators []*types.Validator
         
         for _, validator := range validators {
                 // Skip byzantine validators (simplified simulation)
-                if pbft.isByzantineValidator(validator.Address) {
-                        pbft.logger.LogConsensus("pbft", "prepare_byzantine_skip", logrus.Fields{
+                byzantine := pbft.isByzantineValidator(validator.Address)
+                pbft.votes.count(validator.Address, byzantine)
+                if byzantine {
+                        pbft.logger.LogConsensusVote("pbft", "prepare_byzantine_skip", logrus.Fields{
                                 "validator":  validator.Address,
                                 "block_hash": block.Hash,
+                                "trace_id":   block.TraceID,
                                 "timestamp":  time.Now().UTC(),
                         })
                         continue
@@ -260,19 +301,28 @@ func (pbft *PBFT) preparePhase(block *types.Block, validators []*types.Validator
                 vote := &Vote{
                         ValidatorAddress: validator.Address,
                         BlockHash:        block.Hash,
+                        TraceID:          block.TraceID,
                         VoteType:         "prepare",
                         Round:            pbft.currentRound,
                         View:             pbft.currentView,
                         Signature:        fmt.Sprintf("prepare_%s_%s", validator.Address, block.Hash),
                         Timestamp:        time.Now().Unix(),
                 }
+                if pbft.aggregateVotes {
+                        share, err := signVoteShare(pbft.voteKeys, validator, vote)
+                        if err != nil {
+                                return withReason(FailureInvalidSignature, err)
+                        }
+                        vote.Signature = share
+                }
                 
                 pbft.prepareVotes[block.Hash][validator.Address] = vote
                 validVotes++
                 
-                pbft.logger.LogConsensus("pbft", "prepare_vote_received", logrus.Fields{
+                pbft.logger.LogConsensusVote("pbft", "prepare_vote_received", logrus.Fields{
                         "validator":     validator.Address,
                         "block_hash":    block.Hash,
+                        "trace_id":      block.TraceID,
                         "vote_count":    validVotes,
                         "required_votes": requiredVotes,
                         "timestamp":     time.Now().UTC(),
@@ -281,13 +331,18 @@ func (pbft *PBFT) preparePhase(block *types.Block, validators []*types.Validator
         
         // Check if we have enough prepare votes
         if validVotes < requiredVotes {
-                return fmt.Errorf("insufficient prepare votes: got %d, required %d", validVotes, requiredVotes)
+                return withReason(FailureInsufficientVotes, fmt.Errorf("insufficient prepare votes: got %d, required %d", validVotes, requiredVotes))
+        }
+        
+        if err := pbft.aggregatePhaseVotes(pbft.prepareVotes[block.Hash], validators); err != nil {
+                return err
         }
         
         pbft.phase = "commit"
         
         pbft.logger.LogConsensus("pbft", "prepare_completed", logrus.Fields{
                 "block_hash":     block.Hash,
+                "trace_id":       block.TraceID,
                 "valid_votes":    validVotes,
                 "required_votes": requiredVotes,
                 "timestamp":      time.Now().UTC(),
@@ -300,6 +355,7 @@ func (pbft *PBFT) preparePhase(block *types.Block, validators []*types.Validator
 func (pbft *PBFT) commitPhase(block *types.Block, validators []*types.Validator) (bool, error) {
         pbft.logger.LogConsensus("pbft", "commit_start", logrus.Fields{
                 "block_hash": block.Hash,
+                "trace_id":   block.TraceID,
                 "view":       pbft.currentView,
                 "round":      pbft.currentRound,
                 "timestamp":  time.Now().UTC(),
@@ -316,10 +372,13 @@ func (pbft *PBFT) commitPhase(block *types.Block, validators []*types.Validator)
         
         for _, validator := range validators {
                 // Skip byzantine validators
-                if pbft.isByzantineValidator(validator.Address) {
-                        pbft.logger.LogConsensus("pbft", "commit_byzantine_skip", logrus.Fields{
+                byzantine := pbft.isByzantineValidator(validator.Address)
+                pbft.votes.count(validator.Address, byzantine)
+                if byzantine {
+                        pbft.logger.LogConsensusVote("pbft", "commit_byzantine_skip", logrus.Fields{
                                 "validator":  validator.Address,
                                 "block_hash": block.Hash,
+                                "trace_id":   block.TraceID,
                                 "timestamp":  time.Now().UTC(),
                         })
                         continue
@@ -329,19 +388,28 @@ func (pbft *PBFT) commitPhase(block *types.Block, validators []*types.Validator)
                 vote := &Vote{
                         ValidatorAddress: validator.Address,
                         BlockHash:        block.Hash,
+                        TraceID:          block.TraceID,
                         VoteType:         "commit",
                         Round:            pbft.currentRound,
                         View:             pbft.currentView,
                         Signature:        fmt.Sprintf("commit_%s_%s", validator.Address, block.Hash),
                         Timestamp:        time.Now().Unix(),
                 }
+                if pbft.aggregateVotes {
+                        share, err := signVoteShare(pbft.voteKeys, validator, vote)
+                        if err != nil {
+                                return false, withReason(FailureInvalidSignature, err)
+                        }
+                        vote.Signature = share
+                }
                 
                 pbft.commitVotes[block.Hash][validator.Address] = vote
                 validVotes++
                 
-                pbft.logger.LogConsensus("pbft", "commit_vote_received", logrus.Fields{
+                pbft.logger.LogConsensusVote("pbft", "commit_vote_received", logrus.Fields{
                         "validator":      validator.Address,
                         "block_hash":     block.Hash,
+                        "trace_id":       block.TraceID,
                         "vote_count":     validVotes,
                         "required_votes": requiredVotes,
                         "timestamp":      time.Now().UTC(),
@@ -351,8 +419,15 @@ func (pbft *PBFT) commitPhase(block *types.Block, validators []*types.Validator)
         // Check if we have enough commit votes
         committed := validVotes >= requiredVotes
         
+        if committed {
+                if err := pbft.aggregatePhaseVotes(pbft.commitVotes[block.Hash], validators); err != nil {
+                        return false, err
+                }
+        }
+        
         pbft.logger.LogConsensus("pbft", "commit_completed", logrus.Fields{
                 "block_hash":     block.Hash,
+                "trace_id":       block.TraceID,
                 "committed":      committed,
                 "valid_votes":    validVotes,
                 "required_votes": requiredVotes,
@@ -362,6 +437,38 @@ func (pbft *PBFT) commitPhase(block *types.Block, validators []*types.Validator)
         return committed, nil
 }
 
+// aggregatePhaseVotes replaces a phase's votes with one aggregated signature
+// when aggregation is enabled, failing the phase if it does not verify. The
+// aggregate is kept in place of the votes it combines.
+func (pbft *PBFT) aggregatePhaseVotes(votes map[string]*Vote, validators []*types.Validator) error {
+        if !pbft.aggregateVotes {
+                return nil
+        }
+        
+        aggregated, err := aggregatePhase(votes, pbft.currentView, validators, pbft.voteKeys)
+        if err != nil {
+                return err
+        }
+        pbft.aggregates.put(aggregated)
+        for _, signer := range aggregated.Signers {
+                delete(votes, signer)
+        }
+        
+        saved := individualVoteBytes(aggregated) - aggregated.Size()
+        pbft.aggregatedSets++
+        pbft.bytesSaved += int64(saved)
+        
+        pbft.logger.LogConsensus("pbft", "votes_aggregated", logrus.Fields{
+                "block_hash":  aggregated.BlockHash,
+                "vote_type":   aggregated.VoteType,
+                "signers":     len(aggregated.Signers),
+                "bytes_saved": saved,
+                "timestamp":   time.Now().UTC(),
+        })
+        
+        return nil
+}
+
 // validateBlockStructure validates the basic structure of a block
 func (pbft *PBFT) validateBlockStructure(block *types.Block) error {
         if block.Hash == "" {
@@ -387,14 +494,10 @@ func (pbft *PBFT) validateBlockStructure(block *types.Block) error {
         return nil
 }
 
-// getPrimary returns the primary node for the given view
-func (pbft *PBFT) getPrimary(validators []*types.Validator, view int64) *types.Validator {
-        if len(validators) == 0 {
-                return nil
-        }
-        
-        primaryIndex := view % int64(len(validators))
-        return validators[primaryIndex]
+// getPrimary returns the primary node for the given view of the block
+// following prevHash
+func (pbft *PBFT) getPrimary(validators []*types.Validator, prevHash string, view int64) *types.Validator {
+        return SelectPrimaryVRF(validators, prevHash, view)
 }
 
 // getRequiredVoteCount calculates the required number of votes for consensus
@@ -426,6 +529,7 @@ func (pbft *PBFT) cleanupVotes(excludeBlockHash string) {
                         delete(pbft.commitVotes, blockHash)
                 }
         }
+        pbft.aggregates.keepOnly(excludeBlockHash)
         
         // Clean up old view change votes
         currentView := pbft.currentView
@@ -442,11 +546,16 @@ func (pbft *PBFT) ValidateBlock(block *types.Block, validators []*types.Validato
         
         pbft.logger.LogConsensus("pbft", "validate_block", logrus.Fields{
                 "block_hash":  block.Hash,
+                "trace_id":    block.TraceID,
                 "block_index": block.Index,
                 "validator":   block.Validator,
                 "timestamp":   startTime,
         })
         
+        if err := block.CheckLimits(pbft.config.Consensus.MaxTxPerBlock, pbft.config.Consensus.MaxBlockSize); err != nil {
+                return err
+        }
+        
         // Basic structural validation
         if err := pbft.validateBlockStructure(block); err != nil {
                 return fmt.Errorf("block structure validation failed: %w", err)
@@ -472,6 +581,7 @@ func (pbft *PBFT) ValidateBlock(block *types.Block, validators []*types.Validato
         
         pbft.logger.LogConsensus("pbft", "block_validated", logrus.Fields{
                 "block_hash":         block.Hash,
+                "trace_id":           block.TraceID,
                 "block_index":        block.Index,
                 "validation_duration": validationDuration.Milliseconds(),
                 "timestamp":          time.Now().UTC(),
@@ -483,11 +593,18 @@ func (pbft *PBFT) ValidateBlock(block *types.Block, validators []*types.Validato
 // SelectValidator selects a validator for the given round (primary selection)
 func (pbft *PBFT) SelectValidator(validators []*types.Validator, round int64) (*types.Validator, error) {
         if len(validators) == 0 {
-                return nil, fmt.Errorf("no validators available")
+                return nil, ErrNoValidators
         }
         
-        // In PBFT, the primary is selected based on the view
-        primary := pbft.getPrimary(validators, pbft.currentView)
+        pbft.mu.RLock()
+        defer pbft.mu.RUnlock()
+        
+        // In PBFT, the primary is drawn by stake from the view and last block
+        primary := pbft.getPrimary(validators, pbft.lastBlockHash, pbft.currentView)
+        if primary == nil {
+                // No validator carries any weight to draw
+                return nil, ErrNoValidators
+        }
         
         pbft.logger.LogConsensus("pbft", "validator_selected", logrus.Fields{
                 "primary":        primary.Address,
@@ -513,12 +630,12 @@ func (pbft *PBFT) GetConsensusState() *types.ConsensusState {
         pbft.state.Performance["uptime"] = time.Since(pbft.startTime).Seconds()
         
         // Count votes
-        prepareCount := 0
+        prepareCount := pbft.aggregates.signers("prepare")
         for _, votes := range pbft.prepareVotes {
                 prepareCount += len(votes)
         }
         
-        commitCount := 0
+        commitCount := pbft.aggregates.signers("commit")
         for _, votes := range pbft.commitVotes {
                 commitCount += len(votes)
         }
@@ -529,11 +646,16 @@ func (pbft *PBFT) GetConsensusState() *types.ConsensusState {
         return pbft.state
 }
 
-// UpdateValidators updates the validator set
+// UpdateValidators updates the validator set, refusing one that breaks the
+// Byzantine threshold when consensus.byzantine_check is "reject"
 func (pbft *PBFT) UpdateValidators(validators []*types.Validator) error {
         pbft.mu.Lock()
         defer pbft.mu.Unlock()
         
+        if err := CheckValidatorSet("pbft", pbft.byzantineCheck, pbft.byzantineNodes, validators, pbft.logger); err != nil {
+                return err
+        }
+        
         oldCount := len(pbft.state.Validators)
         pbft.state.Validators = validators
         pbft.totalNodes = len(validators)
@@ -567,6 +689,7 @@ func (pbft *PBFT) updateMetrics() {
         uptime := time.Since(pbft.startTime)
         
         pbft.metrics["algorithm"] = "pbft"
+        pbft.metrics["failure_reasons"] = pbft.failures.snapshot()
         pbft.metrics["node_id"] = pbft.nodeID
         pbft.metrics["current_view"] = pbft.currentView
         pbft.metrics["current_round"] = pbft.currentRound
@@ -578,12 +701,12 @@ func (pbft *PBFT) updateMetrics() {
         pbft.metrics["uptime_seconds"] = uptime.Seconds()
         
         // Count current votes
-        prepareCount := 0
+        prepareCount := pbft.aggregates.signers("prepare")
         for _, votes := range pbft.prepareVotes {
                 prepareCount += len(votes)
         }
         
-        commitCount := 0
+        commitCount := pbft.aggregates.signers("commit")
         for _, votes := range pbft.commitVotes {
                 commitCount += len(votes)
         }
@@ -596,9 +719,22 @@ func (pbft *PBFT) updateMetrics() {
         pbft.metrics["prepare_votes"] = prepareCount
         pbft.metrics["commit_votes"] = commitCount
         pbft.metrics["view_change_votes"] = viewChangeCount
+        pbft.metrics["bls_aggregation"] = pbft.aggregateVotes
+        pbft.metrics["aggregated_vote_sets"] = pbft.aggregatedSets
+        pbft.metrics["aggregation_bytes_saved"] = pbft.bytesSaved
         pbft.metrics["timestamp"] = time.Now().UTC()
 }
 
+// VoteAudit implements VoteAuditor
+func (pbft *PBFT) VoteAudit() VoteAudit {
+        return pbft.votes.audit()
+}
+
+// SetParticipationHook implements ParticipationRecorder
+func (pbft *PBFT) SetParticipationHook(hook func(address string)) {
+        pbft.votes.setHook(hook)
+}
+
 // Reset resets the consensus state
 func (pbft *PBFT) Reset() error {
         pbft.mu.Lock()
@@ -608,6 +744,11 @@ func (pbft *PBFT) Reset() error {
                 "timestamp": time.Now().UTC(),
         })
         
+        pbft.failures.reset()
+        pbft.votes.reset()
+        pbft.aggregatedSets = 0
+        pbft.bytesSaved = 0
+        
         pbft.state.Round = 0
         pbft.state.View = 0
         pbft.state.Phase = "prepare"
@@ -617,8 +758,10 @@ func (pbft *PBFT) Reset() error {
         pbft.state.Performance = make(map[string]float64)
         
         pbft.currentView = 0
+        pbft.lastBlockHash = ""
         pbft.currentRound = 0
         pbft.prepareVotes = make(map[string]map[string]*Vote)
+        pbft.aggregates = make(phaseAggregates)
         pbft.commitVotes = make(map[string]map[string]*Vote)
         pbft.viewChangeVotes = make(map[int64]map[string]*Vote)
         pbft.isPrimary = false
@@ -645,6 +788,7 @@ func (pbft *PBFT) consensusWorker() {
                         // Handle queued blocks (if needed)
                         pbft.logger.LogConsensus("pbft", "block_queued", logrus.Fields{
                                 "block_hash": block.Hash,
+                                "trace_id":   block.TraceID,
                                 "timestamp":  time.Now().UTC(),
                         })
                 }
@@ -685,6 +829,7 @@ func (pbft *PBFT) initiateViewChange() {
         
         // Clean up votes from previous view
         pbft.prepareVotes = make(map[string]map[string]*Vote)
+        pbft.aggregates = make(phaseAggregates)
         pbft.commitVotes = make(map[string]map[string]*Vote)
 }
 
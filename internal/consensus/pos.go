@@ -29,6 +29,13 @@ type ProofOfStake struct {
         currentEpoch     int64
         startTime        time.Time
         metrics          map[string]interface{}
+        failures         failureCounter
+}
+
+func init() {
+        Register("pos", func(cfg *config.Config, logger *utils.Logger) (Consensus, error) {
+                return NewProofOfStake(cfg, logger)
+        })
 }
 
 // NewProofOfStake creates a new Proof of Stake consensus instance
@@ -80,12 +87,23 @@ func NewProofOfStake(cfg *config.Config, logger *utils.Logger) (*ProofOfStake, e
 
 // ProcessBlock processes a block using Proof of Stake
 func (pos *ProofOfStake) ProcessBlock(block *types.Block, validators []*types.Validator) (bool, error) {
+        committed, err := pos.processBlock(block, validators)
+        pos.failures.record(committed, err)
+        return committed, err
+}
+
+// processBlock runs one round of consensus on a block
+func (pos *ProofOfStake) processBlock(block *types.Block, validators []*types.Validator) (bool, error) {
+        if err := requireValidators(validators); err != nil {
+                return false, err
+        }
         startTime := time.Now()
         pos.mu.Lock()
         defer pos.mu.Unlock()
         
         pos.logger.LogConsensus("pos", "process_block", logrus.Fields{
                 "block_hash":   block.Hash,
+                "trace_id":     block.TraceID,
                 "block_index":  block.Index,
                 "validator":    block.Validator,
                 "tx_count":     len(block.Transactions),
@@ -123,7 +141,7 @@ func (pos *ProofOfStake) ProcessBlock(block *types.Block, validators []*types.Va
                         "block_index":        block.Index,
                         "timestamp":          time.Now().UTC(),
                 })
-                return false, fmt.Errorf("block was not created by selected validator")
+                return false, withReason(FailureWrongValidator, fmt.Errorf("block was not created by selected validator"))
         }
         
         // Validate validator stake
@@ -134,7 +152,7 @@ func (pos *ProofOfStake) ProcessBlock(block *types.Block, validators []*types.Va
                         "stake":     selectedValidator.Stake,
                         "timestamp": time.Now().UTC(),
                 })
-                return false, fmt.Errorf("validator stake validation failed: %w", err)
+                return false, withReason(FailureInsufficientStake, fmt.Errorf("validator stake validation failed: %w", err))
         }
         validationDuration := time.Since(validationStart)
         
@@ -143,10 +161,11 @@ func (pos *ProofOfStake) ProcessBlock(block *types.Block, validators []*types.Va
         if err := pos.verifyBlockSignature(block, selectedValidator); err != nil {
                 pos.logger.LogError("consensus", "verify_signature", err, logrus.Fields{
                         "block_hash": block.Hash,
+                        "trace_id":   block.TraceID,
                         "validator":  selectedValidator.Address,
                         "timestamp":  time.Now().UTC(),
                 })
-                return false, fmt.Errorf("block signature verification failed: %w", err)
+                return false, withReason(FailureInvalidSignature, fmt.Errorf("block signature verification failed: %w", err))
         }
         signatureDuration := time.Since(signatureStart)
         
@@ -170,6 +189,7 @@ func (pos *ProofOfStake) ProcessBlock(block *types.Block, validators []*types.Va
         
         pos.logger.LogConsensus("pos", "block_processed", logrus.Fields{
                 "block_hash":          block.Hash,
+                "trace_id":            block.TraceID,
                 "block_index":         block.Index,
                 "selected_validator":  selectedValidator.Address,
                 "validator_stake":     selectedValidator.Stake,
@@ -188,7 +208,7 @@ func (pos *ProofOfStake) ProcessBlock(block *types.Block, validators []*types.Va
 // selectValidatorByStake selects a validator based on stake weight
 func (pos *ProofOfStake) selectValidatorByStake(validators []*types.Validator, round int64) (*types.Validator, error) {
         if len(validators) == 0 {
-                return nil, fmt.Errorf("no validators available")
+                return nil, ErrNoValidators
         }
         
         // Filter active validators with sufficient stake
@@ -283,6 +303,7 @@ func (pos *ProofOfStake) verifyBlockSignature(block *types.Block, validator *typ
                 // Allow blocks without signature verification if no public key
                 pos.logger.LogConsensus("pos", "signature_skip_no_pubkey", logrus.Fields{
                         "block_hash": block.Hash,
+                        "trace_id":   block.TraceID,
                         "validator":  validator.Address,
                         "timestamp":  time.Now().UTC(),
                 })
@@ -294,6 +315,7 @@ func (pos *ProofOfStake) verifyBlockSignature(block *types.Block, validator *typ
         
         pos.logger.LogConsensus("pos", "signature_verified", logrus.Fields{
                 "block_hash": block.Hash,
+                "trace_id":   block.TraceID,
                 "validator":  validator.Address,
                 "signature":  block.Signature[:utils.MinInt(16, len(block.Signature))],
                 "timestamp":  time.Now().UTC(),
@@ -343,11 +365,16 @@ func (pos *ProofOfStake) ValidateBlock(block *types.Block, validators []*types.V
         
         pos.logger.LogConsensus("pos", "validate_block", logrus.Fields{
                 "block_hash":  block.Hash,
+                "trace_id":    block.TraceID,
                 "block_index": block.Index,
                 "validator":   block.Validator,
                 "timestamp":   startTime,
         })
         
+        if err := block.CheckLimits(pos.config.Consensus.MaxTxPerBlock, pos.config.Consensus.MaxBlockSize); err != nil {
+                return err
+        }
+        
         // Find the validator who created this block
         var blockValidator *types.Validator
         for _, v := range validators {
@@ -386,6 +413,7 @@ func (pos *ProofOfStake) ValidateBlock(block *types.Block, validators []*types.V
         
         pos.logger.LogConsensus("pos", "block_validated", logrus.Fields{
                 "block_hash":         block.Hash,
+                "trace_id":           block.TraceID,
                 "block_index":        block.Index,
                 "validator":          block.Validator,
                 "validator_stake":    blockValidator.Stake,
@@ -455,6 +483,7 @@ func (pos *ProofOfStake) updateMetrics() {
         uptime := time.Since(pos.startTime)
         
         pos.metrics["algorithm"] = "pos"
+        pos.metrics["failure_reasons"] = pos.failures.snapshot()
         pos.metrics["min_stake"] = pos.minStake
         pos.metrics["stake_ratio"] = pos.stakeRatio
         pos.metrics["total_stake"] = pos.totalStake
@@ -495,6 +524,8 @@ func (pos *ProofOfStake) Reset() error {
                 "timestamp": time.Now().UTC(),
         })
         
+        pos.failures.reset()
+        
         pos.state.Round = 0
         pos.state.View = 0
         pos.state.Phase = "selection"
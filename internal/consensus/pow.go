@@ -26,6 +26,13 @@ type ProofOfWork struct {
         blocksFound int64
         startTime   time.Time
         metrics     map[string]interface{}
+        failures    failureCounter
+}
+
+func init() {
+        Register("pow", func(cfg *config.Config, logger *utils.Logger) (Consensus, error) {
+                return NewProofOfWork(cfg, logger)
+        })
 }
 
 // NewProofOfWork creates a new Proof of Work consensus instance
@@ -69,12 +76,23 @@ func NewProofOfWork(cfg *config.Config, logger *utils.Logger) (*ProofOfWork, err
 
 // ProcessBlock processes a block using Proof of Work
 func (pow *ProofOfWork) ProcessBlock(block *types.Block, validators []*types.Validator) (bool, error) {
+        committed, err := pow.processBlock(block, validators)
+        pow.failures.record(committed, err)
+        return committed, err
+}
+
+// processBlock runs one round of consensus on a block
+func (pow *ProofOfWork) processBlock(block *types.Block, validators []*types.Validator) (bool, error) {
+        if err := requireValidators(validators); err != nil {
+                return false, err
+        }
         startTime := time.Now()
         pow.mu.Lock()
         defer pow.mu.Unlock()
         
         pow.logger.LogConsensus("pow", "process_block", logrus.Fields{
                 "block_hash":   block.Hash,
+                "trace_id":     block.TraceID,
                 "block_index":  block.Index,
                 "difficulty":   pow.difficulty,
                 "validator":    block.Validator,
@@ -95,14 +113,16 @@ func (pow *ProofOfWork) ProcessBlock(block *types.Block, validators []*types.Val
         if err != nil {
                 pow.logger.LogError("consensus", "mine_block", err, logrus.Fields{
                         "block_hash": block.Hash,
+                        "trace_id":   block.TraceID,
                         "timestamp":  time.Now().UTC(),
                 })
-                return false, fmt.Errorf("mining failed: %w", err)
+                return false, withReason(FailureMiningFailed, fmt.Errorf("mining failed: %w", err))
         }
         
         if !success {
                 pow.logger.LogConsensus("pow", "mining_failed", logrus.Fields{
                         "block_hash":      block.Hash,
+                        "trace_id":        block.TraceID,
                         "hash_attempts":   hashAttempts,
                         "mining_duration": miningDuration.Milliseconds(),
                         "difficulty":      pow.difficulty,
@@ -132,6 +152,7 @@ func (pow *ProofOfWork) ProcessBlock(block *types.Block, validators []*types.Val
         
         pow.logger.LogConsensus("pow", "block_processed", logrus.Fields{
                 "block_hash":       block.Hash,
+                "trace_id":         block.TraceID,
                 "block_index":      block.Index,
                 "nonce":            block.Nonce,
                 "hash_attempts":    hashAttempts,
@@ -206,6 +227,7 @@ func (pow *ProofOfWork) mineBlock(block *types.Block) (bool, int64, error) {
         
         pow.logger.LogConsensus("pow", "mining_timeout", logrus.Fields{
                 "block_hash":      block.Hash,
+                "trace_id":        block.TraceID,
                 "max_attempts":    maxAttempts,
                 "final_nonce":     block.Nonce,
                 "difficulty":      pow.difficulty,
@@ -233,12 +255,17 @@ func (pow *ProofOfWork) ValidateBlock(block *types.Block, validators []*types.Va
         
         pow.logger.LogConsensus("pow", "validate_block", logrus.Fields{
                 "block_hash":  block.Hash,
+                "trace_id":    block.TraceID,
                 "block_index": block.Index,
                 "difficulty":  block.Difficulty,
                 "nonce":       block.Nonce,
                 "timestamp":   startTime,
         })
         
+        if err := block.CheckLimits(pow.config.Consensus.MaxTxPerBlock, pow.config.Consensus.MaxBlockSize); err != nil {
+                return err
+        }
+        
         // Check if block meets difficulty requirement
         target := strings.Repeat("0", pow.difficulty)
         if !strings.HasPrefix(block.Hash, target) {
@@ -263,6 +290,7 @@ func (pow *ProofOfWork) ValidateBlock(block *types.Block, validators []*types.Va
         
         pow.logger.LogConsensus("pow", "block_validated", logrus.Fields{
                 "block_hash":         block.Hash,
+                "trace_id":           block.TraceID,
                 "block_index":        block.Index,
                 "validation_duration": validationDuration.Milliseconds(),
                 "timestamp":          time.Now().UTC(),
@@ -275,16 +303,7 @@ func (pow *ProofOfWork) ValidateBlock(block *types.Block, validators []*types.Va
 func (pow *ProofOfWork) SelectValidator(validators []*types.Validator, round int64) (*types.Validator, error) {
         // In PoW, any validator can be a miner
         if len(validators) == 0 {
-                return &types.Validator{
-                        Address:    "pow-miner",
-                        PublicKey:  "",
-                        Stake:      0,
-                        Power:      1.0,
-                        LastActive: time.Now(),
-                        ShardID:    0,
-                        Status:     "active",
-                        Reputation: 1.0,
-                }, nil
+                return nil, ErrNoValidators
         }
         
         // Select validator based on round (round-robin for simplicity)
@@ -351,6 +370,7 @@ func (pow *ProofOfWork) updateMetrics() {
         uptime := time.Since(pow.startTime)
         
         pow.metrics["algorithm"] = "pow"
+        pow.metrics["failure_reasons"] = pow.failures.snapshot()
         pow.metrics["difficulty"] = pow.difficulty
         pow.metrics["hash_rate"] = pow.hashRate
         pow.metrics["total_hashes"] = pow.totalHashes
@@ -379,6 +399,8 @@ func (pow *ProofOfWork) Reset() error {
                 "timestamp": time.Now().UTC(),
         })
         
+        pow.failures.reset()
+        
         pow.state.Round = 0
         pow.state.View = 0
         pow.state.Phase = "mining"
@@ -1,6 +1,7 @@
 package consensus
 
 import (
+        "errors"
         "fmt"
         "lscc-blockchain/config"
         "lscc-blockchain/internal/utils"
@@ -11,10 +12,15 @@ import (
         "github.com/sirupsen/logrus"
 )
 
+// ErrCheckpointNotAhead is returned by ForceCheckpoint for a sequence at or
+// below the last stable checkpoint
+var ErrCheckpointNotAhead = errors.New("sequence is not past the last checkpoint")
+
 // PracticalPBFT implements an enhanced Practical Byzantine Fault Tolerance consensus algorithm
 type PracticalPBFT struct {
         config             *config.Config
         logger             *utils.Logger
+        clock              utils.Clock
         nodeID             string
         state              *types.ConsensusState
         mu                 sync.RWMutex
@@ -25,8 +31,12 @@ type PracticalPBFT struct {
         viewChangeVotes    map[int64]map[string]*Vote  // view -> validatorAddress -> vote
         checkpointVotes    map[int64]map[string]*Vote  // sequence -> validatorAddress -> vote
         isPrimary          bool
+        lastBlockHash      string // last committed block, seeds primary selection
+        failures           failureCounter
+        votes              voteCounter
         viewTimeout        time.Duration
         byzantineNodes     int
+        byzantineCheck     string // what UpdateValidators does with a set too small for byzantineNodes
         totalNodes         int
         startTime          time.Time
         metrics            map[string]interface{}
@@ -38,19 +48,48 @@ type PracticalPBFT struct {
         watermarkHigh      int64
         watermarkLow       int64
         windowSize         int64
-        messageLog         map[string]*ConsensusMessage
+        lastCommitted      int64     // sequence of the last committed block
+        rejectedSince      time.Time // first rejection above the window since it last moved, zero if none
+        highestRejected    int64     // highest sequence rejected above the window
+        windowStalls       int64     // times the window was found stuck
+        aggregateVotes     bool            // combine each phase's votes into one signature
+        aggregatedSets     int64           // phases carried by an aggregated signature
+        bytesSaved         int64           // vote bytes saved by aggregation
+        aggregates         phaseAggregates // aggregates standing in for the votes they combine
+        voteKeys           *voteKeyring    // signing keys of the validators whose votes are simulated
+        messageLog         *ppbftMessageLog
+        messagesPruned     int64 // messages dropped from messageLog below the low watermark
+        retention          voteRetention // caps the votes kept across rounds
         performanceMetrics map[string]time.Duration
+        events             *EventLog // typed event history, nil when not recorded
+}
+
+func init() {
+        Register("ppbft", func(cfg *config.Config, logger *utils.Logger) (Consensus, error) {
+                return NewPracticalPBFT(cfg, logger)
+        })
 }
 
 // NewPracticalPBFT creates a new Practical PBFT consensus instance with optimizations
 func NewPracticalPBFT(cfg *config.Config, logger *utils.Logger) (*PracticalPBFT, error) {
-        startTime := time.Now()
+        return NewPracticalPBFTWithClock(cfg, logger, utils.SystemClock)
+}
+
+// NewPracticalPBFTWithClock creates a Practical PBFT instance whose
+// timestamps, view timeouts and background workers all run on clock
+func NewPracticalPBFTWithClock(cfg *config.Config, logger *utils.Logger, clock utils.Clock) (*PracticalPBFT, error) {
+        startTime := clock.Now()
+        
+        checkpointInterval := cfg.Consensus.PPBFTCheckpointInterval
+        if checkpointInterval <= 0 {
+                checkpointInterval = 10 // Configs built without LoadConfig skip validation
+        }
         
         logger.LogConsensus("ppbft", "initialize", logrus.Fields{
                 "node_id":           cfg.Node.ID,
                 "byzantine":         cfg.Consensus.Byzantine,
                 "view_timeout":      cfg.Consensus.ViewTimeout,
-                "checkpoint_interval": 10,
+                "checkpoint_interval": checkpointInterval,
                 "window_size":       100,
                 "timestamp":         startTime,
         })
@@ -58,6 +97,7 @@ func NewPracticalPBFT(cfg *config.Config, logger *utils.Logger) (*PracticalPBFT,
         ppbft := &PracticalPBFT{
                 config:             cfg,
                 logger:             logger,
+                clock:              clock,
                 nodeID:             cfg.Node.ID,
                 currentView:        0,
                 currentRound:       0,
@@ -68,17 +108,22 @@ func NewPracticalPBFT(cfg *config.Config, logger *utils.Logger) (*PracticalPBFT,
                 isPrimary:          false,
                 viewTimeout:        time.Duration(cfg.Consensus.ViewTimeout) * time.Second,
                 byzantineNodes:     cfg.Consensus.Byzantine,
+                byzantineCheck:     cfg.Consensus.ByzantineCheck,
                 startTime:          startTime,
                 metrics:            make(map[string]interface{}),
                 blockQueue:         make(chan *types.Block, 100),
                 stopChan:           make(chan struct{}),
                 phase:              "prepare",
+                aggregateVotes:     cfg.Consensus.UseBLSAggregation,
+                aggregates:         make(phaseAggregates),
+                voteKeys:           newVoteKeyring(),
                 lastCheckpoint:     0,
-                checkpointInterval: 10,
+                checkpointInterval: checkpointInterval,
                 watermarkHigh:      100,
                 watermarkLow:       0,
                 windowSize:         100,
-                messageLog:         make(map[string]*ConsensusMessage),
+                messageLog:         newPPBFTMessageLog(),
+                retention:          voteRetention{limit: cfg.Consensus.MaxRetainedVotes},
                 performanceMetrics: make(map[string]time.Duration),
                 state: &types.ConsensusState{
                         Algorithm:    "ppbft",
@@ -107,7 +152,7 @@ func NewPracticalPBFT(cfg *config.Config, logger *utils.Logger) (*PracticalPBFT,
                 "byzantine_nodes":     ppbft.byzantineNodes,
                 "checkpoint_interval": ppbft.checkpointInterval,
                 "window_size":         ppbft.windowSize,
-                "timestamp":           time.Now().UTC(),
+                "timestamp":           clock.Now().UTC(),
         })
         
         return ppbft, nil
@@ -115,12 +160,26 @@ func NewPracticalPBFT(cfg *config.Config, logger *utils.Logger) (*PracticalPBFT,
 
 // ProcessBlock processes a block using Practical PBFT consensus with optimizations
 func (ppbft *PracticalPBFT) ProcessBlock(block *types.Block, validators []*types.Validator) (bool, error) {
-        startTime := time.Now()
+        committed, err := ppbft.processBlock(block, validators)
+        ppbft.failures.record(committed, err)
+        return committed, err
+}
+
+// processBlock runs one round of consensus on a block
+func (ppbft *PracticalPBFT) processBlock(block *types.Block, validators []*types.Validator) (bool, error) {
+        if err := requireValidators(validators); err != nil {
+                return false, err
+        }
+        startTime := ppbft.clock.Now()
         ppbft.mu.Lock()
-        defer ppbft.mu.Unlock()
+        defer func() {
+                ppbft.boundVotes()
+                ppbft.mu.Unlock()
+        }()
         
         ppbft.logger.LogConsensus("ppbft", "process_block", logrus.Fields{
                 "block_hash":      block.Hash,
+                "trace_id":        block.TraceID,
                 "block_index":     block.Index,
                 "validator":       block.Validator,
                 "current_view":    ppbft.currentView,
@@ -134,14 +193,17 @@ func (ppbft *PracticalPBFT) ProcessBlock(block *types.Block, validators []*types
         
         // Check if block is within processing window
         if !ppbft.isWithinWindow(block.Index) {
+                if block.Index > ppbft.watermarkHigh {
+                        ppbft.noteWindowRejection(block.Index)
+                }
                 ppbft.logger.LogConsensus("ppbft", "block_outside_window", logrus.Fields{
                         "block_index":    block.Index,
                         "watermark_low":  ppbft.watermarkLow,
                         "watermark_high": ppbft.watermarkHigh,
-                        "timestamp":      time.Now().UTC(),
+                        "timestamp":      ppbft.clock.Now().UTC(),
                 })
-                return false, fmt.Errorf("block sequence %d is outside processing window [%d, %d]", 
-                        block.Index, ppbft.watermarkLow, ppbft.watermarkHigh)
+                return false, withReason(FailureOutsideWindow, fmt.Errorf("block sequence %d is outside processing window [%d, %d]", 
+                        block.Index, ppbft.watermarkLow, ppbft.watermarkHigh))
         }
         
         // Update consensus state
@@ -152,7 +214,7 @@ func (ppbft *PracticalPBFT) ProcessBlock(block *types.Block, validators []*types
         ppbft.totalNodes = len(validators)
         
         // Determine if this node is the primary for current view
-        primary := ppbft.getPrimary(validators, ppbft.currentView)
+        primary := ppbft.getPrimary(validators, block.PreviousHash, ppbft.currentView)
         ppbft.isPrimary = (primary != nil && primary.Address == ppbft.nodeID)
         ppbft.state.Leader = ""
         if primary != nil {
@@ -160,32 +222,34 @@ func (ppbft *PracticalPBFT) ProcessBlock(block *types.Block, validators []*types
         }
         
         // Enhanced three-phase protocol with performance optimizations
-        phaseStart := time.Now()
+        phaseStart := ppbft.clock.Now()
         
         // Phase 1: Pre-prepare with batching optimization
         if ppbft.isPrimary {
                 if err := ppbft.enhancedPrePreparePhase(block, validators); err != nil {
                         ppbft.logger.LogError("consensus", "enhanced_pre_prepare", err, logrus.Fields{
                                 "block_hash": block.Hash,
-                                "timestamp":  time.Now().UTC(),
+                                "trace_id":   block.TraceID,
+                                "timestamp":  ppbft.clock.Now().UTC(),
                         })
                         return false, fmt.Errorf("enhanced pre-prepare phase failed: %w", err)
                 }
         }
         
-        prepareStart := time.Now()
+        prepareStart := ppbft.clock.Now()
         ppbft.performanceMetrics["pre_prepare"] = prepareStart.Sub(phaseStart)
         
         // Phase 2: Prepare with early voting optimization
         if err := ppbft.enhancedPreparePhase(block, validators); err != nil {
                 ppbft.logger.LogError("consensus", "enhanced_prepare", err, logrus.Fields{
                         "block_hash": block.Hash,
-                        "timestamp":  time.Now().UTC(),
+                        "trace_id":   block.TraceID,
+                        "timestamp":  ppbft.clock.Now().UTC(),
                 })
                 return false, fmt.Errorf("enhanced prepare phase failed: %w", err)
         }
         
-        commitStart := time.Now()
+        commitStart := ppbft.clock.Now()
         ppbft.performanceMetrics["prepare"] = commitStart.Sub(prepareStart)
         
         // Phase 3: Commit with fast path optimization
@@ -193,33 +257,38 @@ func (ppbft *PracticalPBFT) ProcessBlock(block *types.Block, validators []*types
         if err != nil {
                 ppbft.logger.LogError("consensus", "enhanced_commit", err, logrus.Fields{
                         "block_hash": block.Hash,
-                        "timestamp":  time.Now().UTC(),
+                        "trace_id":   block.TraceID,
+                        "timestamp":  ppbft.clock.Now().UTC(),
                 })
                 return false, fmt.Errorf("enhanced commit phase failed: %w", err)
         }
         
-        commitEnd := time.Now()
+        commitEnd := ppbft.clock.Now()
         ppbft.performanceMetrics["commit"] = commitEnd.Sub(commitStart)
         
         // Check if checkpoint is needed
         if committed && ppbft.shouldCreateCheckpoint(block.Index) {
-                checkpointStart := time.Now()
+                checkpointStart := ppbft.clock.Now()
                 if err := ppbft.createCheckpoint(block.Index, validators); err != nil {
                         ppbft.logger.LogError("consensus", "checkpoint", err, logrus.Fields{
                                 "block_index": block.Index,
-                                "timestamp":   time.Now().UTC(),
+                                "timestamp":   ppbft.clock.Now().UTC(),
                         })
                 }
-                ppbft.performanceMetrics["checkpoint"] = time.Since(checkpointStart)
+                ppbft.performanceMetrics["checkpoint"] = ppbft.clock.Since(checkpointStart)
         }
         
-        totalDuration := time.Since(startTime)
+        totalDuration := ppbft.clock.Since(startTime)
         
         if committed {
                 ppbft.currentRound++
+                ppbft.lastBlockHash = block.Hash
+                if block.Index > ppbft.lastCommitted {
+                        ppbft.lastCommitted = block.Index
+                }
                 ppbft.phase = "prepare" // Reset for next round
                 ppbft.state.Phase = "completed"
-                ppbft.state.LastDecision = time.Now()
+                ppbft.state.LastDecision = ppbft.clock.Now()
                 
                 // Clean up old votes and messages
                 ppbft.cleanupOldData(block.Hash, block.Index)
@@ -242,6 +311,7 @@ func (ppbft *PracticalPBFT) ProcessBlock(block *types.Block, validators []*types
         
         ppbft.logger.LogConsensus("ppbft", "block_processed", logrus.Fields{
                 "block_hash":           block.Hash,
+                "trace_id":             block.TraceID,
                 "block_index":          block.Index,
                 "committed":            committed,
                 "is_primary":           ppbft.isPrimary,
@@ -256,7 +326,7 @@ func (ppbft *PracticalPBFT) ProcessBlock(block *types.Block, validators []*types
                 "watermark_low":        ppbft.watermarkLow,
                 "watermark_high":       ppbft.watermarkHigh,
                 "last_checkpoint":      ppbft.lastCheckpoint,
-                "timestamp":            time.Now().UTC(),
+                "timestamp":            ppbft.clock.Now().UTC(),
         })
         
         return committed, nil
@@ -266,15 +336,16 @@ func (ppbft *PracticalPBFT) ProcessBlock(block *types.Block, validators []*types
 func (ppbft *PracticalPBFT) enhancedPrePreparePhase(block *types.Block, validators []*types.Validator) error {
         ppbft.logger.LogConsensus("ppbft", "enhanced_pre_prepare_start", logrus.Fields{
                 "block_hash":   block.Hash,
+                "trace_id":     block.TraceID,
                 "view":         ppbft.currentView,
                 "round":        ppbft.currentRound,
                 "tx_count":     len(block.Transactions),
-                "timestamp":    time.Now().UTC(),
+                "timestamp":    ppbft.clock.Now().UTC(),
         })
         
         // Enhanced validation with transaction batching optimization
         if err := ppbft.validateBlockWithBatching(block); err != nil {
-                return fmt.Errorf("enhanced block validation failed: %w", err)
+                return withReason(FailureInvalidBlock, fmt.Errorf("enhanced block validation failed: %w", err))
         }
         
         // Create and log pre-prepare message
@@ -286,7 +357,7 @@ func (ppbft *PracticalPBFT) enhancedPrePreparePhase(block *types.Block, validato
                 BlockHash: block.Hash,
                 Data:      block,
                 Signature: fmt.Sprintf("preprepare_%s_%s", ppbft.nodeID, block.Hash),
-                Timestamp: time.Now().Unix(),
+                Timestamp: ppbft.clock.Now().Unix(),
                 Metadata: map[string]interface{}{
                         "tx_count":      len(block.Transactions),
                         "block_size":    block.Size,
@@ -295,14 +366,15 @@ func (ppbft *PracticalPBFT) enhancedPrePreparePhase(block *types.Block, validato
                 },
         }
         
-        ppbft.messageLog[fmt.Sprintf("preprepare_%d_%d", ppbft.currentView, ppbft.currentRound)] = prePrepareMsg
+        ppbft.messageLog.add(block.Index, fmt.Sprintf("preprepare_%d_%d", ppbft.currentView, ppbft.currentRound), prePrepareMsg)
         
         ppbft.logger.LogConsensus("ppbft", "enhanced_pre_prepare_broadcast", logrus.Fields{
                 "block_hash":       block.Hash,
+                "trace_id":         block.TraceID,
                 "validator_count":  len(validators),
                 "message_size":     len(block.Transactions),
                 "batching_enabled": true,
-                "timestamp":        time.Now().UTC(),
+                "timestamp":        ppbft.clock.Now().UTC(),
         })
         
         ppbft.phase = "prepare"
@@ -313,9 +385,10 @@ func (ppbft *PracticalPBFT) enhancedPrePreparePhase(block *types.Block, validato
 func (ppbft *PracticalPBFT) enhancedPreparePhase(block *types.Block, validators []*types.Validator) error {
         ppbft.logger.LogConsensus("ppbft", "enhanced_prepare_start", logrus.Fields{
                 "block_hash": block.Hash,
+                "trace_id":   block.TraceID,
                 "view":       ppbft.currentView,
                 "round":      ppbft.currentRound,
-                "timestamp":  time.Now().UTC(),
+                "timestamp":  ppbft.clock.Now().UTC(),
         })
         
         // Initialize prepare votes for this block if not exists
@@ -330,12 +403,15 @@ func (ppbft *PracticalPBFT) enhancedPreparePhase(block *types.Block, validators
         
         for _, validator := range validators {
                 // Skip byzantine validators with improved detection
-                if ppbft.isEnhancedByzantineValidator(validator.Address, block.Hash) {
-                        ppbft.logger.LogConsensus("ppbft", "enhanced_prepare_byzantine_skip", logrus.Fields{
+                byzantine := ppbft.isEnhancedByzantineValidator(validator.Address, block.Hash)
+                ppbft.votes.count(validator.Address, byzantine)
+                if byzantine {
+                        ppbft.logger.LogConsensusVote("ppbft", "enhanced_prepare_byzantine_skip", logrus.Fields{
                                 "validator":  validator.Address,
                                 "block_hash": block.Hash,
+                                "trace_id":   block.TraceID,
                                 "reputation": validator.Reputation,
-                                "timestamp":  time.Now().UTC(),
+                                "timestamp":  ppbft.clock.Now().UTC(),
                         })
                         continue
                 }
@@ -344,39 +420,50 @@ func (ppbft *PracticalPBFT) enhancedPreparePhase(block *types.Block, validators
                 vote := &Vote{
                         ValidatorAddress: validator.Address,
                         BlockHash:        block.Hash,
+                        TraceID:          block.TraceID,
                         VoteType:         "prepare",
                         Round:            ppbft.currentRound,
                         View:             ppbft.currentView,
-                        Signature:        fmt.Sprintf("prepare_%s_%s_%d", validator.Address, block.Hash, time.Now().UnixNano()),
-                        Timestamp:        time.Now().Unix(),
+                        Signature:        fmt.Sprintf("prepare_%s_%s_%d", validator.Address, block.Hash, ppbft.clock.Now().UnixNano()),
+                        Timestamp:        ppbft.clock.Now().Unix(),
                         Metadata: map[string]interface{}{
                                 "validator_stake": validator.Stake,
                                 "validator_power": validator.Power,
                                 "optimization":    "early_voting",
                         },
                 }
+                if ppbft.aggregateVotes {
+                        share, err := signVoteShare(ppbft.voteKeys, validator, vote)
+                        if err != nil {
+                                return withReason(FailureInvalidSignature, err)
+                        }
+                        vote.Signature = share
+                }
                 
                 ppbft.prepareVotes[block.Hash][validator.Address] = vote
                 validVotes++
+                ppbft.events.Record(voteEvent("ppbft", vote))
                 
-                ppbft.logger.LogConsensus("ppbft", "enhanced_prepare_vote_received", logrus.Fields{
+                ppbft.logger.LogConsensusVote("ppbft", "enhanced_prepare_vote_received", logrus.Fields{
                         "validator":               validator.Address,
                         "block_hash":              block.Hash,
+                        "trace_id":                block.TraceID,
                         "vote_count":              validVotes,
                         "required_votes":          requiredVotes,
                         "early_termination_threshold": earlyTerminationThreshold,
                         "validator_stake":         validator.Stake,
-                        "timestamp":               time.Now().UTC(),
+                        "timestamp":               ppbft.clock.Now().UTC(),
                 })
                 
                 // Early termination optimization
                 if validVotes >= earlyTerminationThreshold {
                         ppbft.logger.LogConsensus("ppbft", "enhanced_prepare_early_termination", logrus.Fields{
                                 "block_hash":   block.Hash,
+                                "trace_id":     block.TraceID,
                                 "valid_votes":  validVotes,
                                 "threshold":    earlyTerminationThreshold,
                                 "optimization": "early_termination",
-                                "timestamp":    time.Now().UTC(),
+                                "timestamp":    ppbft.clock.Now().UTC(),
                         })
                         break
                 }
@@ -384,17 +471,31 @@ func (ppbft *PracticalPBFT) enhancedPreparePhase(block *types.Block, validators
         
         // Check if we have enough prepare votes
         if validVotes < requiredVotes {
-                return fmt.Errorf("insufficient prepare votes: got %d, required %d", validVotes, requiredVotes)
+                return withReason(FailureInsufficientVotes, fmt.Errorf("insufficient prepare votes: got %d, required %d", validVotes, requiredVotes))
+        }
+        
+        if err := ppbft.aggregatePhaseVotes(ppbft.prepareVotes[block.Hash], validators); err != nil {
+                return err
         }
         
         ppbft.phase = "commit"
         
         ppbft.logger.LogConsensus("ppbft", "enhanced_prepare_completed", logrus.Fields{
                 "block_hash":       block.Hash,
+                "trace_id":         block.TraceID,
                 "valid_votes":      validVotes,
                 "required_votes":   requiredVotes,
                 "early_termination": validVotes >= earlyTerminationThreshold,
-                "timestamp":        time.Now().UTC(),
+                "timestamp":        ppbft.clock.Now().UTC(),
+        })
+        ppbft.events.Record(ConsensusEvent{
+                Type:      EventPhaseCompleted,
+                Algorithm: "ppbft",
+                BlockHash: block.Hash,
+                Round:     ppbft.currentRound,
+                View:      ppbft.currentView,
+                Phase:     "prepare",
+                Details:   map[string]interface{}{"valid_votes": validVotes},
         })
         
         return nil
@@ -404,9 +505,10 @@ func (ppbft *PracticalPBFT) enhancedPreparePhase(block *types.Block, validators
 func (ppbft *PracticalPBFT) enhancedCommitPhase(block *types.Block, validators []*types.Validator) (bool, error) {
         ppbft.logger.LogConsensus("ppbft", "enhanced_commit_start", logrus.Fields{
                 "block_hash": block.Hash,
+                "trace_id":   block.TraceID,
                 "view":       ppbft.currentView,
                 "round":      ppbft.currentRound,
-                "timestamp":  time.Now().UTC(),
+                "timestamp":  ppbft.clock.Now().UTC(),
         })
         
         // Initialize commit votes for this block if not exists
@@ -427,11 +529,14 @@ func (ppbft *PracticalPBFT) enhancedCommitPhase(block *types.Block, validators [
         
         for _, validator := range validators {
                 // Skip byzantine validators
-                if ppbft.isEnhancedByzantineValidator(validator.Address, block.Hash) {
-                        ppbft.logger.LogConsensus("ppbft", "enhanced_commit_byzantine_skip", logrus.Fields{
+                byzantine := ppbft.isEnhancedByzantineValidator(validator.Address, block.Hash)
+                ppbft.votes.count(validator.Address, byzantine)
+                if byzantine {
+                        ppbft.logger.LogConsensusVote("ppbft", "enhanced_commit_byzantine_skip", logrus.Fields{
                                 "validator":  validator.Address,
                                 "block_hash": block.Hash,
-                                "timestamp":  time.Now().UTC(),
+                                "trace_id":   block.TraceID,
+                                "timestamp":  ppbft.clock.Now().UTC(),
                         })
                         continue
                 }
@@ -440,35 +545,45 @@ func (ppbft *PracticalPBFT) enhancedCommitPhase(block *types.Block, validators [
                 vote := &Vote{
                         ValidatorAddress: validator.Address,
                         BlockHash:        block.Hash,
+                        TraceID:          block.TraceID,
                         VoteType:         "commit",
                         Round:            ppbft.currentRound,
                         View:             ppbft.currentView,
-                        Signature:        fmt.Sprintf("commit_%s_%s_%d", validator.Address, block.Hash, time.Now().UnixNano()),
-                        Timestamp:        time.Now().Unix(),
+                        Signature:        fmt.Sprintf("commit_%s_%s_%d", validator.Address, block.Hash, ppbft.clock.Now().UnixNano()),
+                        Timestamp:        ppbft.clock.Now().Unix(),
                         Metadata: map[string]interface{}{
                                 "validator_stake": validator.Stake,
                                 "stake_ratio":     float64(validator.Stake) / float64(totalStake),
                                 "optimization":    "fast_path",
                         },
                 }
+                if ppbft.aggregateVotes {
+                        share, err := signVoteShare(ppbft.voteKeys, validator, vote)
+                        if err != nil {
+                                return false, withReason(FailureInvalidSignature, err)
+                        }
+                        vote.Signature = share
+                }
                 
                 ppbft.commitVotes[block.Hash][validator.Address] = vote
                 validVotes++
+                ppbft.events.Record(voteEvent("ppbft", vote))
                 
                 // Count high-stake validators for fast path
                 if validator.Stake > totalStake/int64(len(validators)) {
                         highStakeVotes++
                 }
                 
-                ppbft.logger.LogConsensus("ppbft", "enhanced_commit_vote_received", logrus.Fields{
+                ppbft.logger.LogConsensusVote("ppbft", "enhanced_commit_vote_received", logrus.Fields{
                         "validator":       validator.Address,
                         "block_hash":      block.Hash,
+                        "trace_id":        block.TraceID,
                         "vote_count":      validVotes,
                         "required_votes":  requiredVotes,
                         "high_stake_votes": highStakeVotes,
                         "validator_stake": validator.Stake,
                         "stake_ratio":     float64(validator.Stake) / float64(totalStake),
-                        "timestamp":       time.Now().UTC(),
+                        "timestamp":       ppbft.clock.Now().UTC(),
                 })
         }
         
@@ -476,20 +591,72 @@ func (ppbft *PracticalPBFT) enhancedCommitPhase(block *types.Block, validators [
         committed := validVotes >= requiredVotes
         fastPath := highStakeVotes >= (len(validators)*2)/3 // Fast path if 2/3 of high-stake validators commit
         
+        if committed {
+                if err := ppbft.aggregatePhaseVotes(ppbft.commitVotes[block.Hash], validators); err != nil {
+                        return false, err
+                }
+        }
+        
         ppbft.logger.LogConsensus("ppbft", "enhanced_commit_completed", logrus.Fields{
                 "block_hash":       block.Hash,
+                "trace_id":         block.TraceID,
                 "committed":        committed,
                 "valid_votes":      validVotes,
                 "required_votes":   requiredVotes,
                 "high_stake_votes": highStakeVotes,
                 "fast_path":        fastPath,
                 "total_stake":      totalStake,
-                "timestamp":        time.Now().UTC(),
+                "timestamp":        ppbft.clock.Now().UTC(),
+        })
+        ppbft.events.Record(ConsensusEvent{
+                Type:      EventPhaseCompleted,
+                Algorithm: "ppbft",
+                BlockHash: block.Hash,
+                Round:     ppbft.currentRound,
+                View:      ppbft.currentView,
+                Phase:     "commit",
+                Details: map[string]interface{}{
+                        "committed":   committed,
+                        "valid_votes": validVotes,
+                        "fast_path":   fastPath,
+                },
         })
         
         return committed, nil
 }
 
+// aggregatePhaseVotes replaces a phase's votes with one aggregated signature
+// when aggregation is enabled, failing the phase if it does not verify. The
+// aggregate is kept in place of the votes it combines.
+func (ppbft *PracticalPBFT) aggregatePhaseVotes(votes map[string]*Vote, validators []*types.Validator) error {
+        if !ppbft.aggregateVotes {
+                return nil
+        }
+        
+        aggregated, err := aggregatePhase(votes, ppbft.currentView, validators, ppbft.voteKeys)
+        if err != nil {
+                return err
+        }
+        ppbft.aggregates.put(aggregated)
+        for _, signer := range aggregated.Signers {
+                delete(votes, signer)
+        }
+        
+        saved := individualVoteBytes(aggregated) - aggregated.Size()
+        ppbft.aggregatedSets++
+        ppbft.bytesSaved += int64(saved)
+        
+        ppbft.logger.LogConsensus("ppbft", "votes_aggregated", logrus.Fields{
+                "block_hash":  aggregated.BlockHash,
+                "vote_type":   aggregated.VoteType,
+                "signers":     len(aggregated.Signers),
+                "bytes_saved": saved,
+                "timestamp":   ppbft.clock.Now().UTC(),
+        })
+        
+        return nil
+}
+
 // validateBlockWithBatching validates block with transaction batching optimization
 func (ppbft *PracticalPBFT) validateBlockWithBatching(block *types.Block) error {
         if block.Hash == "" {
@@ -512,14 +679,19 @@ func (ppbft *PracticalPBFT) validateBlockWithBatching(block *types.Block) error
                 return fmt.Errorf("block validator is empty")
         }
         
+        if err := block.CheckLimits(ppbft.config.Consensus.MaxTxPerBlock, ppbft.config.Consensus.MaxBlockSize); err != nil {
+                return err
+        }
+        
         // Enhanced validation: check transaction batching efficiency
         if len(block.Transactions) > 1000 {
                 ppbft.logger.LogConsensus("ppbft", "large_batch_detected", logrus.Fields{
                         "block_hash":  block.Hash,
+                        "trace_id":    block.TraceID,
                         "tx_count":    len(block.Transactions),
                         "block_size":  block.Size,
                         "optimization": "batching",
-                        "timestamp":   time.Now().UTC(),
+                        "timestamp":   ppbft.clock.Now().UTC(),
                 })
         }
         
@@ -545,7 +717,7 @@ func (ppbft *PracticalPBFT) isEnhancedByzantineValidator(address string, blockHa
         }
         
         // Factor 3: Network conditions simulation
-        if time.Now().Second()%7 == 0 {
+        if ppbft.clock.Now().Second()%7 == 0 {
                 byzantineScore += 10
         }
         
@@ -557,7 +729,7 @@ func (ppbft *PracticalPBFT) isEnhancedByzantineValidator(address string, blockHa
                         "block_hash":      blockHash,
                         "byzantine_score": byzantineScore,
                         "hash_sample":     hash[:utils.MinInt(8, len(hash))],
-                        "timestamp":       time.Now().UTC(),
+                        "timestamp":       ppbft.clock.Now().UTC(),
                 })
         }
         
@@ -569,7 +741,7 @@ func (ppbft *PracticalPBFT) createCheckpoint(sequence int64, validators []*types
         ppbft.logger.LogConsensus("ppbft", "checkpoint_create", logrus.Fields{
                 "sequence":        sequence,
                 "last_checkpoint": ppbft.lastCheckpoint,
-                "timestamp":       time.Now().UTC(),
+                "timestamp":       ppbft.clock.Now().UTC(),
         })
         
         // Initialize checkpoint votes
@@ -592,7 +764,7 @@ func (ppbft *PracticalPBFT) createCheckpoint(sequence int64, validators []*types
                         Round:            sequence,
                         View:             ppbft.currentView,
                         Signature:        fmt.Sprintf("checkpoint_%s_%d", validator.Address, sequence),
-                        Timestamp:        time.Now().Unix(),
+                        Timestamp:        ppbft.clock.Now().Unix(),
                         Metadata: map[string]interface{}{
                                 "checkpoint_type": "stability",
                                 "sequence":        sequence,
@@ -612,13 +784,73 @@ func (ppbft *PracticalPBFT) createCheckpoint(sequence int64, validators []*types
                         "valid_votes":    validVotes,
                         "required_votes": requiredVotes,
                         "new_watermark_low": ppbft.watermarkLow,
-                        "timestamp":      time.Now().UTC(),
+                        "timestamp":      ppbft.clock.Now().UTC(),
+                })
+                ppbft.events.Record(ConsensusEvent{
+                        Type:      EventCheckpoint,
+                        Algorithm: "ppbft",
+                        Round:     sequence,
+                        View:      ppbft.currentView,
+                        Details:   map[string]interface{}{"valid_votes": validVotes},
                 })
                 
                 return nil
         }
         
-        return fmt.Errorf("insufficient checkpoint votes: got %d, required %d", validVotes, requiredVotes)
+        return withReason(FailureInsufficientVotes, fmt.Errorf("insufficient checkpoint votes: got %d, required %d", validVotes, requiredVotes))
+}
+
+// CheckpointStatus describes PPBFT's last stable checkpoint and the window
+// it anchors
+type CheckpointStatus struct {
+        LastCheckpoint int64 `json:"last_checkpoint"`
+        Interval       int64 `json:"interval"`
+        WatermarkLow   int64 `json:"watermark_low"`
+        WatermarkHigh  int64 `json:"watermark_high"`
+}
+
+// ForceCheckpoint checkpoints sequence now instead of waiting for the next
+// multiple of the checkpoint interval. The checkpoint still needs 2f+1 votes
+// from validators; once it has them the watermarks advance and messages
+// below the new low watermark are pruned. Without a quorum nothing changes
+// and the error is classified as FailureInsufficientVotes.
+func (ppbft *PracticalPBFT) ForceCheckpoint(sequence int64, validators []*types.Validator) (CheckpointStatus, error) {
+        ppbft.mu.Lock()
+        defer ppbft.mu.Unlock()
+
+        if sequence <= ppbft.lastCheckpoint {
+                return ppbft.checkpointStatus(), fmt.Errorf("%w: sequence %d, last checkpoint %d", ErrCheckpointNotAhead, sequence, ppbft.lastCheckpoint)
+        }
+
+        ppbft.logger.LogConsensus("ppbft", "checkpoint_forced", logrus.Fields{
+                "sequence":        sequence,
+                "last_checkpoint": ppbft.lastCheckpoint,
+                "validators":      len(validators),
+                "timestamp":       ppbft.clock.Now().UTC(),
+        })
+        if err := ppbft.createCheckpoint(sequence, validators); err != nil {
+                return ppbft.checkpointStatus(), err
+        }
+        ppbft.pruneMessageLog()
+        ppbft.updateMetrics()
+        return ppbft.checkpointStatus(), nil
+}
+
+// Checkpoint returns the last stable checkpoint and the current window
+func (ppbft *PracticalPBFT) Checkpoint() CheckpointStatus {
+        ppbft.mu.RLock()
+        defer ppbft.mu.RUnlock()
+        return ppbft.checkpointStatus()
+}
+
+// checkpointStatus describes the last checkpoint. Caller must hold ppbft.mu.
+func (ppbft *PracticalPBFT) checkpointStatus() CheckpointStatus {
+        return CheckpointStatus{
+                LastCheckpoint: ppbft.lastCheckpoint,
+                Interval:       ppbft.checkpointInterval,
+                WatermarkLow:   ppbft.watermarkLow,
+                WatermarkHigh:  ppbft.watermarkHigh,
+        }
 }
 
 // shouldCreateCheckpoint determines if a checkpoint should be created
@@ -631,20 +863,94 @@ func (ppbft *PracticalPBFT) isWithinWindow(sequence int64) bool {
         return sequence >= ppbft.watermarkLow && sequence <= ppbft.watermarkHigh
 }
 
-// updateWatermarks updates the processing window watermarks
+// updateWatermarks updates the processing window watermarks. The window
+// only moves forward, so a window advanced by recoverStalledWindow is not
+// pulled back to an older checkpoint.
 func (ppbft *PracticalPBFT) updateWatermarks(sequence int64) {
-        if sequence > ppbft.lastCheckpoint {
-                ppbft.watermarkLow = ppbft.lastCheckpoint
-                ppbft.watermarkHigh = ppbft.lastCheckpoint + ppbft.windowSize
-                
-                ppbft.logger.LogConsensus("ppbft", "watermarks_updated", logrus.Fields{
-                        "sequence":         sequence,
-                        "last_checkpoint":  ppbft.lastCheckpoint,
-                        "watermark_low":    ppbft.watermarkLow,
-                        "watermark_high":   ppbft.watermarkHigh,
-                        "timestamp":        time.Now().UTC(),
-                })
+        low := ppbft.lastCheckpoint
+        high := ppbft.lastCheckpoint + ppbft.windowSize
+        if low <= ppbft.watermarkLow && high <= ppbft.watermarkHigh {
+                return
+        }
+        
+        ppbft.setWatermarks(utils.MaxInt64(low, ppbft.watermarkLow), utils.MaxInt64(high, ppbft.watermarkHigh))
+        
+        ppbft.logger.LogConsensus("ppbft", "watermarks_updated", logrus.Fields{
+                "sequence":         sequence,
+                "last_checkpoint":  ppbft.lastCheckpoint,
+                "watermark_low":    ppbft.watermarkLow,
+                "watermark_high":   ppbft.watermarkHigh,
+                "timestamp":        ppbft.clock.Now().UTC(),
+        })
+}
+
+// setWatermarks moves the window and clears stall tracking
+func (ppbft *PracticalPBFT) setWatermarks(low, high int64) {
+        ppbft.watermarkLow = low
+        ppbft.watermarkHigh = high
+        ppbft.rejectedSince = time.Time{}
+        ppbft.highestRejected = 0
+}
+
+// noteWindowRejection records a block rejected for being above the window
+func (ppbft *PracticalPBFT) noteWindowRejection(sequence int64) {
+        if ppbft.rejectedSince.IsZero() {
+                ppbft.rejectedSince = ppbft.clock.Now()
+        }
+        if sequence > ppbft.highestRejected {
+                ppbft.highestRejected = sequence
+        }
+}
+
+// windowStalled reports whether the window is stuck: blocks keep arriving
+// above it while nothing has been committed for a view timeout, as happens
+// when checkpoints stop reaching quorum. Caller must hold ppbft.mu.
+func (ppbft *PracticalPBFT) windowStalled() bool {
+        if ppbft.rejectedSince.IsZero() {
+                return false
+        }
+        return ppbft.clock.Since(ppbft.rejectedSince) > ppbft.viewTimeout &&
+                ppbft.clock.Since(ppbft.state.LastDecision) > ppbft.viewTimeout
+}
+
+// recoverStalledWindow unblocks a stuck window. It first forces a checkpoint
+// at the last committed sequence; if that cannot reach quorum either, the
+// window is advanced without one, anchored at the last committed sequence
+// and extended by at most one window size toward the rejected blocks, so a
+// run of far-ahead blocks only moves it one step per view timeout.
+// Caller must hold ppbft.mu.
+func (ppbft *PracticalPBFT) recoverStalledWindow() {
+        ppbft.windowStalls++
+        
+        ppbft.logger.LogConsensus("ppbft", "window_stalled", logrus.Fields{
+                "level":            "warning",
+                "watermark_low":    ppbft.watermarkLow,
+                "watermark_high":   ppbft.watermarkHigh,
+                "last_checkpoint":  ppbft.lastCheckpoint,
+                "last_committed":   ppbft.lastCommitted,
+                "highest_rejected": ppbft.highestRejected,
+                "stalled_for":      ppbft.clock.Since(ppbft.rejectedSince).Seconds(),
+                "timestamp":        ppbft.clock.Now().UTC(),
+        })
+        
+        if ppbft.lastCommitted > ppbft.lastCheckpoint && len(ppbft.state.Validators) > 0 {
+                if err := ppbft.createCheckpoint(ppbft.lastCommitted, ppbft.state.Validators); err == nil {
+                        return
+                }
         }
+        
+        low := utils.MaxInt64(ppbft.watermarkLow, ppbft.lastCommitted)
+        high := utils.MinInt64(ppbft.highestRejected, ppbft.watermarkHigh+ppbft.windowSize)
+        high = utils.MaxInt64(high, low+ppbft.windowSize)
+        ppbft.setWatermarks(low, high)
+        
+        ppbft.logger.LogConsensus("ppbft", "window_advanced", logrus.Fields{
+                "level":          "warning",
+                "reason":         "no checkpoint quorum",
+                "watermark_low":  ppbft.watermarkLow,
+                "watermark_high": ppbft.watermarkHigh,
+                "timestamp":      ppbft.clock.Now().UTC(),
+        })
 }
 
 // cleanupOldData removes old votes and messages to prevent memory leaks
@@ -662,6 +968,7 @@ func (ppbft *PracticalPBFT) cleanupOldData(excludeBlockHash string, currentSeque
                         delete(ppbft.commitVotes, blockHash)
                 }
         }
+        ppbft.aggregates.keepOnly(excludeBlockHash)
         
         // Clean up old view change votes
         for view := range ppbft.viewChangeVotes {
@@ -677,14 +984,8 @@ func (ppbft *PracticalPBFT) cleanupOldData(excludeBlockHash string, currentSeque
                 }
         }
         
-        // Clean up old messages
-        for msgID := range ppbft.messageLog {
-                // Keep only recent messages (simplified cleanup)
-                if len(ppbft.messageLog) > 1000 {
-                        delete(ppbft.messageLog, msgID)
-                        break
-                }
-        }
+        // Clean up messages below the stable checkpoint
+        ppbft.pruneMessageLog()
         
         ppbft.logger.LogConsensus("ppbft", "cleanup_completed", logrus.Fields{
                 "current_sequence":   currentSequence,
@@ -692,17 +993,52 @@ func (ppbft *PracticalPBFT) cleanupOldData(excludeBlockHash string, currentSeque
                 "commit_votes":       len(ppbft.commitVotes),
                 "view_change_votes":  len(ppbft.viewChangeVotes),
                 "checkpoint_votes":   len(ppbft.checkpointVotes),
-                "message_log_size":   len(ppbft.messageLog),
-                "timestamp":          time.Now().UTC(),
+                "message_log_size":   ppbft.messageLog.len(),
+                "timestamp":          ppbft.clock.Now().UTC(),
         })
 }
 
+// boundVotes evicts the oldest rounds' votes once more than
+// max_retained_votes are kept. cleanupOldData only runs after a commit, so
+// without this the votes of rounds that keep failing pile up. Vote sets left
+// empty are removed. Caller must hold ppbft.mu.
+func (ppbft *PracticalPBFT) boundVotes() {
+        votes := make([]retainedVote, 0)
+        for blockHash, byValidator := range ppbft.prepareVotes {
+                blockHash := blockHash
+                votes = retainVotes(votes, byValidator, func() { delete(ppbft.prepareVotes, blockHash) })
+        }
+        for blockHash, byValidator := range ppbft.commitVotes {
+                blockHash := blockHash
+                votes = retainVotes(votes, byValidator, func() { delete(ppbft.commitVotes, blockHash) })
+        }
+        for view, byValidator := range ppbft.viewChangeVotes {
+                view := view
+                votes = retainVotes(votes, byValidator, func() { delete(ppbft.viewChangeVotes, view) })
+        }
+        for sequence, byValidator := range ppbft.checkpointVotes {
+                sequence := sequence
+                votes = retainVotes(votes, byValidator, func() { delete(ppbft.checkpointVotes, sequence) })
+        }
+
+        if evicted := ppbft.retention.enforce(votes); evicted > 0 {
+                ppbft.logger.LogConsensus("ppbft", "votes_evicted", logrus.Fields{
+                        "evicted":   evicted,
+                        "retained":  ppbft.retention.entries,
+                        "limit":     ppbft.retention.limit,
+                        "round":     ppbft.currentRound,
+                        "timestamp": ppbft.clock.Now().UTC(),
+                })
+        }
+}
+
 // ValidateBlock validates a block according to Practical PBFT rules
 func (ppbft *PracticalPBFT) ValidateBlock(block *types.Block, validators []*types.Validator) error {
-        startTime := time.Now()
+        startTime := ppbft.clock.Now()
         
         ppbft.logger.LogConsensus("ppbft", "validate_block", logrus.Fields{
                 "block_hash":  block.Hash,
+                "trace_id":    block.TraceID,
                 "block_index": block.Index,
                 "validator":   block.Validator,
                 "timestamp":   startTime,
@@ -710,7 +1046,7 @@ func (ppbft *PracticalPBFT) ValidateBlock(block *types.Block, validators []*type
         
         // Enhanced validation with batching
         if err := ppbft.validateBlockWithBatching(block); err != nil {
-                return fmt.Errorf("enhanced block validation failed: %w", err)
+                return withReason(FailureInvalidBlock, fmt.Errorf("enhanced block validation failed: %w", err))
         }
         
         // Check if validator is in the validator set
@@ -728,18 +1064,19 @@ func (ppbft *PracticalPBFT) ValidateBlock(block *types.Block, validators []*type
         
         // Check processing window
         if !ppbft.isWithinWindow(block.Index) {
-                return fmt.Errorf("block sequence %d is outside processing window [%d, %d]", 
-                        block.Index, ppbft.watermarkLow, ppbft.watermarkHigh)
+                return withReason(FailureOutsideWindow, fmt.Errorf("block sequence %d is outside processing window [%d, %d]", 
+                        block.Index, ppbft.watermarkLow, ppbft.watermarkHigh))
         }
         
-        validationDuration := time.Since(startTime)
+        validationDuration := ppbft.clock.Since(startTime)
         
         ppbft.logger.LogConsensus("ppbft", "block_validated", logrus.Fields{
                 "block_hash":         block.Hash,
+                "trace_id":           block.TraceID,
                 "block_index":        block.Index,
                 "validation_duration": validationDuration.Milliseconds(),
                 "within_window":      true,
-                "timestamp":          time.Now().UTC(),
+                "timestamp":          ppbft.clock.Now().UTC(),
         })
         
         return nil
@@ -748,30 +1085,33 @@ func (ppbft *PracticalPBFT) ValidateBlock(block *types.Block, validators []*type
 // SelectValidator selects a validator for the given round (primary selection)
 func (ppbft *PracticalPBFT) SelectValidator(validators []*types.Validator, round int64) (*types.Validator, error) {
         if len(validators) == 0 {
-                return nil, fmt.Errorf("no validators available")
+                return nil, ErrNoValidators
         }
         
-        primary := ppbft.getPrimary(validators, ppbft.currentView)
+        ppbft.mu.RLock()
+        defer ppbft.mu.RUnlock()
+        
+        primary := ppbft.getPrimary(validators, ppbft.lastBlockHash, ppbft.currentView)
+        if primary == nil {
+                // No validator carries any weight to draw
+                return nil, ErrNoValidators
+        }
         
         ppbft.logger.LogConsensus("ppbft", "validator_selected", logrus.Fields{
                 "primary":          primary.Address,
                 "view":             ppbft.currentView,
                 "round":            round,
                 "total_validators": len(validators),
-                "timestamp":        time.Now().UTC(),
+                "timestamp":        ppbft.clock.Now().UTC(),
         })
         
         return primary, nil
 }
 
-// getPrimary returns the primary node for the given view
-func (ppbft *PracticalPBFT) getPrimary(validators []*types.Validator, view int64) *types.Validator {
-        if len(validators) == 0 {
-                return nil
-        }
-        
-        primaryIndex := view % int64(len(validators))
-        return validators[primaryIndex]
+// getPrimary returns the primary node for the given view of the block
+// following prevHash
+func (ppbft *PracticalPBFT) getPrimary(validators []*types.Validator, prevHash string, view int64) *types.Validator {
+        return SelectPrimaryVRF(validators, prevHash, view)
 }
 
 // getRequiredVoteCount calculates the required number of votes for consensus
@@ -792,15 +1132,15 @@ func (ppbft *PracticalPBFT) GetConsensusState() *types.ConsensusState {
         ppbft.state.Performance["last_checkpoint"] = float64(ppbft.lastCheckpoint)
         ppbft.state.Performance["watermark_low"] = float64(ppbft.watermarkLow)
         ppbft.state.Performance["watermark_high"] = float64(ppbft.watermarkHigh)
-        ppbft.state.Performance["uptime"] = time.Since(ppbft.startTime).Seconds()
+        ppbft.state.Performance["uptime"] = ppbft.clock.Since(ppbft.startTime).Seconds()
         
         // Count votes by type
-        prepareCount := 0
+        prepareCount := ppbft.aggregates.signers("prepare")
         for _, votes := range ppbft.prepareVotes {
                 prepareCount += len(votes)
         }
         
-        commitCount := 0
+        commitCount := ppbft.aggregates.signers("commit")
         for _, votes := range ppbft.commitVotes {
                 commitCount += len(votes)
         }
@@ -813,16 +1153,21 @@ func (ppbft *PracticalPBFT) GetConsensusState() *types.ConsensusState {
         ppbft.state.Performance["prepare_votes"] = float64(prepareCount)
         ppbft.state.Performance["commit_votes"] = float64(commitCount)
         ppbft.state.Performance["checkpoint_votes"] = float64(checkpointCount)
-        ppbft.state.Performance["message_log_size"] = float64(len(ppbft.messageLog))
+        ppbft.state.Performance["message_log_size"] = float64(ppbft.messageLog.len())
         
         return ppbft.state
 }
 
-// UpdateValidators updates the validator set
+// UpdateValidators updates the validator set, refusing one that breaks the
+// Byzantine threshold when consensus.byzantine_check is "reject"
 func (ppbft *PracticalPBFT) UpdateValidators(validators []*types.Validator) error {
         ppbft.mu.Lock()
         defer ppbft.mu.Unlock()
         
+        if err := CheckValidatorSet("ppbft", ppbft.byzantineCheck, ppbft.byzantineNodes, validators, ppbft.logger); err != nil {
+                return err
+        }
+        
         oldCount := len(ppbft.state.Validators)
         ppbft.state.Validators = validators
         ppbft.totalNodes = len(validators)
@@ -831,7 +1176,7 @@ func (ppbft *PracticalPBFT) UpdateValidators(validators []*types.Validator) erro
                 "old_count":   oldCount,
                 "new_count":   len(validators),
                 "total_nodes": ppbft.totalNodes,
-                "timestamp":   time.Now().UTC(),
+                "timestamp":   ppbft.clock.Now().UTC(),
         })
         
         return nil
@@ -842,6 +1187,13 @@ func (ppbft *PracticalPBFT) GetAlgorithmName() string {
         return "ppbft"
 }
 
+// SetEventLog sets the log consensus events are recorded to
+func (ppbft *PracticalPBFT) SetEventLog(log *EventLog) {
+        ppbft.mu.Lock()
+        defer ppbft.mu.Unlock()
+        ppbft.events = log
+}
+
 // GetMetrics returns Practical PBFT-specific metrics
 func (ppbft *PracticalPBFT) GetMetrics() map[string]interface{} {
         ppbft.mu.RLock()
@@ -853,9 +1205,10 @@ func (ppbft *PracticalPBFT) GetMetrics() map[string]interface{} {
 
 // updateMetrics updates internal metrics
 func (ppbft *PracticalPBFT) updateMetrics() {
-        uptime := time.Since(ppbft.startTime)
+        uptime := ppbft.clock.Since(ppbft.startTime)
         
         ppbft.metrics["algorithm"] = "ppbft"
+        ppbft.metrics["failure_reasons"] = ppbft.failures.snapshot()
         ppbft.metrics["node_id"] = ppbft.nodeID
         ppbft.metrics["current_view"] = ppbft.currentView
         ppbft.metrics["current_round"] = ppbft.currentRound
@@ -869,15 +1222,19 @@ func (ppbft *PracticalPBFT) updateMetrics() {
         ppbft.metrics["watermark_low"] = ppbft.watermarkLow
         ppbft.metrics["watermark_high"] = ppbft.watermarkHigh
         ppbft.metrics["window_size"] = ppbft.windowSize
+        ppbft.metrics["window_stalls"] = ppbft.windowStalls
+        ppbft.metrics["bls_aggregation"] = ppbft.aggregateVotes
+        ppbft.metrics["aggregated_vote_sets"] = ppbft.aggregatedSets
+        ppbft.metrics["aggregation_bytes_saved"] = ppbft.bytesSaved
         ppbft.metrics["uptime_seconds"] = uptime.Seconds()
         
         // Count current votes by type
-        prepareCount := 0
+        prepareCount := ppbft.aggregates.signers("prepare")
         for _, votes := range ppbft.prepareVotes {
                 prepareCount += len(votes)
         }
         
-        commitCount := 0
+        commitCount := ppbft.aggregates.signers("commit")
         for _, votes := range ppbft.commitVotes {
                 commitCount += len(votes)
         }
@@ -896,7 +1253,11 @@ func (ppbft *PracticalPBFT) updateMetrics() {
         ppbft.metrics["commit_votes"] = commitCount
         ppbft.metrics["view_change_votes"] = viewChangeCount
         ppbft.metrics["checkpoint_votes"] = checkpointCount
-        ppbft.metrics["message_log_size"] = len(ppbft.messageLog)
+        ppbft.metrics["message_log_size"] = ppbft.messageLog.len()
+        ppbft.metrics["message_log_pruned"] = ppbft.messagesPruned
+        ppbft.metrics["vote_map_entries"] = ppbft.retention.entries
+        ppbft.metrics["vote_map_bytes"] = ppbft.retention.bytes
+        ppbft.metrics["votes_evicted"] = ppbft.retention.evicted
         
         // Performance optimizations metrics
         ppbft.metrics["optimizations"] = map[string]interface{}{
@@ -907,7 +1268,17 @@ func (ppbft *PracticalPBFT) updateMetrics() {
                 "watermark_enabled":      true,
         }
         
-        ppbft.metrics["timestamp"] = time.Now().UTC()
+        ppbft.metrics["timestamp"] = ppbft.clock.Now().UTC()
+}
+
+// VoteAudit implements VoteAuditor
+func (ppbft *PracticalPBFT) VoteAudit() VoteAudit {
+        return ppbft.votes.audit()
+}
+
+// SetParticipationHook implements ParticipationRecorder
+func (ppbft *PracticalPBFT) SetParticipationHook(hook func(address string)) {
+        ppbft.votes.setHook(hook)
 }
 
 // Reset resets the consensus state
@@ -916,31 +1287,41 @@ func (ppbft *PracticalPBFT) Reset() error {
         defer ppbft.mu.Unlock()
         
         ppbft.logger.LogConsensus("ppbft", "reset", logrus.Fields{
-                "timestamp": time.Now().UTC(),
+                "timestamp": ppbft.clock.Now().UTC(),
         })
         
+        ppbft.failures.reset()
+        ppbft.votes.reset()
+        
         ppbft.state.Round = 0
         ppbft.state.View = 0
         ppbft.state.Phase = "prepare"
         ppbft.state.Leader = ""
         ppbft.state.Votes = make(map[string]interface{})
-        ppbft.state.LastDecision = time.Now()
+        ppbft.state.LastDecision = ppbft.clock.Now()
         ppbft.state.Performance = make(map[string]float64)
         
         ppbft.currentView = 0
+        ppbft.lastBlockHash = ""
         ppbft.currentRound = 0
         ppbft.prepareVotes = make(map[string]map[string]*Vote)
+        ppbft.aggregates = make(phaseAggregates)
         ppbft.commitVotes = make(map[string]map[string]*Vote)
         ppbft.viewChangeVotes = make(map[int64]map[string]*Vote)
         ppbft.checkpointVotes = make(map[int64]map[string]*Vote)
         ppbft.isPrimary = false
         ppbft.phase = "prepare"
         ppbft.lastCheckpoint = 0
-        ppbft.watermarkLow = 0
-        ppbft.watermarkHigh = ppbft.windowSize
-        ppbft.messageLog = make(map[string]*ConsensusMessage)
+        ppbft.lastCommitted = 0
+        ppbft.windowStalls = 0
+        ppbft.aggregatedSets = 0
+        ppbft.bytesSaved = 0
+        ppbft.setWatermarks(0, ppbft.windowSize)
+        ppbft.messageLog = newPPBFTMessageLog()
+        ppbft.messagesPruned = 0
+        ppbft.retention = voteRetention{limit: ppbft.retention.limit}
         ppbft.performanceMetrics = make(map[string]time.Duration)
-        ppbft.startTime = time.Now()
+        ppbft.startTime = ppbft.clock.Now()
         
         ppbft.updateMetrics()
         
@@ -949,19 +1330,20 @@ func (ppbft *PracticalPBFT) Reset() error {
 
 // consensusWorker handles consensus operations in background
 func (ppbft *PracticalPBFT) consensusWorker() {
-        ticker := time.NewTicker(1 * time.Second)
+        ticker := ppbft.clock.NewTicker(1 * time.Second)
         defer ticker.Stop()
         
         for {
                 select {
                 case <-ppbft.stopChan:
                         return
-                case <-ticker.C:
+                case <-ticker.C():
                         ppbft.checkViewTimeout()
                 case block := <-ppbft.blockQueue:
                         ppbft.logger.LogConsensus("ppbft", "block_queued", logrus.Fields{
                                 "block_hash": block.Hash,
-                                "timestamp":  time.Now().UTC(),
+                                "trace_id":   block.TraceID,
+                                "timestamp":  ppbft.clock.Now().UTC(),
                         })
                 }
         }
@@ -969,15 +1351,28 @@ func (ppbft *PracticalPBFT) consensusWorker() {
 
 // checkpointWorker handles checkpoint operations
 func (ppbft *PracticalPBFT) checkpointWorker() {
-        ticker := time.NewTicker(30 * time.Second)
+        ticker := ppbft.clock.NewTicker(30 * time.Second)
         defer ticker.Stop()
         
+        stallInterval := ppbft.viewTimeout / 2
+        if stallInterval <= 0 {
+                stallInterval = time.Second
+        }
+        stallTicker := ppbft.clock.NewTicker(stallInterval)
+        defer stallTicker.Stop()
+        
         for {
                 select {
                 case <-ppbft.stopChan:
                         return
-                case <-ticker.C:
+                case <-ticker.C():
                         ppbft.performPeriodicCheckpoint()
+                case <-stallTicker.C():
+                        ppbft.mu.Lock()
+                        if ppbft.windowStalled() {
+                                ppbft.recoverStalledWindow()
+                        }
+                        ppbft.mu.Unlock()
                 }
         }
 }
@@ -992,7 +1387,7 @@ func (ppbft *PracticalPBFT) checkViewTimeout() {
                 return
         }
         
-        if time.Since(ppbft.state.LastDecision) > ppbft.viewTimeout {
+        if ppbft.clock.Since(ppbft.state.LastDecision) > ppbft.viewTimeout {
                 ppbft.initiateViewChange()
         }
 }
@@ -1009,7 +1404,7 @@ func (ppbft *PracticalPBFT) performPeriodicCheckpoint() {
                         "current_round":   currentRound,
                         "last_checkpoint": lastCheckpoint,
                         "interval":        ppbft.checkpointInterval,
-                        "timestamp":       time.Now().UTC(),
+                        "timestamp":       ppbft.clock.Now().UTC(),
                 })
         }
 }
@@ -1023,7 +1418,18 @@ func (ppbft *PracticalPBFT) initiateViewChange() {
                 "new_view": newView,
                 "reason":   "timeout",
                 "timeout":  ppbft.viewTimeout,
-                "timestamp": time.Now().UTC(),
+                "timestamp": ppbft.clock.Now().UTC(),
+        })
+        
+        ppbft.events.Record(ConsensusEvent{
+                Type:      EventViewChange,
+                Algorithm: "ppbft",
+                Round:     ppbft.currentRound,
+                View:      newView,
+                Details: map[string]interface{}{
+                        "old_view": ppbft.currentView,
+                        "reason":   "timeout",
+                },
         })
         
         ppbft.currentView = newView
@@ -1033,6 +1439,7 @@ func (ppbft *PracticalPBFT) initiateViewChange() {
         
         // Clean up votes from previous view
         ppbft.prepareVotes = make(map[string]map[string]*Vote)
+        ppbft.aggregates = make(phaseAggregates)
         ppbft.commitVotes = make(map[string]map[string]*Vote)
 }
 
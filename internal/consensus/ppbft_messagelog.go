@@ -0,0 +1,119 @@
+package consensus
+
+import (
+        "github.com/sirupsen/logrus"
+)
+
+// MessageLogStats describes the PPBFT message log
+type MessageLogStats struct {
+        Size           int   `json:"size"`
+        Sequences      int   `json:"sequences"`
+        OldestSequence int64 `json:"oldest_sequence"`
+        NewestSequence int64 `json:"newest_sequence"`
+        WatermarkLow   int64 `json:"watermark_low"`
+        Pruned         int64 `json:"pruned"` // messages removed since the last reset
+}
+
+// ppbftMessageLog holds consensus messages by block sequence. Messages are
+// only dropped a whole sequence at a time once the sequence falls below the
+// low watermark, because everything from the last stable checkpoint onward
+// may be needed to prove a view change.
+type ppbftMessageLog struct {
+        bySequence map[int64]map[string]*ConsensusMessage // sequence -> message ID -> message
+        size       int
+}
+
+func newPPBFTMessageLog() *ppbftMessageLog {
+        return &ppbftMessageLog{bySequence: make(map[int64]map[string]*ConsensusMessage)}
+}
+
+// add records a message for a sequence, replacing one with the same ID
+func (l *ppbftMessageLog) add(sequence int64, id string, message *ConsensusMessage) {
+        messages, exists := l.bySequence[sequence]
+        if !exists {
+                messages = make(map[string]*ConsensusMessage)
+                l.bySequence[sequence] = messages
+        }
+        if _, replaced := messages[id]; !replaced {
+                l.size++
+        }
+        messages[id] = message
+}
+
+// pruneBelow removes every message for sequences lower than low and returns
+// how many were removed
+func (l *ppbftMessageLog) pruneBelow(low int64) int {
+        removed := 0
+        for sequence, messages := range l.bySequence {
+                if sequence < low {
+                        removed += len(messages)
+                        delete(l.bySequence, sequence)
+                }
+        }
+        l.size -= removed
+        return removed
+}
+
+// bounds returns the lowest and highest sequence with messages; ok is false
+// when the log is empty
+func (l *ppbftMessageLog) bounds() (oldest, newest int64, ok bool) {
+        for sequence := range l.bySequence {
+                if !ok || sequence < oldest {
+                        oldest = sequence
+                }
+                if !ok || sequence > newest {
+                        newest = sequence
+                }
+                ok = true
+        }
+        return oldest, newest, ok
+}
+
+func (l *ppbftMessageLog) len() int {
+        return l.size
+}
+
+// MessageLog returns the size and sequence range of the message log
+func (ppbft *PracticalPBFT) MessageLog() MessageLogStats {
+        ppbft.mu.RLock()
+        defer ppbft.mu.RUnlock()
+        return ppbft.messageLogStats()
+}
+
+// PruneMessageLog drops messages below the low watermark now rather than at
+// the next commit, returning how many were removed
+func (ppbft *PracticalPBFT) PruneMessageLog() int {
+        ppbft.mu.Lock()
+        defer ppbft.mu.Unlock()
+        return ppbft.pruneMessageLog()
+}
+
+// messageLogStats describes the message log. Caller must hold ppbft.mu.
+func (ppbft *PracticalPBFT) messageLogStats() MessageLogStats {
+        stats := MessageLogStats{
+                Size:         ppbft.messageLog.len(),
+                Sequences:    len(ppbft.messageLog.bySequence),
+                WatermarkLow: ppbft.watermarkLow,
+                Pruned:       ppbft.messagesPruned,
+        }
+        stats.OldestSequence, stats.NewestSequence, _ = ppbft.messageLog.bounds()
+        return stats
+}
+
+// pruneMessageLog drops messages below the low watermark. Caller must hold
+// ppbft.mu.
+func (ppbft *PracticalPBFT) pruneMessageLog() int {
+        removed := ppbft.messageLog.pruneBelow(ppbft.watermarkLow)
+        if removed == 0 {
+                return 0
+        }
+        ppbft.messagesPruned += int64(removed)
+
+        ppbft.logger.LogConsensus("ppbft", "message_log_pruned", logrus.Fields{
+                "removed":       removed,
+                "remaining":     ppbft.messageLog.len(),
+                "watermark_low": ppbft.watermarkLow,
+                "timestamp":     ppbft.clock.Now().UTC(),
+        })
+        return removed
+}
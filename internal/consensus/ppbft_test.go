@@ -0,0 +1,253 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/storage"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+)
+
+// newTestPPBFT returns a PPBFT engine on clock with a one second view
+// timeout, stopped when the test ends
+func newTestPPBFT(t *testing.T, clock utils.Clock) *PracticalPBFT {
+	t.Helper()
+	ppbft, err := NewPracticalPBFTWithClock(testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.ViewTimeout = 1
+	}), discardLogger(), clock)
+	if err != nil {
+		t.Fatalf("failed to create PPBFT: %v", err)
+	}
+	t.Cleanup(ppbft.Stop)
+	return ppbft
+}
+
+// checkWindow runs the checkpoint worker's stall check once
+func checkWindow(ppbft *PracticalPBFT) bool {
+	ppbft.mu.Lock()
+	defer ppbft.mu.Unlock()
+	if !ppbft.windowStalled() {
+		return false
+	}
+	ppbft.recoverStalledWindow()
+	return true
+}
+
+func TestStalledWindowRecovers(t *testing.T) {
+	clock := utils.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ppbft := newTestPPBFT(t, clock)
+	validators := testValidators(4)
+
+	// No checkpoint ever forms, so blocks past the first window are refused
+	for i := 0; i < 3; i++ {
+		_, err := ppbft.ProcessBlock(testBlock(150), validators)
+		if got := ClassifyFailure(err); got != FailureOutsideWindow {
+			t.Fatalf("block above the window failed with %q (%v)", got, err)
+		}
+	}
+	if checkWindow(ppbft) {
+		t.Fatal("window reported stalled before a view timeout passed")
+	}
+
+	clock.Advance(1500 * time.Millisecond)
+	if !checkWindow(ppbft) {
+		t.Fatal("window not reported stalled after a view timeout")
+	}
+	if ppbft.watermarkHigh < 150 {
+		t.Fatalf("window [%d, %d] still excludes the rejected block", ppbft.watermarkLow, ppbft.watermarkHigh)
+	}
+	if got := ppbft.GetMetrics()["window_stalls"]; got != int64(1) {
+		t.Fatalf("window_stalls = %v, want 1", got)
+	}
+
+	_, err := ppbft.ProcessBlock(testBlock(150), validators)
+	if ClassifyFailure(err) == FailureOutsideWindow {
+		t.Fatalf("block still outside the recovered window: %v", err)
+	}
+	if checkWindow(ppbft) {
+		t.Fatal("recovered window reported stalled")
+	}
+}
+
+func TestStalledWindowAdvancesOneStepAtATime(t *testing.T) {
+	clock := utils.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ppbft := newTestPPBFT(t, clock)
+	validators := testValidators(4)
+
+	// A block far ahead moves the window one window size per view timeout
+	for _, wantHigh := range []int64{200, 300} {
+		if _, err := ppbft.ProcessBlock(testBlock(1000), validators); ClassifyFailure(err) != FailureOutsideWindow {
+			t.Fatalf("far-ahead block: got %v", err)
+		}
+		clock.Advance(1500 * time.Millisecond)
+		if !checkWindow(ppbft) {
+			t.Fatal("window not reported stalled")
+		}
+		if ppbft.watermarkHigh != wantHigh {
+			t.Fatalf("window high = %d, want %d", ppbft.watermarkHigh, wantHigh)
+		}
+	}
+}
+
+// checkpointVoters returns honest validators that vote for the checkpoint at
+// sequence and byzantine ones that withhold their vote, on a clock whose
+// network conditions never tip a validator either way
+func checkpointVoters(ppbft *PracticalPBFT, sequence int64, honest, byzantine int) []*types.Validator {
+	var validators []*types.Validator
+	for i := 0; honest > 0 || byzantine > 0; i++ {
+		validator := testValidators(1)[0]
+		validator.Address = fmt.Sprintf("validator_%d", i)
+		if ppbft.isEnhancedByzantineValidator(validator.Address, fmt.Sprintf("checkpoint_%d", sequence)) {
+			if byzantine > 0 {
+				validators = append(validators, validator)
+				byzantine--
+			}
+		} else if honest > 0 {
+			validators = append(validators, validator)
+			honest--
+		}
+	}
+	return validators
+}
+
+// newCheckpointPPBFT returns a PPBFT engine checkpointing every 50 sequences
+func newCheckpointPPBFT(t *testing.T) *PracticalPBFT {
+	t.Helper()
+	// Second 1 is not a multiple of 7, so only the address decides votes
+	clock := utils.NewManualClock(time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC))
+	ppbft, err := NewPracticalPBFTWithClock(testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.PPBFTCheckpointInterval = 50
+	}), discardLogger(), clock)
+	if err != nil {
+		t.Fatalf("failed to create PPBFT: %v", err)
+	}
+	t.Cleanup(ppbft.Stop)
+	return ppbft
+}
+
+func TestForcedCheckpointAdvancesWatermarks(t *testing.T) {
+	ppbft := newCheckpointPPBFT(t)
+	before := ppbft.Checkpoint()
+	if before.Interval != 50 || before.LastCheckpoint != 0 {
+		t.Fatalf("initial checkpoint state %+v", before)
+	}
+
+	// Sequence 7 is not on the interval, but a forced checkpoint takes it
+	status, err := ppbft.ForceCheckpoint(7, checkpointVoters(ppbft, 7, 3, 1))
+	if err != nil {
+		t.Fatalf("forced checkpoint with 3 of 4 votes failed: %v", err)
+	}
+	if status.LastCheckpoint != 7 || status.WatermarkLow != 7 || status.WatermarkHigh != 7+ppbft.windowSize {
+		t.Fatalf("after the forced checkpoint: %+v", status)
+	}
+	if got := ppbft.Checkpoint(); got != status {
+		t.Fatalf("Checkpoint() = %+v, ForceCheckpoint reported %+v", got, status)
+	}
+
+	// Nothing at or below the stable checkpoint can be forced again
+	for _, sequence := range []int64{7, 3} {
+		if _, err := ppbft.ForceCheckpoint(sequence, checkpointVoters(ppbft, sequence, 4, 0)); !errors.Is(err, ErrCheckpointNotAhead) {
+			t.Fatalf("sequence %d: expected ErrCheckpointNotAhead, got %v", sequence, err)
+		}
+	}
+}
+
+func TestForcedCheckpointWithoutQuorum(t *testing.T) {
+	ppbft := newCheckpointPPBFT(t)
+	before := ppbft.Checkpoint()
+
+	// 2 of 4 votes is short of 2f+1 = 3
+	status, err := ppbft.ForceCheckpoint(7, checkpointVoters(ppbft, 7, 2, 2))
+	if got := ClassifyFailure(err); got != FailureInsufficientVotes {
+		t.Fatalf("expected %q, got %q (%v)", FailureInsufficientVotes, got, err)
+	}
+	if status != before || ppbft.Checkpoint() != before {
+		t.Fatalf("failed checkpoint changed state from %+v to %+v", before, status)
+	}
+
+	// The same sequence can be retried once enough validators vote
+	if _, err := ppbft.ForceCheckpoint(7, checkpointVoters(ppbft, 7, 4, 0)); err != nil {
+		t.Fatalf("retry with a quorum failed: %v", err)
+	}
+}
+
+func TestCheckpointIntervalFromConfig(t *testing.T) {
+	ppbft := newCheckpointPPBFT(t)
+	for sequence, want := range map[int64]bool{10: false, 50: true, 100: true, 0: false} {
+		if got := ppbft.shouldCreateCheckpoint(sequence); got != want {
+			t.Fatalf("sequence %d: checkpoint = %v, want %v", sequence, got, want)
+		}
+	}
+
+	// A config that skipped validation keeps the old interval of 10
+	unset, err := NewPracticalPBFT(testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.PPBFTCheckpointInterval = 0
+	}), discardLogger())
+	if err != nil {
+		t.Fatalf("failed to create PPBFT: %v", err)
+	}
+	defer unset.Stop()
+	if got := unset.Checkpoint().Interval; got != 10 {
+		t.Fatalf("interval with no setting = %d, want 10", got)
+	}
+}
+
+func TestViewTimeoutOnManualClock(t *testing.T) {
+	clock := utils.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ppbft, err := NewPracticalPBFTWithClock(testConfig(t, func(cfg *config.Config) {
+		cfg.Consensus.Algorithm = "ppbft"
+		cfg.Consensus.ViewTimeout = 5
+	}), discardLogger(), clock)
+	if err != nil {
+		t.Fatalf("failed to create PPBFT: %v", err)
+	}
+	t.Cleanup(ppbft.Stop)
+	events, err := NewEventLog(storage.NewMemoryDB(), 100, discardLogger())
+	if err != nil {
+		t.Fatalf("failed to open event log: %v", err)
+	}
+	ppbft.SetEventLog(events)
+	started := time.Now()
+
+	// The consensus and checkpoint workers set up their three tickers
+	for clock.Waiters() < 3 {
+		if time.Since(started) > 5*time.Second {
+			t.Fatalf("workers set up %d tickers, want 3", clock.Waiters())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	view := func() int64 {
+		ppbft.mu.RLock()
+		defer ppbft.mu.RUnlock()
+		return ppbft.currentView
+	}
+
+	// Up to the timeout nothing happens
+	clock.Advance(5 * time.Second)
+	time.Sleep(20 * time.Millisecond)
+	if got := view(); got != 0 {
+		t.Fatalf("view changed to %d before the timeout", got)
+	}
+
+	// The next tick past it starts a view change
+	clock.Advance(time.Second)
+	for view() == 0 {
+		if time.Since(started) > 5*time.Second {
+			t.Fatal("no view change after the timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	changes := events.Query(EventFilter{Type: EventViewChange})
+	if len(changes) == 0 || changes[0].View != 1 || changes[0].Details["reason"] != "timeout" {
+		t.Fatalf("view change events %+v", changes)
+	}
+
+	// Six seconds of clock passed in far less real time
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("took %v of real time", elapsed)
+	}
+}
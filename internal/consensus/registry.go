@@ -0,0 +1,64 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/utils"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownAlgorithm is returned when no consensus algorithm is registered under a name
+var ErrUnknownAlgorithm = errors.New("unknown consensus algorithm")
+
+// Factory creates a consensus algorithm instance
+type Factory func(cfg *config.Config, logger *utils.Logger) (Consensus, error)
+
+// Each implementation registers itself from an init function in its own
+// file, so adding an algorithm needs no changes here or in callers.
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a consensus algorithm available under name, replacing any
+// existing registration
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// IsRegistered reports whether a consensus algorithm is registered under name
+func IsRegistered(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	_, exists := registry[name]
+	return exists
+}
+
+// RegisteredAlgorithms returns the names of all registered algorithms, sorted
+func RegisteredAlgorithms() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the consensus algorithm registered under name
+func New(name string, cfg *config.Config, logger *utils.Logger) (Consensus, error) {
+	registryMu.RLock()
+	factory, exists := registry[name]
+	registryMu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, name)
+	}
+	return factory(cfg, logger)
+}
@@ -0,0 +1,124 @@
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+)
+
+// Every implementation satisfies the full interface
+var (
+	_ Consensus = (*LSCC)(nil)
+	_ Consensus = (*PBFT)(nil)
+	_ Consensus = (*PracticalPBFT)(nil)
+	_ Consensus = (*ProofOfWork)(nil)
+	_ Consensus = (*ProofOfStake)(nil)
+)
+
+func TestRegistryHoldsEveryAlgorithm(t *testing.T) {
+	want := []string{"lscc", "pbft", "pos", "pow", "ppbft"}
+	got := RegisteredAlgorithms()
+	if len(got) != len(want) {
+		t.Fatalf("registered %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] || !IsRegistered(want[i]) {
+			t.Fatalf("registered %v, want %v in order", got, want)
+		}
+	}
+
+	if _, err := New("raft", testConfig(t, nil), discardLogger()); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Fatalf("unknown algorithm: got %v", err)
+	}
+	if IsRegistered("raft") {
+		t.Fatal("unregistered algorithm reported registered")
+	}
+}
+
+func TestRegisteredAlgorithmsSurviveARound(t *testing.T) {
+	for _, algorithm := range RegisteredAlgorithms() {
+		t.Run(algorithm, func(t *testing.T) {
+			engine := newTestEngine(t, algorithm, func(cfg *config.Config) {
+				cfg.Consensus.Difficulty = 1
+			})
+			if got := engine.GetAlgorithmName(); got != algorithm {
+				t.Fatalf("registered as %s but named %s", algorithm, got)
+			}
+
+			validators := testValidators(4)
+			if err := engine.UpdateValidators(validators); err != nil {
+				t.Fatalf("UpdateValidators failed: %v", err)
+			}
+			if _, err := engine.SelectValidator(validators, 1); err != nil {
+				t.Fatalf("SelectValidator failed: %v", err)
+			}
+			// Whether the round commits depends on the algorithm's rules; it
+			// must return either way
+			engine.ProcessBlock(testBlock(1), validators)
+			if engine.GetConsensusState() == nil {
+				t.Fatal("no consensus state after a round")
+			}
+			if engine.GetMetrics() == nil {
+				t.Fatal("no metrics after a round")
+			}
+
+			if err := engine.Reset(); err != nil {
+				t.Fatalf("Reset failed: %v", err)
+			}
+			if state := engine.GetConsensusState(); state == nil || state.Round != 0 {
+				t.Fatalf("state after Reset = %+v, want round 0", state)
+			}
+			engine.ProcessBlock(testBlock(1), validators)
+		})
+	}
+}
+
+func TestRegisterReplacesFactory(t *testing.T) {
+	var built bool
+	Register("test-only", func(cfg *config.Config, logger *utils.Logger) (Consensus, error) {
+		built = true
+		return NewPBFT(cfg, logger)
+	})
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "test-only")
+		registryMu.Unlock()
+	}()
+
+	engine := newTestEngine(t, "test-only", nil)
+	if !built || engine.GetAlgorithmName() != "pbft" {
+		t.Fatalf("factory built=%v, engine %s", built, engine.GetAlgorithmName())
+	}
+}
+
+func TestEmptyValidatorSetRefused(t *testing.T) {
+	for _, algorithm := range RegisteredAlgorithms() {
+		for name, validators := range map[string][]*types.Validator{"nil": nil, "empty": {}} {
+			t.Run(algorithm+"/"+name, func(t *testing.T) {
+				engine := newTestEngine(t, algorithm, nil)
+
+				selected, err := engine.SelectValidator(validators, 1)
+				if selected != nil || !errors.Is(err, ErrNoValidators) {
+					t.Fatalf("SelectValidator = %v, %v, want ErrNoValidators", selected, err)
+				}
+
+				// The round fails before any phase runs
+				before := engine.GetConsensusState()
+				round, view := before.Round, before.View
+				committed, err := engine.ProcessBlock(testBlock(1), validators)
+				if committed || !errors.Is(err, ErrNoValidators) {
+					t.Fatalf("ProcessBlock = %v, %v, want ErrNoValidators", committed, err)
+				}
+				if got := ClassifyFailure(err); got != FailureNoValidators {
+					t.Fatalf("failure classified %q, want %q", got, FailureNoValidators)
+				}
+				if after := engine.GetConsensusState(); after.Round != round || after.View != view {
+					t.Fatalf("round %d view %d moved to round %d view %d", round, view, after.Round, after.View)
+				}
+			})
+		}
+	}
+}
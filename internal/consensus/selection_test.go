@@ -0,0 +1,121 @@
+package consensus
+
+import (
+	"math"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/pkg/types"
+)
+
+// stakedLayerValidators returns validators whose first layer holds one
+// validator per stake, the rest staking 1000
+func stakedLayerValidators(lscc *LSCC, stakes ...int64) []*types.Validator {
+	validators := testValidators(len(stakes) * lscc.layerDepth)
+	for i, stake := range stakes {
+		validators[i*lscc.layerDepth].Stake = stake
+	}
+	return validators
+}
+
+// layerSelections counts who SelectValidator picks over rounds rounds of the
+// first layer. Rotation indexes the layer by round, so a layer whose size
+// shares a factor with the layer depth is only partly visited.
+func layerSelections(t *testing.T, lscc *LSCC, validators []*types.Validator, rounds int) map[string]int {
+	t.Helper()
+	counts := make(map[string]int)
+	for i := 0; i < rounds; i++ {
+		selected, err := lscc.SelectValidator(validators, int64(i*lscc.layerDepth))
+		if err != nil {
+			t.Fatalf("round %d: %v", i, err)
+		}
+		counts[selected.Address]++
+	}
+	return counts
+}
+
+func stakeWeighted(seed string) func(cfg *config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Consensus.LSCCSelectionMode = "stake_weighted"
+		cfg.Consensus.LSCCSelectionSeed = seed
+	}
+}
+
+func TestStakeWeightedSelectionFollowsStake(t *testing.T) {
+	lscc := newTestLSCC(t, stakeWeighted("test-seed"))
+	stakes := []int64{100, 400, 4500}
+	validators := stakedLayerValidators(lscc, stakes...)
+
+	const rounds = 20000
+	counts := layerSelections(t, lscc, validators, rounds)
+
+	var total int64
+	for _, stake := range stakes {
+		total += stake
+	}
+	for i, stake := range stakes {
+		address := validators[i*lscc.layerDepth].Address
+		p := float64(stake) / float64(total)
+		expected := p * rounds
+		sigma := math.Sqrt(rounds * p * (1 - p))
+		if got := float64(counts[address]); math.Abs(got-expected) > 5*sigma {
+			t.Fatalf("validator with stake %d chosen %v times in %d rounds, expected %.0f ± %.0f", stake, got, rounds, expected, 5*sigma)
+		}
+	}
+	if len(counts) != len(stakes) {
+		t.Fatalf("selections outside the layer: %v", counts)
+	}
+}
+
+func TestStakeWeightedSelectionIsDeterministic(t *testing.T) {
+	first := newTestLSCC(t, stakeWeighted("shared"))
+	second := newTestLSCC(t, stakeWeighted("shared"))
+	other := newTestLSCC(t, stakeWeighted("other"))
+	validators := stakedLayerValidators(first, 100, 400, 4500, 1000)
+
+	differs := false
+	for round := int64(0); round < 200; round++ {
+		a, _ := first.SelectValidator(validators, round)
+		b, _ := second.SelectValidator(validators, round)
+		if a.Address != b.Address {
+			t.Fatalf("round %d: nodes sharing a seed picked %s and %s", round, a.Address, b.Address)
+		}
+		if c, _ := other.SelectValidator(validators, round); c.Address != a.Address {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Fatal("a different seed drew the same proposer in every round")
+	}
+}
+
+func TestStakeWeightedSelectionSkipsUnstaked(t *testing.T) {
+	lscc := newTestLSCC(t, stakeWeighted("test-seed"))
+	validators := stakedLayerValidators(lscc, 0, 1000, -5)
+	counts := layerSelections(t, lscc, validators, 500)
+	if counts[validators[1*lscc.layerDepth].Address] != 500 {
+		t.Fatalf("expected only the staked validator drawn, got %v", counts)
+	}
+
+	// With no stake in the layer it falls back to rotating
+	validators = stakedLayerValidators(lscc, 0, 0, 0, 0)
+	counts = layerSelections(t, lscc, validators, 400)
+	for i := 0; i < 4; i++ {
+		if got := counts[validators[i*lscc.layerDepth].Address]; got != 100 {
+			t.Fatalf("unstaked layer: expected round robin, got %v", counts)
+		}
+	}
+}
+
+func TestRoundRobinSelectionIgnoresStake(t *testing.T) {
+	lscc := newTestLSCC(t, func(cfg *config.Config) {
+		cfg.Consensus.LSCCSelectionMode = "round_robin"
+	})
+	validators := stakedLayerValidators(lscc, 100, 400, 4500, 1000)
+	counts := layerSelections(t, lscc, validators, 400)
+	for i := 0; i < 4; i++ {
+		if got := counts[validators[i*lscc.layerDepth].Address]; got != 100 {
+			t.Fatalf("expected every layer validator chosen equally, got %v", counts)
+		}
+	}
+}
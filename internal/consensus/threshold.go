@@ -0,0 +1,62 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrByzantineThreshold is returned for a validator set too small to
+// tolerate the configured number of Byzantine validators
+var ErrByzantineThreshold = errors.New("too few validators to tolerate the configured byzantine validators")
+
+// What to do with a validator set that breaks the Byzantine threshold, as
+// set by consensus.byzantine_check
+const (
+	ByzantineCheckWarn   = "warn"   // log a warning and use the set anyway
+	ByzantineCheckReject = "reject" // refuse the set
+)
+
+// CheckByzantineThreshold checks that validators can tolerate byzantine
+// faulty ones. The 2f+1 votes a round needs are only guaranteed from
+// honest validators when 3f+1 <= n.
+func CheckByzantineThreshold(byzantine int, validators int) error {
+	if required := 3*byzantine + 1; validators < required {
+		return fmt.Errorf("%w: %d byzantine need at least %d validators, have %d", ErrByzantineThreshold, byzantine, required, validators)
+	}
+	return nil
+}
+
+// byzantineTolerant lists the algorithms that vote with 2f+1 quorums and so
+// depend on the Byzantine threshold
+var byzantineTolerant = map[string]bool{"pbft": true, "ppbft": true, "lscc": true}
+
+// CheckValidatorSet applies policy to a validator set for algorithm: a set
+// breaking the Byzantine threshold is refused under ByzantineCheckReject and
+// accepted with a warning otherwise. Algorithms that do not vote, and empty
+// sets, which rounds already fail on with ErrNoValidators, are let through.
+func CheckValidatorSet(algorithm string, policy string, byzantine int, validators []*types.Validator, logger *utils.Logger) error {
+	if !byzantineTolerant[algorithm] || len(validators) == 0 {
+		return nil
+	}
+	err := CheckByzantineThreshold(byzantine, len(validators))
+	if err == nil {
+		return nil
+	}
+	if policy == ByzantineCheckReject {
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"component":  "consensus",
+		"algorithm":  algorithm,
+		"byzantine":  byzantine,
+		"validators": len(validators),
+		"error":      err.Error(),
+		"timestamp":  time.Now().UTC(),
+	}).Warn("Validator set cannot tolerate the configured byzantine validators; rounds may never reach quorum")
+	return nil
+}
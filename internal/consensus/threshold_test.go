@@ -0,0 +1,74 @@
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"lscc-blockchain/internal/utils"
+)
+
+func bufferLogger() (*utils.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logger := utils.NewLogger()
+	logger.Logger.SetOutput(&buf)
+	return logger, &buf
+}
+
+func TestCheckByzantineThreshold(t *testing.T) {
+	tests := []struct {
+		byzantine  int
+		validators int
+		ok         bool
+	}{
+		{byzantine: 0, validators: 1, ok: true},
+		{byzantine: 1, validators: 3, ok: false},
+		{byzantine: 1, validators: 4, ok: true},
+		{byzantine: 2, validators: 6, ok: false},
+		{byzantine: 2, validators: 7, ok: true},
+	}
+	for _, tt := range tests {
+		err := CheckByzantineThreshold(tt.byzantine, tt.validators)
+		if tt.ok && err != nil {
+			t.Errorf("f=%d n=%d: unexpected error %v", tt.byzantine, tt.validators, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrByzantineThreshold) {
+			t.Errorf("f=%d n=%d: expected ErrByzantineThreshold, got %v", tt.byzantine, tt.validators, err)
+		}
+	}
+}
+
+func TestCheckValidatorSetRejects(t *testing.T) {
+	logger, _ := bufferLogger()
+	err := CheckValidatorSet("pbft", ByzantineCheckReject, 1, testValidators(3), logger)
+	if !errors.Is(err, ErrByzantineThreshold) {
+		t.Fatalf("expected ErrByzantineThreshold, got %v", err)
+	}
+	if err := CheckValidatorSet("pbft", ByzantineCheckReject, 1, testValidators(4), logger); err != nil {
+		t.Fatalf("a set at the bound was refused: %v", err)
+	}
+}
+
+func TestCheckValidatorSetWarns(t *testing.T) {
+	logger, buf := bufferLogger()
+	if err := CheckValidatorSet("lscc", ByzantineCheckWarn, 1, testValidators(3), logger); err != nil {
+		t.Fatalf("warn policy refused the set: %v", err)
+	}
+	if !strings.Contains(buf.String(), "cannot tolerate") {
+		t.Fatalf("expected a warning, got %q", buf.String())
+	}
+}
+
+func TestCheckValidatorSetSkips(t *testing.T) {
+	logger, buf := bufferLogger()
+	if err := CheckValidatorSet("pos", ByzantineCheckReject, 1, testValidators(1), logger); err != nil {
+		t.Fatalf("non-voting algorithm was checked: %v", err)
+	}
+	if err := CheckValidatorSet("ppbft", ByzantineCheckReject, 1, nil, logger); err != nil {
+		t.Fatalf("empty set was checked: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected log output %q", buf.String())
+	}
+}
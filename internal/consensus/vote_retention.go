@@ -0,0 +1,89 @@
+package consensus
+
+import (
+        "sort"
+)
+
+// voteEntryOverhead approximates the bytes a retained vote costs beyond its
+// strings and metadata: the struct itself and its slot in the vote maps
+const voteEntryOverhead = 192
+
+// metadataEntryBytes approximates one metadata key and its boxed value
+const metadataEntryBytes = 48
+
+// retainedVote is one vote held in an engine's vote maps, with what is
+// needed to rank it for eviction and remove it
+type retainedVote struct {
+        round     int64
+        timestamp int64
+        bytes     int
+        drop      func() // deletes the vote from the map holding it
+}
+
+// voteRetention caps the votes an engine keeps across rounds. Once the cap
+// is passed the votes of the oldest rounds are dropped first, so the round
+// in progress and those just before it are the last to lose theirs.
+type voteRetention struct {
+        limit   int   // most votes kept; 0 keeps them all
+        evicted int64 // votes dropped to stay within limit since the last reset
+        entries int   // votes kept after the last enforce
+        bytes   int   // estimated size of those votes
+}
+
+// enforce drops the oldest of votes beyond the limit and records the
+// footprint of what remains, returning how many were dropped
+func (r *voteRetention) enforce(votes []retainedVote) int {
+        excess := 0
+        if r.limit > 0 && len(votes) > r.limit {
+                excess = len(votes) - r.limit
+                sort.Slice(votes, func(i, j int) bool {
+                        if votes[i].round != votes[j].round {
+                                return votes[i].round < votes[j].round
+                        }
+                        return votes[i].timestamp < votes[j].timestamp
+                })
+                for _, vote := range votes[:excess] {
+                        vote.drop()
+                }
+                votes = votes[excess:]
+                r.evicted += int64(excess)
+        }
+
+        r.entries = len(votes)
+        r.bytes = 0
+        for _, vote := range votes {
+                r.bytes += vote.bytes
+        }
+        return excess
+}
+
+// voteBytes estimates the memory held by a vote with the given strings and
+// metadata
+func voteBytes(metadata map[string]interface{}, fields ...string) int {
+        size := voteEntryOverhead + len(metadata)*metadataEntryBytes
+        for _, field := range fields {
+                size += len(field)
+        }
+        return size
+}
+
+// retainVotes appends every vote in a validator -> vote map to votes, each
+// removable from byValidator, and deletes the map from its parent through
+// emptied once the last of its votes is dropped
+func retainVotes(votes []retainedVote, byValidator map[string]*Vote, emptied func()) []retainedVote {
+        for address, vote := range byValidator {
+                address := address
+                votes = append(votes, retainedVote{
+                        round:     vote.Round,
+                        timestamp: vote.Timestamp,
+                        bytes:     voteBytes(vote.Metadata, vote.ValidatorAddress, vote.BlockHash, vote.VoteType, vote.Signature, vote.TraceID),
+                        drop: func() {
+                                delete(byValidator, address)
+                                if len(byValidator) == 0 && emptied != nil {
+                                        emptied()
+                                }
+                        },
+                })
+        }
+        return votes
+}
@@ -2,9 +2,13 @@ package metrics
 
 import (
 	"math"
+	"strconv"
 	"sync"
 	"time"
 
+	"lscc-blockchain/internal/consensus"
+	"lscc-blockchain/internal/storage"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	dto "github.com/prometheus/client_model/go"
@@ -22,6 +26,10 @@ type MetricsCollector struct {
 	crossShardMessages    prometheus.Counter
 	shardLoad             *prometheus.GaugeVec
 	shardUtilization      *prometheus.GaugeVec
+	shardBlockHeight      *prometheus.GaugeVec
+	shardBlockConflicts   *prometheus.CounterVec
+	shardCoordination     *prometheus.GaugeVec
+	crossShardJointRounds *prometheus.CounterVec
 	crossShardSuccess     prometheus.Counter
 	crossShardFailed      prometheus.Counter
 	crossShardLatency     prometheus.Histogram
@@ -30,6 +38,8 @@ type MetricsCollector struct {
 	relayBufferSize    *prometheus.GaugeVec
 	relayProcessed     *prometheus.CounterVec
 	relayFailed        *prometheus.CounterVec
+	relayDropped       *prometheus.CounterVec
+	relaySpilled       *prometheus.GaugeVec
 	relayLatency       prometheus.Histogram
 
 	// Consensus algorithm metrics
@@ -54,6 +64,16 @@ type MetricsCollector struct {
 	// System metrics
 	nodeUptime prometheus.Counter
 
+	// Service level objectives
+	sloViolation *prometheus.GaugeVec
+	slo          *SLOMonitor // nil until EnableSLO
+
+	// Consensus engine gauges, nil until TrackConsensus
+	consensus *ConsensusGauges
+
+	// Account cache in front of the database, nil until TrackAccountCache
+	accountCache AccountCacheSource
+
 	mu        sync.RWMutex
 	startTime time.Time
 }
@@ -94,6 +114,22 @@ func NewMetricsCollector() *MetricsCollector {
 			Name: "lscc_shard_utilization_percent",
 			Help: "Current utilization percentage of each shard (0-100)",
 		}, []string{"shard_id"}),
+		shardBlockHeight: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "lscc_shard_block_height",
+			Help: "Index of the last block on each shard's chain under per-shard consensus",
+		}, []string{"shard_id"}),
+		shardBlockConflicts: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "lscc_shard_block_conflicts_total",
+			Help: "Blocks received for a shard height that already holds a different block, by how the fork was settled",
+		}, []string{"shard_id", "resolution"}),
+		shardCoordination: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "lscc_shard_coordination_mode",
+			Help: "1 for the schedule per-shard consensus is following (parallel or sequential), with the configured mode; absent while it is stopped",
+		}, []string{"mode", "configured"}),
+		crossShardJointRounds: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "lscc_cross_shard_joint_rounds_total",
+			Help: "Rounds committing cross-shard transactions on every shard they touch at once, by outcome",
+		}, []string{"outcome"}),
 		crossShardSuccess: promauto.NewCounter(prometheus.CounterOpts{
 			Name: "lscc_cross_shard_success_total",
 			Help: "Total number of successful cross-shard transactions",
@@ -121,6 +157,14 @@ func NewMetricsCollector() *MetricsCollector {
 			Name: "lscc_relay_failed_total",
 			Help: "Total messages failed by each relay node",
 		}, []string{"relay_id"}),
+		relayDropped: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "lscc_relay_dropped_total",
+			Help: "Total messages a full relay node refused or evicted, by overflow policy",
+		}, []string{"relay_id", "policy"}),
+		relaySpilled: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "lscc_relay_spilled_messages",
+			Help: "Current number of messages a relay node holds on disk under the spill_to_disk policy",
+		}, []string{"relay_id"}),
 		relayLatency: promauto.NewHistogram(prometheus.HistogramOpts{
 			Name:    "lscc_relay_latency_seconds",
 			Help:    "Latency for relay node message forwarding",
@@ -188,9 +232,33 @@ func NewMetricsCollector() *MetricsCollector {
 			Help: "Total uptime of the node in seconds",
 		}),
 
+		// Service level objectives
+		sloViolation: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "lscc_slo_violation",
+			Help: "1 while an SLO has been breached for its whole window, else 0",
+		}, []string{"type"}),
+
 		startTime: time.Now(),
 	}
 
+	// Account cache metrics, read from the cache when scraped
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "lscc_account_cache_hits_total",
+		Help: "Account balance and nonce lookups answered from the cache",
+	}, func() float64 { return float64(mc.accountCacheStats().Hits) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "lscc_account_cache_misses_total",
+		Help: "Account balance and nonce lookups read from the database",
+	}, func() float64 { return float64(mc.accountCacheStats().Misses) })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "lscc_account_cache_hit_ratio",
+		Help: "Share of account lookups answered from the cache",
+	}, func() float64 { return mc.accountCacheStats().HitRatio })
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "lscc_account_cache_entries",
+		Help: "Accounts currently held in the cache",
+	}, func() float64 { return float64(mc.accountCacheStats().Entries) })
+
 	return mc
 }
 
@@ -212,6 +280,56 @@ func (mc *MetricsCollector) RecordBlockTime(duration time.Duration) {
 	mc.blockTime.Observe(duration.Seconds())
 }
 
+// RecordBlockCommitted counts a committed block and its transactions and
+// feeds the SLO monitor
+func (mc *MetricsCollector) RecordBlockCommitted(txCount int, duration time.Duration) {
+	mc.blocksCreated.Inc()
+	mc.transactionsProcessed.Add(float64(txCount))
+	mc.blockTime.Observe(duration.Seconds())
+	if slo := mc.sloMonitor(); slo != nil {
+		slo.RecordBlock(time.Now(), txCount)
+	}
+
+	mc.mu.RLock()
+	gauges := mc.consensus
+	mc.mu.RUnlock()
+	gauges.Update()
+}
+
+// TrackConsensus exports engine's GetMetrics output as Prometheus gauges,
+// refreshed on every RecordBlockCommitted. It replaces any engine tracked
+// before.
+func (mc *MetricsCollector) TrackConsensus(engine consensus.Consensus) {
+	gauges := RegisterConsensus(engine)
+
+	mc.mu.Lock()
+	mc.consensus = gauges
+	mc.mu.Unlock()
+}
+
+// AccountCacheSource reports on an account cache, such as storage.CachedDB
+type AccountCacheSource interface {
+	AccountCacheStats() storage.AccountCacheStats
+}
+
+// TrackAccountCache exports source's hit and miss counts, replacing any
+// cache tracked before
+func (mc *MetricsCollector) TrackAccountCache(source AccountCacheSource) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.accountCache = source
+}
+
+func (mc *MetricsCollector) accountCacheStats() storage.AccountCacheStats {
+	mc.mu.RLock()
+	source := mc.accountCache
+	mc.mu.RUnlock()
+	if source == nil {
+		return storage.AccountCacheStats{}
+	}
+	return source.AccountCacheStats()
+}
+
 // Sharding metric methods
 
 func (mc *MetricsCollector) IncrementCrossShardMessages() {
@@ -226,6 +344,32 @@ func (mc *MetricsCollector) SetShardUtilization(shardID string, utilizationPerce
 	mc.shardUtilization.WithLabelValues(shardID).Set(utilizationPercent)
 }
 
+// SetShardBlockHeight records the index of a shard's last committed block
+func (mc *MetricsCollector) SetShardBlockHeight(shardID int, height int64) {
+	mc.shardBlockHeight.WithLabelValues(strconv.Itoa(shardID)).Set(float64(height))
+}
+
+// IncrementShardBlockConflict counts a conflicting block seen on a shard and
+// how the fork between it and the block already held was settled
+func (mc *MetricsCollector) IncrementShardBlockConflict(shardID int, resolution string) {
+	mc.shardBlockConflicts.WithLabelValues(strconv.Itoa(shardID), resolution).Inc()
+}
+
+// SetShardCoordinationMode records the schedule per-shard consensus is
+// following and the mode it was configured with. An empty mode clears it.
+func (mc *MetricsCollector) SetShardCoordinationMode(mode, configured string) {
+	mc.shardCoordination.Reset()
+	if mode != "" {
+		mc.shardCoordination.WithLabelValues(mode, configured).Set(1)
+	}
+}
+
+// IncrementCrossShardJointRound counts a joint cross-shard commit round by
+// whether its blocks were committed or all abandoned
+func (mc *MetricsCollector) IncrementCrossShardJointRound(outcome string) {
+	mc.crossShardJointRounds.WithLabelValues(outcome).Inc()
+}
+
 func (mc *MetricsCollector) IncrementCrossShardSuccess() {
 	mc.crossShardSuccess.Inc()
 }
@@ -238,6 +382,16 @@ func (mc *MetricsCollector) RecordCrossShardLatency(duration time.Duration) {
 	mc.crossShardLatency.Observe(duration.Seconds())
 }
 
+// RecordCrossShardCommitted counts a cross-shard transaction committed after
+// latency and adds it to the throughput the SLO monitor sees
+func (mc *MetricsCollector) RecordCrossShardCommitted(latency time.Duration) {
+	mc.crossShardSuccess.Inc()
+	mc.crossShardLatency.Observe(latency.Seconds())
+	if slo := mc.sloMonitor(); slo != nil {
+		slo.RecordTransactions(time.Now(), 1)
+	}
+}
+
 // Relay node metric methods
 
 func (mc *MetricsCollector) SetRelayBufferSize(relayID string, size float64) {
@@ -252,6 +406,16 @@ func (mc *MetricsCollector) IncrementRelayFailed(relayID string) {
 	mc.relayFailed.WithLabelValues(relayID).Inc()
 }
 
+// IncrementRelayDropped counts a message a full relay refused or evicted
+// under the overflow policy
+func (mc *MetricsCollector) IncrementRelayDropped(relayID, policy string) {
+	mc.relayDropped.WithLabelValues(relayID, policy).Inc()
+}
+
+func (mc *MetricsCollector) SetRelaySpilled(relayID string, count float64) {
+	mc.relaySpilled.WithLabelValues(relayID).Set(count)
+}
+
 func (mc *MetricsCollector) RecordRelayLatency(duration time.Duration) {
 	mc.relayLatency.Observe(duration.Seconds())
 }
@@ -320,6 +484,43 @@ func (mc *MetricsCollector) GetUptime() time.Duration {
 	return time.Since(mc.startTime)
 }
 
+// EnableSLO starts checking the node against config, replacing any monitor
+// already running
+func (mc *MetricsCollector) EnableSLO(config SLOConfig) {
+	mc.StopSLO()
+	mc.sloViolation.Reset()
+
+	monitor := NewSLOMonitor(config, mc.sloViolation)
+	monitor.Start()
+
+	mc.mu.Lock()
+	mc.slo = monitor
+	mc.mu.Unlock()
+}
+
+// StopSLO stops the SLO monitor, if any
+func (mc *MetricsCollector) StopSLO() {
+	if slo := mc.sloMonitor(); slo != nil {
+		slo.Stop()
+	}
+}
+
+// GetSLOReport returns the current SLO state. ok is false when SLOs are not
+// enabled.
+func (mc *MetricsCollector) GetSLOReport() (report *SLOReport, ok bool) {
+	slo := mc.sloMonitor()
+	if slo == nil {
+		return nil, false
+	}
+	return slo.Report(), true
+}
+
+func (mc *MetricsCollector) sloMonitor() *SLOMonitor {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	return mc.slo
+}
+
 // Metrics holds current real-time metrics
 type Metrics struct {
 	TPS        float64 `json:"tps"`
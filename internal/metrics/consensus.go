@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"lscc-blockchain/internal/consensus"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// consensusMetricPrefix starts the name of every gauge built from an
+// engine's GetMetrics output
+const consensusMetricPrefix = "lscc_consensus_"
+
+// ConsensusGauges exports the active consensus engine's GetMetrics map to
+// Prometheus. Every numeric entry becomes a gauge named after its key, with
+// nested maps flattened into the name: LSCC's layer_consensus.layer_0.vote_count
+// is lscc_consensus_layer_consensus_layer_0_vote_count. Booleans export as 0
+// or 1. String entries such as the phase are exported as
+// lscc_consensus_state{metric,value} set to 1 for the current value. All
+// series carry an algorithm label. Entries that stop being reported, or
+// belong to an engine that has been replaced, are deleted on the next Update.
+type ConsensusGauges struct {
+	mu     sync.Mutex
+	engine consensus.Consensus
+	gauges map[string]*prometheus.GaugeVec
+	state  *prometheus.GaugeVec
+
+	// Series written by the last Update, so stale ones can be deleted
+	algorithm string
+	numeric   map[string]bool
+	strings   map[[2]string]bool
+}
+
+var (
+	consensusGaugesOnce sync.Once
+	consensusGauges     *ConsensusGauges
+)
+
+// RegisterConsensus makes engine the one exported by the process-wide
+// ConsensusGauges, registering it with the default Prometheus registry on
+// first use, and exports engine's current metrics
+func RegisterConsensus(engine consensus.Consensus) *ConsensusGauges {
+	consensusGaugesOnce.Do(func() {
+		consensusGauges = &ConsensusGauges{
+			gauges: make(map[string]*prometheus.GaugeVec),
+			state: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: consensusMetricPrefix + "state",
+				Help: "1 for the current value of a string consensus metric",
+			}, []string{"algorithm", "metric", "value"}),
+		}
+		prometheus.MustRegister(consensusGauges.state)
+	})
+
+	consensusGauges.mu.Lock()
+	consensusGauges.engine = engine
+	consensusGauges.mu.Unlock()
+
+	consensusGauges.Update()
+	return consensusGauges
+}
+
+// Update re-reads the engine's metrics and refreshes the gauges
+func (g *ConsensusGauges) Update() {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.engine == nil {
+		return
+	}
+	algorithm := g.engine.GetAlgorithmName()
+
+	numeric := make(map[string]float64)
+	strs := make(map[string]string)
+	flattenConsensusMetrics("", reflect.ValueOf(g.engine.GetMetrics()), numeric, strs)
+
+	names := make([]string, 0, len(numeric))
+	for name := range numeric {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	written := make(map[string]bool, len(numeric))
+	for _, name := range names {
+		gauge, err := g.gauge(name)
+		if err != nil {
+			// The name is taken by a metric registered elsewhere
+			continue
+		}
+		gauge.WithLabelValues(algorithm).Set(numeric[name])
+		written[name] = true
+	}
+
+	writtenStrings := make(map[[2]string]bool, len(strs))
+	for metric, value := range strs {
+		g.state.WithLabelValues(algorithm, metric, value).Set(1)
+		writtenStrings[[2]string{metric, value}] = true
+	}
+
+	for name := range g.numeric {
+		if g.algorithm != algorithm || !written[name] {
+			g.gauges[name].DeleteLabelValues(g.algorithm)
+		}
+	}
+	for key := range g.strings {
+		if g.algorithm != algorithm || !writtenStrings[key] {
+			g.state.DeleteLabelValues(g.algorithm, key[0], key[1])
+		}
+	}
+
+	g.algorithm = algorithm
+	g.numeric = written
+	g.strings = writtenStrings
+}
+
+// gauge returns the gauge for a flattened metric name, registering it the
+// first time the name is seen. Caller must hold g.mu.
+func (g *ConsensusGauges) gauge(name string) (*prometheus.GaugeVec, error) {
+	if gauge, exists := g.gauges[name]; exists {
+		return gauge, nil
+	}
+
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: consensusMetricPrefix + name,
+		Help: "Consensus engine metric " + name,
+	}, []string{"algorithm"})
+	if err := prometheus.Register(gauge); err != nil {
+		return nil, err
+	}
+	g.gauges[name] = gauge
+	return gauge, nil
+}
+
+// flattenConsensusMetrics walks a GetMetrics value, collecting numbers and
+// booleans into numeric and strings into strs under their flattened key.
+// Values of any other type, such as timestamps and slices, are skipped.
+func flattenConsensusMetrics(prefix string, value reflect.Value, numeric map[string]float64, strs map[string]string) {
+	if !value.IsValid() {
+		return
+	}
+	if value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	if duration, ok := value.Interface().(time.Duration); ok {
+		numeric[prefix] = duration.Seconds()
+		return
+	}
+
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return
+		}
+		iter := value.MapRange()
+		for iter.Next() {
+			key := metricNameSegment(iter.Key().String())
+			if key == "" {
+				continue
+			}
+			if prefix != "" {
+				key = prefix + "_" + key
+			}
+			flattenConsensusMetrics(key, iter.Value(), numeric, strs)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		numeric[prefix] = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		numeric[prefix] = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		numeric[prefix] = value.Float()
+	case reflect.Bool:
+		numeric[prefix] = 0
+		if value.Bool() {
+			numeric[prefix] = 1
+		}
+	case reflect.String:
+		// The algorithm is already a label on every series
+		if prefix != "algorithm" && prefix != "" {
+			strs[prefix] = value.String()
+		}
+	}
+}
+
+// metricNameSegment lower-cases key and replaces anything Prometheus does
+// not allow in a metric name with an underscore
+func metricNameSegment(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(key) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
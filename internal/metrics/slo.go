@@ -0,0 +1,317 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SLO types, used as the type label of lscc_slo_violation
+const (
+	SLOThroughput    = "tps"            // committed transactions per second over the window
+	SLOBlockInterval = "block_interval" // seconds since the last committed block
+)
+
+// sloCheckInterval is how often a running monitor evaluates its SLOs
+const sloCheckInterval = time.Second
+
+// SLOConfig sets the service level objectives a node is held to. A zero
+// threshold disables its SLO. A threshold must stay breached for a whole
+// Window before it counts as a violation.
+type SLOConfig struct {
+	MinTPS           float64
+	MaxBlockInterval time.Duration
+	Window           time.Duration
+	WebhookURL       string // receives a POST of an SLOEvent whenever a violation starts or ends
+}
+
+// SLOStatus is the current state of one SLO
+type SLOStatus struct {
+	Type          string     `json:"type"`
+	Threshold     float64    `json:"threshold"`
+	Current       float64    `json:"current"`
+	Breached      bool       `json:"breached"` // the threshold is currently not met
+	Violated      bool       `json:"violated"` // breached for at least the window
+	BreachedSince *time.Time `json:"breached_since,omitempty"`
+	ViolatedSince *time.Time `json:"violated_since,omitempty"`
+}
+
+// SLOReport is the state of every enabled SLO
+type SLOReport struct {
+	Window           time.Duration `json:"window"`
+	SLOs             []SLOStatus   `json:"slos"`
+	LastWebhookError string        `json:"last_webhook_error,omitempty"`
+	EvaluatedAt      time.Time     `json:"evaluated_at"`
+}
+
+// SLOEvent is the webhook payload sent when a violation starts or ends
+type SLOEvent struct {
+	Type      string    `json:"type"`
+	Violated  bool      `json:"violated"`
+	Threshold float64   `json:"threshold"`
+	Current   float64   `json:"current"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// sloState tracks one SLO between evaluations
+type sloState struct {
+	sloType       string
+	threshold     float64
+	current       float64
+	breachedSince time.Time
+	violatedSince time.Time
+}
+
+func (s *sloState) status() SLOStatus {
+	status := SLOStatus{
+		Type:      s.sloType,
+		Threshold: s.threshold,
+		Current:   s.current,
+		Breached:  !s.breachedSince.IsZero(),
+		Violated:  !s.violatedSince.IsZero(),
+	}
+	if status.Breached {
+		since := s.breachedSince
+		status.BreachedSince = &since
+	}
+	if status.Violated {
+		since := s.violatedSince
+		status.ViolatedSince = &since
+	}
+	return status
+}
+
+// txSample is a batch of transactions committed at one time
+type txSample struct {
+	at    time.Time
+	count int
+}
+
+// SLOMonitor checks throughput and block production against an SLOConfig.
+// The block-commit path and cross-shard commits feed it through
+// RecordBlock and RecordTransactions; Evaluate, run every second once
+// started, moves the lscc_slo_violation gauge and fires the webhook.
+type SLOMonitor struct {
+	mu               sync.Mutex
+	config           SLOConfig
+	gauge            *prometheus.GaugeVec
+	client           *http.Client
+	started          time.Time
+	lastBlock        time.Time
+	transactions     []txSample
+	states           []*sloState
+	lastWebhookError string
+	evaluatedAt      time.Time
+	stopChan         chan struct{}
+	running          bool
+	wg               sync.WaitGroup
+}
+
+// NewSLOMonitor creates a monitor reporting violations on gauge
+func NewSLOMonitor(config SLOConfig, gauge *prometheus.GaugeVec) *SLOMonitor {
+	now := time.Now()
+	m := &SLOMonitor{
+		config:    config,
+		gauge:     gauge,
+		client:    &http.Client{Timeout: 5 * time.Second},
+		started:   now,
+		lastBlock: now,
+	}
+	if config.MinTPS > 0 {
+		m.states = append(m.states, &sloState{sloType: SLOThroughput, threshold: config.MinTPS})
+	}
+	if config.MaxBlockInterval > 0 {
+		m.states = append(m.states, &sloState{sloType: SLOBlockInterval, threshold: config.MaxBlockInterval.Seconds()})
+	}
+	for _, state := range m.states {
+		gauge.WithLabelValues(state.sloType).Set(0)
+	}
+	return m
+}
+
+// Start evaluates the SLOs every second until Stop
+func (m *SLOMonitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running {
+		return
+	}
+	m.running = true
+	m.stopChan = make(chan struct{})
+	m.wg.Add(1)
+	go m.run(m.stopChan)
+}
+
+// Stop halts periodic evaluation
+func (m *SLOMonitor) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = false
+	close(m.stopChan)
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+func (m *SLOMonitor) run(stop chan struct{}) {
+	defer m.wg.Done()
+	ticker := time.NewTicker(sloCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			m.Evaluate(now)
+		}
+	}
+}
+
+// RecordBlock notes a block committed at with txCount transactions
+func (m *SLOMonitor) RecordBlock(at time.Time, txCount int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if at.After(m.lastBlock) {
+		m.lastBlock = at
+	}
+	m.addTransactionsLocked(at, txCount)
+}
+
+// RecordTransactions notes count transactions committed at, outside a block
+// of this chain (cross-shard commits)
+func (m *SLOMonitor) RecordTransactions(at time.Time, count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.addTransactionsLocked(at, count)
+}
+
+func (m *SLOMonitor) addTransactionsLocked(at time.Time, count int) {
+	if count > 0 {
+		m.transactions = append(m.transactions, txSample{at: at, count: count})
+	}
+}
+
+// Evaluate measures every SLO as of now, updating the gauge and notifying
+// the webhook of violations that started or ended
+func (m *SLOMonitor) Evaluate(now time.Time) {
+	m.mu.Lock()
+	var events []SLOEvent
+	for _, state := range m.states {
+		var breached bool
+		switch state.sloType {
+		case SLOThroughput:
+			state.current = m.throughputLocked(now)
+			breached = state.current < state.threshold
+		case SLOBlockInterval:
+			state.current = now.Sub(m.lastBlock).Seconds()
+			breached = state.current > state.threshold
+		}
+
+		if !breached {
+			state.breachedSince = time.Time{}
+			if !state.violatedSince.IsZero() {
+				state.violatedSince = time.Time{}
+				events = append(events, SLOEvent{Type: state.sloType, Violated: false, Threshold: state.threshold, Current: state.current, Timestamp: now})
+			}
+			m.gauge.WithLabelValues(state.sloType).Set(0)
+			continue
+		}
+
+		if state.breachedSince.IsZero() {
+			state.breachedSince = now
+		}
+		if state.violatedSince.IsZero() && now.Sub(state.breachedSince) >= m.config.Window {
+			state.violatedSince = now
+			events = append(events, SLOEvent{Type: state.sloType, Violated: true, Threshold: state.threshold, Current: state.current, Timestamp: now})
+		}
+		if !state.violatedSince.IsZero() {
+			m.gauge.WithLabelValues(state.sloType).Set(1)
+		}
+	}
+	m.evaluatedAt = now
+	m.mu.Unlock()
+
+	if m.config.WebhookURL != "" && len(events) > 0 {
+		go func() {
+			for _, event := range events {
+				m.notify(event)
+			}
+		}()
+	}
+}
+
+// throughputLocked returns transactions per second over the window ending at
+// now, dropping samples older than the window. Until a full window has
+// passed since start the rate is taken over the time elapsed so far, but
+// never over less than one check interval.
+func (m *SLOMonitor) throughputLocked(now time.Time) float64 {
+	cutoff := now.Add(-m.config.Window)
+	kept := m.transactions[:0]
+	total := 0
+	for _, sample := range m.transactions {
+		if sample.at.After(cutoff) {
+			kept = append(kept, sample)
+			total += sample.count
+		}
+	}
+	m.transactions = kept
+
+	span := m.config.Window
+	if elapsed := now.Sub(m.started); elapsed < span {
+		span = elapsed
+		if span < sloCheckInterval {
+			span = sloCheckInterval
+		}
+	}
+	if span <= 0 {
+		return 0
+	}
+	return float64(total) / span.Seconds()
+}
+
+// notify posts event to the webhook
+func (m *SLOMonitor) notify(event SLOEvent) {
+	body, err := json.Marshal(event)
+	if err == nil {
+		var resp *http.Response
+		resp, err = m.client.Post(m.config.WebhookURL, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				err = fmt.Errorf("webhook returned %s", resp.Status)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.lastWebhookError = err.Error()
+	} else {
+		m.lastWebhookError = ""
+	}
+}
+
+// Report returns the state of every enabled SLO as of the last evaluation
+func (m *SLOMonitor) Report() *SLOReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := &SLOReport{
+		Window:           m.config.Window,
+		SLOs:             make([]SLOStatus, 0, len(m.states)),
+		LastWebhookError: m.lastWebhookError,
+		EvaluatedAt:      m.evaluatedAt,
+	}
+	for _, state := range m.states {
+		report.SLOs = append(report.SLOs, state.status())
+	}
+	return report
+}
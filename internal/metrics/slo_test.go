@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// newTestSLOMonitor returns a monitor reporting on a gauge of its own, so
+// tests do not share the registered lscc_slo_violation
+func newTestSLOMonitor(config SLOConfig) (*SLOMonitor, *prometheus.GaugeVec) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_slo_violation"}, []string{"type"})
+	return NewSLOMonitor(config, gauge), gauge
+}
+
+// gaugeValue reads the gauge for sloType
+func gaugeValue(t *testing.T, gauge *prometheus.GaugeVec, sloType string) float64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := gauge.WithLabelValues(sloType).Write(&metric); err != nil {
+		t.Fatalf("failed to read gauge: %v", err)
+	}
+	return metric.GetGauge().GetValue()
+}
+
+// sloStatus returns the reported state of sloType
+func sloStatus(t *testing.T, m *SLOMonitor, sloType string) SLOStatus {
+	t.Helper()
+	for _, status := range m.Report().SLOs {
+		if status.Type == sloType {
+			return status
+		}
+	}
+	t.Fatalf("no %s SLO reported", sloType)
+	return SLOStatus{}
+}
+
+func TestThroughputSLOFlipsAndRecovers(t *testing.T) {
+	events := make(chan SLOEvent, 4)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event SLOEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err == nil {
+			events <- event
+		}
+	}))
+	defer webhook.Close()
+
+	m, gauge := newTestSLOMonitor(SLOConfig{MinTPS: 10, Window: 10 * time.Second, WebhookURL: webhook.URL})
+	start := m.started
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	// 20 TPS for the first ten seconds
+	for s := 1; s <= 10; s++ {
+		m.RecordBlock(at(s), 20)
+	}
+	m.Evaluate(at(10))
+	if status := sloStatus(t, m, SLOThroughput); status.Breached || gaugeValue(t, gauge, SLOThroughput) != 0 {
+		t.Fatalf("expected the SLO met at 20 TPS, got %+v", status)
+	}
+
+	// Throughput falls below 10 TPS: breached, but not yet for a window
+	m.Evaluate(at(16))
+	status := sloStatus(t, m, SLOThroughput)
+	if !status.Breached || status.Violated || status.Current >= 10 {
+		t.Fatalf("expected a fresh breach, got %+v", status)
+	}
+	if gaugeValue(t, gauge, SLOThroughput) != 0 {
+		t.Fatal("gauge flipped before the breach lasted a window")
+	}
+
+	// Breached for a whole window: violated
+	m.Evaluate(at(26))
+	if status := sloStatus(t, m, SLOThroughput); !status.Violated {
+		t.Fatalf("expected a violation, got %+v", status)
+	}
+	if gaugeValue(t, gauge, SLOThroughput) != 1 {
+		t.Fatal("gauge did not flip on violation")
+	}
+	expectEvent(t, events, true)
+
+	// Throughput returns
+	m.RecordTransactions(at(27), 300)
+	m.Evaluate(at(27))
+	if status := sloStatus(t, m, SLOThroughput); status.Breached || status.Violated {
+		t.Fatalf("expected recovery, got %+v", status)
+	}
+	if gaugeValue(t, gauge, SLOThroughput) != 0 {
+		t.Fatal("gauge did not recover")
+	}
+	expectEvent(t, events, false)
+}
+
+func TestBlockIntervalSLO(t *testing.T) {
+	m, gauge := newTestSLOMonitor(SLOConfig{MaxBlockInterval: 5 * time.Second, Window: 10 * time.Second})
+	start := m.started
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	m.RecordBlock(at(1), 0)
+	m.Evaluate(at(4))
+	if sloStatus(t, m, SLOBlockInterval).Breached {
+		t.Fatal("breached within the block interval")
+	}
+	m.Evaluate(at(7))
+	if status := sloStatus(t, m, SLOBlockInterval); !status.Breached || status.Violated {
+		t.Fatalf("expected a fresh breach, got %+v", status)
+	}
+	m.Evaluate(at(17))
+	if !sloStatus(t, m, SLOBlockInterval).Violated || gaugeValue(t, gauge, SLOBlockInterval) != 1 {
+		t.Fatal("expected a violation after no block for a window")
+	}
+
+	m.RecordBlock(at(18), 0)
+	m.Evaluate(at(18))
+	if sloStatus(t, m, SLOBlockInterval).Violated || gaugeValue(t, gauge, SLOBlockInterval) != 0 {
+		t.Fatal("expected recovery once a block is committed")
+	}
+}
+
+func TestDisabledSLOsNotReported(t *testing.T) {
+	m, _ := newTestSLOMonitor(SLOConfig{Window: time.Minute})
+	m.Evaluate(m.started.Add(time.Hour))
+	if report := m.Report(); len(report.SLOs) != 0 {
+		t.Fatalf("expected no SLOs without thresholds, got %+v", report.SLOs)
+	}
+}
+
+// expectEvent waits for the webhook to receive an event
+func expectEvent(t *testing.T, events <-chan SLOEvent, violated bool) {
+	t.Helper()
+	select {
+	case event := <-events:
+		if event.Type != SLOThroughput || event.Violated != violated {
+			t.Fatalf("webhook got %+v, want a %s event with violated=%v", event, SLOThroughput, violated)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("webhook not called for violated=%v", violated)
+	}
+}
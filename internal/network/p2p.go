@@ -1,6 +1,7 @@
 package network
 
 import (
+        "encoding/json"
         "fmt"
         "lscc-blockchain/config"
         "lscc-blockchain/internal/blockchain"
@@ -8,6 +9,7 @@ import (
         "lscc-blockchain/internal/utils"
         "lscc-blockchain/pkg/types"
         "net"
+        "net/http"
         "os"
         "strings"
         "sync"
@@ -71,6 +73,7 @@ func NewP2PNetwork(cfg *config.Config, bc *blockchain.Blockchain, sm *sharding.S
                 StartTime:          startTime,
                 LastSeen:           startTime,
                 Version:            cfg.App.Version,
+                ChainID:            cfg.Network.ChainID,
         }
         
         // Auto-detect external IP if not provided
@@ -90,7 +93,7 @@ func NewP2PNetwork(cfg *config.Config, bc *blockchain.Blockchain, sm *sharding.S
                 "timestamp": startTime,
         })
         
-        return &P2PNetwork{
+        p2p := &P2PNetwork{
                 config:         cfg,
                 blockchain:     bc,
                 shardManager:   sm,
@@ -103,7 +106,15 @@ func NewP2PNetwork(cfg *config.Config, bc *blockchain.Blockchain, sm *sharding.S
                 stopChan:       make(chan struct{}),
                 startTime:      startTime,
                 messageQueue:   make(chan types.CrossAlgorithmMessage, 100),
-        }, nil
+        }
+        
+        // Blocks missed while behind are fetched from peers
+        if bc != nil {
+                bc.SetBackfillHandler(p2p.RequestBlockRange)
+                bc.SetPeerCounter(p2p.PeerCount)
+        }
+        
+        return p2p, nil
 }
 
 // Start starts the P2P network
@@ -176,6 +187,13 @@ func (p2p *P2PNetwork) GetPeers() map[string]*NetworkPeer {
         return peers
 }
 
+// PeerCount returns the number of connected peers
+func (p2p *P2PNetwork) PeerCount() int {
+        p2p.mu.RLock()
+        defer p2p.mu.RUnlock()
+        return len(p2p.peers)
+}
+
 // GetAlgorithmPeers returns peers grouped by consensus algorithm
 func (p2p *P2PNetwork) GetAlgorithmPeers() map[types.ConsensusAlgorithm][]types.NetworkPeer {
         p2p.mu.RLock()
@@ -205,8 +223,20 @@ func (p2p *P2PNetwork) GetNodeInfo() *types.NodeInfo {
         return &nodeInfoCopy
 }
 
-// AddPeer adds a new peer
+// AddPeer adds a new peer. Peers that reported a different chain ID during
+// the handshake are rejected.
 func (p2p *P2PNetwork) AddPeer(peer *NetworkPeer) error {
+        if peer.ChainID != "" && peer.ChainID != p2p.nodeInfo.ChainID {
+                p2p.logger.LogBlockchain("peer_rejected", logrus.Fields{
+                        "peer_id": peer.ID,
+                        "address": peer.Address,
+                        "peer_chain_id": peer.ChainID,
+                        "chain_id": p2p.nodeInfo.ChainID,
+                        "timestamp": time.Now().UTC(),
+                })
+                return fmt.Errorf("%w: peer %s is on %s, expected %s", types.ErrChainIDMismatch, peer.Address, peer.ChainID, p2p.nodeInfo.ChainID)
+        }
+        
         p2p.mu.Lock()
         defer p2p.mu.Unlock()
         
@@ -506,16 +536,25 @@ func (p2p *P2PNetwork) connectToPeer(address string) error {
                 5004: types.AlgorithmLSCC,
         }
         
+        apiReachable := false
         for port, algorithm := range algorithmPorts {
                 testAddr := fmt.Sprintf("%s:%d", parts[0], port)
                 if testConn, testErr := net.DialTimeout("tcp", testAddr, 1*time.Second); testErr == nil {
                         testConn.Close()
                         consensusAlgorithm = algorithm
                         httpAddress = testAddr
+                        apiReachable = true
                         break
                 }
         }
         
+        // Handshake: learn which chain the peer is on before accepting it
+        var chainID, apiAddress string
+        if apiReachable {
+                chainID = p2p.handshake(httpAddress)
+                apiAddress = httpAddress
+        }
+        
         // Log connection result
         if err != nil {
                 p2p.logger.LogBlockchain("peer_connection_failed", logrus.Fields{
@@ -540,9 +579,11 @@ func (p2p *P2PNetwork) connectToPeer(address string) error {
                         Role:               types.RoleValidator,
                         ExternalIP:         parts[0],
                         LastSeen:           time.Now(),
+                        ChainID:            chainID,
                 },
                 Address:   parts[0],
                 Port:      9000, // P2P port
+                APIAddress: apiAddress,
                 Connected: isConnected,
                 Latency:   time.Millisecond * 50,
                 LastPing:  time.Now(),
@@ -551,6 +592,32 @@ func (p2p *P2PNetwork) connectToPeer(address string) error {
         return p2p.AddPeer(peer)
 }
 
+// handshake asks a peer's API for its node info and returns the chain ID it
+// reports, or "" when the peer does not answer or predates chain IDs
+func (p2p *P2PNetwork) handshake(httpAddress string) string {
+        client := &http.Client{Timeout: 2 * time.Second}
+        resp, err := client.Get(fmt.Sprintf("http://%s/api/v1/network/node-info", httpAddress))
+        if err != nil {
+                p2p.logger.LogBlockchain("peer_handshake_failed", logrus.Fields{
+                        "http_address": httpAddress,
+                        "error": err.Error(),
+                        "timestamp": time.Now().UTC(),
+                })
+                return ""
+        }
+        defer resp.Body.Close()
+        
+        var info struct {
+                NodeInfo struct {
+                        ChainID string `json:"chain_id"`
+                } `json:"node_info"`
+        }
+        if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+                return ""
+        }
+        return info.NodeInfo.ChainID
+}
+
 // sendDiscoveryMessage sends a discovery message to a specific peer
 func (p2p *P2PNetwork) sendDiscoveryMessage(peer *NetworkPeer, message PeerDiscoveryMessage) {
         p2p.logger.LogBlockchain("sending_discovery_message", logrus.Fields{
@@ -667,6 +734,9 @@ func (p2p *P2PNetwork) discoverLocalMultiAlgorithmPeers() {
                 }
                 
                 if isConnected {
+                        // HTTP API ports mirror the P2P ports (9001 -> 5001)
+                        chainID := p2p.handshake(net.JoinHostPort(localIP, fmt.Sprint(p2pPort-4000)))
+                        
                         // Create peer entry for this algorithm
                         peer := &NetworkPeer{
                                 NodeInfo: types.NodeInfo{
@@ -675,6 +745,7 @@ func (p2p *P2PNetwork) discoverLocalMultiAlgorithmPeers() {
                                         Role:               types.RoleValidator,
                                         ExternalIP:         localIP,
                                         LastSeen:           time.Now(),
+                                        ChainID:            chainID,
                                 },
                                 Address:   localIP,
                                 Port:      p2pPort,
@@ -683,7 +754,9 @@ func (p2p *P2PNetwork) discoverLocalMultiAlgorithmPeers() {
                                 LastPing:  time.Now(),
                         }
                         
-                        p2p.AddPeer(peer)
+                        if err := p2p.AddPeer(peer); err != nil {
+                                continue
+                        }
                         
                         p2p.logger.LogBlockchain("local_algorithm_peer_discovered", logrus.Fields{
                                 "algorithm": algorithm,
@@ -0,0 +1,209 @@
+package network
+
+import (
+        "encoding/json"
+        "errors"
+        "fmt"
+        "io"
+        "lscc-blockchain/internal/blockchain"
+        "lscc-blockchain/pkg/types"
+        "net/http"
+        "sort"
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// ErrNoSyncPeers is returned when blocks are requested while no peer on this
+// chain has a reachable API
+var ErrNoSyncPeers = errors.New("no peers to fetch blocks from")
+
+// blockBatch is one slice of a requested range, fetched by a single request
+type blockBatch struct {
+        from, to int64
+        blocks   []*types.Block
+        err      error
+        done     chan struct{}
+}
+
+// RequestBlockRange fetches the blocks from index from to index to, inclusive,
+// from peers and adds them to the chain in order. The range is split into
+// batches of network.sync_batch_size blocks, up to network.sync_concurrency
+// of which are fetched at once; each batch is tried against every peer until
+// one returns it complete and correctly linked. Batches are imported in
+// order as they arrive, so a failed batch stops the blocks after it from
+// being applied. A range wider than network.sync_max_span blocks is refused;
+// callers fetch a longer gap over several requests.
+func (p2p *P2PNetwork) RequestBlockRange(from, to int64) error {
+        if from < 0 || to < from {
+                return fmt.Errorf("invalid block range %d-%d", from, to)
+        }
+        if maxSpan := int64(p2p.config.Network.SyncMaxSpan); maxSpan > 0 && to-from+1 > maxSpan {
+                return fmt.Errorf("block range %d-%d spans %d blocks, more than the %d allowed", from, to, to-from+1, maxSpan)
+        }
+
+        peers := p2p.syncPeers()
+        if len(peers) == 0 {
+                return ErrNoSyncPeers
+        }
+
+        batchSize := int64(p2p.config.Network.SyncBatchSize)
+        if batchSize < 1 {
+                batchSize = 1
+        }
+        concurrency := p2p.config.Network.SyncConcurrency
+        if concurrency < 1 {
+                concurrency = 1
+        }
+
+        startTime := time.Now()
+        p2p.logger.LogBlockchain("request_block_range", logrus.Fields{
+                "from": from,
+                "to": to,
+                "peer_count": len(peers),
+                "batch_size": batchSize,
+                "concurrency": concurrency,
+                "timestamp": startTime.UTC(),
+        })
+
+        batches := make([]*blockBatch, 0, (to-from)/batchSize+1)
+        for start := from; start <= to; start += batchSize {
+                end := start + batchSize - 1
+                if end > to {
+                        end = to
+                }
+                batches = append(batches, &blockBatch{from: start, to: end, done: make(chan struct{})})
+        }
+
+        // Fetchers stop picking up batches once an import has failed
+        abort := make(chan struct{})
+        defer close(abort)
+
+        go func() {
+                slots := make(chan struct{}, concurrency)
+                for i, batch := range batches {
+                        select {
+                        case slots <- struct{}{}:
+                        case <-abort:
+                                return
+                        }
+                        go func(i int, batch *blockBatch) {
+                                defer func() { <-slots }()
+                                batch.blocks, batch.err = p2p.fetchBatch(peers, i, batch.from, batch.to)
+                                close(batch.done)
+                        }(i, batch)
+                }
+        }()
+
+        imported := 0
+        for _, batch := range batches {
+                <-batch.done
+                if batch.err != nil {
+                        return fmt.Errorf("failed to fetch blocks %d-%d: %w", batch.from, batch.to, batch.err)
+                }
+                count, err := p2p.blockchain.ImportBlocks(batch.blocks)
+                imported += count
+                if err != nil {
+                        return fmt.Errorf("failed to import blocks %d-%d: %w", batch.from, batch.to, err)
+                }
+        }
+
+        p2p.logger.LogBlockchain("block_range_imported", logrus.Fields{
+                "from": from,
+                "to": to,
+                "imported": imported,
+                "height": p2p.blockchain.GetBlockHeight(),
+                "duration": time.Since(startTime).Milliseconds(),
+                "timestamp": time.Now().UTC(),
+        })
+
+        return nil
+}
+
+// syncPeers returns the peers on this chain whose API is reachable, lowest
+// latency first
+func (p2p *P2PNetwork) syncPeers() []*NetworkPeer {
+        p2p.mu.RLock()
+        defer p2p.mu.RUnlock()
+
+        peers := make([]*NetworkPeer, 0, len(p2p.peers))
+        for _, peer := range p2p.peers {
+                if peer.APIAddress == "" {
+                        continue
+                }
+                if peer.ChainID != "" && peer.ChainID != p2p.config.Network.ChainID {
+                        continue
+                }
+                peers = append(peers, peer)
+        }
+
+        sort.Slice(peers, func(i, j int) bool {
+                if peers[i].Latency != peers[j].Latency {
+                        return peers[i].Latency < peers[j].Latency
+                }
+                return peers[i].ID < peers[j].ID
+        })
+        return peers
+}
+
+// fetchBatch asks peers in turn for the blocks from from to to, starting
+// with a different peer for each batch so the load is spread
+func (p2p *P2PNetwork) fetchBatch(peers []*NetworkPeer, batch int, from, to int64) ([]*types.Block, error) {
+        var lastErr error
+        for attempt := 0; attempt < len(peers); attempt++ {
+                peer := peers[(batch+attempt)%len(peers)]
+                blocks, err := p2p.fetchBlocks(peer, from, to)
+                if err == nil {
+                        return blocks, nil
+                }
+
+                p2p.logger.LogBlockchain("block_fetch_failed", logrus.Fields{
+                        "peer_id": peer.ID,
+                        "api_address": peer.APIAddress,
+                        "from": from,
+                        "to": to,
+                        "error": err.Error(),
+                        "timestamp": time.Now().UTC(),
+                })
+                lastErr = err
+        }
+        return nil, lastErr
+}
+
+// fetchBlocks reads the blocks from from to to from a peer's block export
+// and checks that the peer sent the whole range as one linked chain
+func (p2p *P2PNetwork) fetchBlocks(peer *NetworkPeer, from, to int64) ([]*types.Block, error) {
+        client := &http.Client{Timeout: time.Duration(p2p.config.Network.Timeout) * time.Second}
+        resp, err := client.Get(fmt.Sprintf("http://%s/export/blocks?from=%d&to=%d", peer.APIAddress, from, to))
+        if err != nil {
+                return nil, err
+        }
+        defer resp.Body.Close()
+
+        if resp.StatusCode != http.StatusOK {
+                return nil, fmt.Errorf("peer answered %s", resp.Status)
+        }
+
+        blocks := make([]*types.Block, 0, to-from+1)
+        decoder := json.NewDecoder(resp.Body)
+        for {
+                var block types.Block
+                if err := decoder.Decode(&block); err == io.EOF {
+                        break
+                } else if err != nil {
+                        return nil, fmt.Errorf("failed to decode block: %w", err)
+                }
+                blocks = append(blocks, &block)
+        }
+
+        if int64(len(blocks)) != to-from+1 {
+                return nil, fmt.Errorf("peer sent %d of %d blocks", len(blocks), to-from+1)
+        }
+        if blocks[0].Index != from {
+                return nil, fmt.Errorf("peer sent block %d, expected %d", blocks[0].Index, from)
+        }
+        if err := blockchain.CheckBlockLinkage(blocks); err != nil {
+                return nil, err
+        }
+        return blocks, nil
+}
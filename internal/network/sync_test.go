@@ -0,0 +1,339 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/blockchain"
+	"lscc-blockchain/internal/storage"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+)
+
+const testValidator = "0xaaaa"
+
+// newSyncNode returns a P2P network over an empty chain built from a
+// genesis file funding sender, and the main-chain blocks a peer holds on
+// top of that genesis: count blocks of one transfer each
+func newSyncNode(t *testing.T, count int, configure func(cfg *config.Config)) (*P2PNetwork, []*types.Block) {
+	t.Helper()
+	key, err := utils.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := utils.SigningKeyToAddress(key.PubKey())
+
+	cfg, err := config.LoadConfigFromPath("../../config/config.yaml")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Consensus.Algorithm = "lscc"
+	cfg.Node.ExternalIP = "127.0.0.1"
+	cfg.Genesis.Path = writeGenesis(t, &blockchain.Genesis{
+		ChainID:    cfg.Network.ChainID,
+		Timestamp:  time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		Alloc:      map[string]int64{sender: 1000},
+		Validators: []blockchain.GenesisValidator{{Address: testValidator, Stake: 1000}},
+	})
+	if configure != nil {
+		configure(cfg)
+	}
+
+	logger := utils.NewLogger()
+	logger.Logger.SetOutput(io.Discard)
+	bc, err := blockchain.NewBlockchain(cfg, storage.NewMemoryDB(), logger)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	p2p, err := NewP2PNetwork(cfg, bc, nil, logger)
+	if err != nil {
+		t.Fatalf("failed to create P2P network: %v", err)
+	}
+
+	txManager := blockchain.NewTransactionManager(cfg.Sharding.NumShards, logger)
+	blockManager := blockchain.NewBlockManager(logger, 0, cfg.Consensus.MaxTxPerBlock, cfg.Consensus.MaxBlockSize, cfg.Network.ChainID)
+	recipient := "0x" + strings.Repeat("b", 40)
+	parent := bc.GetGenesisBlock()
+	blocks := make([]*types.Block, 0, count)
+	for i := 1; i <= count; i++ {
+		tx, err := txManager.CreateTransaction(sender, recipient, 10, 10, nil, key)
+		if err != nil {
+			t.Fatalf("failed to create transaction: %v", err)
+		}
+		tx.Nonce = int64(i)
+		tx.ID = tx.Hash()
+		if err := utils.SignTransaction(tx, key); err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		block, err := blockManager.CreateBlock(parent, []*types.Transaction{tx}, testValidator, 0)
+		if err != nil {
+			t.Fatalf("failed to create block: %v", err)
+		}
+		blocks = append(blocks, block)
+		parent = block
+	}
+	return p2p, blocks
+}
+
+func writeGenesis(t *testing.T, genesis *blockchain.Genesis) string {
+	t.Helper()
+	data, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// exportPeer serves blocks the way a node's /export/blocks does, passing
+// each requested range through serve, and tracks the requests it answers
+type exportPeer struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	ranges   [][2]int64
+	inFlight int
+	peak     int
+}
+
+func newExportPeer(t *testing.T, blocks []*types.Block, serve func(from, to int64, blocks []*types.Block) []*types.Block) *exportPeer {
+	t.Helper()
+	peer := &exportPeer{}
+	peer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		peer.mu.Lock()
+		peer.ranges = append(peer.ranges, [2]int64{from, to})
+		peer.inFlight++
+		if peer.inFlight > peer.peak {
+			peer.peak = peer.inFlight
+		}
+		peer.mu.Unlock()
+		defer func() {
+			peer.mu.Lock()
+			peer.inFlight--
+			peer.mu.Unlock()
+		}()
+
+		// Long enough for concurrent batches to overlap
+		time.Sleep(20 * time.Millisecond)
+
+		requested := make([]*types.Block, 0, to-from+1)
+		for _, block := range blocks {
+			if block.Index >= from && block.Index <= to {
+				requested = append(requested, block)
+			}
+		}
+		if serve != nil {
+			requested = serve(from, to, requested)
+		}
+		encoder := json.NewEncoder(w)
+		for _, block := range requested {
+			encoder.Encode(block)
+		}
+	}))
+	t.Cleanup(peer.server.Close)
+	return peer
+}
+
+// addSyncPeer registers peer as a connected peer on p2p's chain
+func addSyncPeer(p2p *P2PNetwork, id string, peer *exportPeer, latency time.Duration) {
+	p2p.mu.Lock()
+	defer p2p.mu.Unlock()
+	p2p.peers[id] = &NetworkPeer{
+		NodeInfo:   types.NodeInfo{ID: id, ChainID: p2p.config.Network.ChainID},
+		APIAddress: strings.TrimPrefix(peer.server.URL, "http://"),
+		Connected:  true,
+		Latency:    latency,
+	}
+}
+
+func TestGapBackfilledFromPeers(t *testing.T) {
+	p2p, blocks := newSyncNode(t, 7, func(cfg *config.Config) {
+		cfg.Network.SyncBatchSize = 2
+		cfg.Network.SyncConcurrency = 2
+	})
+	peer := newExportPeer(t, blocks, nil)
+	addSyncPeer(p2p, "peer-1", peer, time.Millisecond)
+	bc := p2p.blockchain
+
+	// The peer's head arrives while blocks 1 to 6 are missing
+	head := blocks[len(blocks)-1]
+	if err := bc.AddOrphan(head); err != nil {
+		t.Fatalf("failed to accept the peer's head: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for bc.IsBackfilling() || bc.GetBlockHeight() != head.Index {
+		if time.Now().After(deadline) {
+			t.Fatalf("node at height %d, want the peer's %d", bc.GetBlockHeight(), head.Index)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if bc.GetLatestBlock().Hash != head.Hash {
+		t.Fatalf("node's head is %s, want the peer's %s", bc.GetLatestBlock().Hash, head.Hash)
+	}
+
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+	requested := make(map[[2]int64]bool)
+	for _, r := range peer.ranges {
+		requested[r] = true
+	}
+	for _, want := range [][2]int64{{1, 2}, {3, 4}, {5, 6}} {
+		if !requested[want] {
+			t.Fatalf("batch %d-%d never requested: %v", want[0], want[1], peer.ranges)
+		}
+	}
+	if len(peer.ranges) != 3 {
+		t.Fatalf("expected 3 batch requests, got %v", peer.ranges)
+	}
+	if peer.peak > 2 {
+		t.Fatalf("%d batches fetched at once, configured for 2", peer.peak)
+	}
+}
+
+func TestRequestBlockRangeSkipsBadPeer(t *testing.T) {
+	p2p, blocks := newSyncNode(t, 4, nil)
+
+	// The fastest peer leaves out a block from every range
+	broken := newExportPeer(t, blocks, func(from, to int64, blocks []*types.Block) []*types.Block {
+		if len(blocks) < 2 {
+			return blocks
+		}
+		return append(blocks[:1:1], blocks[2:]...)
+	})
+	good := newExportPeer(t, blocks, nil)
+	addSyncPeer(p2p, "peer-broken", broken, time.Millisecond)
+	addSyncPeer(p2p, "peer-good", good, 10*time.Millisecond)
+
+	if err := p2p.RequestBlockRange(1, 4); err != nil {
+		t.Fatalf("failed to fetch from the good peer: %v", err)
+	}
+	if got := p2p.blockchain.GetBlockHeight(); got != 4 {
+		t.Fatalf("node at height %d, want 4", got)
+	}
+	broken.mu.Lock()
+	defer broken.mu.Unlock()
+	if len(broken.ranges) == 0 {
+		t.Fatal("lowest latency peer never asked")
+	}
+}
+
+func TestRequestBlockRangeStopsAtBadBatch(t *testing.T) {
+	p2p, blocks := newSyncNode(t, 4, func(cfg *config.Config) {
+		cfg.Network.SyncBatchSize = 2
+	})
+
+	// Every peer sends blocks 3 and 4 from another chain
+	forged := newExportPeer(t, blocks, func(from, to int64, blocks []*types.Block) []*types.Block {
+		if from != 3 {
+			return blocks
+		}
+		forged := make([]*types.Block, len(blocks))
+		for i, block := range blocks {
+			copied := *block
+			copied.ChainID = "other-chain"
+			copied.Hash = copied.ComputeHash()
+			if i > 0 {
+				copied.PreviousHash = forged[i-1].Hash
+			}
+			forged[i] = &copied
+		}
+		return forged
+	})
+	addSyncPeer(p2p, "peer-forged", forged, time.Millisecond)
+
+	if err := p2p.RequestBlockRange(1, 4); err == nil {
+		t.Fatal("expected blocks from another chain refused")
+	}
+	if got := p2p.blockchain.GetBlockHeight(); got != 2 {
+		t.Fatalf("node at height %d, want the batch before the bad one applied", got)
+	}
+}
+
+func TestRequestBlockRangeWithoutPeers(t *testing.T) {
+	p2p, _ := newSyncNode(t, 0, nil)
+	if err := p2p.RequestBlockRange(1, 4); !errors.Is(err, ErrNoSyncPeers) {
+		t.Fatalf("expected ErrNoSyncPeers, got %v", err)
+	}
+
+	// Peers on another chain, or without a reachable API, do not count
+	peer := newExportPeer(t, nil, nil)
+	addSyncPeer(p2p, "peer-other", peer, time.Millisecond)
+	p2p.peers["peer-other"].ChainID = "other-chain"
+	p2p.peers["peer-offline"] = &NetworkPeer{NodeInfo: types.NodeInfo{ID: "peer-offline"}}
+	if err := p2p.RequestBlockRange(1, 4); !errors.Is(err, ErrNoSyncPeers) {
+		t.Fatalf("expected ErrNoSyncPeers, got %v", err)
+	}
+
+	for _, r := range [][2]int64{{-1, 2}, {4, 3}} {
+		if err := p2p.RequestBlockRange(r[0], r[1]); err == nil || errors.Is(err, ErrNoSyncPeers) {
+			t.Fatalf("range %v: expected it refused as invalid, got %v", r, err)
+		}
+	}
+}
+
+func TestRequestBlockRangeRefusesWideRange(t *testing.T) {
+	p2p, blocks := newSyncNode(t, 4, func(cfg *config.Config) {
+		cfg.Network.SyncMaxSpan = 3
+	})
+	peer := newExportPeer(t, blocks, nil)
+	addSyncPeer(p2p, "peer-1", peer, time.Millisecond)
+
+	if err := p2p.RequestBlockRange(1, 4); err == nil {
+		t.Fatal("range wider than the sync max span accepted")
+	}
+	peer.mu.Lock()
+	requested := len(peer.ranges)
+	peer.mu.Unlock()
+	if requested != 0 {
+		t.Fatalf("refused range still fetched: %d requests", requested)
+	}
+
+	if err := p2p.RequestBlockRange(1, 3); err != nil {
+		t.Fatalf("range within the sync max span refused: %v", err)
+	}
+	if got := p2p.blockchain.GetBlockHeight(); got != 3 {
+		t.Fatalf("node at height %d, want 3", got)
+	}
+}
+
+func TestWideGapBackfilledFromPeersOverRounds(t *testing.T) {
+	p2p, blocks := newSyncNode(t, 8, func(cfg *config.Config) {
+		cfg.Network.SyncMaxSpan = 3
+	})
+	peer := newExportPeer(t, blocks, nil)
+	addSyncPeer(p2p, "peer-1", peer, time.Millisecond)
+	bc := p2p.blockchain
+
+	// The peer's head arrives while blocks 1 to 7 are missing
+	head := blocks[len(blocks)-1]
+	if err := bc.AddOrphan(head); err != nil {
+		t.Fatalf("failed to accept the peer's head: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for bc.IsBackfilling() || bc.GetBlockHeight() != head.Index {
+		if time.Now().After(deadline) {
+			t.Fatalf("node at height %d, want the peer's %d", bc.GetBlockHeight(), head.Index)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if bc.GetLatestBlock().Hash != head.Hash {
+		t.Fatalf("node's head is %s, want the peer's %s", bc.GetLatestBlock().Hash, head.Hash)
+	}
+}
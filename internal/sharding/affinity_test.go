@@ -0,0 +1,67 @@
+package sharding
+
+import (
+	"fmt"
+	"testing"
+
+	"lscc-blockchain/pkg/types"
+)
+
+// addressesOnDifferentShards returns two addresses the router places on
+// different shards when neither has an affinity
+func addressesOnDifferentShards(t *testing.T, sm *ShardManager) (string, string) {
+	t.Helper()
+	first := "account_0"
+	for i := 1; i < 1000; i++ {
+		other := fmt.Sprintf("account_%d", i)
+		if sm.GetShardForAddress(other) != sm.GetShardForAddress(first) {
+			return first, other
+		}
+	}
+	t.Fatal("every address routed to one shard")
+	return "", ""
+}
+
+func TestAffinityGroupSharesShard(t *testing.T) {
+	sm := newTestShardManager(t, nil)
+	alice, bob := addressesOnDifferentShards(t, sm)
+
+	sm.SetAffinity(alice, "exchange")
+	sm.SetAffinity(bob, "exchange")
+	if a, b := sm.GetShardForAddress(alice), sm.GetShardForAddress(bob); a != b {
+		t.Fatalf("same group routed to shards %d and %d", a, b)
+	}
+	from, to := sm.crossShardRouter.RouteTransaction(&types.Transaction{From: alice, To: bob})
+	if from != to {
+		t.Fatalf("transfer within a group routed from shard %d to %d", from, to)
+	}
+
+	sm.SetAffinity(bob, "")
+	if got := sm.crossShardRouter.GetAffinity(bob); got != "" {
+		t.Fatalf("affinity after removal = %q", got)
+	}
+	if a, b := sm.GetShardForAddress(alice), sm.GetShardForAddress(bob); a == b {
+		t.Fatalf("address left in group %d after removal", b)
+	}
+}
+
+func TestTransactionAffinityHint(t *testing.T) {
+	sm := newTestShardManager(t, nil)
+	alice, bob := addressesOnDifferentShards(t, sm)
+
+	from, to := sm.crossShardRouter.RouteTransaction(&types.Transaction{From: alice, To: bob})
+	if from == to {
+		t.Fatal("accounts without a hint routed to one shard")
+	}
+	from, to = sm.crossShardRouter.RouteTransaction(&types.Transaction{From: alice, To: bob, AffinityGroup: "game"})
+	if from != to {
+		t.Fatalf("hinted transfer routed from shard %d to %d", from, to)
+	}
+
+	// A registered group outranks the transaction's hint
+	sm.SetAffinity(alice, "exchange")
+	from, _ = sm.crossShardRouter.RouteTransaction(&types.Transaction{From: alice, To: bob, AffinityGroup: "game"})
+	if want := sm.GetShardForAddress(alice); from != want {
+		t.Fatalf("registered account routed to shard %d, want %d", from, want)
+	}
+}
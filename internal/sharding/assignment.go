@@ -0,0 +1,93 @@
+package sharding
+
+import (
+        "crypto/sha256"
+        "encoding/binary"
+        "fmt"
+        "lscc-blockchain/internal/utils"
+        "sort"
+)
+
+// Shard assignment strategies
+const (
+        AssignmentModulo     = "modulo"     // hash the address modulo the shard count
+        AssignmentConsistent = "consistent" // place shards on a hash ring of virtual nodes
+)
+
+// shardAssigner maps a routing key to a shard
+type shardAssigner interface {
+        shardFor(key string) int
+}
+
+// newShardAssigner builds the assigner for strategy. Unknown strategies fall
+// back to modulo hashing.
+func newShardAssigner(strategy string, numShards, virtualNodes int) shardAssigner {
+        if strategy == AssignmentConsistent {
+                return newHashRing(numShards, virtualNodes)
+        }
+        return moduloAssigner{numShards: numShards}
+}
+
+// moduloAssigner hashes keys modulo the shard count. Changing the shard count
+// moves most keys.
+type moduloAssigner struct {
+        numShards int
+}
+
+func (m moduloAssigner) shardFor(key string) int {
+        return utils.GenerateShardKey(key, m.numShards)
+}
+
+// ringPoint is one virtual node on a hashRing
+type ringPoint struct {
+        hash  uint64
+        shard int
+}
+
+// hashRing is a consistent hash ring. Each shard owns virtualNodes points and
+// a key belongs to the shard of the first point at or after its hash, so
+// adding or removing a shard only moves the keys between its points and
+// their predecessors, about 1/N of the total.
+type hashRing struct {
+        points []ringPoint
+}
+
+func newHashRing(numShards, virtualNodes int) *hashRing {
+        if virtualNodes < 1 {
+                virtualNodes = 1
+        }
+        ring := &hashRing{points: make([]ringPoint, 0, numShards*virtualNodes)}
+        for shard := 0; shard < numShards; shard++ {
+                for v := 0; v < virtualNodes; v++ {
+                        ring.points = append(ring.points, ringPoint{
+                                hash:  ringHash(fmt.Sprintf("shard-%d#%d", shard, v)),
+                                shard: shard,
+                        })
+                }
+        }
+        sort.Slice(ring.points, func(i, j int) bool {
+                if ring.points[i].hash != ring.points[j].hash {
+                        return ring.points[i].hash < ring.points[j].hash
+                }
+                return ring.points[i].shard < ring.points[j].shard
+        })
+        return ring
+}
+
+func (r *hashRing) shardFor(key string) int {
+        if len(r.points) == 0 {
+                return 0
+        }
+        h := ringHash(key)
+        i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+        if i == len(r.points) {
+                i = 0
+        }
+        return r.points[i].shard
+}
+
+// ringHash places a key on the ring
+func ringHash(key string) uint64 {
+        sum := sha256.Sum256([]byte(key))
+        return binary.BigEndian.Uint64(sum[:8])
+}
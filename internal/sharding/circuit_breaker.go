@@ -0,0 +1,154 @@
+package sharding
+
+import (
+        "errors"
+        "sync"
+        "time"
+)
+
+// ErrCircuitOpen is returned by SendMessage while sends to the destination
+// shard are cut off by its circuit breaker
+var ErrCircuitOpen = errors.New("circuit breaker open for destination shard")
+
+// Circuit breaker states
+const (
+        CircuitClosed   = "closed"    // sends go through
+        CircuitOpen     = "open"      // sends fail fast until the cooldown ends
+        CircuitHalfOpen = "half_open" // one probe send decides whether to close or reopen
+)
+
+// CircuitBreakerStatus is a point-in-time copy of one shard's breaker
+type CircuitBreakerStatus struct {
+        State               string     `json:"state"`
+        ConsecutiveFailures int        `json:"consecutive_failures"`
+        OpenedAt            *time.Time `json:"opened_at,omitempty"`
+        Trips               int64      `json:"trips"`    // times the breaker has opened
+        Rejected            int64      `json:"rejected"` // sends failed fast while open or probing
+}
+
+// circuitBreaker cuts off sends to a shard that keeps refusing them. It
+// opens after threshold consecutive failures and half-opens once cooldown
+// has passed, letting one send through as a probe; a probe whose outcome is
+// never recorded is replaced after another cooldown. A nil breaker allows
+// every send.
+type circuitBreaker struct {
+        mu        sync.Mutex
+        threshold int
+        cooldown  time.Duration
+        state     string
+        failures  int
+        openedAt  time.Time
+        probeAt   time.Time // when the in-flight half-open probe was let through
+        trips     int64
+        rejected  int64
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+        return &circuitBreaker{
+                threshold: threshold,
+                cooldown:  cooldown,
+                state:     CircuitClosed,
+        }
+}
+
+// allow reports whether a send may go ahead at now
+func (b *circuitBreaker) allow(now time.Time) bool {
+        if b == nil {
+                return true
+        }
+        b.mu.Lock()
+        defer b.mu.Unlock()
+
+        if b.state == CircuitOpen {
+                if now.Sub(b.openedAt) < b.cooldown {
+                        b.rejected++
+                        return false
+                }
+                b.state = CircuitHalfOpen
+                b.probeAt = time.Time{}
+        }
+        if b.state == CircuitHalfOpen {
+                if !b.probeAt.IsZero() && now.Sub(b.probeAt) < b.cooldown {
+                        b.rejected++
+                        return false
+                }
+                b.probeAt = now
+        }
+        return true
+}
+
+// record notes the outcome of a send that allow let through and returns the
+// breaker's state before and after
+func (b *circuitBreaker) record(now time.Time, err error) (from, to string) {
+        if b == nil {
+                return CircuitClosed, CircuitClosed
+        }
+        b.mu.Lock()
+        defer b.mu.Unlock()
+
+        from = b.state
+        if err == nil {
+                b.state = CircuitClosed
+                b.failures = 0
+                b.probeAt = time.Time{}
+                return from, b.state
+        }
+
+        b.failures++
+        if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+                if b.state != CircuitOpen {
+                        b.trips++
+                }
+                b.state = CircuitOpen
+                b.openedAt = now
+                b.probeAt = time.Time{}
+        }
+        return from, b.state
+}
+
+// status copies the breaker's state
+func (b *circuitBreaker) status() *CircuitBreakerStatus {
+        b.mu.Lock()
+        defer b.mu.Unlock()
+
+        status := &CircuitBreakerStatus{
+                State:               b.state,
+                ConsecutiveFailures: b.failures,
+                Trips:               b.trips,
+                Rejected:            b.rejected,
+        }
+        if b.state != CircuitClosed {
+                openedAt := b.openedAt
+                status.OpenedAt = &openedAt
+        }
+        return status
+}
+
+// breaker returns the circuit breaker for sends to shardID, creating it on
+// first use, or nil when circuit breaking is disabled
+func (csc *CrossShardCommunicator) breaker(shardID int) *circuitBreaker {
+        if csc.breakerLimit <= 0 {
+                return nil
+        }
+        csc.breakerMu.Lock()
+        defer csc.breakerMu.Unlock()
+
+        b, exists := csc.breakers[shardID]
+        if !exists {
+                b = newCircuitBreaker(csc.breakerLimit, csc.breakerCooldown)
+                csc.breakers[shardID] = b
+        }
+        return b
+}
+
+// breakerStatus returns the state of every shard's circuit breaker
+func (csc *CrossShardCommunicator) breakerStatus() map[int]*CircuitBreakerStatus {
+        csc.breakerMu.Lock()
+        defer csc.breakerMu.Unlock()
+
+        status := make(map[int]*CircuitBreakerStatus, len(csc.breakers))
+        for shardID, b := range csc.breakers {
+                status[shardID] = b.status()
+        }
+        return status
+}
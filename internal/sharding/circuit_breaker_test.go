@@ -0,0 +1,181 @@
+package sharding
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+)
+
+// newBreakerCommunicator returns a running communicator whose breakers open
+// after 3 failed sends and probe after 30 seconds of clock
+func newBreakerCommunicator(t *testing.T) (*CrossShardCommunicator, *utils.ManualClock) {
+	t.Helper()
+	sm := newTestShardManager(t, func(cfg *config.Config) {
+		cfg.CrossShard.CircuitBreaker.FailureThreshold = 3
+		cfg.CrossShard.CircuitBreaker.Cooldown = 30
+	})
+	clock := utils.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	csc := NewCrossShardCommunicatorWithClock(sm, sm.logger, clock)
+	if err := csc.Start(); err != nil {
+		t.Fatalf("failed to start communicator: %v", err)
+	}
+	t.Cleanup(func() { csc.Stop() })
+	return csc, clock
+}
+
+// cutOffShard makes shard unreachable by removing its message queue, and
+// returns a function that reconnects it. The shard is reconnected before the
+// communicator stops at the latest, so Stop closes the queue's worker.
+func cutOffShard(t *testing.T, csc *CrossShardCommunicator, shard int) func() {
+	t.Helper()
+	csc.mu.Lock()
+	defer csc.mu.Unlock()
+	queue, exists := csc.messageQueues[shard]
+	if !exists {
+		t.Fatalf("no message queue for shard %d", shard)
+	}
+	delete(csc.messageQueues, shard)
+
+	var once sync.Once
+	reconnect := func() {
+		once.Do(func() {
+			csc.mu.Lock()
+			defer csc.mu.Unlock()
+			csc.messageQueues[shard] = queue
+		})
+	}
+	t.Cleanup(reconnect)
+	return reconnect
+}
+
+func sendTo(csc *CrossShardCommunicator, n, toShard int) error {
+	return csc.SendMessage(&types.CrossShardMessage{
+		ID:        fmt.Sprintf("breaker-%d", n),
+		FromShard: 0,
+		ToShard:   toShard,
+		Type:      "sync",
+	})
+}
+
+func breakerState(t *testing.T, csc *CrossShardCommunicator, shard int) *CircuitBreakerStatus {
+	t.Helper()
+	status, exists := csc.GetMetrics().CircuitBreakers[shard]
+	if !exists {
+		t.Fatalf("no circuit breaker reported for shard %d", shard)
+	}
+	return status
+}
+
+func TestCircuitBreakerOpensAndFailsFast(t *testing.T) {
+	csc, _ := newBreakerCommunicator(t)
+	cutOffShard(t, csc, 1)
+
+	for n := 0; n < 3; n++ {
+		err := sendTo(csc, n, 1)
+		if err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("send %d: expected the unreachable shard's own error, got %v", n, err)
+		}
+	}
+	status := breakerState(t, csc, 1)
+	if status.State != CircuitOpen || status.ConsecutiveFailures != 3 || status.Trips != 1 || status.OpenedAt == nil {
+		t.Fatalf("after 3 failures: %+v", status)
+	}
+
+	// Further sends fail at once without reaching the shard
+	failed := csc.GetMetrics().MessagesFailed
+	for n := 3; n < 6; n++ {
+		if err := sendTo(csc, n, 1); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("send %d: expected ErrCircuitOpen, got %v", n, err)
+		}
+	}
+	if status := breakerState(t, csc, 1); status.Rejected != 3 || status.ConsecutiveFailures != 3 {
+		t.Fatalf("fast-failed sends counted as %+v", status)
+	}
+	if got := csc.GetMetrics().MessagesFailed; got != failed+3 {
+		t.Fatalf("messages failed = %d, want %d", got, failed+3)
+	}
+
+	// Other shards are unaffected
+	if err := sendTo(csc, 6, 2); err != nil {
+		t.Fatalf("send to a healthy shard refused: %v", err)
+	}
+	if status := breakerState(t, csc, 2); status.State != CircuitClosed {
+		t.Fatalf("healthy shard's breaker is %s", status.State)
+	}
+}
+
+func TestCircuitBreakerHalfOpenRecovery(t *testing.T) {
+	csc, clock := newBreakerCommunicator(t)
+	reconnect := cutOffShard(t, csc, 1)
+	for n := 0; n < 3; n++ {
+		sendTo(csc, n, 1)
+	}
+
+	clock.Advance(30*time.Second - time.Millisecond)
+	if err := sendTo(csc, 3, 1); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("before the cooldown ends: expected ErrCircuitOpen, got %v", err)
+	}
+
+	// A failed probe reopens the breaker for another cooldown
+	clock.Advance(time.Millisecond)
+	if err := sendTo(csc, 4, 1); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("probe: expected it let through and failing, got %v", err)
+	}
+	if status := breakerState(t, csc, 1); status.State != CircuitOpen || status.Trips != 2 {
+		t.Fatalf("after a failed probe: %+v", status)
+	}
+	if err := sendTo(csc, 5, 1); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("after a failed probe: expected ErrCircuitOpen, got %v", err)
+	}
+
+	// Once the shard is back, the next probe closes it
+	reconnect()
+	clock.Advance(30 * time.Second)
+	if err := sendTo(csc, 6, 1); err != nil {
+		t.Fatalf("probe to the recovered shard failed: %v", err)
+	}
+	status := breakerState(t, csc, 1)
+	if status.State != CircuitClosed || status.ConsecutiveFailures != 0 || status.OpenedAt != nil {
+		t.Fatalf("after a successful probe: %+v", status)
+	}
+	if err := sendTo(csc, 7, 1); err != nil {
+		t.Fatalf("send after recovery failed: %v", err)
+	}
+}
+
+func TestCircuitBreakerLetsOneProbeThrough(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker(1, time.Minute)
+	b.record(start, errors.New("unreachable"))
+
+	now := start.Add(time.Minute)
+	if !b.allow(now) {
+		t.Fatal("probe refused after the cooldown")
+	}
+	if b.status().State != CircuitHalfOpen {
+		t.Fatalf("state while probing = %s, want %s", b.status().State, CircuitHalfOpen)
+	}
+	if b.allow(now) {
+		t.Fatal("second send let through while the probe is in flight")
+	}
+
+	// A probe that never reports back is replaced after another cooldown
+	if !b.allow(now.Add(time.Minute)) {
+		t.Fatal("lost probe never replaced")
+	}
+	if from, to := b.record(now.Add(time.Minute), nil); from != CircuitHalfOpen || to != CircuitClosed {
+		t.Fatalf("successful probe moved %s -> %s", from, to)
+	}
+
+	// Disabled breaking allows everything
+	var disabled *circuitBreaker
+	if !disabled.allow(now) {
+		t.Fatal("nil breaker refused a send")
+	}
+}
@@ -0,0 +1,543 @@
+package sharding
+
+import (
+        "errors"
+        "fmt"
+        "lscc-blockchain/internal/blockchain"
+        "lscc-blockchain/internal/consensus"
+        "lscc-blockchain/pkg/types"
+        "sort"
+        "sync"
+        "sync/atomic"
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// Coordination modes for per-shard consensus
+const (
+        CoordinationParallel   = "parallel"   // every shard runs its rounds on its own schedule
+        CoordinationSequential = "sequential" // one round per shard per block time, in shard order
+        CoordinationAdaptive   = "adaptive"   // parallel, or sequential while cross-shard conflicts are frequent
+)
+
+// adaptiveMinSamples is how many cross-shard transactions adaptive
+// coordination waits for before judging the conflict rate
+const adaptiveMinSamples = 20
+
+// errShardBlockRejected is recorded when a shard's engine does not approve
+// its block
+var errShardBlockRejected = errors.New("block not approved by shard consensus")
+
+// shardEngine is the consensus instance driving one shard's chain
+type shardEngine struct {
+        shard     *Shard
+        engine    consensus.Consensus
+        members   map[string]bool // the shard's validators in the current epoch, nil when epochs are disabled; guarded by the coordinator lock
+        roundMu   sync.Mutex      // held from building a block to committing it, by shard and joint cross-shard rounds alike
+        rounds    atomic.Int64
+        committed atomic.Int64
+        failed    atomic.Int64
+}
+
+// ShardConsensusStatus reports one shard's consensus engine
+type ShardConsensusStatus struct {
+        ShardID     int    `json:"shard_id"`
+        Algorithm   string `json:"algorithm"`
+        BlockHeight int64  `json:"block_height"`
+        Rounds      int64  `json:"rounds"`
+        Committed   int64  `json:"committed"`
+        Failed      int64  `json:"failed"`
+}
+
+// startShardConsensus gives every shard its own consensus engine and starts
+// the rounds that build each shard's chain from its transaction pool. Shard
+// chains are kept in memory, apart from the main chain. Caller must hold
+// sm.mu.
+func (sm *ShardManager) startShardConsensus() error {
+        // Read before taking the coordinator lock, which epoch updates from
+        // the main chain take while it is reporting a commit
+        var epoch *blockchain.Epoch
+        if sm.blockchain != nil {
+                epoch, _ = sm.blockchain.GetEpoch()
+        }
+
+        coordinator := sm.consensusCoordinator
+        coordinator.mu.Lock()
+        defer coordinator.mu.Unlock()
+
+        if coordinator.engines != nil {
+                return nil
+        }
+
+        engines := make(map[int]*shardEngine, len(sm.shards))
+        for shardID, shard := range sm.shards {
+                engine, err := consensus.New(sm.config.Consensus.Algorithm, sm.config, sm.logger)
+                if err != nil {
+                        return fmt.Errorf("failed to create consensus for shard %d: %w", shardID, err)
+                }
+                engines[shardID] = &shardEngine{shard: shard, engine: engine}
+        }
+
+        interval := time.Duration(sm.config.Consensus.BlockTime) * time.Second
+        if interval <= 0 {
+                interval = time.Second
+        }
+
+        coordinator.engines = engines
+        coordinator.stopConsensus = make(chan struct{})
+        if epoch != nil {
+                sm.applyEpochLocked(epoch)
+        }
+        if sm.blockchain != nil {
+                coordinator.unsubscribe = sm.blockchain.RegisterCommitObserver(blockchain.BlockCommittedFunc(func(block *types.Block, result blockchain.CommitResult) {
+                        if result.Epoch != nil {
+                                sm.applyEpoch(result.Epoch)
+                        }
+                }))
+        }
+        switch coordinator.coordinationMode {
+        case CoordinationSequential:
+                coordinator.wg.Add(1)
+                go sm.sequentialRounds(engines, interval, coordinator.stopConsensus)
+        case CoordinationAdaptive:
+                coordinator.activeMode = CoordinationParallel
+                coordinator.wg.Add(1)
+                go sm.adaptiveRounds(engines, interval, coordinator.stopConsensus)
+        default:
+                for _, se := range engines {
+                        coordinator.wg.Add(1)
+                        go sm.parallelRounds(se, interval, coordinator.stopConsensus)
+                }
+                coordinator.wg.Add(1)
+                go sm.jointRounds(engines, interval, coordinator.stopConsensus)
+        }
+
+        sm.logger.LogSharding(-1, "shard_consensus_started", logrus.Fields{
+                "algorithm":         sm.config.Consensus.Algorithm,
+                "coordination_mode": coordinator.coordinationMode,
+                "shards":            len(engines),
+                "block_time":        interval.String(),
+                "timestamp":         time.Now().UTC(),
+        })
+        return nil
+}
+
+// stopShardConsensus stops the shard rounds and their engines. It must not
+// be called with sm.mu held.
+func (sm *ShardManager) stopShardConsensus() {
+        coordinator := sm.consensusCoordinator
+        coordinator.mu.Lock()
+        engines := coordinator.engines
+        stop := coordinator.stopConsensus
+        unsubscribe := coordinator.unsubscribe
+        coordinator.engines = nil
+        coordinator.stopConsensus = nil
+        coordinator.activeMode = ""
+        coordinator.unsubscribe = nil
+        coordinator.mu.Unlock()
+
+        if unsubscribe != nil {
+                unsubscribe()
+        }
+        if engines == nil {
+                return
+        }
+        close(stop)
+        coordinator.wg.Wait()
+        sm.returnCrossShardCommits(engines)
+        sm.commMu.Lock()
+        if sm.collector != nil {
+                sm.collector.SetShardCoordinationMode("", coordinator.coordinationMode)
+        }
+        sm.commMu.Unlock()
+
+        for _, se := range engines {
+                if stopper, ok := se.engine.(interface{ Stop() }); ok {
+                        stopper.Stop()
+                }
+        }
+
+        sm.logger.LogSharding(-1, "shard_consensus_stopped", logrus.Fields{
+                "shards":    len(engines),
+                "timestamp": time.Now().UTC(),
+        })
+}
+
+// parallelRounds runs se's shard on its own ticker
+func (sm *ShardManager) parallelRounds(se *shardEngine, interval time.Duration, stop chan struct{}) {
+        defer sm.consensusCoordinator.wg.Done()
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+
+        for {
+                select {
+                case <-stop:
+                        return
+                case <-ticker.C:
+                        sm.runShardRound(se)
+                }
+        }
+}
+
+// sequentialRounds runs one round per shard each interval, lowest shard ID
+// first, so no two shards are ever in a round at once, then a joint round
+// for the cross-shard transactions waiting
+func (sm *ShardManager) sequentialRounds(engines map[int]*shardEngine, interval time.Duration, stop chan struct{}) {
+        defer sm.consensusCoordinator.wg.Done()
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+
+        order := shardOrder(engines)
+        for {
+                select {
+                case <-stop:
+                        return
+                case <-ticker.C:
+                }
+                sm.runRoundsInOrder(engines, order, stop)
+                sm.runJointRound(engines)
+        }
+}
+
+// adaptiveRounds runs a round on every shard each interval: all at once
+// while cross-shard conflicts are rare, and one shard at a time in shard
+// order once they become frequent, so conflicting transfers are not decided
+// by shards racing each other. The mode changes only when the conflict rate
+// crosses the high or low threshold, never on every sample. A joint round
+// for waiting cross-shard transactions runs alongside the shard rounds, or
+// after them while sequential.
+func (sm *ShardManager) adaptiveRounds(engines map[int]*shardEngine, interval time.Duration, stop chan struct{}) {
+        defer sm.consensusCoordinator.wg.Done()
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+
+        order := shardOrder(engines)
+        rate := conflictRateMonitor{
+                high: sm.config.Sharding.AdaptiveHighConflictRate,
+                low:  sm.config.Sharding.AdaptiveLowConflictRate,
+        }
+        rate.conflicts, rate.checked = sm.conflictCounts()
+        mode := CoordinationParallel
+
+        for {
+                select {
+                case <-stop:
+                        return
+                case <-ticker.C:
+                }
+
+                conflicts, checked := sm.conflictCounts()
+                if observed, ok := rate.observe(conflicts, checked); ok {
+                        if next := rate.nextMode(mode, observed); next != mode {
+                                sm.setActiveCoordination(mode, next, observed)
+                                mode = next
+                        }
+                }
+
+                if mode == CoordinationSequential {
+                        sm.runRoundsInOrder(engines, order, stop)
+                        sm.runJointRound(engines)
+                        continue
+                }
+                var wg sync.WaitGroup
+                wg.Add(1)
+                go func() {
+                        defer wg.Done()
+                        sm.runJointRound(engines)
+                }()
+                for _, se := range engines {
+                        wg.Add(1)
+                        go func(se *shardEngine) {
+                                defer wg.Done()
+                                sm.runShardRound(se)
+                        }(se)
+                }
+                wg.Wait()
+        }
+}
+
+// runRoundsInOrder runs one round per shard, lowest shard ID first, stopping
+// early if stop is closed
+func (sm *ShardManager) runRoundsInOrder(engines map[int]*shardEngine, order []int, stop chan struct{}) {
+        for _, shardID := range order {
+                select {
+                case <-stop:
+                        return
+                default:
+                }
+                sm.runShardRound(engines[shardID])
+        }
+}
+
+// shardOrder returns the shard IDs of engines in ascending order
+func shardOrder(engines map[int]*shardEngine) []int {
+        order := make([]int, 0, len(engines))
+        for shardID := range engines {
+                order = append(order, shardID)
+        }
+        sort.Ints(order)
+        return order
+}
+
+// conflictRateMonitor turns the conflict resolver's running totals into a
+// conflict rate per sample of cross-shard transactions
+type conflictRateMonitor struct {
+        high      float64
+        low       float64
+        conflicts int64 // totals at the start of the current sample
+        checked   int64
+}
+
+// observe returns the conflict rate since the last sample once enough
+// cross-shard transactions have been checked. A sample with no cross-shard
+// traffic at all counts as conflict-free.
+func (m *conflictRateMonitor) observe(conflicts, checked int64) (float64, bool) {
+        if checked < m.checked || conflicts < m.conflicts {
+                // The communicator was restarted and its totals began again
+                m.conflicts, m.checked = conflicts, checked
+                return 0, false
+        }
+
+        sampled := checked - m.checked
+        if sampled > 0 && sampled < adaptiveMinSamples {
+                return 0, false
+        }
+        rate := 0.0
+        if sampled > 0 {
+                rate = float64(conflicts-m.conflicts) / float64(sampled)
+        }
+        m.conflicts, m.checked = conflicts, checked
+        return rate, true
+}
+
+// nextMode applies the thresholds to rate: parallel turns sequential at the
+// high rate and sequential turns parallel only at or below the low one
+func (m *conflictRateMonitor) nextMode(mode string, rate float64) string {
+        switch {
+        case mode == CoordinationParallel && rate >= m.high:
+                return CoordinationSequential
+        case mode == CoordinationSequential && rate <= m.low:
+                return CoordinationParallel
+        }
+        return mode
+}
+
+// conflictCounts returns the cross-shard conflict resolver's totals, zero
+// while cross-shard communication is stopped
+func (sm *ShardManager) conflictCounts() (conflicts, checked int64) {
+        sm.commMu.Lock()
+        communicator := sm.communicator
+        sm.commMu.Unlock()
+        if communicator == nil {
+                return 0, 0
+        }
+        return communicator.conflictCounts()
+}
+
+// setActiveCoordination records and logs a switch of adaptive scheduling
+func (sm *ShardManager) setActiveCoordination(from, to string, rate float64) {
+        coordinator := sm.consensusCoordinator
+        coordinator.mu.Lock()
+        coordinator.activeMode = to
+        coordinator.mu.Unlock()
+
+        sm.logger.LogSharding(-1, "coordination_mode_changed", logrus.Fields{
+                "from":          from,
+                "to":            to,
+                "conflict_rate": rate,
+                "high":          sm.config.Sharding.AdaptiveHighConflictRate,
+                "low":           sm.config.Sharding.AdaptiveLowConflictRate,
+                "timestamp":     time.Now().UTC(),
+        })
+}
+
+// ActiveCoordinationMode returns the schedule shard consensus is following:
+// the configured mode, or for adaptive coordination whichever of parallel
+// and sequential it is using now. It is empty unless shard consensus is
+// running.
+func (sm *ShardManager) ActiveCoordinationMode() string {
+        coordinator := sm.consensusCoordinator
+        coordinator.mu.RLock()
+        defer coordinator.mu.RUnlock()
+
+        if coordinator.engines == nil {
+                return ""
+        }
+        if coordinator.activeMode != "" {
+                return coordinator.activeMode
+        }
+        return coordinator.coordinationMode
+}
+
+// applyEpoch recomputes every shard's validator set when the main chain
+// starts a new epoch
+func (sm *ShardManager) applyEpoch(epoch *blockchain.Epoch) {
+        coordinator := sm.consensusCoordinator
+        coordinator.mu.Lock()
+        defer coordinator.mu.Unlock()
+        if coordinator.engines != nil {
+                sm.applyEpochLocked(epoch)
+        }
+}
+
+// applyEpochLocked picks each shard's validators for the epoch by the main
+// chain's rules, applied to the shard's own validators: active, staking at
+// least min_stake, highest stake first up to max_validators. Each engine is
+// handed its shard's set; a shard with none eligible, such as one that is
+// inactive, is left unrestricted until the next epoch. Caller must hold the
+// coordinator lock.
+func (sm *ShardManager) applyEpochLocked(epoch *blockchain.Epoch) {
+        coordinator := sm.consensusCoordinator
+        for shardID, se := range coordinator.engines {
+                validators := sm.shardEpochValidators(se.shard)
+                se.members = nil
+                if len(validators) == 0 {
+                        continue
+                }
+                se.members = make(map[string]bool, len(validators))
+                for _, validator := range validators {
+                        se.members[validator.Address] = true
+                }
+                if err := se.engine.UpdateValidators(validators); err != nil {
+                        sm.logger.LogError("sharding", "update_shard_validators", err, logrus.Fields{
+                                "shard_id":  shardID,
+                                "epoch":     epoch.Number,
+                                "timestamp": time.Now().UTC(),
+                        })
+                }
+                sm.logger.LogSharding(shardID, "shard_epoch_started", logrus.Fields{
+                        "epoch":      epoch.Number,
+                        "validators": len(validators),
+                        "timestamp":  time.Now().UTC(),
+                })
+        }
+}
+
+// shardEpochValidators returns the shard's validators eligible for an
+// epoch, highest stake first
+func (sm *ShardManager) shardEpochValidators(shard *Shard) []*types.Validator {
+        eligible := make([]*types.Validator, 0)
+        for _, validator := range shard.consensusValidators() {
+                if validator.Stake >= sm.config.Consensus.MinStake {
+                        eligible = append(eligible, validator)
+                }
+        }
+        sort.SliceStable(eligible, func(i, j int) bool {
+                if eligible[i].Stake != eligible[j].Stake {
+                        return eligible[i].Stake > eligible[j].Stake
+                }
+                return eligible[i].Address < eligible[j].Address
+        })
+        if limit := sm.config.Consensus.MaxValidators; limit > 0 && len(eligible) > limit {
+                eligible = eligible[:limit]
+        }
+        return eligible
+}
+
+// epochValidators returns the validators that belong to members, or all of
+// them when members is nil
+func epochValidators(validators []*types.Validator, members map[string]bool) []*types.Validator {
+        if members == nil {
+                return validators
+        }
+        admitted := make([]*types.Validator, 0, len(validators))
+        for _, validator := range validators {
+                if members[validator.Address] {
+                        admitted = append(admitted, validator)
+                }
+        }
+        return admitted
+}
+
+// runShardRound proposes a block of the shard's pending transactions to its
+// engine and appends it to the shard's chain once approved. Transactions of
+// a block that fails go back to the pool.
+func (sm *ShardManager) runShardRound(se *shardEngine) {
+        se.roundMu.Lock()
+        defer se.roundMu.Unlock()
+
+        shard := se.shard
+        sm.consensusCoordinator.mu.RLock()
+        members := se.members
+        sm.consensusCoordinator.mu.RUnlock()
+        validators := epochValidators(shard.consensusValidators(), members)
+        if len(validators) == 0 || !shard.hasPendingTransactions() {
+                return
+        }
+
+        maxTxs := sm.config.Consensus.MaxTxPerBlock
+        if maxTxs <= 0 {
+                maxTxs = shard.GetConfiguration().MaxTransactions
+        }
+        transactions := shard.GetTransactionsForBlock(maxTxs)
+        if len(transactions) == 0 {
+                return
+        }
+
+        block := shard.nextBlock(transactions, sm.config.Network.ChainID)
+        if proposer, err := se.engine.SelectValidator(validators, block.Index); err == nil && proposer != nil {
+                block.Validator = proposer.Address
+        }
+        block.Size = block.EncodedSize()
+        block.Hash = block.ComputeHash()
+
+        se.rounds.Add(1)
+        started := time.Now()
+        approved, err := se.engine.ProcessBlock(block, validators)
+        if err == nil && !approved {
+                err = errShardBlockRejected
+        }
+        if err == nil {
+                err = shard.commitBlock(block)
+        }
+        if err != nil {
+                se.failed.Add(1)
+                shard.requeueTransactions(transactions)
+                sm.logger.LogError("sharding", "shard_consensus_round", err, logrus.Fields{
+                        "shard_id":    shard.ID,
+                        "block_index": block.Index,
+                        "tx_count":    len(transactions),
+                        "timestamp":   time.Now().UTC(),
+                })
+                return
+        }
+
+        se.committed.Add(1)
+        sm.commMu.Lock()
+        collector := sm.collector
+        sm.commMu.Unlock()
+        if collector != nil {
+                collector.SetShardBlockHeight(shard.ID, block.Index)
+        }
+
+        sm.logger.LogSharding(shard.ID, "shard_block_committed", logrus.Fields{
+                "block_hash":    block.Hash,
+                "block_index":   block.Index,
+                "tx_count":      len(transactions),
+                "round_time_ms": time.Since(started).Milliseconds(),
+                "timestamp":     time.Now().UTC(),
+        })
+}
+
+// GetShardConsensusStatus reports each shard's consensus engine, ordered by
+// shard ID. It is empty unless shard consensus is running.
+func (sm *ShardManager) GetShardConsensusStatus() []ShardConsensusStatus {
+        coordinator := sm.consensusCoordinator
+        coordinator.mu.RLock()
+        defer coordinator.mu.RUnlock()
+
+        statuses := make([]ShardConsensusStatus, 0, len(coordinator.engines))
+        for shardID, se := range coordinator.engines {
+                statuses = append(statuses, ShardConsensusStatus{
+                        ShardID:     shardID,
+                        Algorithm:   se.engine.GetAlgorithmName(),
+                        BlockHeight: se.shard.height(),
+                        Rounds:      se.rounds.Load(),
+                        Committed:   se.committed.Load(),
+                        Failed:      se.failed.Load(),
+                })
+        }
+        sort.Slice(statuses, func(i, j int) bool { return statuses[i].ShardID < statuses[j].ShardID })
+        return statuses
+}
@@ -0,0 +1,360 @@
+package sharding
+
+import (
+        "errors"
+        "fmt"
+        "lscc-blockchain/pkg/types"
+        "sort"
+        "sync"
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// errNoShardValidators is recorded when a shard touched by a joint round has
+// no validators to vote on its block
+var errNoShardValidators = errors.New("shard has no validators for a joint cross-shard round")
+
+// Outcomes of a joint cross-shard round
+const (
+        JointRoundCommitted = "committed" // every shard's block was approved and appended
+        JointRoundAborted   = "aborted"   // a shard did not approve, so no shard appended its block
+)
+
+// crossShardCommit is a cross-shard transaction waiting to be recorded on
+// both the shard it leaves and the shard it enters
+type crossShardCommit struct {
+        sequence int64 // position in the global commit order
+        tx       *types.Transaction
+        from     int
+        to       int
+        queuedAt time.Time
+        aborts   int // joint rounds it was part of that aborted
+}
+
+// crossCommitQueue holds cross-shard transactions for shard consensus in
+// global commit order. Per-shard rounds never see them; joint rounds take
+// them from the front and commit each on both of its shards together.
+type crossCommitQueue struct {
+        mu        sync.Mutex
+        next      int64
+        pending   []*crossShardCommit
+        committed int64 // transactions committed by joint rounds
+        aborted   int64 // joint rounds abandoned
+        dropped   int64 // transactions given up on after too many aborts
+        last      int64 // sequence of the last committed transaction
+}
+
+// CrossShardCommitStatus describes the global commit order for cross-shard
+// transactions under per-shard consensus
+type CrossShardCommitStatus struct {
+        Pending       int   `json:"pending"`
+        Committed     int64 `json:"committed"`
+        AbortedRounds int64 `json:"aborted_rounds"`
+        Dropped       int64 `json:"dropped"` // handed to their destination shard's pool after too many aborts
+        LastSequence  int64 `json:"last_sequence"` // sequence of the last committed transaction
+}
+
+// push queues c at the back, giving it the next sequence
+func (q *crossCommitQueue) push(c *crossShardCommit) {
+        q.mu.Lock()
+        defer q.mu.Unlock()
+        q.next++
+        c.sequence = q.next
+        q.pending = append(q.pending, c)
+}
+
+// take removes up to max transactions from the front. A transaction that
+// has been in retries aborted rounds is taken alone, so one that cannot
+// commit stops holding back the rest.
+func (q *crossCommitQueue) take(max, retries int) []*crossShardCommit {
+        q.mu.Lock()
+        defer q.mu.Unlock()
+        if max <= 0 || max > len(q.pending) {
+                max = len(q.pending)
+        }
+        if max > 1 && q.pending[0].aborts >= retries {
+                max = 1
+        }
+        batch := q.pending[:max:max]
+        q.pending = q.pending[max:]
+        return batch
+}
+
+// settle records a batch's outcome. An aborted batch goes back to the front
+// so later transactions never commit ahead of it, unless it is a single
+// transaction past retries aborts, which is returned to be given up on.
+func (q *crossCommitQueue) settle(batch []*crossShardCommit, committed bool, retries int) []*crossShardCommit {
+        q.mu.Lock()
+        defer q.mu.Unlock()
+        if committed {
+                q.committed += int64(len(batch))
+                q.last = batch[len(batch)-1].sequence
+                return nil
+        }
+        q.aborted++
+        for _, commit := range batch {
+                commit.aborts++
+        }
+        if len(batch) == 1 && batch[0].aborts > retries {
+                q.dropped++
+                return batch
+        }
+        q.pending = append(append([]*crossShardCommit{}, batch...), q.pending...)
+        return nil
+}
+
+// drain removes every waiting transaction
+func (q *crossCommitQueue) drain() []*crossShardCommit {
+        q.mu.Lock()
+        defer q.mu.Unlock()
+        pending := q.pending
+        q.pending = nil
+        return pending
+}
+
+func (q *crossCommitQueue) status() CrossShardCommitStatus {
+        q.mu.Lock()
+        defer q.mu.Unlock()
+        return CrossShardCommitStatus{
+                Pending:       len(q.pending),
+                Committed:     q.committed,
+                AbortedRounds: q.aborted,
+                Dropped:       q.dropped,
+                LastSequence:  q.last,
+        }
+}
+
+// queueCrossShardCommit hands a cross-shard transaction to the coordinator
+// when shard consensus is running, reporting false otherwise
+func (sm *ShardManager) queueCrossShardCommit(tx *types.Transaction, from, to int) bool {
+        coordinator := sm.consensusCoordinator
+        coordinator.mu.RLock()
+        defer coordinator.mu.RUnlock()
+        if coordinator.engines == nil {
+                return false
+        }
+
+        commit := &crossShardCommit{tx: tx, from: from, to: to, queuedAt: time.Now()}
+        coordinator.crossCommits.push(commit)
+        sm.logger.LogCrossShard(from, to, "joint_commit_queued", logrus.Fields{
+                "tx_id":     tx.ID,
+                "sequence":  commit.sequence,
+                "trace_id":  tx.TraceID,
+                "timestamp": time.Now().UTC(),
+        })
+        return true
+}
+
+// returnCrossShardCommits hands transactions still waiting for a joint round
+// to their destination shards' pools once shard consensus has stopped, as
+// they would have gone without it
+func (sm *ShardManager) returnCrossShardCommits(engines map[int]*shardEngine) {
+        for _, commit := range sm.consensusCoordinator.crossCommits.drain() {
+                sm.returnCrossShardCommit(engines, commit)
+        }
+}
+
+// returnCrossShardCommit hands a transaction joint rounds will not commit to
+// its destination shard's pool
+func (sm *ShardManager) returnCrossShardCommit(engines map[int]*shardEngine, commit *crossShardCommit) {
+        se, exists := engines[commit.to]
+        if !exists {
+                return
+        }
+        if err := se.shard.AddTransaction(commit.tx); err != nil {
+                sm.logger.LogError("sharding", "return_cross_shard_commit", err, logrus.Fields{
+                        "tx_id":     commit.tx.ID,
+                        "sequence":  commit.sequence,
+                        "to_shard":  commit.to,
+                        "timestamp": time.Now().UTC(),
+                })
+        }
+}
+
+// jointRounds runs a joint round each interval alongside the shards' own
+// rounds under parallel coordination
+func (sm *ShardManager) jointRounds(engines map[int]*shardEngine, interval time.Duration, stop chan struct{}) {
+        defer sm.consensusCoordinator.wg.Done()
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+
+        for {
+                select {
+                case <-stop:
+                        return
+                case <-ticker.C:
+                        sm.runJointRound(engines)
+                }
+        }
+}
+
+// GetCrossShardCommitStatus reports the cross-shard transactions the
+// coordinator has ordered since the manager was created
+func (sm *ShardManager) GetCrossShardCommitStatus() CrossShardCommitStatus {
+        return sm.consensusCoordinator.crossCommits.status()
+}
+
+// runJointRound commits the oldest queued cross-shard transactions on every
+// shard they touch, or on none. Each shard involved builds one block of its
+// share of the batch and puts it to its own engine while the coordinator
+// holds the shard's round; only when every engine approves are the blocks
+// appended, in shard order and without letting any shard's chain move in
+// between. A batch that fails goes back to the front of the queue; a
+// transaction that keeps failing is retried alone, then handed to its
+// destination shard's pool.
+func (sm *ShardManager) runJointRound(engines map[int]*shardEngine) {
+        sm.reportCoordinationMode()
+
+        maxTxs := sm.config.Consensus.MaxTxPerBlock
+        retries := sm.config.Sharding.JointRoundRetries
+        batch := sm.consensusCoordinator.crossCommits.take(maxTxs, retries)
+        if len(batch) == 0 {
+                return
+        }
+
+        byShard := make(map[int][]*types.Transaction)
+        for _, commit := range batch {
+                byShard[commit.from] = append(byShard[commit.from], commit.tx)
+                if commit.to != commit.from {
+                        byShard[commit.to] = append(byShard[commit.to], commit.tx)
+                }
+        }
+        order := make([]int, 0, len(byShard))
+        for shardID := range byShard {
+                order = append(order, shardID)
+        }
+        sort.Ints(order)
+
+        started := time.Now()
+        blocks, err := sm.jointCommit(engines, order, byShard)
+        dropped := sm.consensusCoordinator.crossCommits.settle(batch, err == nil, retries)
+        for _, commit := range dropped {
+                sm.logger.LogCrossShard(commit.from, commit.to, "joint_commit_dropped", logrus.Fields{
+                        "tx_id":     commit.tx.ID,
+                        "sequence":  commit.sequence,
+                        "aborts":    commit.aborts,
+                        "timestamp": time.Now().UTC(),
+                })
+                sm.returnCrossShardCommit(engines, commit)
+        }
+
+        outcome := JointRoundCommitted
+        if err != nil {
+                outcome = JointRoundAborted
+        }
+        sm.commMu.Lock()
+        collector := sm.collector
+        sm.commMu.Unlock()
+        if collector != nil {
+                collector.IncrementCrossShardJointRound(outcome)
+                for _, block := range blocks {
+                        collector.SetShardBlockHeight(block.ShardID, block.Index)
+                }
+        }
+
+        if err != nil {
+                sm.logger.LogError("sharding", "joint_cross_shard_round", err, logrus.Fields{
+                        "shards":         order,
+                        "tx_count":       len(batch),
+                        "first_sequence": batch[0].sequence,
+                        "timestamp":      time.Now().UTC(),
+                })
+                return
+        }
+        sm.logger.LogSharding(-1, "joint_round_committed", logrus.Fields{
+                "shards":         order,
+                "tx_count":       len(batch),
+                "first_sequence": batch[0].sequence,
+                "last_sequence":  batch[len(batch)-1].sequence,
+                "queued_ms":      time.Since(batch[0].queuedAt).Milliseconds(),
+                "round_time_ms":  time.Since(started).Milliseconds(),
+                "timestamp":      time.Now().UTC(),
+        })
+}
+
+// jointCommit agrees and appends one block per shard in order, returning
+// them once all are appended. Nothing is appended unless every shard's
+// engine approves and every block still extends its shard's head.
+func (sm *ShardManager) jointCommit(engines map[int]*shardEngine, order []int, byShard map[int][]*types.Transaction) ([]*types.Block, error) {
+        involved := make([]*shardEngine, 0, len(order))
+        for _, shardID := range order {
+                se, exists := engines[shardID]
+                if !exists {
+                        return nil, fmt.Errorf("shard %d has no consensus engine", shardID)
+                }
+                involved = append(involved, se)
+        }
+
+        // Rounds are taken in shard order so joint rounds never deadlock
+        for _, se := range involved {
+                se.roundMu.Lock()
+                defer se.roundMu.Unlock()
+        }
+
+        blocks := make([]*types.Block, 0, len(involved))
+        for _, se := range involved {
+                block, err := sm.agreeJointBlock(se, byShard[se.shard.ID])
+                if err != nil {
+                        return nil, fmt.Errorf("shard %d: %w", se.shard.ID, err)
+                }
+                blocks = append(blocks, block)
+        }
+
+        for _, se := range involved {
+                se.shard.mu.Lock()
+                defer se.shard.mu.Unlock()
+        }
+        for i, se := range involved {
+                if err := se.shard.checkNextLocked(blocks[i]); err != nil {
+                        se.failed.Add(1)
+                        return nil, err
+                }
+        }
+        for i, se := range involved {
+                se.shard.appendAgreedLocked(blocks[i])
+                se.committed.Add(1)
+        }
+        return blocks, nil
+}
+
+// agreeJointBlock builds a block of transactions on the shard's chain and
+// has the shard's engine approve it. Caller must hold se.roundMu.
+func (sm *ShardManager) agreeJointBlock(se *shardEngine, transactions []*types.Transaction) (*types.Block, error) {
+        sm.consensusCoordinator.mu.RLock()
+        members := se.members
+        sm.consensusCoordinator.mu.RUnlock()
+        validators := epochValidators(se.shard.consensusValidators(), members)
+        if len(validators) == 0 {
+                return nil, errNoShardValidators
+        }
+
+        block := se.shard.nextBlock(transactions, sm.config.Network.ChainID)
+        if proposer, err := se.engine.SelectValidator(validators, block.Index); err == nil && proposer != nil {
+                block.Validator = proposer.Address
+        }
+        block.Size = block.EncodedSize()
+        block.Hash = block.ComputeHash()
+
+        se.rounds.Add(1)
+        approved, err := se.engine.ProcessBlock(block, validators)
+        if err == nil && !approved {
+                err = errShardBlockRejected
+        }
+        if err != nil {
+                se.failed.Add(1)
+                return nil, err
+        }
+        return block, nil
+}
+
+// reportCoordinationMode publishes the schedule shard consensus is following
+func (sm *ShardManager) reportCoordinationMode() {
+        mode := sm.ActiveCoordinationMode()
+        sm.commMu.Lock()
+        collector := sm.collector
+        sm.commMu.Unlock()
+        if collector != nil {
+                collector.SetShardCoordinationMode(mode, sm.consensusCoordinator.coordinationMode)
+        }
+}
@@ -0,0 +1,204 @@
+package sharding
+
+import (
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/pkg/types"
+)
+
+// newShardConsensusManager returns a started shard manager running
+// per-shard consensus under mode, with rounds every blockTime seconds
+func newShardConsensusManager(t *testing.T, mode string, blockTime int) *ShardManager {
+	t.Helper()
+	sm := newTestShardManager(t, func(cfg *config.Config) {
+		cfg.Sharding.ShardConsensus = true
+		cfg.Sharding.CoordinationMode = mode
+		cfg.Consensus.BlockTime = blockTime
+	})
+	if err := sm.Start(); err != nil {
+		t.Fatalf("failed to start shard manager: %v", err)
+	}
+	t.Cleanup(func() { sm.Stop() })
+	return sm
+}
+
+// committedOn reports, for each of shards, whether one of its blocks holds
+// txID. The shards are read together, locked in shard order as joint rounds
+// lock them, so a transaction appended to one but not yet the other shows.
+func committedOn(t *testing.T, sm *ShardManager, txID string, shards ...int) []bool {
+	t.Helper()
+	held := make([]*Shard, len(shards))
+	for i, shardID := range shards {
+		shard, err := sm.GetShard(shardID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		held[i] = shard
+		shard.mu.RLock()
+		defer shard.mu.RUnlock()
+	}
+
+	found := make([]bool, len(held))
+	for i, shard := range held {
+		for _, block := range shard.Blocks {
+			for _, tx := range block.Transactions {
+				if tx.ID == txID {
+					if found[i] {
+						t.Fatalf("transaction %s committed twice on shard %d", txID, shard.ID)
+					}
+					found[i] = true
+				}
+			}
+		}
+	}
+	return found
+}
+
+func TestCrossShardTransactionCommitsAtomicallyInEveryMode(t *testing.T) {
+	for _, mode := range []string{CoordinationParallel, CoordinationSequential, CoordinationAdaptive} {
+		t.Run(mode, func(t *testing.T) {
+			sm := newShardConsensusManager(t, mode, 1)
+			if got := sm.ActiveCoordinationMode(); got == "" || (mode != CoordinationAdaptive && got != mode) {
+				t.Fatalf("active mode = %q under %s coordination", got, mode)
+			}
+
+			tx := &types.Transaction{ID: "xs-" + mode, From: "sender", To: "recipient", Amount: 5, Fee: 1}
+			if !sm.queueCrossShardCommit(tx, 0, 1) {
+				t.Fatal("cross-shard transaction not handed to the coordinator")
+			}
+
+			// At no point may one shard hold the transaction without the other
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				found := committedOn(t, sm, tx.ID, 0, 1)
+				if found[0] != found[1] {
+					t.Fatalf("transaction committed on one shard only: %v", found)
+				}
+				if found[0] {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("transaction not committed: %+v", sm.GetCrossShardCommitStatus())
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			status := sm.GetCrossShardCommitStatus()
+			if status.Committed != 1 || status.Pending != 0 || status.LastSequence != 1 {
+				t.Fatalf("commit status %+v", status)
+			}
+			if found := committedOn(t, sm, tx.ID, 2, 3); found[0] || found[1] {
+				t.Fatalf("transaction committed on an uninvolved shard: %v", found)
+			}
+		})
+	}
+}
+
+func TestJointRoundAbortsOnEveryShard(t *testing.T) {
+	// Rounds are driven by hand, so the hour-long block time never fires
+	sm := newShardConsensusManager(t, CoordinationSequential, 3600)
+	coordinator := sm.consensusCoordinator
+	coordinator.mu.RLock()
+	engines := coordinator.engines
+	coordinator.mu.RUnlock()
+
+	// Shard 1 cannot agree a block while its validators are inactive
+	destination, err := sm.GetShard(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setStatus := func(status string) {
+		destination.mu.Lock()
+		defer destination.mu.Unlock()
+		for _, validator := range destination.Validators {
+			validator.Status = status
+		}
+	}
+	setStatus("inactive")
+
+	first := &types.Transaction{ID: "xs-first", From: "sender", To: "recipient", Amount: 5, Fee: 1}
+	second := &types.Transaction{ID: "xs-second", From: "sender", To: "recipient", Amount: 5, Fee: 1}
+	sm.queueCrossShardCommit(first, 0, 1)
+	sm.queueCrossShardCommit(second, 0, 1)
+	heights := []int64{engines[0].shard.height(), engines[1].shard.height()}
+
+	sm.runJointRound(engines)
+	if found := committedOn(t, sm, first.ID, 0, 1); found[0] || found[1] {
+		t.Fatalf("aborted round committed on %v", found)
+	}
+	if engines[0].shard.height() != heights[0] || engines[1].shard.height() != heights[1] {
+		t.Fatal("aborted round moved a shard's chain")
+	}
+	status := sm.GetCrossShardCommitStatus()
+	if status.Committed != 0 || status.AbortedRounds != 1 || status.Pending != 2 {
+		t.Fatalf("after the aborted round: %+v", status)
+	}
+
+	// Once the shard can vote, the batch commits in its original order
+	setStatus("active")
+	sm.runJointRound(engines)
+	for _, tx := range []*types.Transaction{first, second} {
+		if found := committedOn(t, sm, tx.ID, 0, 1); !found[0] || !found[1] {
+			t.Fatalf("%s committed on %v, want both shards", tx.ID, found)
+		}
+	}
+	status = sm.GetCrossShardCommitStatus()
+	if status.Committed != 2 || status.Pending != 0 || status.LastSequence != 2 {
+		t.Fatalf("after the retried round: %+v", status)
+	}
+}
+
+func TestJointRoundDropsTransactionThatKeepsAborting(t *testing.T) {
+	sm := newShardConsensusManager(t, CoordinationSequential, 3600)
+	coordinator := sm.consensusCoordinator
+	coordinator.mu.RLock()
+	engines := coordinator.engines
+	coordinator.mu.RUnlock()
+	retries := sm.config.Sharding.JointRoundRetries
+
+	// Shard 1 never agrees a block, shard 2 does
+	destination, err := sm.GetShard(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	destination.mu.Lock()
+	for _, validator := range destination.Validators {
+		validator.Status = "inactive"
+	}
+	destination.mu.Unlock()
+
+	stuck := &types.Transaction{ID: "xs-stuck", From: "sender", To: "recipient", Amount: 5, Fee: 1, Type: "cross_shard"}
+	behind := &types.Transaction{ID: "xs-behind", From: "sender", To: "recipient", Amount: 5, Fee: 1, Type: "cross_shard"}
+	sm.queueCrossShardCommit(stuck, 0, 1)
+	sm.queueCrossShardCommit(behind, 0, 2)
+
+	// The pair aborts together until the stuck transaction is retried alone
+	for round := 1; round <= retries; round++ {
+		sm.runJointRound(engines)
+		if status := sm.GetCrossShardCommitStatus(); status.AbortedRounds != int64(round) || status.Pending != 2 {
+			t.Fatalf("after round %d: %+v", round, status)
+		}
+	}
+	sm.runJointRound(engines)
+	status := sm.GetCrossShardCommitStatus()
+	if status.Dropped != 1 || status.Pending != 1 || status.Committed != 0 {
+		t.Fatalf("after the stuck transaction was retried alone: %+v", status)
+	}
+	destination.TransactionPool.mu.RLock()
+	_, returned := destination.TransactionPool.CrossShard[stuck.ID]
+	destination.TransactionPool.mu.RUnlock()
+	if !returned {
+		t.Fatal("dropped transaction not handed to its destination shard's pool")
+	}
+
+	// The transaction behind it is no longer held back
+	sm.runJointRound(engines)
+	if found := committedOn(t, sm, behind.ID, 0, 2); !found[0] || !found[1] {
+		t.Fatalf("%s committed on %v, want both shards", behind.ID, found)
+	}
+	if status := sm.GetCrossShardCommitStatus(); status.Committed != 1 || status.Pending != 0 || status.LastSequence != 2 {
+		t.Fatalf("after the remaining round: %+v", status)
+	}
+}
@@ -1,20 +1,47 @@
 package sharding
 
 import (
+        "errors"
         "fmt"
+        "lscc-blockchain/internal/blockchain"
+        "lscc-blockchain/internal/metrics"
         "lscc-blockchain/internal/utils"
         "lscc-blockchain/pkg/types"
+        "math/rand"
+        "sort"
+        "strings"
         "sync"
+        "sync/atomic"
         "time"
 
         "github.com/sirupsen/logrus"
 )
 
+// ErrTooManyHops is returned for a message dropped after passing through
+// more relays than cross_shard.max_hops allows
+var ErrTooManyHops = errors.New("cross-shard message exceeded the maximum relay hops")
+
 // CrossShardCommunicator handles communication between shards
 type CrossShardCommunicator struct {
         shardManager     *ShardManager
         logger           *utils.Logger
-        messageChannels  map[int]chan *types.CrossShardMessage // shardID -> message channel
+        clock            utils.Clock
+        messageQueues    map[int]*messageQueue                  // shardID -> priority queue of pending messages
+        delivered        map[int]*deliveredSet                  // shardID -> recently handled message IDs
+        workers          int
+        workerWG         sync.WaitGroup
+        enqueueTimeout   time.Duration
+        priorityAging    time.Duration
+        txTTL            time.Duration                          // age at which carried transactions expire; 0 never
+        maxHops          int                                    // relays a message may pass through
+        relayBufferSize  int                                    // messages each relay buffers
+        relayOverflow    string                                 // one of the RelayOverflow policies
+        relayWait        time.Duration                          // how long RelayOverflowBlock waits for buffer space
+        breakers         map[int]*circuitBreaker                // destination shardID -> breaker; guarded by breakerMu
+        breakerMu        sync.Mutex
+        breakerLimit     int                                    // consecutive failed sends that open a breaker; 0 disables
+        breakerCooldown  time.Duration
+        chainID          string
         relayNodes       map[int]*RelayNode                     // shardID -> relay node
         routingTable     *RoutingTable
         syncManager      *CrossShardSyncManager
@@ -24,10 +51,60 @@ type CrossShardCommunicator struct {
         stopChan         chan struct{}
         startTime        time.Time
         metrics          *CrossShardMetrics
+        metricsMu        sync.Mutex  // guards metrics; never held while taking another lock
+        latencyEWMA      *utils.EWMA // smoothed message processing time in milliseconds
+        latencySMA       *utils.SMA  // mean processing time over the last messages
+        errorRate        *errorRateMonitor
+        collector        *metrics.MetricsCollector // receives 2PC outcomes, nil when unset
+        wal              *messageWAL               // in-flight messages, nil unless cross_shard.durable
 }
 
-// RelayNode represents a relay node for cross-shard communication
+// Relay overflow policies, applied when every relay on a message's route has
+// a full buffer
+const (
+        RelayOverflowDropNew    = "drop_new"           // refuse the new message
+        RelayOverflowDropOldest = "drop_oldest"        // evict the oldest message buffered at the first relay
+        RelayOverflowBlock      = "block_with_timeout" // wait for space at the first relay, then refuse
+        RelayOverflowSpill      = "spill_to_disk"      // hold the message in storage until the first relay has room
+        RelayOverflowReject     = "reject"             // alias of RelayOverflowDropNew
+)
+
+// relayOverflowPolicy returns the policy a configured name selects, resolving
+// aliases so metrics label each policy one way
+func relayOverflowPolicy(name string) string {
+        if name == RelayOverflowReject {
+                return RelayOverflowDropNew
+        }
+        return name
+}
+
+// RelayNode represents a relay node for cross-shard communication. ID,
+// ShardID, ConnectedShards and MaxBufferSize are fixed when the node is
+// created; the buffer, activity and status fields are guarded by mu and the
+// message counters are atomic. FailedMsgs counts failed deliveries out of
+// the buffer, EvictedMsgs messages thrown out of it to make room and
+// RejectedMsgs messages the relay was too full to take.
 type RelayNode struct {
+        ID               string
+        ShardID          int
+        ConnectedShards  []int
+        MessageBuffer    []*types.CrossShardMessage
+        LastActivity     time.Time
+        Latency          time.Duration
+        Throughput       float64
+        Status           string // "active", "busy", "inactive"
+        MaxBufferSize    int
+        ProcessedMsgs    atomic.Int64
+        FailedMsgs       atomic.Int64
+        EvictedMsgs      atomic.Int64
+        RejectedMsgs     atomic.Int64
+        space            chan struct{} // signalled when messages leave the buffer
+        spill            *relaySpill   // overflow held in storage, nil unless RelayOverflowSpill
+        mu               sync.RWMutex
+}
+
+// RelayNodeInfo is a point-in-time copy of a relay node
+type RelayNodeInfo struct {
         ID               string                    `json:"id"`
         ShardID          int                       `json:"shard_id"`
         ConnectedShards  []int                     `json:"connected_shards"`
@@ -35,16 +112,43 @@ type RelayNode struct {
         LastActivity     time.Time                 `json:"last_activity"`
         Latency          time.Duration             `json:"latency"`
         Throughput       float64                   `json:"throughput"`
-        Status           string                    `json:"status"` // "active", "busy", "inactive"
+        Status           string                    `json:"status"`
         MaxBufferSize    int                       `json:"max_buffer_size"`
         ProcessedMsgs    int64                     `json:"processed_msgs"`
         FailedMsgs       int64                     `json:"failed_msgs"`
-        mu               sync.RWMutex
+        EvictedMsgs      int64                     `json:"evicted_msgs"`
+        RejectedMsgs     int64                     `json:"rejected_msgs"`
+        SpilledMsgs      int                       `json:"spilled_msgs"`
+}
+
+// snapshot copies the relay node's state
+func (r *RelayNode) snapshot() *RelayNodeInfo {
+        r.mu.RLock()
+        defer r.mu.RUnlock()
+        
+        info := &RelayNodeInfo{
+                ID:              r.ID,
+                ShardID:         r.ShardID,
+                ConnectedShards: append([]int{}, r.ConnectedShards...),
+                MessageBuffer:   append([]*types.CrossShardMessage{}, r.MessageBuffer...),
+                LastActivity:    r.LastActivity,
+                Latency:         r.Latency,
+                Throughput:      r.Throughput,
+                Status:          r.Status,
+                MaxBufferSize:   r.MaxBufferSize,
+                ProcessedMsgs:   r.ProcessedMsgs.Load(),
+                FailedMsgs:      r.FailedMsgs.Load(),
+                EvictedMsgs:     r.EvictedMsgs.Load(),
+                RejectedMsgs:    r.RejectedMsgs.Load(),
+                SpilledMsgs:     r.spill.size(),
+        }
+        return info
 }
 
 // RoutingTable maintains routing information for cross-shard messages
 type RoutingTable struct {
-        routes          map[RoutingKey]*Route // (fromShard, toShard) -> Route
+        routes          map[RoutingKey]*Route   // (fromShard, toShard) -> route last chosen
+        candidates      map[RoutingKey][]*Route // (fromShard, toShard) -> every known route
         relayMapping    map[int][]int         // shardID -> list of relay nodes
         loadBalancer    *LoadBalancer
         updateInterval  time.Duration
@@ -78,6 +182,7 @@ type LoadBalancer struct {
         shardLoads  map[int]float64          // shardID -> load factor
         relayLoads  map[int]float64          // relayID -> load factor
         history     []*LoadBalanceDecision
+        rrNext      map[RoutingKey]int       // next round-robin candidate per shard pair
         mu          sync.RWMutex
 }
 
@@ -96,10 +201,22 @@ type LoadBalanceDecision struct {
 type CrossShardSyncManager struct {
         syncRequests     map[string]*SyncRequest
         syncStatus       map[int]string // shardID -> status
+        strategy         string         // how Shard.Sync transfers blocks: incremental or full
+        // Larger batches let a lagging shard catch up in fewer passes but
+        // hold both shards' locks longer per pass
         batchSize        int
+        // A shorter interval picks up new requests sooner at the cost of
+        // waking the sync worker more often
         syncInterval     time.Duration
         maxRetries       int
+        // More concurrent requests drain a backlog faster but run that many
+        // shard syncs, each copying a peer's chain, at once
+        concurrency      int
+        retryBaseDelay   time.Duration  // backoff before the first retry, doubled per attempt
+        retryMaxDelay    time.Duration  // upper bound on the backoff between retries
         conflictResolver *ConflictResolver
+        twoPhaseTxs      map[string]*TwoPhaseTransaction // txID -> two-phase commit state
+        prepareTimeout   time.Duration
         mu               sync.RWMutex
         logger           *utils.Logger
 }
@@ -115,6 +232,8 @@ type SyncRequest struct {
         CreatedAt    time.Time `json:"created_at"`
         Status       string    `json:"status"`
         RetryCount   int       `json:"retry_count"`
+        NextRetryAt  time.Time `json:"next_retry_at,omitempty"` // the request is not retried before this time
+        LastError    string    `json:"last_error,omitempty"`
         Data         interface{} `json:"data"`
 }
 
@@ -130,9 +249,10 @@ type ConflictResolver struct {
 // TransactionConflict represents a transaction conflict
 type TransactionConflict struct {
         ID             string                 `json:"id"`
-        ConflictType   string                 `json:"conflict_type"` // "double_spend", "ordering", "state"
+        ConflictType   string                 `json:"conflict_type"` // "double_spend", "ordering", "state", "block_fork"
         InvolvedShards []int                  `json:"involved_shards"`
         Transactions   []*types.Transaction   `json:"transactions"`
+        Blocks         []*types.Block         `json:"blocks,omitempty"` // rival blocks at one height, for "block_fork"
         CreatedAt      time.Time              `json:"created_at"`
         ResolvedAt     *time.Time             `json:"resolved_at,omitempty"`
         Resolution     string                 `json:"resolution"`
@@ -150,12 +270,13 @@ type ConflictRule struct {
 
 // ConflictStats tracks conflict resolution statistics
 type ConflictStats struct {
-        TotalConflicts    int64                  `json:"total_conflicts"`
-        ResolvedConflicts int64                  `json:"resolved_conflicts"`
-        FailedResolutions int64                  `json:"failed_resolutions"`
-        AvgResolutionTime time.Duration          `json:"avg_resolution_time"`
-        ConflictsByType   map[string]int64       `json:"conflicts_by_type"`
-        LastUpdate        time.Time              `json:"last_update"`
+        TotalConflicts      int64                  `json:"total_conflicts"`
+        TransactionsChecked int64                  `json:"transactions_checked"` // cross-shard transactions checked for conflicts
+        ResolvedConflicts   int64                  `json:"resolved_conflicts"`
+        FailedResolutions   int64                  `json:"failed_resolutions"`
+        AvgResolutionTime   time.Duration          `json:"avg_resolution_time"`
+        ConflictsByType     map[string]int64       `json:"conflicts_by_type"`
+        LastUpdate          time.Time              `json:"last_update"`
 }
 
 // CrossShardValidationRequest represents a validation request
@@ -182,21 +303,39 @@ type ValidationResult struct {
 type CrossShardMetrics struct {
         MessagesProcessed    int64                  `json:"messages_processed"`
         MessagesFailed       int64                  `json:"messages_failed"`
+        DuplicatesDropped    int64                  `json:"duplicates_dropped"`
         AverageLatency       time.Duration          `json:"average_latency"`
+        MovingAvgLatency     time.Duration          `json:"moving_average_latency"`
         Throughput           float64                `json:"throughput"`
         ActiveRelayNodes     int                    `json:"active_relay_nodes"`
         QueuedMessages       int                    `json:"queued_messages"`
+        QueueDepthByPriority map[int]int            `json:"queue_depth_by_priority"`
         ConflictsResolved    int64                  `json:"conflicts_resolved"`
+        BlockConflicts       int64                  `json:"block_conflicts"` // blocks received for a shard height already holding a different block
         SyncOperations       int64                  `json:"sync_operations"`
+        SyncRetries          int64                  `json:"sync_retries"`
+        SyncFailures         int64                  `json:"sync_failures"` // sync requests abandoned after the last retry
         BandwidthUtilization float64                `json:"bandwidth_utilization"`
         ErrorRate            float64                `json:"error_rate"`
+        WindowErrorRate      float64                `json:"window_error_rate"` // percent of messages failed over the alert window
+        Degraded             bool                   `json:"degraded"`          // window error rate is above the alert threshold
+        TwoPhaseInFlight     int                    `json:"two_phase_in_flight"`
+        TwoPhaseCommitted    int64                  `json:"two_phase_committed"`
+        TwoPhaseAborted      int64                  `json:"two_phase_aborted"`
+        CircuitBreakers      map[int]*CircuitBreakerStatus `json:"circuit_breakers"` // by destination shard, for shards sent to since start
         LastUpdate           time.Time              `json:"last_update"`
         DetailedMetrics      map[string]interface{} `json:"detailed_metrics"`
 }
 
 // NewCrossShardCommunicator creates a new cross-shard communicator
 func NewCrossShardCommunicator(shardManager *ShardManager, logger *utils.Logger) *CrossShardCommunicator {
-        startTime := time.Now()
+        return NewCrossShardCommunicatorWithClock(shardManager, logger, utils.SystemClock)
+}
+
+// NewCrossShardCommunicatorWithClock creates a communicator whose
+// timestamps, timeouts and background workers all run on clock
+func NewCrossShardCommunicatorWithClock(shardManager *ShardManager, logger *utils.Logger, clock utils.Clock) *CrossShardCommunicator {
+        startTime := clock.Now()
         
         logger.LogCrossShard(-1, -1, "initialize", logrus.Fields{
                 "timestamp": startTime,
@@ -205,12 +344,31 @@ func NewCrossShardCommunicator(shardManager *ShardManager, logger *utils.Logger)
         csc := &CrossShardCommunicator{
                 shardManager:    shardManager,
                 logger:          logger,
-                messageChannels: make(map[int]chan *types.CrossShardMessage),
+                clock:           clock,
+                messageQueues:   make(map[int]*messageQueue),
+                delivered:       make(map[int]*deliveredSet),
+                enqueueTimeout:  time.Duration(shardManager.config.CrossShard.EnqueueTimeout) * time.Millisecond,
+                priorityAging:   time.Duration(shardManager.config.CrossShard.PriorityAging) * time.Millisecond,
+                txTTL:           time.Duration(shardManager.config.Mempool.TxTTL) * time.Second,
+                maxHops:         shardManager.config.CrossShard.MaxHops,
+                relayBufferSize: shardManager.config.CrossShard.RelayBufferSize,
+                relayOverflow:   relayOverflowPolicy(shardManager.config.CrossShard.RelayOverflowPolicy),
+                relayWait:       time.Duration(shardManager.config.CrossShard.RelayOverflowTimeout) * time.Millisecond,
+                breakers:        make(map[int]*circuitBreaker),
+                breakerLimit:    shardManager.config.CrossShard.CircuitBreaker.FailureThreshold,
+                breakerCooldown: time.Duration(shardManager.config.CrossShard.CircuitBreaker.Cooldown) * time.Second,
+                chainID:         shardManager.config.Network.ChainID,
                 relayNodes:      make(map[int]*RelayNode),
                 validationQueue: make(chan *CrossShardValidationRequest, 1000),
                 isRunning:       false,
                 stopChan:        make(chan struct{}),
                 startTime:       startTime,
+                latencyEWMA:     utils.NewEWMA(shardManager.config.Consensus.MetricsAlpha),
+                latencySMA:      utils.NewSMA(shardManager.config.Consensus.MetricsWindow),
+                errorRate: newErrorRateMonitor(
+                        shardManager.config.CrossShard.ErrorRateAlertThreshold,
+                        time.Duration(shardManager.config.CrossShard.ErrorRateWindow)*time.Second,
+                ),
                 metrics: &CrossShardMetrics{
                         MessagesProcessed:    0,
                         MessagesFailed:       0,
@@ -218,6 +376,7 @@ func NewCrossShardCommunicator(shardManager *ShardManager, logger *utils.Logger)
                         Throughput:           0.0,
                         ActiveRelayNodes:     0,
                         QueuedMessages:       0,
+                        QueueDepthByPriority: make(map[int]int),
                         ConflictsResolved:    0,
                         SyncOperations:       0,
                         BandwidthUtilization: 0.0,
@@ -230,26 +389,34 @@ func NewCrossShardCommunicator(shardManager *ShardManager, logger *utils.Logger)
         // Initialize routing table
         csc.routingTable = &RoutingTable{
                 routes:         make(map[RoutingKey]*Route),
+                candidates:     make(map[RoutingKey][]*Route),
                 relayMapping:   make(map[int][]int),
                 updateInterval: 30 * time.Second,
                 lastUpdate:     startTime,
                 logger:         logger,
                 loadBalancer: &LoadBalancer{
-                        strategy:   "adaptive",
+                        strategy:   shardManager.config.CrossShard.LoadBalanceStrategy,
                         shardLoads: make(map[int]float64),
                         relayLoads: make(map[int]float64),
                         history:    make([]*LoadBalanceDecision, 0),
+                        rrNext:     make(map[RoutingKey]int),
                 },
         }
         
         // Initialize sync manager
         csc.syncManager = &CrossShardSyncManager{
-                syncRequests: make(map[string]*SyncRequest),
-                syncStatus:   make(map[int]string),
-                batchSize:    100,
-                syncInterval: 10 * time.Second,
-                maxRetries:   3,
-                logger:       logger,
+                syncRequests:   make(map[string]*SyncRequest),
+                syncStatus:     make(map[int]string),
+                strategy:       shardManager.config.CrossShard.SyncStrategy,
+                batchSize:      shardManager.config.CrossShard.Sync.BatchSize,
+                syncInterval:   time.Duration(shardManager.config.CrossShard.Sync.Interval) * time.Second,
+                maxRetries:     shardManager.config.CrossShard.Sync.MaxRetries,
+                concurrency:    shardManager.config.CrossShard.Sync.Concurrency,
+                retryBaseDelay: 10 * time.Second,
+                retryMaxDelay:  5 * time.Minute,
+                twoPhaseTxs:    make(map[string]*TwoPhaseTransaction),
+                prepareTimeout: 30 * time.Second,
+                logger:         logger,
                 conflictResolver: &ConflictResolver{
                         conflicts:       make(map[string]*TransactionConflict),
                         resolutionRules: make([]*ConflictRule, 0),
@@ -270,8 +437,8 @@ func NewCrossShardCommunicator(shardManager *ShardManager, logger *utils.Logger)
         
         logger.LogCrossShard(-1, -1, "communicator_created", logrus.Fields{
                 "relay_nodes":     len(csc.relayNodes),
-                "message_channels": len(csc.messageChannels),
-                "timestamp":       time.Now().UTC(),
+                "message_queues":  len(csc.messageQueues),
+                "timestamp":       clock.Now().UTC(),
         })
         
         return csc
@@ -287,67 +454,174 @@ func (csc *CrossShardCommunicator) Start() error {
         }
         
         csc.logger.LogCrossShard(-1, -1, "start_communicator", logrus.Fields{
-                "timestamp": time.Now().UTC(),
+                "timestamp": csc.clock.Now().UTC(),
         })
         
-        // Initialize message channels for each shard
+        // Messages left in the log by the last run are replayed once the
+        // workers are up
+        var replay []*types.CrossShardMessage
+        if csc.shardManager.config.CrossShard.Durable {
+                db := csc.shardManager.GetDB()
+                if db == nil {
+                        return fmt.Errorf("durable cross-shard messaging needs a database")
+                }
+                wal, logged, err := openMessageWAL(db)
+                if err != nil {
+                        return fmt.Errorf("failed to open cross-shard message log: %w", err)
+                }
+                csc.wal = wal
+                replay = logged
+        }
+        
+        // Every destination shard has its own priority queue, served by a
+        // single worker, so messages of equal priority to the same shard are
+        // handled in the order they were queued
         shards := csc.shardManager.GetAllShards()
+        workers := utils.MaxInt(csc.shardManager.config.CrossShard.Workers, 1)
+        queueSize := utils.MaxInt(csc.shardManager.config.CrossShard.QueueSize/utils.MaxInt(len(shards), 1), 1)
+        wake := make([]chan struct{}, workers)
+        assigned := make([][]*messageQueue, workers)
+        dedupCapacity := csc.shardManager.config.CrossShard.DedupCapacity
+        dedupTTL := time.Duration(csc.shardManager.config.CrossShard.DedupTTL) * time.Second
+        for i := range wake {
+                wake[i] = make(chan struct{}, 1)
+        }
+        
         for shardID := range shards {
-                csc.messageChannels[shardID] = make(chan *types.CrossShardMessage, 100)
-                csc.initializeRelayNode(shardID)
+                worker := shardID % workers
+                queue := newMessageQueue(queueSize, csc.priorityAging, wake[worker], csc.clock)
+                csc.messageQueues[shardID] = queue
+                assigned[worker] = append(assigned[worker], queue)
+                if _, exists := csc.delivered[shardID]; !exists {
+                        csc.delivered[shardID] = newDeliveredSet(dedupCapacity, dedupTTL, csc.clock)
+                }
+                if err := csc.initializeRelayNode(shardID); err != nil {
+                        return err
+                }
         }
+        csc.workers = workers
         
         // Initialize routing table
         csc.initializeRoutingTable()
         
         // Start workers
+        for i := range assigned {
+                if len(assigned[i]) == 0 {
+                        continue
+                }
+                csc.workerWG.Add(1)
+                go csc.messageWorker(i, assigned[i], wake[i])
+        }
         go csc.messageProcessor()
         go csc.validationWorker()
         go csc.syncWorker()
         go csc.routingTableUpdater()
         go csc.metricsCollector()
         go csc.conflictResolver()
+        go csc.twoPhaseCoordinator()
         
         csc.isRunning = true
+        csc.replayMessages(replay)
         
         csc.logger.LogCrossShard(-1, -1, "communicator_started", logrus.Fields{
-                "active_channels": len(csc.messageChannels),
+                "active_queues":   len(csc.messageQueues),
+                "workers":         workers,
                 "relay_nodes":     len(csc.relayNodes),
-                "timestamp":       time.Now().UTC(),
+                "timestamp":       csc.clock.Now().UTC(),
         })
         
         return nil
 }
 
-// Stop stops the cross-shard communicator
+// Stop stops the cross-shard communicator. New messages are rejected
+// immediately; messages already queued are handled before Stop returns.
+//
+// Senders hold csc.mu for reading from the isRunning check until their
+// message is queued, so taking the write lock waits out every send in
+// progress. isRunning is cleared and the relay buffers are flushed before the
+// queues are closed, and a closed queue refuses a push with an error rather
+// than panicking, so a send racing Stop fails cleanly.
 func (csc *CrossShardCommunicator) Stop() error {
         csc.mu.Lock()
-        defer csc.mu.Unlock()
         
         if !csc.isRunning {
+                csc.mu.Unlock()
                 return fmt.Errorf("cross-shard communicator is not running")
         }
         
         csc.logger.LogCrossShard(-1, -1, "stop_communicator", logrus.Fields{
-                "timestamp": time.Now().UTC(),
+                "timestamp": csc.clock.Now().UTC(),
         })
         
         csc.isRunning = false
         close(csc.stopChan)
         
-        // Close message channels
-        for shardID, channel := range csc.messageChannels {
-                close(channel)
-                delete(csc.messageChannels, shardID)
+        csc.flushRelayBuffers()
+        
+        // Close the queues so workers exit once drained
+        for shardID, queue := range csc.messageQueues {
+                queue.close()
+                delete(csc.messageQueues, shardID)
         }
+        csc.mu.Unlock()
+        
+        // Wait outside the lock: handlers may send follow-up messages, which
+        // are rejected now that the communicator is stopped
+        csc.workerWG.Wait()
         
         csc.logger.LogCrossShard(-1, -1, "communicator_stopped", logrus.Fields{
-                "timestamp": time.Now().UTC(),
+                "timestamp": csc.clock.Now().UTC(),
         })
         
         return nil
 }
 
+// flushRelayBuffers empties the relay buffers into the destination shards'
+// queues, so the workers deliver what the relays held before they exit. A
+// message that finds its queue full is left in the message log when
+// cross_shard.durable is set, to be replayed on the next start, and lost
+// otherwise. Messages spilled to storage stay there for the next start.
+// Caller must hold csc.mu.
+func (csc *CrossShardCommunicator) flushRelayBuffers() {
+        flushed, logged, lost := 0, 0, 0
+        for _, relayNode := range csc.relayNodes {
+                relayNode.mu.Lock()
+                buffered := relayNode.MessageBuffer
+                relayNode.MessageBuffer = make([]*types.CrossShardMessage, 0)
+                relayNode.mu.Unlock()
+                
+                for _, message := range buffered {
+                        // No waiting: the workers may be blocked on csc.mu
+                        if queue, exists := csc.messageQueues[message.ToShard]; exists && queue.push(message, 0) == nil {
+                                flushed++
+                                continue
+                        }
+                        if csc.wal.holds(message.ID) {
+                                logged++
+                                continue
+                        }
+                        lost++
+                        csc.countFailed()
+                        csc.logger.LogCrossShard(message.FromShard, message.ToShard, "message_dropped", logrus.Fields{
+                                "message_id": message.ID,
+                                "trace_id":   message.TraceID,
+                                "relay_node": relayNode.ShardID,
+                                "reason":     "shutdown",
+                                "timestamp":  csc.clock.Now().UTC(),
+                        })
+                }
+        }
+        
+        if flushed+logged+lost > 0 {
+                csc.logger.LogCrossShard(-1, -1, "relay_buffers_flushed", logrus.Fields{
+                        "flushed":   flushed,
+                        "logged":    logged,
+                        "lost":      lost,
+                        "timestamp": csc.clock.Now().UTC(),
+                })
+        }
+}
+
 // SendMessage sends a cross-shard message
 func (csc *CrossShardCommunicator) SendMessage(message *types.CrossShardMessage) error {
         csc.mu.RLock()
@@ -357,122 +631,412 @@ func (csc *CrossShardCommunicator) SendMessage(message *types.CrossShardMessage)
                 return fmt.Errorf("cross-shard communicator is not running")
         }
         
-        startTime := time.Now()
+        startTime := csc.clock.Now()
         
         csc.logger.LogCrossShard(message.FromShard, message.ToShard, message.Type, logrus.Fields{
                 "message_id": message.ID,
+                "trace_id":   message.TraceID,
                 "timestamp":  startTime,
         })
         
+        // Handlers read the payload by its declared type
+        message.InferDataType()
+        
+        // Messages created locally are stamped with our chain; anything
+        // carrying another chain's ID is dropped
+        if message.ChainID == "" {
+                message.ChainID = csc.chainID
+        } else if message.ChainID != csc.chainID {
+                csc.countFailed()
+                csc.logger.LogCrossShard(message.FromShard, message.ToShard, "message_dropped", logrus.Fields{
+                        "message_id": message.ID,
+                        "trace_id":   message.TraceID,
+                        "chain_id":   message.ChainID,
+                        "reason":     "chain_id_mismatch",
+                        "timestamp":  csc.clock.Now().UTC(),
+                })
+                return fmt.Errorf("%w: message %s is from %s, expected %s", types.ErrChainIDMismatch, message.ID, message.ChainID, csc.chainID)
+        }
+        
+        // Fail fast while the destination shard keeps refusing messages
+        breaker := csc.breaker(message.ToShard)
+        if !breaker.allow(csc.clock.Now()) {
+                csc.countFailed()
+                csc.logger.LogCrossShard(message.FromShard, message.ToShard, "message_dropped", logrus.Fields{
+                        "message_id": message.ID,
+                        "trace_id":   message.TraceID,
+                        "reason":     "circuit_open",
+                        "timestamp":  csc.clock.Now().UTC(),
+                })
+                return fmt.Errorf("%w %d: message %s not sent", ErrCircuitOpen, message.ToShard, message.ID)
+        }
+        
+        // Log the message before it is queued, so a crash cannot lose it
+        // between here and its handler
+        logged, err := csc.wal.append(message)
+        if err != nil {
+                csc.countFailed()
+                return err
+        }
+        
         // Find optimal route
         route, err := csc.findOptimalRoute(message.FromShard, message.ToShard)
-        if err != nil {
-                csc.metrics.MessagesFailed++
-                return fmt.Errorf("failed to find route: %w", err)
+        if err == nil {
+                // Send via relay nodes if needed
+                if len(route.RelayNodes) > 0 {
+                        err = csc.sendViaRelay(message, route)
+                } else {
+                        err = csc.sendDirect(message)
+                }
+        } else {
+                csc.countFailed()
+                err = fmt.Errorf("failed to find route: %w", err)
         }
         
-        // Send via relay nodes if needed
-        if len(route.RelayNodes) > 0 {
-                return csc.sendViaRelay(message, route)
+        if from, to := breaker.record(csc.clock.Now(), err); from != to {
+                csc.logger.LogCrossShard(message.FromShard, message.ToShard, "circuit_"+to, logrus.Fields{
+                        "message_id": message.ID,
+                        "trace_id":   message.TraceID,
+                        "previous":   from,
+                        "timestamp":  csc.clock.Now().UTC(),
+                })
+        }
+        
+        // The sender is told the message was not sent, so it is no longer
+        // in flight
+        if err != nil && logged {
+                csc.forgetMessage(message)
+        }
+        return err
+}
+
+// replayMessages queues messages logged by an earlier run straight to their
+// destination shards. A message that cannot be queued stays in the log for
+// the next start. Caller must hold csc.mu.
+func (csc *CrossShardCommunicator) replayMessages(messages []*types.CrossShardMessage) {
+        if len(messages) == 0 {
+                return
+        }
+        
+        replayed := 0
+        for _, message := range messages {
+                queue, exists := csc.messageQueues[message.ToShard]
+                if !exists {
+                        csc.logger.LogError("cross_shard", "replay_message", fmt.Errorf("no message queue for shard %d", message.ToShard), logrus.Fields{
+                                "message_id": message.ID,
+                                "trace_id":   message.TraceID,
+                                "timestamp":  csc.clock.Now().UTC(),
+                        })
+                        continue
+                }
+                if err := queue.push(message, csc.enqueueTimeout); err != nil {
+                        csc.logger.LogError("cross_shard", "replay_message", err, logrus.Fields{
+                                "message_id": message.ID,
+                                "trace_id":   message.TraceID,
+                                "shard_id":   message.ToShard,
+                                "timestamp":  csc.clock.Now().UTC(),
+                        })
+                        continue
+                }
+                replayed++
         }
         
-        // Direct send
-        return csc.sendDirect(message)
+        csc.logger.LogCrossShard(-1, -1, "messages_replayed", logrus.Fields{
+                "logged":    len(messages),
+                "replayed":  replayed,
+                "timestamp": csc.clock.Now().UTC(),
+        })
+}
+
+// forgetMessage removes a message that is no longer in flight from the log
+func (csc *CrossShardCommunicator) forgetMessage(message *types.CrossShardMessage) {
+        if err := csc.wal.remove(message.ID); err != nil {
+                csc.logger.LogError("cross_shard", "forget_message", err, logrus.Fields{
+                        "message_id": message.ID,
+                        "trace_id":   message.TraceID,
+                        "timestamp":  csc.clock.Now().UTC(),
+                })
+        }
 }
 
 // sendDirect sends a message directly to the target shard
 func (csc *CrossShardCommunicator) sendDirect(message *types.CrossShardMessage) error {
-        channel, exists := csc.messageChannels[message.ToShard]
+        queue, exists := csc.messageQueues[message.ToShard]
         if !exists {
-                return fmt.Errorf("no message channel for shard %d", message.ToShard)
+                return fmt.Errorf("no message queue for shard %d", message.ToShard)
         }
         
-        select {
-        case channel <- message:
-                csc.metrics.MessagesProcessed++
-                csc.logger.LogCrossShard(message.FromShard, message.ToShard, "direct_send", logrus.Fields{
-                        "message_id": message.ID,
-                        "timestamp":  time.Now().UTC(),
-                })
-                return nil
-        default:
-                csc.metrics.MessagesFailed++
-                return fmt.Errorf("message channel for shard %d is full", message.ToShard)
+        // Apply backpressure: wait a bounded time for the worker to make room
+        if err := queue.push(message, csc.enqueueTimeout); err != nil {
+                csc.countFailed()
+                return fmt.Errorf("%w for shard %d", err, message.ToShard)
         }
+        
+        csc.metricsMu.Lock()
+        csc.metrics.MessagesProcessed++
+        csc.metricsMu.Unlock()
+        csc.logger.LogCrossShard(message.FromShard, message.ToShard, "direct_send", logrus.Fields{
+                "message_id": message.ID,
+                "trace_id":   message.TraceID,
+                "priority":   messagePriority(message),
+                "timestamp":  csc.clock.Now().UTC(),
+        })
+        return nil
 }
 
-// sendViaRelay sends a message via relay nodes
+// sendViaRelay sends a message via relay nodes. When every relay on the
+// route is full, the relay overflow policy is applied at the first of them:
+// the message is refused, room is made by evicting the oldest buffered
+// message, or the send waits for room.
 func (csc *CrossShardCommunicator) sendViaRelay(message *types.CrossShardMessage, route *Route) error {
+        var first *RelayNode
         for _, relayNodeID := range route.RelayNodes {
                 relayNode, exists := csc.relayNodes[relayNodeID]
                 if !exists {
                         continue
                 }
+                if first == nil {
+                        first = relayNode
+                }
                 
-                relayNode.mu.Lock()
-                if len(relayNode.MessageBuffer) < relayNode.MaxBufferSize {
-                        relayNode.MessageBuffer = append(relayNode.MessageBuffer, message)
-                        relayNode.LastActivity = time.Now()
-                        relayNode.mu.Unlock()
-                        
-                        csc.logger.LogCrossShard(message.FromShard, message.ToShard, "relay_send", logrus.Fields{
-                                "message_id":   message.ID,
-                                "relay_node":   relayNodeID,
-                                "buffer_size":  len(relayNode.MessageBuffer),
-                                "timestamp":    time.Now().UTC(),
-                        })
-                        
+                accepted, err := csc.enqueueAtRelay(message, relayNode)
+                if err != nil {
+                        return err
+                }
+                if accepted {
                         return nil
                 }
-                relayNode.mu.Unlock()
+        }
+        
+        if first != nil {
+                accepted, err := csc.overflowAtRelay(message, first)
+                if err != nil {
+                        return err
+                }
+                if accepted {
+                        return nil
+                }
+                first.RejectedMsgs.Add(1)
+                csc.countRelayDropped(first)
         }
         
         return fmt.Errorf("all relay nodes are busy")
 }
 
-// findOptimalRoute finds the optimal route between shards
+// countRelayDropped records a message refused or evicted by a full
+// relayNode under the overflow policy
+func (csc *CrossShardCommunicator) countRelayDropped(relayNode *RelayNode) {
+        if csc.collector != nil {
+                csc.collector.IncrementRelayDropped(relayNode.ID, csc.relayOverflow)
+        }
+}
+
+// overflowAtRelay buffers message at a full relayNode according to the relay
+// overflow policy. It reports false if the message is still refused.
+func (csc *CrossShardCommunicator) overflowAtRelay(message *types.CrossShardMessage, relayNode *RelayNode) (bool, error) {
+        switch csc.relayOverflow {
+        case RelayOverflowDropOldest:
+                return csc.admitAtRelay(message, relayNode, true)
+        case RelayOverflowBlock:
+                deadline := csc.clock.After(csc.relayWait)
+                for {
+                        select {
+                        case <-relayNode.space:
+                        case <-deadline:
+                                return false, nil
+                        case <-csc.stopChan:
+                                return false, nil
+                        }
+                        accepted, err := csc.enqueueAtRelay(message, relayNode)
+                        if accepted || err != nil {
+                                return accepted, err
+                        }
+                }
+        case RelayOverflowSpill:
+                if err := csc.checkHops(message, relayNode); err != nil {
+                        return false, err
+                }
+                message.HopCount++
+                if err := relayNode.spill.push(message); err != nil {
+                        message.HopCount--
+                        csc.logger.LogCrossShard(message.FromShard, message.ToShard, "relay_spill_failed", logrus.Fields{
+                                "message_id": message.ID,
+                                "trace_id":   message.TraceID,
+                                "relay_node": relayNode.ShardID,
+                                "error":      err.Error(),
+                                "timestamp":  csc.clock.Now().UTC(),
+                        })
+                        return false, nil
+                }
+                csc.logger.LogCrossShard(message.FromShard, message.ToShard, "relay_spill", logrus.Fields{
+                        "message_id": message.ID,
+                        "trace_id":   message.TraceID,
+                        "relay_node": relayNode.ShardID,
+                        "hop_count":  message.HopCount,
+                        "spilled":    relayNode.spill.size(),
+                        "timestamp":  csc.clock.Now().UTC(),
+                })
+                return true, nil
+        default:
+                return false, nil
+        }
+}
+
+// enqueueAtRelay buffers message at relayNode as one more hop. It reports
+// false if the relay's buffer is full. A message that has already used up
+// its hops is dropped, counted as failed, and ErrTooManyHops returned.
+func (csc *CrossShardCommunicator) enqueueAtRelay(message *types.CrossShardMessage, relayNode *RelayNode) (bool, error) {
+        return csc.admitAtRelay(message, relayNode, false)
+}
+
+// admitAtRelay does the work of enqueueAtRelay. Messages spilled at the
+// relay take any room first, so a new message cannot overtake them. With
+// evict set, a full buffer gives up its oldest message to make room; the
+// evicted message is counted in the relay's EvictedMsgs and as a failed
+// message.
+func (csc *CrossShardCommunicator) admitAtRelay(message *types.CrossShardMessage, relayNode *RelayNode, evict bool) (bool, error) {
+        if err := csc.checkHops(message, relayNode); err != nil {
+                return false, err
+        }
+        
+        relayNode.mu.Lock()
+        csc.refillFromSpill(relayNode)
+        var evicted *types.CrossShardMessage
+        if len(relayNode.MessageBuffer) >= relayNode.MaxBufferSize {
+                if !evict || len(relayNode.MessageBuffer) == 0 {
+                        relayNode.mu.Unlock()
+                        return false, nil
+                }
+                evicted = relayNode.MessageBuffer[0]
+                relayNode.MessageBuffer[0] = nil
+                relayNode.MessageBuffer = relayNode.MessageBuffer[1:]
+        }
+        message.HopCount++
+        relayNode.MessageBuffer = append(relayNode.MessageBuffer, message)
+        relayNode.LastActivity = csc.clock.Now()
+        bufferSize := len(relayNode.MessageBuffer)
+        relayNode.mu.Unlock()
+        
+        if evicted != nil {
+                relayNode.EvictedMsgs.Add(1)
+                csc.countRelayDropped(relayNode)
+                csc.countFailed()
+                csc.forgetMessage(evicted)
+                csc.logger.LogCrossShard(evicted.FromShard, evicted.ToShard, "message_dropped", logrus.Fields{
+                        "message_id":   evicted.ID,
+                        "trace_id":     evicted.TraceID,
+                        "relay_node":   relayNode.ShardID,
+                        "displaced_by": message.ID,
+                        "reason":       "relay_buffer_overflow",
+                        "timestamp":    csc.clock.Now().UTC(),
+                })
+        }
+        
+        csc.logger.LogCrossShard(message.FromShard, message.ToShard, "relay_send", logrus.Fields{
+                "message_id":   message.ID,
+                "trace_id":     message.TraceID,
+                "relay_node":   relayNode.ShardID,
+                "hop_count":    message.HopCount,
+                "buffer_size":  bufferSize,
+                "timestamp":    csc.clock.Now().UTC(),
+        })
+        return true, nil
+}
+
+// checkHops drops message, counting it as failed, if it has already used up
+// its hops by the time it reaches relayNode, and returns ErrTooManyHops
+func (csc *CrossShardCommunicator) checkHops(message *types.CrossShardMessage, relayNode *RelayNode) error {
+        if message.HopCount < csc.maxHops {
+                return nil
+        }
+        csc.countFailed()
+        relayNode.FailedMsgs.Add(1)
+        csc.forgetMessage(message)
+        csc.logger.LogCrossShard(message.FromShard, message.ToShard, "message_dropped", logrus.Fields{
+                "message_id": message.ID,
+                "trace_id":   message.TraceID,
+                "relay_node": relayNode.ShardID,
+                "hop_count":  message.HopCount,
+                "max_hops":   csc.maxHops,
+                "reason":     "too_many_hops",
+                "timestamp":  csc.clock.Now().UTC(),
+        })
+        return fmt.Errorf("%w: message %s reached relay %s after %d hops", ErrTooManyHops, message.ID, relayNode.ID, message.HopCount)
+}
+
+// nextRelay returns the relay after relayShard on the route currently used
+// between message's shards, or -1 when relayShard is the last relay and the
+// message goes straight to its destination
+func (csc *CrossShardCommunicator) nextRelay(relayShard int, message *types.CrossShardMessage) int {
+        csc.routingTable.mu.Lock()
+        defer csc.routingTable.mu.Unlock()
+        
+        route := csc.routingTable.routes[RoutingKey{FromShard: message.FromShard, ToShard: message.ToShard}]
+        if route == nil {
+                return -1
+        }
+        for i, relay := range route.RelayNodes {
+                if relay == relayShard {
+                        if i+1 < len(route.RelayNodes) {
+                                return route.RelayNodes[i+1]
+                        }
+                        return -1
+                }
+        }
+        return -1
+}
+
+// findOptimalRoute chooses among the known routes between shards using the
+// load balancer's strategy
 func (csc *CrossShardCommunicator) findOptimalRoute(fromShard, toShard int) (*Route, error) {
-        csc.routingTable.mu.RLock()
-        defer csc.routingTable.mu.RUnlock()
+        csc.routingTable.mu.Lock()
+        defer csc.routingTable.mu.Unlock()
         
         key := RoutingKey{FromShard: fromShard, ToShard: toShard}
-        route, exists := csc.routingTable.routes[key]
-        if !exists {
+        candidates := csc.routingTable.candidates[key]
+        if len(candidates) == 0 {
                 // Create default direct route
-                route = &Route{
-                        FromShard:   fromShard,
-                        ToShard:     toShard,
-                        RelayNodes:  []int{},
-                        Latency:     10 * time.Millisecond,
-                        Reliability: 0.95,
-                        Capacity:    100,
-                        CurrentLoad: 0,
-                        LastUsed:    time.Now(),
-                        Priority:    1,
-                }
-                csc.routingTable.routes[key] = route
+                candidates = []*Route{csc.newRoute(fromShard, toShard, nil)}
+                csc.routingTable.candidates[key] = candidates
         }
         
-        route.LastUsed = time.Now()
+        route := csc.routingTable.loadBalancer.selectRoute(key, candidates)
+        csc.routingTable.routes[key] = route
+        
+        route.LastUsed = csc.clock.Now()
         route.CurrentLoad++
         
         return route, nil
 }
 
-// initializeRelayNode initializes a relay node for a shard
-func (csc *CrossShardCommunicator) initializeRelayNode(shardID int) {
+// initializeRelayNode initializes a relay node for a shard. Under
+// RelayOverflowSpill the node's spilled messages are opened too; with the
+// message log on they are discarded instead, as the log replays them.
+func (csc *CrossShardCommunicator) initializeRelayNode(shardID int) error {
         relayNode := &RelayNode{
                 ID:              fmt.Sprintf("relay-%d", shardID),
                 ShardID:         shardID,
                 ConnectedShards: make([]int, 0),
                 MessageBuffer:   make([]*types.CrossShardMessage, 0),
-                LastActivity:    time.Now(),
+                LastActivity:    csc.clock.Now(),
                 Latency:         0,
                 Throughput:      0.0,
                 Status:          "active",
-                MaxBufferSize:   1000,
-                ProcessedMsgs:   0,
-                FailedMsgs:      0,
+                MaxBufferSize:   csc.relayBufferSize,
+                space:           make(chan struct{}, 1),
+        }
+        
+        if csc.relayOverflow == RelayOverflowSpill {
+                db := csc.shardManager.GetDB()
+                if db == nil {
+                        return fmt.Errorf("relay overflow policy %s needs a database", RelayOverflowSpill)
+                }
+                relayNode.spill = openRelaySpill(db, shardID)
+                if csc.wal != nil {
+                        if err := relayNode.spill.discard(); err != nil {
+                                return err
+                        }
+                }
         }
         
         // Connect to adjacent shards
@@ -489,8 +1053,10 @@ func (csc *CrossShardCommunicator) initializeRelayNode(shardID int) {
                 "relay_id":         relayNode.ID,
                 "connected_shards": len(relayNode.ConnectedShards),
                 "max_buffer_size":  relayNode.MaxBufferSize,
-                "timestamp":        time.Now().UTC(),
+                "spilled":          relayNode.spill.size(),
+                "timestamp":        csc.clock.Now().UTC(),
         })
+        return nil
 }
 
 // initializeRoutingTable initializes the routing table
@@ -508,25 +1074,18 @@ func (csc *CrossShardCommunicator) initializeRoutingTable() {
                         }
                         
                         key := RoutingKey{FromShard: fromShard, ToShard: toShard}
-                        route := &Route{
-                                FromShard:   fromShard,
-                                ToShard:     toShard,
-                                RelayNodes:  []int{},
-                                Latency:     10 * time.Millisecond,
-                                Reliability: 0.95,
-                                Capacity:    100,
-                                CurrentLoad: 0,
-                                LastUsed:    time.Now(),
-                                Priority:    1,
-                        }
+                        direct := csc.newRoute(fromShard, toShard, nil)
+                        candidates := []*Route{direct}
                         
-                        // Add relay nodes for distant shards
+                        // Distant shards can also be reached through the
+                        // relay of the shard between them
                         if abs(fromShard-toShard) > 2 {
                                 intermediateNode := (fromShard + toShard) / 2
-                                route.RelayNodes = append(route.RelayNodes, intermediateNode)
+                                candidates = append(candidates, csc.newRoute(fromShard, toShard, []int{intermediateNode}))
                         }
                         
-                        csc.routingTable.routes[key] = route
+                        csc.routingTable.candidates[key] = candidates
+                        csc.routingTable.routes[key] = direct
                 }
                 
                 // Initialize relay mapping
@@ -535,15 +1094,33 @@ func (csc *CrossShardCommunicator) initializeRoutingTable() {
                 }
         }
         
-        csc.routingTable.lastUpdate = time.Now()
+        csc.routingTable.lastUpdate = csc.clock.Now()
         
         csc.logger.LogCrossShard(-1, -1, "routing_table_initialized", logrus.Fields{
                 "total_routes":   len(csc.routingTable.routes),
                 "relay_mappings": len(csc.routingTable.relayMapping),
-                "timestamp":      time.Now().UTC(),
+                "strategy":       csc.routingTable.loadBalancer.strategy,
+                "timestamp":      csc.clock.Now().UTC(),
         })
 }
 
+// newRoute creates a route through relays with the latency and reliability
+// the route model predicts for it when idle
+func (csc *CrossShardCommunicator) newRoute(fromShard, toShard int, relays []int) *Route {
+        route := &Route{
+                FromShard:   fromShard,
+                ToShard:     toShard,
+                RelayNodes:  append([]int{}, relays...),
+                Capacity:    100,
+                CurrentLoad: 0,
+                LastUsed:    csc.clock.Now(),
+                Priority:    1,
+        }
+        route.Latency = csc.calculateRouteLatency(route)
+        route.Reliability = csc.calculateRouteReliability(route)
+        return route
+}
+
 // initializeConflictRules initializes default conflict resolution rules
 func (csc *CrossShardCommunicator) initializeConflictRules() {
         resolver := csc.syncManager.conflictResolver
@@ -586,48 +1163,110 @@ func (csc *CrossShardCommunicator) initializeConflictRules() {
                         "min_stake_difference": 1000,
                 },
         })
+        
+        // Rule 4: Block forks - prefer the earlier block
+        resolver.resolutionRules = append(resolver.resolutionRules, &ConflictRule{
+                Type:     "block_fork",
+                Priority: 4,
+                Condition: map[string]interface{}{
+                        "conflict_type": "block_fork",
+                },
+                Action: "prefer_earlier_block",
+                Parameters: map[string]interface{}{
+                        "tie_breaker": "lower_hash",
+                },
+        })
 }
 
 // Worker methods
 
-// messageProcessor processes cross-shard messages
+// messageWorker handles the messages queued for the shards assigned to it,
+// highest ranked first, until every one of its queues is closed and drained
+func (csc *CrossShardCommunicator) messageWorker(workerID int, queues []*messageQueue, wake <-chan struct{}) {
+        defer csc.workerWG.Done()
+        
+        handled := 0
+        for {
+                if message, ok := nextMessage(queues); ok {
+                        csc.handleMessage(message.ToShard, message)
+                        handled++
+                        continue
+                }
+                
+                drained := true
+                for _, queue := range queues {
+                        if !queue.drained() {
+                                drained = false
+                                break
+                        }
+                }
+                if drained {
+                        break
+                }
+                <-wake
+        }
+        
+        csc.logger.LogCrossShard(-1, -1, "worker_stopped", logrus.Fields{
+                "worker_id": workerID,
+                "handled":   handled,
+                "timestamp": csc.clock.Now().UTC(),
+        })
+}
+
+// messageProcessor forwards relayed messages to the worker queues
 func (csc *CrossShardCommunicator) messageProcessor() {
-        ticker := time.NewTicker(100 * time.Millisecond)
+        ticker := csc.clock.NewTicker(100 * time.Millisecond)
         defer ticker.Stop()
         
         for {
                 select {
                 case <-csc.stopChan:
                         return
-                case <-ticker.C:
+                case <-ticker.C():
                         csc.processMessages()
                 }
         }
 }
 
-// processMessages processes pending messages
+// processMessages moves buffered relay messages onto the worker queues
 func (csc *CrossShardCommunicator) processMessages() {
-        for shardID, channel := range csc.messageChannels {
-                select {
-                case message := <-channel:
-                        csc.handleMessage(shardID, message)
-                default:
-                        // No messages pending
-                }
+        csc.mu.RLock()
+        defer csc.mu.RUnlock()
+        
+        // Queues are closed once the communicator stops
+        if !csc.isRunning {
+                return
         }
         
-        // Process relay node buffers
         for _, relayNode := range csc.relayNodes {
                 csc.processRelayBuffer(relayNode)
         }
 }
 
-// handleMessage handles a cross-shard message
+// handleMessage handles a cross-shard message. Relays deliver at least
+// once, so a message already handled by the shard is skipped; a message
+// whose handling failed is not remembered and may be retried.
 func (csc *CrossShardCommunicator) handleMessage(shardID int, message *types.CrossShardMessage) {
-        startTime := time.Now()
+        startTime := csc.clock.Now()
+        
+        delivered := csc.delivered[shardID]
+        if delivered != nil && delivered.contains(message.ID) {
+                csc.metricsMu.Lock()
+                csc.metrics.DuplicatesDropped++
+                csc.metricsMu.Unlock()
+                csc.forgetMessage(message)
+                csc.logger.LogCrossShard(message.FromShard, message.ToShard, "duplicate_dropped", logrus.Fields{
+                        "message_id": message.ID,
+                        "trace_id":   message.TraceID,
+                        "shard_id":   shardID,
+                        "timestamp":  startTime,
+                })
+                return
+        }
         
         csc.logger.LogCrossShard(message.FromShard, message.ToShard, "handle_message", logrus.Fields{
                 "message_id":   message.ID,
+                "trace_id":     message.TraceID,
                 "message_type": message.Type,
                 "shard_id":     shardID,
                 "timestamp":    startTime,
@@ -639,9 +1278,10 @@ func (csc *CrossShardCommunicator) handleMessage(shardID int, message *types.Cro
                 csc.logger.LogError("cross_shard", "get_shard", err, logrus.Fields{
                         "shard_id":   shardID,
                         "message_id": message.ID,
-                        "timestamp":  time.Now().UTC(),
+                        "trace_id":   message.TraceID,
+                        "timestamp":  csc.clock.Now().UTC(),
                 })
-                csc.metrics.MessagesFailed++
+                csc.countFailed()
                 return
         }
         
@@ -655,75 +1295,197 @@ func (csc *CrossShardCommunicator) handleMessage(shardID int, message *types.Cro
                 err = csc.handleSyncMessage(shard, message)
         case "validation":
                 err = csc.handleValidationMessage(shard, message)
+        case MessageTypePrepare:
+                err = csc.handlePrepareMessage(shard, message)
+        case MessageTypeCommit:
+                err = csc.handleCommitMessage(shard, message)
+        case MessageTypeAbort:
+                err = csc.handleAbortMessage(shard, message)
         default:
                 err = fmt.Errorf("unknown message type: %s", message.Type)
         }
         
         // Update metrics
-        processingTime := time.Since(startTime)
+        processingTime := csc.clock.Since(startTime)
         if err != nil {
-                csc.metrics.MessagesFailed++
+                csc.countFailed()
                 csc.logger.LogError("cross_shard", "handle_message", err, logrus.Fields{
                         "message_id":      message.ID,
+                        "trace_id":        message.TraceID,
                         "processing_time": processingTime.Milliseconds(),
-                        "timestamp":       time.Now().UTC(),
+                        "timestamp":       csc.clock.Now().UTC(),
                 })
         } else {
-                csc.metrics.MessagesProcessed++
                 message.Processed = true
+                if delivered != nil {
+                        delivered.add(message.ID)
+                }
+                csc.forgetMessage(message)
                 
                 // Update average latency
-                if csc.metrics.AverageLatency == 0 {
-                        csc.metrics.AverageLatency = processingTime
-                } else {
-                        csc.metrics.AverageLatency = (csc.metrics.AverageLatency + processingTime) / 2
-                }
+                latencyMs := float64(processingTime) / float64(time.Millisecond)
+                csc.latencyEWMA.Add(latencyMs)
+                csc.latencySMA.Add(latencyMs)
+                
+                csc.metricsMu.Lock()
+                csc.metrics.MessagesProcessed++
+                csc.metrics.AverageLatency = time.Duration(csc.latencyEWMA.Value() * float64(time.Millisecond))
+                csc.metrics.MovingAvgLatency = time.Duration(csc.latencySMA.Value() * float64(time.Millisecond))
+                csc.metricsMu.Unlock()
                 
                 csc.logger.LogCrossShard(message.FromShard, message.ToShard, "message_processed", logrus.Fields{
                         "message_id":      message.ID,
+                        "trace_id":        message.TraceID,
                         "processing_time": processingTime.Milliseconds(),
-                        "timestamp":       time.Now().UTC(),
+                        "timestamp":       csc.clock.Now().UTC(),
                 })
         }
 }
 
 // handleTransactionMessage handles transaction messages
 func (csc *CrossShardCommunicator) handleTransactionMessage(shard *Shard, message *types.CrossShardMessage) error {
-        if tx, ok := message.Data.(*types.Transaction); ok {
-                return shard.AddTransaction(tx)
+        tx, err := message.Transaction()
+        if err != nil {
+                return err
+        }
+        // Cross-shard transfers are credited only once both shards agree to
+        // commit
+        if tx.Type == "cross_shard" {
+                return csc.BeginTwoPhaseCommit(tx, message.FromShard, shard.ID)
         }
-        return fmt.Errorf("invalid transaction data in message")
+        return shard.AddTransaction(tx)
 }
 
 // handleBlockMessage handles block messages
 func (csc *CrossShardCommunicator) handleBlockMessage(shard *Shard, message *types.CrossShardMessage) error {
-        if block, ok := message.Data.(*types.Block); ok {
-                return shard.AddBlock(block)
+        block, err := message.Block()
+        if err != nil {
+                return err
+        }
+        err = shard.AddBlock(block)
+        var fork *BlockConflictError
+        if errors.As(err, &fork) {
+                return csc.handleBlockConflict(shard, message.FromShard, fork)
+        }
+        return err
+}
+
+// handleBlockConflict settles a block received for a height the shard
+// already holds a different block for. The fork is recorded with the
+// conflict resolver and resolved at once by the block_fork rule; when the
+// incoming block wins and the block it rivals is still the shard's head, the
+// head is replaced.
+func (csc *CrossShardCommunicator) handleBlockConflict(shard *Shard, fromShard int, fork *BlockConflictError) error {
+        if !fork.Incoming.VerifyHash() {
+                return fmt.Errorf("conflicting block %d hash mismatch: expected %s, got %s", fork.Index, fork.Incoming.ComputeHash(), fork.Incoming.Hash)
+        }
+        
+        now := csc.clock.Now()
+        conflict := &TransactionConflict{
+                ID:             fmt.Sprintf("fork_%d_%d_%s", shard.ID, fork.Index, fork.Incoming.Hash),
+                ConflictType:   "block_fork",
+                InvolvedShards: []int{fromShard, shard.ID},
+                Blocks:         []*types.Block{fork.Existing, fork.Incoming},
+                CreatedAt:      now,
+                Metadata: map[string]interface{}{
+                        "shard_id": shard.ID,
+                        "height":   fork.Index,
+                },
+        }
+        
+        resolver := csc.syncManager.conflictResolver
+        resolver.mu.Lock()
+        resolver.conflicts[conflict.ID] = conflict
+        resolver.resolutionStats.TotalConflicts++
+        resolver.resolutionStats.ConflictsByType[conflict.ConflictType]++
+        resolved := csc.resolveConflict(conflict)
+        if resolved {
+                conflict.ResolvedAt = &now
+                resolver.resolutionStats.ResolvedConflicts++
+        } else {
+                resolver.resolutionStats.FailedResolutions++
+        }
+        resolver.resolutionStats.LastUpdate = now
+        winner, _ := conflict.Metadata["winner_block"].(string)
+        resolver.mu.Unlock()
+        
+        outcome := "kept_existing"
+        var err error
+        switch {
+        case !resolved:
+                outcome = "unresolved"
+                err = fork
+        case winner == fork.Incoming.Hash:
+                if err = shard.ReplaceHead(fork.Existing, fork.Incoming); err != nil {
+                        outcome = "replace_failed"
+                } else {
+                        outcome = "replaced_head"
+                }
+        }
+        
+        csc.metricsMu.Lock()
+        csc.metrics.BlockConflicts++
+        if resolved {
+                csc.metrics.ConflictsResolved++
+        }
+        csc.metricsMu.Unlock()
+        if csc.collector != nil {
+                csc.collector.IncrementShardBlockConflict(shard.ID, outcome)
         }
-        return fmt.Errorf("invalid block data in message")
+        
+        csc.logger.LogCrossShard(fromShard, shard.ID, "conflict_detected", logrus.Fields{
+                "conflict_id":   conflict.ID,
+                "conflict_type": conflict.ConflictType,
+                "block_index":   fork.Index,
+                "existing_hash": fork.Existing.Hash,
+                "incoming_hash": fork.Incoming.Hash,
+                "resolution":    conflict.Resolution,
+                "outcome":       outcome,
+                "timestamp":     now.UTC(),
+        })
+        return err
 }
 
-// handleSyncMessage handles synchronization messages
+// handleSyncMessage queues a sync of the sending shard from this one over
+// the block range the message carries. A message without a range asks for
+// every block after the sender's head.
 func (csc *CrossShardCommunicator) handleSyncMessage(shard *Shard, message *types.CrossShardMessage) error {
+        var syncRange types.SyncRange
+        if message.Data != nil {
+                r, err := message.SyncRange()
+                if err != nil {
+                        return err
+                }
+                syncRange = *r
+        }
+        if syncRange.StartBlock < 0 || syncRange.EndBlock < 0 || (syncRange.EndBlock > 0 && syncRange.EndBlock < syncRange.StartBlock) {
+                return fmt.Errorf("invalid sync range %d-%d in message %s", syncRange.StartBlock, syncRange.EndBlock, message.ID)
+        }
+        
         csc.syncManager.mu.Lock()
         defer csc.syncManager.mu.Unlock()
         
         // Create sync request
         syncRequest := &SyncRequest{
-                ID:        fmt.Sprintf("sync_%s", message.ID),
-                FromShard: message.FromShard,
-                ToShard:   message.ToShard,
-                Priority:  1,
-                CreatedAt: time.Now(),
-                Status:    "pending",
-                Data:      message.Data,
+                ID:         fmt.Sprintf("sync_%s", message.ID),
+                FromShard:  message.FromShard,
+                ToShard:    message.ToShard,
+                StartBlock: syncRange.StartBlock,
+                EndBlock:   syncRange.EndBlock,
+                Priority:   1,
+                CreatedAt:  csc.clock.Now(),
+                Status:     "pending",
+                Data:       message.Data,
         }
         
         csc.syncManager.syncRequests[syncRequest.ID] = syncRequest
         
         csc.logger.LogCrossShard(message.FromShard, message.ToShard, "sync_request_created", logrus.Fields{
-                "sync_id":   syncRequest.ID,
-                "timestamp": time.Now().UTC(),
+                "sync_id":     syncRequest.ID,
+                "start_block": syncRequest.StartBlock,
+                "end_block":   syncRequest.EndBlock,
+                "trace_id":    message.TraceID,
+                "timestamp":   csc.clock.Now().UTC(),
         })
         
         return nil
@@ -738,11 +1500,15 @@ func (csc *CrossShardCommunicator) handleValidationMessage(shard *Shard, message
                 ToShard:        message.ToShard,
                 ValidationType: "cross_shard",
                 Priority:       1,
-                CreatedAt:      time.Now(),
+                CreatedAt:      csc.clock.Now(),
                 Callback:       make(chan ValidationResult, 1),
         }
         
-        if tx, ok := message.Data.(*types.Transaction); ok {
+        if message.DataType == types.CrossShardDataTransaction {
+                tx, err := message.Transaction()
+                if err != nil {
+                        return err
+                }
                 validationReq.Transaction = tx
         }
         
@@ -751,7 +1517,8 @@ func (csc *CrossShardCommunicator) handleValidationMessage(shard *Shard, message
         case csc.validationQueue <- validationReq:
                 csc.logger.LogCrossShard(message.FromShard, message.ToShard, "validation_queued", logrus.Fields{
                         "validation_id": validationReq.ID,
-                        "timestamp":     time.Now().UTC(),
+                        "trace_id":      message.TraceID,
+                        "timestamp":     csc.clock.Now().UTC(),
                 })
                 return nil
         default:
@@ -759,44 +1526,130 @@ func (csc *CrossShardCommunicator) handleValidationMessage(shard *Shard, message
         }
 }
 
-// processRelayBuffer processes messages in a relay node buffer
+// relayForward is a message leaving a relay for the next relay on its route
+type relayForward struct {
+        message *types.CrossShardMessage
+        next    int // shard ID of the next relay
+}
+
+// processRelayBuffer processes messages in a relay node's buffer, passing
+// each to the next relay on its route or, from the last relay, straight to
+// its destination shard
 func (csc *CrossShardCommunicator) processRelayBuffer(relayNode *RelayNode) {
         relayNode.mu.Lock()
-        defer relayNode.mu.Unlock()
+        csc.refillFromSpill(relayNode)
         
         if len(relayNode.MessageBuffer) == 0 {
+                relayNode.mu.Unlock()
                 return
         }
         
         // Process up to 10 messages per cycle
         processed := 0
+        expired := 0
         remaining := make([]*types.CrossShardMessage, 0)
+        forwards := make([]relayForward, 0)
+        now := csc.clock.Now()
         
         for _, message := range relayNode.MessageBuffer {
+                // A transaction that outlived its TTL would be refused anyway
+                if tx, err := message.Transaction(); err == nil && tx.Expired(csc.txTTL, now) {
+                        expired++
+                        csc.forgetMessage(message)
+                        csc.logger.LogCrossShard(message.FromShard, message.ToShard, "relay_message_expired", logrus.Fields{
+                                "relay_id":   relayNode.ID,
+                                "message_id": message.ID,
+                                "trace_id":   message.TraceID,
+                                "tx_id":      tx.ID,
+                                "timestamp":  now.UTC(),
+                        })
+                        continue
+                }
+                
                 if processed >= 10 {
                         remaining = append(remaining, message)
                         continue
                 }
                 
+                // Forwarded once this relay's lock is released, as the
+                // next relay may be this one
+                if next := csc.nextRelay(relayNode.ShardID, message); next >= 0 {
+                        forwards = append(forwards, relayForward{message: message, next: next})
+                        processed++
+                        continue
+                }
+                
                 err := csc.sendDirect(message)
                 if err != nil {
                         remaining = append(remaining, message)
-                        relayNode.FailedMsgs++
+                        relayNode.FailedMsgs.Add(1)
                 } else {
-                        relayNode.ProcessedMsgs++
+                        relayNode.ProcessedMsgs.Add(1)
                         processed++
                 }
         }
         
+        freed := len(remaining) < len(relayNode.MessageBuffer)
         relayNode.MessageBuffer = remaining
-        relayNode.LastActivity = time.Now()
+        relayNode.LastActivity = csc.clock.Now()
+        relayNode.mu.Unlock()
+        if freed {
+                signal(relayNode.space)
+        }
+        
+        dropped := 0
+        for _, forward := range forwards {
+                message := forward.message
+                nextRelay, exists := csc.relayNodes[forward.next]
+                accepted := false
+                var err error
+                if exists {
+                        accepted, err = csc.enqueueAtRelay(message, nextRelay)
+                }
+                switch {
+                case errors.Is(err, ErrTooManyHops):
+                        dropped++
+                case accepted:
+                        relayNode.ProcessedMsgs.Add(1)
+                default:
+                        // The next relay is full or gone; try again next cycle
+                        relayNode.mu.Lock()
+                        relayNode.MessageBuffer = append(relayNode.MessageBuffer, message)
+                        relayNode.mu.Unlock()
+                        relayNode.FailedMsgs.Add(1)
+                }
+        }
         
-        if processed > 0 {
+        if processed > 0 || expired > 0 {
                 csc.logger.LogCrossShard(relayNode.ShardID, -1, "relay_buffer_processed", logrus.Fields{
                         "relay_id":   relayNode.ID,
                         "processed":  processed,
+                        "forwarded":  len(forwards) - dropped,
+                        "dropped":    dropped,
+                        "expired":    expired,
                         "remaining":  len(remaining),
-                        "timestamp":  time.Now().UTC(),
+                        "timestamp":  csc.clock.Now().UTC(),
+                })
+        }
+}
+
+// refillFromSpill moves spilled messages, oldest first, into whatever room
+// relayNode's buffer has. They already count the hop through this relay.
+// Caller must hold relayNode.mu.
+func (csc *CrossShardCommunicator) refillFromSpill(relayNode *RelayNode) {
+        room := relayNode.MaxBufferSize - len(relayNode.MessageBuffer)
+        if relayNode.spill.size() == 0 || room <= 0 {
+                return
+        }
+        
+        messages, err := relayNode.spill.pop(room)
+        relayNode.MessageBuffer = append(relayNode.MessageBuffer, messages...)
+        if err != nil {
+                csc.logger.LogCrossShard(relayNode.ShardID, -1, "relay_spill_failed", logrus.Fields{
+                        "relay_id":  relayNode.ID,
+                        "refilled":  len(messages),
+                        "error":     err.Error(),
+                        "timestamp": csc.clock.Now().UTC(),
                 })
         }
 }
@@ -816,7 +1669,7 @@ func (csc *CrossShardCommunicator) validationWorker() {
 
 // processValidationRequest processes a validation request
 func (csc *CrossShardCommunicator) processValidationRequest(req *CrossShardValidationRequest) ValidationResult {
-        startTime := time.Now()
+        startTime := csc.clock.Now()
         
         csc.logger.LogCrossShard(req.FromShard, req.ToShard, "process_validation", logrus.Fields{
                 "validation_id": req.ID,
@@ -827,30 +1680,33 @@ func (csc *CrossShardCommunicator) processValidationRequest(req *CrossShardValid
         result := ValidationResult{
                 Valid:       true,
                 Details:     make(map[string]interface{}),
-                ProcessedAt: time.Now(),
+                ProcessedAt: csc.clock.Now(),
         }
         
         // Perform validation based on type
-        switch req.ValidationType {
-        case "cross_shard":
+        switch {
+        case req.Transaction != nil && req.Transaction.Expired(csc.txTTL, startTime):
+                result.Valid = false
+                result.Error = fmt.Errorf("%w: older than %s", blockchain.ErrTransactionExpired, csc.txTTL)
+        case req.ValidationType == "cross_shard":
                 result = csc.validateCrossShardTransaction(req.Transaction)
-        case "balance":
+        case req.ValidationType == "balance":
                 result = csc.validateBalance(req.Transaction)
-        case "signature":
+        case req.ValidationType == "signature":
                 result = csc.validateSignature(req.Transaction)
         default:
                 result.Valid = false
                 result.Error = fmt.Errorf("unknown validation type: %s", req.ValidationType)
         }
         
-        processingTime := time.Since(startTime)
+        processingTime := csc.clock.Since(startTime)
         result.Details["processing_time"] = processingTime.Milliseconds()
         
         csc.logger.LogCrossShard(req.FromShard, req.ToShard, "validation_completed", logrus.Fields{
                 "validation_id":   req.ID,
                 "valid":          result.Valid,
                 "processing_time": processingTime.Milliseconds(),
-                "timestamp":       time.Now().UTC(),
+                "timestamp":       csc.clock.Now().UTC(),
         })
         
         return result
@@ -861,7 +1717,7 @@ func (csc *CrossShardCommunicator) validateCrossShardTransaction(tx *types.Trans
         result := ValidationResult{
                 Valid:       true,
                 Details:     make(map[string]interface{}),
-                ProcessedAt: time.Now(),
+                ProcessedAt: csc.clock.Now(),
         }
         
         // Check transaction structure
@@ -872,8 +1728,8 @@ func (csc *CrossShardCommunicator) validateCrossShardTransaction(tx *types.Trans
         }
         
         // Check if it's actually a cross-shard transaction
-        fromShard := utils.GenerateShardKey(tx.From, csc.shardManager.totalShards)
-        toShard := utils.GenerateShardKey(tx.To, csc.shardManager.totalShards)
+        // Same-group transfers route to one shard and are not cross-shard
+        fromShard, toShard := csc.shardManager.crossShardRouter.RouteTransaction(tx)
         
         if fromShard == toShard {
                 result.Valid = false
@@ -894,7 +1750,16 @@ func (csc *CrossShardCommunicator) validateCrossShardTransaction(tx *types.Trans
                 return result
         }
         
+        // Check nonce against the sender's last committed nonce to prevent replay
+        lastNonce := csc.shardManager.blockchain.GetAccountNonce(tx.From)
+        if tx.Nonce != lastNonce+1 {
+                result.Valid = false
+                result.Error = fmt.Errorf("invalid nonce %d for %s, expected %d", tx.Nonce, tx.From, lastNonce+1)
+                return result
+        }
+        
         result.Details["from_shard"] = fromShard
+        result.Details["nonce"] = tx.Nonce
         result.Details["to_shard"] = toShard
         result.Details["validation_type"] = "cross_shard"
         
@@ -906,11 +1771,9 @@ func (csc *CrossShardCommunicator) validateBalance(tx *types.Transaction) Valida
         result := ValidationResult{
                 Valid:       true,
                 Details:     make(map[string]interface{}),
-                ProcessedAt: time.Now(),
+                ProcessedAt: csc.clock.Now(),
         }
         
-        // Simplified balance validation
-        // In a real implementation, this would check the actual balance
         if tx.Amount <= 0 {
                 result.Valid = false
                 result.Error = fmt.Errorf("invalid transaction amount: %d", tx.Amount)
@@ -921,8 +1784,23 @@ func (csc *CrossShardCommunicator) validateBalance(tx *types.Transaction) Valida
                 result.Error = fmt.Errorf("invalid transaction fee: %d", tx.Fee)
         }
         
+        floor := csc.shardManager.blockchain.GetFeeEstimate().Floor
+        if result.Valid && tx.Fee < floor {
+                result.Valid = false
+                result.Error = fmt.Errorf("%w: fee %d, floor %d", blockchain.ErrFeeTooLow, tx.Fee, floor)
+        }
+        
+        // Check the sender can cover amount and fee
+        balance := csc.shardManager.blockchain.GetBalance(tx.From)
+        if result.Valid && tx.Amount+tx.Fee > balance {
+                result.Valid = false
+                result.Error = fmt.Errorf("insufficient balance: have %d, need %d", balance, tx.Amount+tx.Fee)
+        }
+        
         result.Details["amount"] = tx.Amount
         result.Details["fee"] = tx.Fee
+        result.Details["fee_floor"] = floor
+        result.Details["balance"] = balance
         result.Details["validation_type"] = "balance"
         
         return result
@@ -933,16 +1811,21 @@ func (csc *CrossShardCommunicator) validateSignature(tx *types.Transaction) Vali
         result := ValidationResult{
                 Valid:       true,
                 Details:     make(map[string]interface{}),
-                ProcessedAt: time.Now(),
+                ProcessedAt: csc.clock.Now(),
         }
         
-        // Simplified signature validation
-        if tx.Signature == "" {
+        // Recover the signer and make sure it is the sender
+        sender, err := utils.RecoverSender(tx)
+        if err != nil {
                 result.Valid = false
-                result.Error = fmt.Errorf("transaction signature is empty")
+                result.Error = fmt.Errorf("signature recovery failed: %w", err)
+        } else if !strings.EqualFold(sender, tx.From) {
+                result.Valid = false
+                result.Error = fmt.Errorf("signature does not match sender: signed by %s, from %s", sender, tx.From)
         }
         
         result.Details["signature_length"] = len(tx.Signature)
+        result.Details["recovered_sender"] = sender
         result.Details["validation_type"] = "signature"
         
         return result
@@ -950,53 +1833,105 @@ func (csc *CrossShardCommunicator) validateSignature(tx *types.Transaction) Vali
 
 // syncWorker handles synchronization between shards
 func (csc *CrossShardCommunicator) syncWorker() {
-        ticker := time.NewTicker(csc.syncManager.syncInterval)
+        ticker := csc.clock.NewTicker(csc.syncManager.syncInterval)
         defer ticker.Stop()
         
         for {
                 select {
                 case <-csc.stopChan:
                         return
-                case <-ticker.C:
+                case <-ticker.C():
                         csc.processSyncRequests()
                 }
         }
 }
 
-// processSyncRequests processes pending synchronization requests
+// processSyncRequests runs the pending sync requests that are due, at most
+// concurrency of them at once. A request whose range is longer than the
+// batch size stays pending and continues on the next pass.
 func (csc *CrossShardCommunicator) processSyncRequests() {
         csc.syncManager.mu.Lock()
         defer csc.syncManager.mu.Unlock()
         
-        processed := 0
-        for reqID, syncReq := range csc.syncManager.syncRequests {
-                if syncReq.Status != "pending" {
+        now := csc.clock.Now()
+        due := make([]*SyncRequest, 0, csc.syncManager.concurrency)
+        for _, syncReq := range csc.syncManager.syncRequests {
+                if syncReq.Status != "pending" || now.Before(syncReq.NextRetryAt) {
                         continue
                 }
-                
-                if processed >= 5 { // Process max 5 sync requests per cycle
-                        break
-                }
-                
-                err := csc.processSyncRequest(syncReq)
+                due = append(due, syncReq)
+        }
+        // Oldest first, so a steady stream of new requests cannot starve old ones
+        sort.Slice(due, func(i, j int) bool {
+                return due[i].CreatedAt.Before(due[j].CreatedAt)
+        })
+        if len(due) > csc.syncManager.concurrency {
+                due = due[:csc.syncManager.concurrency]
+        }
+        
+        // Each sync touches only its own request and the two shards, so they
+        // can run while the manager lock is held
+        results := make([]SyncResult, len(due))
+        errs := make([]error, len(due))
+        var wg sync.WaitGroup
+        for i, syncReq := range due {
+                wg.Add(1)
+                go func(i int, syncReq *SyncRequest) {
+                        defer wg.Done()
+                        results[i], errs[i] = csc.processSyncRequest(syncReq)
+                }(i, syncReq)
+        }
+        wg.Wait()
+        
+        for i, syncReq := range due {
+                reqID := syncReq.ID
+                err := errs[i]
                 if err != nil {
                         syncReq.RetryCount++
+                        syncReq.LastError = err.Error()
                         if syncReq.RetryCount >= csc.syncManager.maxRetries {
                                 syncReq.Status = "failed"
+                                csc.metricsMu.Lock()
+                                csc.metrics.SyncFailures++
+                                csc.metricsMu.Unlock()
                                 csc.logger.LogError("cross_shard", "sync_failed", err, logrus.Fields{
                                         "sync_id":     reqID,
                                         "retry_count": syncReq.RetryCount,
-                                        "timestamp":   time.Now().UTC(),
+                                        "timestamp":   csc.clock.Now().UTC(),
+                                })
+                        } else {
+                                backoff := csc.syncManager.retryBackoff(syncReq.RetryCount)
+                                syncReq.NextRetryAt = now.Add(backoff)
+                                csc.metricsMu.Lock()
+                                csc.metrics.SyncRetries++
+                                csc.metricsMu.Unlock()
+                                csc.logger.LogCrossShard(syncReq.FromShard, syncReq.ToShard, "sync_retry_scheduled", logrus.Fields{
+                                        "sync_id":       reqID,
+                                        "retry_count":   syncReq.RetryCount,
+                                        "backoff":       backoff.String(),
+                                        "next_retry_at": syncReq.NextRetryAt.UTC(),
+                                        "error":         err.Error(),
+                                        "timestamp":     csc.clock.Now().UTC(),
                                 })
                         }
+                } else if results[i].More {
+                        // Resume from the block after this batch next pass
+                        syncReq.StartBlock = results[i].LastBlock + 1
+                        csc.logger.LogCrossShard(syncReq.FromShard, syncReq.ToShard, "sync_batch_transferred", logrus.Fields{
+                                "sync_id":     reqID,
+                                "transferred": results[i].Transferred,
+                                "last_block":  results[i].LastBlock,
+                                "timestamp":   csc.clock.Now().UTC(),
+                        })
                 } else {
                         syncReq.Status = "completed"
+                        csc.metricsMu.Lock()
                         csc.metrics.SyncOperations++
-                        processed++
+                        csc.metricsMu.Unlock()
                         
                         csc.logger.LogCrossShard(syncReq.FromShard, syncReq.ToShard, "sync_completed", logrus.Fields{
                                 "sync_id":   reqID,
-                                "timestamp": time.Now().UTC(),
+                                "timestamp": csc.clock.Now().UTC(),
                         })
                 }
         }
@@ -1004,40 +1939,66 @@ func (csc *CrossShardCommunicator) processSyncRequests() {
         // Clean up completed/failed requests
         for reqID, syncReq := range csc.syncManager.syncRequests {
                 if syncReq.Status == "completed" || syncReq.Status == "failed" {
-                        if time.Since(syncReq.CreatedAt) > 1*time.Hour {
+                        if csc.clock.Since(syncReq.CreatedAt) > 1*time.Hour {
                                 delete(csc.syncManager.syncRequests, reqID)
                         }
                 }
         }
 }
 
-// processSyncRequest processes a single sync request
-func (csc *CrossShardCommunicator) processSyncRequest(syncReq *SyncRequest) error {
+// retryBackoff returns how long to wait before retrying a sync request that
+// has failed attempt times. The delay doubles per attempt up to retryMaxDelay
+// and is jittered over its upper half, so requests failing together spread
+// out without the delay ever shrinking from one attempt to the next.
+func (sm *CrossShardSyncManager) retryBackoff(attempt int) time.Duration {
+        delay := sm.retryBaseDelay
+        for i := 1; i < attempt && delay < sm.retryMaxDelay; i++ {
+                delay *= 2
+        }
+        if delay > sm.retryMaxDelay {
+                delay = sm.retryMaxDelay
+        }
+        half := delay / 2
+        return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// processSyncRequest runs one batch of a sync request
+func (csc *CrossShardCommunicator) processSyncRequest(syncReq *SyncRequest) (SyncResult, error) {
         // Get source and target shards
         sourceShard, err := csc.shardManager.GetShard(syncReq.FromShard)
         if err != nil {
-                return fmt.Errorf("source shard not found: %w", err)
+                return SyncResult{}, fmt.Errorf("source shard not found: %w", err)
         }
         
         targetShard, err := csc.shardManager.GetShard(syncReq.ToShard)
         if err != nil {
-                return fmt.Errorf("target shard not found: %w", err)
+                return SyncResult{}, fmt.Errorf("target shard not found: %w", err)
         }
         
         // Perform synchronization
-        return sourceShard.Sync(targetShard)
+        result, err := sourceShard.Sync(targetShard, SyncOptions{
+                Strategy:   csc.syncManager.strategy,
+                StartBlock: syncReq.StartBlock,
+                EndBlock:   syncReq.EndBlock,
+                MaxBlocks:  csc.syncManager.batchSize,
+        })
+        if err != nil {
+                return result, err
+        }
+        syncReq.Data = result
+        return result, nil
 }
 
 // routingTableUpdater updates the routing table periodically
 func (csc *CrossShardCommunicator) routingTableUpdater() {
-        ticker := time.NewTicker(csc.routingTable.updateInterval)
+        ticker := csc.clock.NewTicker(csc.routingTable.updateInterval)
         defer ticker.Stop()
         
         for {
                 select {
                 case <-csc.stopChan:
                         return
-                case <-ticker.C:
+                case <-ticker.C():
                         csc.updateRoutingTable()
                 }
         }
@@ -1048,32 +2009,16 @@ func (csc *CrossShardCommunicator) updateRoutingTable() {
         csc.routingTable.mu.Lock()
         defer csc.routingTable.mu.Unlock()
         
-        now := time.Now()
+        now := csc.clock.Now()
         updatedRoutes := 0
         
         // Update route metrics
-        for key, route := range csc.routingTable.routes {
-                // Update latency based on recent usage
-                if now.Sub(route.LastUsed) < 5*time.Minute {
-                        // Recently used route - calculate actual latency
-                        route.Latency = csc.calculateRouteLatency(route)
-                        route.Reliability = csc.calculateRouteReliability(route)
-                        updatedRoutes++
-                }
-                
-                // Reset load counters
-                route.CurrentLoad = 0
-                
-                // Update priority based on performance
-                if route.Reliability > 0.9 && route.Latency < 50*time.Millisecond {
-                        route.Priority = 1 // High priority
-                } else if route.Reliability > 0.7 && route.Latency < 100*time.Millisecond {
-                        route.Priority = 2 // Medium priority
-                } else {
-                        route.Priority = 3 // Low priority
+        for _, candidates := range csc.routingTable.candidates {
+                for _, route := range candidates {
+                        if csc.refreshRoute(route, now) {
+                                updatedRoutes++
+                        }
                 }
-                
-                _ = key // Avoid unused variable warning
         }
         
         // Update load balancer
@@ -1088,6 +2033,34 @@ func (csc *CrossShardCommunicator) updateRoutingTable() {
         })
 }
 
+// refreshRoute re-estimates the metrics of a recently used route, resets its
+// load and reports whether it was re-estimated
+func (csc *CrossShardCommunicator) refreshRoute(route *Route, now time.Time) bool {
+        refreshed := false
+        
+        // Update latency based on recent usage
+        if now.Sub(route.LastUsed) < 5*time.Minute {
+                // Recently used route - calculate actual latency
+                route.Latency = csc.calculateRouteLatency(route)
+                route.Reliability = csc.calculateRouteReliability(route)
+                refreshed = true
+        }
+        
+        // Reset load counters
+        route.CurrentLoad = 0
+        
+        // Update priority based on performance
+        if route.Reliability > 0.9 && route.Latency < 50*time.Millisecond {
+                route.Priority = 1 // High priority
+        } else if route.Reliability > 0.7 && route.Latency < 100*time.Millisecond {
+                route.Priority = 2 // Medium priority
+        } else {
+                route.Priority = 3 // Low priority
+        }
+        
+        return refreshed
+}
+
 // calculateRouteLatency calculates latency for a route
 func (csc *CrossShardCommunicator) calculateRouteLatency(route *Route) time.Duration {
         baseLatency := 5 * time.Millisecond
@@ -1131,7 +2104,7 @@ func (csc *CrossShardCommunicator) updateLoadBalancer() {
         defer lb.mu.Unlock()
         
         // Update shard loads
-        for shardID := range csc.messageChannels {
+        for shardID := range csc.messageQueues {
                 load := 0.0
                 if shard, err := csc.shardManager.GetShard(shardID); err == nil {
                         if shard.TransactionPool != nil {
@@ -1152,45 +2125,132 @@ func (csc *CrossShardCommunicator) updateLoadBalancer() {
         }
         
         // Limit history size
-        if len(lb.history) > 1000 {
-                lb.history = lb.history[len(lb.history)-1000:]
+        if len(lb.history) > maxLoadBalanceHistory {
+                lb.history = lb.history[len(lb.history)-maxLoadBalanceHistory:]
         }
 }
 
 // metricsCollector collects and updates metrics
 func (csc *CrossShardCommunicator) metricsCollector() {
-        ticker := time.NewTicker(5 * time.Second)
+        ticker := csc.clock.NewTicker(5 * time.Second)
         defer ticker.Stop()
         
         for {
                 select {
                 case <-csc.stopChan:
                         return
-                case <-ticker.C:
+                case <-ticker.C():
                         csc.updateMetrics()
+                        csc.checkErrorRate()
                 }
         }
 }
 
+// OnErrorRateExceeded registers a callback invoked with the window error
+// rate, in percent, each time it rises above the configured alert threshold
+func (csc *CrossShardCommunicator) OnErrorRateExceeded(callback func(rate float64)) {
+        csc.errorRate.onExceeded(callback)
+}
+
+// checkErrorRate updates the windowed error rate and the degraded flag,
+// warning and notifying callbacks when the threshold is crossed
+func (csc *CrossShardCommunicator) checkErrorRate() {
+        csc.metricsMu.Lock()
+        now := csc.clock.Now()
+        rate, degraded, changed := csc.errorRate.observe(now, csc.metrics.MessagesProcessed, csc.metrics.MessagesFailed)
+        csc.metrics.WindowErrorRate = rate
+        csc.metrics.Degraded = degraded
+        if degraded {
+                csc.metrics.DetailedMetrics["cross_shard_degraded"] = 1
+        } else {
+                csc.metrics.DetailedMetrics["cross_shard_degraded"] = 0
+        }
+        csc.metricsMu.Unlock()
+        
+        if !changed {
+                return
+        }
+        
+        fields := logrus.Fields{
+                "window_error_rate": rate,
+                "threshold":         csc.errorRate.threshold,
+                "window_seconds":    csc.errorRate.window.Seconds(),
+                "timestamp":         now.UTC(),
+        }
+        if !degraded {
+                csc.logger.LogCrossShard(-1, -1, "error_rate_recovered", fields)
+                return
+        }
+        
+        csc.logger.GetContextLogger("cross_shard", fields).Warn("Cross-shard error rate above alert threshold")
+        for _, callback := range csc.errorRate.exceededCallbacks() {
+                callback(rate)
+        }
+}
+
+// countFailed records a message that could not be sent or handled
+func (csc *CrossShardCommunicator) countFailed() {
+        csc.metricsMu.Lock()
+        csc.metrics.MessagesFailed++
+        csc.metricsMu.Unlock()
+}
+
 // updateMetrics updates cross-shard communication metrics
 func (csc *CrossShardCommunicator) updateMetrics() {
         csc.mu.Lock()
         defer csc.mu.Unlock()
         
-        now := time.Now()
+        now := csc.clock.Now()
         
         // Count active relay nodes
         activeRelays := 0
         totalBufferSize := 0
+        spilled := 0
         for _, relayNode := range csc.relayNodes {
+                relayNode.mu.RLock()
                 if relayNode.Status == "active" {
                         activeRelays++
                 }
-                relayNode.mu.RLock()
-                totalBufferSize += len(relayNode.MessageBuffer)
+                buffered := len(relayNode.MessageBuffer)
                 relayNode.mu.RUnlock()
+                totalBufferSize += buffered
+                
+                relaySpilled := relayNode.spill.size()
+                spilled += relaySpilled
+                if csc.collector != nil {
+                        csc.collector.SetRelayBufferSize(relayNode.ID, float64(buffered))
+                        csc.collector.SetRelaySpilled(relayNode.ID, float64(relaySpilled))
+                }
         }
         
+        for _, queue := range csc.messageQueues {
+                totalBufferSize += queue.size()
+        }
+        
+        remembered := 0
+        for _, delivered := range csc.delivered {
+                remembered += delivered.size()
+        }
+        
+        csc.routingTable.mu.RLock()
+        totalRoutes := len(csc.routingTable.routes)
+        csc.routingTable.mu.RUnlock()
+        
+        csc.syncManager.mu.RLock()
+        twoPhaseInFlight := csc.syncManager.countInFlightTwoPhase()
+        twoPhaseTracked := len(csc.syncManager.twoPhaseTxs)
+        syncRequests := len(csc.syncManager.syncRequests)
+        csc.syncManager.mu.RUnlock()
+        
+        csc.syncManager.conflictResolver.mu.RLock()
+        conflicts := len(csc.syncManager.conflictResolver.conflicts)
+        csc.syncManager.conflictResolver.mu.RUnlock()
+        
+        // Everything read under other locks is gathered; the rest of the
+        // update only touches metrics
+        csc.metricsMu.Lock()
+        defer csc.metricsMu.Unlock()
+        
         csc.metrics.ActiveRelayNodes = activeRelays
         csc.metrics.QueuedMessages = totalBufferSize
         
@@ -1208,10 +2268,20 @@ func (csc *CrossShardCommunicator) updateMetrics() {
         
         // Update detailed metrics
         csc.metrics.DetailedMetrics["uptime_seconds"] = uptime
-        csc.metrics.DetailedMetrics["active_channels"] = len(csc.messageChannels)
-        csc.metrics.DetailedMetrics["total_routes"] = len(csc.routingTable.routes)
-        csc.metrics.DetailedMetrics["sync_requests"] = len(csc.syncManager.syncRequests)
-        csc.metrics.DetailedMetrics["conflicts"] = len(csc.syncManager.conflictResolver.conflicts)
+        csc.metrics.DetailedMetrics["active_queues"] = len(csc.messageQueues)
+        csc.metrics.DetailedMetrics["workers"] = csc.workers
+        
+        csc.metrics.DetailedMetrics["delivered_ids_tracked"] = remembered
+        csc.metrics.DetailedMetrics["total_routes"] = totalRoutes
+        csc.metrics.DetailedMetrics["load_balance_strategy"] = csc.routingTable.loadBalancer.strategy
+        csc.metrics.DetailedMetrics["sync_requests"] = syncRequests
+        csc.metrics.DetailedMetrics["conflicts"] = conflicts
+        csc.metrics.DetailedMetrics["logged_messages"] = csc.wal.size()
+        csc.metrics.DetailedMetrics["relay_overflow_policy"] = csc.relayOverflow
+        csc.metrics.DetailedMetrics["relay_spilled_messages"] = spilled
+        
+        csc.metrics.TwoPhaseInFlight = twoPhaseInFlight
+        csc.metrics.DetailedMetrics["two_phase_tracked"] = twoPhaseTracked
         
         csc.metrics.LastUpdate = now
         
@@ -1223,20 +2293,21 @@ func (csc *CrossShardCommunicator) updateMetrics() {
                 "queued_messages":     csc.metrics.QueuedMessages,
                 "error_rate":          csc.metrics.ErrorRate,
                 "average_latency":     csc.metrics.AverageLatency.Milliseconds(),
+                "moving_avg_latency":  csc.metrics.MovingAvgLatency.Milliseconds(),
                 "timestamp":           now,
         })
 }
 
 // conflictResolver handles conflict resolution
 func (csc *CrossShardCommunicator) conflictResolver() {
-        ticker := time.NewTicker(2 * time.Second)
+        ticker := csc.clock.NewTicker(2 * time.Second)
         defer ticker.Stop()
         
         for {
                 select {
                 case <-csc.stopChan:
                         return
-                case <-ticker.C:
+                case <-ticker.C():
                         csc.processConflicts()
                 }
         }
@@ -1260,10 +2331,12 @@ func (csc *CrossShardCommunicator) processConflicts() {
                 
                 resolved := csc.resolveConflict(conflict)
                 if resolved {
-                        now := time.Now()
+                        now := csc.clock.Now()
                         conflict.ResolvedAt = &now
                         resolver.resolutionStats.ResolvedConflicts++
+                        csc.metricsMu.Lock()
                         csc.metrics.ConflictsResolved++
+                        csc.metricsMu.Unlock()
                         processed++
                         
                         csc.logger.LogCrossShard(-1, -1, "conflict_resolved", logrus.Fields{
@@ -1277,12 +2350,58 @@ func (csc *CrossShardCommunicator) processConflicts() {
         
         // Clean up old resolved conflicts
         for conflictID, conflict := range resolver.conflicts {
-                if conflict.ResolvedAt != nil && time.Since(*conflict.ResolvedAt) > 1*time.Hour {
+                if conflict.ResolvedAt != nil && csc.clock.Since(*conflict.ResolvedAt) > 1*time.Hour {
                         delete(resolver.conflicts, conflictID)
                 }
         }
         
-        resolver.resolutionStats.LastUpdate = time.Now()
+        resolver.resolutionStats.LastUpdate = csc.clock.Now()
+}
+
+// recordConflictCheck counts a cross-shard transaction checked for
+// conflicts and, when it competes with rivals for the sender's funds,
+// queues a double-spend conflict for resolution
+func (csc *CrossShardCommunicator) recordConflictCheck(tx *types.Transaction, rivals []*types.Transaction, fromShard, toShard int) {
+        resolver := csc.syncManager.conflictResolver
+        resolver.mu.Lock()
+        defer resolver.mu.Unlock()
+        
+        now := csc.clock.Now()
+        resolver.resolutionStats.TransactionsChecked++
+        resolver.resolutionStats.LastUpdate = now
+        if len(rivals) == 0 {
+                return
+        }
+        
+        conflict := &TransactionConflict{
+                ID:             fmt.Sprintf("conflict_%s", tx.ID),
+                ConflictType:   "double_spend",
+                InvolvedShards: []int{fromShard, toShard},
+                Transactions:   append([]*types.Transaction{tx}, rivals...),
+                CreatedAt:      now,
+                Metadata:       map[string]interface{}{"sender": tx.From},
+        }
+        resolver.conflicts[conflict.ID] = conflict
+        resolver.resolutionStats.TotalConflicts++
+        resolver.resolutionStats.ConflictsByType[conflict.ConflictType]++
+        
+        csc.logger.LogCrossShard(fromShard, toShard, "conflict_detected", logrus.Fields{
+                "conflict_id":   conflict.ID,
+                "conflict_type": conflict.ConflictType,
+                "tx_id":         tx.ID,
+                "trace_id":      tx.TraceID,
+                "rivals":        len(rivals),
+                "timestamp":     now.UTC(),
+        })
+}
+
+// conflictCounts returns the conflicts detected so far and the cross-shard
+// transactions checked for them
+func (csc *CrossShardCommunicator) conflictCounts() (conflicts, checked int64) {
+        resolver := csc.syncManager.conflictResolver
+        resolver.mu.RLock()
+        defer resolver.mu.RUnlock()
+        return resolver.resolutionStats.TotalConflicts, resolver.resolutionStats.TransactionsChecked
 }
 
 // resolveConflict resolves a transaction conflict
@@ -1311,6 +2430,8 @@ func (csc *CrossShardCommunicator) resolveConflict(conflict *TransactionConflict
                 return csc.resolveByEarlierTimestamp(conflict)
         case "prefer_higher_stake":
                 return csc.resolveByHigherStake(conflict)
+        case "prefer_earlier_block":
+                return csc.resolveByEarlierBlock(conflict)
         default:
                 conflict.Resolution = "unknown_action"
                 return false
@@ -1350,7 +2471,7 @@ func (csc *CrossShardCommunicator) resolveByEarlierTimestamp(conflict *Transacti
         }
         
         var winnerTx *types.Transaction
-        earliestTime := time.Now()
+        earliestTime := csc.clock.Now()
         
         for _, tx := range conflict.Transactions {
                 if tx.Timestamp.Before(earliestTime) {
@@ -1385,13 +2506,49 @@ func (csc *CrossShardCommunicator) resolveByHigherStake(conflict *TransactionCon
         return true
 }
 
+// resolveByEarlierBlock resolves a block fork by preferring the block with
+// the earlier timestamp, then the lower hash, so every node holding both
+// blocks keeps the same one
+func (csc *CrossShardCommunicator) resolveByEarlierBlock(conflict *TransactionConflict) bool {
+        if len(conflict.Blocks) < 2 {
+                return false
+        }
+        
+        winner := conflict.Blocks[0]
+        for _, block := range conflict.Blocks[1:] {
+                if block.Timestamp.Before(winner.Timestamp) || (block.Timestamp.Equal(winner.Timestamp) && block.Hash < winner.Hash) {
+                        winner = block
+                }
+        }
+        
+        conflict.Resolution = fmt.Sprintf("preferred_block_%s_earlier_timestamp_%d", winner.Hash, winner.Timestamp.Unix())
+        conflict.Metadata["winner_block"] = winner.Hash
+        conflict.Metadata["winning_timestamp"] = winner.Timestamp.Unix()
+        return true
+}
+
 // GetMetrics returns cross-shard communication metrics
 func (csc *CrossShardCommunicator) GetMetrics() *CrossShardMetrics {
         csc.mu.RLock()
         defer csc.mu.RUnlock()
         
+        // Queue depth is read live rather than from the last collection
+        depth := make(map[int]int)
+        for _, queue := range csc.messageQueues {
+                queue.depthByPriority(depth)
+        }
+        
         // Return a copy
+        csc.metricsMu.Lock()
         metrics := *csc.metrics
+        metrics.DetailedMetrics = make(map[string]interface{}, len(csc.metrics.DetailedMetrics))
+        for key, value := range csc.metrics.DetailedMetrics {
+                metrics.DetailedMetrics[key] = value
+        }
+        csc.metricsMu.Unlock()
+        
+        metrics.QueueDepthByPriority = depth
+        metrics.CircuitBreakers = csc.breakerStatus()
         return &metrics
 }
 
@@ -1410,20 +2567,109 @@ func (csc *CrossShardCommunicator) GetRoutingTable() map[RoutingKey]*Route {
         return routes
 }
 
-// GetRelayNodes returns information about relay nodes
-func (csc *CrossShardCommunicator) GetRelayNodes() map[int]*RelayNode {
+// RouteInfo is a copy of a known route between two shards
+type RouteInfo struct {
+        Route
+        Active  bool          `json:"active"`   // the route last chosen for its shard pair
+        IdleFor time.Duration `json:"idle_for"` // time since the route last carried a message
+}
+
+// ListRoutes returns every known route, including alternatives through
+// relays that are not currently chosen, ordered by shard pair
+func (csc *CrossShardCommunicator) ListRoutes() []RouteInfo {
+        csc.routingTable.mu.RLock()
+        defer csc.routingTable.mu.RUnlock()
+        
+        now := csc.clock.Now()
+        routes := make([]RouteInfo, 0, len(csc.routingTable.candidates))
+        for key, candidates := range csc.routingTable.candidates {
+                active := csc.routingTable.routes[key]
+                for _, route := range candidates {
+                        info := RouteInfo{Route: *route, Active: route == active, IdleFor: now.Sub(route.LastUsed)}
+                        info.RelayNodes = append([]int{}, route.RelayNodes...)
+                        routes = append(routes, info)
+                }
+        }
+        
+        sort.Slice(routes, func(i, j int) bool {
+                if routes[i].FromShard != routes[j].FromShard {
+                        return routes[i].FromShard < routes[j].FromShard
+                }
+                if routes[i].ToShard != routes[j].ToShard {
+                        return routes[i].ToShard < routes[j].ToShard
+                }
+                return len(routes[i].RelayNodes) < len(routes[j].RelayNodes)
+        })
+        return routes
+}
+
+// PruneRoutes forgets routes that have not carried a message within
+// olderThan and returns how many were removed. It holds the routing table
+// lock, so it is safe while messages are flowing: a shard pair left without
+// routes gets a fresh direct route the next time a message is sent.
+func (csc *CrossShardCommunicator) PruneRoutes(olderThan time.Duration) int {
+        csc.routingTable.mu.Lock()
+        defer csc.routingTable.mu.Unlock()
+        
+        cutoff := csc.clock.Now().Add(-olderThan)
+        removed := 0
+        for key, candidates := range csc.routingTable.candidates {
+                kept := candidates[:0]
+                for _, route := range candidates {
+                        if route.LastUsed.Before(cutoff) {
+                                removed++
+                                continue
+                        }
+                        kept = append(kept, route)
+                }
+                for i := len(kept); i < len(candidates); i++ {
+                        candidates[i] = nil
+                }
+                
+                if len(kept) == 0 {
+                        delete(csc.routingTable.candidates, key)
+                        delete(csc.routingTable.routes, key)
+                        continue
+                }
+                csc.routingTable.candidates[key] = kept
+                
+                active := csc.routingTable.routes[key]
+                stillKnown := false
+                for _, route := range kept {
+                        if route == active {
+                                stillKnown = true
+                                break
+                        }
+                }
+                if !stillKnown {
+                        csc.routingTable.routes[key] = kept[0]
+                }
+        }
+        
+        csc.logger.LogCrossShard(-1, -1, "routes_pruned", logrus.Fields{
+                "older_than":   olderThan.String(),
+                "removed":      removed,
+                "total_routes": len(csc.routingTable.routes),
+                "timestamp":    csc.clock.Now().UTC(),
+        })
+        
+        return removed
+}
+
+// GetLoadBalanceHistory returns up to limit of the most recent routing
+// decisions, oldest first. A limit of zero returns all that are kept.
+func (csc *CrossShardCommunicator) GetLoadBalanceHistory(limit int) []*LoadBalanceDecision {
+        return csc.routingTable.loadBalancer.recentDecisions(limit)
+}
+
+// GetRelayNodes returns a snapshot of every relay node
+func (csc *CrossShardCommunicator) GetRelayNodes() map[int]*RelayNodeInfo {
         csc.mu.RLock()
         defer csc.mu.RUnlock()
         
-        // Return a copy
-        relays := make(map[int]*RelayNode)
+        relays := make(map[int]*RelayNodeInfo, len(csc.relayNodes))
         for id, relay := range csc.relayNodes {
-                relay.mu.RLock()
-                relayCopy := *relay
-                relayCopy.MessageBuffer = make([]*types.CrossShardMessage, len(relay.MessageBuffer))
-                copy(relayCopy.MessageBuffer, relay.MessageBuffer)
-                relay.mu.RUnlock()
-                relays[id] = &relayCopy
+                relays[id] = relay.snapshot()
         }
         
         return relays
@@ -0,0 +1,74 @@
+package sharding
+
+import (
+        "container/list"
+        "lscc-blockchain/internal/utils"
+        "sync"
+        "time"
+)
+
+// deliveredSet remembers recently delivered message IDs for one shard so a
+// message redelivered by a relay retry is recognised and skipped. IDs are
+// forgotten after ttl of clock time, or oldest first once capacity is
+// reached.
+type deliveredSet struct {
+        mu       sync.Mutex
+        clock    utils.Clock
+        ttl      time.Duration
+        capacity int
+        expiry   map[string]time.Time
+        order    *list.List // message IDs, oldest first
+}
+
+func newDeliveredSet(capacity int, ttl time.Duration, clock utils.Clock) *deliveredSet {
+        return &deliveredSet{
+                clock:    clock,
+                ttl:      ttl,
+                capacity: capacity,
+                expiry:   make(map[string]time.Time),
+                order:    list.New(),
+        }
+}
+
+// contains reports whether id was delivered within the last ttl
+func (s *deliveredSet) contains(id string) bool {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+
+        expiry, exists := s.expiry[id]
+        return exists && s.clock.Now().Before(expiry)
+}
+
+// add records id as delivered
+func (s *deliveredSet) add(id string) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+
+        now := s.clock.Now()
+        s.evict(now)
+        if _, exists := s.expiry[id]; exists {
+                return
+        }
+        s.expiry[id] = now.Add(s.ttl)
+        s.order.PushBack(id)
+}
+
+// size returns the number of remembered IDs
+func (s *deliveredSet) size() int {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        return len(s.expiry)
+}
+
+// evict drops expired IDs and makes room for one more. Every ID lives for
+// the same ttl, so insertion order is also expiry order.
+func (s *deliveredSet) evict(now time.Time) {
+        for front := s.order.Front(); front != nil; front = s.order.Front() {
+                id := front.Value.(string)
+                if len(s.expiry) < s.capacity && now.Before(s.expiry[id]) {
+                        return
+                }
+                delete(s.expiry, id)
+                s.order.Remove(front)
+        }
+}
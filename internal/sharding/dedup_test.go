@@ -0,0 +1,77 @@
+package sharding
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+)
+
+func TestRedeliveredMessageHandledOnce(t *testing.T) {
+	sm := newTestShardManager(t, nil)
+	csc := NewCrossShardCommunicator(sm, sm.logger)
+	if err := csc.Start(); err != nil {
+		t.Fatalf("failed to start communicator: %v", err)
+	}
+
+	// A relay retrying after a lost acknowledgement delivers the message again
+	message := &types.CrossShardMessage{ID: "retried", FromShard: 0, ToShard: 1, Type: "sync"}
+	for attempt := 0; attempt < 3; attempt++ {
+		if err := csc.sendDirect(message); err != nil {
+			t.Fatalf("delivery attempt %d failed: %v", attempt, err)
+		}
+	}
+	// A failed handling is not remembered, so the retry is handled again
+	unknown := &types.CrossShardMessage{ID: "unknown", FromShard: 0, ToShard: 1, Type: "bogus"}
+	for attempt := 0; attempt < 2; attempt++ {
+		if err := csc.sendDirect(unknown); err != nil {
+			t.Fatalf("delivery attempt %d failed: %v", attempt, err)
+		}
+	}
+	// Stop returns once every queued message is handled
+	if err := csc.Stop(); err != nil {
+		t.Fatalf("failed to stop communicator: %v", err)
+	}
+
+	metrics := csc.GetMetrics()
+	if metrics.DuplicatesDropped != 2 {
+		t.Fatalf("duplicates dropped = %d, want 2", metrics.DuplicatesDropped)
+	}
+	if metrics.MessagesFailed != 2 {
+		t.Fatalf("messages failed = %d, want both attempts at the unknown message", metrics.MessagesFailed)
+	}
+	if !message.Processed {
+		t.Fatal("delivered message not marked processed")
+	}
+	if !csc.delivered[1].contains("retried") || csc.delivered[1].contains("unknown") {
+		t.Fatal("delivered set should hold only the handled message")
+	}
+}
+
+func TestDeliveredSetExpiresAndEvicts(t *testing.T) {
+	clock := utils.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	set := newDeliveredSet(3, 20*time.Millisecond, clock)
+	set.add("a")
+	set.add("a")
+	if !set.contains("a") || set.size() != 1 {
+		t.Fatalf("after adding a twice: contains=%v size=%d", set.contains("a"), set.size())
+	}
+
+	clock.Advance(30 * time.Millisecond)
+	if set.contains("a") {
+		t.Fatal("id remembered past its ttl")
+	}
+
+	set = newDeliveredSet(3, time.Minute, clock)
+	for i := 0; i < 5; i++ {
+		set.add(fmt.Sprintf("m%d", i))
+	}
+	if set.size() != 3 {
+		t.Fatalf("size = %d, want the capacity of 3", set.size())
+	}
+	if set.contains("m0") || set.contains("m1") || !set.contains("m4") {
+		t.Fatal("capacity should evict the oldest ids first")
+	}
+}
@@ -0,0 +1,80 @@
+package sharding
+
+import (
+        "sync"
+        "time"
+)
+
+// minErrorRateMessages is the traffic a window needs before its error rate
+// can raise an alert, so a single early failure does not read as 100%
+const minErrorRateMessages = 10
+
+// errorRateSample is a reading of the cumulative message counters
+type errorRateSample struct {
+        at        time.Time
+        processed int64
+        failed    int64
+}
+
+// errorRateMonitor tracks the cross-shard error rate over a sliding window
+// and reports when it crosses the alert threshold in either direction
+type errorRateMonitor struct {
+        mu        sync.Mutex
+        threshold float64 // percent of messages failed; 0 disables alerting
+        window    time.Duration
+        samples   []errorRateSample
+        degraded  bool
+        callbacks []func(rate float64)
+}
+
+func newErrorRateMonitor(threshold float64, window time.Duration) *errorRateMonitor {
+        return &errorRateMonitor{
+                threshold: threshold,
+                window:    window,
+                samples:   make([]errorRateSample, 0),
+        }
+}
+
+// onExceeded registers a callback fired when the monitor becomes degraded
+func (m *errorRateMonitor) onExceeded(callback func(rate float64)) {
+        m.mu.Lock()
+        defer m.mu.Unlock()
+        m.callbacks = append(m.callbacks, callback)
+}
+
+// observe records the current counters and returns the error rate over the
+// window, whether the rate is above the threshold, and whether that state
+// changed with this reading
+func (m *errorRateMonitor) observe(now time.Time, processed, failed int64) (rate float64, degraded bool, changed bool) {
+        m.mu.Lock()
+        defer m.mu.Unlock()
+
+        m.samples = append(m.samples, errorRateSample{at: now, processed: processed, failed: failed})
+
+        // Keep the newest sample that is at least a window old as the baseline
+        cutoff := now.Add(-m.window)
+        drop := 0
+        for drop+1 < len(m.samples) && !m.samples[drop+1].at.After(cutoff) {
+                drop++
+        }
+        m.samples = m.samples[drop:]
+
+        baseline := m.samples[0]
+        windowFailed := failed - baseline.failed
+        windowTotal := (processed - baseline.processed) + windowFailed
+        if windowTotal > 0 {
+                rate = float64(windowFailed) / float64(windowTotal) * 100
+        }
+
+        degraded = m.threshold > 0 && windowTotal >= minErrorRateMessages && rate > m.threshold
+        changed = degraded != m.degraded
+        m.degraded = degraded
+        return rate, degraded, changed
+}
+
+// exceededCallbacks returns the registered callbacks
+func (m *errorRateMonitor) exceededCallbacks() []func(rate float64) {
+        m.mu.Lock()
+        defer m.mu.Unlock()
+        return append([]func(rate float64){}, m.callbacks...)
+}
@@ -0,0 +1,105 @@
+package sharding
+
+import (
+        "math"
+        "time"
+)
+
+// Load-balancing strategies for choosing between routes to the same shard
+const (
+        LoadBalanceRoundRobin   = "round_robin"
+        LoadBalanceLeastLatency = "least_latency"
+        LoadBalanceAdaptive     = "adaptive"
+)
+
+// maxLoadBalanceHistory bounds the decisions kept by a LoadBalancer
+const maxLoadBalanceHistory = 1000
+
+// selectRoute picks one of the candidate routes for key according to the
+// balancer's strategy and records the decision. candidates must not be empty.
+//
+// round_robin cycles through the candidates, least_latency takes the lowest
+// Latency, and adaptive scales each route's latency by its load and divides
+// by its reliability, so a fast but saturated or flaky path loses to a
+// slower healthy one. Ties go to the earlier candidate.
+func (lb *LoadBalancer) selectRoute(key RoutingKey, candidates []*Route) *Route {
+        lb.mu.Lock()
+        defer lb.mu.Unlock()
+
+        selected := 0
+        switch lb.strategy {
+        case LoadBalanceRoundRobin:
+                selected = lb.rrNext[key] % len(candidates)
+                lb.rrNext[key] = selected + 1
+        case LoadBalanceLeastLatency:
+                for i, route := range candidates {
+                        if route.Latency < candidates[selected].Latency {
+                                selected = i
+                        }
+                }
+        default:
+                best := math.Inf(1)
+                for i, route := range candidates {
+                        if score := lb.adaptiveScore(route); score < best {
+                                selected, best = i, score
+                        }
+                }
+        }
+
+        route := candidates[selected]
+        relay := -1
+        if len(route.RelayNodes) > 0 {
+                relay = route.RelayNodes[0]
+        }
+
+        lb.history = append(lb.history, &LoadBalanceDecision{
+                Timestamp:     time.Now(),
+                FromShard:     key.FromShard,
+                ToShard:       key.ToShard,
+                SelectedRelay: relay,
+                Strategy:      lb.strategy,
+                LoadFactor:    lb.routeLoad(route),
+                Latency:       route.Latency,
+        })
+        if len(lb.history) > maxLoadBalanceHistory {
+                lb.history = lb.history[len(lb.history)-maxLoadBalanceHistory:]
+        }
+
+        return route
+}
+
+// routeLoad returns the load factor of a route: its own utilisation or that
+// of its busiest relay, whichever is higher
+func (lb *LoadBalancer) routeLoad(route *Route) float64 {
+        load := 0.0
+        if route.Capacity > 0 {
+                load = float64(route.CurrentLoad) / float64(route.Capacity)
+        }
+        for _, relay := range route.RelayNodes {
+                load = math.Max(load, lb.relayLoads[relay])
+        }
+        return load
+}
+
+// adaptiveScore ranks a route for the adaptive strategy; lower is better
+func (lb *LoadBalancer) adaptiveScore(route *Route) float64 {
+        reliability := math.Max(route.Reliability, 0.01)
+        return float64(route.Latency) * (1 + lb.routeLoad(route)) / reliability
+}
+
+// recentDecisions returns up to limit of the latest decisions, oldest first
+func (lb *LoadBalancer) recentDecisions(limit int) []*LoadBalanceDecision {
+        lb.mu.RLock()
+        defer lb.mu.RUnlock()
+
+        start := 0
+        if limit > 0 && len(lb.history) > limit {
+                start = len(lb.history) - limit
+        }
+        decisions := make([]*LoadBalanceDecision, 0, len(lb.history)-start)
+        for _, decision := range lb.history[start:] {
+                decisionCopy := *decision
+                decisions = append(decisions, &decisionCopy)
+        }
+        return decisions
+}
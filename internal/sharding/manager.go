@@ -4,6 +4,7 @@ import (
         "fmt"
         "lscc-blockchain/config"
         "lscc-blockchain/internal/blockchain"
+        "lscc-blockchain/internal/metrics"
         "lscc-blockchain/internal/storage"
         "lscc-blockchain/internal/utils"
         "lscc-blockchain/pkg/types"
@@ -29,6 +30,10 @@ type ShardManager struct {
         rebalancer           *ShardRebalancer
         performanceTracker   *ShardPerformanceTracker
         consensusCoordinator *ConsensusCoordinator
+        communicator         *CrossShardCommunicator // nil until StartCrossCommunication
+        collector            *metrics.MetricsCollector // handed to the communicator; guarded by commMu
+        clock                utils.Clock             // handed to the communicator; guarded by commMu
+        commMu               sync.Mutex              // guards communicator, collector and clock; never held with mu
         mu                   sync.RWMutex
         isRunning            bool
         stopChan             chan struct{}
@@ -39,6 +44,9 @@ type ShardManager struct {
 // CrossShardRouter handles routing of cross-shard transactions
 type CrossShardRouter struct {
         routingTable    map[string]int                     // address -> shard
+        affinity        map[string]string                  // address -> affinity group
+        numShards       int
+        assigner        shardAssigner                      // maps addresses and affinity groups to shards
         messageQueue    chan *types.CrossShardMessage
         deliveryStatus  map[string]string                  // messageID -> status
         retryQueue      []*types.CrossShardMessage
@@ -123,6 +131,12 @@ type ConsensusCoordinator struct {
         coordinationMode string         // "parallel", "sequential", "adaptive"
         lastSync         time.Time
         syncInterval     time.Duration
+        engines          map[int]*shardEngine // per-shard consensus, nil unless running
+        stopConsensus    chan struct{}        // closed to stop the shard rounds
+        wg               sync.WaitGroup       // shard round goroutines
+        activeMode       string               // schedule adaptive coordination is following, empty otherwise
+        unsubscribe      func()               // stops epoch updates from the main chain, nil unless running
+        crossCommits     crossCommitQueue     // cross-shard transactions awaiting a joint round
         mu               sync.RWMutex
         logger           *utils.Logger
 }
@@ -150,11 +164,15 @@ func NewShardManager(cfg *config.Config, bc *blockchain.Blockchain, logger *util
                 stopChan:           make(chan struct{}),
                 startTime:          startTime,
                 metrics:            make(map[string]interface{}),
+                clock:              utils.SystemClock,
         }
         
         // Initialize cross-shard router
         sm.crossShardRouter = &CrossShardRouter{
                 routingTable:   make(map[string]int),
+                affinity:       make(map[string]string),
+                numShards:      sm.totalShards,
+                assigner:       newShardAssigner(cfg.Sharding.AssignmentStrategy, sm.totalShards, cfg.Sharding.VirtualNodes),
                 messageQueue:   make(chan *types.CrossShardMessage, 1000),
                 deliveryStatus: make(map[string]string),
                 retryQueue:     make([]*types.CrossShardMessage, 0),
@@ -192,7 +210,7 @@ func NewShardManager(cfg *config.Config, bc *blockchain.Blockchain, logger *util
         sm.consensusCoordinator = &ConsensusCoordinator{
                 shardConsensus:   make(map[int]string),
                 globalConsensus:  "syncing",
-                coordinationMode: "adaptive",
+                coordinationMode: cfg.Sharding.CoordinationMode,
                 lastSync:         startTime,
                 syncInterval:     30 * time.Second,
                 logger:           logger,
@@ -245,6 +263,7 @@ func (sm *ShardManager) Initialize() error {
                 }
                 
                 shard := NewShard(i, layer, sm.db, sm.logger)
+                shard.router = sm.crossShardRouter
                 sm.shards[i] = shard
                 
                 // Initialize shard metrics
@@ -340,6 +359,12 @@ func (sm *ShardManager) Start() error {
                 }
         }
         
+        if sm.config.Sharding.ShardConsensus {
+                if err := sm.startShardConsensus(); err != nil {
+                        return err
+                }
+        }
+        
         sm.isRunning = true
         sm.consensusCoordinator.globalConsensus = "active"
         
@@ -351,8 +376,12 @@ func (sm *ShardManager) Start() error {
         return nil
 }
 
-// Stop stops all shards and the manager
+// Stop stops cross-shard communication, shard consensus, all shards and the
+// manager
 func (sm *ShardManager) Stop() error {
+        sm.stopCrossCommunication()
+        sm.stopShardConsensus()
+        
         sm.mu.Lock()
         defer sm.mu.Unlock()
         
@@ -446,7 +475,7 @@ func (sm *ShardManager) SubmitTransaction(tx *types.Transaction) error {
         defer sm.mu.RUnlock()
         
         // Determine target shard
-        targetShardID := utils.GenerateShardKey(tx.From, sm.totalShards)
+        targetShardID, toShardID := sm.crossShardRouter.RouteTransaction(tx)
         tx.ShardID = targetShardID
         
         sm.logger.LogTransaction(tx.ID, "submit_to_shard", logrus.Fields{
@@ -455,6 +484,7 @@ func (sm *ShardManager) SubmitTransaction(tx *types.Transaction) error {
                 "from":         tx.From,
                 "to":           tx.To,
                 "amount":       tx.Amount,
+                "trace_id":     tx.TraceID,
                 "timestamp":    time.Now().UTC(),
         })
         
@@ -465,11 +495,11 @@ func (sm *ShardManager) SubmitTransaction(tx *types.Transaction) error {
         }
         
         // Check if this is a cross-shard transaction
-        toShardID := utils.GenerateShardKey(tx.To, sm.totalShards)
         if targetShardID != toShardID {
                 tx.Type = "cross_shard"
                 sm.logger.LogCrossShard(targetShardID, toShardID, tx.Type, logrus.Fields{
                         "tx_id":     tx.ID,
+                        "trace_id":  tx.TraceID,
                         "timestamp": time.Now().UTC(),
                 })
                 
@@ -481,6 +511,76 @@ func (sm *ShardManager) SubmitTransaction(tx *types.Transaction) error {
         return targetShard.AddTransaction(tx)
 }
 
+// GetShardForAddress returns the shard address is assigned to under the
+// configured assignment strategy, honouring any affinity group it belongs to
+func (sm *ShardManager) GetShardForAddress(address string) int {
+        return sm.crossShardRouter.ShardFor(address)
+}
+
+// SetAffinity places address in an affinity group so it is routed to the
+// same shard as every other member. An empty group removes the address from
+// its group.
+func (sm *ShardManager) SetAffinity(address, group string) {
+        sm.crossShardRouter.SetAffinity(address, group)
+
+        sm.logger.LogSharding(-1, "affinity_set", logrus.Fields{
+                "address":   address,
+                "group":     group,
+                "shard_id":  sm.crossShardRouter.ShardFor(address),
+                "timestamp": time.Now().UTC(),
+        })
+}
+
+// SetAffinity registers address as a member of an affinity group, or removes
+// it from its group when group is empty
+func (router *CrossShardRouter) SetAffinity(address, group string) {
+        router.mu.Lock()
+        defer router.mu.Unlock()
+
+        if group == "" {
+                delete(router.affinity, address)
+                return
+        }
+        router.affinity[address] = group
+}
+
+// GetAffinity returns the affinity group registered for address, if any
+func (router *CrossShardRouter) GetAffinity(address string) string {
+        router.mu.RLock()
+        defer router.mu.RUnlock()
+        return router.affinity[address]
+}
+
+// ShardFor returns the shard an address is routed to. Addresses in an
+// affinity group hash by group name so the whole group lands together;
+// others hash by address.
+func (router *CrossShardRouter) ShardFor(address string) int {
+        router.mu.RLock()
+        defer router.mu.RUnlock()
+        return router.shardForLocked(address, "")
+}
+
+// RouteTransaction returns the source and destination shards of tx. A
+// registered affinity wins; otherwise the transaction's AffinityGroup hint
+// applies to both accounts, so a transfer within one group stays on a
+// single shard.
+func (router *CrossShardRouter) RouteTransaction(tx *types.Transaction) (int, int) {
+        router.mu.RLock()
+        defer router.mu.RUnlock()
+        return router.shardForLocked(tx.From, tx.AffinityGroup), router.shardForLocked(tx.To, tx.AffinityGroup)
+}
+
+func (router *CrossShardRouter) shardForLocked(address, hint string) int {
+        group := router.affinity[address]
+        if group == "" {
+                group = hint
+        }
+        if group != "" {
+                return router.assigner.shardFor("affinity:" + group)
+        }
+        return router.assigner.shardFor(address)
+}
+
 // handleCrossShardTransaction handles cross-shard transactions
 func (sm *ShardManager) handleCrossShardTransaction(tx *types.Transaction, fromShard, toShard int) error {
         // Create cross-shard message
@@ -489,10 +589,10 @@ func (sm *ShardManager) handleCrossShardTransaction(tx *types.Transaction, fromS
                 FromShard: fromShard,
                 ToShard:   toShard,
                 Type:      "transaction",
-                Data:      tx,
                 Timestamp: time.Now(),
                 Processed: false,
         }
+        message.SetTransaction(tx)
         
         // Route the message
         return sm.routeCrossShardMessage(message)
@@ -510,6 +610,7 @@ func (sm *ShardManager) routeCrossShardMessage(message *types.CrossShardMessage)
                 router.deliveryStatus[message.ID] = "queued"
                 sm.logger.LogCrossShard(message.FromShard, message.ToShard, message.Type, logrus.Fields{
                         "message_id": message.ID,
+                        "trace_id":   message.TraceID,
                         "status":     "queued",
                         "timestamp":  time.Now().UTC(),
                 })
@@ -520,6 +621,7 @@ func (sm *ShardManager) routeCrossShardMessage(message *types.CrossShardMessage)
                 router.deliveryStatus[message.ID] = "retry_queued"
                 sm.logger.LogCrossShard(message.FromShard, message.ToShard, message.Type, logrus.Fields{
                         "message_id": message.ID,
+                        "trace_id":   message.TraceID,
                         "status":     "retry_queued",
                         "timestamp":  time.Now().UTC(),
                 })
@@ -589,12 +691,70 @@ func (sm *ShardManager) GetGlobalMetrics() *GlobalShardMetrics {
 
 // StartCrossCommunication starts cross-shard communication
 func (sm *ShardManager) StartCrossCommunication() {
+        sm.commMu.Lock()
+        defer sm.commMu.Unlock()
+        
+        if sm.communicator != nil {
+                return
+        }
+        
         sm.logger.LogSharding(-1, "start_cross_communication", logrus.Fields{
                 "timestamp": time.Now().UTC(),
         })
         
-        // Cross-communication is handled by background workers
-        // This method is for API compatibility
+        communicator := NewCrossShardCommunicatorWithClock(sm, sm.logger, sm.clock)
+        communicator.collector = sm.collector
+        if err := communicator.Start(); err != nil {
+                sm.logger.LogError("sharding", "start_cross_communication", err, logrus.Fields{
+                        "timestamp": time.Now().UTC(),
+                })
+                return
+        }
+        sm.communicator = communicator
+}
+
+// stopCrossCommunication stops the communicator started by
+// StartCrossCommunication, if any
+func (sm *ShardManager) stopCrossCommunication() {
+        sm.commMu.Lock()
+        communicator := sm.communicator
+        sm.communicator = nil
+        sm.commMu.Unlock()
+        
+        if communicator == nil {
+                return
+        }
+        if err := communicator.Stop(); err != nil {
+                sm.logger.LogError("sharding", "stop_cross_communication", err, logrus.Fields{
+                        "timestamp": time.Now().UTC(),
+                })
+        }
+}
+
+// SetMetricsCollector reports cross-shard commits to collector. It takes
+// effect the next time cross-shard communication starts, so call it before
+// Start.
+func (sm *ShardManager) SetMetricsCollector(collector *metrics.MetricsCollector) {
+        sm.commMu.Lock()
+        defer sm.commMu.Unlock()
+        sm.collector = collector
+}
+
+// SetClock runs cross-shard communication on clock instead of the wall
+// clock. Like SetMetricsCollector, it takes effect the next time cross-shard
+// communication starts.
+func (sm *ShardManager) SetClock(clock utils.Clock) {
+        sm.commMu.Lock()
+        defer sm.commMu.Unlock()
+        sm.clock = clock
+}
+
+// Communicator returns the running cross-shard communicator, or nil before
+// StartCrossCommunication
+func (sm *ShardManager) Communicator() *CrossShardCommunicator {
+        sm.commMu.Lock()
+        defer sm.commMu.Unlock()
+        return sm.communicator
 }
 
 // Background workers
@@ -624,6 +784,7 @@ func (sm *ShardManager) processCrossShardMessage(message *types.CrossShardMessag
         
         sm.logger.LogCrossShard(message.FromShard, message.ToShard, message.Type, logrus.Fields{
                 "message_id": message.ID,
+                "trace_id":   message.TraceID,
                 "status":     "processing",
                 "timestamp":  time.Now().UTC(),
         })
@@ -634,6 +795,7 @@ func (sm *ShardManager) processCrossShardMessage(message *types.CrossShardMessag
                 sm.logger.LogError("sharding", "process_cross_shard_message", 
                         fmt.Errorf("target shard %d not found", message.ToShard), logrus.Fields{
                         "message_id": message.ID,
+                        "trace_id":   message.TraceID,
                         "timestamp":  time.Now().UTC(),
                 })
                 
@@ -647,10 +809,14 @@ func (sm *ShardManager) processCrossShardMessage(message *types.CrossShardMessag
         var err error
         switch message.Type {
         case "transaction":
-                if tx, ok := message.Data.(*types.Transaction); ok {
-                        err = targetShard.AddTransaction(tx)
+                if tx, txErr := message.Transaction(); txErr == nil {
+                        // Credit the recipient once the sender's debit has been committed
+                        err = sm.blockchain.CommitCrossShardTransfer(tx.ID)
+                        if err == nil && !sm.queueCrossShardCommit(tx, message.FromShard, message.ToShard) {
+                                err = targetShard.AddTransaction(tx)
+                        }
                 } else {
-                        err = fmt.Errorf("invalid transaction data in cross-shard message")
+                        err = txErr
                 }
         default:
                 // Add message to target shard
@@ -671,6 +837,7 @@ func (sm *ShardManager) processCrossShardMessage(message *types.CrossShardMessag
         
         sm.logger.LogCrossShard(message.FromShard, message.ToShard, message.Type, logrus.Fields{
                 "message_id": message.ID,
+                "trace_id":   message.TraceID,
                 "status":     sm.crossShardRouter.deliveryStatus[message.ID],
                 "error":      err,
                 "timestamp":  time.Now().UTC(),
@@ -1124,6 +1291,7 @@ func (sm *ShardManager) GetManagerStatus() map[string]interface{} {
                 "uptime":            time.Since(sm.startTime).Seconds(),
                 "global_consensus":   sm.consensusCoordinator.globalConsensus,
                 "coordination_mode":  sm.consensusCoordinator.coordinationMode,
+                "active_coordination": sm.ActiveCoordinationMode(),
                 "rebalance_enabled":  sm.rebalancer.enabled,
                 "last_rebalance":     sm.rebalancer.lastRebalance,
                 "message_queue_size": len(sm.crossShardRouter.messageQueue),
@@ -1143,6 +1311,10 @@ func (sm *ShardManager) GetManagerStatus() map[string]interface{} {
                 }
         }
         status["shards"] = shardStatuses
+        if sm.config.Sharding.ShardConsensus {
+                status["shard_consensus"] = sm.GetShardConsensusStatus()
+                status["cross_shard_commits"] = sm.GetCrossShardCommitStatus()
+        }
         
         return status
 }
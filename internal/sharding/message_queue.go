@@ -0,0 +1,219 @@
+package sharding
+
+import (
+        "container/heap"
+        "errors"
+        "lscc-blockchain/internal/utils"
+        "lscc-blockchain/pkg/types"
+        "sync"
+        "time"
+)
+
+// Cross-shard message priorities. Higher values are handled first; a
+// message without a priority is treated as normal.
+const (
+        MessagePriorityLow    = 1
+        MessagePriorityNormal = 2
+        MessagePriorityHigh   = 3
+)
+
+var (
+        errMessageQueueFull   = errors.New("message queue is full")
+        errMessageQueueClosed = errors.New("message queue is closed")
+)
+
+// messagePriority returns the effective priority of a message. Priorities
+// outside the defined levels are clamped to the nearest one, so a sender
+// cannot rank its message ahead of every other by inflating the value.
+func messagePriority(message *types.CrossShardMessage) int {
+        switch {
+        case message.Priority == 0:
+                return MessagePriorityNormal
+        case message.Priority < MessagePriorityLow:
+                return MessagePriorityLow
+        case message.Priority > MessagePriorityHigh:
+                return MessagePriorityHigh
+        }
+        return message.Priority
+}
+
+// queuedMessage is a message waiting in a messageQueue
+type queuedMessage struct {
+        message  *types.CrossShardMessage
+        priority int
+        rank     int64  // enqueue time in nanoseconds, moved earlier by priority
+        seq      uint64 // breaks ties in arrival order
+}
+
+// messageHeap orders queued messages by rank, then arrival
+type messageHeap []*queuedMessage
+
+func (h messageHeap) Len() int { return len(h) }
+
+func (h messageHeap) Less(i, j int) bool {
+        if h[i].rank != h[j].rank {
+                return h[i].rank < h[j].rank
+        }
+        return h[i].seq < h[j].seq
+}
+
+func (h messageHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *messageHeap) Push(x interface{}) { *h = append(*h, x.(*queuedMessage)) }
+
+func (h *messageHeap) Pop() interface{} {
+        old := *h
+        n := len(old)
+        item := old[n-1]
+        old[n-1] = nil
+        *h = old[:n-1]
+        return item
+}
+
+// messageQueue is a bounded priority queue of messages for one shard.
+//
+// Each level of priority counts as aging of waiting time: a message is
+// ranked as if it had been queued aging*priority earlier. Because every
+// waiting message ages at the same rate the order never changes while
+// queued, and a low-priority message is overtaken only by messages that
+// arrive within (difference in priority)*aging of it, so it cannot starve.
+type messageQueue struct {
+        mu       sync.Mutex
+        items    messageHeap
+        capacity int
+        aging    time.Duration
+        clock    utils.Clock
+        seq      uint64
+        closed   bool
+        wake     chan struct{} // shared with the worker serving this queue
+        space    chan struct{} // signalled when a message is removed
+}
+
+func newMessageQueue(capacity int, aging time.Duration, wake chan struct{}, clock utils.Clock) *messageQueue {
+        return &messageQueue{
+                items:    make(messageHeap, 0, capacity),
+                capacity: capacity,
+                aging:    aging,
+                clock:    clock,
+                wake:     wake,
+                space:    make(chan struct{}, 1),
+        }
+}
+
+// push queues a message, waiting up to timeout of clock time for room when
+// the queue is full
+func (q *messageQueue) push(message *types.CrossShardMessage, timeout time.Duration) error {
+        var deadline <-chan time.Time
+        for {
+                q.mu.Lock()
+                if q.closed {
+                        q.mu.Unlock()
+                        return errMessageQueueClosed
+                }
+                if len(q.items) < q.capacity {
+                        priority := messagePriority(message)
+                        q.seq++
+                        heap.Push(&q.items, &queuedMessage{
+                                message:  message,
+                                priority: priority,
+                                rank:     q.clock.Now().UnixNano() - int64(priority)*int64(q.aging),
+                                seq:      q.seq,
+                        })
+                        q.mu.Unlock()
+                        signal(q.wake)
+                        return nil
+                }
+                q.mu.Unlock()
+
+                if deadline == nil {
+                        deadline = q.clock.After(timeout)
+                }
+                select {
+                case <-q.space:
+                case <-deadline:
+                        return errMessageQueueFull
+                }
+        }
+}
+
+// peek returns the next message without removing it
+func (q *messageQueue) peek() (*queuedMessage, bool) {
+        q.mu.Lock()
+        defer q.mu.Unlock()
+        if len(q.items) == 0 {
+                return nil, false
+        }
+        return q.items[0], true
+}
+
+// pop removes and returns the next message
+func (q *messageQueue) pop() (*types.CrossShardMessage, bool) {
+        q.mu.Lock()
+        if len(q.items) == 0 {
+                q.mu.Unlock()
+                return nil, false
+        }
+        item := heap.Pop(&q.items).(*queuedMessage)
+        q.mu.Unlock()
+        signal(q.space)
+        return item.message, true
+}
+
+// close rejects further messages. Messages already queued can still be popped.
+func (q *messageQueue) close() {
+        q.mu.Lock()
+        q.closed = true
+        q.mu.Unlock()
+        signal(q.wake)
+}
+
+// drained reports whether the queue is closed and empty
+func (q *messageQueue) drained() bool {
+        q.mu.Lock()
+        defer q.mu.Unlock()
+        return q.closed && len(q.items) == 0
+}
+
+// size returns the number of queued messages
+func (q *messageQueue) size() int {
+        q.mu.Lock()
+        defer q.mu.Unlock()
+        return len(q.items)
+}
+
+// depthByPriority adds the number of queued messages at each priority to depth
+func (q *messageQueue) depthByPriority(depth map[int]int) {
+        q.mu.Lock()
+        defer q.mu.Unlock()
+        for _, item := range q.items {
+                depth[item.priority]++
+        }
+}
+
+// nextMessage pops the highest-ranked message across queues. ok is false when
+// every queue is empty.
+func nextMessage(queues []*messageQueue) (message *types.CrossShardMessage, ok bool) {
+        var best *messageQueue
+        var bestItem *queuedMessage
+        for _, q := range queues {
+                item, exists := q.peek()
+                if !exists {
+                        continue
+                }
+                if bestItem == nil || item.rank < bestItem.rank {
+                        best, bestItem = q, item
+                }
+        }
+        if best == nil {
+                return nil, false
+        }
+        return best.pop()
+}
+
+// signal performs a non-blocking send on a notification channel
+func signal(ch chan struct{}) {
+        select {
+        case ch <- struct{}{}:
+        default:
+        }
+}
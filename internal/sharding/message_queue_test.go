@@ -0,0 +1,203 @@
+package sharding
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+)
+
+func queueMessage(id string, priority int) *types.CrossShardMessage {
+	return &types.CrossShardMessage{ID: id, Priority: priority}
+}
+
+// popIDs pops every queued message and returns their IDs in order
+func popIDs(q *messageQueue) []string {
+	var ids []string
+	for {
+		message, ok := q.pop()
+		if !ok {
+			return ids
+		}
+		ids = append(ids, message.ID)
+	}
+}
+
+func TestMessageQueueServesHigherPriorityFirst(t *testing.T) {
+	q := newMessageQueue(16, time.Second, make(chan struct{}, 1), utils.SystemClock)
+	for _, message := range []*types.CrossShardMessage{
+		queueMessage("low", MessagePriorityLow),
+		queueMessage("normal", MessagePriorityNormal),
+		queueMessage("high", MessagePriorityHigh),
+		queueMessage("unset", 0),
+		queueMessage("high2", MessagePriorityHigh),
+	} {
+		if err := q.push(message, time.Second); err != nil {
+			t.Fatalf("failed to push %s: %v", message.ID, err)
+		}
+	}
+
+	depth := make(map[int]int)
+	q.depthByPriority(depth)
+	if depth[MessagePriorityHigh] != 2 || depth[MessagePriorityNormal] != 2 || depth[MessagePriorityLow] != 1 {
+		t.Fatalf("depth by priority = %v", depth)
+	}
+
+	// Equal priorities keep arrival order; an unset priority is normal
+	want := []string{"high", "high2", "normal", "unset", "low"}
+	got := popIDs(q)
+	if len(got) != len(want) {
+		t.Fatalf("popped %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("popped %v, want %v", got, want)
+		}
+	}
+}
+
+func TestMessageQueueAgesLowPriority(t *testing.T) {
+	aging := 10 * time.Millisecond
+	clock := utils.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q := newMessageQueue(16, aging, make(chan struct{}, 1), clock)
+	if err := q.push(queueMessage("old-low", MessagePriorityLow), time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// High priority is worth 2*aging of waiting over low; the low message
+	// has waited far longer
+	clock.Advance(10 * aging)
+	for _, id := range []string{"high1", "high2"} {
+		if err := q.push(queueMessage(id, MessagePriorityHigh), time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := popIDs(q); got[0] != "old-low" {
+		t.Fatalf("popped %v, want the aged low-priority message first", got)
+	}
+
+	// A fresh low-priority message is still overtaken
+	if err := q.push(queueMessage("new-low", MessagePriorityLow), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.push(queueMessage("high3", MessagePriorityHigh), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := popIDs(q); got[0] != "high3" {
+		t.Fatalf("popped %v, want the high-priority message to jump ahead", got)
+	}
+}
+
+func TestMessageQueueBounded(t *testing.T) {
+	q := newMessageQueue(2, time.Second, make(chan struct{}, 1), utils.SystemClock)
+	for _, id := range []string{"a", "b"} {
+		if err := q.push(queueMessage(id, 0), time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.push(queueMessage("c", 0), 10*time.Millisecond); !errors.Is(err, errMessageQueueFull) {
+		t.Fatalf("push to a full queue: got %v", err)
+	}
+
+	// A waiting push goes through once a message is removed
+	done := make(chan error, 1)
+	go func() { done <- q.push(queueMessage("c", 0), time.Second) }()
+	time.Sleep(10 * time.Millisecond)
+	q.pop()
+	if err := <-done; err != nil {
+		t.Fatalf("waiting push failed: %v", err)
+	}
+
+	q.close()
+	if err := q.push(queueMessage("d", 0), time.Second); !errors.Is(err, errMessageQueueClosed) {
+		t.Fatalf("push to a closed queue: got %v", err)
+	}
+	if q.drained() {
+		t.Fatal("closed queue with messages reported drained")
+	}
+	popIDs(q)
+	if !q.drained() {
+		t.Fatal("closed empty queue not drained")
+	}
+}
+
+func TestMessageQueueClampsPriority(t *testing.T) {
+	clock := utils.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q := newMessageQueue(16, time.Second, make(chan struct{}, 1), clock)
+	if err := q.push(queueMessage("high", MessagePriorityHigh), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Millisecond)
+	for _, message := range []*types.CrossShardMessage{
+		queueMessage("inflated", math.MaxInt64),
+		queueMessage("negative", math.MinInt64),
+		queueMessage("low", MessagePriorityLow),
+	} {
+		if err := q.push(message, time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	depth := make(map[int]int)
+	q.depthByPriority(depth)
+	if depth[MessagePriorityHigh] != 2 || depth[MessagePriorityLow] != 2 || len(depth) != 2 {
+		t.Fatalf("depth by priority = %v, want out-of-range priorities clamped", depth)
+	}
+	want := []string{"high", "inflated", "negative", "low"}
+	got := popIDs(q)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("popped %v, want %v", got, want)
+		}
+	}
+}
+
+func TestMessageQueuePushTimesOutOnClock(t *testing.T) {
+	clock := utils.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q := newMessageQueue(1, time.Second, make(chan struct{}, 1), clock)
+	if err := q.push(queueMessage("a", 0), time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- q.push(queueMessage("b", 0), time.Minute) }()
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(59 * time.Second)
+	select {
+	case err := <-done:
+		t.Fatalf("push returned %v before its timeout passed on the clock", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	if err := <-done; !errors.Is(err, errMessageQueueFull) {
+		t.Fatalf("push to a full queue: got %v", err)
+	}
+}
+
+func TestNextMessageAcrossQueues(t *testing.T) {
+	wake := make(chan struct{}, 1)
+	first := newMessageQueue(4, time.Second, wake, utils.SystemClock)
+	second := newMessageQueue(4, time.Second, wake, utils.SystemClock)
+	if err := first.push(queueMessage("normal", MessagePriorityNormal), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.push(queueMessage("high", MessagePriorityHigh), time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	queues := []*messageQueue{first, second}
+	for _, want := range []string{"high", "normal"} {
+		message, ok := nextMessage(queues)
+		if !ok || message.ID != want {
+			t.Fatalf("next message = %v, want %s", message, want)
+		}
+	}
+	if _, ok := nextMessage(queues); ok {
+		t.Fatal("message from empty queues")
+	}
+}
@@ -0,0 +1,178 @@
+package sharding
+
+import (
+        "encoding/json"
+        "fmt"
+        "lscc-blockchain/internal/storage"
+        "lscc-blockchain/pkg/types"
+        "sync"
+)
+
+const messageWALMetaKey = "crossshard:wal:meta"
+
+func messageWALKey(seq uint64) string {
+        return fmt.Sprintf("crossshard:wal:msg:%d", seq)
+}
+
+// messageWALMeta records the range of sequence numbers that may still hold
+// messages
+type messageWALMeta struct {
+        Oldest uint64 `json:"oldest"`
+        Next   uint64 `json:"next"`
+}
+
+// messageWALEntry is one logged message in its wire encoding, so its
+// payload comes back as the type the message handlers expect
+type messageWALEntry struct {
+        Seq     uint64          `json:"seq"`
+        Message json.RawMessage `json:"message"`
+}
+
+// messageWAL is a write-ahead log of cross-shard messages that have been
+// sent but not yet handled by their destination shard. Messages are logged
+// under increasing sequence numbers and deleted once handled; whatever is
+// left after a crash is replayed when the communicator starts again, giving
+// at-least-once delivery across restarts.
+type messageWAL struct {
+        mu      sync.Mutex
+        store   storage.Database
+        meta    messageWALMeta
+        pending map[string]uint64 // message ID -> seq
+        live    map[uint64]bool   // seqs still in the log
+}
+
+// openMessageWAL opens the log in store and returns it with the messages
+// still in it, oldest first
+func openMessageWAL(store storage.Database) (*messageWAL, []*types.CrossShardMessage, error) {
+        wal := &messageWAL{
+                store:   store,
+                pending: make(map[string]uint64),
+                live:    make(map[uint64]bool),
+        }
+
+        if err := store.GetState(messageWALMetaKey, &wal.meta); err != nil {
+                // Nothing logged yet
+                wal.meta = messageWALMeta{}
+                return wal, nil, nil
+        }
+
+        messages := make([]*types.CrossShardMessage, 0)
+        for seq := wal.meta.Oldest; seq < wal.meta.Next; seq++ {
+                var entry messageWALEntry
+                if err := store.GetState(messageWALKey(seq), &entry); err != nil || len(entry.Message) == 0 {
+                        continue
+                }
+                message, err := types.DecodeCrossShardMessage(entry.Message)
+                if err != nil {
+                        return nil, nil, fmt.Errorf("failed to decode logged message %d: %w", seq, err)
+                }
+                wal.pending[message.ID] = seq
+                wal.live[seq] = true
+                messages = append(messages, message)
+        }
+
+        if err := wal.advance(); err != nil {
+                return nil, nil, err
+        }
+        return wal, messages, nil
+}
+
+// append logs message unless a message with its ID is already pending. It
+// reports whether the message was added.
+func (wal *messageWAL) append(message *types.CrossShardMessage) (bool, error) {
+        if wal == nil {
+                return false, nil
+        }
+
+        wal.mu.Lock()
+        defer wal.mu.Unlock()
+
+        if _, exists := wal.pending[message.ID]; exists {
+                return false, nil
+        }
+
+        encoded, err := types.EncodeCrossShardMessage(message)
+        if err != nil {
+                return false, err
+        }
+        entry := messageWALEntry{Seq: wal.meta.Next, Message: encoded}
+
+        // The range is widened before the entry is written, so a crash in
+        // between leaves a gap rather than an entry replay cannot see
+        meta := wal.meta
+        meta.Next++
+        if err := wal.store.SaveState(messageWALMetaKey, meta); err != nil {
+                return false, fmt.Errorf("failed to log message %s: %w", message.ID, err)
+        }
+        wal.meta = meta
+        if err := wal.store.SaveState(messageWALKey(entry.Seq), entry); err != nil {
+                return false, fmt.Errorf("failed to log message %s: %w", message.ID, err)
+        }
+
+        wal.pending[message.ID] = entry.Seq
+        wal.live[entry.Seq] = true
+        return true, nil
+}
+
+// remove deletes the message with messageID from the log, if it is there
+func (wal *messageWAL) remove(messageID string) error {
+        if wal == nil {
+                return nil
+        }
+
+        wal.mu.Lock()
+        defer wal.mu.Unlock()
+
+        seq, exists := wal.pending[messageID]
+        if !exists {
+                return nil
+        }
+        if err := wal.store.DeleteState(messageWALKey(seq)); err != nil {
+                return fmt.Errorf("failed to remove logged message %s: %w", messageID, err)
+        }
+        delete(wal.pending, messageID)
+        delete(wal.live, seq)
+
+        return wal.advance()
+}
+
+// size returns the number of messages in the log
+func (wal *messageWAL) size() int {
+        if wal == nil {
+                return 0
+        }
+        wal.mu.Lock()
+        defer wal.mu.Unlock()
+        return len(wal.pending)
+}
+
+// holds reports whether the message with messageID is in the log
+func (wal *messageWAL) holds(messageID string) bool {
+        if wal == nil {
+                return false
+        }
+        wal.mu.Lock()
+        defer wal.mu.Unlock()
+        _, exists := wal.pending[messageID]
+        return exists
+}
+
+// advance moves the start of the range past removed entries, so a restart
+// does not look them up again. Caller must hold wal.mu.
+func (wal *messageWAL) advance() error {
+        oldest := wal.meta.Oldest
+        for oldest < wal.meta.Next && !wal.live[oldest] {
+                oldest++
+        }
+        if oldest == wal.meta.Oldest {
+                return nil
+        }
+
+        meta := wal.meta
+        meta.Oldest = oldest
+        if err := wal.store.SaveState(messageWALMetaKey, meta); err != nil {
+                return fmt.Errorf("failed to update message log: %w", err)
+        }
+        wal.meta = meta
+        return nil
+}
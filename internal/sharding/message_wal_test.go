@@ -0,0 +1,121 @@
+package sharding
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/storage"
+	"lscc-blockchain/pkg/types"
+)
+
+// loggedMessage returns a sync message from shard 0 to shard 1
+func loggedMessage(id string) *types.CrossShardMessage {
+	return &types.CrossShardMessage{
+		ID:        id,
+		FromShard: 0,
+		ToShard:   1,
+		Type:      "sync",
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+func TestMessageWALKeepsUnhandledMessages(t *testing.T) {
+	db := storage.NewMemoryDB()
+	wal, replay, err := openMessageWAL(db)
+	if err != nil || len(replay) != 0 {
+		t.Fatalf("expected an empty log, got %d messages, %v", len(replay), err)
+	}
+
+	transfer := loggedMessage("transfer")
+	transfer.Type = "transaction"
+	transfer.SetTransaction(&types.Transaction{ID: "tx-1", Amount: 42})
+	for _, message := range []*types.CrossShardMessage{loggedMessage("first"), loggedMessage("handled"), transfer} {
+		if added, err := wal.append(message); err != nil || !added {
+			t.Fatalf("failed to log %s: %v", message.ID, err)
+		}
+	}
+	if added, _ := wal.append(loggedMessage("first")); added {
+		t.Fatal("a message already in the log was logged twice")
+	}
+	if err := wal.remove("handled"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart reads back what was left, oldest first, payloads intact
+	reopened, replay, err := openMessageWAL(db)
+	if err != nil {
+		t.Fatalf("failed to reopen the log: %v", err)
+	}
+	if len(replay) != 2 || replay[0].ID != "first" || replay[1].ID != "transfer" {
+		t.Fatalf("expected first and transfer replayed, got %v", messageIDs(replay))
+	}
+	if tx, err := replay[1].Transaction(); err != nil || tx.ID != "tx-1" || tx.Amount != 42 {
+		t.Fatalf("transaction payload not restored: %+v, %v", tx, err)
+	}
+	if reopened.size() != 2 || !reopened.holds("first") || reopened.holds("handled") {
+		t.Fatalf("reopened log holds the wrong messages")
+	}
+
+	for _, message := range replay {
+		if err := reopened.remove(message.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, replay, _ := openMessageWAL(db); len(replay) != 0 {
+		t.Fatalf("expected nothing left to replay, got %v", messageIDs(replay))
+	}
+}
+
+func TestDurableCommunicatorReplaysAfterRestart(t *testing.T) {
+	sm := newTestShardManager(t, func(cfg *config.Config) {
+		cfg.CrossShard.Durable = true
+	})
+
+	// Messages a previous run sent but never handled
+	wal, _, err := openMessageWAL(sm.GetDB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for i := 0; i < 3; i++ {
+		message := loggedMessage(fmt.Sprintf("inflight-%d", i))
+		if _, err := wal.append(message); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, message.ID)
+	}
+
+	csc := NewCrossShardCommunicator(sm, sm.logger)
+	if err := csc.Start(); err != nil {
+		t.Fatalf("failed to start communicator: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for csc.wal.size() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := csc.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range ids {
+		if !csc.delivered[1].contains(id) {
+			t.Fatalf("logged message %s was not replayed to its shard", id)
+		}
+		if _, exists := csc.syncManager.syncRequests["sync_"+id]; !exists {
+			t.Fatalf("replayed message %s was not handled", id)
+		}
+	}
+	if _, replay, _ := openMessageWAL(sm.GetDB()); len(replay) != 0 {
+		t.Fatalf("handled messages left in the log: %v", messageIDs(replay))
+	}
+}
+
+func messageIDs(messages []*types.CrossShardMessage) []string {
+	ids := make([]string, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	return ids
+}
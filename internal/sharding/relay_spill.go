@@ -0,0 +1,135 @@
+package sharding
+
+import (
+        "encoding/json"
+        "fmt"
+        "lscc-blockchain/internal/storage"
+        "lscc-blockchain/pkg/types"
+        "sync"
+)
+
+// relaySpillMeta records the range of sequence numbers a relay's spilled
+// messages occupy
+type relaySpillMeta struct {
+        Oldest uint64 `json:"oldest"`
+        Next   uint64 `json:"next"`
+}
+
+// relaySpill holds, in storage, the messages a relay had no buffer room for
+// under the spill_to_disk overflow policy. Messages come back out oldest
+// first as the relay's buffer drains, and survive a restart.
+type relaySpill struct {
+        mu     sync.Mutex
+        store  storage.Database
+        prefix string
+        meta   relaySpillMeta
+}
+
+// openRelaySpill opens the spilled messages of the relay for shardID
+func openRelaySpill(store storage.Database, shardID int) *relaySpill {
+        spill := &relaySpill{
+                store:  store,
+                prefix: fmt.Sprintf("crossshard:relay:%d:spill", shardID),
+        }
+        if err := store.GetState(spill.metaKey(), &spill.meta); err != nil {
+                // Nothing spilled yet
+                spill.meta = relaySpillMeta{}
+        }
+        return spill
+}
+
+func (s *relaySpill) metaKey() string {
+        return s.prefix + ":meta"
+}
+
+func (s *relaySpill) entryKey(seq uint64) string {
+        return fmt.Sprintf("%s:msg:%d", s.prefix, seq)
+}
+
+// push writes message behind those already spilled
+func (s *relaySpill) push(message *types.CrossShardMessage) error {
+        encoded, err := types.EncodeCrossShardMessage(message)
+        if err != nil {
+                return err
+        }
+
+        s.mu.Lock()
+        defer s.mu.Unlock()
+
+        // As in the message log, the range is widened before the entry is
+        // written, so a crash in between leaves a gap pop skips
+        meta := s.meta
+        meta.Next++
+        if err := s.store.SaveState(s.metaKey(), meta); err != nil {
+                return fmt.Errorf("failed to spill message %s: %w", message.ID, err)
+        }
+        seq := s.meta.Next
+        s.meta = meta
+        if err := s.store.SaveState(s.entryKey(seq), json.RawMessage(encoded)); err != nil {
+                return fmt.Errorf("failed to spill message %s: %w", message.ID, err)
+        }
+        return nil
+}
+
+// pop removes and returns up to max of the oldest spilled messages
+func (s *relaySpill) pop(max int) ([]*types.CrossShardMessage, error) {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+
+        messages := make([]*types.CrossShardMessage, 0)
+        meta := s.meta
+        for meta.Oldest < meta.Next && len(messages) < max {
+                seq := meta.Oldest
+                meta.Oldest++
+
+                var encoded json.RawMessage
+                if err := s.store.GetState(s.entryKey(seq), &encoded); err != nil || len(encoded) == 0 {
+                        continue
+                }
+                message, err := types.DecodeCrossShardMessage(encoded)
+                if err != nil {
+                        return messages, fmt.Errorf("failed to decode spilled message %d: %w", seq, err)
+                }
+                if err := s.store.DeleteState(s.entryKey(seq)); err != nil {
+                        return messages, fmt.Errorf("failed to remove spilled message %s: %w", message.ID, err)
+                }
+                messages = append(messages, message)
+        }
+
+        if meta != s.meta {
+                if err := s.store.SaveState(s.metaKey(), meta); err != nil {
+                        return messages, fmt.Errorf("failed to update relay spill: %w", err)
+                }
+                s.meta = meta
+        }
+        return messages, nil
+}
+
+// discard removes every spilled message
+func (s *relaySpill) discard() error {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+
+        for seq := s.meta.Oldest; seq < s.meta.Next; seq++ {
+                if err := s.store.DeleteState(s.entryKey(seq)); err != nil {
+                        return fmt.Errorf("failed to discard spilled message %d: %w", seq, err)
+                }
+        }
+        meta := relaySpillMeta{Oldest: s.meta.Next, Next: s.meta.Next}
+        if err := s.store.SaveState(s.metaKey(), meta); err != nil {
+                return fmt.Errorf("failed to update relay spill: %w", err)
+        }
+        s.meta = meta
+        return nil
+}
+
+// size returns the number of messages spilled, counting any gaps left by a
+// crash until pop passes them
+func (s *relaySpill) size() int {
+        if s == nil {
+                return 0
+        }
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        return int(s.meta.Next - s.meta.Oldest)
+}
@@ -0,0 +1,349 @@
+package sharding
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/pkg/types"
+)
+
+// TestRelayNodesUnderLoad relays messages while relay state is read and
+// metrics are collected. Run under -race.
+func TestRelayNodesUnderLoad(t *testing.T) {
+	sm := newTestShardManager(t, nil)
+	csc := NewCrossShardCommunicator(sm, sm.logger)
+	if err := csc.Start(); err != nil {
+		t.Fatalf("failed to start communicator: %v", err)
+	}
+	defer csc.Stop()
+
+	relayIDs := make([]int, 0, len(csc.relayNodes))
+	for id := range csc.relayNodes {
+		relayIDs = append(relayIDs, id)
+	}
+	if len(relayIDs) == 0 {
+		t.Fatal("no relay nodes")
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var sent int64
+	for sender := 0; sender < 4; sender++ {
+		wg.Add(1)
+		go func(sender int) {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				relay := relayIDs[n%len(relayIDs)]
+				message := &types.CrossShardMessage{
+					ID:        fmt.Sprintf("relayed-%d-%d", sender, n),
+					FromShard: sender % sm.totalShards,
+					ToShard:   (sender + 1) % sm.totalShards,
+					Type:      "sync",
+				}
+				route := csc.newRoute(message.FromShard, message.ToShard, []int{relay})
+				if csc.sendViaRelay(message, route) == nil {
+					atomic.AddInt64(&sent, 1)
+				}
+			}
+		}(sender)
+	}
+
+	readers := []func(){
+		func() { csc.GetRelayNodes() },
+		func() { csc.GetMetrics() },
+		func() { csc.updateMetrics() },
+		func() {
+			for _, id := range relayIDs {
+				csc.processRelayBuffer(csc.relayNodes[id])
+			}
+		},
+	}
+	for _, read := range readers {
+		wg.Add(1)
+		go func(read func()) {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					read()
+				}
+			}
+		}(read)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	close(done)
+	wg.Wait()
+
+	if atomic.LoadInt64(&sent) == 0 {
+		t.Fatal("no message accepted by a relay")
+	}
+	var processed int64
+	for _, relay := range csc.GetRelayNodes() {
+		processed += relay.ProcessedMsgs
+	}
+	if processed == 0 {
+		t.Fatal("relays forwarded nothing")
+	}
+}
+
+func TestRelaySkipsExpiredTransactions(t *testing.T) {
+	sm := newTestShardManager(t, func(cfg *config.Config) {
+		cfg.Mempool.TxTTL = 60
+	})
+	// Not started, so the relay's own worker stays out of the way and
+	// nothing can be delivered: every message the relay tries fails
+	csc := NewCrossShardCommunicator(sm, sm.logger)
+	if err := csc.initializeRelayNode(0); err != nil {
+		t.Fatalf("failed to create relay: %v", err)
+	}
+	relay := csc.relayNodes[0]
+
+	carrying := func(id string, age time.Duration) *types.CrossShardMessage {
+		message := &types.CrossShardMessage{ID: id, FromShard: 1, ToShard: 0, Type: "transaction"}
+		message.SetTransaction(&types.Transaction{ID: "tx-" + id, Timestamp: time.Now().Add(-age)})
+		return message
+	}
+	stale := carrying("stale", 2*time.Minute)
+	fresh := carrying("fresh", time.Second)
+	relay.MessageBuffer = append(relay.MessageBuffer, stale, fresh)
+
+	csc.processRelayBuffer(relay)
+
+	if len(relay.MessageBuffer) != 1 || relay.MessageBuffer[0] != fresh {
+		t.Fatalf("expected only the fresh message kept for another try, have %d buffered", len(relay.MessageBuffer))
+	}
+	if failed := relay.FailedMsgs.Load(); failed != 1 {
+		t.Fatalf("expected one delivery attempt, for the fresh message, got %d", failed)
+	}
+}
+
+func TestRelayLoopDroppedAfterMaxHops(t *testing.T) {
+	sm := newTestShardManager(t, func(cfg *config.Config) {
+		cfg.CrossShard.MaxHops = 3
+	})
+	// Not started, so relays only move messages when the test says so
+	csc := NewCrossShardCommunicator(sm, sm.logger)
+	for _, id := range []int{1, 2} {
+		if err := csc.initializeRelayNode(id); err != nil {
+			t.Fatalf("failed to create relay %d: %v", id, err)
+		}
+	}
+
+	// A bad route sending relay 1 to relay 2 and relay 2 back to relay 1
+	route := csc.newRoute(0, 3, []int{1, 2, 1})
+	csc.routingTable.mu.Lock()
+	csc.routingTable.routes[RoutingKey{FromShard: 0, ToShard: 3}] = route
+	csc.routingTable.mu.Unlock()
+
+	message := &types.CrossShardMessage{ID: "looping", FromShard: 0, ToShard: 3, Type: "sync"}
+	if err := csc.sendViaRelay(message, route); err != nil {
+		t.Fatalf("failed to hand the message to the first relay: %v", err)
+	}
+
+	buffered := func() int {
+		count := 0
+		for _, relay := range csc.relayNodes {
+			relay.mu.Lock()
+			count += len(relay.MessageBuffer)
+			relay.mu.Unlock()
+		}
+		return count
+	}
+	cycles := 0
+	for ; buffered() > 0; cycles++ {
+		if cycles == 10 {
+			t.Fatalf("message still bouncing between relays after %d cycles, %d hops", cycles, message.HopCount)
+		}
+		for _, id := range []int{1, 2} {
+			csc.processRelayBuffer(csc.relayNodes[id])
+		}
+	}
+
+	if message.HopCount != 3 {
+		t.Fatalf("message dropped after %d hops, want 3", message.HopCount)
+	}
+	if failed := csc.GetMetrics().MessagesFailed; failed != 1 {
+		t.Fatalf("expected the dropped message counted as failed, got %d", failed)
+	}
+
+	// A message arriving with its hops used up is refused outright
+	spent := &types.CrossShardMessage{ID: "spent", FromShard: 0, ToShard: 3, Type: "sync", HopCount: 3}
+	if err := csc.sendViaRelay(spent, route); !errors.Is(err, ErrTooManyHops) {
+		t.Fatalf("expected ErrTooManyHops, got %v", err)
+	}
+	if buffered() != 0 {
+		t.Fatal("message past its hops buffered at a relay")
+	}
+}
+
+// newOverflowRelay returns an unstarted communicator whose relays hold two
+// messages and overflow under policy, with a route from shard 0 to shard 3
+// through relay 1. Nothing is delivered, so the relay's buffer only drains
+// when the test empties it.
+func newOverflowRelay(t *testing.T, policy string) (*CrossShardCommunicator, *RelayNode, *Route) {
+	t.Helper()
+	sm := newTestShardManager(t, func(cfg *config.Config) {
+		cfg.CrossShard.RelayBufferSize = 2
+		cfg.CrossShard.RelayOverflowPolicy = policy
+	})
+	csc := NewCrossShardCommunicator(sm, sm.logger)
+	if err := csc.initializeRelayNode(1); err != nil {
+		t.Fatalf("failed to create relay: %v", err)
+	}
+	relay := csc.relayNodes[1]
+	if relay.MaxBufferSize != 2 {
+		t.Fatalf("relay buffers %d messages, want the configured 2", relay.MaxBufferSize)
+	}
+	return csc, relay, csc.newRoute(0, 3, []int{1})
+}
+
+// fillRelay sends count messages through route, failing the test if the
+// relay refuses any, and returns them
+func fillRelay(t *testing.T, csc *CrossShardCommunicator, route *Route, count int) []*types.CrossShardMessage {
+	t.Helper()
+	messages := make([]*types.CrossShardMessage, count)
+	for i := range messages {
+		messages[i] = &types.CrossShardMessage{ID: fmt.Sprintf("overflow-%d", i), FromShard: 0, ToShard: 3, Type: "sync"}
+		if err := csc.sendViaRelay(messages[i], route); err != nil {
+			t.Fatalf("message %d refused: %v", i, err)
+		}
+	}
+	return messages
+}
+
+func bufferedIDs(relay *RelayNode) []string {
+	relay.mu.RLock()
+	defer relay.mu.RUnlock()
+	ids := make([]string, len(relay.MessageBuffer))
+	for i, message := range relay.MessageBuffer {
+		ids[i] = message.ID
+	}
+	return ids
+}
+
+func TestRelayOverflowDropNewRejects(t *testing.T) {
+	for _, policy := range []string{RelayOverflowDropNew, RelayOverflowReject} {
+		t.Run(policy, func(t *testing.T) {
+			csc, relay, route := newOverflowRelay(t, policy)
+			if csc.relayOverflow != RelayOverflowDropNew {
+				t.Fatalf("policy %s resolved to %s, want %s", policy, csc.relayOverflow, RelayOverflowDropNew)
+			}
+			fillRelay(t, csc, route, 2)
+
+			refused := &types.CrossShardMessage{ID: "refused", FromShard: 0, ToShard: 3, Type: "sync"}
+			if err := csc.sendViaRelay(refused, route); err == nil {
+				t.Fatalf("full relay accepted a message under %s", policy)
+			}
+			if ids := bufferedIDs(relay); len(ids) != 2 || ids[0] != "overflow-0" || ids[1] != "overflow-1" {
+				t.Fatalf("buffer holds %v, want the first two messages", ids)
+			}
+			info := relay.snapshot()
+			if info.RejectedMsgs != 1 || info.EvictedMsgs != 0 || info.SpilledMsgs != 0 {
+				t.Fatalf("relay counted %+v", info)
+			}
+		})
+	}
+}
+
+func TestRelayOverflowDropOldestEvicts(t *testing.T) {
+	csc, relay, route := newOverflowRelay(t, RelayOverflowDropOldest)
+	fillRelay(t, csc, route, 3)
+
+	if ids := bufferedIDs(relay); len(ids) != 2 || ids[0] != "overflow-1" || ids[1] != "overflow-2" {
+		t.Fatalf("buffer holds %v, want the oldest message evicted", ids)
+	}
+	info := relay.snapshot()
+	if info.EvictedMsgs != 1 || info.RejectedMsgs != 0 {
+		t.Fatalf("relay counted %+v", info)
+	}
+	if failed := csc.GetMetrics().MessagesFailed; failed != 1 {
+		t.Fatalf("expected the evicted message counted as failed, got %d", failed)
+	}
+}
+
+func TestRelayOverflowSpillsToDisk(t *testing.T) {
+	csc, relay, route := newOverflowRelay(t, RelayOverflowSpill)
+	messages := fillRelay(t, csc, route, 4)
+
+	if ids := bufferedIDs(relay); len(ids) != 2 || ids[0] != "overflow-0" || ids[1] != "overflow-1" {
+		t.Fatalf("buffer holds %v, want the first two messages", ids)
+	}
+	info := relay.snapshot()
+	if info.SpilledMsgs != 2 || info.RejectedMsgs != 0 || info.EvictedMsgs != 0 {
+		t.Fatalf("relay counted %+v", info)
+	}
+	// Spilled messages already count their hop through the relay
+	if messages[2].HopCount != 1 || messages[3].HopCount != 1 {
+		t.Fatalf("spilled messages at hops %d and %d, want 1", messages[2].HopCount, messages[3].HopCount)
+	}
+
+	// The overflow is in storage, where a relay opened again finds it
+	if reopened := openRelaySpill(csc.shardManager.GetDB(), 1); reopened.size() != 2 {
+		t.Fatalf("reopened spill holds %d messages, want 2", reopened.size())
+	}
+
+	// Once the buffer drains, the spilled messages come back oldest first
+	relay.mu.Lock()
+	relay.MessageBuffer = relay.MessageBuffer[:1]
+	relay.mu.Unlock()
+	csc.processRelayBuffer(relay)
+	if ids := bufferedIDs(relay); len(ids) != 2 || ids[1] != "overflow-2" {
+		t.Fatalf("buffer holds %v after one slot freed, want overflow-2 refilled", ids)
+	}
+	if spilled := relay.spill.size(); spilled != 1 {
+		t.Fatalf("%d messages still spilled, want 1", spilled)
+	}
+
+	relay.mu.Lock()
+	relay.MessageBuffer = nil
+	relay.mu.Unlock()
+	csc.processRelayBuffer(relay)
+	if ids := bufferedIDs(relay); len(ids) != 1 || ids[0] != "overflow-3" {
+		t.Fatalf("buffer holds %v, want the last spilled message", ids)
+	}
+	if spilled := relay.snapshot().SpilledMsgs; spilled != 0 {
+		t.Fatalf("%d messages still spilled, want none", spilled)
+	}
+}
+
+func TestRelaySpilledMessagesAdmittedBeforeNewOnes(t *testing.T) {
+	csc, relay, route := newOverflowRelay(t, RelayOverflowSpill)
+	fillRelay(t, csc, route, 4)
+
+	// Room frees up before the relay next processes its buffer
+	relay.mu.Lock()
+	relay.MessageBuffer = relay.MessageBuffer[:1]
+	relay.mu.Unlock()
+
+	late := &types.CrossShardMessage{ID: "late", FromShard: 0, ToShard: 3, Type: "sync"}
+	if err := csc.sendViaRelay(late, route); err != nil {
+		t.Fatalf("message refused: %v", err)
+	}
+	if ids := bufferedIDs(relay); len(ids) != 2 || ids[1] != "overflow-2" {
+		t.Fatalf("buffer holds %v, want the oldest spilled message in the freed slot", ids)
+	}
+	if spilled := relay.spill.size(); spilled != 2 {
+		t.Fatalf("%d messages spilled, want overflow-3 and the late message", spilled)
+	}
+
+	relay.mu.Lock()
+	relay.MessageBuffer = nil
+	relay.mu.Unlock()
+	csc.processRelayBuffer(relay)
+	if ids := bufferedIDs(relay); len(ids) != 2 || ids[0] != "overflow-3" || ids[1] != "late" {
+		t.Fatalf("buffer holds %v, want the spilled messages in arrival order", ids)
+	}
+}
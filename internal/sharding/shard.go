@@ -1,7 +1,9 @@
 package sharding
 
 import (
+        "errors"
         "fmt"
+        "lscc-blockchain/internal/blockchain"
         "lscc-blockchain/internal/storage"
         "lscc-blockchain/internal/utils"
         "lscc-blockchain/pkg/types"
@@ -30,6 +32,7 @@ type Shard struct {
         mu                sync.RWMutex
         db                storage.Database
         logger            *utils.Logger
+        router            *CrossShardRouter // resolves which shard owns a sender, including affinity groups
         startTime         time.Time
         isActive          bool
         stopChan          chan struct{}
@@ -225,6 +228,9 @@ func (s *Shard) AddTransaction(tx *types.Transaction) error {
         
         // Validate transaction belongs to this shard
         expectedShard := utils.GenerateShardKey(tx.From, 4) // TODO: Get from config
+        if s.router != nil {
+                expectedShard, _ = s.router.RouteTransaction(tx)
+        }
         if expectedShard != s.ID && tx.Type != "cross_shard" {
                 return fmt.Errorf("transaction does not belong to shard %d", s.ID)
         }
@@ -335,7 +341,11 @@ func (s *Shard) GetTransactionsForBlock(maxTxCount int) []*types.Transaction {
 func (s *Shard) ConfirmTransactions(txIDs []string) {
         s.mu.Lock()
         defer s.mu.Unlock()
-        
+        s.confirmTransactionsLocked(txIDs)
+}
+
+// confirmTransactionsLocked marks transactions as confirmed. Caller must hold s.mu.
+func (s *Shard) confirmTransactionsLocked(txIDs []string) {
         pool := s.TransactionPool
         pool.mu.Lock()
         defer pool.mu.Unlock()
@@ -359,7 +369,22 @@ func (s *Shard) ConfirmTransactions(txIDs []string) {
         })
 }
 
-// AddBlock adds a block to the shard
+// BlockConflictError is returned by AddBlock for a block at a height the
+// shard already holds a different block for
+type BlockConflictError struct {
+        ShardID  int
+        Index    int64
+        Existing *types.Block // block the shard holds at Index
+        Incoming *types.Block
+}
+
+func (e *BlockConflictError) Error() string {
+        return fmt.Sprintf("shard %d already has block %s at height %d, got %s", e.ShardID, e.Existing.Hash, e.Index, e.Incoming.Hash)
+}
+
+// AddBlock adds a block to the shard. A block the shard already holds is
+// ignored; a different block at a height the shard already holds is
+// returned as a *BlockConflictError for the caller to settle.
 func (s *Shard) AddBlock(block *types.Block) error {
         s.mu.Lock()
         defer s.mu.Unlock()
@@ -376,10 +401,20 @@ func (s *Shard) AddBlock(block *types.Block) error {
                 return fmt.Errorf("block shard ID %d does not match shard %d", block.ShardID, s.ID)
         }
         
+        if existing := s.blockAtLocked(block.Index); existing != nil {
+                if existing.Hash == block.Hash {
+                        return nil
+                }
+                return &BlockConflictError{ShardID: s.ID, Index: block.Index, Existing: existing, Incoming: block}
+        }
+        
         // Validate block sequence
         if s.LastBlock != nil && block.Index != s.LastBlock.Index+1 {
                 return fmt.Errorf("invalid block sequence: expected %d, got %d", s.LastBlock.Index+1, block.Index)
         }
+        if s.LastBlock != nil && block.PreviousHash != s.LastBlock.Hash {
+                return fmt.Errorf("block %d does not link to block %d: previous hash %s, expected %s", block.Index, s.LastBlock.Index, block.PreviousHash, s.LastBlock.Hash)
+        }
         
         // Add block to shard
         s.Blocks = append(s.Blocks, block)
@@ -391,7 +426,7 @@ func (s *Shard) AddBlock(block *types.Block) error {
         for i, tx := range block.Transactions {
                 txIDs[i] = tx.ID
         }
-        s.ConfirmTransactions(txIDs)
+        s.confirmTransactionsLocked(txIDs)
         
         // Save block to database
         if err := s.db.SaveBlock(block); err != nil {
@@ -416,6 +451,81 @@ func (s *Shard) AddBlock(block *types.Block) error {
         return nil
 }
 
+// blockAtLocked returns the block the shard holds at index, nil if none.
+// Caller must hold s.mu.
+func (s *Shard) blockAtLocked(index int64) *types.Block {
+        if len(s.Blocks) == 0 {
+                return nil
+        }
+        first := s.Blocks[0].Index
+        if index < first || index > s.Blocks[len(s.Blocks)-1].Index {
+                return nil
+        }
+        return s.Blocks[index-first]
+}
+
+// ReplaceHead swaps the shard's last block, existing, for incoming, a rival
+// block at the same height on the same parent that won the fork choice.
+// Transactions only existing held go back to the pending pool.
+func (s *Shard) ReplaceHead(existing, incoming *types.Block) error {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        
+        if s.LastBlock == nil || s.LastBlock.Hash != existing.Hash {
+                return fmt.Errorf("block %s is no longer the head of shard %d", existing.Hash, s.ID)
+        }
+        if incoming.Index != existing.Index || incoming.PreviousHash != existing.PreviousHash {
+                return fmt.Errorf("block %s does not share a parent with block %s", incoming.Hash, existing.Hash)
+        }
+        
+        kept := make(map[string]bool, len(incoming.Transactions))
+        for _, tx := range incoming.Transactions {
+                kept[tx.ID] = true
+        }
+        pool := s.TransactionPool
+        pool.mu.Lock()
+        reverted := 0
+        for _, tx := range existing.Transactions {
+                if kept[tx.ID] {
+                        continue
+                }
+                if confirmed, exists := pool.Confirmed[tx.ID]; exists {
+                        delete(pool.Confirmed, tx.ID)
+                        pool.Pending[tx.ID] = confirmed
+                        pool.CurrentSize++
+                        s.insertIntoPriorityQueue(confirmed)
+                        reverted++
+                }
+        }
+        pool.mu.Unlock()
+        
+        s.Blocks[len(s.Blocks)-1] = incoming
+        s.LastBlock = incoming
+        
+        txIDs := make([]string, len(incoming.Transactions))
+        for i, tx := range incoming.Transactions {
+                txIDs[i] = tx.ID
+        }
+        s.confirmTransactionsLocked(txIDs)
+        
+        if err := s.db.SaveBlock(incoming); err != nil {
+                s.logger.LogError("sharding", "save_block", err, logrus.Fields{
+                        "shard_id":   s.ID,
+                        "block_hash": incoming.Hash,
+                        "timestamp":  time.Now().UTC(),
+                })
+        }
+        
+        s.logger.LogSharding(s.ID, "head_replaced", logrus.Fields{
+                "block_index":   incoming.Index,
+                "replaced_hash": existing.Hash,
+                "block_hash":    incoming.Hash,
+                "reverted_txs":  reverted,
+                "timestamp":     time.Now().UTC(),
+        })
+        return nil
+}
+
 // AddValidator adds a validator to the shard
 func (s *Shard) AddValidator(validator *types.Validator) error {
         s.mu.Lock()
@@ -484,6 +594,7 @@ func (s *Shard) AddCrossShardMessage(message *types.CrossShardMessage) error {
         
         s.logger.LogCrossShard(message.FromShard, message.ToShard, message.Type, logrus.Fields{
                 "message_id": message.ID,
+                "trace_id":   message.TraceID,
                 "shard_id":   s.ID,
                 "timestamp":  time.Now().UTC(),
         })
@@ -502,6 +613,7 @@ func (s *Shard) AddCrossShardMessage(message *types.CrossShardMessage) error {
         
         s.logger.LogCrossShard(message.FromShard, message.ToShard, message.Type, logrus.Fields{
                 "message_id":     message.ID,
+                "trace_id":       message.TraceID,
                 "shard_id":       s.ID,
                 "message_count":  len(s.CrossShardMessages),
                 "timestamp":      time.Now().UTC(),
@@ -774,46 +886,326 @@ func max(a, b int64) int64 {
         return b
 }
 
-// Sync synchronizes shard state with other shards
-func (s *Shard) Sync(targetShard *Shard) error {
+// Shard sync strategies
+const (
+        SyncStrategyIncremental = "incremental" // transfer only the missing blocks in the requested range
+        SyncStrategyFull        = "full"        // replace the chain with the peer's
+)
+
+// SyncOptions selects how Shard.Sync transfers blocks
+type SyncOptions struct {
+        Strategy   string // SyncStrategyIncremental or SyncStrategyFull
+        StartBlock int64  // first block wanted; 0 means the block after our head
+        EndBlock   int64  // last block wanted; 0 means the peer's head
+        MaxBlocks  int    // most blocks an incremental sync transfers; 0 means no limit
+}
+
+// SyncResult reports what a sync transferred
+type SyncResult struct {
+        Strategy    string `json:"strategy"` // strategy actually used
+        Transferred int    `json:"transferred"`
+        FirstBlock  int64  `json:"first_block"`
+        LastBlock   int64  `json:"last_block"`
+        More        bool   `json:"more"` // blocks of the range were left for another sync by MaxBlocks
+}
+
+// Sync brings this shard's chain up to date with peer's. An incremental sync
+// copies only the blocks of [opts.StartBlock, opts.EndBlock] missing from our
+// chain, checking each block's hash and that it links to the one before it,
+// and falls back to a full sync when our head is not on the peer's chain. A
+// full sync replaces our chain with the peer's up to opts.EndBlock. Nothing
+// is applied unless the whole transfer links.
+func (s *Shard) Sync(peer *Shard, opts SyncOptions) (SyncResult, error) {
+        // Copy the peer's chain first so the two shard locks are never held together
+        peer.mu.RLock()
+        peerBlocks := append([]*types.Block{}, peer.Blocks...)
+        peer.mu.RUnlock()
+        
         s.mu.Lock()
         defer s.mu.Unlock()
         
         startTime := time.Now()
         
         s.logger.LogSharding(s.ID, "sync_start", logrus.Fields{
-                "target_shard": targetShard.ID,
+                "target_shard": peer.ID,
+                "strategy":     opts.Strategy,
+                "start_block":  opts.StartBlock,
+                "end_block":    opts.EndBlock,
                 "timestamp":    startTime,
         })
         
-        // Simple sync: compare block heights
-        if targetShard.BlockHeight > s.BlockHeight {
-                // We're behind, need to sync
-                s.State = "syncing"
-                
-                // In a real implementation, this would request blocks from the target shard
-                // For now, we'll just update the state
-                syncDuration := time.Since(startTime)
-                s.Performance.SyncTime = syncDuration
-                
-                s.logger.LogSharding(s.ID, "sync_completed", logrus.Fields{
-                        "target_shard":   targetShard.ID,
-                        "sync_duration":  syncDuration.Milliseconds(),
-                        "blocks_behind":  targetShard.BlockHeight - s.BlockHeight,
-                        "timestamp":      time.Now().UTC(),
+        var result SyncResult
+        var err error
+        if opts.Strategy == SyncStrategyFull {
+                result, err = s.syncFull(peerBlocks, opts.EndBlock)
+        } else {
+                result, err = s.syncIncremental(peerBlocks, opts)
+                if errors.Is(err, errSyncDiverged) {
+                        s.logger.LogSharding(s.ID, "sync_fallback_full", logrus.Fields{
+                                "target_shard": peer.ID,
+                                "our_height":   s.BlockHeight,
+                                "timestamp":    time.Now().UTC(),
+                        })
+                        result, err = s.syncFull(peerBlocks, opts.EndBlock)
+                }
+        }
+        if err != nil {
+                return result, fmt.Errorf("sync from shard %d failed: %w", peer.ID, err)
+        }
+        
+        if result.Transferred == 0 {
+                s.logger.LogSharding(s.ID, "sync_not_needed", logrus.Fields{
+                        "target_shard": peer.ID,
+                        "our_height":   s.BlockHeight,
+                        "timestamp":    time.Now().UTC(),
                 })
-                
-                s.State = "active"
-                return nil
+                return result, nil
         }
         
-        s.logger.LogSharding(s.ID, "sync_not_needed", logrus.Fields{
-                "target_shard":  targetShard.ID,
-                "our_height":    s.BlockHeight,
-                "target_height": targetShard.BlockHeight,
+        syncDuration := time.Since(startTime)
+        s.Performance.SyncTime = syncDuration
+        
+        s.logger.LogSharding(s.ID, "sync_completed", logrus.Fields{
+                "target_shard":  peer.ID,
+                "strategy":      result.Strategy,
+                "transferred":   result.Transferred,
+                "first_block":   result.FirstBlock,
+                "last_block":    result.LastBlock,
+                "sync_duration": syncDuration.Milliseconds(),
                 "timestamp":     time.Now().UTC(),
         })
         
+        return result, nil
+}
+
+// errSyncDiverged means our head is not on the peer's chain, so missing
+// blocks cannot simply be appended
+var errSyncDiverged = errors.New("shard chain has diverged from peer")
+
+// syncIncremental appends the peer blocks after our head that fall within
+// the requested range. Caller must hold s.mu.
+func (s *Shard) syncIncremental(peerBlocks []*types.Block, opts SyncOptions) (SyncResult, error) {
+        result := SyncResult{Strategy: SyncStrategyIncremental}
+        if len(peerBlocks) == 0 {
+                return result, nil
+        }
+        
+        next := peerBlocks[0].Index
+        if s.LastBlock != nil {
+                next = s.LastBlock.Index + 1
+        }
+        if opts.StartBlock > next {
+                return result, fmt.Errorf("requested range starts at block %d but shard %d is at block %d", opts.StartBlock, s.ID, next-1)
+        }
+        
+        blocks := blockRange(peerBlocks, next, opts.EndBlock)
+        if len(blocks) == 0 {
+                return result, nil
+        }
+        
+        if s.LastBlock != nil && blocks[0].PreviousHash != s.LastBlock.Hash {
+                return result, errSyncDiverged
+        }
+        if opts.MaxBlocks > 0 && len(blocks) > opts.MaxBlocks {
+                blocks = blocks[:opts.MaxBlocks]
+                result.More = true
+        }
+        if err := verifyLinkage(blocks); err != nil {
+                return result, err
+        }
+        
+        for _, block := range blocks {
+                s.applySyncedBlock(block)
+        }
+        
+        result.Transferred = len(blocks)
+        result.FirstBlock = blocks[0].Index
+        result.LastBlock = blocks[len(blocks)-1].Index
+        return result, nil
+}
+
+// syncFull replaces our chain with the peer's, up to end when it is
+// positive. Caller must hold s.mu.
+func (s *Shard) syncFull(peerBlocks []*types.Block, end int64) (SyncResult, error) {
+        result := SyncResult{Strategy: SyncStrategyFull}
+        if len(peerBlocks) == 0 {
+                return result, nil
+        }
+        
+        blocks := blockRange(peerBlocks, peerBlocks[0].Index, end)
+        if len(blocks) == 0 {
+                return result, nil
+        }
+        if err := verifyLinkage(blocks); err != nil {
+                return result, err
+        }
+        
+        s.Blocks = make([]*types.Block, 0, len(blocks))
+        s.LastBlock = nil
+        s.BlockHeight = 0
+        for _, block := range blocks {
+                s.applySyncedBlock(block)
+        }
+        
+        result.Transferred = len(blocks)
+        result.FirstBlock = blocks[0].Index
+        result.LastBlock = blocks[len(blocks)-1].Index
+        return result, nil
+}
+
+// applySyncedBlock appends a block received from a peer. Caller must hold s.mu.
+func (s *Shard) applySyncedBlock(block *types.Block) {
+        s.Blocks = append(s.Blocks, block)
+        s.LastBlock = block
+        s.BlockHeight = block.Index
+        
+        txIDs := make([]string, len(block.Transactions))
+        for i, tx := range block.Transactions {
+                txIDs[i] = tx.ID
+        }
+        s.confirmTransactionsLocked(txIDs)
+}
+
+// consensusValidators returns the active validators that vote on the
+// shard's blocks, none while the shard is stopped
+func (s *Shard) consensusValidators() []*types.Validator {
+        s.mu.RLock()
+        defer s.mu.RUnlock()
+        
+        if !s.isActive {
+                return nil
+        }
+        validators := make([]*types.Validator, 0, len(s.Validators))
+        for _, v := range s.Validators {
+                if v.Status == "active" {
+                        validators = append(validators, v)
+                }
+        }
+        return validators
+}
+
+// hasPendingTransactions reports whether the pool holds transactions ready
+// for a block
+func (s *Shard) hasPendingTransactions() bool {
+        pool := s.TransactionPool
+        pool.mu.RLock()
+        defer pool.mu.RUnlock()
+        return len(pool.Pending) > 0
+}
+
+// height returns the index of the shard's last block
+func (s *Shard) height() int64 {
+        s.mu.RLock()
+        defer s.mu.RUnlock()
+        return s.BlockHeight
+}
+
+// nextBlock builds an unsealed block of transactions on top of the shard's
+// chain. The caller sets the proposer and hash.
+func (s *Shard) nextBlock(transactions []*types.Transaction, chainID string) *types.Block {
+        s.mu.RLock()
+        defer s.mu.RUnlock()
+        
+        transactions = blockchain.OrderTransactions(transactions)
+        block := &types.Block{
+                Index:        s.BlockHeight + 1,
+                Timestamp:    time.Now(),
+                Transactions: transactions,
+                MerkleRoot:   blockchain.NewMerkleTree(transactions).GetRootHash(),
+                ShardID:      s.ID,
+                ChainID:      chainID,
+        }
+        if s.LastBlock != nil {
+                block.PreviousHash = s.LastBlock.Hash
+        }
+        return block
+}
+
+// commitBlock appends a block agreed by the shard's own consensus. It fails
+// if the chain moved on, through a sync or another commit, since the block
+// was built.
+func (s *Shard) commitBlock(block *types.Block) error {
+        s.mu.Lock()
+        defer s.mu.Unlock()
+        
+        if err := s.checkNextLocked(block); err != nil {
+                return err
+        }
+        s.appendAgreedLocked(block)
+        return nil
+}
+
+// checkNextLocked reports whether block still extends the shard's head.
+// Caller must hold s.mu.
+func (s *Shard) checkNextLocked(block *types.Block) error {
+        if block.Index != s.BlockHeight+1 || (s.LastBlock != nil && block.PreviousHash != s.LastBlock.Hash) {
+                return fmt.Errorf("shard %d chain moved to height %d while block %d was agreed", s.ID, s.BlockHeight, block.Index)
+        }
+        return nil
+}
+
+// appendAgreedLocked appends a block checked by checkNextLocked. Caller must
+// hold s.mu.
+func (s *Shard) appendAgreedLocked(block *types.Block) {
+        s.updatePerformanceMetrics(block)
+        s.applySyncedBlock(block)
+}
+
+// requeueTransactions returns transactions taken for a block that was not
+// committed to the pending pool
+func (s *Shard) requeueTransactions(transactions []*types.Transaction) {
+        pool := s.TransactionPool
+        pool.mu.Lock()
+        defer pool.mu.Unlock()
+        
+        for _, tx := range transactions {
+                if _, processing := pool.Processing[tx.ID]; !processing {
+                        continue
+                }
+                delete(pool.Processing, tx.ID)
+                pool.Pending[tx.ID] = tx
+                s.insertIntoPriorityQueue(tx)
+        }
+}
+
+// blockRange returns the blocks of a contiguous chain with indexes from start
+// to end inclusive; an end of zero or less means the chain's head
+func blockRange(chain []*types.Block, start, end int64) []*types.Block {
+        if len(chain) == 0 {
+                return nil
+        }
+        first := chain[0].Index
+        last := chain[len(chain)-1].Index
+        if end <= 0 || end > last {
+                end = last
+        }
+        if start < first {
+                start = first
+        }
+        if start > end {
+                return nil
+        }
+        return chain[start-first : end-first+1]
+}
+
+// verifyLinkage checks that each block's hash matches its contents and that
+// it follows the one before it by index and previous hash
+func verifyLinkage(blocks []*types.Block) error {
+        for i, block := range blocks {
+                if !block.VerifyHash() {
+                        return fmt.Errorf("block %d hash mismatch: expected %s, got %s", block.Index, block.ComputeHash(), block.Hash)
+                }
+                if i == 0 {
+                        continue
+                }
+                prev := blocks[i-1]
+                if block.Index != prev.Index+1 {
+                        return fmt.Errorf("block %d follows block %d", block.Index, prev.Index)
+                }
+                if block.PreviousHash != prev.Hash {
+                        return fmt.Errorf("block %d does not link to block %d: previous hash %s, expected %s", block.Index, prev.Index, block.PreviousHash, prev.Hash)
+                }
+        }
         return nil
 }
 
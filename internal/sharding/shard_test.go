@@ -0,0 +1,173 @@
+package sharding
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"lscc-blockchain/internal/storage"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+)
+
+// newTestShard returns a shard holding blocks, logging discarded
+func newTestShard(t *testing.T, id int, blocks []*types.Block) *Shard {
+	t.Helper()
+
+	logger := utils.NewLogger()
+	logger.Logger.SetOutput(io.Discard)
+	shard := NewShard(id, 0, storage.NewMemoryDB(), logger)
+	for _, block := range blocks {
+		shard.Blocks = append(shard.Blocks, block)
+		shard.LastBlock = block
+		shard.BlockHeight = block.Index
+	}
+	return shard
+}
+
+// testChain returns blocks 0 to length-1, each linked to the one before and
+// tagged so chains built with different tags diverge from the first block
+func testChain(length int, tag string) []*types.Block {
+	chain := make([]*types.Block, 0, length)
+	previous := ""
+	for i := 0; i < length; i++ {
+		block := &types.Block{
+			Index:        int64(i),
+			Timestamp:    time.Unix(int64(1700000000+i), 0).UTC(),
+			PreviousHash: previous,
+			MerkleRoot:   fmt.Sprintf("%s-%d", tag, i),
+			Validator:    "validator_0",
+		}
+		block.Hash = block.ComputeHash()
+		chain = append(chain, block)
+		previous = block.Hash
+	}
+	return chain
+}
+
+func TestIncrementalSyncTransfersOnlyMissingRange(t *testing.T) {
+	source := testChain(10, "main")
+	peer := newTestShard(t, 1, source)
+	behind := newTestShard(t, 0, source[:5])
+
+	result, err := behind.Sync(peer, SyncOptions{Strategy: SyncStrategyIncremental})
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if result.Strategy != SyncStrategyIncremental || result.Transferred != 5 || result.FirstBlock != 5 || result.LastBlock != 9 {
+		t.Fatalf("expected incremental transfer of blocks 5-9, got %+v", result)
+	}
+	if len(behind.Blocks) != 10 || behind.BlockHeight != 9 || behind.LastBlock != source[9] {
+		t.Fatalf("expected the chain to end at block 9, have %d blocks at height %d", len(behind.Blocks), behind.BlockHeight)
+	}
+	for i, block := range behind.Blocks {
+		if block != source[i] {
+			t.Fatalf("block %d is not the peer's", i)
+		}
+	}
+	if err := verifyLinkage(behind.Blocks); err != nil {
+		t.Fatalf("synced chain does not link: %v", err)
+	}
+}
+
+func TestIncrementalSyncHonorsBounds(t *testing.T) {
+	source := testChain(10, "main")
+
+	behind := newTestShard(t, 0, source[:5])
+	result, err := behind.Sync(newTestShard(t, 1, source), SyncOptions{Strategy: SyncStrategyIncremental, StartBlock: 5, EndBlock: 7})
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if result.Transferred != 3 || result.FirstBlock != 5 || result.LastBlock != 7 || behind.BlockHeight != 7 {
+		t.Fatalf("expected blocks 5-7 only, got %+v at height %d", result, behind.BlockHeight)
+	}
+
+	behind = newTestShard(t, 0, source[:5])
+	result, err = behind.Sync(newTestShard(t, 1, source), SyncOptions{Strategy: SyncStrategyIncremental, MaxBlocks: 2})
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if result.Transferred != 2 || result.LastBlock != 6 || !result.More {
+		t.Fatalf("expected two blocks with more to come, got %+v", result)
+	}
+
+	behind = newTestShard(t, 0, source[:5])
+	if _, err := behind.Sync(newTestShard(t, 1, source), SyncOptions{Strategy: SyncStrategyIncremental, StartBlock: 8}); err == nil {
+		t.Fatal("expected a range starting past the head to be refused")
+	}
+	if behind.BlockHeight != 4 {
+		t.Fatalf("expected a refused sync to leave the chain alone, height %d", behind.BlockHeight)
+	}
+
+	upToDate := newTestShard(t, 0, source)
+	result, err = upToDate.Sync(newTestShard(t, 1, source), SyncOptions{Strategy: SyncStrategyIncremental})
+	if err != nil || result.Transferred != 0 {
+		t.Fatalf("expected nothing to transfer, got %+v, %v", result, err)
+	}
+}
+
+func TestIncrementalSyncRejectsBrokenLinkage(t *testing.T) {
+	source := testChain(10, "main")
+	tampered := append([]*types.Block{}, source...)
+	forged := *source[7]
+	forged.PreviousHash = "forged"
+	forged.Hash = forged.ComputeHash()
+	tampered[7] = &forged
+
+	behind := newTestShard(t, 0, source[:5])
+	if _, err := behind.Sync(newTestShard(t, 1, tampered), SyncOptions{Strategy: SyncStrategyIncremental}); err == nil {
+		t.Fatal("expected a range that does not link to be refused")
+	}
+	if len(behind.Blocks) != 5 || behind.BlockHeight != 4 {
+		t.Fatalf("expected nothing applied, have %d blocks at height %d", len(behind.Blocks), behind.BlockHeight)
+	}
+
+	corrupted := append([]*types.Block{}, source...)
+	bad := *source[6]
+	bad.MerkleRoot = "changed"
+	corrupted[6] = &bad
+	if _, err := behind.Sync(newTestShard(t, 1, corrupted), SyncOptions{Strategy: SyncStrategyIncremental}); err == nil {
+		t.Fatal("expected a block whose hash does not match to be refused")
+	}
+	if behind.BlockHeight != 4 {
+		t.Fatalf("expected nothing applied, height %d", behind.BlockHeight)
+	}
+}
+
+func TestDivergedIncrementalSyncFallsBackToFull(t *testing.T) {
+	source := testChain(10, "main")
+	diverged := newTestShard(t, 0, testChain(5, "fork"))
+
+	result, err := diverged.Sync(newTestShard(t, 1, source), SyncOptions{Strategy: SyncStrategyIncremental})
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if result.Strategy != SyncStrategyFull || result.Transferred != 10 {
+		t.Fatalf("expected a full sync of 10 blocks, got %+v", result)
+	}
+	for i, block := range diverged.Blocks {
+		if block != source[i] {
+			t.Fatalf("block %d was not replaced with the peer's", i)
+		}
+	}
+}
+
+func TestFullSyncReplacesChain(t *testing.T) {
+	source := testChain(10, "main")
+	behind := newTestShard(t, 0, source[:5])
+
+	result, err := behind.Sync(newTestShard(t, 1, source), SyncOptions{Strategy: SyncStrategyFull, EndBlock: 8})
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if result.Strategy != SyncStrategyFull || result.Transferred != 9 || result.FirstBlock != 0 || result.LastBlock != 8 {
+		t.Fatalf("expected a full transfer of blocks 0-8, got %+v", result)
+	}
+	if len(behind.Blocks) != 9 || behind.BlockHeight != 8 {
+		t.Fatalf("expected 9 blocks at height 8, have %d at %d", len(behind.Blocks), behind.BlockHeight)
+	}
+	if err := verifyLinkage(behind.Blocks); err != nil {
+		t.Fatalf("synced chain does not link: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package sharding
+
+import (
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/utils"
+)
+
+func TestRetryBackoffGrowsPerAttempt(t *testing.T) {
+	sm := newTestShardManager(t, nil)
+	csc := NewCrossShardCommunicator(sm, sm.logger)
+	manager := csc.syncManager
+
+	var previousMax time.Duration
+	for attempt := 1; attempt <= 8; attempt++ {
+		want := manager.retryBaseDelay << (attempt - 1)
+		if want > manager.retryMaxDelay {
+			want = manager.retryMaxDelay
+		}
+		var lowest, highest time.Duration
+		for i := 0; i < 200; i++ {
+			backoff := manager.retryBackoff(attempt)
+			if backoff < want/2 || backoff > want {
+				t.Fatalf("attempt %d: backoff %v outside [%v, %v]", attempt, backoff, want/2, want)
+			}
+			if i == 0 || backoff < lowest {
+				lowest = backoff
+			}
+			if backoff > highest {
+				highest = backoff
+			}
+		}
+		// Jitter never lets a later attempt wait less than an earlier one
+		if lowest < previousMax && want < manager.retryMaxDelay {
+			t.Fatalf("attempt %d: backoff %v shorter than attempt %d's %v", attempt, lowest, attempt-1, previousMax)
+		}
+		previousMax = highest
+	}
+}
+
+func TestSyncRequestNotRetriedBeforeNextRetryAt(t *testing.T) {
+	sm := newTestShardManager(t, func(cfg *config.Config) {
+		cfg.CrossShard.Sync.MaxRetries = 3
+	})
+	clock := utils.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	csc := NewCrossShardCommunicatorWithClock(sm, sm.logger, clock)
+
+	// No shard 99, so every attempt fails
+	request := &SyncRequest{
+		ID:        "sync_unreachable",
+		FromShard: 99,
+		ToShard:   0,
+		CreatedAt: clock.Now(),
+		Status:    "pending",
+	}
+	csc.syncManager.syncRequests[request.ID] = request
+
+	csc.processSyncRequests()
+	if request.RetryCount != 1 || request.Status != "pending" || request.LastError == "" {
+		t.Fatalf("expected one failed attempt, got %+v", request)
+	}
+	firstWait := request.NextRetryAt.Sub(clock.Now())
+	if firstWait <= 0 {
+		t.Fatalf("expected a retry scheduled in the future, got %v", request.NextRetryAt)
+	}
+
+	// Not due yet: passes before NextRetryAt leave the request alone
+	csc.processSyncRequests()
+	clock.Advance(firstWait - time.Millisecond)
+	csc.processSyncRequests()
+	if request.RetryCount != 1 {
+		t.Fatalf("retried before NextRetryAt: %d attempts", request.RetryCount)
+	}
+
+	clock.Advance(time.Millisecond)
+	csc.processSyncRequests()
+	if request.RetryCount != 2 {
+		t.Fatalf("expected a retry once due, got %d attempts", request.RetryCount)
+	}
+	if secondWait := request.NextRetryAt.Sub(clock.Now()); secondWait < firstWait {
+		t.Fatalf("second backoff %v shorter than first %v", secondWait, firstWait)
+	}
+
+	clock.Advance(request.NextRetryAt.Sub(clock.Now()))
+	csc.processSyncRequests()
+	if request.RetryCount != 3 || request.Status != "failed" {
+		t.Fatalf("expected the request abandoned after 3 attempts, got %+v", request)
+	}
+
+	metrics := csc.GetMetrics()
+	if metrics.SyncRetries != 2 || metrics.SyncFailures != 1 {
+		t.Fatalf("expected 2 retries and 1 permanent failure, got %d and %d", metrics.SyncRetries, metrics.SyncFailures)
+	}
+
+	clock.Advance(time.Hour)
+	csc.processSyncRequests()
+	if request.RetryCount != 3 {
+		t.Fatalf("failed request retried: %d attempts", request.RetryCount)
+	}
+}
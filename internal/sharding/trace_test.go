@@ -0,0 +1,47 @@
+package sharding
+
+import (
+	"testing"
+	"time"
+
+	"lscc-blockchain/pkg/types"
+)
+
+func TestCrossShardMessageLogsCarryTraceID(t *testing.T) {
+	sm := newTestShardManager(t, nil)
+	traces := sm.logger.EnableTraceRecording(10, 100)
+	csc := NewCrossShardCommunicator(sm, sm.logger)
+	if err := csc.Start(); err != nil {
+		t.Fatalf("failed to start communicator: %v", err)
+	}
+	t.Cleanup(func() { csc.Stop() })
+
+	tx := &types.Transaction{ID: "tx-traced", From: "from", To: "to", Amount: 1, TraceID: "req-xs-1"}
+	message := &types.CrossShardMessage{ID: "msg-traced", FromShard: 0, ToShard: 1, Type: "transaction"}
+	message.SetTransaction(tx)
+	if err := csc.SendMessage(message); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	// The message takes the trace ID of the transaction it carries, and
+	// every line about it, sending and delivering, is recorded under it
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		trace, found := traces.Get("req-xs-1")
+		if found && len(trace.Events) >= 2 {
+			for _, event := range trace.Events {
+				if event.Component != "cross_shard" {
+					t.Fatalf("unexpected %s line in the message's trace: %+v", event.Component, event)
+				}
+				if id := string(event.Fields["message_id"]); id != `"msg-traced"` {
+					t.Fatalf("line about message %s in the trace", id)
+				}
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("message trace not recorded past sending: %+v", trace)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
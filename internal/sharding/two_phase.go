@@ -0,0 +1,387 @@
+package sharding
+
+import (
+        "fmt"
+        "lscc-blockchain/pkg/types"
+        "time"
+
+        "github.com/sirupsen/logrus"
+)
+
+// Two-phase commit message types
+const (
+        MessageTypePrepare = "2pc_prepare"
+        MessageTypeCommit  = "2pc_commit"
+        MessageTypeAbort   = "2pc_abort"
+)
+
+// TwoPhaseTransaction tracks a cross-shard transaction through two-phase commit.
+// The sender's funds are locked (escrowed) on the source shard when the debit
+// commits; the source shard votes to prepare once that lock exists and the
+// destination shard votes once it is able to accept the credit.
+type TwoPhaseTransaction struct {
+        TxID        string             `json:"tx_id"`
+        Transaction *types.Transaction `json:"transaction"`
+        FromShard   int                `json:"from_shard"`
+        ToShard     int                `json:"to_shard"`
+        State       string             `json:"state"` // "preparing", "committing", "committed", "aborting", "aborted"
+        Votes       map[int]bool       `json:"votes"` // shardID -> prepared
+        Reason      string             `json:"reason,omitempty"`
+        CreatedAt   time.Time          `json:"created_at"`
+        Deadline    time.Time          `json:"deadline"`
+        DecidedAt   *time.Time         `json:"decided_at,omitempty"`
+}
+
+// isFinal reports whether the transaction has reached a terminal state
+func (tpt *TwoPhaseTransaction) isFinal() bool {
+        return tpt.State == "committed" || tpt.State == "aborted"
+}
+
+// BeginTwoPhaseCommit registers a cross-shard transaction with the coordinator
+// and asks both participating shards to prepare
+func (csc *CrossShardCommunicator) BeginTwoPhaseCommit(tx *types.Transaction, fromShard, toShard int) error {
+        sm := csc.syncManager
+        sm.mu.Lock()
+        if _, exists := sm.twoPhaseTxs[tx.ID]; exists {
+                sm.mu.Unlock()
+                return fmt.Errorf("two-phase commit already in progress for transaction %s", tx.ID)
+        }
+
+        // Another unsettled transfer from the same sender competes for the
+        // same funds
+        var rivals []*types.Transaction
+        for _, other := range sm.twoPhaseTxs {
+                if !other.isFinal() && other.Transaction.From == tx.From {
+                        rivals = append(rivals, other.Transaction)
+                }
+        }
+
+        now := csc.clock.Now()
+        entry := &TwoPhaseTransaction{
+                TxID:        tx.ID,
+                Transaction: tx,
+                FromShard:   fromShard,
+                ToShard:     toShard,
+                State:       "preparing",
+                Votes:       make(map[int]bool),
+                CreatedAt:   now,
+                Deadline:    now.Add(sm.prepareTimeout),
+        }
+        sm.twoPhaseTxs[tx.ID] = entry
+        sm.mu.Unlock()
+
+        csc.recordConflictCheck(tx, rivals, fromShard, toShard)
+
+        csc.logger.LogCrossShard(fromShard, toShard, "2pc_begin", logrus.Fields{
+                "tx_id":     tx.ID,
+                "trace_id":  tx.TraceID,
+                "deadline":  entry.Deadline,
+                "timestamp": now.UTC(),
+        })
+
+        csc.sendPrepare(entry, fromShard)
+        csc.sendPrepare(entry, toShard)
+
+        return nil
+}
+
+// sendPrepare sends a prepare request for entry to a participating shard
+func (csc *CrossShardCommunicator) sendPrepare(entry *TwoPhaseTransaction, shardID int) {
+        csc.sendTwoPhaseMessage(entry, MessageTypePrepare, shardID)
+}
+
+// sendTwoPhaseMessage sends a two-phase commit message to a participating shard
+func (csc *CrossShardCommunicator) sendTwoPhaseMessage(entry *TwoPhaseTransaction, messageType string, shardID int) {
+        message := &types.CrossShardMessage{
+                ID:        fmt.Sprintf("%s_%s_%d", messageType, entry.TxID, shardID),
+                FromShard: entry.FromShard,
+                ToShard:   shardID,
+                Type:      messageType,
+                Timestamp: csc.clock.Now(),
+                Priority:  MessagePriorityHigh, // votes and decisions release escrowed funds
+                Processed: false,
+        }
+        message.SetTransaction(entry.Transaction)
+
+        if err := csc.SendMessage(message); err != nil {
+                // The coordinator resends on its next pass
+                csc.logger.LogError("cross_shard", "send_"+messageType, err, logrus.Fields{
+                        "tx_id":     entry.TxID,
+                        "shard_id":  shardID,
+                        "timestamp": csc.clock.Now().UTC(),
+                })
+        }
+}
+
+// handlePrepareMessage records the vote of a participating shard
+func (csc *CrossShardCommunicator) handlePrepareMessage(shard *Shard, message *types.CrossShardMessage) error {
+        tx, err := message.Transaction()
+        if err != nil {
+                return fmt.Errorf("invalid prepare message: %w", err)
+        }
+
+        sm := csc.syncManager
+        sm.mu.Lock()
+        defer sm.mu.Unlock()
+
+        entry, exists := sm.twoPhaseTxs[tx.ID]
+        if !exists || entry.State != "preparing" {
+                return nil // Decision already taken
+        }
+
+        var prepared bool
+        switch shard.ID {
+        case entry.FromShard:
+                // Source votes yes once the sender's funds are locked in escrow
+                if _, err := csc.shardManager.blockchain.GetPendingCrossShardTransfer(tx.ID); err != nil {
+                        return nil // Not locked yet, the coordinator will ask again
+                }
+                prepared = true
+        case entry.ToShard:
+                prepared = shard.IsHealthy()
+        default:
+                return fmt.Errorf("shard %d is not a participant of transaction %s", shard.ID, tx.ID)
+        }
+
+        entry.Votes[shard.ID] = prepared
+
+        csc.logger.LogCrossShard(entry.FromShard, entry.ToShard, "2pc_vote", logrus.Fields{
+                "tx_id":     tx.ID,
+                "trace_id":  tx.TraceID,
+                "shard_id":  shard.ID,
+                "prepared":  prepared,
+                "timestamp": csc.clock.Now().UTC(),
+        })
+
+        return nil
+}
+
+// handleCommitMessage applies a commit decision on a participating shard
+func (csc *CrossShardCommunicator) handleCommitMessage(shard *Shard, message *types.CrossShardMessage) error {
+        tx, err := message.Transaction()
+        if err != nil {
+                return fmt.Errorf("invalid commit message: %w", err)
+        }
+
+        sm := csc.syncManager
+        sm.mu.Lock()
+        defer sm.mu.Unlock()
+
+        entry, exists := sm.twoPhaseTxs[tx.ID]
+        if !exists || entry.State != "committing" || shard.ID != entry.ToShard {
+                return nil
+        }
+
+        // Release the escrow to the recipient
+        if err := csc.shardManager.blockchain.CommitCrossShardTransfer(tx.ID); err != nil {
+                return fmt.Errorf("failed to commit cross-shard transfer: %w", err)
+        }
+        if err := shard.AddTransaction(tx); err != nil {
+                csc.logger.LogError("cross_shard", "2pc_record_transaction", err, logrus.Fields{
+                        "tx_id":     tx.ID,
+                        "shard_id":  shard.ID,
+                        "timestamp": csc.clock.Now().UTC(),
+                })
+        }
+
+        entry.State = "committed"
+        csc.metrics.TwoPhaseCommitted++
+        if csc.collector != nil {
+                csc.collector.RecordCrossShardCommitted(csc.clock.Since(entry.CreatedAt))
+        }
+
+        csc.logger.LogCrossShard(entry.FromShard, entry.ToShard, "2pc_committed", logrus.Fields{
+                "tx_id":     tx.ID,
+                "trace_id":  tx.TraceID,
+                "duration":  csc.clock.Since(entry.CreatedAt).Milliseconds(),
+                "timestamp": csc.clock.Now().UTC(),
+        })
+
+        return nil
+}
+
+// handleAbortMessage applies an abort decision on a participating shard
+func (csc *CrossShardCommunicator) handleAbortMessage(shard *Shard, message *types.CrossShardMessage) error {
+        tx, err := message.Transaction()
+        if err != nil {
+                return fmt.Errorf("invalid abort message: %w", err)
+        }
+
+        sm := csc.syncManager
+        sm.mu.Lock()
+        defer sm.mu.Unlock()
+
+        entry, exists := sm.twoPhaseTxs[tx.ID]
+        if !exists || entry.State != "aborting" || shard.ID != entry.FromShard {
+                return nil
+        }
+
+        // Keep a debit that has not committed out of later blocks, then
+        // release the lock by refunding the sender if it has
+        csc.shardManager.blockchain.DropPendingTransaction(tx.ID, "cross-shard transfer aborted: "+entry.Reason)
+        if err := csc.releaseEscrow(entry); err != nil {
+                return err
+        }
+
+        entry.State = "aborted"
+        csc.metrics.TwoPhaseAborted++
+        if csc.collector != nil {
+                csc.collector.IncrementCrossShardFailed()
+        }
+
+        csc.logger.LogCrossShard(entry.FromShard, entry.ToShard, "2pc_aborted", logrus.Fields{
+                "tx_id":     tx.ID,
+                "trace_id":  tx.TraceID,
+                "reason":    entry.Reason,
+                "timestamp": csc.clock.Now().UTC(),
+        })
+
+        return nil
+}
+
+// releaseEscrow refunds the sender of an aborted transfer whose debit has
+// committed. Caller must hold the sync manager lock.
+func (csc *CrossShardCommunicator) releaseEscrow(entry *TwoPhaseTransaction) error {
+        bc := csc.shardManager.blockchain
+        if _, err := bc.GetPendingCrossShardTransfer(entry.TxID); err != nil {
+                return nil // Nothing locked
+        }
+        if err := bc.AbortCrossShardTransfer(entry.TxID); err != nil {
+                return fmt.Errorf("failed to abort cross-shard transfer: %w", err)
+        }
+        return nil
+}
+
+// twoPhaseCoordinator drives in-flight two-phase commits to a decision
+func (csc *CrossShardCommunicator) twoPhaseCoordinator() {
+        ticker := csc.clock.NewTicker(500 * time.Millisecond)
+        defer ticker.Stop()
+
+        for {
+                select {
+                case <-csc.stopChan:
+                        return
+                case <-ticker.C():
+                        csc.coordinateTwoPhaseCommits()
+                }
+        }
+}
+
+// coordinateTwoPhaseCommits decides prepared transactions, aborts those past
+// their deadline, and resends messages that have not been acted upon
+func (csc *CrossShardCommunicator) coordinateTwoPhaseCommits() {
+        sm := csc.syncManager
+        sm.mu.Lock()
+
+        now := csc.clock.Now()
+        type pendingSend struct {
+                entry       *TwoPhaseTransaction
+                messageType string
+                shardID     int
+        }
+        sends := make([]pendingSend, 0)
+
+        for txID, entry := range sm.twoPhaseTxs {
+                switch entry.State {
+                case "preparing":
+                        if csc.hasVoted(entry, entry.FromShard, false) || csc.hasVoted(entry, entry.ToShard, false) {
+                                csc.decideTwoPhase(entry, "aborting", "participant voted no", now)
+                        } else if entry.Votes[entry.FromShard] && entry.Votes[entry.ToShard] {
+                                csc.decideTwoPhase(entry, "committing", "", now)
+                        } else if now.After(entry.Deadline) {
+                                csc.decideTwoPhase(entry, "aborting", "prepare timeout", now)
+                        } else {
+                                // Ask shards that have not voted yet again
+                                for _, shardID := range []int{entry.FromShard, entry.ToShard} {
+                                        if _, voted := entry.Votes[shardID]; !voted {
+                                                sends = append(sends, pendingSend{entry, MessageTypePrepare, shardID})
+                                        }
+                                }
+                                continue
+                        }
+
+                        if entry.State == "committing" {
+                                sends = append(sends, pendingSend{entry, MessageTypeCommit, entry.ToShard})
+                        } else {
+                                sends = append(sends, pendingSend{entry, MessageTypeAbort, entry.FromShard})
+                        }
+                case "committing":
+                        sends = append(sends, pendingSend{entry, MessageTypeCommit, entry.ToShard})
+                case "aborting":
+                        sends = append(sends, pendingSend{entry, MessageTypeAbort, entry.FromShard})
+                case "aborted":
+                        // A debit already in a block when the abort arrived
+                        // commits afterwards; refund it as soon as it does
+                        if err := csc.releaseEscrow(entry); err != nil {
+                                csc.logger.LogError("cross_shard", "2pc_release_escrow", err, logrus.Fields{
+                                        "tx_id":     txID,
+                                        "timestamp": now.UTC(),
+                                })
+                        }
+                        fallthrough
+                default:
+                        // Keep finished transactions around briefly for inspection
+                        if entry.DecidedAt != nil && now.Sub(*entry.DecidedAt) > 10*time.Minute {
+                                delete(sm.twoPhaseTxs, txID)
+                        }
+                }
+        }
+        sm.mu.Unlock()
+
+        // Send outside the sync manager lock; handlers acquire it
+        for _, send := range sends {
+                csc.sendTwoPhaseMessage(send.entry, send.messageType, send.shardID)
+        }
+}
+
+// hasVoted reports whether shardID has cast the given vote for entry
+func (csc *CrossShardCommunicator) hasVoted(entry *TwoPhaseTransaction, shardID int, vote bool) bool {
+        cast, voted := entry.Votes[shardID]
+        return voted && cast == vote
+}
+
+// decideTwoPhase records the coordinator's decision for entry
+func (csc *CrossShardCommunicator) decideTwoPhase(entry *TwoPhaseTransaction, state, reason string, now time.Time) {
+        entry.State = state
+        entry.Reason = reason
+        entry.DecidedAt = &now
+
+        csc.logger.LogCrossShard(entry.FromShard, entry.ToShard, "2pc_decision", logrus.Fields{
+                "tx_id":     entry.TxID,
+                "trace_id":  entry.Transaction.TraceID,
+                "decision":  state,
+                "reason":    reason,
+                "votes":     entry.Votes,
+                "timestamp": now.UTC(),
+        })
+}
+
+// GetTwoPhaseTransactions returns a snapshot of tracked two-phase commits
+func (csc *CrossShardCommunicator) GetTwoPhaseTransactions() map[string]*TwoPhaseTransaction {
+        csc.syncManager.mu.RLock()
+        defer csc.syncManager.mu.RUnlock()
+
+        snapshot := make(map[string]*TwoPhaseTransaction, len(csc.syncManager.twoPhaseTxs))
+        for txID, entry := range csc.syncManager.twoPhaseTxs {
+                entryCopy := *entry
+                entryCopy.Votes = make(map[int]bool, len(entry.Votes))
+                for shardID, vote := range entry.Votes {
+                        entryCopy.Votes[shardID] = vote
+                }
+                snapshot[txID] = &entryCopy
+        }
+
+        return snapshot
+}
+
+// countInFlightTwoPhase returns the number of undecided or unapplied two-phase
+// commits. Caller must hold the sync manager lock.
+func (sm *CrossShardSyncManager) countInFlightTwoPhase() int {
+        inFlight := 0
+        for _, entry := range sm.twoPhaseTxs {
+                if !entry.isFinal() {
+                        inFlight++
+                }
+        }
+        return inFlight
+}
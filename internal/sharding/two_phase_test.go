@@ -0,0 +1,166 @@
+package sharding
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/internal/blockchain"
+	"lscc-blockchain/internal/utils"
+	"lscc-blockchain/pkg/types"
+)
+
+// newFundedTransfer returns a shard manager whose genesis credits a new
+// account with balance, and a signed cross-shard transfer of amount from it
+func newFundedTransfer(t *testing.T, balance, amount int64) (*ShardManager, *types.Transaction) {
+	t.Helper()
+	key, err := utils.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := utils.SigningKeyToAddress(key.PubKey())
+	var to string
+	for to == "" || utils.GenerateShardKey(to, 4) == utils.GenerateShardKey(from, 4) {
+		other, err := utils.GenerateSigningKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		to = utils.SigningKeyToAddress(other.PubKey())
+	}
+
+	sm := newTestShardManager(t, func(cfg *config.Config) {
+		data, err := json.Marshal(map[string]interface{}{
+			"chain_id":   cfg.Network.ChainID,
+			"timestamp":  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			"alloc":      map[string]int64{from: balance},
+			"validators": []interface{}{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Genesis.Path = filepath.Join(t.TempDir(), "genesis.json")
+		if err := os.WriteFile(cfg.Genesis.Path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	tx := &types.Transaction{
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Fee:       10,
+		Nonce:     1,
+		Timestamp: time.Now().UTC(),
+		Type:      "cross_shard",
+		ShardID:   utils.GenerateShardKey(from, 4),
+	}
+	tx.ID = tx.Hash()
+	if err := utils.SignTransaction(tx, key); err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return sm, tx
+}
+
+// abortedEntry registers tx with csc as decided to abort
+func abortedEntry(csc *CrossShardCommunicator, tx *types.Transaction) *TwoPhaseTransaction {
+	now := csc.clock.Now()
+	entry := &TwoPhaseTransaction{
+		TxID:        tx.ID,
+		Transaction: tx,
+		FromShard:   utils.GenerateShardKey(tx.From, 4),
+		ToShard:     utils.GenerateShardKey(tx.To, 4),
+		State:       "aborting",
+		Votes:       map[int]bool{},
+		Reason:      "prepare timeout",
+		CreatedAt:   now,
+		DecidedAt:   &now,
+	}
+	csc.syncManager.twoPhaseTxs[tx.ID] = entry
+	return entry
+}
+
+func deliverAbort(t *testing.T, csc *CrossShardCommunicator, entry *TwoPhaseTransaction) {
+	t.Helper()
+	shard, err := csc.shardManager.GetShard(entry.FromShard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := &types.CrossShardMessage{FromShard: entry.FromShard, ToShard: entry.FromShard, Type: MessageTypeAbort}
+	message.SetTransaction(entry.Transaction)
+	if err := csc.handleAbortMessage(shard, message); err != nil {
+		t.Fatalf("failed to handle abort: %v", err)
+	}
+	if entry.State != "aborted" {
+		t.Fatalf("state = %s after abort", entry.State)
+	}
+}
+
+func commitTransfer(t *testing.T, sm *ShardManager, tx *types.Transaction) {
+	t.Helper()
+	bc := sm.blockchain
+	bm := blockchain.NewBlockManager(sm.logger, 200000000, 100, 1<<20, bc.GetLatestBlock().ChainID)
+	block, err := bm.CreateBlock(bc.GetLatestBlock(), []*types.Transaction{tx}, "validator_0", 0)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+}
+
+func TestAbortDropsUncommittedDebitFromPool(t *testing.T) {
+	sm, tx := newFundedTransfer(t, 1000, 100)
+	bc := sm.blockchain
+	if err := bc.SubmitTransaction(tx); err != nil {
+		t.Fatalf("failed to submit: %v", err)
+	}
+
+	csc := NewCrossShardCommunicator(sm, sm.logger)
+	deliverAbort(t, csc, abortedEntry(csc, tx))
+
+	for _, pending := range bc.GetPendingTransactions() {
+		if pending.ID == tx.ID {
+			t.Fatal("aborted transfer still pending")
+		}
+	}
+	receipt, err := bc.GetTransactionReceipt(tx.ID)
+	if err != nil {
+		t.Fatalf("no receipt: %v", err)
+	}
+	if receipt.Status != "failed" {
+		t.Fatalf("receipt %+v", receipt)
+	}
+	if balance := bc.GetBalance(tx.From); balance != 1000 {
+		t.Fatalf("sender balance = %d, want 1000", balance)
+	}
+}
+
+func TestLateEscrowRefundedAfterAbort(t *testing.T) {
+	sm, tx := newFundedTransfer(t, 1000, 100)
+	bc := sm.blockchain
+
+	csc := NewCrossShardCommunicator(sm, sm.logger)
+	entry := abortedEntry(csc, tx)
+	deliverAbort(t, csc, entry)
+
+	// The debit was already in a block when the abort arrived
+	commitTransfer(t, sm, tx)
+	if _, err := bc.GetPendingCrossShardTransfer(tx.ID); err != nil {
+		t.Fatalf("no escrow after the debit committed: %v", err)
+	}
+
+	csc.coordinateTwoPhaseCommits()
+	if _, err := bc.GetPendingCrossShardTransfer(tx.ID); err == nil {
+		t.Fatal("escrow of an aborted transfer kept")
+	}
+	// Only the fee is lost
+	if balance := bc.GetBalance(tx.From); balance != 990 {
+		t.Fatalf("sender balance = %d, want 990", balance)
+	}
+	if balance := bc.GetBalance(tx.To); balance != 0 {
+		t.Fatalf("recipient balance = %d, want 0", balance)
+	}
+}
@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"container/list"
+	"sync"
+)
+
+// AccountCacheStats describes the account cache in front of a database
+type AccountCacheStats struct {
+	Capacity      int     `json:"capacity"`
+	Entries       int     `json:"entries"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	Evictions     int64   `json:"evictions"`     // entries dropped to make room
+	Invalidations int64   `json:"invalidations"` // entries dropped because their account was written
+	HitRatio      float64 `json:"hit_ratio"`     // hits over all lookups, 0 before the first
+}
+
+// CachedDB is a Database whose account balance and nonce lookups are served
+// from a least-recently-used cache of at most size accounts, reading through
+// to the database on a miss. Every write to an account, whether through
+// SaveAccountBalance, SaveAccountNonce or a batch committing a block or
+// rolling one back, drops the cached entry once it reaches the database, so
+// the cache never returns a value the database no longer holds.
+type CachedDB struct {
+	Database
+
+	mu      sync.Mutex
+	size    int
+	order   *list.List               // most recently used first
+	entries map[string]*list.Element // storage key -> element holding an accountEntry
+	version uint64                   // bumped by every invalidation, so a read that raced one is not cached
+
+	hits, misses, evictions, invalidations int64
+}
+
+// accountEntry is one cached account value under its storage key
+type accountEntry struct {
+	key   string
+	value int64
+}
+
+// NewCachedDB puts an account cache of size entries in front of db
+func NewCachedDB(db Database, size int) *CachedDB {
+	if size < 1 {
+		size = 1
+	}
+	return &CachedDB{
+		Database: db,
+		size:     size,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// GetAccountBalance returns the balance of an address, from the cache when
+// it holds one
+func (c *CachedDB) GetAccountBalance(address string) (int64, error) {
+	return c.readThrough(accountBalanceKey(address), func() (int64, error) {
+		return c.Database.GetAccountBalance(address)
+	})
+}
+
+// GetAccountNonce returns the last committed nonce for an address, from the
+// cache when it holds one
+func (c *CachedDB) GetAccountNonce(address string) (int64, error) {
+	return c.readThrough(accountNonceKey(address), func() (int64, error) {
+		return c.Database.GetAccountNonce(address)
+	})
+}
+
+// SaveAccountBalance writes a balance and drops the cached one
+func (c *CachedDB) SaveAccountBalance(address string, balance int64) error {
+	defer c.invalidate(accountBalanceKey(address))
+	return c.Database.SaveAccountBalance(address, balance)
+}
+
+// SaveAccountNonce writes a nonce and drops the cached one
+func (c *CachedDB) SaveAccountNonce(address string, nonce int64) error {
+	defer c.invalidate(accountNonceKey(address))
+	return c.Database.SaveAccountNonce(address, nonce)
+}
+
+// WriteBatch applies ops and drops the cached entries of the accounts they
+// write. Entries are dropped even when the batch fails, as the database may
+// not say how much of it was applied.
+func (c *CachedDB) WriteBatch(ops []WriteOp) error {
+	keys := make([]string, len(ops))
+	for i, op := range ops {
+		keys[i] = string(op.Key)
+	}
+	defer c.invalidate(keys...)
+	return c.Database.WriteBatch(ops)
+}
+
+// NewBatch returns a batch that drops the cached entries of the keys it
+// wrote once it commits
+func (c *CachedDB) NewBatch() Batch {
+	return &cachedBatch{Batch: c.Database.NewBatch(), cache: c}
+}
+
+// AccountCacheStats returns the cache's size and hit counts
+func (c *CachedDB) AccountCacheStats() AccountCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := AccountCacheStats{
+		Capacity:      c.size,
+		Entries:       len(c.entries),
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Evictions:     c.evictions,
+		Invalidations: c.invalidations,
+	}
+	if lookups := c.hits + c.misses; lookups > 0 {
+		stats.HitRatio = float64(c.hits) / float64(lookups)
+	}
+	return stats
+}
+
+// readThrough returns the cached value under key, or loads it and caches it
+// unless an invalidation happened while it was being read
+func (c *CachedDB) readThrough(key string, load func() (int64, error)) (int64, error) {
+	c.mu.Lock()
+	if element, exists := c.entries[key]; exists {
+		c.order.MoveToFront(element)
+		c.hits++
+		value := element.Value.(*accountEntry).value
+		c.mu.Unlock()
+		return value, nil
+	}
+	c.misses++
+	version := c.version
+	c.mu.Unlock()
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version == version {
+		c.storeLocked(key, value)
+	}
+	return value, nil
+}
+
+// storeLocked caches value under key, evicting the least recently used
+// entry when full. Caller must hold c.mu.
+func (c *CachedDB) storeLocked(key string, value int64) {
+	if element, exists := c.entries[key]; exists {
+		element.Value.(*accountEntry).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*accountEntry).key)
+		c.evictions++
+	}
+	c.entries[key] = c.order.PushFront(&accountEntry{key: key, value: value})
+}
+
+// invalidate drops the cached entries under keys
+func (c *CachedDB) invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	for _, key := range keys {
+		if element, exists := c.entries[key]; exists {
+			c.order.Remove(element)
+			delete(c.entries, key)
+			c.invalidations++
+		}
+	}
+}
+
+// cachedBatch records the keys a batch writes so their cached entries can
+// be dropped when it commits
+type cachedBatch struct {
+	Batch
+	cache *CachedDB
+	keys  []string
+}
+
+func (b *cachedBatch) Set(key []byte, value []byte) error {
+	b.keys = append(b.keys, string(key))
+	return b.Batch.Set(key, value)
+}
+
+func (b *cachedBatch) Delete(key []byte) error {
+	b.keys = append(b.keys, string(key))
+	return b.Batch.Delete(key)
+}
+
+func (b *cachedBatch) Commit() error {
+	defer b.cache.invalidate(b.keys...)
+	return b.Batch.Commit()
+}
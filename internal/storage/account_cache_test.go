@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"lscc-blockchain/config"
+)
+
+// countingDB is a memory database that counts the account reads reaching it
+type countingDB struct {
+	*MemoryDB
+	balanceReads, nonceReads int64
+}
+
+func (db *countingDB) GetAccountBalance(address string) (int64, error) {
+	atomic.AddInt64(&db.balanceReads, 1)
+	return db.MemoryDB.GetAccountBalance(address)
+}
+
+func (db *countingDB) GetAccountNonce(address string) (int64, error) {
+	atomic.AddInt64(&db.nonceReads, 1)
+	return db.MemoryDB.GetAccountNonce(address)
+}
+
+func newCountingCache(t *testing.T, size int) (*CachedDB, *countingDB) {
+	t.Helper()
+	db := &countingDB{MemoryDB: NewMemoryDB()}
+	cache := NewCachedDB(db, size)
+	t.Cleanup(func() { cache.Close() })
+	return cache, db
+}
+
+// expectBalance reads address through cache, checking the balance and how
+// many reads have reached the database in all
+func expectBalance(t *testing.T, cache *CachedDB, db *countingDB, address string, want, wantReads int64) {
+	t.Helper()
+	got, err := cache.GetAccountBalance(address)
+	if err != nil {
+		t.Fatalf("failed to read balance of %s: %v", address, err)
+	}
+	if got != want {
+		t.Fatalf("balance of %s = %d, want %d", address, got, want)
+	}
+	if reads := atomic.LoadInt64(&db.balanceReads); reads != wantReads {
+		t.Fatalf("after reading %s: %d database reads, want %d", address, reads, wantReads)
+	}
+}
+
+func TestAccountCacheHitAvoidsDatabaseRead(t *testing.T) {
+	cache, db := newCountingCache(t, 10)
+	if err := cache.SaveAccountBalance("alice", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.SaveAccountNonce("alice", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	expectBalance(t, cache, db, "alice", 100, 1)
+	expectBalance(t, cache, db, "alice", 100, 1)
+	expectBalance(t, cache, db, "alice", 100, 1)
+
+	// Balances and nonces are cached separately
+	for i := 0; i < 2; i++ {
+		if nonce, err := cache.GetAccountNonce("alice"); err != nil || nonce != 3 {
+			t.Fatalf("nonce = %d, %v, want 3", nonce, err)
+		}
+	}
+	if reads := atomic.LoadInt64(&db.nonceReads); reads != 1 {
+		t.Fatalf("%d nonce reads reached the database, want 1", reads)
+	}
+
+	stats := cache.AccountCacheStats()
+	if stats.Hits != 3 || stats.Misses != 2 || stats.Entries != 2 || stats.Capacity != 10 {
+		t.Fatalf("stats %+v", stats)
+	}
+	if stats.HitRatio != 0.6 {
+		t.Fatalf("hit ratio = %v, want 0.6", stats.HitRatio)
+	}
+}
+
+func TestAccountCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, db := newCountingCache(t, 2)
+	for address, balance := range map[string]int64{"alice": 1, "bob": 2, "carol": 3} {
+		if err := cache.SaveAccountBalance(address, balance); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectBalance(t, cache, db, "alice", 1, 1)
+	expectBalance(t, cache, db, "bob", 2, 2)
+	expectBalance(t, cache, db, "alice", 1, 2)
+	// Carol pushes out bob, the least recently used
+	expectBalance(t, cache, db, "carol", 3, 3)
+	expectBalance(t, cache, db, "alice", 1, 3)
+	expectBalance(t, cache, db, "bob", 2, 4)
+
+	if stats := cache.AccountCacheStats(); stats.Entries != 2 || stats.Evictions != 2 {
+		t.Fatalf("stats %+v", stats)
+	}
+}
+
+func TestAccountCacheInvalidatedByCommitAndRollback(t *testing.T) {
+	cache, db := newCountingCache(t, 10)
+	if err := cache.SaveAccountBalance("alice", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.SaveAccountBalance("bob", 50); err != nil {
+		t.Fatal(err)
+	}
+	expectBalance(t, cache, db, "alice", 100, 1)
+	expectBalance(t, cache, db, "bob", 50, 2)
+
+	// Committing a block writes alice's balance in a batch
+	commit := NewWriteSet()
+	if err := commit.SetAccountBalance("alice", 70); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.WriteBatch(commit.Ops()); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	expectBalance(t, cache, db, "alice", 70, 3)
+	// Bob was not written, so his entry stays
+	expectBalance(t, cache, db, "bob", 50, 3)
+
+	// Rolling the block back restores the old balance
+	rollback := NewWriteSet()
+	if err := rollback.SetAccountBalance("alice", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.WriteBatch(rollback.Ops()); err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+	expectBalance(t, cache, db, "alice", 100, 4)
+
+	// A batch drops entries only once it commits
+	batch := cache.NewBatch()
+	if err := batch.Set([]byte(accountBalanceKey("bob")), []byte("80")); err != nil {
+		t.Fatal(err)
+	}
+	expectBalance(t, cache, db, "bob", 50, 4)
+	if err := batch.Commit(); err != nil {
+		t.Fatalf("failed to commit batch: %v", err)
+	}
+	expectBalance(t, cache, db, "bob", 80, 5)
+
+	if stats := cache.AccountCacheStats(); stats.Invalidations != 3 {
+		t.Fatalf("stats %+v, want 3 invalidations", stats)
+	}
+}
+
+func TestNewDatabaseCachesAccounts(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Storage.Backend = "badger"
+	cfg.Storage.DataDir = t.TempDir()
+	cfg.Storage.AccountCacheSize = 5
+	db, err := NewDatabase(cfg)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	cache, ok := db.(*CachedDB)
+	if !ok {
+		t.Fatalf("badger with an account cache is %T", db)
+	}
+	if capacity := cache.AccountCacheStats().Capacity; capacity != 5 {
+		t.Fatalf("cache capacity = %d, want 5", capacity)
+	}
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"lscc-blockchain/pkg/types"
+)
+
+// WriteOp is a single key write or delete applied by Database.WriteBatch
+type WriteOp struct {
+	Key    []byte
+	Value  []byte
+	Delete bool
+}
+
+// WriteSet accumulates writes to be committed atomically with
+// Database.WriteBatch. Reads through AccountBalance and State see the
+// set's own uncommitted writes. Writing a key twice keeps only the last
+// write.
+type WriteSet struct {
+	ops   []WriteOp
+	index map[string]int // key -> position in ops
+}
+
+// NewWriteSet creates an empty write set
+func NewWriteSet() *WriteSet {
+	return &WriteSet{index: make(map[string]int)}
+}
+
+// Set records a write of value to key
+func (ws *WriteSet) Set(key string, value []byte) {
+	ws.put(WriteOp{Key: []byte(key), Value: value})
+}
+
+// Delete records a delete of key
+func (ws *WriteSet) Delete(key string) {
+	ws.put(WriteOp{Key: []byte(key), Delete: true})
+}
+
+func (ws *WriteSet) put(op WriteOp) {
+	if i, exists := ws.index[string(op.Key)]; exists {
+		ws.ops[i] = op
+		return
+	}
+	ws.index[string(op.Key)] = len(ws.ops)
+	ws.ops = append(ws.ops, op)
+}
+
+// Get returns the pending write for key. found is false when the set does
+// not touch key; deleted is true when the pending write is a delete.
+func (ws *WriteSet) Get(key string) (value []byte, deleted bool, found bool) {
+	i, exists := ws.index[key]
+	if !exists {
+		return nil, false, false
+	}
+	return ws.ops[i].Value, ws.ops[i].Delete, true
+}
+
+func (ws *WriteSet) setJSON(key string, value interface{}, what string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", what, err)
+	}
+	ws.Set(key, data)
+	return nil
+}
+
+// PutBlock records a block with its index entry and makes it the latest block
+func (ws *WriteSet) PutBlock(block *types.Block) error {
+	if err := ws.setJSON(blockHashKey(block.Hash), block, "block"); err != nil {
+		return err
+	}
+	ws.Set(blockIndexKey(block.Index), []byte(block.Hash))
+	ws.Set(latestBlockKey, []byte(block.Hash))
+	return nil
+}
+
+// RemoveBlock undoes PutBlock's index entry for block and makes the block
+// with hash parentHash the latest block. The block itself is kept, as it may
+// rejoin the main chain.
+func (ws *WriteSet) RemoveBlock(block *types.Block, parentHash string) {
+	ws.Delete(blockIndexKey(block.Index))
+	ws.Set(latestBlockKey, []byte(parentHash))
+}
+
+// PutTransaction records a transaction with its sender and recipient indexes
+func (ws *WriteSet) PutTransaction(tx *types.Transaction) error {
+	if err := ws.setJSON(transactionKey(tx.ID), tx, "transaction"); err != nil {
+		return err
+	}
+	ws.Set(transactionFromKey(tx.From, tx.ID), []byte(tx.ID))
+	ws.Set(transactionToKey(tx.To, tx.ID), []byte(tx.ID))
+	return nil
+}
+
+// IndexTransaction records the lookup entries for a transaction committed
+// in the block with the given hash and height: one from the transaction ID
+// to the block, and one per participating address ordered by height
+func (ws *WriteSet) IndexTransaction(tx *types.Transaction, blockHash string, height int64) {
+	ws.Set(txIndexHashKey(tx.ID), []byte(blockHash))
+	for _, address := range txIndexAddresses(tx) {
+		ws.Set(txIndexAddressKey(address, height, tx.ID), []byte(tx.ID))
+	}
+}
+
+// UnindexTransaction removes the entries written by IndexTransaction
+func (ws *WriteSet) UnindexTransaction(tx *types.Transaction, height int64) {
+	ws.Delete(txIndexHashKey(tx.ID))
+	for _, address := range txIndexAddresses(tx) {
+		ws.Delete(txIndexAddressKey(address, height, tx.ID))
+	}
+}
+
+// txIndexAddresses returns the distinct non-empty addresses a transaction touches
+func txIndexAddresses(tx *types.Transaction) []string {
+	addresses := make([]string, 0, 2)
+	if tx.From != "" {
+		addresses = append(addresses, tx.From)
+	}
+	if tx.To != "" && tx.To != tx.From {
+		addresses = append(addresses, tx.To)
+	}
+	return addresses
+}
+
+// SetAccountNonce records the last committed nonce for an address
+func (ws *WriteSet) SetAccountNonce(address string, nonce int64) error {
+	return ws.setJSON(accountNonceKey(address), nonce, "account nonce")
+}
+
+// SetAccountBalance records the balance of an address
+func (ws *WriteSet) SetAccountBalance(address string, balance int64) error {
+	return ws.setJSON(accountBalanceKey(address), balance, "account balance")
+}
+
+// AccountBalance returns the pending balance of an address, if the set
+// writes one
+func (ws *WriteSet) AccountBalance(address string) (int64, bool) {
+	data, deleted, found := ws.Get(accountBalanceKey(address))
+	if !found || deleted {
+		return 0, false
+	}
+	var balance int64
+	if err := json.Unmarshal(data, &balance); err != nil {
+		return 0, false
+	}
+	return balance, true
+}
+
+// SetState records a state value under key, as Database.SaveState would
+func (ws *WriteSet) SetState(key string, value interface{}) error {
+	return ws.setJSON(stateKey(key), value, "state")
+}
+
+// DeleteState records removal of a state value, as Database.DeleteState would
+func (ws *WriteSet) DeleteState(key string) {
+	ws.Delete(stateKey(key))
+}
+
+// State decodes the pending state value under key into value. found is false
+// when the set does not touch key; deleted is true when it removes it.
+func (ws *WriteSet) State(key string, value interface{}) (deleted bool, found bool, err error) {
+	data, deleted, found := ws.Get(stateKey(key))
+	if !found || deleted {
+		return deleted, found, nil
+	}
+	return false, true, json.Unmarshal(data, value)
+}
+
+// Ops returns the accumulated writes in the order their keys were first written
+func (ws *WriteSet) Ops() []WriteOp {
+	return ws.ops
+}
+
+// Len returns the number of distinct keys written
+func (ws *WriteSet) Len() int {
+	return len(ws.ops)
+}
+
+// Key layout shared by every backend
+
+const latestBlockKey = "block:latest"
+
+func blockHashKey(hash string) string {
+	return fmt.Sprintf("block:hash:%s", hash)
+}
+
+func blockIndexKey(index int64) string {
+	return fmt.Sprintf("block:index:%d", index)
+}
+
+func transactionKey(txID string) string {
+	return fmt.Sprintf("tx:%s", txID)
+}
+
+func transactionFromKey(address, txID string) string {
+	return fmt.Sprintf("tx:from:%s:%s", address, txID)
+}
+
+func transactionToKey(address, txID string) string {
+	return fmt.Sprintf("tx:to:%s:%s", address, txID)
+}
+
+func txIndexHashKey(txID string) string {
+	return fmt.Sprintf("txindex/hash/%s", txID)
+}
+
+// txIndexAddressPrefix is the prefix of every address index entry for
+// address. Heights are zero-padded so keys sort in block order.
+func txIndexAddressPrefix(address string) string {
+	return fmt.Sprintf("txindex/addr/%s/", address)
+}
+
+func txIndexAddressKey(address string, height int64, txID string) string {
+	return fmt.Sprintf("%s%020d/%s", txIndexAddressPrefix(address), height, txID)
+}
+
+func accountNonceKey(address string) string {
+	return fmt.Sprintf("account:nonce:%s", address)
+}
+
+func accountBalanceKey(address string) string {
+	return fmt.Sprintf("account:balance:%s", address)
+}
+
+func stateKey(key string) string {
+	return fmt.Sprintf("state:%s", key)
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"lscc-blockchain/config"
 	"lscc-blockchain/pkg/types"
 	"time"
 
@@ -19,16 +21,21 @@ type Database interface {
 	GetBlock(hash string) (*types.Block, error)
 	GetBlockByIndex(index int64) (*types.Block, error)
 	GetLatestBlock() (*types.Block, error)
+	DeleteBlockIndex(index int64) error
+	SetLatestBlock(hash string) error
 	
 	// Transaction operations
 	SaveTransaction(tx *types.Transaction) error
 	GetTransaction(txID string) (*types.Transaction, error)
 	GetTransactionsByAddress(address string) ([]*types.Transaction, error)
+	GetTransactionBlockHash(txID string) (string, error)
+	GetTransactionIDsByAddress(address string, limit int) ([]string, error)
 	
 	// Validator operations
 	SaveValidator(validator *types.Validator) error
 	GetValidator(address string) (*types.Validator, error)
 	GetAllValidators() ([]*types.Validator, error)
+	DeleteValidator(address string) error
 	
 	// Shard operations
 	SaveShard(shard *types.Shard) error
@@ -40,12 +47,22 @@ type Database interface {
 	GetState(key string, value interface{}) error
 	DeleteState(key string) error
 	
+	// Account operations
+	SaveAccountNonce(address string, nonce int64) error
+	GetAccountNonce(address string) (int64, error)
+	SaveAccountBalance(address string, balance int64) error
+	GetAccountBalance(address string) (int64, error)
+	
 	// Metrics operations
 	SaveMetric(key string, value interface{}) error
 	GetMetric(key string, value interface{}) error
 	
 	// Batch operations
 	NewBatch() Batch
+	WriteBatch(ops []WriteOp) error
+	
+	// Snapshot operations
+	Backup(w io.Writer) error
 }
 
 // Batch interface for atomic operations
@@ -66,6 +83,22 @@ type BadgerBatch struct {
 	txn *badger.Txn
 }
 
+// NewDatabase opens the storage backend selected by cfg.Storage.Backend
+func NewDatabase(cfg *config.Config) (Database, error) {
+	switch cfg.Storage.Backend {
+	case "", "badger":
+		db, err := NewBadgerDB(cfg.Storage.DataDir)
+		if err != nil || cfg.Storage.AccountCacheSize <= 0 {
+			return db, err
+		}
+		return NewCachedDB(db, cfg.Storage.AccountCacheSize), nil
+	case "memory":
+		return NewMemoryDB(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Storage.Backend)
+	}
+}
+
 // NewBadgerDB creates a new BadgerDB instance
 func NewBadgerDB(dataDir string) (*BadgerDB, error) {
 	opts := badger.DefaultOptions(dataDir)
@@ -86,32 +119,11 @@ func (bdb *BadgerDB) Close() error {
 
 // Block operations
 func (bdb *BadgerDB) SaveBlock(block *types.Block) error {
-	return bdb.db.Update(func(txn *badger.Txn) error {
-		data, err := json.Marshal(block)
-		if err != nil {
-			return fmt.Errorf("failed to marshal block: %w", err)
-		}
-		
-		// Save by hash
-		hashKey := fmt.Sprintf("block:hash:%s", block.Hash)
-		if err := txn.Set([]byte(hashKey), data); err != nil {
-			return fmt.Errorf("failed to save block by hash: %w", err)
-		}
-		
-		// Save by index
-		indexKey := fmt.Sprintf("block:index:%d", block.Index)
-		if err := txn.Set([]byte(indexKey), []byte(block.Hash)); err != nil {
-			return fmt.Errorf("failed to save block index: %w", err)
-		}
-		
-		// Update latest block
-		latestKey := "block:latest"
-		if err := txn.Set([]byte(latestKey), []byte(block.Hash)); err != nil {
-			return fmt.Errorf("failed to update latest block: %w", err)
-		}
-		
-		return nil
-	})
+	ws := NewWriteSet()
+	if err := ws.PutBlock(block); err != nil {
+		return err
+	}
+	return bdb.WriteBatch(ws.Ops())
 }
 
 func (bdb *BadgerDB) GetBlock(hash string) (*types.Block, error) {
@@ -188,36 +200,37 @@ func (bdb *BadgerDB) GetLatestBlock() (*types.Block, error) {
 	return bdb.GetBlock(hash)
 }
 
-// Transaction operations
-func (bdb *BadgerDB) SaveTransaction(tx *types.Transaction) error {
+// DeleteBlockIndex removes the height -> hash mapping for index. The block
+// itself stays retrievable by hash.
+func (bdb *BadgerDB) DeleteBlockIndex(index int64) error {
 	return bdb.db.Update(func(txn *badger.Txn) error {
-		data, err := json.Marshal(tx)
-		if err != nil {
-			return fmt.Errorf("failed to marshal transaction: %w", err)
-		}
-		
-		// Save by ID
-		key := fmt.Sprintf("tx:%s", tx.ID)
-		if err := txn.Set([]byte(key), data); err != nil {
-			return fmt.Errorf("failed to save transaction: %w", err)
-		}
-		
-		// Index by from address
-		fromKey := fmt.Sprintf("tx:from:%s:%s", tx.From, tx.ID)
-		if err := txn.Set([]byte(fromKey), []byte(tx.ID)); err != nil {
-			return fmt.Errorf("failed to index transaction by from: %w", err)
+		key := fmt.Sprintf("block:index:%d", index)
+		if err := txn.Delete([]byte(key)); err != nil {
+			return fmt.Errorf("failed to delete block index: %w", err)
 		}
-		
-		// Index by to address
-		toKey := fmt.Sprintf("tx:to:%s:%s", tx.To, tx.ID)
-		if err := txn.Set([]byte(toKey), []byte(tx.ID)); err != nil {
-			return fmt.Errorf("failed to index transaction by to: %w", err)
+		return nil
+	})
+}
+
+// SetLatestBlock points the chain head at the block with the given hash
+func (bdb *BadgerDB) SetLatestBlock(hash string) error {
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set([]byte("block:latest"), []byte(hash)); err != nil {
+			return fmt.Errorf("failed to update latest block: %w", err)
 		}
-		
 		return nil
 	})
 }
 
+// Transaction operations
+func (bdb *BadgerDB) SaveTransaction(tx *types.Transaction) error {
+	ws := NewWriteSet()
+	if err := ws.PutTransaction(tx); err != nil {
+		return err
+	}
+	return bdb.WriteBatch(ws.Ops())
+}
+
 func (bdb *BadgerDB) GetTransaction(txID string) (*types.Transaction, error) {
 	var transaction *types.Transaction
 	
@@ -301,6 +314,64 @@ func (bdb *BadgerDB) GetTransactionsByAddress(address string) ([]*types.Transact
 	return transactions, err
 }
 
+// GetTransactionBlockHash returns the hash of the block a transaction was
+// committed in, from the transaction index
+func (bdb *BadgerDB) GetTransactionBlockHash(txID string) (string, error) {
+	var blockHash string
+	
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(txIndexHashKey(txID)))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return errors.New("transaction not indexed")
+			}
+			return fmt.Errorf("failed to get transaction index: %w", err)
+		}
+		
+		return item.Value(func(val []byte) error {
+			blockHash = string(val)
+			return nil
+		})
+	})
+	
+	return blockHash, err
+}
+
+// GetTransactionIDsByAddress returns the IDs of indexed transactions sent or
+// received by address, newest block first. A non-positive limit returns all.
+func (bdb *BadgerDB) GetTransactionIDsByAddress(address string, limit int) ([]string, error) {
+	var txIDs []string
+	
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		prefix := []byte(txIndexAddressPrefix(address))
+		opts := badger.DefaultIteratorOptions
+		opts.Reverse = true
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		
+		// Reverse iteration starts at the last key <= the seek key, so seek
+		// past every key under the prefix
+		seek := append(append([]byte{}, prefix...), 0xff)
+		for it.Seek(seek); it.ValidForPrefix(prefix); it.Next() {
+			if limit > 0 && len(txIDs) >= limit {
+				break
+			}
+			err := it.Item().Value(func(val []byte) error {
+				txIDs = append(txIDs, string(val))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		
+		return nil
+	})
+	
+	return txIDs, err
+}
+
 // Validator operations
 func (bdb *BadgerDB) SaveValidator(validator *types.Validator) error {
 	return bdb.db.Update(func(txn *badger.Txn) error {
@@ -335,6 +406,16 @@ func (bdb *BadgerDB) GetValidator(address string) (*types.Validator, error) {
 	return validator, err
 }
 
+func (bdb *BadgerDB) DeleteValidator(address string) error {
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		key := fmt.Sprintf("validator:%s", address)
+		if err := txn.Delete([]byte(key)); err != nil {
+			return fmt.Errorf("failed to delete validator: %w", err)
+		}
+		return nil
+	})
+}
+
 func (bdb *BadgerDB) GetAllValidators() ([]*types.Validator, error) {
 	var validators []*types.Validator
 	
@@ -468,6 +549,74 @@ func (bdb *BadgerDB) DeleteState(key string) error {
 	})
 }
 
+// Account operations
+func (bdb *BadgerDB) SaveAccountNonce(address string, nonce int64) error {
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(nonce)
+		if err != nil {
+			return fmt.Errorf("failed to marshal account nonce: %w", err)
+		}
+		
+		nonceKey := fmt.Sprintf("account:nonce:%s", address)
+		return txn.Set([]byte(nonceKey), data)
+	})
+}
+
+// GetAccountNonce returns the last committed nonce for an address, or 0 if
+// the address has never sent a transaction
+func (bdb *BadgerDB) GetAccountNonce(address string) (int64, error) {
+	var nonce int64
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		nonceKey := fmt.Sprintf("account:nonce:%s", address)
+		item, err := txn.Get([]byte(nonceKey))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return nil
+			}
+			return fmt.Errorf("failed to get account nonce: %w", err)
+		}
+		
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &nonce)
+		})
+	})
+	
+	return nonce, err
+}
+
+func (bdb *BadgerDB) SaveAccountBalance(address string, balance int64) error {
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(balance)
+		if err != nil {
+			return fmt.Errorf("failed to marshal account balance: %w", err)
+		}
+		
+		balanceKey := fmt.Sprintf("account:balance:%s", address)
+		return txn.Set([]byte(balanceKey), data)
+	})
+}
+
+// GetAccountBalance returns the balance of an address, or 0 for unknown addresses
+func (bdb *BadgerDB) GetAccountBalance(address string) (int64, error) {
+	var balance int64
+	err := bdb.db.View(func(txn *badger.Txn) error {
+		balanceKey := fmt.Sprintf("account:balance:%s", address)
+		item, err := txn.Get([]byte(balanceKey))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return nil
+			}
+			return fmt.Errorf("failed to get account balance: %w", err)
+		}
+		
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &balance)
+		})
+	})
+	
+	return balance, err
+}
+
 // Metrics operations
 func (bdb *BadgerDB) SaveMetric(key string, value interface{}) error {
 	data := map[string]interface{}{
@@ -498,6 +647,14 @@ func (bdb *BadgerDB) GetMetric(key string, value interface{}) error {
 	return errors.New("metric value not found")
 }
 
+// Snapshot operations
+
+// Backup writes a full snapshot of the database to w
+func (bdb *BadgerDB) Backup(w io.Writer) error {
+	_, err := bdb.db.Backup(w, 0)
+	return err
+}
+
 // Batch operations
 func (bdb *BadgerDB) NewBatch() Batch {
 	return &BadgerBatch{
@@ -505,6 +662,25 @@ func (bdb *BadgerDB) NewBatch() Batch {
 	}
 }
 
+// WriteBatch applies every op in a single transaction, so either all of
+// them are persisted or none are
+func (bdb *BadgerDB) WriteBatch(ops []WriteOp) error {
+	return bdb.db.Update(func(txn *badger.Txn) error {
+		for _, op := range ops {
+			var err error
+			if op.Delete {
+				err = txn.Delete(op.Key)
+			} else {
+				err = txn.Set(op.Key, op.Value)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", op.Key, err)
+			}
+		}
+		return nil
+	})
+}
+
 func (bb *BadgerBatch) Set(key []byte, value []byte) error {
 	return bb.txn.Set(key, value)
 }
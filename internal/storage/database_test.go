@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	"lscc-blockchain/config"
+	"lscc-blockchain/pkg/types"
+)
+
+// backends returns a fresh instance of each storage backend, closed when the
+// test ends
+func backends(t *testing.T) map[string]Database {
+	t.Helper()
+	badgerDB, err := NewBadgerDB(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to open badger: %v", err)
+	}
+	dbs := map[string]Database{"memory": NewMemoryDB(), "badger": badgerDB}
+	for _, db := range dbs {
+		db := db
+		t.Cleanup(func() { db.Close() })
+	}
+	return dbs
+}
+
+func TestBackendsStoreBlocks(t *testing.T) {
+	for name, db := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			block := &types.Block{Index: 1, PreviousHash: "parent", Validator: "validator", Transactions: []*types.Transaction{}}
+			block.Hash = block.ComputeHash()
+			if err := db.SaveBlock(block); err != nil {
+				t.Fatalf("failed to save block: %v", err)
+			}
+			if err := db.SetLatestBlock(block.Hash); err != nil {
+				t.Fatalf("failed to set latest block: %v", err)
+			}
+
+			byHash, err := db.GetBlock(block.Hash)
+			if err != nil || byHash.Hash != block.Hash {
+				t.Fatalf("GetBlock = %v, %v", byHash, err)
+			}
+			byIndex, err := db.GetBlockByIndex(1)
+			if err != nil || byIndex.Hash != block.Hash {
+				t.Fatalf("GetBlockByIndex = %v, %v", byIndex, err)
+			}
+			latest, err := db.GetLatestBlock()
+			if err != nil || latest.Hash != block.Hash {
+				t.Fatalf("GetLatestBlock = %v, %v", latest, err)
+			}
+
+			if err := db.DeleteBlockIndex(1); err != nil {
+				t.Fatalf("failed to delete block index: %v", err)
+			}
+			if _, err := db.GetBlockByIndex(1); err == nil {
+				t.Fatal("block still found by a deleted index")
+			}
+			if _, err := db.GetBlock("missing"); err == nil {
+				t.Fatal("missing block found")
+			}
+		})
+	}
+}
+
+func TestBackendsStoreTransactions(t *testing.T) {
+	for name, db := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			tx := &types.Transaction{From: "alice", To: "bob", Amount: 5, Nonce: 1, Type: "regular"}
+			tx.ID = tx.Hash()
+			if err := db.SaveTransaction(tx); err != nil {
+				t.Fatalf("failed to save transaction: %v", err)
+			}
+
+			stored, err := db.GetTransaction(tx.ID)
+			if err != nil || stored.Amount != 5 {
+				t.Fatalf("GetTransaction = %v, %v", stored, err)
+			}
+			for _, address := range []string{"alice", "bob"} {
+				txs, err := db.GetTransactionsByAddress(address)
+				if err != nil || len(txs) != 1 || txs[0].ID != tx.ID {
+					t.Fatalf("GetTransactionsByAddress(%s) = %v, %v", address, txs, err)
+				}
+			}
+			if _, err := db.GetTransaction("missing"); err == nil {
+				t.Fatal("missing transaction found")
+			}
+		})
+	}
+}
+
+func TestBackendsStoreValidatorsStateAndAccounts(t *testing.T) {
+	for name, db := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, address := range []string{"v1", "v2"} {
+				if err := db.SaveValidator(&types.Validator{Address: address, Stake: 100}); err != nil {
+					t.Fatalf("failed to save validator: %v", err)
+				}
+			}
+			if err := db.DeleteValidator("v1"); err != nil {
+				t.Fatalf("failed to delete validator: %v", err)
+			}
+			validators, err := db.GetAllValidators()
+			if err != nil || len(validators) != 1 || validators[0].Address != "v2" {
+				t.Fatalf("GetAllValidators = %v, %v", validators, err)
+			}
+
+			if err := db.SaveState("height", int64(7)); err != nil {
+				t.Fatalf("failed to save state: %v", err)
+			}
+			var height int64
+			if err := db.GetState("height", &height); err != nil || height != 7 {
+				t.Fatalf("GetState = %d, %v", height, err)
+			}
+			if err := db.DeleteState("height"); err != nil {
+				t.Fatalf("failed to delete state: %v", err)
+			}
+			if err := db.GetState("height", &height); err == nil {
+				t.Fatal("deleted state still found")
+			}
+
+			if nonce, err := db.GetAccountNonce("nobody"); err != nil || nonce != 0 {
+				t.Fatalf("nonce of an unknown account = %d, %v", nonce, err)
+			}
+			if err := db.SaveAccountNonce("alice", 3); err != nil {
+				t.Fatalf("failed to save nonce: %v", err)
+			}
+			if err := db.SaveAccountBalance("alice", 250); err != nil {
+				t.Fatalf("failed to save balance: %v", err)
+			}
+			if nonce, err := db.GetAccountNonce("alice"); err != nil || nonce != 3 {
+				t.Fatalf("nonce = %d, %v", nonce, err)
+			}
+			if balance, err := db.GetAccountBalance("alice"); err != nil || balance != 250 {
+				t.Fatalf("balance = %d, %v", balance, err)
+			}
+		})
+	}
+}
+
+func TestNewDatabaseSelectsBackend(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Storage.Backend = "memory"
+	db, err := NewDatabase(cfg)
+	if err != nil {
+		t.Fatalf("memory backend: %v", err)
+	}
+	if _, ok := db.(*MemoryDB); !ok {
+		t.Fatalf("memory backend is %T", db)
+	}
+
+	cfg.Storage.Backend = "badger"
+	cfg.Storage.DataDir = t.TempDir()
+	db, err = NewDatabase(cfg)
+	if err != nil {
+		t.Fatalf("badger backend: %v", err)
+	}
+	defer db.Close()
+	if _, ok := db.(*BadgerDB); !ok {
+		t.Fatalf("badger backend without a cache is %T", db)
+	}
+
+	cfg.Storage.Backend = "etcd"
+	if _, err := NewDatabase(cfg); err == nil {
+		t.Fatal("unknown backend accepted")
+	}
+}
+
+// blockWrites returns the writes committing a block of n transfers: the
+// block, each transaction with its indexes, and the sender and recipient
+// balances and nonces
+func blockWrites(b *testing.B, n int) []WriteOp {
+	b.Helper()
+	txs := make([]*types.Transaction, n)
+	for i := range txs {
+		txs[i] = &types.Transaction{
+			ID:     fmt.Sprintf("tx-%d", i),
+			From:   fmt.Sprintf("sender-%d", i),
+			To:     fmt.Sprintf("recipient-%d", i),
+			Amount: 100,
+			Fee:    1,
+			Nonce:  1,
+			Type:   "transfer",
+		}
+	}
+	block := &types.Block{Index: 1, PreviousHash: "parent", Validator: "validator", Transactions: txs}
+	block.Hash = block.ComputeHash()
+
+	ws := NewWriteSet()
+	if err := ws.PutBlock(block); err != nil {
+		b.Fatal(err)
+	}
+	for _, tx := range txs {
+		if err := ws.PutTransaction(tx); err != nil {
+			b.Fatal(err)
+		}
+		ws.IndexTransaction(tx, block.Hash, block.Index)
+		if err := ws.SetAccountBalance(tx.From, 899); err != nil {
+			b.Fatal(err)
+		}
+		if err := ws.SetAccountNonce(tx.From, tx.Nonce); err != nil {
+			b.Fatal(err)
+		}
+		if err := ws.SetAccountBalance(tx.To, 100); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return ws.Ops()
+}
+
+func openBenchmarkBadger(b *testing.B) *BadgerDB {
+	b.Helper()
+	db, err := NewBadgerDB(b.TempDir())
+	if err != nil {
+		b.Fatalf("failed to open badger: %v", err)
+	}
+	b.Cleanup(func() { db.Close() })
+	return db
+}
+
+// BenchmarkBlockCommitPerKey commits a 1000-transaction block with one
+// badger transaction per key, as blocks were written before batching
+func BenchmarkBlockCommitPerKey(b *testing.B) {
+	db := openBenchmarkBadger(b)
+	ops := blockWrites(b, 1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, op := range ops {
+			if err := db.WriteBatch([]WriteOp{op}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkBlockCommitBatch commits the same block in a single batch
+func BenchmarkBlockCommitBatch(b *testing.B) {
+	db := openBenchmarkBadger(b)
+	ops := blockWrites(b, 1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.WriteBatch(ops); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
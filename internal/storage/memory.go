@@ -0,0 +1,473 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lscc-blockchain/pkg/types"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryDB implements Database interface in memory. It uses the same key
+// layout and JSON encoding as BadgerDB but keeps nothing on disk, so it is
+// suited to tests and short-lived nodes.
+type MemoryDB struct {
+	data   map[string][]byte
+	mu     sync.RWMutex
+	closed bool
+}
+
+// MemoryBatch implements Batch interface for MemoryDB
+type MemoryBatch struct {
+	db      *MemoryDB
+	sets    map[string][]byte
+	deletes map[string]bool
+}
+
+// NewMemoryDB creates an empty in-memory database
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{data: make(map[string][]byte)}
+}
+
+// Close releases the stored data
+func (mdb *MemoryDB) Close() error {
+	mdb.mu.Lock()
+	defer mdb.mu.Unlock()
+	mdb.closed = true
+	mdb.data = make(map[string][]byte)
+	return nil
+}
+
+func (mdb *MemoryDB) set(key string, value []byte) error {
+	mdb.mu.Lock()
+	defer mdb.mu.Unlock()
+	if mdb.closed {
+		return errors.New("database is closed")
+	}
+	stored := make([]byte, len(value))
+	copy(stored, value)
+	mdb.data[key] = stored
+	return nil
+}
+
+func (mdb *MemoryDB) setJSON(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", strings.SplitN(key, ":", 2)[0], err)
+	}
+	return mdb.set(key, data)
+}
+
+func (mdb *MemoryDB) get(key string) ([]byte, bool, error) {
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
+	if mdb.closed {
+		return nil, false, errors.New("database is closed")
+	}
+	value, exists := mdb.data[key]
+	return value, exists, nil
+}
+
+func (mdb *MemoryDB) delete(key string) error {
+	mdb.mu.Lock()
+	defer mdb.mu.Unlock()
+	if mdb.closed {
+		return errors.New("database is closed")
+	}
+	delete(mdb.data, key)
+	return nil
+}
+
+// scanPrefix returns the values of all keys starting with prefix, in key order
+func (mdb *MemoryDB) scanPrefix(prefix string) ([][]byte, error) {
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
+	if mdb.closed {
+		return nil, errors.New("database is closed")
+	}
+
+	keys := make([]string, 0)
+	for key := range mdb.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = mdb.data[key]
+	}
+	return values, nil
+}
+
+// Block operations
+func (mdb *MemoryDB) SaveBlock(block *types.Block) error {
+	ws := NewWriteSet()
+	if err := ws.PutBlock(block); err != nil {
+		return err
+	}
+	return mdb.WriteBatch(ws.Ops())
+}
+
+func (mdb *MemoryDB) GetBlock(hash string) (*types.Block, error) {
+	data, exists, err := mdb.get(fmt.Sprintf("block:hash:%s", hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("block not found")
+	}
+
+	var block *types.Block
+	err = json.Unmarshal(data, &block)
+	return block, err
+}
+
+func (mdb *MemoryDB) GetBlockByIndex(index int64) (*types.Block, error) {
+	hash, exists, err := mdb.get(fmt.Sprintf("block:index:%d", index))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block index: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("block not found")
+	}
+	return mdb.GetBlock(string(hash))
+}
+
+func (mdb *MemoryDB) GetLatestBlock() (*types.Block, error) {
+	hash, exists, err := mdb.get("block:latest")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("no blocks found")
+	}
+	return mdb.GetBlock(string(hash))
+}
+
+// DeleteBlockIndex removes the height -> hash mapping for index. The block
+// itself stays retrievable by hash.
+func (mdb *MemoryDB) DeleteBlockIndex(index int64) error {
+	return mdb.delete(fmt.Sprintf("block:index:%d", index))
+}
+
+// SetLatestBlock points the chain head at the block with the given hash
+func (mdb *MemoryDB) SetLatestBlock(hash string) error {
+	return mdb.set("block:latest", []byte(hash))
+}
+
+// Transaction operations
+func (mdb *MemoryDB) SaveTransaction(tx *types.Transaction) error {
+	ws := NewWriteSet()
+	if err := ws.PutTransaction(tx); err != nil {
+		return err
+	}
+	return mdb.WriteBatch(ws.Ops())
+}
+
+func (mdb *MemoryDB) GetTransaction(txID string) (*types.Transaction, error) {
+	data, exists, err := mdb.get(fmt.Sprintf("tx:%s", txID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("transaction not found")
+	}
+
+	var transaction *types.Transaction
+	err = json.Unmarshal(data, &transaction)
+	return transaction, err
+}
+
+func (mdb *MemoryDB) GetTransactionsByAddress(address string) ([]*types.Transaction, error) {
+	var transactions []*types.Transaction
+	seen := make(map[string]bool)
+
+	for _, prefix := range []string{fmt.Sprintf("tx:from:%s:", address), fmt.Sprintf("tx:to:%s:", address)} {
+		ids, err := mdb.scanPrefix(prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			txID := string(id)
+			if seen[txID] {
+				continue
+			}
+			seen[txID] = true
+
+			tx, err := mdb.GetTransaction(txID)
+			if err != nil {
+				return nil, err
+			}
+			transactions = append(transactions, tx)
+		}
+	}
+
+	return transactions, nil
+}
+
+// GetTransactionBlockHash returns the hash of the block a transaction was
+// committed in, from the transaction index
+func (mdb *MemoryDB) GetTransactionBlockHash(txID string) (string, error) {
+	data, exists, err := mdb.get(txIndexHashKey(txID))
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction index: %w", err)
+	}
+	if !exists {
+		return "", errors.New("transaction not indexed")
+	}
+	return string(data), nil
+}
+
+// GetTransactionIDsByAddress returns the IDs of indexed transactions sent or
+// received by address, newest block first. A non-positive limit returns all.
+func (mdb *MemoryDB) GetTransactionIDsByAddress(address string, limit int) ([]string, error) {
+	values, err := mdb.scanPrefix(txIndexAddressPrefix(address))
+	if err != nil {
+		return nil, err
+	}
+
+	txIDs := make([]string, 0, len(values))
+	for i := len(values) - 1; i >= 0; i-- {
+		if limit > 0 && len(txIDs) >= limit {
+			break
+		}
+		txIDs = append(txIDs, string(values[i]))
+	}
+	return txIDs, nil
+}
+
+// Validator operations
+func (mdb *MemoryDB) SaveValidator(validator *types.Validator) error {
+	return mdb.setJSON(fmt.Sprintf("validator:%s", validator.Address), validator)
+}
+
+func (mdb *MemoryDB) GetValidator(address string) (*types.Validator, error) {
+	data, exists, err := mdb.get(fmt.Sprintf("validator:%s", address))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get validator: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("validator not found")
+	}
+
+	var validator *types.Validator
+	err = json.Unmarshal(data, &validator)
+	return validator, err
+}
+
+func (mdb *MemoryDB) DeleteValidator(address string) error {
+	return mdb.delete(fmt.Sprintf("validator:%s", address))
+}
+
+func (mdb *MemoryDB) GetAllValidators() ([]*types.Validator, error) {
+	values, err := mdb.scanPrefix("validator:")
+	if err != nil {
+		return nil, err
+	}
+
+	var validators []*types.Validator
+	for _, data := range values {
+		var validator *types.Validator
+		if err := json.Unmarshal(data, &validator); err != nil {
+			return nil, err
+		}
+		validators = append(validators, validator)
+	}
+	return validators, nil
+}
+
+// Shard operations
+func (mdb *MemoryDB) SaveShard(shard *types.Shard) error {
+	return mdb.setJSON(fmt.Sprintf("shard:%d", shard.ID), shard)
+}
+
+func (mdb *MemoryDB) GetShard(shardID int) (*types.Shard, error) {
+	data, exists, err := mdb.get(fmt.Sprintf("shard:%d", shardID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shard: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("shard not found")
+	}
+
+	var shard *types.Shard
+	err = json.Unmarshal(data, &shard)
+	return shard, err
+}
+
+func (mdb *MemoryDB) GetAllShards() ([]*types.Shard, error) {
+	values, err := mdb.scanPrefix("shard:")
+	if err != nil {
+		return nil, err
+	}
+
+	var shards []*types.Shard
+	for _, data := range values {
+		var shard *types.Shard
+		if err := json.Unmarshal(data, &shard); err != nil {
+			return nil, err
+		}
+		shards = append(shards, shard)
+	}
+	return shards, nil
+}
+
+// State operations
+func (mdb *MemoryDB) SaveState(key string, value interface{}) error {
+	return mdb.setJSON(fmt.Sprintf("state:%s", key), value)
+}
+
+func (mdb *MemoryDB) GetState(key string, value interface{}) error {
+	data, exists, err := mdb.get(fmt.Sprintf("state:%s", key))
+	if err != nil {
+		return fmt.Errorf("failed to get state: %w", err)
+	}
+	if !exists {
+		return errors.New("state not found")
+	}
+	return json.Unmarshal(data, value)
+}
+
+func (mdb *MemoryDB) DeleteState(key string) error {
+	return mdb.delete(fmt.Sprintf("state:%s", key))
+}
+
+// Account operations
+func (mdb *MemoryDB) SaveAccountNonce(address string, nonce int64) error {
+	return mdb.setJSON(fmt.Sprintf("account:nonce:%s", address), nonce)
+}
+
+// GetAccountNonce returns the last committed nonce for an address, or 0 if
+// the address has never sent a transaction
+func (mdb *MemoryDB) GetAccountNonce(address string) (int64, error) {
+	var nonce int64
+	data, exists, err := mdb.get(fmt.Sprintf("account:nonce:%s", address))
+	if err != nil || !exists {
+		return 0, err
+	}
+	err = json.Unmarshal(data, &nonce)
+	return nonce, err
+}
+
+func (mdb *MemoryDB) SaveAccountBalance(address string, balance int64) error {
+	return mdb.setJSON(fmt.Sprintf("account:balance:%s", address), balance)
+}
+
+// GetAccountBalance returns the balance of an address, or 0 for unknown addresses
+func (mdb *MemoryDB) GetAccountBalance(address string) (int64, error) {
+	var balance int64
+	data, exists, err := mdb.get(fmt.Sprintf("account:balance:%s", address))
+	if err != nil || !exists {
+		return 0, err
+	}
+	err = json.Unmarshal(data, &balance)
+	return balance, err
+}
+
+// Metrics operations
+func (mdb *MemoryDB) SaveMetric(key string, value interface{}) error {
+	data := map[string]interface{}{
+		"value":     value,
+		"timestamp": time.Now().UTC(),
+	}
+
+	return mdb.SaveState(fmt.Sprintf("metric:%s", key), data)
+}
+
+func (mdb *MemoryDB) GetMetric(key string, value interface{}) error {
+	var data map[string]json.RawMessage
+	if err := mdb.GetState(fmt.Sprintf("metric:%s", key), &data); err != nil {
+		return err
+	}
+
+	raw, ok := data["value"]
+	if !ok {
+		return errors.New("metric value not found")
+	}
+	return json.Unmarshal(raw, value)
+}
+
+// Batch operations
+func (mdb *MemoryDB) NewBatch() Batch {
+	return &MemoryBatch{
+		db:      mdb,
+		sets:    make(map[string][]byte),
+		deletes: make(map[string]bool),
+	}
+}
+
+// WriteBatch applies every op under a single lock, so readers never see a
+// partial batch
+func (mdb *MemoryDB) WriteBatch(ops []WriteOp) error {
+	mdb.mu.Lock()
+	defer mdb.mu.Unlock()
+	if mdb.closed {
+		return errors.New("database is closed")
+	}
+
+	for _, op := range ops {
+		if op.Delete {
+			delete(mdb.data, string(op.Key))
+			continue
+		}
+		stored := make([]byte, len(op.Value))
+		copy(stored, op.Value)
+		mdb.data[string(op.Key)] = stored
+	}
+	return nil
+}
+
+func (mb *MemoryBatch) Set(key []byte, value []byte) error {
+	stored := make([]byte, len(value))
+	copy(stored, value)
+	mb.sets[string(key)] = stored
+	delete(mb.deletes, string(key))
+	return nil
+}
+
+func (mb *MemoryBatch) Delete(key []byte) error {
+	delete(mb.sets, string(key))
+	mb.deletes[string(key)] = true
+	return nil
+}
+
+// Commit applies every buffered write at once
+func (mb *MemoryBatch) Commit() error {
+	mb.db.mu.Lock()
+	defer mb.db.mu.Unlock()
+	if mb.db.closed {
+		return errors.New("database is closed")
+	}
+
+	for key, value := range mb.sets {
+		mb.db.data[key] = value
+	}
+	for key := range mb.deletes {
+		delete(mb.db.data, key)
+	}
+	mb.Cancel()
+	return nil
+}
+
+func (mb *MemoryBatch) Cancel() {
+	mb.sets = make(map[string][]byte)
+	mb.deletes = make(map[string]bool)
+}
+
+// Snapshot operations
+
+// Backup writes every key and value to w as a JSON object
+func (mdb *MemoryDB) Backup(w io.Writer) error {
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
+	if mdb.closed {
+		return errors.New("database is closed")
+	}
+	return json.NewEncoder(w).Encode(mdb.data)
+}
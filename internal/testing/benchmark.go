@@ -301,20 +301,7 @@ func (bs *BenchmarkSuite) initializeConsensus(algorithm string, validatorCount i
         // Generate test validators (not used in current implementation)
         _ = bs.generateTestValidators(validatorCount, byzantineRatio)
         
-        switch algorithm {
-        case "pbft":
-                return consensus.NewPBFT(bs.config, bs.logger)  
-        case "ppbft":
-                return consensus.NewPracticalPBFT(bs.config, bs.logger)
-        case "pos":
-                return consensus.NewPBFT(bs.config, bs.logger) // Use PBFT as PoS placeholder
-        case "pow":
-                return consensus.NewPBFT(bs.config, bs.logger) // Use PBFT as PoW placeholder
-        case "lscc":
-                return consensus.NewLSCC(bs.config, bs.logger)
-        default:
-                return nil, fmt.Errorf("unsupported consensus algorithm: %s", algorithm)
-        }
+        return consensus.New(algorithm, bs.config, bs.logger)
 }
 
 // generateTestTransactions creates a set of test transactions with specified cross-shard ratio
@@ -370,8 +357,8 @@ func (bs *BenchmarkSuite) processTransactionWithConsensus(consensusEngine consen
                 Timestamp:    time.Now().UTC(),
                 Transactions: []*types.Transaction{tx},
                 ShardID:      tx.ShardID,
-                Hash:         utils.HashString(fmt.Sprintf("block_%s_%d", tx.ID, time.Now().UnixNano())),
         }
+        block.Hash = block.ComputeHash()
         
         // Track message count (simplified estimation)
         messageCount := int64(1) // Base message count
@@ -4,11 +4,13 @@ import (
         "context"
         "fmt"
         "lscc-blockchain/internal/blockchain"
+        "lscc-blockchain/internal/utils"
         "lscc-blockchain/pkg/types"
         "math/rand"
         "sync"
         "time"
 
+        "github.com/decred/dcrd/dcrec/secp256k1/v4"
         "github.com/sirupsen/logrus"
 )
 
@@ -18,6 +20,7 @@ type TransactionGenerator struct {
         running    bool
         mutex      sync.RWMutex
         stats      TransactionStats
+        keys       []*secp256k1.PrivateKey // Signing keys of the generated sender accounts
 }
 
 type TransactionStats struct {
@@ -32,14 +35,37 @@ type TransactionStats struct {
 }
 
 func NewTransactionGenerator(bc *blockchain.Blockchain, logger *logrus.Logger) *TransactionGenerator {
+        // Generate a fixed set of accounts so transactions can be properly signed
+        keys := make([]*secp256k1.PrivateKey, 0, 10)
+        for i := 0; i < 10; i++ {
+                key, err := utils.GenerateSigningKey()
+                if err != nil {
+                        logger.Error("Failed to generate signing key", logrus.Fields{
+                                "error": err,
+                        })
+                        continue
+                }
+                keys = append(keys, key)
+        }
+
         return &TransactionGenerator{
                 blockchain: bc,
                 logger:     logger,
                 running:    false,
                 stats:      TransactionStats{},
+                keys:       keys,
         }
 }
 
+// GetAccountAddresses returns the addresses of the accounts the generator sends from
+func (tg *TransactionGenerator) GetAccountAddresses() []string {
+        addresses := make([]string, len(tg.keys))
+        for i, key := range tg.keys {
+                addresses[i] = utils.SigningKeyToAddress(key.PubKey())
+        }
+        return addresses
+}
+
 func (tg *TransactionGenerator) StartTransactionStream(ctx context.Context, tpsRate float64) error {
         tg.mutex.Lock()
         defer tg.mutex.Unlock()
@@ -130,27 +156,17 @@ func (tg *TransactionGenerator) generateTransactionStream(ctx context.Context, t
 }
 
 func (tg *TransactionGenerator) generateRandomTransaction() *types.Transaction {
-        // Generate realistic transaction data with proper Ethereum-style addresses
-        addresses := []string{
-                "0x1234567890abcdef1234567890abcdef12345678",
-                "0x2345678901bcdef12345678901bcdef123456789", 
-                "0x3456789012cdef123456789012cdef1234567890",
-                "0x456789013def1234567890123def12345678901a",
-                "0x56789014ef123456789014ef1234567890123abc",
-                "0x6789015f23456789015f23456789015f23456def",
-                "0x789016023456789016023456789016023456789a",
-                "0x89017123456789017123456789017123456789ab", 
-                "0x9018234567890182345678901823456789012abc",
-                "0xa019345678901934567890193456789012345bcd",
-        }
-
-        fromAddr := addresses[rand.Intn(len(addresses))]
-        toAddr := addresses[rand.Intn(len(addresses))]
+        // Pick distinct sender and receiver from the generator's signing accounts
+        addresses := tg.GetAccountAddresses()
+        fromIndex := rand.Intn(len(addresses))
+        toIndex := rand.Intn(len(addresses))
         
         // Ensure from and to are different
-        for toAddr == fromAddr {
-                toAddr = addresses[rand.Intn(len(addresses))]
+        for toIndex == fromIndex {
+                toIndex = rand.Intn(len(addresses))
         }
+        fromAddr := addresses[fromIndex]
+        toAddr := addresses[toIndex]
 
         // Generate realistic amounts (0.1 to 100 units)
         amount := float64(rand.Intn(1000)+1) / 10.0
@@ -161,20 +177,45 @@ func (tg *TransactionGenerator) generateRandomTransaction() *types.Transaction {
                 To:        toAddr,
                 Amount:    int64(amount * 100), // Convert to smallest unit (e.g., wei)
                 Fee:       int64(rand.Intn(50)+10), // Gas fee
-                Nonce:     rand.Int63n(1000),
+                Nonce:     tg.blockchain.GetNextNonce(fromAddr),
                 Timestamp: time.Now(),
                 Data:      []byte(fmt.Sprintf("transfer_%d", rand.Intn(1000))),
                 Type:      "regular",
                 ShardID:   rand.Intn(4), // Distribute across shards 0-3 (matching config)
-                Signature: "mock_signature_for_testing_purposes_1234567890abcdef", // Add required signature
         }
         
         // Recalculate ID to match hash after setting all fields
         tx.ID = tx.Hash()
+        tg.signTransaction(tx, tg.keys[fromIndex])
         
         return tx
 }
 
+// signTransaction signs tx with key, logging failures so the unsigned
+// transaction is rejected on submission
+func (tg *TransactionGenerator) signTransaction(tx *types.Transaction, key *secp256k1.PrivateKey) {
+        if key == nil {
+                return
+        }
+        if err := utils.SignTransaction(tx, key); err != nil {
+                tg.logger.Debug("Transaction signing failed",
+                        logrus.Fields{
+                                "tx_id": tx.ID,
+                                "error": err,
+                        })
+        }
+}
+
+// keyFor returns the signing key of a generator account
+func (tg *TransactionGenerator) keyFor(address string) *secp256k1.PrivateKey {
+        for _, key := range tg.keys {
+                if utils.SigningKeyToAddress(key.PubKey()) == address {
+                        return key
+                }
+        }
+        return nil
+}
+
 func (tg *TransactionGenerator) updateAverageLatency(newLatency float64) {
         // Simple exponential moving average
         alpha := 0.1
@@ -270,8 +311,16 @@ func (tg *TransactionGenerator) BatchGenerate(count int) ([]*types.Transaction,
                         "timestamp": time.Now().UTC(),
                 })
 
+        // Transactions in the batch are not yet in the pool, so later ones from
+        // the same sender must continue the nonce sequence themselves
+        batchNonces := make(map[string]int64)
         for i := 0; i < count; i++ {
-                transactions[i] = tg.generateRandomTransaction()
+                tx := tg.generateRandomTransaction()
+                tx.Nonce += batchNonces[tx.From]
+                tx.ID = tx.Hash()
+                tg.signTransaction(tx, tg.keyFor(tx.From))
+                batchNonces[tx.From]++
+                transactions[i] = tx
         }
 
         return transactions, nil
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and schedules ticks for components that need to run
+// against a controlled clock, such as the consensus engines and cross-shard
+// communicator under test
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	NewTicker(d time.Duration) Ticker
+	After(d time.Duration) <-chan time.Time
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is the wall clock
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time { return t.ticker.C }
+func (t systemTicker) Stop()               { t.ticker.Stop() }
+
+// ManualClock is a Clock that only moves when told to. Tickers and timers
+// created from it fire during Advance, in the order their deadlines fall,
+// so a test can drive timeouts without sleeping. Like time.Ticker, a ticker
+// whose last tick has not been received drops the ticks that follow.
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*manualWaiter
+}
+
+// manualWaiter is a ticker, or a one-shot timer when period is zero
+type manualWaiter struct {
+	clock  *ManualClock
+	next   time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+// NewManualClock returns a ManualClock reading start
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the clock's current time
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed on the clock since t
+func (c *ManualClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// NewTicker returns a ticker firing every d of clock time. It panics if d is
+// not positive, as time.NewTicker does.
+func (c *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("utils: non-positive interval for ManualClock.NewTicker")
+	}
+	return c.addWaiter(d, d)
+}
+
+// After returns a channel receiving the clock's time once d has passed on it
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	return c.addWaiter(d, 0).ch
+}
+
+func (c *ManualClock) addWaiter(d, period time.Duration) *manualWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &manualWaiter{clock: c, next: c.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	c.waiters = append(c.waiters, w)
+	return w
+}
+
+// Waiters returns how many tickers and pending timers the clock holds, so a
+// test can wait for the goroutines it drives to have set theirs up
+func (c *ManualClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// Advance moves the clock forward by d, firing every ticker and timer whose
+// deadline is reached on the way
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		due := make([]*manualWaiter, 0)
+		for _, w := range c.waiters {
+			if !w.next.After(end) {
+				due = append(due, w)
+			}
+		}
+		if len(due) == 0 {
+			break
+		}
+		sort.SliceStable(due, func(i, j int) bool { return due[i].next.Before(due[j].next) })
+
+		w := due[0]
+		c.now = w.next
+		select {
+		case w.ch <- c.now:
+		default:
+		}
+		if w.period > 0 {
+			w.next = w.next.Add(w.period)
+		} else {
+			c.removeLocked(w)
+		}
+	}
+	c.now = end
+}
+
+func (c *ManualClock) removeLocked(w *manualWaiter) {
+	for i, waiter := range c.waiters {
+		if waiter == w {
+			c.waiters = append(c.waiters[:i:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (w *manualWaiter) C() <-chan time.Time { return w.ch }
+
+// Stop removes the ticker from its clock
+func (w *manualWaiter) Stop() {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	w.clock.removeLocked(w)
+}
@@ -9,6 +9,7 @@ import (
         "errors"
         "fmt"
         "math/big"
+        "strings"
 
         "golang.org/x/crypto/ripemd160"
 )
@@ -207,13 +208,52 @@ func HashDifficulty(hash string, difficulty int) bool {
         return true
 }
 
-// GenerateShardKey generates a deterministic shard key from address
+// Shard key errors
+var (
+        ErrEmptyShardAddress = errors.New("shard key address is empty")
+        ErrInvalidShardCount = errors.New("shard count must be positive")
+)
+
+// GenerateShardKey generates a deterministic shard key from address. The
+// result is always in [0, numShards); an empty address or a shard count
+// below one maps to shard 0.
 func GenerateShardKey(address string, numShards int) int {
-        hash := sha256.Sum256([]byte(address))
+        shard, err := ShardKeyWithError(address, numShards)
+        if err != nil {
+                return 0
+        }
+        return shard
+}
+
+// ShardKeyWithError is GenerateShardKey for callers that need to tell an
+// invalid input apart from a genuine assignment to shard 0. Any non-empty
+// string is a valid address: the key is the SHA-256 of its bytes, except that
+// 0x-prefixed hex addresses are lowercased first so checksummed and plain
+// spellings of the same address land on the same shard.
+func ShardKeyWithError(address string, numShards int) (int, error) {
+        if numShards <= 0 {
+                return 0, fmt.Errorf("%w: %d", ErrInvalidShardCount, numShards)
+        }
+        if address == "" {
+                return 0, ErrEmptyShardAddress
+        }
+
+        hash := sha256.Sum256([]byte(normalizeShardAddress(address)))
         key := big.NewInt(0).SetBytes(hash[:])
         mod := big.NewInt(int64(numShards))
         result := big.NewInt(0).Mod(key, mod)
-        return int(result.Int64())
+        return int(result.Int64()), nil
+}
+
+// normalizeShardAddress lowercases 0x-prefixed hex addresses and leaves
+// anything else untouched
+func normalizeShardAddress(address string) string {
+        if len(address) > 2 && (address[:2] == "0x" || address[:2] == "0X") {
+                if _, err := hex.DecodeString(address[2:]); err == nil {
+                        return "0x" + strings.ToLower(address[2:])
+                }
+        }
+        return address
 }
 
 // EncryptData encrypts data using AES (placeholder for actual implementation)
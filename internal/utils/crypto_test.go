@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestShardKeyEdgeCases(t *testing.T) {
+	tests := []struct {
+		name      string
+		address   string
+		numShards int
+		wantErr   error
+	}{
+		{"empty address", "", 4, ErrEmptyShardAddress},
+		{"zero shards", "0xabc", 0, ErrInvalidShardCount},
+		{"negative shards", "0xabc", -3, ErrInvalidShardCount},
+		{"empty address and no shards", "", 0, ErrInvalidShardCount},
+		{"non-hex address", "not-an-address", 4, nil},
+		{"bare prefix", "0x", 4, nil},
+		{"single shard", "0xabc", 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shard, err := ShardKeyWithError(tt.address, tt.numShards)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && shard != 0 {
+				t.Fatalf("shard = %d alongside an error, want 0", shard)
+			}
+			if err == nil && (shard < 0 || shard >= tt.numShards) {
+				t.Fatalf("shard %d outside [0, %d)", shard, tt.numShards)
+			}
+			// GenerateShardKey never fails: invalid input lands on shard 0
+			if got := GenerateShardKey(tt.address, tt.numShards); got != shard {
+				t.Fatalf("GenerateShardKey = %d, ShardKeyWithError = %d", got, shard)
+			}
+		})
+	}
+}
+
+func TestShardKeyIgnoresHexCase(t *testing.T) {
+	plain := "0x52908400098527886e0f7030069857d2e4169ee7"
+	for _, spelling := range []string{
+		"0x52908400098527886E0F7030069857D2E4169EE7",
+		"0X52908400098527886e0f7030069857d2e4169ee7",
+	} {
+		if got, want := GenerateShardKey(spelling, 64), GenerateShardKey(plain, 64); got != want {
+			t.Fatalf("%s on shard %d, %s on %d", spelling, got, plain, want)
+		}
+	}
+	// Case still matters outside hex addresses
+	if GenerateShardKey("Validator", 1<<20) == GenerateShardKey("validator", 1<<20) {
+		t.Fatal("non-hex addresses differing in case mapped together")
+	}
+}
+
+func TestShardKeyIsDeterministic(t *testing.T) {
+	address := "0xdeadbeef"
+	want := GenerateShardKey(address, 16)
+	for i := 0; i < 100; i++ {
+		if got := GenerateShardKey(address, 16); got != want {
+			t.Fatalf("call %d: shard %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestShardKeyDistributionIsUniform(t *testing.T) {
+	const (
+		shards    = 16
+		addresses = 32000
+		// Chi-square critical value for 15 degrees of freedom at p = 0.001
+		critical = 37.70
+	)
+	rng := rand.New(rand.NewSource(1))
+	counts := make([]int, shards)
+	raw := make([]byte, 20)
+	for i := 0; i < addresses; i++ {
+		rng.Read(raw)
+		counts[GenerateShardKey("0x"+hex.EncodeToString(raw), shards)]++
+	}
+
+	expected := float64(addresses) / shards
+	chiSquare := 0.0
+	for _, observed := range counts {
+		diff := float64(observed) - expected
+		chiSquare += diff * diff / expected
+	}
+	if chiSquare > critical {
+		t.Fatalf("chi-square %.2f exceeds %.2f: counts %v", chiSquare, critical, counts)
+	}
+}
@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"fmt"
 	"io"
+	"lscc-blockchain/config"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -13,36 +16,99 @@ import (
 // Logger wraps logrus.Logger with additional functionality
 type Logger struct {
 	*logrus.Logger
+	traces *TraceRecorder // recent log lines by correlation ID, nil when disabled
 }
 
-// NewLogger creates a new logger instance
+// Log formats accepted by NewLoggerWithConfig
+const (
+	LogFormatJSON = "json" // one JSON object per line, for log aggregation
+	LogFormatText = "text" // logfmt-style key=value lines, for reading locally
+)
+
+// NewLogger creates a new logger instance at the level and in the format set
+// by the LOG_LEVEL and LOG_FORMAT environment variables, falling back to info
+// and JSON when they are unset or invalid
 func NewLogger() *Logger {
-	logger := logrus.New()
-	
-	// Set formatter
-	logger.SetFormatter(&logrus.JSONFormatter{
-		TimestampFormat: time.RFC3339Nano,
-		FieldMap: logrus.FieldMap{
-			logrus.FieldKeyTime:  "timestamp",
-			logrus.FieldKeyLevel: "level",
-			logrus.FieldKeyMsg:   "message",
-		},
-	})
-	
-	// Set log level from environment
-	level := os.Getenv("LSCC_LOG_LEVEL")
-	switch level {
+	level, err := ParseLogLevel(logSetting("LOG_LEVEL", "LSCC_LOG_LEVEL", ""))
+	if err != nil {
+		level = logrus.InfoLevel
+	}
+	formatter, err := newFormatter(logSetting("LOG_FORMAT", "LSCC_LOG_FORMAT", ""))
+	if err != nil {
+		formatter, _ = newFormatter(LogFormatJSON)
+	}
+	return newLogger(level, formatter)
+}
+
+// NewLoggerWithConfig creates a logger at cfg.Level in cfg.Format. The
+// LOG_LEVEL and LOG_FORMAT environment variables take precedence over the
+// configuration; an empty level or format means info or JSON. It fails on a
+// level or format it does not recognise.
+func NewLoggerWithConfig(cfg config.LoggingConfig) (*Logger, error) {
+	level, err := ParseLogLevel(logSetting("LOG_LEVEL", "LSCC_LOG_LEVEL", cfg.Level))
+	if err != nil {
+		return nil, err
+	}
+	formatter, err := newFormatter(logSetting("LOG_FORMAT", "LSCC_LOG_FORMAT", cfg.Format))
+	if err != nil {
+		return nil, err
+	}
+	return newLogger(level, formatter), nil
+}
+
+// ParseLogLevel returns the logrus level named by level: debug, info, warn
+// or error. An empty level is info.
+func ParseLogLevel(level string) (logrus.Level, error) {
+	switch strings.ToLower(level) {
 	case "debug":
-		logger.SetLevel(logrus.DebugLevel)
-	case "info":
-		logger.SetLevel(logrus.InfoLevel)
-	case "warn":
-		logger.SetLevel(logrus.WarnLevel)
+		return logrus.DebugLevel, nil
+	case "", "info":
+		return logrus.InfoLevel, nil
+	case "warn", "warning":
+		return logrus.WarnLevel, nil
 	case "error":
-		logger.SetLevel(logrus.ErrorLevel)
+		return logrus.ErrorLevel, nil
+	default:
+		return logrus.InfoLevel, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", level)
+	}
+}
+
+// logSetting returns the environment variable env, then the older variable
+// legacy, then fallback, whichever is set first
+func logSetting(env, legacy, fallback string) string {
+	if value := os.Getenv(env); value != "" {
+		return value
+	}
+	if value := os.Getenv(legacy); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// newFormatter returns the formatter for format, json or text. Both name
+// the time, level and message fields the same way.
+func newFormatter(format string) (logrus.Formatter, error) {
+	fieldMap := logrus.FieldMap{
+		logrus.FieldKeyTime:  "timestamp",
+		logrus.FieldKeyLevel: "level",
+		logrus.FieldKeyMsg:   "message",
+	}
+	switch strings.ToLower(format) {
+	case "", LogFormatJSON:
+		return &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano, FieldMap: fieldMap}, nil
+	case LogFormatText:
+		return &logrus.TextFormatter{TimestampFormat: time.RFC3339Nano, FullTimestamp: true, DisableColors: true, FieldMap: fieldMap}, nil
 	default:
-		logger.SetLevel(logrus.InfoLevel)
+		return nil, fmt.Errorf("unknown log format %q (want json or text)", format)
 	}
+}
+
+// newLogger creates a logger at level using formatter, writing to stdout and,
+// when LSCC_LOG_OUTPUT is "file", to a rotated file under LSCC_LOG_DIR
+func newLogger(level logrus.Level, formatter logrus.Formatter) *Logger {
+	logger := logrus.New()
+	logger.SetFormatter(formatter)
+	logger.SetLevel(level)
 	
 	// Set output
 	output := os.Getenv("LSCC_LOG_OUTPUT")
@@ -76,6 +142,19 @@ func NewLogger() *Logger {
 	return &Logger{Logger: logger}
 }
 
+// EnableTraceRecording keeps the most recent maxTraces traces, of at most
+// maxEvents log lines each, for lookup through Traces
+func (l *Logger) EnableTraceRecording(maxTraces, maxEvents int) *TraceRecorder {
+	l.traces = NewTraceRecorder(maxTraces, maxEvents)
+	l.AddHook(l.traces)
+	return l.traces
+}
+
+// Traces returns the trace recorder, nil if trace recording is disabled
+func (l *Logger) Traces() *TraceRecorder {
+	return l.traces
+}
+
 // LogBlockchain logs blockchain-specific information
 func (l *Logger) LogBlockchain(action string, fields logrus.Fields) {
 	if fields == nil {
@@ -101,6 +180,24 @@ func (l *Logger) LogConsensus(algorithm string, action string, fields logrus.Fie
 	l.WithFields(fields).Info("Consensus operation")
 }
 
+// LogConsensusVote logs a single vote, or a validator skipped while
+// collecting votes. A round logs one line per validator per phase, so these
+// are only written at debug level.
+func (l *Logger) LogConsensusVote(algorithm string, action string, fields logrus.Fields) {
+	if !l.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	if fields == nil {
+		fields = logrus.Fields{}
+	}
+	fields["component"] = "consensus"
+	fields["algorithm"] = algorithm
+	fields["action"] = action
+	fields["timestamp"] = time.Now().UTC()
+	
+	l.WithFields(fields).Debug("Consensus vote")
+}
+
 // LogSharding logs sharding-specific information
 func (l *Logger) LogSharding(shardID int, action string, fields logrus.Fields) {
 	if fields == nil {
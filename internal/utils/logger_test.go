@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"lscc-blockchain/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// clearLogEnv unsets every environment variable the logger reads its level
+// and format from
+func clearLogEnv(t *testing.T) {
+	t.Helper()
+	for _, env := range []string{"LOG_LEVEL", "LSCC_LOG_LEVEL", "LOG_FORMAT", "LSCC_LOG_FORMAT", "LSCC_LOG_OUTPUT"} {
+		t.Setenv(env, "")
+	}
+}
+
+// captureLogger returns a logger built from cfg writing into the returned
+// buffer
+func captureLogger(t *testing.T, cfg config.LoggingConfig) (*Logger, *bytes.Buffer) {
+	t.Helper()
+	logger, err := NewLoggerWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	var buf bytes.Buffer
+	logger.Logger.SetOutput(&buf)
+	return logger, &buf
+}
+
+func TestLoggerRespectsConfiguredLevel(t *testing.T) {
+	clearLogEnv(t)
+	for level, want := range map[string][]string{
+		"debug": {"debug line", "info line", "warn line", "error line"},
+		"":      {"info line", "warn line", "error line"},
+		"WARN":  {"warn line", "error line"},
+		"error": {"error line"},
+	} {
+		logger, buf := captureLogger(t, config.LoggingConfig{Level: level})
+		logger.Debug("debug line")
+		logger.Info("info line")
+		logger.Warn("warn line")
+		logger.Error("error line")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != len(want) {
+			t.Fatalf("level %q: wrote %d lines, want %d:\n%s", level, len(lines), len(want), buf.String())
+		}
+		for i, line := range lines {
+			if !strings.Contains(line, want[i]) {
+				t.Fatalf("level %q: line %d is %q, want %q", level, i, line, want[i])
+			}
+		}
+	}
+}
+
+func TestLoggerRespectsConfiguredFormat(t *testing.T) {
+	clearLogEnv(t)
+
+	logger, buf := captureLogger(t, config.LoggingConfig{Format: LogFormatJSON})
+	logger.WithField("block", 7).Info("committed")
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("json format wrote %q: %v", buf.String(), err)
+	}
+	if entry["message"] != "committed" || entry["level"] != "info" || entry["block"] != float64(7) || entry["timestamp"] == nil {
+		t.Fatalf("unexpected JSON entry %v", entry)
+	}
+
+	logger, buf = captureLogger(t, config.LoggingConfig{Format: "TEXT"})
+	logger.WithField("block", 7).Info("committed")
+	line := buf.String()
+	if json.Valid(buf.Bytes()) {
+		t.Fatalf("text format wrote JSON: %q", line)
+	}
+	for _, want := range []string{"level=info", "message=committed", "block=7", "timestamp="} {
+		if !strings.Contains(line, want) {
+			t.Fatalf("text line %q lacks %q", line, want)
+		}
+	}
+}
+
+func TestLoggerEnvironmentOverridesConfig(t *testing.T) {
+	clearLogEnv(t)
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("LOG_FORMAT", "text")
+
+	logger, buf := captureLogger(t, config.LoggingConfig{Level: "debug", Format: "json"})
+	logger.Warn("dropped")
+	logger.Error("kept")
+	if line := buf.String(); strings.Contains(line, "dropped") || !strings.Contains(line, "message=kept") {
+		t.Fatalf("expected LOG_LEVEL and LOG_FORMAT to win, got %q", line)
+	}
+
+	// The older variables still apply when the new ones are unset
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("LSCC_LOG_LEVEL", "debug")
+	if logger := NewLogger(); logger.GetLevel() != logrus.DebugLevel {
+		t.Fatalf("LSCC_LOG_LEVEL ignored: level %v", logger.GetLevel())
+	}
+}
+
+func TestLoggerRejectsUnknownSettings(t *testing.T) {
+	clearLogEnv(t)
+	for _, cfg := range []config.LoggingConfig{{Level: "verbose"}, {Format: "xml"}} {
+		if _, err := NewLoggerWithConfig(cfg); err == nil {
+			t.Fatalf("expected %+v refused", cfg)
+		}
+	}
+
+	// NewLogger has no way to report one, so it falls back to the defaults
+	t.Setenv("LOG_LEVEL", "verbose")
+	t.Setenv("LOG_FORMAT", "xml")
+	logger := NewLogger()
+	if logger.GetLevel() != logrus.InfoLevel {
+		t.Fatalf("invalid LOG_LEVEL gave level %v, want info", logger.GetLevel())
+	}
+	if _, ok := logger.Formatter.(*logrus.JSONFormatter); !ok {
+		t.Fatalf("invalid LOG_FORMAT gave %T, want JSON", logger.Formatter)
+	}
+}
+
+func TestConsensusVotesOnlyLoggedAtDebug(t *testing.T) {
+	clearLogEnv(t)
+	for level, want := range map[string]bool{"debug": true, "info": false} {
+		logger, buf := captureLogger(t, config.LoggingConfig{Level: level})
+		logger.LogConsensusVote("pbft", "prepare", nil)
+		if got := strings.Contains(buf.String(), "Consensus vote"); got != want {
+			t.Fatalf("level %s: vote logged = %v, want %v", level, got, want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+        "encoding/hex"
+        "errors"
+        "fmt"
+        "lscc-blockchain/pkg/types"
+        "strings"
+
+        "github.com/decred/dcrd/dcrec/secp256k1/v4"
+        secpecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+        "golang.org/x/crypto/sha3"
+)
+
+// GenerateSigningKey generates a new secp256k1 private key for signing transactions
+func GenerateSigningKey() (*secp256k1.PrivateKey, error) {
+        privateKey, err := secp256k1.GeneratePrivateKey()
+        if err != nil {
+                return nil, fmt.Errorf("failed to generate signing key: %w", err)
+        }
+        return privateKey, nil
+}
+
+// SigningKeyToAddress derives the Ethereum-style address (0x + 40 hex chars)
+// of a secp256k1 public key
+func SigningKeyToAddress(pubKey *secp256k1.PublicKey) string {
+        // Keccak-256 of the uncompressed key without its 0x04 prefix
+        hasher := sha3.NewLegacyKeccak256()
+        hasher.Write(pubKey.SerializeUncompressed()[1:])
+        hash := hasher.Sum(nil)
+
+        return "0x" + hex.EncodeToString(hash[12:])
+}
+
+// transactionDigest returns the canonical bytes a transaction signature covers
+func transactionDigest(tx *types.Transaction) ([]byte, error) {
+        return hex.DecodeString(tx.Hash())
+}
+
+// SignTransaction signs the canonical transaction bytes with privateKey and
+// stores the recoverable signature on the transaction
+func SignTransaction(tx *types.Transaction, privateKey *secp256k1.PrivateKey) error {
+        digest, err := transactionDigest(tx)
+        if err != nil {
+                return fmt.Errorf("failed to compute transaction digest: %w", err)
+        }
+
+        signature := secpecdsa.SignCompact(privateKey, digest, false)
+        tx.Signature = hex.EncodeToString(signature)
+        return nil
+}
+
+// RecoverSender recovers the address that signed the transaction
+func RecoverSender(tx *types.Transaction) (string, error) {
+        if tx.Signature == "" {
+                return "", errors.New("transaction is not signed")
+        }
+
+        signature, err := hex.DecodeString(tx.Signature)
+        if err != nil {
+                return "", fmt.Errorf("failed to decode signature: %w", err)
+        }
+
+        digest, err := transactionDigest(tx)
+        if err != nil {
+                return "", fmt.Errorf("failed to compute transaction digest: %w", err)
+        }
+
+        pubKey, _, err := secpecdsa.RecoverCompact(signature, digest)
+        if err != nil {
+                return "", fmt.Errorf("failed to recover signer: %w", err)
+        }
+
+        return SigningKeyToAddress(pubKey), nil
+}
+
+// VerifyTransactionSender checks that the transaction was signed by its From address
+func VerifyTransactionSender(tx *types.Transaction) error {
+        sender, err := RecoverSender(tx)
+        if err != nil {
+                return err
+        }
+
+        if !strings.EqualFold(sender, tx.From) {
+                return fmt.Errorf("signature does not match sender: signed by %s, from %s", sender, tx.From)
+        }
+
+        return nil
+}
+
+// SignDigest signs a 32-byte digest with privateKey and returns the
+// recoverable signature
+func SignDigest(privateKey *secp256k1.PrivateKey, digest []byte) string {
+        return hex.EncodeToString(secpecdsa.SignCompact(privateKey, digest, true))
+}
+
+// VerifyDigest checks that signature over digest was made with the private
+// key of pubKey
+func VerifyDigest(pubKey *secp256k1.PublicKey, digest []byte, signature string) error {
+        raw, err := hex.DecodeString(signature)
+        if err != nil {
+                return fmt.Errorf("failed to decode signature: %w", err)
+        }
+
+        signer, _, err := secpecdsa.RecoverCompact(raw, digest)
+        if err != nil {
+                return fmt.Errorf("failed to recover signer: %w", err)
+        }
+
+        if !signer.IsEqual(pubKey) {
+                return errors.New("signature was made with a different key")
+        }
+
+        return nil
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"lscc-blockchain/pkg/types"
+)
+
+// signedTestTransaction returns a transaction from the address of a new key,
+// signed with that key
+func signedTestTransaction(t *testing.T) *types.Transaction {
+	t.Helper()
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := &types.Transaction{
+		From:      SigningKeyToAddress(key.PubKey()),
+		To:        "0x000000000000000000000000000000000000beef",
+		Amount:    100,
+		Fee:       10,
+		Nonce:     1,
+		Timestamp: time.Unix(1700000000, 0).UTC(),
+		Type:      "regular",
+	}
+	if err := SignTransaction(tx, key); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	return tx
+}
+
+func TestVerifyTransactionSenderAcceptsSignedTransaction(t *testing.T) {
+	tx := signedTestTransaction(t)
+	if err := VerifyTransactionSender(tx); err != nil {
+		t.Fatalf("correctly signed transaction rejected: %v", err)
+	}
+	sender, err := RecoverSender(tx)
+	if err != nil || sender != tx.From {
+		t.Fatalf("recovered %q (%v), want %q", sender, err, tx.From)
+	}
+}
+
+func TestVerifyTransactionSenderRejectsTamperedAmount(t *testing.T) {
+	tx := signedTestTransaction(t)
+	tx.Amount = 1000000
+	if err := VerifyTransactionSender(tx); err == nil {
+		t.Fatal("transaction with a tampered amount accepted")
+	}
+}
+
+func TestVerifyTransactionSenderRejectsWrongSigner(t *testing.T) {
+	tx := signedTestTransaction(t)
+	other, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SignTransaction(tx, other); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if err := VerifyTransactionSender(tx); err == nil {
+		t.Fatal("transaction signed by another key accepted")
+	}
+}
+
+func TestVerifyTransactionSenderRejectsUnsigned(t *testing.T) {
+	tx := signedTestTransaction(t)
+	tx.Signature = ""
+	if err := VerifyTransactionSender(tx); err == nil {
+		t.Fatal("unsigned transaction accepted")
+	}
+}
+
+func TestVerifyDigestChecksSigningKey(t *testing.T) {
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("prepare|block|1"))
+	signature := SignDigest(key, digest[:])
+
+	if err := VerifyDigest(key.PubKey(), digest[:], signature); err != nil {
+		t.Fatalf("signature rejected: %v", err)
+	}
+	if err := VerifyDigest(other.PubKey(), digest[:], signature); err == nil {
+		t.Fatal("signature accepted for another key")
+	}
+	tampered := sha256.Sum256([]byte("commit|block|1"))
+	if err := VerifyDigest(key.PubKey(), tampered[:], signature); err == nil {
+		t.Fatal("signature accepted for another digest")
+	}
+	if err := VerifyDigest(key.PubKey(), digest[:], "zz"); err == nil {
+		t.Fatal("malformed signature accepted")
+	}
+}
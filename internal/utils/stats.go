@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// EWMA is an exponentially-weighted moving average. Each sample moves the
+// average by alpha of the distance to it, so older samples decay
+// geometrically instead of being discarded.
+type EWMA struct {
+	alpha       float64
+	value       float64
+	initialized bool
+	mu          sync.RWMutex
+}
+
+// NewEWMA creates an EWMA with smoothing factor alpha in (0, 1]. Out of
+// range values fall back to 0.2.
+func NewEWMA(alpha float64) *EWMA {
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.2
+	}
+	return &EWMA{alpha: alpha}
+}
+
+// Add records a sample
+func (e *EWMA) Add(sample float64) {
+	if math.IsNaN(sample) || math.IsInf(sample, 0) {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.initialized {
+		e.value = sample
+		e.initialized = true
+		return
+	}
+	e.value += e.alpha * (sample - e.value)
+}
+
+// Value returns the current average, or 0 before the first sample
+func (e *EWMA) Value() float64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.value
+}
+
+// Reset discards all samples
+func (e *EWMA) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.value = 0
+	e.initialized = false
+}
+
+// SMA is a simple moving average over the most recent samples, each weighted
+// equally
+type SMA struct {
+	samples []float64
+	next    int
+	count   int
+	sum     float64
+	mu      sync.RWMutex
+}
+
+// NewSMA creates a moving average over the last size samples
+func NewSMA(size int) *SMA {
+	if size < 1 {
+		size = 1
+	}
+	return &SMA{samples: make([]float64, size)}
+}
+
+// Add records a sample, evicting the oldest one when the window is full
+func (s *SMA) Add(sample float64) {
+	if math.IsNaN(sample) || math.IsInf(sample, 0) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.count == len(s.samples) {
+		s.sum -= s.samples[s.next]
+	} else {
+		s.count++
+	}
+	s.samples[s.next] = sample
+	s.sum += sample
+	s.next = (s.next + 1) % len(s.samples)
+}
+
+// Value returns the mean of the samples in the window, or 0 when empty
+func (s *SMA) Value() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.count == 0 {
+		return 0
+	}
+	return s.sum / float64(s.count)
+}
+
+// Reset discards all samples
+func (s *SMA) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next = 0
+	s.count = 0
+	s.sum = 0
+}
+
+// PercentileWindow keeps the most recent samples in a ring buffer and reports
+// percentiles over them
+type PercentileWindow struct {
+	samples []float64
+	next    int
+	full    bool
+	mu      sync.RWMutex
+}
+
+// NewPercentileWindow creates a window holding up to size samples
+func NewPercentileWindow(size int) *PercentileWindow {
+	if size < 1 {
+		size = 1
+	}
+	return &PercentileWindow{samples: make([]float64, size)}
+}
+
+// Add records a sample, overwriting the oldest one when the window is full
+func (w *PercentileWindow) Add(sample float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.samples[w.next] = sample
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+// Count returns the number of samples in the window
+func (w *PercentileWindow) Count() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.count()
+}
+
+// Percentiles returns the nearest-rank value for each requested percentile
+// (0-100). All results are 0 when the window is empty.
+func (w *PercentileWindow) Percentiles(percentiles ...float64) []float64 {
+	w.mu.RLock()
+	sorted := make([]float64, w.count())
+	copy(sorted, w.samples[:len(sorted)])
+	w.mu.RUnlock()
+
+	results := make([]float64, len(percentiles))
+	if len(sorted) == 0 {
+		return results
+	}
+	sort.Float64s(sorted)
+
+	for i, p := range percentiles {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		rank = MaxInt(1, MinInt(rank, len(sorted)))
+		results[i] = sorted[rank-1]
+	}
+	return results
+}
+
+// Reset discards all samples
+func (w *PercentileWindow) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.next = 0
+	w.full = false
+}
+
+func (w *PercentileWindow) count() int {
+	if w.full {
+		return len(w.samples)
+	}
+	return w.next
+}
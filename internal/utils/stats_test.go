@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEWMAFollowsKnownSequence(t *testing.T) {
+	ewma := NewEWMA(0.5)
+	if got := ewma.Value(); got != 0 {
+		t.Fatalf("empty average = %v, want 0", got)
+	}
+
+	// 10, then 20 -> 15, then 40 -> 27.5, then 0 -> 13.75
+	want := []float64{10, 15, 27.5, 13.75}
+	for i, sample := range []float64{10, 20, 40, 0} {
+		ewma.Add(sample)
+		if got := ewma.Value(); math.Abs(got-want[i]) > 1e-9 {
+			t.Fatalf("after sample %d: average = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestEWMAConvergesToSteadyInput(t *testing.T) {
+	ewma := NewEWMA(0.2)
+	ewma.Add(0)
+	for i := 0; i < 100; i++ {
+		ewma.Add(100)
+	}
+	// The distance to the input shrinks by 1-alpha per sample
+	if got, want := ewma.Value(), 100-100*math.Pow(0.8, 100); math.Abs(got-want) > 1e-6 {
+		t.Fatalf("average = %v, want %v", got, want)
+	}
+	if got := ewma.Value(); math.Abs(got-100) > 1e-6 {
+		t.Fatalf("average = %v, want converged to 100", got)
+	}
+}
+
+func TestEWMAIgnoresInvalidSamples(t *testing.T) {
+	ewma := NewEWMA(0.5)
+	ewma.Add(10)
+	ewma.Add(math.NaN())
+	ewma.Add(math.Inf(1))
+	if got := ewma.Value(); got != 10 {
+		t.Fatalf("average = %v after invalid samples, want 10", got)
+	}
+
+	ewma.Reset()
+	ewma.Add(4)
+	if got := ewma.Value(); got != 4 {
+		t.Fatalf("first sample after reset: average = %v, want 4", got)
+	}
+}
+
+func TestEWMAOutOfRangeAlphaFallsBack(t *testing.T) {
+	for _, alpha := range []float64{0, -1, 1.5} {
+		ewma := NewEWMA(alpha)
+		ewma.Add(0)
+		ewma.Add(10)
+		if got := ewma.Value(); math.Abs(got-2) > 1e-9 {
+			t.Fatalf("alpha %v: average = %v, want 2 with the 0.2 fallback", alpha, got)
+		}
+	}
+}
+
+func TestSMAMatchesWindowMean(t *testing.T) {
+	sma := NewSMA(3)
+	if got := sma.Value(); got != 0 {
+		t.Fatalf("empty average = %v, want 0", got)
+	}
+
+	// Window of 3: (2) (2+4)/2 (2+4+9)/3 (4+9+1)/3 (9+1+6)/3
+	want := []float64{2, 3, 5, 14.0 / 3, 16.0 / 3}
+	for i, sample := range []float64{2, 4, 9, 1, 6} {
+		sma.Add(sample)
+		if got := sma.Value(); math.Abs(got-want[i]) > 1e-9 {
+			t.Fatalf("after sample %d: average = %v, want %v", i, got, want[i])
+		}
+	}
+
+	sma.Reset()
+	sma.Add(7)
+	if got := sma.Value(); got != 7 {
+		t.Fatalf("first sample after reset: average = %v, want 7", got)
+	}
+}
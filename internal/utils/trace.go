@@ -0,0 +1,310 @@
+package utils
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TraceField is the log field carrying a correlation ID
+const TraceField = "trace_id"
+
+// maxTraceIDLength bounds trace IDs accepted from clients
+const maxTraceIDLength = 128
+
+type traceKey struct{}
+
+// NewTraceID returns a random correlation ID
+func NewTraceID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// ValidTraceID reports whether id is usable as a correlation ID: non-empty,
+// at most 128 characters, and limited to letters, digits, '-', '_', '.' and
+// ':' so it can be logged and echoed in a header as is
+func ValidTraceID(id string) bool {
+	if id == "" || len(id) > maxTraceIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ContextWithTraceID returns a copy of ctx carrying traceID
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceKey{}, traceID)
+}
+
+// TraceIDFromContext returns the correlation ID carried by ctx, "" if none
+func TraceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	traceID, _ := ctx.Value(traceKey{}).(string)
+	return traceID
+}
+
+// WithTrace adds traceID to fields under TraceField, unless it is empty
+func WithTrace(fields logrus.Fields, traceID string) logrus.Fields {
+	if fields == nil {
+		fields = logrus.Fields{}
+	}
+	if traceID != "" {
+		fields[TraceField] = traceID
+	}
+	return fields
+}
+
+// TraceEvent is one log line recorded under a correlation ID
+type TraceEvent struct {
+	TraceID   string                     `json:"trace_id"`
+	Time      time.Time                  `json:"time"`
+	Level     string                     `json:"level"`
+	Component string                     `json:"component,omitempty"`
+	Action    string                     `json:"action,omitempty"`
+	Message   string                     `json:"message"`
+	Fields    map[string]json.RawMessage `json:"fields"`
+}
+
+// Trace is everything recorded under one correlation ID, oldest first,
+// together with the events of the traces it led to
+type Trace struct {
+	ID         string       `json:"trace_id"`
+	Events     []TraceEvent `json:"events"`
+	Linked     []string     `json:"linked_traces"` // traces started on behalf of this one, such as consensus rounds
+	Dropped    int          `json:"dropped"`       // oldest events discarded to stay within the per-trace limit
+	Components []string     `json:"components"`
+	FirstSeen  time.Time    `json:"first_seen"`
+	LastSeen   time.Time    `json:"last_seen"`
+}
+
+// TraceRecorder is a logrus hook that keeps recent log lines by correlation
+// ID so a request can be followed across components after the fact. Lines
+// are recorded under their trace_id. A line that also lists tx_trace_ids,
+// like a consensus round proposing a block, links each of those traces to
+// its own, so looking up a transaction's trace also returns the round that
+// committed it. The most recent maxTraces traces are kept, each holding at
+// most maxEvents lines of its own.
+type TraceRecorder struct {
+	mu        sync.Mutex
+	maxTraces int
+	maxEvents int
+	traces    map[string]*list.Element // trace ID -> element holding *Trace
+	order     *list.List               // traces, least recently written first
+}
+
+// NewTraceRecorder returns a recorder keeping maxTraces traces of at most
+// maxEvents events each
+func NewTraceRecorder(maxTraces, maxEvents int) *TraceRecorder {
+	if maxTraces < 1 {
+		maxTraces = 1
+	}
+	if maxEvents < 1 {
+		maxEvents = 1
+	}
+	return &TraceRecorder{
+		maxTraces: maxTraces,
+		maxEvents: maxEvents,
+		traces:    make(map[string]*list.Element),
+		order:     list.New(),
+	}
+}
+
+// Levels implements logrus.Hook
+func (r *TraceRecorder) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (r *TraceRecorder) Fire(entry *logrus.Entry) error {
+	traceID, _ := entry.Data[TraceField].(string)
+	parents, _ := entry.Data["tx_trace_ids"].([]string)
+	if traceID == "" && len(parents) == 0 {
+		return nil
+	}
+
+	event := TraceEvent{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Message: entry.Message,
+		Fields:  make(map[string]json.RawMessage, len(entry.Data)),
+	}
+	for key, value := range entry.Data {
+		switch key {
+		case "component":
+			event.Component, _ = value.(string)
+		case "action":
+			event.Action, _ = value.(string)
+		case "timestamp", TraceField:
+		default:
+			// Encoded now, as the logged value may change after the call
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				encoded, _ = json.Marshal(fmt.Sprint(value))
+			}
+			event.Fields[key] = encoded
+		}
+	}
+	if event.Action == "" {
+		// Cross-shard lines name their step in message_type
+		event.Action, _ = entry.Data["message_type"].(string)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if traceID == "" {
+		// Nothing to link to, so the line belongs to each listed trace
+		for _, parent := range parents {
+			r.record(parent, event)
+		}
+		return nil
+	}
+
+	r.record(traceID, event)
+	for _, parent := range parents {
+		if parent != traceID {
+			r.link(parent, traceID, event.Time)
+		}
+	}
+	return nil
+}
+
+// trace returns the trace id, creating it and evicting the least recently
+// written trace if needed. Caller must hold r.mu.
+func (r *TraceRecorder) trace(id string, at time.Time) *Trace {
+	if element, exists := r.traces[id]; exists {
+		r.order.MoveToBack(element)
+		return element.Value.(*Trace)
+	}
+
+	if r.order.Len() >= r.maxTraces {
+		oldest := r.order.Front()
+		r.order.Remove(oldest)
+		delete(r.traces, oldest.Value.(*Trace).ID)
+	}
+	trace := &Trace{ID: id, FirstSeen: at}
+	r.traces[id] = r.order.PushBack(trace)
+	return trace
+}
+
+// record appends event to the trace id. Caller must hold r.mu.
+func (r *TraceRecorder) record(id string, event TraceEvent) {
+	trace := r.trace(id, event.Time)
+	event.TraceID = id
+	if len(trace.Events) >= r.maxEvents {
+		trace.Events = trace.Events[1:]
+		trace.Dropped++
+	}
+	trace.Events = append(trace.Events, event)
+	trace.LastSeen = event.Time
+	if event.Component != "" && !containsString(trace.Components, event.Component) {
+		trace.Components = append(trace.Components, event.Component)
+	}
+}
+
+// link records that child was started on behalf of parent. Caller must hold
+// r.mu.
+func (r *TraceRecorder) link(parent, child string, at time.Time) {
+	trace := r.trace(parent, at)
+	if !containsString(trace.Linked, child) {
+		trace.Linked = append(trace.Linked, child)
+	}
+}
+
+// Get returns the trace recorded under id with the events of the traces
+// linked to it merged in, oldest first
+func (r *TraceRecorder) Get(id string) (*Trace, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	element, exists := r.traces[id]
+	if !exists {
+		return nil, false
+	}
+	root := element.Value.(*Trace)
+	merged := &Trace{
+		ID:        root.ID,
+		Linked:    append([]string{}, root.Linked...),
+		FirstSeen: root.FirstSeen,
+		LastSeen:  root.LastSeen,
+	}
+
+	visited := map[string]bool{id: true}
+	pending := []*Trace{root}
+	for len(pending) > 0 {
+		trace := pending[0]
+		pending = pending[1:]
+
+		merged.Events = append(merged.Events, trace.Events...)
+		merged.Dropped += trace.Dropped
+		for _, component := range trace.Components {
+			if !containsString(merged.Components, component) {
+				merged.Components = append(merged.Components, component)
+			}
+		}
+		if trace.LastSeen.After(merged.LastSeen) {
+			merged.LastSeen = trace.LastSeen
+		}
+
+		for _, child := range trace.Linked {
+			if visited[child] {
+				continue
+			}
+			visited[child] = true
+			// Linked traces may have been evicted since
+			if element, exists := r.traces[child]; exists {
+				pending = append(pending, element.Value.(*Trace))
+			}
+		}
+	}
+
+	sort.SliceStable(merged.Events, func(i, j int) bool {
+		return merged.Events[i].Time.Before(merged.Events[j].Time)
+	})
+	if merged.Events == nil {
+		merged.Events = []TraceEvent{}
+	}
+	if merged.Components == nil {
+		merged.Components = []string{}
+	}
+	return merged, true
+}
+
+// Len returns the number of traces held
+func (r *TraceRecorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.order.Len()
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
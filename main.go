@@ -2,7 +2,7 @@ package main
 
 import (
         "context"
-        "crypto/rand"
+        "crypto/sha256"
         "encoding/hex"
         "flag"
         "fmt"
@@ -96,6 +96,13 @@ func main() {
                         })
         }
 
+        // Reopen the logger at the configured level and format
+        logger, err = utils.NewLoggerWithConfig(cfg.Logging)
+        if err != nil {
+                fmt.Fprintf(os.Stderr, "Failed to configure logging: %v\n", err)
+                os.Exit(1)
+        }
+
         logger.Info("Configuration loaded successfully",
                 logrus.Fields{
                         "consensus": cfg.Consensus.Algorithm,
@@ -104,12 +111,18 @@ func main() {
                         "timestamp": time.Now().UTC(),
                 })
 
+        // Keep recent log lines by trace ID for GET /api/v1/trace/:id
+        if cfg.Logging.TraceBuffer > 0 {
+                logger.EnableTraceRecording(cfg.Logging.TraceBuffer, cfg.Logging.TraceEvents)
+        }
+
         // Initialize storage
-        db, err := storage.NewBadgerDB(cfg.Storage.DataDir)
+        db, err := storage.NewDatabase(cfg)
         if err != nil {
                 logger.Fatal("Failed to initialize database",
                         logrus.Fields{
                                 "error":    err,
+                                "backend":  cfg.Storage.Backend,
                                 "data_dir": cfg.Storage.DataDir,
                                 "timestamp": time.Now().UTC(),
                         })
@@ -118,7 +131,7 @@ func main() {
 
         logger.Info("Database initialized successfully",
                 logrus.Fields{
-                        "type":      "BadgerDB",
+                        "type":      cfg.Storage.Backend,
                         "data_dir":  cfg.Storage.DataDir,
                         "timestamp": time.Now().UTC(),
                 })
@@ -136,6 +149,16 @@ func main() {
                         })
         }
 
+        bc.SetMetricsCollector(metricsCollector)
+
+        // Check block production and throughput against the configured SLOs
+        metricsCollector.EnableSLO(metrics.SLOConfig{
+                MinTPS:           cfg.SLO.MinTPS,
+                MaxBlockInterval: time.Duration(cfg.SLO.MaxBlockInterval) * time.Second,
+                Window:           time.Duration(cfg.SLO.Window) * time.Second,
+                WebhookURL:       cfg.SLO.WebhookURL,
+        })
+
         logger.Info("Blockchain initialized successfully",
                 logrus.Fields{
                         "genesis_hash": bc.GetGenesisBlock().Hash,
@@ -143,9 +166,15 @@ func main() {
                         "timestamp":    time.Now().UTC(),
                 })
 
-        // Add validators to make consensus functional
-        err = addInitialValidators(bc, cfg, logger)
-        if err != nil {
+        // Add validators to make consensus functional, unless the genesis
+        // file or an earlier run already stored a validator set
+        if len(bc.GetValidators()) > 0 {
+                logger.Info("Using stored validator set",
+                        logrus.Fields{
+                                "validator_count": len(bc.GetValidators()),
+                                "timestamp":       time.Now().UTC(),
+                        })
+        } else if err = addInitialValidators(bc, cfg, logger); err != nil {
                 logger.Error("Failed to add initial validators",
                         logrus.Fields{
                                 "error":     err,
@@ -159,8 +188,19 @@ func main() {
                         })
         }
 
+        // A validator set too small for the configured Byzantine faults may
+        // never reach quorum
+        if err := bc.CheckValidatorSet(); err != nil {
+                logger.Fatal("Validator set rejected",
+                        logrus.Fields{
+                                "error":     err,
+                                "timestamp": time.Now().UTC(),
+                        })
+        }
+
         // Initialize sharding manager
         shardManager := sharding.NewShardManager(cfg, bc, logger)
+        shardManager.SetMetricsCollector(metricsCollector)
         err = shardManager.Initialize()
         if err != nil {
                 logger.Fatal("Failed to initialize shard manager",
@@ -250,7 +290,9 @@ func main() {
         router := gin.New()
         router.Use(gin.Logger())
         router.Use(gin.Recovery())
-        router.Use(api.CORSMiddleware())
+        router.Use(api.TraceMiddleware())
+        router.Use(api.CORSMiddleware(cfg.Server))
+        router.Use(api.AuthMiddleware(cfg.Server, logger))
         router.Use(api.RateLimitMiddleware())
 
         // Setup routes
@@ -280,7 +322,9 @@ func main() {
                         algoRouter := gin.New()
                         algoRouter.Use(gin.Logger())
                         algoRouter.Use(gin.Recovery())
-                        algoRouter.Use(api.CORSMiddleware())
+                        algoRouter.Use(api.TraceMiddleware())
+                        algoRouter.Use(api.CORSMiddleware(cfg.Server))
+                        algoRouter.Use(api.AuthMiddleware(cfg.Server, logger))
                         algoRouter.Use(api.RateLimitMiddleware())
 
                         // Create algorithm-specific configuration copy
@@ -294,7 +338,7 @@ func main() {
                         api.SetupRoutesWithoutHealth(algoRouter, algoHandlers, consensusComparator, p2pNetwork)
 
                         // Add algorithm-specific health endpoint
-                        algoRouter.GET("/health", createHealthHandler(algorithm, port, cfg.Node.ID))
+                        algoRouter.GET("/health", createHealthHandler(algorithm, port, algoHandlers))
 
                         // Prometheus metrics endpoint for each algorithm
                         algoRouter.GET("/metrics", gin.WrapH(promhttp.Handler()))
@@ -418,12 +462,21 @@ func main() {
         // Stop P2P network
         p2pNetwork.Stop()
 
-        // Stop blockchain consensus
-        bc.StopConsensus()
+        // Stop blockchain consensus, letting the round in progress commit
+        if err := bc.StopConsensus(ctx); err != nil {
+                logger.Error("Consensus forced to stop",
+                        logrus.Fields{
+                                "error":     err,
+                                "timestamp": time.Now().UTC(),
+                        })
+        }
 
         // Stop shard manager
         shardManager.Stop()
 
+        // Stop SLO checks
+        metricsCollector.StopSLO()
+
         logger.Info("Server exited gracefully",
                 logrus.Fields{
                         "timestamp": time.Now().UTC(),
@@ -431,35 +484,38 @@ func main() {
 }
 
 // createHealthHandler creates a health handler for a specific algorithm and port
-func createHealthHandler(algorithm string, port int, nodeID string) gin.HandlerFunc {
+func createHealthHandler(algorithm string, port int, handlers *api.Handlers) gin.HandlerFunc {
         return func(c *gin.Context) {
-                c.JSON(200, gin.H{
-                        "status":    "healthy",
-                        "algorithm": algorithm,
-                        "node_id":   nodeID,
-                        "port":      port,
-                        "timestamp": time.Now().UTC(),
+                report := handlers.CheckHealth()
+                c.JSON(report.HTTPStatus(), gin.H{
+                        "status":     report.Status,
+                        "algorithm":  algorithm,
+                        "node_id":    report.NodeID,
+                        "port":       port,
+                        "components": report.Components,
+                        "unhealthy":  report.Unhealthy,
+                        "timestamp":  report.Timestamp,
                 })
         }
 }
 
-// addInitialValidators adds initial validators to make the consensus network functional
+// addInitialValidators adds initial validators to make the consensus network
+// functional when no genesis file provides them. Keys are derived from the
+// chain ID so every node on the network, and every restart, gets the same set.
 func addInitialValidators(bc *blockchain.Blockchain, cfg *config.Config, logger *utils.Logger) error {
         // Create 8 validators to ensure sufficient participation in consensus
         validators := make([]*types.Validator, 8)
 
         for i := 0; i < 8; i++ {
-                // Generate random validator address (20 bytes for Ethereum-style address)
-                validatorID := make([]byte, 20)
-                rand.Read(validatorID)
+                // Derive validator address (20 bytes for Ethereum-style address)
+                validatorID := sha256.Sum256([]byte(fmt.Sprintf("%s/validator/%d", cfg.Network.ChainID, i)))
 
-                // Generate random public key
-                pubKey := make([]byte, 32)
-                rand.Read(pubKey)
+                // Derive public key
+                pubKey := sha256.Sum256([]byte(fmt.Sprintf("%s/validator/%d/key", cfg.Network.ChainID, i)))
 
                 validator := &types.Validator{
-                        Address:    fmt.Sprintf("0x%s", hex.EncodeToString(validatorID)),
-                        PublicKey:  hex.EncodeToString(pubKey),
+                        Address:    fmt.Sprintf("0x%s", hex.EncodeToString(validatorID[:20])),
+                        PublicKey:  hex.EncodeToString(pubKey[:]),
                         Stake:      1000 + int64(i*500), // Varying stakes from 1000 to 4500
                         Power:      float64(1000 + i*500), // Power proportional to stake
                         LastActive: time.Now(),
@@ -34,6 +34,7 @@ type NodeInfo struct {
 	StartTime          time.Time          `json:"start_time"`
 	LastSeen           time.Time          `json:"last_seen"`
 	Version            string             `json:"version"`
+	ChainID            string             `json:"chain_id,omitempty"`
 }
 
 // NetworkPeer represents a peer in the network
@@ -41,6 +42,7 @@ type NetworkPeer struct {
 	NodeInfo
 	Address     string        `json:"address"`
 	Port        int           `json:"port"`
+	APIAddress  string        `json:"api_address,omitempty"` // host:port of the peer's HTTP API, "" if unreachable
 	Connected   bool          `json:"connected"`
 	Latency     time.Duration `json:"latency"`
 	MessagesSent int64        `json:"messages_sent"`
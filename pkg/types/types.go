@@ -2,8 +2,11 @@ package types
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -25,47 +28,108 @@ func (a Address) String() string {
 
 // Transaction represents a blockchain transaction
 type Transaction struct {
-	ID        string    `json:"id"`
-	From      string    `json:"from"`
-	To        string    `json:"to"`
-	Amount    int64     `json:"amount"`
-	Fee       int64     `json:"fee"`
-	Data      []byte    `json:"data,omitempty"`
-	Timestamp time.Time `json:"timestamp"`
-	Signature string    `json:"signature"`
-	Nonce     int64     `json:"nonce"`
-	ShardID   int       `json:"shard_id"`
-	Type      string    `json:"type"` // "regular", "cross_shard", "stake", "unstake"
+	ID            string    `json:"id"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	Amount        int64     `json:"amount"`
+	Fee           int64     `json:"fee"`
+	Data          []byte    `json:"data,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	Signature     string    `json:"signature"`
+	Nonce         int64     `json:"nonce"`
+	ShardID       int       `json:"shard_id"`
+	Type          string    `json:"type"`                     // "regular", "cross_shard", "stake", "unstake"
+	AffinityGroup string    `json:"affinity_group,omitempty"` // Routing hint: accounts in one group share a shard; hashed, so covered by the signature
+	TraceID       string    `json:"trace_id,omitempty"`       // Correlation ID of the request that submitted it; not hashed
+	GasUsed       int64     `json:"gas_used,omitempty"`       // Gas charged for the transaction, set once it is validated; not hashed
 }
 
 // Hash calculates the hash of the transaction
 func (tx *Transaction) Hash() string {
 	data, _ := json.Marshal(struct {
-		From      string    `json:"from"`
-		To        string    `json:"to"`
-		Amount    int64     `json:"amount"`
-		Fee       int64     `json:"fee"`
-		Data      []byte    `json:"data,omitempty"`
-		Timestamp time.Time `json:"timestamp"`
-		Nonce     int64     `json:"nonce"`
-		ShardID   int       `json:"shard_id"`
-		Type      string    `json:"type"`
+		From          string    `json:"from"`
+		To            string    `json:"to"`
+		Amount        int64     `json:"amount"`
+		Fee           int64     `json:"fee"`
+		Data          []byte    `json:"data,omitempty"`
+		Timestamp     time.Time `json:"timestamp"`
+		Nonce         int64     `json:"nonce"`
+		ShardID       int       `json:"shard_id"`
+		Type          string    `json:"type"`
+		AffinityGroup string    `json:"affinity_group,omitempty"`
 	}{
-		From:      tx.From,
-		To:        tx.To,
-		Amount:    tx.Amount,
-		Fee:       tx.Fee,
-		Data:      tx.Data,
-		Timestamp: tx.Timestamp,
-		Nonce:     tx.Nonce,
-		ShardID:   tx.ShardID,
-		Type:      tx.Type,
+		From:          tx.From,
+		To:            tx.To,
+		Amount:        tx.Amount,
+		Fee:           tx.Fee,
+		Data:          tx.Data,
+		Timestamp:     tx.Timestamp,
+		Nonce:         tx.Nonce,
+		ShardID:       tx.ShardID,
+		Type:          tx.Type,
+		AffinityGroup: tx.AffinityGroup,
 	})
 
 	hash := sha256.Sum256(data)
 	return hex.EncodeToString(hash[:])
 }
 
+// Size returns the approximate encoded size of the transaction in bytes
+func (tx *Transaction) Size() int {
+	return 150 + len(tx.Data) + len(tx.Signature) + len(tx.From) + len(tx.To)
+}
+
+// Expired reports whether the transaction is older than ttl at now. A
+// non-positive ttl never expires.
+func (tx *Transaction) Expired(ttl time.Duration, now time.Time) bool {
+	return ttl > 0 && now.Sub(tx.Timestamp) > ttl
+}
+
+// Gas charged for a transaction, see Gas
+const (
+	TxBaseGas      int64 = 21000  // charged to every transaction
+	TxDataByteGas  int64 = 68     // charged for each byte of Data
+	CrossShardGas  int64 = 50000  // added for cross_shard transactions
+	StakeChangeGas int64 = 100000 // added for stake and unstake transactions
+)
+
+// Gas returns the gas the transaction consumes: a base cost, a per-byte data
+// cost and surcharges for cross-shard and staking transactions
+func (tx *Transaction) Gas() int64 {
+	gas := TxBaseGas + int64(len(tx.Data))*TxDataByteGas
+
+	switch tx.Type {
+	case "cross_shard":
+		gas += CrossShardGas
+	case "stake", "unstake":
+		gas += StakeChangeGas
+	}
+	return gas
+}
+
+// GasUsedBy returns the gas transactions consume together
+func GasUsedBy(transactions []*Transaction) int64 {
+	var gas int64
+	for _, tx := range transactions {
+		gas += tx.Gas()
+	}
+	return gas
+}
+
+// BlockHeaderSize is the approximate encoded size of a block header in bytes
+const BlockHeaderSize = 200
+
+var (
+	// ErrTooManyTransactions is returned when a block holds more transactions than allowed
+	ErrTooManyTransactions = errors.New("block exceeds maximum transactions")
+	// ErrBlockTooLarge is returned when a block's encoded size exceeds the limit
+	ErrBlockTooLarge = errors.New("block exceeds maximum size")
+	// ErrBlockGasExceeded is returned when a block's transactions consume more gas than allowed
+	ErrBlockGasExceeded = errors.New("block exceeds gas limit")
+	// ErrChainIDMismatch is returned when a block, message or peer belongs to another network
+	ErrChainIDMismatch = errors.New("chain ID mismatch")
+)
+
 // Block represents a blockchain block
 type Block struct {
 	Index         int64                  `json:"index"`
@@ -82,37 +146,99 @@ type Block struct {
 	Size          int                    `json:"size"`
 	GasUsed       int64                  `json:"gas_used"`
 	GasLimit      int64                  `json:"gas_limit"`
+	ChainID       string                 `json:"chain_id,omitempty"`
+	BaseFee       int64                  `json:"base_fee,omitempty"` // Fee each transaction must pay, burned on commit; 0 without a fee market
 	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	TraceID       string                 `json:"trace_id,omitempty"` // Correlation ID of the consensus round that produced it; not hashed
+	Voters        []string               `json:"voters,omitempty"`   // Validators whose votes committed it, sorted; hashed, so the block is rehashed once they are known
+	Rewards       map[string]int64       `json:"rewards,omitempty"`  // Subsidy and tips paid to each validator by address, recomputed on commit; not hashed
+	FailedTxs     []string               `json:"failed_txs,omitempty"` // Transactions that failed to execute and moved no funds, set on commit; not hashed
 }
 
-// CalculateHash calculates the hash of the block
-func (b *Block) CalculateHash() string {
-	data, _ := json.Marshal(struct {
-		Index        int64          `json:"index"`
-		Timestamp    time.Time      `json:"timestamp"`
-		PreviousHash string         `json:"previous_hash"`
-		MerkleRoot   string         `json:"merkle_root"`
-		Nonce        int64          `json:"nonce"`
-		Difficulty   int            `json:"difficulty"`
-		Validator    string         `json:"validator,omitempty"`
-		ShardID      int            `json:"shard_id"`
-		GasUsed      int64          `json:"gas_used"`
-		GasLimit     int64          `json:"gas_limit"`
-	}{
-		Index:        b.Index,
-		Timestamp:    b.Timestamp,
-		PreviousHash: b.PreviousHash,
-		MerkleRoot:   b.MerkleRoot,
-		Nonce:        b.Nonce,
-		Difficulty:   b.Difficulty,
-		Validator:    b.Validator,
-		ShardID:      b.ShardID,
-		GasUsed:      b.GasUsed,
-		GasLimit:     b.GasLimit,
-	})
+// ComputeHash returns the deterministic hash of the block header: Index,
+// PreviousHash, MerkleRoot, Timestamp, Validator, ShardID and Nonce. Fields
+// are written in a fixed order, strings length-prefixed and the timestamp as
+// UTC nanoseconds, so equal headers always hash alike regardless of time
+// zone or encoding, and no two differing headers share an encoding. The
+// nonce is included so proof-of-work can search over it. ChainID, BaseFee
+// and Voters are appended when set, so blocks from before chain IDs, the fee
+// market or voter rewards existed keep their original hash. Voters are
+// hashed because they decide who shares the block's rewards.
+func (b *Block) ComputeHash() string {
+	hasher := sha256.New()
 
-	hash := sha256.Sum256(data)
-	return hex.EncodeToString(hash[:])
+	writeInt := func(v int64) {
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], uint64(v))
+		hasher.Write(buf[:])
+	}
+	writeString := func(v string) {
+		writeInt(int64(len(v)))
+		hasher.Write([]byte(v))
+	}
+
+	writeInt(b.Index)
+	writeString(b.PreviousHash)
+	writeString(b.MerkleRoot)
+	writeInt(b.Timestamp.UTC().UnixNano())
+	writeString(b.Validator)
+	writeInt(int64(b.ShardID))
+	writeInt(b.Nonce)
+	if b.ChainID != "" {
+		writeString(b.ChainID)
+	}
+	if b.BaseFee != 0 {
+		writeInt(b.BaseFee)
+	}
+	if len(b.Voters) > 0 {
+		writeInt(int64(len(b.Voters)))
+		for _, voter := range b.Voters {
+			writeString(voter)
+		}
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// VerifyHash reports whether the stored hash matches the block's contents
+func (b *Block) VerifyHash() bool {
+	return b.Hash == b.ComputeHash()
+}
+
+// EncodedSize returns the approximate size of the block in bytes, computed
+// from its transactions rather than trusting the Size field
+func (b *Block) EncodedSize() int {
+	size := BlockHeaderSize
+	for _, tx := range b.Transactions {
+		size += tx.Size()
+	}
+	return size
+}
+
+// CheckLimits rejects blocks holding more than maxTxs transactions or larger
+// than maxSize bytes. A non-positive limit is not enforced.
+func (b *Block) CheckLimits(maxTxs, maxSize int) error {
+	if maxTxs > 0 && len(b.Transactions) > maxTxs {
+		return fmt.Errorf("%w: %d > %d", ErrTooManyTransactions, len(b.Transactions), maxTxs)
+	}
+	if maxSize > 0 {
+		if size := b.EncodedSize(); size > maxSize {
+			return fmt.Errorf("%w: %d > %d bytes", ErrBlockTooLarge, size, maxSize)
+		}
+	}
+	return nil
+}
+
+// CheckGas rejects blocks whose transactions consume more than limit gas,
+// whatever GasUsed the block declares. A non-positive limit is not enforced.
+func (b *Block) CheckGas(limit int64) error {
+	if limit <= 0 {
+		return nil
+	}
+	if gas := GasUsedBy(b.Transactions); gas > limit {
+		return fmt.Errorf("%w: %d > %d", ErrBlockGasExceeded, gas, limit)
+	}
+	return nil
 }
 
 // Peer represents a network peer
@@ -146,10 +272,15 @@ type CrossShardMessage struct {
 	FromShard   int         `json:"from_shard"`
 	ToShard     int         `json:"to_shard"`
 	Type        string      `json:"type"`
+	DataType    string      `json:"data_type,omitempty"` // concrete type of Data, see CrossShardDataTransaction
 	Data        interface{} `json:"data"`
 	Timestamp   time.Time   `json:"timestamp"`
 	Signature   string      `json:"signature"`
+	ChainID     string      `json:"chain_id"`
+	Priority    int         `json:"priority,omitempty"` // higher is handled first; 0 means normal
 	Processed   bool        `json:"processed"`
+	TraceID     string      `json:"trace_id,omitempty"` // correlation ID of the transaction or block carried
+	HopCount    int         `json:"hop_count,omitempty"` // relays the message has passed through
 }
 
 // Validator represents a consensus validator
@@ -228,6 +359,7 @@ type BlockchainStats struct {
 	AvgBlockTime      float64     `json:"avg_block_time"`
 	TPS               float64     `json:"tps"`
 	RecentBlockTimes  []time.Time `json:"recent_block_times"`
+	OrphanBlocks      int         `json:"orphan_blocks"`
 	LastUpdate        time.Time   `json:"last_update"`
 }
 
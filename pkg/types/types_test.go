@@ -0,0 +1,158 @@
+package types
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func testHeader() *Block {
+	return &Block{
+		Index:        7,
+		Timestamp:    time.Date(2024, 3, 1, 12, 0, 0, 500, time.UTC),
+		PreviousHash: "a1b2",
+		MerkleRoot:   "c3d4",
+		Validator:    "validator_0",
+		ShardID:      2,
+		Nonce:        42,
+	}
+}
+
+func TestComputeHashIsDeterministic(t *testing.T) {
+	first, second := testHeader(), testHeader()
+	if first.ComputeHash() != second.ComputeHash() {
+		t.Fatal("equal headers hash differently")
+	}
+
+	// The same instant in another zone is the same header
+	second.Timestamp = second.Timestamp.In(time.FixedZone("UTC+5", 5*60*60))
+	if first.ComputeHash() != second.ComputeHash() {
+		t.Fatal("hash depends on the timestamp's time zone")
+	}
+
+	// Fields outside the header do not move the hash
+	second.TraceID = "trace"
+	second.Rewards = map[string]int64{"validator_1": 10}
+	second.Transactions = []*Transaction{{ID: "tx"}}
+	if first.ComputeHash() != second.ComputeHash() {
+		t.Fatal("hash covers fields outside the header")
+	}
+}
+
+func TestComputeHashCoversEveryHeaderField(t *testing.T) {
+	base := testHeader().ComputeHash()
+	changes := map[string]func(b *Block){
+		"index":         func(b *Block) { b.Index++ },
+		"previous hash": func(b *Block) { b.PreviousHash = "a1b3" },
+		"merkle root":   func(b *Block) { b.MerkleRoot = "c3d5" },
+		"timestamp":     func(b *Block) { b.Timestamp = b.Timestamp.Add(time.Nanosecond) },
+		"validator":     func(b *Block) { b.Validator = "validator_1" },
+		"shard":         func(b *Block) { b.ShardID++ },
+		"nonce":         func(b *Block) { b.Nonce++ },
+		"chain id":      func(b *Block) { b.ChainID = "testnet" },
+		"base fee":      func(b *Block) { b.BaseFee = 1 },
+		"voters":        func(b *Block) { b.Voters = []string{"validator_1"} },
+		"more voters":   func(b *Block) { b.Voters = []string{"validator_1", "validator_2"} },
+	}
+	seen := map[string]string{base: "base"}
+	for name, change := range changes {
+		block := testHeader()
+		change(block)
+		hash := block.ComputeHash()
+		if other, exists := seen[hash]; exists {
+			t.Errorf("changing %s hashes like %s", name, other)
+		}
+		seen[hash] = name
+	}
+}
+
+func TestComputeHashSeparatesAdjacentStrings(t *testing.T) {
+	first, second := testHeader(), testHeader()
+	first.PreviousHash, first.MerkleRoot = "ab", "c"
+	second.PreviousHash, second.MerkleRoot = "a", "bc"
+	if first.ComputeHash() == second.ComputeHash() {
+		t.Fatal("headers differing only in where one string ends share a hash")
+	}
+}
+
+func TestVerifyHashRejectsTamperedBlock(t *testing.T) {
+	block := testHeader()
+	block.Hash = block.ComputeHash()
+	if !block.VerifyHash() {
+		t.Fatal("block with its own hash failed verification")
+	}
+	block.MerkleRoot = "forged"
+	if block.VerifyHash() {
+		t.Fatal("block whose contents no longer match its hash passed verification")
+	}
+}
+
+func TestTransactionHashCoversAffinityGroup(t *testing.T) {
+	tx := &Transaction{From: "a", To: "b", Amount: 5, Fee: 1, Nonce: 1, Type: "regular", Timestamp: time.Unix(1700000000, 0).UTC()}
+	ungrouped := tx.Hash()
+
+	tx.AffinityGroup = "exchange"
+	grouped := tx.Hash()
+	if grouped == ungrouped {
+		t.Fatal("hash ignores the affinity group")
+	}
+	tx.AffinityGroup = "other"
+	if tx.Hash() == grouped {
+		t.Fatal("different affinity groups hash the same")
+	}
+
+	// The trace ID and gas used are still left out
+	tx.AffinityGroup = "exchange"
+	tx.TraceID, tx.GasUsed = "trace", 21000
+	if tx.Hash() != grouped {
+		t.Fatal("hash covers the trace ID or gas used")
+	}
+}
+
+func TestTransactionGas(t *testing.T) {
+	for name, c := range map[string]struct {
+		tx   *Transaction
+		want int64
+	}{
+		"transfer":    {&Transaction{Type: "regular"}, TxBaseGas},
+		"with data":   {&Transaction{Type: "regular", Data: []byte("hello")}, TxBaseGas + 5*TxDataByteGas},
+		"cross-shard": {&Transaction{Type: "cross_shard", Data: []byte("x")}, TxBaseGas + TxDataByteGas + CrossShardGas},
+		"stake":       {&Transaction{Type: "stake"}, TxBaseGas + StakeChangeGas},
+		"unstake":     {&Transaction{Type: "unstake"}, TxBaseGas + StakeChangeGas},
+	} {
+		if got := c.tx.Gas(); got != c.want {
+			t.Fatalf("%s: gas = %d, want %d", name, got, c.want)
+		}
+	}
+}
+
+func TestBlockGasSumsTransactions(t *testing.T) {
+	block := &Block{Transactions: []*Transaction{
+		{Type: "regular"},
+		{Type: "regular", Data: []byte("abc")},
+		{Type: "stake"},
+	}}
+	want := 3*TxBaseGas + 3*TxDataByteGas + StakeChangeGas
+	if got := GasUsedBy(block.Transactions); got != want {
+		t.Fatalf("gas used = %d, want %d", got, want)
+	}
+	if got := GasUsedBy(nil); got != 0 {
+		t.Fatalf("empty block uses %d gas", got)
+	}
+
+	if err := block.CheckGas(want); err != nil {
+		t.Fatalf("block at its limit refused: %v", err)
+	}
+	if err := block.CheckGas(want - 1); !errors.Is(err, ErrBlockGasExceeded) {
+		t.Fatalf("expected ErrBlockGasExceeded, got %v", err)
+	}
+	if err := block.CheckGas(0); err != nil {
+		t.Fatalf("unlimited gas refused: %v", err)
+	}
+
+	// What the block declares is ignored; its transactions are counted
+	block.GasUsed = 1
+	if err := block.CheckGas(want - 1); !errors.Is(err, ErrBlockGasExceeded) {
+		t.Fatalf("declared gas trusted: %v", err)
+	}
+}
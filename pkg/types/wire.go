@@ -0,0 +1,235 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CrossShardWireVersion is the envelope version written by
+// EncodeCrossShardMessage
+const CrossShardWireVersion = 1
+
+// Cross-shard payload types, carried in CrossShardMessage.DataType so the
+// payload can be rebuilt as the right concrete type on the other side
+const (
+	CrossShardDataTransaction = "transaction" // Data is a *Transaction
+	CrossShardDataBlock       = "block"       // Data is a *Block
+	CrossShardDataSyncRange   = "sync_range"  // Data is a *SyncRange
+	CrossShardDataJSON        = "json"        // Data is plain JSON (maps, slices, strings, numbers)
+)
+
+// SyncRange is the block range a sync message asks for
+type SyncRange struct {
+	StartBlock int64 `json:"start_block"` // first block wanted; 0 means the block after the requester's head
+	EndBlock   int64 `json:"end_block"`   // last block wanted; 0 means the peer's head
+}
+
+var (
+	// ErrUnknownDataType is returned for a cross-shard payload type this
+	// node cannot decode
+	ErrUnknownDataType = errors.New("unknown cross-shard data type")
+	// ErrUnsupportedWireVersion is returned for an envelope written by an
+	// incompatible version
+	ErrUnsupportedWireVersion = errors.New("unsupported cross-shard wire version")
+	// ErrDataTypeMismatch is returned when a message's payload is read as a
+	// type other than the one it carries
+	ErrDataTypeMismatch = errors.New("cross-shard data does not match its data type")
+)
+
+// crossShardEnvelope is the wire form of a CrossShardMessage
+type crossShardEnvelope struct {
+	Version   int             `json:"version"`
+	ID        string          `json:"id"`
+	FromShard int             `json:"from_shard"`
+	ToShard   int             `json:"to_shard"`
+	Type      string          `json:"type"`
+	DataType  string          `json:"data_type,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	Signature string          `json:"signature,omitempty"`
+	ChainID   string          `json:"chain_id"`
+	Priority  int             `json:"priority,omitempty"`
+	Processed bool            `json:"processed,omitempty"`
+	TraceID   string          `json:"trace_id,omitempty"`
+	HopCount  int             `json:"hop_count,omitempty"`
+}
+
+// SetTransaction makes tx the message's payload. The message takes the
+// transaction's trace ID unless it already has one.
+func (m *CrossShardMessage) SetTransaction(tx *Transaction) {
+	m.Data = tx
+	m.DataType = CrossShardDataTransaction
+	if m.TraceID == "" && tx != nil {
+		m.TraceID = tx.TraceID
+	}
+}
+
+// SetBlock makes block the message's payload. The message takes the block's
+// trace ID unless it already has one.
+func (m *CrossShardMessage) SetBlock(block *Block) {
+	m.Data = block
+	m.DataType = CrossShardDataBlock
+	if m.TraceID == "" && block != nil {
+		m.TraceID = block.TraceID
+	}
+}
+
+// SetSyncRange makes r the message's payload
+func (m *CrossShardMessage) SetSyncRange(r *SyncRange) {
+	m.Data = r
+	m.DataType = CrossShardDataSyncRange
+}
+
+// Transaction returns the message's transaction payload, or
+// ErrDataTypeMismatch when it carries something else
+func (m *CrossShardMessage) Transaction() (*Transaction, error) {
+	tx, ok := m.Data.(*Transaction)
+	if m.DataType != CrossShardDataTransaction || !ok || tx == nil {
+		return nil, fmt.Errorf("%w: message %s carries %q, not a transaction", ErrDataTypeMismatch, m.ID, m.DataType)
+	}
+	return tx, nil
+}
+
+// Block returns the message's block payload, or ErrDataTypeMismatch when it
+// carries something else
+func (m *CrossShardMessage) Block() (*Block, error) {
+	block, ok := m.Data.(*Block)
+	if m.DataType != CrossShardDataBlock || !ok || block == nil {
+		return nil, fmt.Errorf("%w: message %s carries %q, not a block", ErrDataTypeMismatch, m.ID, m.DataType)
+	}
+	return block, nil
+}
+
+// SyncRange returns the message's sync range payload, or ErrDataTypeMismatch
+// when it carries something else
+func (m *CrossShardMessage) SyncRange() (*SyncRange, error) {
+	r, ok := m.Data.(*SyncRange)
+	if m.DataType != CrossShardDataSyncRange || !ok || r == nil {
+		return nil, fmt.Errorf("%w: message %s carries %q, not a sync range", ErrDataTypeMismatch, m.ID, m.DataType)
+	}
+	return r, nil
+}
+
+// InferDataType sets DataType from Data for messages built without one.
+// Anything other than a transaction, block or sync range is carried as plain
+// JSON.
+func (m *CrossShardMessage) InferDataType() {
+	if m.DataType != "" || m.Data == nil {
+		return
+	}
+	switch m.Data.(type) {
+	case *Transaction:
+		m.DataType = CrossShardDataTransaction
+	case *Block:
+		m.DataType = CrossShardDataBlock
+	case *SyncRange:
+		m.DataType = CrossShardDataSyncRange
+	default:
+		m.DataType = CrossShardDataJSON
+	}
+}
+
+// EncodeCrossShardMessage serializes m into a versioned envelope that
+// DecodeCrossShardMessage can rebuild on another node. A message without a
+// DataType has it inferred from its payload first.
+func EncodeCrossShardMessage(m *CrossShardMessage) ([]byte, error) {
+	envelope := crossShardEnvelope{
+		Version:   CrossShardWireVersion,
+		ID:        m.ID,
+		FromShard: m.FromShard,
+		ToShard:   m.ToShard,
+		Type:      m.Type,
+		DataType:  m.DataType,
+		Timestamp: m.Timestamp,
+		Signature: m.Signature,
+		ChainID:   m.ChainID,
+		Priority:  m.Priority,
+		Processed: m.Processed,
+		TraceID:   m.TraceID,
+		HopCount:  m.HopCount,
+	}
+
+	if m.Data != nil {
+		if envelope.DataType == "" {
+			inferred := *m
+			inferred.InferDataType()
+			envelope.DataType = inferred.DataType
+		}
+		switch envelope.DataType {
+		case CrossShardDataTransaction, CrossShardDataBlock, CrossShardDataSyncRange, CrossShardDataJSON:
+		default:
+			return nil, fmt.Errorf("%w: %q in message %s", ErrUnknownDataType, envelope.DataType, m.ID)
+		}
+
+		data, err := json.Marshal(m.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode data of message %s: %w", m.ID, err)
+		}
+		envelope.Data = data
+	}
+
+	return json.Marshal(envelope)
+}
+
+// DecodeCrossShardMessage rebuilds a message written by
+// EncodeCrossShardMessage, decoding its payload into the concrete type named
+// by its DataType
+func DecodeCrossShardMessage(encoded []byte) (*CrossShardMessage, error) {
+	var envelope crossShardEnvelope
+	if err := json.Unmarshal(encoded, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode cross-shard message: %w", err)
+	}
+	if envelope.Version != CrossShardWireVersion {
+		return nil, fmt.Errorf("%w: %d (this node reads %d)", ErrUnsupportedWireVersion, envelope.Version, CrossShardWireVersion)
+	}
+
+	message := &CrossShardMessage{
+		ID:        envelope.ID,
+		FromShard: envelope.FromShard,
+		ToShard:   envelope.ToShard,
+		Type:      envelope.Type,
+		DataType:  envelope.DataType,
+		Timestamp: envelope.Timestamp,
+		Signature: envelope.Signature,
+		ChainID:   envelope.ChainID,
+		Priority:  envelope.Priority,
+		Processed: envelope.Processed,
+		TraceID:   envelope.TraceID,
+		HopCount:  envelope.HopCount,
+	}
+	if len(envelope.Data) == 0 {
+		return message, nil
+	}
+
+	switch envelope.DataType {
+	case CrossShardDataTransaction:
+		var tx Transaction
+		if err := json.Unmarshal(envelope.Data, &tx); err != nil {
+			return nil, fmt.Errorf("failed to decode transaction in message %s: %w", envelope.ID, err)
+		}
+		message.Data = &tx
+	case CrossShardDataBlock:
+		var block Block
+		if err := json.Unmarshal(envelope.Data, &block); err != nil {
+			return nil, fmt.Errorf("failed to decode block in message %s: %w", envelope.ID, err)
+		}
+		message.Data = &block
+	case CrossShardDataSyncRange:
+		var r SyncRange
+		if err := json.Unmarshal(envelope.Data, &r); err != nil {
+			return nil, fmt.Errorf("failed to decode sync range in message %s: %w", envelope.ID, err)
+		}
+		message.Data = &r
+	case CrossShardDataJSON:
+		var data interface{}
+		if err := json.Unmarshal(envelope.Data, &data); err != nil {
+			return nil, fmt.Errorf("failed to decode data in message %s: %w", envelope.ID, err)
+		}
+		message.Data = data
+	default:
+		return nil, fmt.Errorf("%w: %q in message %s", ErrUnknownDataType, envelope.DataType, envelope.ID)
+	}
+	return message, nil
+}
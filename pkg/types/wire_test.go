@@ -0,0 +1,186 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testTransaction() *Transaction {
+	tx := &Transaction{
+		From:      "0xfrom",
+		To:        "0xto",
+		Amount:    250,
+		Fee:       3,
+		Data:      []byte{1, 2, 3},
+		Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Signature: "sig",
+		Nonce:     9,
+		ShardID:   1,
+		Type:      "cross_shard",
+		TraceID:   "trace-1",
+	}
+	tx.ID = tx.Hash()
+	return tx
+}
+
+func testMessage() *CrossShardMessage {
+	return &CrossShardMessage{
+		ID:        "msg-1",
+		FromShard: 0,
+		ToShard:   3,
+		Type:      "transaction",
+		Timestamp: time.Date(2024, 3, 1, 12, 0, 1, 0, time.UTC),
+		ChainID:   "lscc-test",
+		Priority:  2,
+		HopCount:  1,
+	}
+}
+
+// roundTrip encodes and decodes message
+func roundTrip(t *testing.T, message *CrossShardMessage) *CrossShardMessage {
+	t.Helper()
+	encoded, err := EncodeCrossShardMessage(message)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	decoded, err := DecodeCrossShardMessage(encoded)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	return decoded
+}
+
+func TestTransactionMessageRoundTrip(t *testing.T) {
+	message := testMessage()
+	tx := testTransaction()
+	message.SetTransaction(tx)
+
+	decoded := roundTrip(t, message)
+	if decoded.DataType != CrossShardDataTransaction || decoded.TraceID != "trace-1" {
+		t.Fatalf("envelope fields lost: %+v", decoded)
+	}
+	got, err := decoded.Transaction()
+	if err != nil {
+		t.Fatalf("payload is not a transaction: %v", err)
+	}
+	if !reflect.DeepEqual(got, tx) {
+		t.Fatalf("transaction changed in transit:\n got %+v\nwant %+v", got, tx)
+	}
+	if got.Hash() != tx.ID {
+		t.Fatal("decoded transaction no longer matches its ID")
+	}
+	decoded.Data = message.Data
+	if !reflect.DeepEqual(decoded, message) {
+		t.Fatalf("message changed in transit:\n got %+v\nwant %+v", decoded, message)
+	}
+}
+
+func TestBlockMessageRoundTrip(t *testing.T) {
+	block := testHeader()
+	block.Transactions = []*Transaction{testTransaction()}
+	block.Hash = block.ComputeHash()
+	message := testMessage()
+	message.Type = "block"
+	message.SetBlock(block)
+
+	got, err := roundTrip(t, message).Block()
+	if err != nil {
+		t.Fatalf("payload is not a block: %v", err)
+	}
+	if got.Hash != block.Hash || !got.VerifyHash() {
+		t.Fatal("decoded block does not match its hash")
+	}
+	if len(got.Transactions) != 1 || got.Transactions[0].ID != block.Transactions[0].ID {
+		t.Fatalf("block transactions lost: %+v", got.Transactions)
+	}
+}
+
+func TestOtherPayloadsRoundTrip(t *testing.T) {
+	message := testMessage()
+	message.Type = "sync"
+	message.Data = &SyncRange{StartBlock: 5, EndBlock: 9}
+	message.DataType = CrossShardDataSyncRange
+	if r, err := roundTrip(t, message).SyncRange(); err != nil || *r != (SyncRange{StartBlock: 5, EndBlock: 9}) {
+		t.Fatalf("sync range not restored: %+v, %v", r, err)
+	}
+
+	// Plain data gets the JSON type inferred
+	message = testMessage()
+	message.Data = map[string]interface{}{"key": "value"}
+	decoded := roundTrip(t, message)
+	if decoded.DataType != CrossShardDataJSON || !reflect.DeepEqual(decoded.Data, map[string]interface{}{"key": "value"}) {
+		t.Fatalf("JSON payload not restored: %q %+v", decoded.DataType, decoded.Data)
+	}
+
+	// A payload with no type set is typed from the value it holds
+	message = testMessage()
+	message.Data = testTransaction()
+	if _, err := roundTrip(t, message).Transaction(); err != nil {
+		t.Fatalf("inferred transaction not restored: %v", err)
+	}
+
+	message = testMessage()
+	decoded = roundTrip(t, message)
+	if decoded.Data != nil || decoded.DataType != "" {
+		t.Fatalf("message without data decoded with %q %+v", decoded.DataType, decoded.Data)
+	}
+}
+
+func TestUnknownDataTypeRejected(t *testing.T) {
+	message := testMessage()
+	message.Data = map[string]interface{}{"key": "value"}
+	message.DataType = "contract"
+	if _, err := EncodeCrossShardMessage(message); !errors.Is(err, ErrUnknownDataType) {
+		t.Fatalf("encode: expected ErrUnknownDataType, got %v", err)
+	}
+
+	encoded, err := json.Marshal(map[string]interface{}{
+		"version":   CrossShardWireVersion,
+		"id":        "msg-1",
+		"data_type": "contract",
+		"data":      map[string]interface{}{"key": "value"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeCrossShardMessage(encoded); !errors.Is(err, ErrUnknownDataType) {
+		t.Fatalf("decode: expected ErrUnknownDataType, got %v", err)
+	}
+}
+
+func TestWireVersionAndMalformedInput(t *testing.T) {
+	encoded, err := json.Marshal(map[string]interface{}{"version": CrossShardWireVersion + 1, "id": "msg-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeCrossShardMessage(encoded); !errors.Is(err, ErrUnsupportedWireVersion) {
+		t.Fatalf("expected ErrUnsupportedWireVersion, got %v", err)
+	}
+
+	for _, input := range []string{
+		`not json`,
+		`{"version": 1, "id": "m", "data_type": "transaction", "data": "a string"}`,
+		`{"version": 1, "id": "m", "data_type": "block", "data": [1, 2]}`,
+	} {
+		if _, err := DecodeCrossShardMessage([]byte(input)); err == nil {
+			t.Fatalf("decoded malformed input %s", input)
+		}
+	}
+}
+
+func TestPayloadAccessorsCheckDataType(t *testing.T) {
+	message := testMessage()
+	message.SetTransaction(testTransaction())
+	if _, err := message.Block(); !errors.Is(err, ErrDataTypeMismatch) {
+		t.Fatalf("read a transaction as a block: %v", err)
+	}
+
+	// The discriminator decides, not the value's Go type
+	message.DataType = CrossShardDataBlock
+	if _, err := message.Transaction(); !errors.Is(err, ErrDataTypeMismatch) {
+		t.Fatalf("read a payload typed as a block as a transaction: %v", err)
+	}
+}